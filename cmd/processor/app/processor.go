@@ -40,6 +40,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/processor/config"
 	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
 	"github.com/nuclio/nuclio/pkg/processor/healthcheck"
+	"github.com/nuclio/nuclio/pkg/processor/invocation"
 	"github.com/nuclio/nuclio/pkg/processor/metricsink"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/timeout"
@@ -53,14 +54,19 @@ import (
 	"github.com/nuclio/logger"
 	"github.com/v3io/version-go"
 
+	// load all data bindings
+	_ "github.com/nuclio/nuclio/pkg/processor/databinding/kafka"
+	_ "github.com/nuclio/nuclio/pkg/processor/databinding/s3"
 	// load all runtimes
 	_ "github.com/nuclio/nuclio/pkg/processor/runtime/dotnetcore"
 	_ "github.com/nuclio/nuclio/pkg/processor/runtime/golang"
+	_ "github.com/nuclio/nuclio/pkg/processor/runtime/httpsidecar"
 	_ "github.com/nuclio/nuclio/pkg/processor/runtime/java"
 	_ "github.com/nuclio/nuclio/pkg/processor/runtime/nodejs"
 	_ "github.com/nuclio/nuclio/pkg/processor/runtime/python"
 	_ "github.com/nuclio/nuclio/pkg/processor/runtime/ruby"
 	_ "github.com/nuclio/nuclio/pkg/processor/runtime/shell"
+	_ "github.com/nuclio/nuclio/pkg/processor/runtime/wasm"
 	// load all triggers
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/cron"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/kafka"
@@ -92,6 +98,7 @@ type Processor struct {
 	stop                      chan bool
 	stopRestartTriggerRoutine chan bool
 	restartTriggerChan        chan trigger.Trigger
+	invocationClient          *invocation.Client
 }
 
 // NewProcessor returns a new Processor
@@ -248,6 +255,22 @@ func (p *Processor) Start() error {
 
 	time.Sleep(5 * time.Second) // Give triggers etc time to finish
 
+	for _, triggerInstance := range p.triggers {
+		if accessLogger := triggerInstance.GetAccessLogger(); accessLogger != nil {
+			if err := accessLogger.Close(); err != nil {
+				p.logger.WarnWith("Failed to close access log",
+					"triggerName", triggerInstance.GetName(),
+					"err", err.Error())
+			}
+		}
+	}
+
+	// async calls still queued are dropped
+	p.invocationClient.Close()
+
+	// push whatever the logger sinks still buffer
+	loggersink.Close(p.logger)
+
 	return nil
 }
 
@@ -380,6 +403,18 @@ func (p *Processor) createTriggers(processorConfiguration *processor.Configurati
 	var triggers []trigger.Trigger
 	abstractControlMessageBroker := controlcommunication.NewAbstractControlMessageBroker()
 
+	// all workers call other functions through the same client, so that they share its connections and the
+	// queue of async calls
+	invocationClient, err := invocation.NewClient(p.logger, &invocation.Configuration{
+		PlatformKind: processorConfiguration.PlatformConfig.Kind,
+		Namespace:    processorConfiguration.Meta.Namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create invocation client")
+	}
+
+	p.invocationClient = invocationClient
+
 	// create error group
 	errGroup, _ := errgroup.WithContext(context.Background(), p.logger)
 	lock := sync.Mutex{}
@@ -410,15 +445,22 @@ func (p *Processor) createTriggers(processorConfiguration *processor.Configurati
 
 		errGroup.Go("Creating trigger", func() error {
 
+			// let logger sinks which support labels tag entries with the trigger they originated from
+			triggerLabels := map[string]string{
+				"trigger":      triggerName,
+				"trigger_kind": triggerConfiguration.Kind,
+			}
+
 			// create an event source based on event source configuration and runtime configuration
-			triggerInstance, err := trigger.RegistrySingleton.NewTrigger(p.logger,
+			triggerInstance, err := trigger.RegistrySingleton.NewTrigger(loggersink.WithLabels(p.logger, triggerLabels),
 				triggerConfiguration.Kind,
 				triggerName,
 				&triggerConfiguration,
 				&runtime.Configuration{
 					Configuration:        processorConfiguration,
-					FunctionLogger:       p.functionLogger,
+					FunctionLogger:       loggersink.WithLabels(p.functionLogger, triggerLabels),
 					ControlMessageBroker: abstractControlMessageBroker,
+					InvocationClient:     invocationClient,
 				},
 				p.namedWorkerAllocators,
 				p.restartTriggerChan)
@@ -609,8 +651,13 @@ func (p *Processor) hardRestartTriggerWorkers(triggerInstance trigger.Trigger) e
 		"triggerKind", triggerInstance.GetKind(),
 		"triggerName", triggerInstance.GetName())
 
-	// iterate over the trigger's workers and force restart each of them
+	// iterate over the trigger's workers and force restart each of them. slots share their runtime
+	// with the worker that's restarted
 	for _, workerInstance := range triggerInstance.GetWorkers() {
+		if workerInstance.IsSlot() {
+			continue
+		}
+
 		if err := workerInstance.Restart(); err != nil {
 			return errors.Wrap(err, "Failed to restart worker")
 		}
@@ -28,6 +28,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/eventrecorder"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
@@ -256,6 +257,16 @@ func (t *testTrigger) PostBatchHooks(batch []nuclio.Event, workerInstance *worke
 	t.Called(batch, workerInstance)
 }
 
+func (t *testTrigger) GetEventRecorder() *eventrecorder.Recorder {
+	t.Called()
+	return nil
+}
+
+func (t *testTrigger) GetAccessLogger() *trigger.AccessLogger {
+	t.Called()
+	return nil
+}
+
 func TestTriggerTestSuite(t *testing.T) {
 	suite.Run(t, new(TriggerTestSuite))
 }
@@ -206,14 +206,19 @@ func (sr *ShellRunner) runAndCaptureOutput(cmd *exec.Cmd,
 	switch runOptions.CaptureOutputMode {
 
 	case CaptureOutputModeCombined:
-		stdoutAndStderr, err := cmd.CombinedOutput()
-		runResult.Output = Redact(runOptions.LogRedactions, string(stdoutAndStderr))
+		var stdoutAndStderr bytes.Buffer
+		cmd.Stdout = sr.teeOutput(&stdoutAndStderr, runOptions)
+		cmd.Stderr = cmd.Stdout
+
+		err := cmd.Run()
+
+		runResult.Output = Redact(runOptions.LogRedactions, stdoutAndStderr.String())
 		return err
 
 	case CaptureOutputModeStdout:
 		var stdOut, stdErr bytes.Buffer
-		cmd.Stdout = &stdOut
-		cmd.Stderr = &stdErr
+		cmd.Stdout = sr.teeOutput(&stdOut, runOptions)
+		cmd.Stderr = sr.teeOutput(&stdErr, runOptions)
 
 		err := cmd.Run()
 
@@ -226,6 +231,18 @@ func (sr *ShellRunner) runAndCaptureOutput(cmd *exec.Cmd,
 	return fmt.Errorf("Invalid output capture mode: %d", runOptions.CaptureOutputMode)
 }
 
+// teeOutput returns a writer that writes the captured output to the run options' output writer as well, if set
+func (sr *ShellRunner) teeOutput(capturedOutput io.Writer, runOptions *RunOptions) io.Writer {
+	if runOptions.OutputWriter == nil {
+		return capturedOutput
+	}
+
+	return io.MultiWriter(capturedOutput, &redactingWriter{
+		redactions: runOptions.LogRedactions,
+		writer:     runOptions.OutputWriter,
+	})
+}
+
 func Redact(redactions []string, runOutput string) string {
 	if redactions == nil {
 		return runOutput
@@ -240,3 +257,16 @@ func Redact(redactions []string, runOutput string) string {
 	replacer := strings.NewReplacer(replacements...)
 	return replacer.Replace(runOutput)
 }
+
+type redactingWriter struct {
+	redactions []string
+	writer     io.Writer
+}
+
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.writer, Redact(rw.redactions, string(p))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -194,6 +194,23 @@ func (suite *ShellRunnerTestSuite) TestRunAndCaptureOutputCombinedRedactsStrings
 	suite.Require().Equal("foo3[redacted]\n", runResult.Stderr)
 }
 
+func (suite *ShellRunnerTestSuite) TestRunAndCaptureOutputWritesRedactedOutputToOutputWriter() {
+	cmd := exec.Command(suite.shellRunner.shell, "-c", `echo "foo1 secret" ; sleep 0.1 ; echo "foo2">&2`)
+	suite.runOptions.CaptureOutputMode = CaptureOutputModeStdout
+	suite.runOptions.LogRedactions = []string{"secret"}
+
+	var outputWriter bytes.Buffer
+	suite.runOptions.OutputWriter = &outputWriter
+
+	var runResult RunResult
+	err := suite.shellRunner.runAndCaptureOutput(cmd, suite.runOptions, &runResult)
+	suite.Require().NoError(err, "Failed to run command")
+
+	suite.Require().Equal("foo1 [redacted]\n", runResult.Output)
+	suite.Require().Equal("foo2\n", runResult.Stderr)
+	suite.Require().Equal("foo1 [redacted]\nfoo2\n", outputWriter.String())
+}
+
 func TestShellRunnerTestSuite(t *testing.T) {
 	if testing.Short() {
 		return
@@ -36,6 +36,9 @@ type RunOptions struct {
 	LogRedactions     []string
 	CaptureOutputMode CaptureOutputMode
 
+	// if set, the command's output is also written to it as it's produced, redacted
+	OutputWriter io.Writer
+
 	// will log if command executed successfully
 	LogOnlyOnFailure bool
 
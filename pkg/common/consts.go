@@ -25,6 +25,7 @@ const (
 	FailedReadFromEventConnection     ReusedMessage = "Failed to read from event connection"
 	FailedReadFromControlConnection   ReusedMessage = "Failed to read from control connection"
 	FailedReadControlMessage          ReusedMessage = "Failed to read control message"
+	WrapperProcessCrashLooping        ReusedMessage = "Wrapper process is crash looping"
 )
 
 type FunctionStateMessage string
@@ -63,3 +64,7 @@ const FunctionConfigFileName = "function.yaml"
 const DefaultIngressHostTemplate = "@nuclio.fromDefault"
 
 const FunctionTagLatest = "latest"
+
+// BuildOutputLogKey is the log field holding a line of build output. build output is logged at debug level, and is
+// streamed to clients following the deployment rather than kept as the function's logs
+const BuildOutputLogKey = "buildOutput"
@@ -18,22 +18,38 @@ package git
 
 import (
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/nuclio/nuclio/pkg/cmdrunner"
 	"github.com/nuclio/nuclio/pkg/common"
 
 	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
+const (
+	defaultSSHUser = "git"
+
+	// the local reference of a commit fetched by its SHA
+	pinnedReferenceName = "refs/heads/nuclio-pinned-commit"
+)
+
+var commitSHARegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Client clones git repositories, returning the SHA of the checked out commit
 type Client interface {
-	Clone(outputDir, repositoryURL string, attributes *Attributes) error
+	Clone(outputDir, repositoryURL string, attributes *Attributes) (string, error)
 }
 
 type AbstractClient struct {
@@ -57,68 +73,180 @@ func NewClient(parentLogger logger.Logger) (Client, error) {
 	return &abstractClient, nil
 }
 
-func (agc *AbstractClient) Clone(outputDir, repositoryURL string, attributes *Attributes) error {
+func (agc *AbstractClient) Clone(outputDir, repositoryURL string, attributes *Attributes) (string, error) {
 	var referenceName string
-	var gitAuth *githttp.BasicAuth
 	var err error
 
+	if err := validateAttributes(repositoryURL, attributes); err != nil {
+		return "", errors.Wrap(err, "Invalid git attributes")
+	}
+
 	// resolve full git reference name
 	referenceName, err = ResolveReference(repositoryURL, attributes)
 	if err != nil {
-		return errors.Wrap(err, "Failed to resolve git reference")
+		return "", errors.Wrap(err, "Failed to resolve git reference")
 	}
 
-	// resolve git credentials when given
-	gitAuth = agc.parseCredentials(attributes)
-
 	// HACK: if it's Azure Devops repo - clone differently (the normal go-git client doesn't support it yet)
 	// TODO: remove when the issue is resolved - https://github.com/go-git/go-git/issues/64
-	if isAzureDevopsRepositoryURL(repositoryURL) {
-		return agc.cloneFromAzureDevops(outputDir, repositoryURL, referenceName, gitAuth, agc.cmdRunner)
+	// go-git can't clone partially either, so sparse checkouts (e.g. of monorepos) are cloned by git as well
+	if isAzureDevopsRepositoryURL(repositoryURL) || len(resolveSparseCheckoutDirs(attributes.SparseCheckout)) > 0 {
+		return agc.cloneWithGitCLI(outputDir,
+			repositoryURL,
+			referenceName,
+			attributes,
+			agc.parseCredentials(attributes),
+			agc.cmdRunner)
+	}
+
+	// resolve git credentials when given
+	gitAuth, err := agc.resolveAuth(repositoryURL, attributes)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to resolve git credentials")
 	}
 
-	return agc.clone(outputDir, repositoryURL, referenceName, gitAuth)
+	return agc.clone(outputDir, repositoryURL, referenceName, attributes, gitAuth)
 }
 
 func (agc *AbstractClient) clone(outputDir string,
 	repositoryURL string,
 	referenceName string,
-	gitAuth transport.AuthMethod) error {
+	attributes *Attributes,
+	gitAuth transport.AuthMethod) (string, error) {
 
 	agc.logger.DebugWith("Cloning",
 		"outputDir", outputDir,
 		"referenceName", referenceName,
-		"repositoryURL", repositoryURL)
+		"repositoryURL", repositoryURL,
+		"commit", attributes.Commit,
+		"submodules", attributes.Submodules)
 
-	if _, err := git.PlainClone(outputDir, false, &git.CloneOptions{
-		URL:           repositoryURL,
-		ReferenceName: plumbing.ReferenceName(referenceName),
-		Depth:         1,
-		Auth:          gitAuth,
-	}); err != nil {
-		return errors.Wrap(err, "Failed to clone git repository")
+	var commitHash plumbing.Hash
+
+	if attributes.Commit != "" {
+		commitHash = plumbing.NewHash(attributes.Commit)
+
+		repository, err := agc.fetchCommit(outputDir, repositoryURL, referenceName, commitHash, gitAuth)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to fetch git commit")
+		}
+
+		if err := checkoutCommit(repository, commitHash, attributes.Submodules, gitAuth); err != nil {
+			return "", errors.Wrap(err, "Failed to check out git commit")
+		}
+	} else {
+		cloneOptions := &git.CloneOptions{
+			URL:           repositoryURL,
+			ReferenceName: plumbing.ReferenceName(referenceName),
+			Depth:         1,
+			Auth:          gitAuth,
+		}
+
+		if attributes.Submodules {
+			cloneOptions.RecurseSubmodules = git.DefaultSubmoduleRecursionDepth
+			cloneOptions.ShallowSubmodules = true
+		}
+
+		repository, err := git.PlainClone(outputDir, false, cloneOptions)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to clone git repository")
+		}
+
+		resolvedHash, err := repository.ResolveRevision(plumbing.Revision(plumbing.HEAD))
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to resolve cloned commit")
+		}
+
+		commitHash = *resolvedHash
 	}
 
-	agc.logCurrentCommitSHA(outputDir, repositoryURL, referenceName)
+	agc.logger.DebugWith("Current commit SHA",
+		"repositoryURL", repositoryURL,
+		"referenceName", referenceName,
+		"commitSHA", commitHash.String())
 
-	return nil
+	return commitHash.String(), nil
 }
 
-func (agc *AbstractClient) cloneFromAzureDevops(outputDir string,
+// fetchCommit fetches a single commit into a new repository. servers that don't allow fetching commits directly
+// have the history of the reference (or of all branches) fetched instead, in which the commit is looked for
+func (agc *AbstractClient) fetchCommit(outputDir string,
 	repositoryURL string,
 	referenceName string,
+	commitHash plumbing.Hash,
+	gitAuth transport.AuthMethod) (*git.Repository, error) {
+
+	repository, err := git.PlainInit(outputDir, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to initialize git repository")
+	}
+
+	remote, err := repository.CreateRemote(&gitconfig.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{repositoryURL},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create git remote")
+	}
+
+	err = remote.Fetch(&git.FetchOptions{
+		RefSpecs: []gitconfig.RefSpec{gitconfig.RefSpec(commitHash.String() + ":" + pinnedReferenceName)},
+		Depth:    1,
+		Auth:     gitAuth,
+		Tags:     git.NoTags,
+	})
+
+	if err == git.ErrExactSHA1NotSupported {
+		agc.logger.DebugWith("Git server doesn't allow fetching commits, fetching history",
+			"repositoryURL", repositoryURL,
+			"referenceName", referenceName)
+
+		refSpec := gitconfig.RefSpec("+refs/heads/*:refs/remotes/origin/*")
+		if referenceName != "" {
+			refSpec = gitconfig.RefSpec(fmt.Sprintf("+%s:%s", referenceName, referenceName))
+		}
+
+		err = remote.Fetch(&git.FetchOptions{
+			RefSpecs: []gitconfig.RefSpec{refSpec},
+			Auth:     gitAuth,
+			Tags:     git.NoTags,
+		})
+	}
+
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, errors.Wrap(err, "Failed to fetch from git repository")
+	}
+
+	if _, err := repository.CommitObject(commitHash); err != nil {
+		return nil, errors.Wrapf(err, "Commit %s wasn't found in git repository", commitHash.String())
+	}
+
+	return repository, nil
+}
+
+// cloneWithGitCLI clones with the git binary, fetching only the blobs of the sparse checkout directories when given
+func (agc *AbstractClient) cloneWithGitCLI(outputDir string,
+	repositoryURL string,
+	referenceName string,
+	attributes *Attributes,
 	gitAuth *githttp.BasicAuth,
-	cmdRunner cmdrunner.CmdRunner) error {
+	cmdRunner cmdrunner.CmdRunner) (string, error) {
 
-	agc.logger.DebugWith("Cloning from azure devops",
+	agc.logger.DebugWith("Cloning with git CLI",
 		"outputDir", outputDir,
 		"referenceName", referenceName,
-		"repositoryURL", repositoryURL)
+		"repositoryURL", repositoryURL,
+		"commit", attributes.Commit,
+		"submodules", attributes.Submodules,
+		"sparseCheckout", attributes.SparseCheckout)
 
 	var runOptions *cmdrunner.RunOptions
 
+	// keep the URL without the credentials for logging
+	cloneURL := repositoryURL
+
 	// compile repository URL with git auth credentials
-	if gitAuth != nil {
+	if gitAuth != nil && attributes.SSHPrivateKey == "" && strings.Contains(repositoryURL, "://") {
 		splitFunctionPath := strings.Split(repositoryURL, "://")
 		prefix := splitFunctionPath[0]
 		projectPath := splitFunctionPath[1]
@@ -130,7 +258,7 @@ func (agc *AbstractClient) cloneFromAzureDevops(outputDir string,
 			projectPath = splitProjectPath[1]
 		}
 
-		repositoryURL = fmt.Sprintf("%s://%s:%s@%s",
+		cloneURL = fmt.Sprintf("%s://%s:%s@%s",
 			prefix,
 			gitAuth.Username,
 			gitAuth.Password,
@@ -142,48 +270,188 @@ func (agc *AbstractClient) cloneFromAzureDevops(outputDir string,
 		}
 	}
 
-	// generate a git clone command
-	cloneCommand := fmt.Sprintf("git clone %s --depth 1 -q %s",
-		common.Quote(repositoryURL),
+	gitCommand := "git"
+
+	// authenticate over SSH with the private key, which git reads from a file
+	if attributes.SSHPrivateKey != "" {
+		sshDir, err := os.MkdirTemp("", "git-ssh-")
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to create temporary dir for SSH files")
+		}
+
+		defer os.RemoveAll(sshDir) // nolint: errcheck
+
+		sshCommand, err := createSSHCommand(sshDir, attributes)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to create SSH command")
+		}
+
+		gitCommand = fmt.Sprintf("GIT_SSH_COMMAND=%s git", common.Quote(sshCommand))
+	}
+
+	// blobs outside of the sparse checkout directories aren't fetched
+	sparseCheckoutDirs := resolveSparseCheckoutDirs(attributes.SparseCheckout)
+	fetchFlags := "--depth 1 -q"
+	if len(sparseCheckoutDirs) > 0 {
+		fetchFlags += " --filter=blob:none"
+	}
+
+	// generate a git clone command, checking out only after the commit and sparse checkout are set
+	cloneCommand := fmt.Sprintf("%s clone %s %s --no-checkout %s",
+		gitCommand,
+		fetchFlags,
+		common.Quote(cloneURL),
 		common.Quote(outputDir))
 
 	// attach git reference name when given (use -b as it works both for branch/tag)
 	if referenceName != "" {
-		cloneCommand = fmt.Sprintf("%s -b %s", cloneCommand, common.Quote(referenceName))
+		branchOrTag := strings.TrimPrefix(strings.TrimPrefix(referenceName, "refs/heads/"), "refs/tags/")
+		cloneCommand = fmt.Sprintf("%s -b %s", cloneCommand, common.Quote(branchOrTag))
 	}
 
 	// run the above git clone command
-	res, err := cmdRunner.Run(runOptions, cloneCommand)
+	if err := agc.runGitCommand(cmdRunner, runOptions, cloneCommand); err != nil {
+		return "", errors.Wrap(err, "Failed to clone git repository")
+	}
+
+	checkoutTarget := "HEAD"
+	if attributes.Commit != "" {
+		fetchCommand := fmt.Sprintf("%s -C %s fetch %s origin %s",
+			gitCommand,
+			common.Quote(outputDir),
+			fetchFlags,
+			common.Quote(attributes.Commit))
+		if err := agc.runGitCommand(cmdRunner, runOptions, fetchCommand); err != nil {
+			return "", errors.Wrap(err, "Failed to fetch git commit")
+		}
+
+		checkoutTarget = attributes.Commit
+	}
+
+	if len(sparseCheckoutDirs) > 0 {
+		sparseCheckoutCommand := fmt.Sprintf("git -C %s sparse-checkout set --", common.Quote(outputDir))
+		for _, sparseCheckoutDir := range sparseCheckoutDirs {
+			sparseCheckoutCommand = fmt.Sprintf("%s %s", sparseCheckoutCommand, common.Quote(sparseCheckoutDir))
+		}
+
+		if err := agc.runGitCommand(cmdRunner, runOptions, sparseCheckoutCommand); err != nil {
+			return "", errors.Wrap(err, "Failed to set git sparse checkout")
+		}
+	}
+
+	// blobs of a partial clone are fetched on checkout
+	checkoutCommand := fmt.Sprintf("%s -C %s reset -q --hard %s",
+		gitCommand,
+		common.Quote(outputDir),
+		common.Quote(checkoutTarget))
+	if err := agc.runGitCommand(cmdRunner, runOptions, checkoutCommand); err != nil {
+		return "", errors.Wrap(err, "Failed to check out git commit")
+	}
+
+	if attributes.Submodules {
+		submoduleCommand := fmt.Sprintf("%s -C %s submodule update -q --init --recursive --depth 1",
+			gitCommand,
+			common.Quote(outputDir))
+		if err := agc.runGitCommand(cmdRunner, runOptions, submoduleCommand); err != nil {
+			return "", errors.Wrap(err, "Failed to update git submodules")
+		}
+	}
+
+	commitSHA, err := agc.getCurrentCommitSHA(outputDir)
 	if err != nil {
-		return errors.Wrap(err, "Failed to run clone command on azure repository")
+		return "", errors.Wrap(err, "Failed to get commit SHA")
+	}
+
+	agc.logger.DebugWith("Current commit SHA",
+		"repositoryURL", repositoryURL,
+		"referenceName", referenceName,
+		"commitSHA", commitSHA)
+
+	return commitSHA, nil
+}
+
+func (agc *AbstractClient) runGitCommand(cmdRunner cmdrunner.CmdRunner,
+	runOptions *cmdrunner.RunOptions,
+	command string) error {
+
+	res, err := cmdRunner.Run(runOptions, "%s", command)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to run git command. Reason: %s", res.Output)
 	}
 
 	if res.ExitCode != 0 {
-		return errors.Errorf("Failed to clone azure devops git repository. Reason: %s", res.Output)
+		return errors.Errorf("Git command failed. Reason: %s", res.Output)
 	}
 
-	agc.logCurrentCommitSHA(outputDir, repositoryURL, referenceName)
 	return nil
 }
 
-func (agc *AbstractClient) logCurrentCommitSHA(gitDir, repositoryURL, referenceName string) {
-	res, err := agc.cmdRunner.Run(nil, fmt.Sprintf("cd %s;git rev-parse HEAD", common.Quote(gitDir)))
-	if err != nil || res.ExitCode != 0 {
-		agc.logger.WarnWith("Failed to get commit SHA", "err", err)
-		return
+func (agc *AbstractClient) getCurrentCommitSHA(gitDir string) (string, error) {
+	res, err := agc.cmdRunner.Run(nil, "git -C %s rev-parse HEAD", common.Quote(gitDir))
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to run rev-parse")
 	}
+
 	if res.ExitCode != 0 {
-		agc.logger.WarnWith("Failed to get commit SHA (non-zero exit code)", "output", res.Output)
-		return
+		return "", errors.Errorf("Failed to run rev-parse. Reason: %s", res.Output)
 	}
 
 	// remove automatic new line from end of res.Output
-	commitSHA := strings.TrimSuffix(res.Output, "\n")
+	return strings.TrimSpace(res.Output), nil
+}
 
-	agc.logger.DebugWith("Current commit SHA",
-		"repositoryURL", repositoryURL,
-		"referenceName", referenceName,
-		"commitSHA", commitSHA)
+// resolveAuth returns the SSH public keys or the basic auth credentials of the attributes, if any
+func (agc *AbstractClient) resolveAuth(repositoryURL string, attributes *Attributes) (transport.AuthMethod, error) {
+	if attributes.SSHPrivateKey == "" {
+		if basicAuth := agc.parseCredentials(attributes); basicAuth != nil {
+			return basicAuth, nil
+		}
+
+		return nil, nil
+	}
+
+	endpoint, err := transport.NewEndpoint(repositoryURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse repository URL")
+	}
+
+	// the user is part of the repository URL (e.g. git@github.com:org/repo.git)
+	user := endpoint.User
+	if user == "" {
+		user = defaultSSHUser
+	}
+
+	publicKeys, err := gitssh.NewPublicKeys(user,
+		[]byte(attributes.SSHPrivateKey),
+		attributes.SSHPrivateKeyPassphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse SSH private key")
+	}
+
+	// the known hosts callback reads the files when created, so a temporary file can be removed right after
+	knownHostsFiles := []string{}
+	if attributes.KnownHosts != "" {
+		knownHostsDir, err := os.MkdirTemp("", "git-ssh-")
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create temporary dir for known hosts")
+		}
+
+		defer os.RemoveAll(knownHostsDir) // nolint: errcheck
+
+		knownHostsPath := filepath.Join(knownHostsDir, "known_hosts")
+		if err := os.WriteFile(knownHostsPath, []byte(attributes.KnownHosts), 0600); err != nil {
+			return nil, errors.Wrap(err, "Failed to write known hosts")
+		}
+
+		knownHostsFiles = append(knownHostsFiles, knownHostsPath)
+	}
+
+	publicKeys.HostKeyCallback, err = gitssh.NewKnownHostsCallback(knownHostsFiles...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to load known hosts (must be given when the user has none)")
+	}
+
+	return publicKeys, nil
 }
 
 func (agc *AbstractClient) parseCredentials(attributes *Attributes) *githttp.BasicAuth {
@@ -230,9 +498,117 @@ func ResolveReference(repositoryURL string, attributes *Attributes) (string, err
 		return ref, nil
 	}
 
-	return "", nuclio.NewErrBadRequest("No git reference was specified. (must specify branch/tag/reference)")
+	// a commit doesn't require a reference
+	if attributes.Commit != "" {
+		return "", nil
+	}
+
+	return "", nuclio.NewErrBadRequest("No git reference was specified. (must specify branch/tag/reference/commit)")
 }
 
 func isAzureDevopsRepositoryURL(repositoryURL string) bool {
 	return strings.Contains(repositoryURL, "dev.azure.com")
 }
+
+func validateAttributes(repositoryURL string, attributes *Attributes) error {
+	if attributes.Commit != "" {
+		attributes.Commit = strings.ToLower(attributes.Commit)
+		if !commitSHARegex.MatchString(attributes.Commit) {
+			return nuclio.NewErrBadRequest(fmt.Sprintf("Git commit must be a full SHA, got %s", attributes.Commit))
+		}
+	}
+
+	if attributes.SSHPrivateKey != "" {
+		endpoint, err := transport.NewEndpoint(repositoryURL)
+		if err != nil || endpoint.Protocol != "ssh" {
+			return nuclio.NewErrBadRequest("SSH private key requires an SSH repository URL")
+		}
+	}
+
+	for _, sparseCheckoutDir := range attributes.SparseCheckout {
+		if strings.Contains(sparseCheckoutDir, "..") {
+			return nuclio.NewErrBadRequest(fmt.Sprintf("Invalid sparse checkout directory %s", sparseCheckoutDir))
+		}
+	}
+
+	return nil
+}
+
+// resolveSparseCheckoutDirs returns the sparse checkout directories relative to the repository root, or none if the
+// whole repository is checked out
+func resolveSparseCheckoutDirs(sparseCheckout []string) []string {
+	var sparseCheckoutDirs []string
+
+	for _, sparseCheckoutDir := range sparseCheckout {
+		sparseCheckoutDir = path.Clean("/" + sparseCheckoutDir)
+		if sparseCheckoutDir == "/" {
+			return nil
+		}
+
+		sparseCheckoutDirs = append(sparseCheckoutDirs, strings.TrimPrefix(sparseCheckoutDir, "/"))
+	}
+
+	return sparseCheckoutDirs
+}
+
+func checkoutCommit(repository *git.Repository,
+	commitHash plumbing.Hash,
+	submodules bool,
+	gitAuth transport.AuthMethod) error {
+
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get worktree")
+	}
+
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: commitHash, Force: true}); err != nil {
+		return errors.Wrap(err, "Failed to check out commit")
+	}
+
+	if !submodules {
+		return nil
+	}
+
+	repositorySubmodules, err := worktree.Submodules()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get submodules")
+	}
+
+	if err := repositorySubmodules.Update(&git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		Auth:              gitAuth,
+		Depth:             1,
+	}); err != nil {
+		return errors.Wrap(err, "Failed to update submodules")
+	}
+
+	return nil
+}
+
+// createSSHCommand writes the private key and known hosts to a directory, and returns an ssh command using them
+func createSSHCommand(sshDir string, attributes *Attributes) (string, error) {
+	if attributes.SSHPrivateKeyPassphrase != "" {
+		return "", nuclio.NewErrBadRequest(
+			"SSH private keys with a passphrase aren't supported for azure devops repositories or sparse checkouts")
+	}
+
+	privateKeyPath := filepath.Join(sshDir, "id")
+	if err := os.WriteFile(privateKeyPath, []byte(attributes.SSHPrivateKey), 0600); err != nil {
+		return "", errors.Wrap(err, "Failed to write SSH private key")
+	}
+
+	sshCommand := fmt.Sprintf("ssh -i %s -o IdentitiesOnly=yes -o StrictHostKeyChecking=yes",
+		common.Quote(privateKeyPath))
+
+	if attributes.KnownHosts != "" {
+		knownHostsPath := filepath.Join(sshDir, "known_hosts")
+		if err := os.WriteFile(knownHostsPath, []byte(attributes.KnownHosts), 0600); err != nil {
+			return "", errors.Wrap(err, "Failed to write known hosts")
+		}
+
+		sshCommand = fmt.Sprintf("%s -o UserKnownHostsFile=%s", sshCommand, common.Quote(knownHostsPath))
+	}
+
+	return sshCommand, nil
+}
@@ -0,0 +1,254 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/ssh"
+)
+
+type GitTestSuite struct {
+	suite.Suite
+	logger        logger.Logger
+	client        Client
+	repositoryURL string
+	commitSHAs    []string
+}
+
+func (suite *GitTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.client, err = NewClient(suite.logger)
+	suite.Require().NoError(err)
+
+	// a repository with two functions, changed by a second commit
+	suite.repositoryURL = suite.T().TempDir()
+	suite.commitSHAs = []string{
+		suite.commit(map[string]string{"funcs/a/main.py": "v1", "funcs/b/main.py": "v1", "README.md": "v1"}),
+		suite.commit(map[string]string{"funcs/a/main.py": "v2"}),
+	}
+}
+
+func (suite *GitTestSuite) TestCloneBranch() {
+	outputDir := suite.T().TempDir()
+
+	commitSHA, err := suite.client.Clone(outputDir, suite.repositoryURL, &Attributes{Branch: "master"})
+	suite.Require().NoError(err)
+	suite.Require().Equal(suite.commitSHAs[1], commitSHA)
+
+	suite.requireFiles(outputDir, map[string]string{
+		"funcs/a/main.py": "v2",
+		"funcs/b/main.py": "v1",
+		"README.md":       "v1",
+	})
+}
+
+func (suite *GitTestSuite) TestCloneSparseCheckout() {
+	outputDir := suite.T().TempDir()
+
+	commitSHA, err := suite.client.Clone(outputDir, suite.repositoryURL, &Attributes{
+		Branch:         "master",
+		SparseCheckout: []string{"/funcs/a/"},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(suite.commitSHAs[1], commitSHA)
+
+	// files in the root are always checked out
+	suite.requireFiles(outputDir, map[string]string{"funcs/a/main.py": "v2", "README.md": "v1"})
+	suite.Require().NoFileExists(filepath.Join(outputDir, "funcs/b/main.py"))
+}
+
+func (suite *GitTestSuite) TestClonePinnedCommit() {
+	for _, testCase := range []struct {
+		name       string
+		attributes *Attributes
+	}{
+		{
+			name:       "WithBranch",
+			attributes: &Attributes{Branch: "master", Commit: suite.commitSHAs[0]},
+		},
+		{
+			name:       "WithoutReference",
+			attributes: &Attributes{Commit: strings.ToUpper(suite.commitSHAs[0])},
+		},
+		{
+			name: "SparseCheckout",
+			attributes: &Attributes{
+				Commit:         suite.commitSHAs[0],
+				SparseCheckout: []string{"funcs/a"},
+			},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			outputDir := suite.T().TempDir()
+
+			commitSHA, err := suite.client.Clone(outputDir, suite.repositoryURL, testCase.attributes)
+			suite.Require().NoError(err)
+			suite.Require().Equal(suite.commitSHAs[0], commitSHA)
+			suite.requireFiles(outputDir, map[string]string{"funcs/a/main.py": "v1"})
+		})
+	}
+}
+
+func (suite *GitTestSuite) TestCloneInvalidAttributes() {
+	for _, testCase := range []struct {
+		name          string
+		repositoryURL string
+		attributes    *Attributes
+	}{
+		{
+			name:       "ShortCommit",
+			attributes: &Attributes{Commit: suite.commitSHAs[0][:7]},
+		},
+		{
+			name:       "MissingCommit",
+			attributes: &Attributes{Branch: "master", Commit: strings.Repeat("0", 40)},
+		},
+		{
+			name:          "SSHKeyWithHTTPURL",
+			repositoryURL: "https://github.com/nuclio/nuclio.git",
+			attributes:    &Attributes{Branch: "master", SSHPrivateKey: "key"},
+		},
+		{
+			name:       "SparseCheckoutOutsideRepository",
+			attributes: &Attributes{Branch: "master", SparseCheckout: []string{"../funcs"}},
+		},
+		{
+			name:       "NoReference",
+			attributes: &Attributes{},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			repositoryURL := testCase.repositoryURL
+			if repositoryURL == "" {
+				repositoryURL = suite.repositoryURL
+			}
+
+			_, err := suite.client.Clone(suite.T().TempDir(), repositoryURL, testCase.attributes)
+			suite.Require().Error(err)
+		})
+	}
+}
+
+func (suite *GitTestSuite) TestResolveSSHAuth() {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	suite.Require().NoError(err)
+
+	privateKeyBlock, err := ssh.MarshalPrivateKey(privateKey, "")
+	suite.Require().NoError(err)
+
+	hostPublicKey, err := ssh.NewPublicKey(privateKey.Public())
+	suite.Require().NoError(err)
+
+	attributes := &Attributes{
+		SSHPrivateKey: string(pem.EncodeToMemory(privateKeyBlock)),
+		KnownHosts:    "github.com " + string(ssh.MarshalAuthorizedKey(hostPublicKey)),
+	}
+
+	for _, testCase := range []struct {
+		repositoryURL string
+		expectedUser  string
+	}{
+		{repositoryURL: "git@github.com:nuclio/nuclio.git", expectedUser: "git"},
+		{repositoryURL: "ssh://deploy@github.com/nuclio/nuclio.git", expectedUser: "deploy"},
+		{repositoryURL: "ssh://github.com/nuclio/nuclio.git", expectedUser: "git"},
+	} {
+		gitAuth, err := suite.client.(*AbstractClient).resolveAuth(testCase.repositoryURL, attributes)
+		suite.Require().NoError(err)
+
+		publicKeys, ok := gitAuth.(*gitssh.PublicKeys)
+		suite.Require().True(ok)
+		suite.Require().Equal(testCase.expectedUser, publicKeys.User)
+
+		// the host is verified against the given known hosts
+		hostAddress := &net.TCPAddr{IP: net.ParseIP("140.82.121.3"), Port: 22}
+		suite.Require().NoError(publicKeys.HostKeyCallback("github.com:22", hostAddress, hostPublicKey))
+		suite.Require().Error(publicKeys.HostKeyCallback("gitlab.com:22", hostAddress, hostPublicKey))
+	}
+
+	// an invalid key fails
+	_, err = suite.client.(*AbstractClient).resolveAuth("git@github.com:nuclio/nuclio.git",
+		&Attributes{SSHPrivateKey: "not a key", KnownHosts: attributes.KnownHosts})
+	suite.Require().Error(err)
+}
+
+func (suite *GitTestSuite) TestResolveSparseCheckoutDirs() {
+	suite.Require().Equal([]string{"funcs/a", "lib"},
+		resolveSparseCheckoutDirs([]string{"/funcs/a/", "lib"}))
+
+	// the root checks out the whole repository
+	suite.Require().Empty(resolveSparseCheckoutDirs([]string{"funcs/a", "/"}))
+}
+
+// commit writes the files to the test repository and commits them, returning the commit's SHA
+func (suite *GitTestSuite) commit(files map[string]string) string {
+	repository, err := git.PlainOpen(suite.repositoryURL)
+	if err == git.ErrRepositoryNotExists {
+		repository, err = git.PlainInit(suite.repositoryURL, false)
+	}
+	suite.Require().NoError(err)
+
+	worktree, err := repository.Worktree()
+	suite.Require().NoError(err)
+
+	for filePath, contents := range files {
+		suite.Require().NoError(os.MkdirAll(filepath.Dir(filepath.Join(suite.repositoryURL, filePath)), 0755))
+		suite.Require().NoError(os.WriteFile(filepath.Join(suite.repositoryURL, filePath), []byte(contents), 0644))
+
+		_, err = worktree.Add(filePath)
+		suite.Require().NoError(err)
+	}
+
+	commitHash, err := worktree.Commit("update functions", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@nuclio.io", When: time.Now()},
+	})
+	suite.Require().NoError(err)
+
+	return commitHash.String()
+}
+
+func (suite *GitTestSuite) requireFiles(dir string, files map[string]string) {
+	for filePath, expectedContents := range files {
+		contents, err := os.ReadFile(filepath.Join(dir, filePath))
+		suite.Require().NoError(err)
+		suite.Require().Equal(expectedContents, string(contents))
+	}
+}
+
+func TestGitTestSuite(t *testing.T) {
+	suite.Run(t, new(GitTestSuite))
+}
@@ -22,4 +22,20 @@ type Attributes struct {
 	Reference string `json:"reference,omitempty"`
 	Username  string `json:"username,omitempty"`
 	Password  string `json:"password,omitempty"`
+
+	// the full SHA of the commit to check out. when a branch, tag or reference is given as well, it's used to find
+	// the commit on servers that don't allow fetching commits directly
+	Commit string `json:"commit,omitempty"`
+
+	// authenticate over SSH with a private key, verifying the server with the given known hosts (in known_hosts
+	// format) or with those of the user
+	SSHPrivateKey           string `json:"sshPrivateKey,omitempty"`
+	SSHPrivateKeyPassphrase string `json:"sshPrivateKeyPassphrase,omitempty"`
+	KnownHosts              string `json:"knownHosts,omitempty"`
+
+	// clone the submodules of the repository, recursively
+	Submodules bool `json:"submodules,omitempty"`
+
+	// check out only these directories of the repository
+	SparseCheckout []string `json:"sparseCheckout,omitempty"`
 }
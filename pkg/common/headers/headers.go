@@ -71,6 +71,7 @@ const (
 	FilterContains = "X-Nuclio-Filter-Contains"
 	StreamNoAck    = "X-Nuclio-Stream-No-Ack"
 	Arguments      = "X-Nuclio-Arguments"
+	RequestID      = "X-Request-Id"
 )
 
 func IsNuclioHeader(headerName string) bool {
@@ -659,3 +659,48 @@ func GetFunctionName(function interface{}) string {
 
 	return shortName
 }
+
+// UnflattenCertificate restores the newlines of a PEM certificate or key that was flattened (e.g. to be passed
+// in an annotation), either by replacing them with "@" or with spaces
+func UnflattenCertificate(certificate string) string {
+
+	// if there are newlines in the certificate, it's not flat. return as is
+	if strings.Contains(certificate, "\n") {
+		return certificate
+	}
+
+	// in this mode, the user replaces newlines with "@"
+	if strings.Contains(certificate, "@") {
+		return strings.ReplaceAll(certificate, "@", "\n")
+	}
+
+	//
+	// try to be fancy and try to auto-unflatten the certificate
+	//
+
+	headers := []string{
+		"BEGIN CERTIFICATE",
+		"END CERTIFICATE",
+		"BEGIN PRIVATE KEY",
+		"END PRIVATE KEY",
+	}
+
+	// headers have spaces... remove them temporarily
+	for _, spacedHeader := range headers {
+		certificate = strings.ReplaceAll(certificate,
+			spacedHeader,
+			strings.ReplaceAll(spacedHeader, " ", "-"))
+	}
+
+	// now replace all spaces with newline
+	certificate = strings.ReplaceAll(certificate, " ", "\n")
+
+	// and revert header
+	for _, spacedHeader := range headers {
+		certificate = strings.ReplaceAll(certificate,
+			strings.ReplaceAll(spacedHeader, " ", "-"),
+			spacedHeader)
+	}
+
+	return certificate
+}
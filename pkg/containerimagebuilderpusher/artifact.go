@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerimagebuilderpusher
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const (
+	ociTitleAnnotation      = "org.opencontainers.image.title"
+	orasUnpackAnnotation    = "io.deis.oras.content.unpack"
+	artifactReferencePrefix = "oci://"
+)
+
+// PullArtifact writes the files of an OCI artifact (e.g. one pushed by "oras push") to a directory, and returns
+// their paths. each layer is a file named by its title annotation, and layers of directories are unpacked
+func PullArtifact(ctx context.Context,
+	logger logger.Logger,
+	builderConfiguration *ContainerBuilderConfiguration,
+	artifactReference string,
+	outputDir string) ([]string, error) {
+
+	registryClient, err := newRegistryClient(logger, builderConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create registry client")
+	}
+
+	reference, err := registryClient.parseReference(strings.TrimPrefix(artifactReference, artifactReferencePrefix),
+		registryClient.builderConfiguration.InsecurePullRegistry)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid artifact reference")
+	}
+
+	logger.DebugWithCtx(ctx, "Pulling artifact", "reference", reference.String())
+
+	// artifacts aren't images, so their manifests are read without resolving a platform
+	artifact, err := remote.Image(reference, registryClient.getRemoteOptions(ctx)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to pull artifact")
+	}
+
+	manifest, err := artifact.Manifest()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read artifact manifest")
+	}
+
+	if len(manifest.Layers) == 0 {
+		return nil, errors.New("Artifact has no files")
+	}
+
+	var artifactPaths []string
+	for layerIndex, layerDescriptor := range manifest.Layers {
+		title := layerDescriptor.Annotations[ociTitleAnnotation]
+		if title == "" {
+			return nil, errors.Errorf("Artifact layer %d has no %s annotation", layerIndex, ociTitleAnnotation)
+		}
+
+		artifactPath, err := resolvePathWithinDir(outputDir, title)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid artifact file name")
+		}
+
+		layer, err := artifact.LayerByDigest(layerDescriptor.Digest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to get artifact layer %s", title)
+		}
+
+		if err := writeArtifactLayer(layer, layerDescriptor, artifactPath); err != nil {
+			return nil, errors.Wrapf(err, "Failed to write artifact layer %s", title)
+		}
+
+		artifactPaths = append(artifactPaths, artifactPath)
+	}
+
+	logger.InfoWithCtx(ctx,
+		"Pulled artifact",
+		"reference", reference.String(),
+		"paths", artifactPaths)
+
+	return artifactPaths, nil
+}
+
+func writeArtifactLayer(layer ociv1.Layer, layerDescriptor ociv1.Descriptor, artifactPath string) error {
+
+	// oras packs directories as gzipped tarballs, which are unpacked to a directory of the same name
+	if layerDescriptor.Annotations[orasUnpackAnnotation] == "true" {
+		layerReader, err := layer.Uncompressed()
+		if err != nil {
+			return errors.Wrap(err, "Failed to read layer")
+		}
+
+		defer layerReader.Close() // nolint: errcheck
+
+		return unpackTar(layerReader, artifactPath)
+	}
+
+	// files are stored as is, so the layer's blob is the file
+	layerReader, err := layer.Compressed()
+	if err != nil {
+		return errors.Wrap(err, "Failed to read layer")
+	}
+
+	defer layerReader.Close() // nolint: errcheck
+
+	artifactFile, err := os.OpenFile(artifactPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create file")
+	}
+
+	defer artifactFile.Close() // nolint: errcheck
+
+	if _, err := io.Copy(artifactFile, layerReader); err != nil {
+		return errors.Wrap(err, "Failed to write file")
+	}
+
+	return nil
+}
+
+// unpackTar unpacks a tarball into a directory, refusing entries that would be written outside of it
+func unpackTar(reader io.Reader, targetDir string) error {
+	tarReader := tar.NewReader(reader)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "Failed to read tarball")
+		}
+
+		targetPath, err := resolvePathWithinDir(targetDir, header.Name)
+		if err != nil {
+			return errors.Wrap(err, "Invalid tarball entry")
+		}
+
+		if header.Typeflag == tar.TypeSymlink {
+			linkTarget := filepath.Join(filepath.Dir(targetPath), header.Linkname)
+			if filepath.IsAbs(header.Linkname) || !isWithinDir(targetDir, linkTarget) {
+				return errors.Errorf("Tarball entry %s links outside of the artifact", header.Name)
+			}
+		}
+
+		if err := writeTarEntry(header, tarReader, targetDir, targetPath); err != nil {
+			return errors.Wrapf(err, "Failed to write %s", header.Name)
+		}
+	}
+}
+
+// resolvePathWithinDir returns the path of a file in the directory, which it mustn't escape
+func resolvePathWithinDir(dir string, name string) (string, error) {
+	resolvedPath := filepath.Join(dir, filepath.FromSlash(name))
+	if !isWithinDir(dir, resolvedPath) {
+		return "", errors.Errorf("Path %s is outside of its directory", name)
+	}
+
+	return resolvedPath, nil
+}
+
+func isWithinDir(dir string, path string) bool {
+	relativePath, err := filepath.Rel(dir, path)
+	return err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
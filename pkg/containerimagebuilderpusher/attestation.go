@@ -0,0 +1,319 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerimagebuilderpusher
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// AttestationOptions are options for attaching an SBOM and a signature to an image
+type AttestationOptions struct {
+
+	// the image in its registry, including the registry URL
+	Image string
+
+	// the SBOM format, or empty to skip the SBOM
+	SBOMFormat functionconfig.SBOMFormat
+
+	// directory whose runtime dependency manifests (requirements.txt, package.json, go.mod) are added to the SBOM
+	ManifestsDir string
+
+	// the private key to sign the image with, or empty to skip the signature
+	SigningKeyPath string
+}
+
+// AttestContainerImage attaches an SBOM and a signature to an image in its registry. they're stored next to the
+// image in tags that cosign looks for, so cosign can download and verify them
+func AttestContainerImage(ctx context.Context,
+	logger logger.Logger,
+	builderConfiguration *ContainerBuilderConfiguration,
+	attestationOptions *AttestationOptions) (*functionconfig.ImageAttestation, error) {
+
+	registryClient, err := newRegistryClient(logger, builderConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create registry client")
+	}
+
+	// attach to the image by digest, so that the image tag can move on
+	imageDigest, err := registryClient.resolveImageDigest(ctx, attestationOptions.Image)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve image digest")
+	}
+
+	imageReference, err := registryClient.parseReference(attestationOptions.Image,
+		registryClient.builderConfiguration.InsecurePushRegistry)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid image name")
+	}
+
+	imageAttestation := &functionconfig.ImageAttestation{
+		Image:      attestationOptions.Image,
+		Digest:     imageDigest,
+		SBOMFormat: attestationOptions.SBOMFormat,
+	}
+
+	platformImageDigests, err := registryClient.getPlatformImageDigests(ctx,
+		imageReference.Context().Digest(imageDigest).String())
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get platform images")
+	}
+
+	// a multi-platform image is signed as a whole, while its contents are described by the SBOM of each platform
+	// image, which is also signed on its own
+	imageAttestation.SBOMImage, imageAttestation.SignatureImage, err = attestManifest(ctx,
+		registryClient,
+		imageReference.Context(),
+		imageDigest,
+		len(platformImageDigests) == 0,
+		attestationOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	platforms := make([]string, 0, len(platformImageDigests))
+	for platform := range platformImageDigests {
+		platforms = append(platforms, platform)
+	}
+
+	sort.Strings(platforms)
+
+	for _, platform := range platforms {
+		platformImageAttestation := functionconfig.PlatformImageAttestation{
+			Platform: platform,
+			Digest:   platformImageDigests[platform],
+		}
+
+		platformImageAttestation.SBOMImage, platformImageAttestation.SignatureImage, err = attestManifest(ctx,
+			registryClient,
+			imageReference.Context(),
+			platformImageAttestation.Digest,
+			true,
+			attestationOptions)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to attest image of platform %s", platform)
+		}
+
+		imageAttestation.Platforms = append(imageAttestation.Platforms, platformImageAttestation)
+	}
+
+	if imageAttestation.SBOMImage == "" && len(imageAttestation.Platforms) == 0 {
+		imageAttestation.SBOMFormat = ""
+	}
+
+	logger.InfoWithCtx(ctx,
+		"Attested image",
+		"image", attestationOptions.Image,
+		"digest", imageDigest,
+		"sbomImage", imageAttestation.SBOMImage,
+		"signatureImage", imageAttestation.SignatureImage,
+		"platforms", len(imageAttestation.Platforms))
+
+	return imageAttestation, nil
+}
+
+// attestManifest attaches an SBOM, unless skipped, and a signature to the image or index of the digest, returning
+// the images holding them
+func attestManifest(ctx context.Context,
+	registryClient *registryClient,
+	repository name.Repository,
+	digest string,
+	withSBOM bool,
+	attestationOptions *AttestationOptions) (string, string, error) {
+	var sbomImage, signatureImage string
+	var err error
+
+	if withSBOM && attestationOptions.SBOMFormat != "" {
+		sbomImage, err = attachSBOM(ctx, registryClient, repository, digest, attestationOptions)
+		if err != nil {
+			return "", "", errors.Wrap(err, "Failed to attach SBOM")
+		}
+	}
+
+	if attestationOptions.SigningKeyPath != "" {
+		signatureImage, err = attachSignature(ctx, registryClient, repository, digest, attestationOptions.SigningKeyPath)
+		if err != nil {
+			return "", "", errors.Wrap(err, "Failed to attach signature")
+		}
+	}
+
+	return sbomImage, signatureImage, nil
+}
+
+func attachSBOM(ctx context.Context,
+	registryClient *registryClient,
+	repository name.Repository,
+	imageDigest string,
+	attestationOptions *AttestationOptions) (string, error) {
+
+	image, err := registryClient.pullImage(ctx, repository.Digest(imageDigest).String())
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to pull image")
+	}
+
+	imagePackages, err := collectImagePackages(image)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to collect installed packages")
+	}
+
+	manifestPackages, err := collectManifestPackages(attestationOptions.ManifestsDir)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to collect dependency manifest packages")
+	}
+
+	document, mediaType, err := encodeSBOM(attestationOptions.SBOMFormat,
+		repository.String(),
+		imageDigest,
+		append(imagePackages, manifestPackages...))
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to encode SBOM")
+	}
+
+	// a new SBOM replaces the previous one
+	sbomImage, err := mutate.AppendLayers(newAttachmentImage(), static.NewLayer(document, types.MediaType(mediaType)))
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create SBOM image")
+	}
+
+	sbomTag := getAttachmentTag(repository, imageDigest, "sbom")
+	if err := registryClient.pushImage(ctx, sbomImage, sbomTag); err != nil {
+		return "", errors.Wrap(err, "Failed to push SBOM image")
+	}
+
+	return sbomTag, nil
+}
+
+func attachSignature(ctx context.Context,
+	registryClient *registryClient,
+	repository name.Repository,
+	imageDigest string,
+	signingKeyPath string) (string, error) {
+
+	signer, err := loadSigningKey(signingKeyPath)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to load signing key")
+	}
+
+	payload, err := createSimpleSigningPayload(repository.String(), imageDigest)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create signing payload")
+	}
+
+	signatureTag := getAttachmentTag(repository, imageDigest, "sig")
+
+	// signatures of other keys are kept, as cosign does
+	signatureImage, err := registryClient.pullImage(ctx, signatureTag)
+	switch {
+	case isNotFoundError(err):
+		signatureImage = newAttachmentImage()
+	case err != nil:
+		return "", errors.Wrap(err, "Failed to pull signature image")
+	default:
+		signed, err := hasSignature(signatureImage, signer.Public(), payload)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to read signature image")
+		}
+
+		if signed {
+			return signatureTag, nil
+		}
+	}
+
+	signature, err := signPayload(signer, payload)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to sign image")
+	}
+
+	signatureImage, err = mutate.Append(signatureImage, mutate.Addendum{
+		Layer: static.NewLayer(payload, cosignSimpleSigningMediaType),
+		Annotations: map[string]string{
+			cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signature),
+		},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to add signature")
+	}
+
+	if err := registryClient.pushImage(ctx, signatureImage, signatureTag); err != nil {
+		return "", errors.Wrap(err, "Failed to push signature image")
+	}
+
+	return signatureTag, nil
+}
+
+// hasSignature returns whether a signature image holds a signature of the payload by the key
+func hasSignature(signatureImage ociv1.Image, publicKey crypto.PublicKey, payload []byte) (bool, error) {
+	manifest, err := signatureImage.Manifest()
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to read manifest")
+	}
+
+	for _, layerDescriptor := range manifest.Layers {
+		signature, err := base64.StdEncoding.DecodeString(layerDescriptor.Annotations[cosignSignatureAnnotation])
+		if err != nil || len(signature) == 0 {
+			continue
+		}
+
+		layer, err := signatureImage.LayerByDigest(layerDescriptor.Digest)
+		if err != nil {
+			return false, errors.Wrap(err, "Failed to get signature layer")
+		}
+
+		layerReader, err := layer.Uncompressed()
+		if err != nil {
+			return false, errors.Wrap(err, "Failed to read signature layer")
+		}
+
+		layerPayload, err := io.ReadAll(layerReader)
+		layerReader.Close() // nolint: errcheck
+		if err != nil {
+			return false, errors.Wrap(err, "Failed to read signature payload")
+		}
+
+		if bytes.Equal(layerPayload, payload) && verifyPayloadSignature(publicKey, payload, signature) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// newAttachmentImage returns an empty image to hold SBOMs and signatures, in the format cosign uses
+func newAttachmentImage() ociv1.Image {
+	return mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), types.OCIConfigJSON)
+}
+
+// getAttachmentTag returns the tag cosign stores an image's attachments under, e.g. "sha256-<hex>.sig"
+func getAttachmentTag(repository name.Repository, imageDigest string, suffix string) string {
+	return repository.Tag(strings.Replace(imageDigest, ":", "-", 1) + "." + suffix).String()
+}
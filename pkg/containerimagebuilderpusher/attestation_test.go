@@ -0,0 +1,438 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerimagebuilderpusher
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+const dpkgStatus = `Package: libc6
+Status: install ok installed
+Version: 2.36-9
+Description: GNU C Library
+ continuation line
+
+Package: removed-package
+Status: deinstall ok config-files
+Version: 1.0
+`
+
+type AttestationTestSuite struct {
+	suite.Suite
+	logger               logger.Logger
+	server               *httptest.Server
+	registryURL          string
+	builderConfiguration *ContainerBuilderConfiguration
+	ctx                  context.Context
+}
+
+func (suite *AttestationTestSuite) SetupTest() {
+	var err error
+
+	suite.ctx = context.Background()
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.server = httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	suite.registryURL = strings.TrimPrefix(suite.server.URL, "http://")
+	suite.builderConfiguration = &ContainerBuilderConfiguration{
+		InsecurePullRegistry: true,
+		InsecurePushRegistry: true,
+	}
+}
+
+func (suite *AttestationTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *AttestationTestSuite) TestAttestContainerImage() {
+	imageName := suite.pushImage("processor-my-function:latest", map[string]string{
+		"etc/os-release":      "ID=debian\nVERSION_ID=\"12\"\n",
+		"var/lib/dpkg/status": dpkgStatus,
+		"usr/lib/python3/site-packages/Requests_OAuthlib-1.3.1.dist-info/METADATA": "Metadata-Version: 2.1\n" +
+			"Name: requests_oauthlib\nVersion: 1.3.1\n",
+	})
+
+	manifestsDir := suite.T().TempDir()
+	suite.writeFile(filepath.Join(manifestsDir, "requirements.txt"),
+		"# comment\nnumpy==1.26.0\npandas[performance]>=2.0 ; python_version > '3.8'\n-r other.txt\n")
+	suite.writeFile(filepath.Join(manifestsDir, "node", "package.json"),
+		`{"dependencies": {"express": "4.18.2", "@scope/lib": "^1.0.0"}}`)
+	suite.writeFile(filepath.Join(manifestsDir, "node", "node_modules", "express", "package.json"),
+		`{"dependencies": {"ignored": "1.0.0"}}`)
+	suite.writeFile(filepath.Join(manifestsDir, "go.mod"),
+		"module handler\n\nrequire github.com/nuclio/errors v0.0.1\n\nrequire (\n"+
+			"\tgithub.com/nuclio/logger v0.0.1 // indirect\n)\n")
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+	encodedSigningKey, err := x509.MarshalECPrivateKey(signingKey)
+	suite.Require().NoError(err)
+	signingKeyPath := filepath.Join(suite.T().TempDir(), "signing.key")
+	suite.writeFile(signingKeyPath,
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: encodedSigningKey})))
+
+	attestationOptions := &AttestationOptions{
+		Image:          imageName,
+		SBOMFormat:     functionconfig.SBOMFormatSPDX,
+		ManifestsDir:   manifestsDir,
+		SigningKeyPath: signingKeyPath,
+	}
+
+	imageAttestation, err := AttestContainerImage(suite.ctx, suite.logger, suite.builderConfiguration, attestationOptions)
+	suite.Require().NoError(err)
+
+	imageDigest, err := suite.pullImage(imageName).Digest()
+	suite.Require().NoError(err)
+
+	attachmentTagPrefix := suite.registryURL + "/processor-my-function:" +
+		strings.Replace(imageDigest.String(), ":", "-", 1)
+	suite.Require().Equal(&functionconfig.ImageAttestation{
+		Image:          imageName,
+		Digest:         imageDigest.String(),
+		SBOMFormat:     functionconfig.SBOMFormatSPDX,
+		SBOMImage:      attachmentTagPrefix + ".sbom",
+		SignatureImage: attachmentTagPrefix + ".sig",
+	}, imageAttestation)
+
+	// the sbom holds the image's packages and those of its manifests
+	var spdxDocument struct {
+		SPDXVersion string `json:"spdxVersion"`
+		Packages    []struct {
+			ExternalRefs []struct {
+				ReferenceLocator string `json:"referenceLocator"`
+			} `json:"externalRefs"`
+		} `json:"packages"`
+	}
+
+	sbomMediaType, encodedSBOM := suite.readSingleLayer(imageAttestation.SBOMImage)
+	suite.Require().Equal(spdxMediaType, sbomMediaType)
+	suite.Require().NoError(json.Unmarshal(encodedSBOM, &spdxDocument))
+	suite.Require().Equal("SPDX-2.3", spdxDocument.SPDXVersion)
+
+	var packageURLs []string
+	for _, spdxPackage := range spdxDocument.Packages {
+		for _, externalRef := range spdxPackage.ExternalRefs {
+			packageURLs = append(packageURLs, externalRef.ReferenceLocator)
+		}
+	}
+
+	suite.Require().Equal([]string{
+		"pkg:deb/debian/libc6@2.36-9",
+		"pkg:golang/github.com/nuclio/errors@v0.0.1",
+		"pkg:golang/github.com/nuclio/logger@v0.0.1",
+		"pkg:npm/%40scope/lib",
+		"pkg:npm/express@4.18.2",
+		"pkg:pypi/numpy@1.26.0",
+		"pkg:pypi/pandas",
+		"pkg:pypi/requests-oauthlib@1.3.1",
+	}, packageURLs)
+
+	// the signature verifies with the public key
+	payloadMediaType, payload := suite.readSingleLayer(imageAttestation.SignatureImage)
+	suite.Require().Equal(cosignSimpleSigningMediaType, payloadMediaType)
+	suite.Require().Contains(string(payload), imageDigest.String())
+
+	signatureImage := suite.pullImage(imageAttestation.SignatureImage)
+	manifest, err := signatureImage.Manifest()
+	suite.Require().NoError(err)
+	signature, err := base64.StdEncoding.DecodeString(manifest.Layers[0].Annotations[cosignSignatureAnnotation])
+	suite.Require().NoError(err)
+	suite.Require().True(verifyPayloadSignature(&signingKey.PublicKey, payload, signature))
+
+	// attesting again doesn't add another signature of the same key
+	_, err = AttestContainerImage(suite.ctx, suite.logger, suite.builderConfiguration, attestationOptions)
+	suite.Require().NoError(err)
+
+	manifest, err = suite.pullImage(imageAttestation.SignatureImage).Manifest()
+	suite.Require().NoError(err)
+	suite.Require().Len(manifest.Layers, 1)
+}
+
+func (suite *AttestationTestSuite) TestCycloneDXSBOM() {
+	imageName := suite.pushImage("processor-my-function:latest", map[string]string{
+		"lib/apk/db/installed": "P:musl\nV:1.2.4-r2\n\nP:busybox\nV:1.36.1-r5\n",
+		"etc/os-release":       "ID=alpine\n",
+	})
+
+	imageAttestation, err := AttestContainerImage(suite.ctx,
+		suite.logger,
+		suite.builderConfiguration,
+		&AttestationOptions{
+			Image:      imageName,
+			SBOMFormat: functionconfig.SBOMFormatCycloneDX,
+		})
+	suite.Require().NoError(err)
+	suite.Require().Empty(imageAttestation.SignatureImage)
+
+	var cycloneDXDocument struct {
+		BOMFormat  string `json:"bomFormat"`
+		Components []struct {
+			PURL string `json:"purl"`
+		} `json:"components"`
+	}
+
+	sbomMediaType, encodedSBOM := suite.readSingleLayer(imageAttestation.SBOMImage)
+	suite.Require().Equal(cycloneDXMediaType, sbomMediaType)
+	suite.Require().NoError(json.Unmarshal(encodedSBOM, &cycloneDXDocument))
+	suite.Require().Equal("CycloneDX", cycloneDXDocument.BOMFormat)
+	suite.Require().Len(cycloneDXDocument.Components, 2)
+	suite.Require().Equal("pkg:apk/alpine/busybox@1.36.1-r5", cycloneDXDocument.Components[0].PURL)
+	suite.Require().Equal("pkg:apk/alpine/musl@1.2.4-r2", cycloneDXDocument.Components[1].PURL)
+}
+
+func (suite *AttestationTestSuite) TestAttestMultiPlatformImage() {
+	functionBuild := functionconfig.Build{
+		Platforms: []string{"linux/arm64", "linux/s390x"},
+	}
+
+	// push the platform images and their index the way multi-platform builds do, none of them for the local platform
+	var platformImages []string
+	for _, platform := range functionBuild.Platforms {
+		platformImage := "processor-my-function:latest-" + strings.ReplaceAll(platform, "/", "-")
+		suite.pushPlatformImage(platformImage, platform, map[string]string{
+			"lib/apk/db/installed": "P:musl-" + path.Base(platform) + "\nV:1.2.4-r2\n",
+			"etc/os-release":       "ID=alpine\n",
+		})
+
+		platformImages = append(platformImages, platformImage)
+	}
+
+	registryClient, err := newRegistryClient(suite.logger, suite.builderConfiguration)
+	suite.Require().NoError(err)
+	err = registryClient.pushPlatformImageIndex(suite.ctx,
+		"processor-my-function:latest",
+		suite.registryURL,
+		platformImages)
+	suite.Require().NoError(err)
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+	encodedSigningKey, err := x509.MarshalECPrivateKey(signingKey)
+	suite.Require().NoError(err)
+	signingKeyPath := filepath.Join(suite.T().TempDir(), "signing.key")
+	suite.writeFile(signingKeyPath,
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: encodedSigningKey})))
+
+	imageName := suite.registryURL + "/processor-my-function:latest"
+	imageAttestation, err := AttestContainerImage(suite.ctx,
+		suite.logger,
+		suite.builderConfiguration,
+		&AttestationOptions{
+			Image:          imageName,
+			SBOMFormat:     functionconfig.SBOMFormatCycloneDX,
+			SigningKeyPath: signingKeyPath,
+		})
+	suite.Require().NoError(err)
+
+	// the index is signed, and each of its platform images has an SBOM of its own packages and is signed too
+	indexDigest, err := registryClient.resolveImageDigest(suite.ctx, imageName)
+	suite.Require().NoError(err)
+	suite.Require().Equal(indexDigest, imageAttestation.Digest)
+	suite.Require().Empty(imageAttestation.SBOMImage)
+	suite.Require().Equal(functionconfig.SBOMFormatCycloneDX, imageAttestation.SBOMFormat)
+
+	_, payload := suite.readSingleLayer(imageAttestation.SignatureImage)
+	suite.Require().Contains(string(payload), indexDigest)
+
+	suite.Require().Len(imageAttestation.Platforms, len(functionBuild.Platforms))
+	for platformIndex, platform := range functionBuild.Platforms {
+		platformImageAttestation := imageAttestation.Platforms[platformIndex]
+		suite.Require().Equal(platform, platformImageAttestation.Platform)
+
+		platformImageDigest, err := suite.pullImage(suite.registryURL + "/" + platformImages[platformIndex]).Digest()
+		suite.Require().NoError(err)
+		suite.Require().Equal(platformImageDigest.String(), platformImageAttestation.Digest)
+
+		var cycloneDXDocument struct {
+			Components []struct {
+				PURL string `json:"purl"`
+			} `json:"components"`
+		}
+
+		_, encodedSBOM := suite.readSingleLayer(platformImageAttestation.SBOMImage)
+		suite.Require().NoError(json.Unmarshal(encodedSBOM, &cycloneDXDocument))
+		suite.Require().Len(cycloneDXDocument.Components, 1)
+		suite.Require().Equal("pkg:apk/alpine/musl-"+path.Base(platform)+"@1.2.4-r2",
+			cycloneDXDocument.Components[0].PURL)
+
+		_, payload := suite.readSingleLayer(platformImageAttestation.SignatureImage)
+		suite.Require().Contains(string(payload), platformImageAttestation.Digest)
+	}
+}
+
+func (suite *AttestationTestSuite) TestLoadEncryptedSigningKey() {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+	encodedSigningKey, err := x509.MarshalPKCS8PrivateKey(signingKey)
+	suite.Require().NoError(err)
+
+	// encrypt the key the way cosign's generate-key-pair does, with cheaper scrypt parameters
+	var encryptedKey encryptedPrivateKey
+	encryptedKey.KDF.Name = "scrypt"
+	encryptedKey.KDF.Params.N, encryptedKey.KDF.Params.R, encryptedKey.KDF.Params.P = 1024, 8, 1
+	encryptedKey.KDF.Salt = []byte("some-salt")
+	encryptedKey.Cipher.Name = "nacl/secretbox"
+	encryptedKey.Cipher.Nonce = bytes.Repeat([]byte{1}, 24)
+
+	derivedKey, err := scrypt.Key([]byte("my-password"), encryptedKey.KDF.Salt, 1024, 8, 1, 32)
+	suite.Require().NoError(err)
+
+	var nonce [24]byte
+	var secretKey [32]byte
+	copy(nonce[:], encryptedKey.Cipher.Nonce)
+	copy(secretKey[:], derivedKey)
+	encryptedKey.Ciphertext = secretbox.Seal(nil, encodedSigningKey, &nonce, &secretKey)
+
+	encodedEncryptedKey, err := json.Marshal(encryptedKey)
+	suite.Require().NoError(err)
+
+	signingKeyPath := filepath.Join(suite.T().TempDir(), "cosign.key")
+	suite.writeFile(signingKeyPath, string(pem.EncodeToMemory(&pem.Block{
+		Type:  encryptedSigstorePrivateKeyPEM,
+		Bytes: encodedEncryptedKey,
+	})))
+
+	suite.T().Setenv(cosignPasswordEnvironmentVar, "wrong-password")
+	_, err = loadSigningKey(signingKeyPath)
+	suite.Require().Error(err)
+
+	suite.T().Setenv(cosignPasswordEnvironmentVar, "my-password")
+	signer, err := loadSigningKey(signingKeyPath)
+	suite.Require().NoError(err)
+	suite.Require().True(signingKey.PublicKey.Equal(signer.Public()))
+}
+
+func (suite *AttestationTestSuite) pushImage(imageName string, files map[string]string) string {
+	return suite.pushPlatformImage(imageName, "", files)
+}
+
+// pushPlatformImage pushes an image whose configuration names the platform, if one is given
+func (suite *AttestationTestSuite) pushPlatformImage(imageName string, platform string, files map[string]string) string {
+	var layerBuffer bytes.Buffer
+	tarWriter := tar.NewWriter(&layerBuffer)
+
+	for filePath, contents := range files {
+		suite.Require().NoError(tarWriter.WriteHeader(&tar.Header{
+			Name:     filePath,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+		}))
+		_, err := tarWriter.Write([]byte(contents))
+		suite.Require().NoError(err)
+	}
+	suite.Require().NoError(tarWriter.Close())
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(layerBuffer.Bytes())), nil
+	})
+	suite.Require().NoError(err)
+
+	image, err := mutate.AppendLayers(empty.Image, layer)
+	suite.Require().NoError(err)
+
+	if platform != "" {
+		imagePlatform, err := ociv1.ParsePlatform(platform)
+		suite.Require().NoError(err)
+
+		configFile, err := image.ConfigFile()
+		suite.Require().NoError(err)
+
+		configFile.OS, configFile.Architecture = imagePlatform.OS, imagePlatform.Architecture
+		image, err = mutate.ConfigFile(image, configFile)
+		suite.Require().NoError(err)
+	}
+
+	taggedImage := suite.registryURL + "/" + imageName
+	reference, err := name.ParseReference(taggedImage, name.Insecure)
+	suite.Require().NoError(err)
+	suite.Require().NoError(remote.Write(reference, image))
+
+	return taggedImage
+}
+
+func (suite *AttestationTestSuite) pullImage(imageName string) ociv1.Image {
+	reference, err := name.ParseReference(imageName, name.Insecure)
+	suite.Require().NoError(err)
+
+	image, err := remote.Image(reference)
+	suite.Require().NoError(err)
+
+	return image
+}
+
+func (suite *AttestationTestSuite) readSingleLayer(imageName string) (string, []byte) {
+	layers, err := suite.pullImage(imageName).Layers()
+	suite.Require().NoError(err)
+	suite.Require().Len(layers, 1)
+
+	mediaType, err := layers[0].MediaType()
+	suite.Require().NoError(err)
+
+	layerReader, err := layers[0].Uncompressed()
+	suite.Require().NoError(err)
+	defer layerReader.Close() // nolint: errcheck
+
+	contents, err := io.ReadAll(layerReader)
+	suite.Require().NoError(err)
+
+	return string(mediaType), contents
+}
+
+func (suite *AttestationTestSuite) writeFile(filePath string, contents string) {
+	suite.Require().NoError(os.MkdirAll(filepath.Dir(filePath), 0755))
+	suite.Require().NoError(os.WriteFile(filePath, []byte(contents), 0644))
+}
+
+func TestAttestationTestSuite(t *testing.T) {
+	suite.Run(t, new(AttestationTestSuite))
+}
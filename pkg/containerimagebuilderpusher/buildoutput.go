@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerimagebuilderpusher
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/nuclio/nuclio/pkg/common"
+
+	"github.com/nuclio/logger"
+)
+
+// logBuildOutput logs a line of build output, so that clients following the deployment see the build as it happens
+func logBuildOutput(ctx context.Context, buildLogger logger.Logger, line string) {
+	line = strings.TrimRight(common.RemoveANSIColorsFromString(line), "\r")
+	if line == "" {
+		return
+	}
+
+	buildLogger.DebugWithCtx(ctx, "Build output", common.BuildOutputLogKey, line)
+}
+
+// buildOutputWriter logs the output of a build command line by line. the command's stdout and stderr may be written
+// to it concurrently
+type buildOutputWriter struct {
+	ctx         context.Context
+	buildLogger logger.Logger
+	lock        sync.Mutex
+	partialLine bytes.Buffer
+}
+
+func newBuildOutputWriter(ctx context.Context, buildLogger logger.Logger) *buildOutputWriter {
+	return &buildOutputWriter{
+		ctx:         ctx,
+		buildLogger: buildLogger,
+	}
+}
+
+func (bow *buildOutputWriter) Write(p []byte) (int, error) {
+	bow.lock.Lock()
+	defer bow.lock.Unlock()
+
+	bow.partialLine.Write(p)
+
+	for {
+		line, err := bow.partialLine.ReadString('\n')
+		if err != nil {
+
+			// keep the incomplete line until the rest of it is written
+			bow.partialLine.Reset()
+			bow.partialLine.WriteString(line)
+			break
+		}
+
+		logBuildOutput(bow.ctx, bow.buildLogger, strings.TrimSuffix(line, "\n"))
+	}
+
+	return len(p), nil
+}
+
+// Flush logs the last line, if the output didn't end with a newline
+func (bow *buildOutputWriter) Flush() {
+	bow.lock.Lock()
+	defer bow.lock.Unlock()
+
+	logBuildOutput(bow.ctx, bow.buildLogger, bow.partialLine.String())
+	bow.partialLine.Reset()
+}
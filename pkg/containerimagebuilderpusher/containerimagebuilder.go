@@ -48,4 +48,15 @@ type BuilderPusher interface {
 
 	// GetDefaultRegistryCredentialsSecretName returns secret with credentials to push/pull from docker registry
 	GetDefaultRegistryCredentialsSecretName() string
+
+	// ResolveImageDigest returns the digest of an image used by builds, or an empty string if it can't be resolved
+	ResolveImageDigest(ctx context.Context, image string) (string, error)
+
+	// ReuseCachedImage makes the image of a previous build with the same fingerprint available as the build's
+	// image, instead of building it. returns whether such an image was found
+	ReuseCachedImage(ctx context.Context, buildOptions *BuildOptions, fingerprint string) (bool, error)
+
+	// PushImageIndex pushes an index of images built for different platforms, so that the image can be pulled
+	// on any of them
+	PushImageIndex(ctx context.Context, image string, registryURL string, platformImages []string) error
 }
@@ -21,7 +21,9 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/dockerclient"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 
@@ -38,6 +40,7 @@ type Docker struct {
 	dockerClient         dockerclient.Client
 	logger               logger.Logger
 	builderConfiguration *ContainerBuilderConfiguration
+	registryClient       *registryClient
 }
 
 func NewDocker(logger logger.Logger, builderConfiguration *ContainerBuilderConfiguration) (*Docker, error) {
@@ -47,10 +50,16 @@ func NewDocker(logger logger.Logger, builderConfiguration *ContainerBuilderConfi
 		return nil, errors.Wrap(err, "Failed to create docker client")
 	}
 
+	registryClient, err := newRegistryClient(logger, builderConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create registry client")
+	}
+
 	dockerBuilder := &Docker{
 		dockerClient:         dockerClient,
 		logger:               logger,
 		builderConfiguration: builderConfiguration,
+		registryClient:       registryClient,
 	}
 
 	return dockerBuilder, nil
@@ -70,7 +79,7 @@ func (d *Docker) BuildAndPushContainerImage(ctx context.Context, buildOptions *B
 		return errors.Wrap(err, "Failed to build docker image")
 	}
 
-	if err := d.pushContainerImage(ctx, buildOptions.Image, buildOptions.RegistryURL); err != nil {
+	if err := d.pushContainerImage(ctx, buildOptions); err != nil {
 		return errors.Wrap(err, "Failed to push docker image into registry")
 	}
 
@@ -100,17 +109,7 @@ func (d *Docker) GetRegistryKind() string {
 }
 
 func (d *Docker) TransformOnbuildArtifactPaths(onbuildArtifacts []runtime.Artifact) (map[string]string, error) {
-
-	// maps between a _relative_ path in staging to the path in the image
-	relativeOnbuildArtifactPaths := map[string]string{}
-	for _, onbuildArtifact := range onbuildArtifacts {
-		for localArtifactPath, imageArtifactPath := range onbuildArtifact.Paths {
-			relativeArtifactPathInStaging := path.Join(artifactDirNameInStaging, path.Base(localArtifactPath))
-			relativeOnbuildArtifactPaths[relativeArtifactPathInStaging] = imageArtifactPath
-		}
-	}
-
-	return relativeOnbuildArtifactPaths, nil
+	return transformOnbuildArtifactPathsToStaging(onbuildArtifacts), nil
 }
 
 func (d *Docker) GetBaseImageRegistry(registry string) string {
@@ -121,9 +120,98 @@ func (d *Docker) GetOnbuildImageRegistry(registry string) string {
 	return d.builderConfiguration.DefaultOnbuildRegistryURL
 }
 
+func (d *Docker) ResolveImageDigest(ctx context.Context, image string) (string, error) {
+
+	// docker builds from the local image, if there is one
+	localImage, err := d.dockerClient.GetImage(image)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get local image")
+	}
+
+	if localImage != nil {
+
+		// prefer the digest of the image in its registry, which doesn't change when it's pulled again
+		for _, repoDigest := range localImage.RepoDigests {
+			if _, digest, found := strings.Cut(repoDigest, "@"); found {
+				return digest, nil
+			}
+		}
+
+		return localImage.ID, nil
+	}
+
+	digest, err := d.registryClient.resolveImageDigest(ctx, image)
+	if err != nil {
+		d.logger.DebugWithCtx(ctx, "Failed to resolve image digest", "image", image, "err", err.Error())
+		return "", nil
+	}
+
+	return digest, nil
+}
+
+func (d *Docker) PushImageIndex(ctx context.Context,
+	image string,
+	registryURL string,
+	platformImages []string) error {
+	return d.registryClient.pushPlatformImageIndex(ctx, image, registryURL, platformImages)
+}
+
+func (d *Docker) ReuseCachedImage(ctx context.Context, buildOptions *BuildOptions, fingerprint string) (bool, error) {
+	localImage, err := d.dockerClient.GetImage(buildOptions.Image)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to get local image")
+	}
+
+	// an image in the local daemon can be pushed and saved as if it was just built
+	if localImage != nil && localImage.Config != nil && imageHasFingerprint(localImage.Config.Labels, fingerprint) {
+		d.logger.InfoWithCtx(ctx, "Found cached image in docker daemon", "image", buildOptions.Image)
+
+		if err := d.pushContainerImage(ctx, buildOptions); err != nil {
+			return false, errors.Wrap(err, "Failed to push docker image into registry")
+		}
+
+		if err := d.saveContainerImage(ctx, buildOptions); err != nil {
+			return false, errors.Wrap(err, "Failed to save docker image")
+		}
+
+		return true, nil
+	}
+
+	// an image in the registry is enough, unless it should also be saved locally
+	if buildOptions.RegistryURL == "" || buildOptions.OutputImageFile != "" {
+		return false, nil
+	}
+
+	labels, err := d.registryClient.getImageLabels(ctx,
+		common.CompileImageName(buildOptions.RegistryURL, buildOptions.Image))
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to get image labels from registry")
+	}
+
+	if !imageHasFingerprint(labels, fingerprint) {
+		return false, nil
+	}
+
+	d.logger.InfoWithCtx(ctx, "Found cached image in registry", "image", buildOptions.Image)
+
+	return true, nil
+}
+
 func (d *Docker) buildContainerImage(ctx context.Context, buildOptions *BuildOptions) error {
 
-	d.logger.InfoWithCtx(ctx, "Building docker image", "image", buildOptions.Image)
+	d.logger.InfoWithCtx(ctx,
+		"Building docker image",
+		"image", buildOptions.Image,
+		"platform", buildOptions.Platform)
+
+	outputWriter := newBuildOutputWriter(ctx, buildOptions.BuildLogger)
+	defer outputWriter.Flush()
+
+	// the build secrets are staged in the context directory, and mounted by the RUN directives
+	secrets := map[string]string{}
+	for _, buildSecret := range buildOptions.DockerfileInfo.BuildSecrets {
+		secrets[buildSecret.FileName] = path.Join(buildOptions.ContextDir, buildSecret.FileName)
+	}
 
 	return d.dockerClient.Build(&dockerclient.BuildOptions{
 		ContextDir:     buildOptions.ContextDir,
@@ -133,21 +221,29 @@ func (d *Docker) buildContainerImage(ctx context.Context, buildOptions *BuildOpt
 		Pull:           buildOptions.Pull,
 		BuildArgs:      buildOptions.BuildArgs,
 		BuildFlags:     buildOptions.BuildFlags,
+		Platform:       buildOptions.Platform,
+		Secrets:        secrets,
+		OutputWriter:   outputWriter,
 	})
 
 }
 
-func (d *Docker) pushContainerImage(ctx context.Context, image string, registryURL string) error {
+func (d *Docker) pushContainerImage(ctx context.Context, buildOptions *BuildOptions) error {
 	d.logger.InfoWithCtx(ctx,
 		"Pushing docker image into registry",
-		"image", image,
-		"registry", registryURL)
+		"image", buildOptions.Image,
+		"registry", buildOptions.RegistryURL)
 
-	if registryURL != "" {
-		return d.dockerClient.PushImage(image, registryURL)
+	if buildOptions.RegistryURL == "" {
+		return nil
 	}
 
-	return nil
+	outputWriter := newBuildOutputWriter(ctx, buildOptions.BuildLogger)
+	defer outputWriter.Flush()
+
+	return d.dockerClient.PushImage(buildOptions.Image, buildOptions.RegistryURL, &dockerclient.PushOptions{
+		OutputWriter: outputWriter,
+	})
 }
 
 func (d *Docker) saveContainerImage(ctx context.Context, buildOptions *BuildOptions) error {
@@ -214,7 +310,8 @@ func (d *Docker) gatherArtifactsForSingleStageDockerfile(ctx context.Context,
 			onbuildArtifact.Image,
 			buildOptions.ContextDir,
 			onbuildArtifactPaths,
-			buildOptions.BuildArgs); err != nil {
+			buildOptions.BuildArgs,
+			buildOptions.Platform); err != nil {
 			return errors.Wrap(err, "Failed to copy objects from onbuild")
 		}
 	}
@@ -226,7 +323,8 @@ func (d *Docker) buildFromAndCopyObjectsFromContainer(ctx context.Context,
 	onbuildImage string,
 	contextDir string,
 	artifactPaths map[string]string,
-	buildArgs map[string]string) error {
+	buildArgs map[string]string,
+	platform string) error {
 
 	dockerfilePath := path.Join(contextDir, "Dockerfile.onbuild")
 
@@ -254,6 +352,7 @@ ARG NUCLIO_ARCH
 		ContextDir:     contextDir,
 		BuildArgs:      buildArgs,
 		DockerfilePath: dockerfilePath,
+		Platform:       platform,
 	}); err != nil {
 		return errors.Wrap(err, "Failed to build onbuild image")
 	}
@@ -263,3 +362,16 @@ ARG NUCLIO_ARCH
 	// now that we have an image, we can copy the artifacts from it
 	return d.dockerClient.CopyObjectsFromImage(onbuildImageName, artifactPaths, false)
 }
+
+// transformOnbuildArtifactPathsToStaging maps between a _relative_ path in staging to the path in the image
+func transformOnbuildArtifactPathsToStaging(onbuildArtifacts []runtime.Artifact) map[string]string {
+	relativeOnbuildArtifactPaths := map[string]string{}
+	for _, onbuildArtifact := range onbuildArtifacts {
+		for localArtifactPath, imageArtifactPath := range onbuildArtifact.Paths {
+			relativeArtifactPathInStaging := path.Join(artifactDirNameInStaging, path.Base(localArtifactPath))
+			relativeOnbuildArtifactPaths[relativeArtifactPathInStaging] = imageArtifactPath
+		}
+	}
+
+	return relativeOnbuildArtifactPaths
+}
@@ -0,0 +1,365 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerimagebuilderpusher
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+
+	// FunctionArchiveFormat is the format of function archives - a tarball of an OCI image layout
+	FunctionArchiveFormat = "oci-archive"
+
+	// docker archives (the output of `docker save`) list their images in this file
+	dockerArchiveManifestFileName = "manifest.json"
+)
+
+// FunctionArchive bundles a function's processor image with its configuration, so that the function can be
+// deployed where the registry it was built for can't be reached (e.g. in air-gapped sites). The archive is a
+// tarball of an OCI image layout holding the image, with the function configuration alongside it
+type FunctionArchive struct {
+	logger logger.Logger
+
+	// the directory the archive is extracted to
+	dir string
+
+	// the name of the archived image, without a registry (e.g. nuclio/processor-my-function:latest)
+	ImageName string
+
+	// the configuration to deploy the function with
+	FunctionConfig functionconfig.Config
+}
+
+// SupportsOutputImageFile returns whether the kind of image builder can save the images it builds to a file
+func SupportsOutputImageFile(builderKind string) bool {
+	return builderKind == "docker" || builderKind == "oci"
+}
+
+// WriteFunctionArchive writes an archive of the image saved by a build (either a docker archive or a tarball of an
+// OCI image layout) and the function configuration
+func WriteFunctionArchive(ctx context.Context,
+	loggerInstance logger.Logger,
+	imageFile string,
+	imageName string,
+	functionConfig *functionconfig.Config,
+	archivePath string) error {
+
+	tempDir, err := os.MkdirTemp("", "nuclio-function-archive-")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create temporary directory")
+	}
+
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	image, err := readImageFile(imageFile, filepath.Join(tempDir, "image"))
+	if err != nil {
+		return errors.Wrap(err, "Failed to read image file")
+	}
+
+	archiveDir := filepath.Join(tempDir, "archive")
+
+	layoutPath, err := layout.Write(archiveDir, empty.Index)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create image layout")
+	}
+
+	if err := layoutPath.AppendImage(image, layout.WithAnnotations(map[string]string{
+		ociRefNameAnnotation: imageName,
+	})); err != nil {
+		return errors.Wrap(err, "Failed to write image to layout")
+	}
+
+	marshalledFunctionConfig, err := yaml.Marshal(functionConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal function configuration")
+	}
+
+	if err := os.WriteFile(filepath.Join(archiveDir, common.FunctionConfigFileName),
+		marshalledFunctionConfig,
+		0644); err != nil {
+		return errors.Wrap(err, "Failed to write function configuration")
+	}
+
+	loggerInstance.InfoWithCtx(ctx, "Writing function archive", "archivePath", archivePath, "image", imageName)
+
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create function archive")
+	}
+
+	defer archiveFile.Close() // nolint: errcheck
+
+	if err := writeTar(archiveDir, archiveFile); err != nil {
+		return errors.Wrap(err, "Failed to write function archive")
+	}
+
+	return archiveFile.Close()
+}
+
+// OpenFunctionArchive extracts a function archive and reads its configuration. The archive must be closed
+// to remove the extracted files
+func OpenFunctionArchive(loggerInstance logger.Logger, archivePath string) (*FunctionArchive, error) {
+	archiveDir, err := os.MkdirTemp("", "nuclio-function-archive-")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create temporary directory")
+	}
+
+	functionArchive := &FunctionArchive{
+		logger: loggerInstance,
+		dir:    archiveDir,
+	}
+
+	if err := functionArchive.open(archivePath); err != nil {
+		functionArchive.Close() // nolint: errcheck
+		return nil, err
+	}
+
+	return functionArchive, nil
+}
+
+// PushImage pushes the archived image to a registry, returning the name it was pushed as
+func (fa *FunctionArchive) PushImage(ctx context.Context,
+	builderConfiguration *ContainerBuilderConfiguration,
+	registryURL string) (string, error) {
+	if registryURL == "" {
+		return "", errors.New("Pushing an archived image requires a registry")
+	}
+
+	image, err := fa.readImage()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to read archived image")
+	}
+
+	registryClient, err := newRegistryClient(fa.logger, builderConfiguration)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create registry client")
+	}
+
+	taggedImage := common.CompileImageName(registryURL, fa.ImageName)
+
+	fa.logger.InfoWithCtx(ctx, "Pushing archived image into registry", "image", taggedImage)
+
+	if err := registryClient.pushImage(ctx, image, taggedImage); err != nil {
+		return "", errors.Wrap(err, "Failed to push archived image")
+	}
+
+	return taggedImage, nil
+}
+
+// SaveDockerArchive writes the archived image as a docker archive, which can be loaded into a docker daemon
+func (fa *FunctionArchive) SaveDockerArchive(outPath string) error {
+	image, err := fa.readImage()
+	if err != nil {
+		return errors.Wrap(err, "Failed to read archived image")
+	}
+
+	tag, err := name.NewTag(fa.ImageName)
+	if err != nil {
+		return errors.Wrap(err, "Invalid archived image name")
+	}
+
+	return tarball.WriteToFile(outPath, tag, image)
+}
+
+// Close removes the extracted archive
+func (fa *FunctionArchive) Close() error {
+	return os.RemoveAll(fa.dir)
+}
+
+func (fa *FunctionArchive) open(archivePath string) error {
+	archiveFile, err := os.Open(archivePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to open function archive")
+	}
+
+	defer archiveFile.Close() // nolint: errcheck
+
+	if err := extractTar(archiveFile, fa.dir); err != nil {
+		return errors.Wrap(err, "Failed to extract function archive")
+	}
+
+	marshalledFunctionConfig, err := os.ReadFile(filepath.Join(fa.dir, common.FunctionConfigFileName))
+	if err != nil {
+		return errors.Wrap(err, "Failed to read function configuration from archive")
+	}
+
+	if err := yaml.Unmarshal(marshalledFunctionConfig, &fa.FunctionConfig); err != nil {
+		return errors.Wrap(err, "Failed to unmarshal function configuration from archive")
+	}
+
+	imageDescriptor, err := fa.resolveImageDescriptor()
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve archived image")
+	}
+
+	fa.ImageName = imageDescriptor.Annotations[ociRefNameAnnotation]
+	if fa.ImageName == "" {
+		return errors.New("Archived image has no name")
+	}
+
+	return nil
+}
+
+func (fa *FunctionArchive) resolveImageDescriptor() (*ociv1.Descriptor, error) {
+	imageIndex, err := layout.ImageIndexFromPath(fa.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read image layout")
+	}
+
+	indexManifest, err := imageIndex.IndexManifest()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read image layout index")
+	}
+
+	if len(indexManifest.Manifests) != 1 || !indexManifest.Manifests[0].MediaType.IsImage() {
+		return nil, errors.New("Function archive must hold exactly one image")
+	}
+
+	return &indexManifest.Manifests[0], nil
+}
+
+func (fa *FunctionArchive) readImage() (ociv1.Image, error) {
+	imageDescriptor, err := fa.resolveImageDescriptor()
+	if err != nil {
+		return nil, err
+	}
+
+	layoutPath, err := layout.FromPath(fa.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read image layout")
+	}
+
+	return layoutPath.Image(imageDescriptor.Digest)
+}
+
+// readImageFile reads the single image in a docker archive or a tarball of an OCI image layout, extracting
+// the latter to the given directory
+func readImageFile(imageFile string, extractDir string) (ociv1.Image, error) {
+	isDockerArchive, err := tarContainsFile(imageFile, dockerArchiveManifestFileName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read image file")
+	}
+
+	if isDockerArchive {
+		return tarball.ImageFromPath(imageFile, nil)
+	}
+
+	file, err := os.Open(imageFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open image file")
+	}
+
+	defer file.Close() // nolint: errcheck
+
+	if err := extractTar(file, extractDir); err != nil {
+		return nil, errors.Wrap(err, "Failed to extract image layout")
+	}
+
+	layoutPath, err := layout.FromPath(extractDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read image layout")
+	}
+
+	imageIndex, err := layoutPath.ImageIndex()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read image layout index")
+	}
+
+	indexManifest, err := imageIndex.IndexManifest()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read image layout index")
+	}
+
+	if len(indexManifest.Manifests) != 1 || !indexManifest.Manifests[0].MediaType.IsImage() {
+		return nil, errors.New("Image file must hold exactly one image")
+	}
+
+	return layoutPath.Image(indexManifest.Manifests[0].Digest)
+}
+
+func tarContainsFile(tarPath string, fileName string) (bool, error) {
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return false, err
+	}
+
+	defer file.Close() // nolint: errcheck
+
+	tarReader := tar.NewReader(file)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if path.Clean(header.Name) == fileName {
+			return true, nil
+		}
+	}
+}
+
+// extractTar extracts the files and directories of a tarball to a directory, rejecting entries that would be
+// written outside of it
+func extractTar(reader io.Reader, targetDir string) error {
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		entryPath := path.Clean(header.Name)
+		if path.IsAbs(entryPath) || entryPath == ".." || strings.HasPrefix(entryPath, "../") {
+			return errors.Errorf("Invalid path in archive: %s", header.Name)
+		}
+
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeDir {
+			return errors.Errorf("Unsupported file type in archive: %s", header.Name)
+		}
+
+		if err := writeTarEntry(header, tarReader, targetDir, filepath.Join(targetDir, filepath.FromSlash(entryPath))); err != nil {
+			return err
+		}
+	}
+}
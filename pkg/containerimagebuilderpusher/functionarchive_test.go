@@ -0,0 +1,189 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerimagebuilderpusher
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type FunctionArchiveTestSuite struct {
+	suite.Suite
+	logger      logger.Logger
+	server      *httptest.Server
+	registryURL string
+	ctx         context.Context
+	tempDir     string
+}
+
+func (suite *FunctionArchiveTestSuite) SetupTest() {
+	var err error
+
+	suite.ctx = context.Background()
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.server = httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	suite.registryURL = strings.TrimPrefix(suite.server.URL, "http://")
+	suite.tempDir = suite.T().TempDir()
+}
+
+func (suite *FunctionArchiveTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *FunctionArchiveTestSuite) TestWriteAndOpenFromDockerArchive() {
+	image, err := random.Image(1024, 2)
+	suite.Require().NoError(err)
+
+	// a docker archive, as saved by the docker builder
+	imageFile := filepath.Join(suite.tempDir, "image.tar")
+	tag, err := name.NewTag("nuclio/processor-my-function:latest")
+	suite.Require().NoError(err)
+	suite.Require().NoError(tarball.WriteToFile(imageFile, tag, image))
+
+	functionArchive := suite.writeAndOpenArchive(imageFile)
+	defer functionArchive.Close() // nolint: errcheck
+
+	suite.Require().Equal("nuclio/processor-my-function:latest", functionArchive.ImageName)
+	suite.Require().Equal("my-function", functionArchive.FunctionConfig.Meta.Name)
+	suite.Require().Equal("python:3.9", functionArchive.FunctionConfig.Spec.Runtime)
+
+	// push the image and verify it's the one that was archived
+	taggedImage, err := functionArchive.PushImage(suite.ctx, &ContainerBuilderConfiguration{
+		InsecurePushRegistry: true,
+	}, suite.registryURL)
+	suite.Require().NoError(err)
+	suite.Require().Equal(suite.registryURL+"/nuclio/processor-my-function:latest", taggedImage)
+
+	reference, err := name.ParseReference(taggedImage, name.Insecure)
+	suite.Require().NoError(err)
+
+	pushedImage, err := remote.Image(reference)
+	suite.Require().NoError(err)
+	suite.requireSameImage(image, pushedImage)
+
+	// save the image for docker to load
+	dockerArchivePath := filepath.Join(suite.tempDir, "docker.tar")
+	suite.Require().NoError(functionArchive.SaveDockerArchive(dockerArchivePath))
+
+	loadedImage, err := tarball.ImageFromPath(dockerArchivePath, &tag)
+	suite.Require().NoError(err)
+	suite.requireSameImage(image, loadedImage)
+}
+
+func (suite *FunctionArchiveTestSuite) TestWriteAndOpenFromImageLayout() {
+	image, err := random.Image(1024, 1)
+	suite.Require().NoError(err)
+
+	// a tarball of an OCI image layout, as saved by the oci builder
+	layoutDir := filepath.Join(suite.tempDir, "layout")
+	layoutPath, err := layout.Write(layoutDir, empty.Index)
+	suite.Require().NoError(err)
+	suite.Require().NoError(layoutPath.AppendImage(image))
+
+	imageFile := filepath.Join(suite.tempDir, "image.tar")
+	file, err := os.Create(imageFile)
+	suite.Require().NoError(err)
+	suite.Require().NoError(writeTar(layoutDir, file))
+	suite.Require().NoError(file.Close())
+
+	functionArchive := suite.writeAndOpenArchive(imageFile)
+	defer functionArchive.Close() // nolint: errcheck
+
+	archivedImage, err := functionArchive.readImage()
+	suite.Require().NoError(err)
+	suite.requireSameImage(image, archivedImage)
+}
+
+func (suite *FunctionArchiveTestSuite) TestOpenInvalidArchive() {
+	var archive bytes.Buffer
+	tarWriter := tar.NewWriter(&archive)
+	suite.Require().NoError(tarWriter.WriteHeader(&tar.Header{
+		Name:     "../function.yaml",
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+	}))
+	suite.Require().NoError(tarWriter.Close())
+
+	archivePath := filepath.Join(suite.tempDir, "archive.tar")
+	suite.Require().NoError(os.WriteFile(archivePath, archive.Bytes(), 0644))
+
+	_, err := OpenFunctionArchive(suite.logger, archivePath)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Invalid path in archive")
+}
+
+func (suite *FunctionArchiveTestSuite) writeAndOpenArchive(imageFile string) *FunctionArchive {
+	functionConfig := functionconfig.NewConfig()
+	functionConfig.Meta.Name = "my-function"
+	functionConfig.Spec.Runtime = "python:3.9"
+	functionConfig.Spec.Image = "nuclio/processor-my-function:latest"
+
+	archivePath := filepath.Join(suite.tempDir, "function.tar")
+	err := WriteFunctionArchive(suite.ctx,
+		suite.logger,
+		imageFile,
+		"nuclio/processor-my-function:latest",
+		functionConfig,
+		archivePath)
+	suite.Require().NoError(err)
+
+	functionArchive, err := OpenFunctionArchive(suite.logger, archivePath)
+	suite.Require().NoError(err)
+
+	return functionArchive
+}
+
+func (suite *FunctionArchiveTestSuite) requireSameImage(expected ociv1.Image, actual ociv1.Image) {
+	expectedDigest, err := expected.Digest()
+	suite.Require().NoError(err)
+
+	actualDigest, err := actual.Digest()
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(expectedDigest, actualDigest)
+}
+
+func TestFunctionArchiveTestSuite(t *testing.T) {
+	suite.Run(t, new(FunctionArchiveTestSuite))
+}
@@ -46,6 +46,7 @@ type Kaniko struct {
 	builderConfiguration *ContainerBuilderConfiguration
 	jobNameRegex         *regexp.Regexp
 	cmdRunner            cmdrunner.CmdRunner
+	registryClient       *registryClient
 }
 
 func NewKaniko(logger logger.Logger,
@@ -65,12 +66,18 @@ func NewKaniko(logger logger.Logger,
 		return nil, errors.Wrap(err, "Failed to create shell runner")
 	}
 
+	registryClient, err := newRegistryClient(logger, builderConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create registry client")
+	}
+
 	kanikoBuilder := &Kaniko{
 		logger:               logger.GetChild("kaniko"),
 		kubeClientSet:        kubeClientSet,
 		builderConfiguration: builderConfiguration,
 		jobNameRegex:         jobNameRegex,
 		cmdRunner:            shellRunner,
+		registryClient:       registryClient,
 	}
 
 	return kanikoBuilder, nil
@@ -133,6 +140,40 @@ func (k *Kaniko) BuildAndPushContainerImage(ctx context.Context,
 		buildOptions.BuildLogger)
 }
 
+func (k *Kaniko) ResolveImageDigest(ctx context.Context, image string) (string, error) {
+	digest, err := k.registryClient.resolveImageDigest(ctx, image)
+	if err != nil {
+		k.logger.DebugWithCtx(ctx, "Failed to resolve image digest", "image", image, "err", err.Error())
+		return "", nil
+	}
+
+	return digest, nil
+}
+
+func (k *Kaniko) PushImageIndex(ctx context.Context,
+	image string,
+	registryURL string,
+	platformImages []string) error {
+	return k.registryClient.pushPlatformImageIndex(ctx, image, registryURL, platformImages)
+}
+
+func (k *Kaniko) ReuseCachedImage(ctx context.Context, buildOptions *BuildOptions, fingerprint string) (bool, error) {
+	taggedImage := common.CompileImageName(buildOptions.RegistryURL, buildOptions.Image)
+
+	labels, err := k.registryClient.getImageLabels(ctx, taggedImage)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to get image labels from registry")
+	}
+
+	if !imageHasFingerprint(labels, fingerprint) {
+		return false, nil
+	}
+
+	k.logger.InfoWithCtx(ctx, "Found cached image in registry", "image", taggedImage)
+
+	return true, nil
+}
+
 func (k *Kaniko) GetOnbuildStages(onbuildArtifacts []runtime.Artifact) ([]string, error) {
 	onbuildStages := make([]string, 0, len(onbuildArtifacts))
 	stage := 0
@@ -267,6 +308,13 @@ func (k *Kaniko) compileJobSpec(ctx context.Context,
 		buildArgs = append(buildArgs, "--cache=true")
 	}
 
+	// kaniko runs the build's commands natively, so it builds for a platform on a node of its architecture
+	nodeSelector := buildOptions.NodeSelector
+	if buildOptions.Platform != "" {
+		buildArgs = append(buildArgs, fmt.Sprintf("--custom-platform=%s", buildOptions.Platform))
+		nodeSelector = k.getPlatformNodeSelector(buildOptions.NodeSelector, buildOptions.Platform)
+	}
+
 	if _, ok := buildOptions.BuildFlags["--insecure"]; !ok && k.builderConfiguration.InsecurePushRegistry {
 		buildArgs = append(buildArgs, "--insecure")
 	}
@@ -361,7 +409,7 @@ func (k *Kaniko) compileJobSpec(ctx context.Context,
 						},
 					},
 					RestartPolicy:      v1.RestartPolicyNever,
-					NodeSelector:       buildOptions.NodeSelector,
+					NodeSelector:       nodeSelector,
 					NodeName:           buildOptions.NodeName,
 					Affinity:           buildOptions.Affinity,
 					PriorityClassName:  buildOptions.PriorityClassName,
@@ -373,9 +421,28 @@ func (k *Kaniko) compileJobSpec(ctx context.Context,
 	}
 
 	k.configureSecretVolumeMount(buildOptions, kanikoJobSpec)
+	k.configureBuildSecretMounts(buildOptions, kanikoJobSpec, tmpFolderVolumeMount)
 	return kanikoJobSpec
 }
 
+// configureBuildSecretMounts mounts the build secrets from the extracted bundle to their paths in kaniko's
+// filesystem, where the RUN directives read them. kaniko doesn't snapshot mounted paths, so they aren't
+// written to the image
+func (k *Kaniko) configureBuildSecretMounts(buildOptions *BuildOptions,
+	kanikoJobSpec *batchv1.Job,
+	bundleVolumeMount v1.VolumeMount) {
+	for _, buildSecret := range buildOptions.DockerfileInfo.BuildSecrets {
+		kanikoJobSpec.Spec.Template.Spec.Containers[0].VolumeMounts =
+			append(kanikoJobSpec.Spec.Template.Spec.Containers[0].VolumeMounts, v1.VolumeMount{
+				Name:      bundleVolumeMount.Name,
+				MountPath: buildSecret.Path,
+				SubPath: strings.TrimPrefix(path.Join(buildOptions.ContextDir, buildSecret.FileName),
+					bundleVolumeMount.MountPath+"/"),
+				ReadOnly: true,
+			})
+	}
+}
+
 func (k *Kaniko) configureSecretVolumeMount(buildOptions *BuildOptions, kanikoJobSpec *batchv1.Job) {
 	if k.matchECRUrl(buildOptions.RegistryURL) {
 		k.configureECRInitContainerAndMount(buildOptions, kanikoJobSpec)
@@ -524,6 +591,25 @@ func (k *Kaniko) waitForJobCompletion(ctx context.Context,
 		return errors.Wrap(err, "Kaniko job failed to run")
 	}
 
+	// log the build's output as kaniko writes it, and let the last of it arrive before returning
+	if jobPod, err := k.getJobPod(ctx, jobName, namespace, true); err == nil {
+		followCtx, cancelFollow := context.WithCancel(ctx)
+		followDone := make(chan struct{})
+
+		go func() {
+			defer close(followDone)
+			k.followPodLogs(followCtx, jobPod, buildLogger)
+		}()
+
+		defer func() {
+			select {
+			case <-followDone:
+			case <-time.After(5 * time.Second):
+			}
+			cancelFollow()
+		}()
+	}
+
 	for time.Now().Before(timeout) {
 		runningJob, err := k.kubeClientSet.
 			BatchV1().
@@ -685,13 +771,7 @@ func (k *Kaniko) getPodLogs(ctx context.Context, jobPod *v1.Pod) (string, error)
 		"name", jobPod.Name,
 		"namespace", jobPod.Namespace)
 
-	// find job pod
-	restClientRequest := k.kubeClientSet.
-		CoreV1().
-		Pods(jobPod.Namespace).
-		GetLogs(jobPod.Name, &v1.PodLogOptions{})
-
-	restReadCloser, err := restClientRequest.Stream(ctx)
+	restReadCloser, err := k.getPodLogsStream(ctx, jobPod, false)
 	if err != nil {
 		return "", errors.Wrap(err, "Failed to get log read/closer")
 	}
@@ -708,6 +788,36 @@ func (k *Kaniko) getPodLogs(ctx context.Context, jobPod *v1.Pod) (string, error)
 	return formattedLogContents, nil
 }
 
+// followPodLogs logs the pod's logs as build output as they are written, until the pod is done or the context is
+func (k *Kaniko) followPodLogs(ctx context.Context, jobPod *v1.Pod, buildLogger logger.Logger) {
+	restReadCloser, err := k.getPodLogsStream(ctx, jobPod, true)
+	if err != nil {
+		k.logger.DebugWithCtx(ctx,
+			"Failed to follow pod logs",
+			"name", jobPod.Name,
+			"err", err.Error())
+		return
+	}
+
+	defer restReadCloser.Close() // nolint: errcheck
+
+	// kaniko may log long lines, e.g. of package installations
+	scanner := bufio.NewScanner(restReadCloser)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		logBuildOutput(ctx, buildLogger, scanner.Text())
+	}
+}
+
+func (k *Kaniko) getPodLogsStream(ctx context.Context, jobPod *v1.Pod, follow bool) (io.ReadCloser, error) {
+	return k.kubeClientSet.
+		CoreV1().
+		Pods(jobPod.Namespace).
+		GetLogs(jobPod.Name, &v1.PodLogOptions{Follow: follow}).
+		Stream(ctx)
+}
+
 // getLastPodWarningEvent returns the last k8s warning event for a given pod
 // if event found, then returns (event, true)
 // else returns nil, false
@@ -804,6 +914,22 @@ func (k *Kaniko) deleteJob(ctx context.Context, namespace string, jobName string
 	return nil
 }
 
+// getPlatformNodeSelector returns the node selector, restricted to nodes of the platform's os and architecture
+func (k *Kaniko) getPlatformNodeSelector(nodeSelector map[string]string, platform string) map[string]string {
+	platformNodeSelector := map[string]string{}
+	for key, value := range nodeSelector {
+		platformNodeSelector[key] = value
+	}
+
+	platformParts := strings.Split(platform, "/")
+	platformNodeSelector[v1.LabelOSStable] = platformParts[0]
+	if len(platformParts) > 1 {
+		platformNodeSelector[v1.LabelArchStable] = platformParts[1]
+	}
+
+	return platformNodeSelector
+}
+
 func (k *Kaniko) matchECRUrl(registryURL string) bool {
 	return strings.Contains(registryURL, ".amazonaws.com") && strings.Contains(registryURL, ".ecr.")
 }
@@ -66,3 +66,15 @@ func (n Nop) GetOnbuildImageRegistry(registry string) string {
 func (n Nop) GetDefaultRegistryCredentialsSecretName() string {
 	return ""
 }
+
+func (n Nop) ResolveImageDigest(ctx context.Context, image string) (string, error) {
+	return "", nil
+}
+
+func (n Nop) ReuseCachedImage(ctx context.Context, buildOptions *BuildOptions, fingerprint string) (bool, error) {
+	return false, nil
+}
+
+func (n Nop) PushImageIndex(ctx context.Context, image string, registryURL string, platformImages []string) error {
+	return nil
+}
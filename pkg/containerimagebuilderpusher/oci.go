@@ -0,0 +1,806 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerimagebuilderpusher
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const (
+
+	// the command every processor image runs
+	processorCommand = "processor"
+
+	// the annotation holding the image name in an OCI layout
+	ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+)
+
+// OCI assembles function images in-process, by appending a layer with the function's files to the
+// runtime's base image. It needs neither a docker daemon nor a cluster, but can't run RUN directives or
+// onbuild triggers, so runtimes that install or compile anything during the build must start from a processor image
+type OCI struct {
+	logger               logger.Logger
+	builderConfiguration *ContainerBuilderConfiguration
+	registryClient       *registryClient
+}
+
+func NewOCI(logger logger.Logger, builderConfiguration *ContainerBuilderConfiguration) (*OCI, error) {
+	registryClient, err := newRegistryClient(logger, builderConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create registry client")
+	}
+
+	ociBuilder := &OCI{
+		logger:               logger,
+		builderConfiguration: builderConfiguration,
+		registryClient:       registryClient,
+	}
+
+	return ociBuilder, nil
+}
+
+func (o *OCI) GetKind() string {
+	return "oci"
+}
+
+func (o *OCI) BuildAndPushContainerImage(ctx context.Context, buildOptions *BuildOptions, namespace string) error {
+	if buildOptions.RegistryURL == "" && buildOptions.OutputImageFile == "" {
+		return errors.New("The oci builder requires either a registry to push to or an output image file")
+	}
+
+	if buildOptions.BuildTimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(buildOptions.BuildTimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	image, err := o.buildContainerImage(ctx, buildOptions)
+	if err != nil {
+		return errors.Wrap(err, "Failed to build image")
+	}
+
+	if err := o.pushContainerImage(ctx, image, buildOptions); err != nil {
+		return errors.Wrap(err, "Failed to push image into registry")
+	}
+
+	if err := o.saveContainerImage(ctx, image, buildOptions); err != nil {
+		return errors.Wrap(err, "Failed to save image")
+	}
+
+	o.logger.InfoWithCtx(ctx,
+		"Image was successfully built and pushed into registry",
+		"image", buildOptions.Image)
+
+	return nil
+}
+
+func (o *OCI) GetOnbuildStages(onbuildArtifacts []runtime.Artifact) ([]string, error) {
+
+	// the dockerfile is only generated for reference, onbuild artifacts are copied from their images directly
+	return []string{}, nil
+}
+
+func (o *OCI) TransformOnbuildArtifactPaths(onbuildArtifacts []runtime.Artifact) (map[string]string, error) {
+	return transformOnbuildArtifactPathsToStaging(onbuildArtifacts), nil
+}
+
+func (o *OCI) GetBaseImageRegistry(registry string) string {
+	return o.builderConfiguration.DefaultBaseRegistryURL
+}
+
+func (o *OCI) GetOnbuildImageRegistry(registry string) string {
+	return o.builderConfiguration.DefaultOnbuildRegistryURL
+}
+
+func (o *OCI) GetRegistryKind() string {
+	return o.builderConfiguration.RegistryKind
+}
+
+func (o *OCI) GetDefaultRegistryCredentialsSecretName() string {
+	return o.builderConfiguration.DefaultRegistryCredentialsSecretName
+}
+
+func (o *OCI) ResolveImageDigest(ctx context.Context, image string) (string, error) {
+	digest, err := o.registryClient.resolveImageDigest(ctx, image)
+	if err != nil {
+		o.logger.DebugWithCtx(ctx, "Failed to resolve image digest", "image", image, "err", err.Error())
+		return "", nil
+	}
+
+	return digest, nil
+}
+
+func (o *OCI) PushImageIndex(ctx context.Context,
+	image string,
+	registryURL string,
+	platformImages []string) error {
+	return o.registryClient.pushPlatformImageIndex(ctx, image, registryURL, platformImages)
+}
+
+func (o *OCI) ReuseCachedImage(ctx context.Context, buildOptions *BuildOptions, fingerprint string) (bool, error) {
+	if buildOptions.RegistryURL == "" {
+		return false, nil
+	}
+
+	taggedImage := common.CompileImageName(buildOptions.RegistryURL, buildOptions.Image)
+
+	labels, err := o.registryClient.getImageLabels(ctx, taggedImage)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to get image labels from registry")
+	}
+
+	if !imageHasFingerprint(labels, fingerprint) {
+		return false, nil
+	}
+
+	o.logger.InfoWithCtx(ctx, "Found cached image in registry", "image", taggedImage)
+
+	// the cached image is pulled only when it should also be saved
+	if buildOptions.OutputImageFile != "" {
+		image, err := o.registryClient.pullImage(ctx, taggedImage)
+		if err != nil {
+			return false, errors.Wrap(err, "Failed to pull cached image")
+		}
+
+		if err := o.saveContainerImage(ctx, image, buildOptions); err != nil {
+			return false, errors.Wrap(err, "Failed to save image")
+		}
+	}
+
+	return true, nil
+}
+
+func (o *OCI) buildContainerImage(ctx context.Context, buildOptions *BuildOptions) (ociv1.Image, error) {
+	dockerfileInfo := buildOptions.DockerfileInfo
+
+	o.logger.InfoWithCtx(ctx,
+		"Building image",
+		"image", buildOptions.Image,
+		"baseImage", dockerfileInfo.BaseImage,
+		"platform", buildOptions.Platform)
+
+	baseImage, err := o.registryClient.pullPlatformImage(ctx, dockerfileInfo.BaseImage, buildOptions.Platform)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to pull base image %s", dockerfileInfo.BaseImage)
+	}
+
+	baseConfigFile, err := baseImage.ConfigFile()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read base image configuration")
+	}
+
+	directives := dockerfileInfo.FunctionDirectives
+	onbuildArtifacts := dockerfileInfo.OnbuildArtifacts
+
+	// a processor image (e.g. one previously built by the docker or kaniko builders) already holds
+	// everything the runtime adds, so only the function's own files and directives are applied on top of it
+	if o.isProcessorImage(baseConfigFile) {
+		o.logger.DebugWithCtx(ctx,
+			"Base image is a processor image, skipping runtime artifacts and directives",
+			"baseImage", dockerfileInfo.BaseImage)
+
+		onbuildArtifacts = nil
+	} else {
+		if err := o.validateDirectives(dockerfileInfo.Directives); err != nil {
+			return nil, errors.Wrap(err, "The runtime prepares its image with directives the oci builder can't apply, "+
+				"set a processor image as the function's base image (spec.build.baseImage)")
+		}
+
+		directives = mergeDirectives(dockerfileInfo.Directives, directives)
+	}
+
+	if err := o.validateDirectives(directives); err != nil {
+		return nil, errors.Wrap(err, "Failed to validate directives")
+	}
+
+	rootfsDir, err := os.MkdirTemp(buildOptions.TempDir, "oci-rootfs-")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create rootfs directory")
+	}
+
+	defer os.RemoveAll(rootfsDir) // nolint: errcheck
+
+	imageConfig := baseConfigFile.Config.DeepCopy()
+
+	if err := o.applyDirectives(directives["preCopy"], buildOptions.ContextDir, rootfsDir, imageConfig); err != nil {
+		return nil, errors.Wrap(err, "Failed to apply pre-copy directives")
+	}
+
+	for _, onbuildArtifact := range onbuildArtifacts {
+		if err := o.copyArtifactFromImage(ctx, onbuildArtifact, buildOptions.Platform, rootfsDir); err != nil {
+			return nil, errors.Wrapf(err, "Failed to copy artifact %s", onbuildArtifact.Name)
+		}
+	}
+
+	for localArtifactPath, imageArtifactPath := range dockerfileInfo.ImageArtifactPaths {
+		if err := copyToRootfs(buildOptions.ContextDir,
+			localArtifactPath,
+			imageArtifactPath,
+			rootfsDir); err != nil {
+			return nil, errors.Wrapf(err, "Failed to copy %s", localArtifactPath)
+		}
+	}
+
+	if err := o.applyDirectives(directives["postCopy"], buildOptions.ContextDir, rootfsDir, imageConfig); err != nil {
+		return nil, errors.Wrap(err, "Failed to apply post-copy directives")
+	}
+
+	// the base image's entrypoint would otherwise wrap the processor command
+	imageConfig.Entrypoint = nil
+	imageConfig.Cmd = []string{processorCommand}
+
+	if len(dockerfileInfo.Labels) > 0 && imageConfig.Labels == nil {
+		imageConfig.Labels = map[string]string{}
+	}
+
+	for key, value := range dockerfileInfo.Labels {
+		imageConfig.Labels[key] = value
+	}
+
+	layer, err := o.createLayer(rootfsDir, buildOptions.TempDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create function layer")
+	}
+
+	image, err := mutate.AppendLayers(baseImage, layer)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to append function layer")
+	}
+
+	image, err = mutate.Config(image, *imageConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to set image configuration")
+	}
+
+	return image, nil
+}
+
+func (o *OCI) pushContainerImage(ctx context.Context, image ociv1.Image, buildOptions *BuildOptions) error {
+	if buildOptions.RegistryURL == "" {
+		return nil
+	}
+
+	taggedImage := common.CompileImageName(buildOptions.RegistryURL, buildOptions.Image)
+
+	o.logger.InfoWithCtx(ctx,
+		"Pushing image into registry",
+		"image", buildOptions.Image,
+		"registry", buildOptions.RegistryURL)
+
+	return o.registryClient.pushImage(ctx, image, taggedImage)
+}
+
+// saveContainerImage writes the image as a tarball of an OCI image layout
+func (o *OCI) saveContainerImage(ctx context.Context, image ociv1.Image, buildOptions *BuildOptions) error {
+	if buildOptions.OutputImageFile == "" {
+		return nil
+	}
+
+	o.logger.InfoWithCtx(ctx, "Archiving built image", "OutputImageFile", buildOptions.OutputImageFile)
+
+	layoutDir, err := os.MkdirTemp(buildOptions.TempDir, "oci-layout-")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create image layout directory")
+	}
+
+	defer os.RemoveAll(layoutDir) // nolint: errcheck
+
+	layoutPath, err := layout.Write(layoutDir, empty.Index)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create image layout")
+	}
+
+	if err := layoutPath.AppendImage(image, layout.WithAnnotations(map[string]string{
+		ociRefNameAnnotation: buildOptions.Image,
+	})); err != nil {
+		return errors.Wrap(err, "Failed to write image to layout")
+	}
+
+	outputFile, err := os.Create(buildOptions.OutputImageFile)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create output image file")
+	}
+
+	defer outputFile.Close() // nolint: errcheck
+
+	return writeTar(layoutDir, outputFile)
+}
+
+// isProcessorImage returns whether the image runs the processor, as images built from the processor dockerfile do
+func (o *OCI) isProcessorImage(configFile *ociv1.ConfigFile) bool {
+	return len(configFile.Config.Cmd) == 1 && configFile.Config.Cmd[0] == processorCommand
+}
+
+// copyArtifactFromImage copies the artifact's paths from the image's filesystem into the rootfs
+func (o *OCI) copyArtifactFromImage(ctx context.Context,
+	artifact runtime.Artifact,
+	platform string,
+	rootfsDir string) error {
+	image, err := o.registryClient.pullPlatformImage(ctx, artifact.Image, platform)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to pull image %s", artifact.Image)
+	}
+
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return errors.Wrap(err, "Failed to read image configuration")
+	}
+
+	// onbuild triggers produce the artifacts by running a build, which requires a container runtime
+	if !artifact.ExternalImage && len(configFile.Config.OnBuild) > 0 {
+		return errors.Errorf("Image %s has onbuild triggers, which can only be run by the docker or kaniko builders",
+			artifact.Image)
+	}
+
+	o.logger.DebugWithCtx(ctx,
+		"Copying artifact from image",
+		"image", artifact.Image,
+		"paths", artifact.Paths)
+
+	filesystemReader := mutate.Extract(image)
+	defer filesystemReader.Close() // nolint: errcheck
+
+	foundSourcePaths := map[string]bool{}
+	tarReader := tar.NewReader(filesystemReader)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.Wrap(err, "Failed to read image filesystem")
+		}
+
+		entryPath := path.Join("/", header.Name)
+
+		for sourcePath, destinationPath := range artifact.Paths {
+			sourcePath = path.Join("/", sourcePath)
+
+			var targetPath string
+
+			switch {
+			case entryPath == sourcePath:
+				targetPath = resolveCopyDestination(sourcePath, destinationPath, header.Typeflag == tar.TypeDir)
+			case strings.HasPrefix(entryPath, sourcePath+"/"):
+				targetPath = path.Join(destinationPath, strings.TrimPrefix(entryPath, sourcePath))
+			default:
+				continue
+			}
+
+			foundSourcePaths[sourcePath] = true
+
+			rootfsPath, err := resolvePathWithinDir(rootfsDir, targetPath)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid destination for %s", entryPath)
+			}
+
+			if err := writeTarEntry(header, tarReader, rootfsDir, rootfsPath); err != nil {
+				return errors.Wrapf(err, "Failed to copy %s", entryPath)
+			}
+
+			break
+		}
+	}
+
+	for sourcePath := range artifact.Paths {
+		if !foundSourcePaths[path.Join("/", sourcePath)] {
+			return errors.Errorf("Failed to find %s in image %s", sourcePath, artifact.Image)
+		}
+	}
+
+	return nil
+}
+
+// validateDirectives fails on directives that can't be applied without running a container
+func (o *OCI) validateDirectives(directives map[string][]functionconfig.Directive) error {
+	for _, key := range []string{"preCopy", "postCopy"} {
+		for _, directive := range directives[key] {
+			switch strings.ToUpper(directive.Kind) {
+			case "COPY", "ENV", "LABEL", "WORKDIR", "USER", "ARG":
+			default:
+				return errors.Errorf("The oci builder can't apply %s directives (%s). Use the docker or kaniko "+
+					"builder, or build from a processor image that already includes the directive's effect",
+					directive.Kind,
+					directive.Value)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *OCI) applyDirectives(directives []functionconfig.Directive,
+	contextDir string,
+	rootfsDir string,
+	imageConfig *ociv1.Config) error {
+
+	for _, directive := range directives {
+		arguments := splitDirectiveArguments(directive.Value)
+
+		switch strings.ToUpper(directive.Kind) {
+		case "COPY":
+			if len(arguments) < 2 {
+				return errors.Errorf("COPY directive requires a source and a destination: %s", directive.Value)
+			}
+
+			for _, argument := range arguments {
+				if strings.HasPrefix(argument, "--") {
+					return errors.Errorf("COPY directive flags are not supported: %s", directive.Value)
+				}
+			}
+
+			destinationPath := arguments[len(arguments)-1]
+			for _, sourcePath := range arguments[:len(arguments)-1] {
+				if err := copyToRootfs(contextDir, sourcePath, destinationPath, rootfsDir); err != nil {
+					return errors.Wrapf(err, "Failed to copy %s", sourcePath)
+				}
+			}
+		case "ENV":
+			for key, value := range parseDirectiveKeyValues(arguments) {
+				imageConfig.Env = setEnv(imageConfig.Env, key, value)
+			}
+		case "LABEL":
+			if imageConfig.Labels == nil {
+				imageConfig.Labels = map[string]string{}
+			}
+
+			for key, value := range parseDirectiveKeyValues(arguments) {
+				imageConfig.Labels[key] = value
+			}
+		case "WORKDIR":
+			imageConfig.WorkingDir = directive.Value
+		case "USER":
+			imageConfig.User = directive.Value
+		case "ARG":
+
+			// build args are only used by the directives the oci builder doesn't run
+		}
+	}
+
+	return nil
+}
+
+// createLayer writes the rootfs into an uncompressed layer tarball
+func (o *OCI) createLayer(rootfsDir string, tempDir string) (ociv1.Layer, error) {
+	layerFile, err := os.CreateTemp(tempDir, "oci-layer-*.tar")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create layer file")
+	}
+
+	defer layerFile.Close() // nolint: errcheck
+
+	if err := writeTar(rootfsDir, layerFile); err != nil {
+		return nil, errors.Wrap(err, "Failed to write layer")
+	}
+
+	return tarball.LayerFromFile(layerFile.Name())
+}
+
+func mergeDirectives(first map[string][]functionconfig.Directive,
+	second map[string][]functionconfig.Directive) map[string][]functionconfig.Directive {
+	merged := map[string][]functionconfig.Directive{}
+
+	for _, key := range []string{"preCopy", "postCopy"} {
+		merged[key] = append(append(merged[key], first[key]...), second[key]...)
+	}
+
+	return merged
+}
+
+// resolveCopyDestination returns where a copied path lands. like the COPY directive, a directory's contents
+// are copied into the destination, and a file is copied into the destination if it ends with a slash
+func resolveCopyDestination(sourcePath string, destinationPath string, isDir bool) string {
+	if !isDir && strings.HasSuffix(destinationPath, "/") {
+		return path.Join(destinationPath, path.Base(sourcePath))
+	}
+
+	return path.Clean(destinationPath)
+}
+
+// copyToRootfs copies a file or directory in the build context to the destination path in the rootfs.
+// neither of them may lead outside of their directory
+func copyToRootfs(contextDir string, contextPath string, destinationPath string, rootfsDir string) error {
+	sourcePath, err := resolveContextPath(contextDir, contextPath)
+	if err != nil {
+		return errors.Wrap(err, "Invalid source")
+	}
+
+	sourceInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to stat source")
+	}
+
+	targetPath, err := resolvePathWithinDir(rootfsDir,
+		resolveCopyDestination(contextPath, destinationPath, sourceInfo.IsDir()))
+	if err != nil {
+		return errors.Wrap(err, "Invalid destination")
+	}
+
+	return filepath.Walk(sourcePath, func(walkedPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(sourcePath, walkedPath)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if header.Linkname, err = os.Readlink(walkedPath); err != nil {
+				return err
+			}
+		}
+
+		var content io.Reader
+		if info.Mode().IsRegular() {
+			file, err := os.Open(walkedPath)
+			if err != nil {
+				return err
+			}
+
+			defer file.Close() // nolint: errcheck
+			content = file
+		}
+
+		return writeTarEntry(header, content, rootfsDir, filepath.Join(targetPath, relativePath))
+	})
+}
+
+// resolveContextPath returns the path of a file in the build context with its symlinks followed, which
+// mustn't lead outside of the build context
+func resolveContextPath(contextDir string, name string) (string, error) {
+	resolvedContextDir, err := filepath.EvalSymlinks(contextDir)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to resolve build context")
+	}
+
+	contextPath, err := resolvePathWithinDir(resolvedContextDir, name)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedContextPath, err := filepath.EvalSymlinks(contextPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to resolve %s", name)
+	}
+
+	if !isWithinDir(resolvedContextDir, resolvedContextPath) {
+		return "", errors.Errorf("Path %s links outside of the build context", name)
+	}
+
+	return resolvedContextPath, nil
+}
+
+// writeTarEntry creates the file, directory or link a tar header describes at the target path, which
+// must be in the root directory. symlinks written by earlier entries aren't followed, so that they can't
+// redirect the entry outside of the root directory
+func writeTarEntry(header *tar.Header, content io.Reader, rootDir string, targetPath string) error {
+	mode := os.FileMode(header.Mode).Perm()
+
+	if err := verifyNoSymlinks(rootDir, targetPath, header.Typeflag == tar.TypeDir); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return err
+	}
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(targetPath, mode); err != nil {
+			return err
+		}
+
+		return os.Chmod(targetPath, mode)
+	case tar.TypeReg:
+		if err := os.RemoveAll(targetPath); err != nil {
+			return err
+		}
+
+		file, err := os.OpenFile(targetPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+		if err != nil {
+			return err
+		}
+
+		defer file.Close() // nolint: errcheck
+
+		_, err = io.Copy(file, content)
+		return err
+	case tar.TypeSymlink:
+		if err := os.RemoveAll(targetPath); err != nil {
+			return err
+		}
+
+		return os.Symlink(header.Linkname, targetPath)
+	default:
+		return errors.Errorf("Unsupported file type %c", header.Typeflag)
+	}
+}
+
+// verifyNoSymlinks fails if the existing directories between the root directory and the target path are
+// symlinks. the target itself may only be a symlink if it's replaced, rather than written into
+func verifyNoSymlinks(rootDir string, targetPath string, writesIntoTarget bool) error {
+	relativePath, err := filepath.Rel(rootDir, targetPath)
+	if err != nil || !isWithinDir(rootDir, targetPath) {
+		return errors.Errorf("Path %s is outside of %s", targetPath, rootDir)
+	}
+
+	pathComponents := strings.Split(relativePath, string(filepath.Separator))
+	currentPath := rootDir
+
+	for componentIndex, pathComponent := range pathComponents {
+		currentPath = filepath.Join(currentPath, pathComponent)
+
+		fileInfo, err := os.Lstat(currentPath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if fileInfo.Mode()&os.ModeSymlink != 0 &&
+			(componentIndex < len(pathComponents)-1 || writesIntoTarget) {
+			return errors.Errorf("Refusing to write %s through the symlink %s", targetPath, currentPath)
+		}
+	}
+
+	return nil
+}
+
+// writeTar writes the contents of a directory as a tarball, with paths relative to it and owned by root
+func writeTar(dir string, writer io.Writer) error {
+	tarWriter := tar.NewWriter(writer)
+
+	if err := filepath.Walk(dir, func(walkedPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(dir, walkedPath)
+		if err != nil || relativePath == "." {
+			return err
+		}
+
+		var linkname string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if linkname, err = os.Readlink(walkedPath); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, linkname)
+		if err != nil {
+			return err
+		}
+
+		header.Name = filepath.ToSlash(relativePath)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		header.Uid, header.Gid = 0, 0
+		header.Uname, header.Gname = "", ""
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(walkedPath)
+		if err != nil {
+			return err
+		}
+
+		defer file.Close() // nolint: errcheck
+
+		_, err = io.Copy(tarWriter, file)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	return tarWriter.Close()
+}
+
+// splitDirectiveArguments splits a directive's value by whitespace, keeping double-quoted strings whole
+func splitDirectiveArguments(value string) []string {
+	var arguments []string
+	var current strings.Builder
+	inQuotes, inArgument := false, false
+
+	for _, character := range value {
+		switch {
+		case character == '"':
+			inQuotes = !inQuotes
+			inArgument = true
+		case !inQuotes && (character == ' ' || character == '\t'):
+			if inArgument {
+				arguments = append(arguments, current.String())
+				current.Reset()
+				inArgument = false
+			}
+		default:
+			current.WriteRune(character)
+			inArgument = true
+		}
+	}
+
+	if inArgument {
+		arguments = append(arguments, current.String())
+	}
+
+	return arguments
+}
+
+// parseDirectiveKeyValues parses the arguments of ENV and LABEL directives, either "key=value ..." or "key value"
+func parseDirectiveKeyValues(arguments []string) map[string]string {
+	keyValues := map[string]string{}
+
+	if len(arguments) > 0 && !strings.Contains(arguments[0], "=") {
+		keyValues[arguments[0]] = strings.Join(arguments[1:], " ")
+		return keyValues
+	}
+
+	for _, argument := range arguments {
+		key, value, _ := strings.Cut(argument, "=")
+		keyValues[key] = value
+	}
+
+	return keyValues
+}
+
+// setEnv sets a variable in a list of "key=value" environment variables
+func setEnv(env []string, key string, value string) []string {
+	for index, variable := range env {
+		if strings.HasPrefix(variable, key+"=") {
+			env[index] = key + "=" + value
+			return env
+		}
+	}
+
+	return append(env, key+"="+value)
+}
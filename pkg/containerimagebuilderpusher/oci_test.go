@@ -0,0 +1,620 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerimagebuilderpusher
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type OCITestSuite struct {
+	suite.Suite
+	logger      logger.Logger
+	server      *httptest.Server
+	registryURL string
+	builder     *OCI
+	ctx         context.Context
+}
+
+func (suite *OCITestSuite) SetupTest() {
+	var err error
+
+	suite.ctx = context.Background()
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.server = httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	suite.registryURL = strings.TrimPrefix(suite.server.URL, "http://")
+
+	suite.builder, err = NewOCI(suite.logger, &ContainerBuilderConfiguration{
+		InsecurePullRegistry: true,
+		InsecurePushRegistry: true,
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *OCITestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *OCITestSuite) TestBuildAndPush() {
+	baseImage := suite.pushImage("base:latest", map[string]string{"etc/base": "base"}, &ociv1.Config{
+		Entrypoint: []string{"/bin/sh", "-c"},
+		Env:        []string{"PATH=/usr/bin", "MY_ENV=old"},
+	})
+	onbuildImage := suite.pushImage("onbuild:latest", map[string]string{
+		"home/nuclio/bin/processor":    "processor",
+		"home/nuclio/bin/py/wrapper":   "wrapper",
+		"home/nuclio/bin/py/lib/other": "other",
+		"home/nuclio/bin/unrelated":    "unrelated",
+	}, nil)
+
+	buildOptions := suite.createBuildOptions(baseImage, map[string]string{"handler.py": "handler"})
+	buildOptions.DockerfileInfo.OnbuildArtifacts = []runtime.Artifact{
+		{
+			Name:  "onbuild",
+			Image: onbuildImage,
+			Paths: map[string]string{
+				"/home/nuclio/bin/processor": "/usr/local/bin/processor",
+				"/home/nuclio/bin/py":        "/opt/nuclio/",
+			},
+		},
+	}
+	buildOptions.DockerfileInfo.FunctionDirectives = map[string][]functionconfig.Directive{
+		"postCopy": {
+			{Kind: "ENV", Value: "MY_ENV=new OTHER_ENV=\"with space\""},
+			{Kind: "LABEL", Value: "my-label=value"},
+		},
+	}
+
+	err := suite.builder.BuildAndPushContainerImage(suite.ctx, buildOptions, "")
+	suite.Require().NoError(err)
+
+	image := suite.pullImage("processor-my-function:latest")
+	configFile, err := image.ConfigFile()
+	suite.Require().NoError(err)
+
+	suite.Require().Empty(configFile.Config.Entrypoint)
+	suite.Require().Equal([]string{"processor"}, configFile.Config.Cmd)
+	suite.Require().Equal([]string{"PATH=/usr/bin", "MY_ENV=new", "OTHER_ENV=with space"}, configFile.Config.Env)
+	suite.Require().Equal("value", configFile.Config.Labels["my-label"])
+
+	suite.Require().Equal(map[string]string{
+		"etc/base":                "base",
+		"usr/local/bin/processor": "processor",
+		"opt/nuclio/wrapper":      "wrapper",
+		"opt/nuclio/lib/other":    "other",
+		"opt/nuclio/handler.py":   "handler",
+	}, suite.readFiles(image))
+}
+
+func (suite *OCITestSuite) TestBuildFromProcessorImage() {
+	baseImage := suite.pushImage("processor-base:latest", map[string]string{
+		"usr/local/bin/processor": "processor",
+		"opt/nuclio/handler.py":   "old handler",
+	}, &ociv1.Config{Cmd: []string{"processor"}})
+
+	buildOptions := suite.createBuildOptions(baseImage, map[string]string{"handler.py": "new handler"})
+
+	// the runtime's directives and artifacts are already in the processor image, so they're skipped
+	buildOptions.DockerfileInfo.OnbuildArtifacts = []runtime.Artifact{
+		{Name: "onbuild", Image: suite.registryURL + "/missing:latest"},
+	}
+	buildOptions.DockerfileInfo.Directives = map[string][]functionconfig.Directive{
+		"postCopy": {{Kind: "RUN", Value: "pip install nuclio-sdk"}},
+	}
+
+	err := suite.builder.BuildAndPushContainerImage(suite.ctx, buildOptions, "")
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(map[string]string{
+		"usr/local/bin/processor": "processor",
+		"opt/nuclio/handler.py":   "new handler",
+	}, suite.readFiles(suite.pullImage("processor-my-function:latest")))
+}
+
+func (suite *OCITestSuite) TestRunDirectiveNotSupported() {
+	baseImage := suite.pushImage("base:latest", map[string]string{"etc/base": "base"}, nil)
+
+	buildOptions := suite.createBuildOptions(baseImage, map[string]string{"handler.py": "handler"})
+	buildOptions.DockerfileInfo.FunctionDirectives = map[string][]functionconfig.Directive{
+		"postCopy": {{Kind: "RUN", Value: "pip install requests"}},
+	}
+
+	err := suite.builder.BuildAndPushContainerImage(suite.ctx, buildOptions, "")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "can't apply RUN directives")
+}
+
+func (suite *OCITestSuite) TestRuntimeRunDirectiveRequiresProcessorImage() {
+	baseImage := suite.pushImage("base:latest", map[string]string{"etc/base": "base"}, nil)
+
+	buildOptions := suite.createBuildOptions(baseImage, map[string]string{"handler.py": "handler"})
+	buildOptions.DockerfileInfo.Directives = map[string][]functionconfig.Directive{
+		"postCopy": {{Kind: "RUN", Value: "pip install nuclio-sdk"}},
+	}
+
+	err := suite.builder.BuildAndPushContainerImage(suite.ctx, buildOptions, "")
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.GetErrorStackString(err, 10), "set a processor image as the function's base image")
+}
+
+func (suite *OCITestSuite) TestCopyDirectiveContainment() {
+	baseImage := suite.pushImage("base:latest", map[string]string{"etc/base": "base"}, nil)
+	outsideDir := suite.T().TempDir()
+	suite.Require().NoError(os.WriteFile(filepath.Join(outsideDir, "secret"), []byte("secret"), 0644))
+
+	for _, testCase := range []struct {
+		name           string
+		value          string
+		expectedReason string
+	}{
+		{name: "sourceOutsideContext", value: "../secret /opt/", expectedReason: "outside of its directory"},
+		{name: "sourceLinksOutsideContext", value: "link/secret /opt/", expectedReason: "links outside of the build context"},
+		{name: "destinationOutsideRootfs", value: "handler ../../escaped", expectedReason: "outside of its directory"},
+	} {
+		suite.Run(testCase.name, func() {
+			buildOptions := suite.createBuildOptions(baseImage, map[string]string{"handler.py": "handler"})
+			suite.Require().NoError(os.Symlink(outsideDir, filepath.Join(buildOptions.ContextDir, "link")))
+
+			buildOptions.DockerfileInfo.FunctionDirectives = map[string][]functionconfig.Directive{
+				"postCopy": {{Kind: "COPY", Value: testCase.value}},
+			}
+
+			err := suite.builder.BuildAndPushContainerImage(suite.ctx, buildOptions, "")
+			suite.Require().Error(err)
+			suite.Require().Contains(errors.RootCause(err).Error(), testCase.expectedReason)
+		})
+	}
+}
+
+func (suite *OCITestSuite) TestUnpackTarThroughSymlink() {
+	outsideDir := suite.T().TempDir()
+	targetDir := suite.T().TempDir()
+
+	// a symlink that stays in the directory is fine, but writing through it isn't
+	var tarBuffer bytes.Buffer
+	tarWriter := tar.NewWriter(&tarBuffer)
+	suite.Require().NoError(tarWriter.WriteHeader(&tar.Header{
+		Name:     "lib",
+		Typeflag: tar.TypeSymlink,
+		Linkname: ".",
+	}))
+	suite.Require().NoError(tarWriter.WriteHeader(&tar.Header{
+		Name:     "lib/file",
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+	}))
+	suite.Require().NoError(tarWriter.Close())
+
+	err := unpackTar(&tarBuffer, targetDir)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "through the symlink")
+
+	// symlinks created before extracting aren't followed either
+	suite.Require().NoError(os.Symlink(outsideDir, filepath.Join(targetDir, "outside")))
+	err = writeTarEntry(&tar.Header{Name: "outside/file", Typeflag: tar.TypeReg, Mode: 0644},
+		strings.NewReader("escaped"),
+		targetDir,
+		filepath.Join(targetDir, "outside", "file"))
+	suite.Require().Error(err)
+	suite.Require().NoFileExists(filepath.Join(outsideDir, "file"))
+}
+
+func (suite *OCITestSuite) TestSaveImage() {
+	baseImage := suite.pushImage("base:latest", map[string]string{"etc/base": "base"}, nil)
+
+	buildOptions := suite.createBuildOptions(baseImage, map[string]string{"handler.py": "handler"})
+	buildOptions.RegistryURL = ""
+	buildOptions.OutputImageFile = filepath.Join(suite.T().TempDir(), "image.tar")
+
+	err := suite.builder.BuildAndPushContainerImage(suite.ctx, buildOptions, "")
+	suite.Require().NoError(err)
+
+	// extract the layout tarball and read the image from it
+	layoutDir := suite.T().TempDir()
+	outputImageFile, err := os.Open(buildOptions.OutputImageFile)
+	suite.Require().NoError(err)
+	defer outputImageFile.Close() // nolint: errcheck
+
+	tarReader := tar.NewReader(outputImageFile)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		suite.Require().NoError(err)
+		suite.Require().NoError(writeTarEntry(header, tarReader, layoutDir, filepath.Join(layoutDir, header.Name)))
+	}
+
+	imageIndex, err := layout.ImageIndexFromPath(layoutDir)
+	suite.Require().NoError(err)
+
+	indexManifest, err := imageIndex.IndexManifest()
+	suite.Require().NoError(err)
+	suite.Require().Len(indexManifest.Manifests, 1)
+	suite.Require().Equal("processor-my-function:latest",
+		indexManifest.Manifests[0].Annotations[ociRefNameAnnotation])
+
+	image, err := imageIndex.Image(indexManifest.Manifests[0].Digest)
+	suite.Require().NoError(err)
+	suite.Require().Equal("handler", suite.readFiles(image)["opt/nuclio/handler.py"])
+}
+
+func (suite *OCITestSuite) TestReuseCachedImage() {
+	baseImage := suite.pushImage("base:latest", map[string]string{"etc/base": "base"}, nil)
+
+	buildOptions := suite.createBuildOptions(baseImage, map[string]string{"handler.py": "handler"})
+	buildOptions.DockerfileInfo.Labels = map[string]string{BuildFingerprintLabel: "some-fingerprint"}
+
+	// nothing was built yet
+	reused, err := suite.builder.ReuseCachedImage(suite.ctx, buildOptions, "some-fingerprint")
+	suite.Require().NoError(err)
+	suite.Require().False(reused)
+
+	err = suite.builder.BuildAndPushContainerImage(suite.ctx, buildOptions, "")
+	suite.Require().NoError(err)
+
+	configFile, err := suite.pullImage("processor-my-function:latest").ConfigFile()
+	suite.Require().NoError(err)
+	suite.Require().Equal("some-fingerprint", configFile.Config.Labels[BuildFingerprintLabel])
+
+	reused, err = suite.builder.ReuseCachedImage(suite.ctx, buildOptions, "some-fingerprint")
+	suite.Require().NoError(err)
+	suite.Require().True(reused)
+
+	// a different build can't reuse the image
+	reused, err = suite.builder.ReuseCachedImage(suite.ctx, buildOptions, "other-fingerprint")
+	suite.Require().NoError(err)
+	suite.Require().False(reused)
+}
+
+func (suite *OCITestSuite) TestResolveImageDigest() {
+	baseImage := suite.pushImage("base:latest", map[string]string{"etc/base": "base"}, nil)
+
+	digest, err := suite.builder.ResolveImageDigest(suite.ctx, baseImage)
+	suite.Require().NoError(err)
+
+	expectedDigest, err := suite.pullImage("base:latest").Digest()
+	suite.Require().NoError(err)
+	suite.Require().Equal(expectedDigest.String(), digest)
+
+	// unknown images have no digest
+	digest, err = suite.builder.ResolveImageDigest(suite.ctx, suite.registryURL+"/missing:latest")
+	suite.Require().NoError(err)
+	suite.Require().Empty(digest)
+}
+
+func (suite *OCITestSuite) TestBuildAndPushImageIndex() {
+	architectures := []string{"amd64", "arm64"}
+
+	// a base image for each architecture, in one index
+	var baseImageIndex ociv1.ImageIndex = empty.Index
+	for _, architecture := range architectures {
+		suite.pushImage("base:"+architecture, map[string]string{"etc/arch": architecture}, nil)
+		baseImage := suite.pullImage("base:" + architecture)
+
+		configFile, err := baseImage.ConfigFile()
+		suite.Require().NoError(err)
+		configFile.OS = "linux"
+		configFile.Architecture = architecture
+
+		baseImage, err = mutate.ConfigFile(baseImage, configFile)
+		suite.Require().NoError(err)
+
+		baseImageIndex = mutate.AppendManifests(baseImageIndex, mutate.IndexAddendum{
+			Add: baseImage,
+			Descriptor: ociv1.Descriptor{
+				Platform: configFile.Platform(),
+			},
+		})
+	}
+
+	baseImageReference, err := name.ParseReference(suite.registryURL+"/base:latest", name.Insecure)
+	suite.Require().NoError(err)
+	suite.Require().NoError(remote.WriteIndex(baseImageReference, baseImageIndex))
+
+	// build the processor image for each platform
+	var platformImages []string
+	for _, architecture := range architectures {
+		buildOptions := suite.createBuildOptions(baseImageReference.String(), map[string]string{"handler.py": "handler"})
+		buildOptions.Image = "processor-my-function:latest-linux-" + architecture
+		buildOptions.Platform = "linux/" + architecture
+
+		err := suite.builder.BuildAndPushContainerImage(suite.ctx, buildOptions, "")
+		suite.Require().NoError(err)
+
+		platformImages = append(platformImages, buildOptions.Image)
+	}
+
+	err = suite.builder.PushImageIndex(suite.ctx, "processor-my-function:latest", suite.registryURL, platformImages)
+	suite.Require().NoError(err)
+
+	// each platform pulls the image built from its own base image
+	for _, architecture := range architectures {
+		image, err := suite.builder.registryClient.pullPlatformImage(suite.ctx,
+			suite.registryURL+"/processor-my-function:latest",
+			"linux/"+architecture)
+		suite.Require().NoError(err)
+
+		configFile, err := image.ConfigFile()
+		suite.Require().NoError(err)
+		suite.Require().Equal(architecture, configFile.Architecture)
+		suite.Require().Equal(architecture, suite.readFiles(image)["etc/arch"])
+	}
+
+	// an index requires a registry
+	err = suite.builder.PushImageIndex(suite.ctx, "processor-my-function:latest", "", platformImages)
+	suite.Require().Error(err)
+}
+
+func (suite *OCITestSuite) TestRegistryCredentials() {
+	credentialsPath := filepath.Join(suite.T().TempDir(), ".dockerconfigjson")
+	err := os.WriteFile(credentialsPath,
+		[]byte(`{"auths": {"my-registry.io/some-repo": {"username": "user", "password": "pass"}}}`),
+		0600)
+	suite.Require().NoError(err)
+
+	builder, err := NewOCI(suite.logger, &ContainerBuilderConfiguration{RegistryCredentialsPath: credentialsPath})
+	suite.Require().NoError(err)
+
+	for _, testCase := range []struct {
+		registry       string
+		expectedConfig *authn.AuthConfig
+	}{
+		{registry: "my-registry.io", expectedConfig: &authn.AuthConfig{Username: "user", Password: "pass"}},
+		{registry: "other-registry.io", expectedConfig: &authn.AuthConfig{}},
+	} {
+		registry, err := name.NewRegistry(testCase.registry)
+		suite.Require().NoError(err)
+
+		authenticator, err := builder.registryClient.keychain.Resolve(registry)
+		suite.Require().NoError(err)
+
+		authConfig, err := authenticator.Authorization()
+		suite.Require().NoError(err)
+		suite.Require().Equal(testCase.expectedConfig, authConfig)
+	}
+
+	// a missing credentials file falls back to the default keychain
+	builder, err = NewOCI(suite.logger, &ContainerBuilderConfiguration{
+		RegistryCredentialsPath: filepath.Join(suite.T().TempDir(), "missing"),
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(authn.DefaultKeychain, builder.registryClient.keychain)
+}
+
+func (suite *OCITestSuite) TestPullArtifact() {
+	var directoryBuffer bytes.Buffer
+	tarWriter := tar.NewWriter(&directoryBuffer)
+	for filePath, contents := range map[string]string{"handler.py": "handler", "lib/util.py": "util"} {
+		suite.Require().NoError(tarWriter.WriteHeader(&tar.Header{
+			Name:     filePath,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+		}))
+		_, err := tarWriter.Write([]byte(contents))
+		suite.Require().NoError(err)
+	}
+	suite.Require().NoError(tarWriter.Close())
+
+	directoryLayer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(directoryBuffer.Bytes())), nil
+	})
+	suite.Require().NoError(err)
+
+	// a file and a directory, as pushed by oras
+	artifactReference := suite.pushArtifact("my-function:v1",
+		mutate.Addendum{
+			Layer:       static.NewLayer([]byte("requests"), "text/plain"),
+			Annotations: map[string]string{ociTitleAnnotation: "requirements.txt"},
+		},
+		mutate.Addendum{
+			Layer: directoryLayer,
+			Annotations: map[string]string{
+				ociTitleAnnotation:   "src",
+				orasUnpackAnnotation: "true",
+			},
+		})
+
+	outputDir := suite.T().TempDir()
+	artifactPaths, err := PullArtifact(suite.ctx,
+		suite.logger,
+		&ContainerBuilderConfiguration{InsecurePullRegistry: true},
+		"oci://"+artifactReference,
+		outputDir)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{
+		filepath.Join(outputDir, "requirements.txt"),
+		filepath.Join(outputDir, "src"),
+	}, artifactPaths)
+
+	for filePath, expectedContents := range map[string]string{
+		"requirements.txt": "requests",
+		"src/handler.py":   "handler",
+		"src/lib/util.py":  "util",
+	} {
+		contents, err := os.ReadFile(filepath.Join(outputDir, filePath))
+		suite.Require().NoError(err)
+		suite.Require().Equal(expectedContents, string(contents))
+	}
+
+	// titles mustn't escape the output directory
+	artifactReference = suite.pushArtifact("my-function:escape", mutate.Addendum{
+		Layer:       static.NewLayer([]byte("escape"), "text/plain"),
+		Annotations: map[string]string{ociTitleAnnotation: "../escape.txt"},
+	})
+
+	_, err = PullArtifact(suite.ctx,
+		suite.logger,
+		&ContainerBuilderConfiguration{InsecurePullRegistry: true},
+		artifactReference,
+		outputDir)
+	suite.Require().Error(err)
+
+	// and every layer must have a title
+	artifactReference = suite.pushArtifact("my-function:untitled", mutate.Addendum{
+		Layer: static.NewLayer([]byte("untitled"), "text/plain"),
+	})
+
+	_, err = PullArtifact(suite.ctx,
+		suite.logger,
+		&ContainerBuilderConfiguration{InsecurePullRegistry: true},
+		artifactReference,
+		outputDir)
+	suite.Require().Error(err)
+}
+
+func (suite *OCITestSuite) createBuildOptions(baseImage string, handlerFiles map[string]string) *BuildOptions {
+	contextDir := suite.T().TempDir()
+	handlerDir := filepath.Join(contextDir, "handler")
+	suite.Require().NoError(os.MkdirAll(handlerDir, 0755))
+
+	for fileName, contents := range handlerFiles {
+		suite.Require().NoError(os.WriteFile(filepath.Join(handlerDir, fileName), []byte(contents), 0644))
+	}
+
+	return &BuildOptions{
+		Image:       "processor-my-function:latest",
+		ContextDir:  contextDir,
+		TempDir:     suite.T().TempDir(),
+		RegistryURL: suite.registryURL,
+		DockerfileInfo: &runtime.ProcessorDockerfileInfo{
+			BaseImage: baseImage,
+			ImageArtifactPaths: map[string]string{
+				"handler": "/opt/nuclio",
+			},
+		},
+	}
+}
+
+func (suite *OCITestSuite) pushImage(imageName string, files map[string]string, config *ociv1.Config) string {
+	var layerBuffer bytes.Buffer
+	tarWriter := tar.NewWriter(&layerBuffer)
+
+	for filePath, contents := range files {
+		suite.Require().NoError(tarWriter.WriteHeader(&tar.Header{
+			Name:     filePath,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+		}))
+		_, err := tarWriter.Write([]byte(contents))
+		suite.Require().NoError(err)
+	}
+	suite.Require().NoError(tarWriter.Close())
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(layerBuffer.Bytes())), nil
+	})
+	suite.Require().NoError(err)
+
+	image, err := mutate.AppendLayers(empty.Image, layer)
+	suite.Require().NoError(err)
+
+	if config != nil {
+		image, err = mutate.Config(image, *config)
+		suite.Require().NoError(err)
+	}
+
+	taggedImage := suite.registryURL + "/" + imageName
+	reference, err := name.ParseReference(taggedImage, name.Insecure)
+	suite.Require().NoError(err)
+	suite.Require().NoError(remote.Write(reference, image))
+
+	return taggedImage
+}
+
+func (suite *OCITestSuite) pushArtifact(artifactName string, layers ...mutate.Addendum) string {
+	artifact, err := mutate.Append(empty.Image, layers...)
+	suite.Require().NoError(err)
+
+	taggedArtifact := suite.registryURL + "/" + artifactName
+	reference, err := name.ParseReference(taggedArtifact, name.Insecure)
+	suite.Require().NoError(err)
+	suite.Require().NoError(remote.Write(reference, artifact))
+
+	return taggedArtifact
+}
+
+func (suite *OCITestSuite) pullImage(imageName string) ociv1.Image {
+	reference, err := name.ParseReference(suite.registryURL+"/"+imageName, name.Insecure)
+	suite.Require().NoError(err)
+
+	image, err := remote.Image(reference)
+	suite.Require().NoError(err)
+
+	return image
+}
+
+// readFiles returns the contents of the regular files in the image's filesystem
+func (suite *OCITestSuite) readFiles(image ociv1.Image) map[string]string {
+	files := map[string]string{}
+
+	filesystemReader := mutate.Extract(image)
+	defer filesystemReader.Close() // nolint: errcheck
+
+	tarReader := tar.NewReader(filesystemReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		suite.Require().NoError(err)
+
+		if header.Typeflag == tar.TypeReg {
+			contents, err := io.ReadAll(tarReader)
+			suite.Require().NoError(err)
+			files[header.Name] = string(contents)
+		}
+	}
+
+	return files
+}
+
+func TestOCITestSuite(t *testing.T) {
+	suite.Run(t, new(OCITestSuite))
+}
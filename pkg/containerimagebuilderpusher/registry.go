@@ -0,0 +1,318 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerimagebuilderpusher
+
+import (
+	"context"
+	"net/http"
+	"os"
+	goruntime "runtime"
+
+	"github.com/nuclio/nuclio/pkg/common"
+
+	dockerconfig "github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// registryClient accesses images in registries directly, without a docker daemon
+type registryClient struct {
+	logger               logger.Logger
+	builderConfiguration *ContainerBuilderConfiguration
+	keychain             authn.Keychain
+}
+
+func newRegistryClient(logger logger.Logger,
+	builderConfiguration *ContainerBuilderConfiguration) (*registryClient, error) {
+	if builderConfiguration == nil {
+		builderConfiguration = &ContainerBuilderConfiguration{}
+	}
+
+	newRegistryClient := &registryClient{
+		logger:               logger,
+		builderConfiguration: builderConfiguration,
+		keychain:             authn.DefaultKeychain,
+	}
+
+	// prefer the registry credentials mounted for the builder over the local docker configuration
+	if builderConfiguration.RegistryCredentialsPath != "" {
+		credentialsFile, err := os.Open(builderConfiguration.RegistryCredentialsPath)
+		switch {
+		case err == nil:
+			defer credentialsFile.Close() // nolint: errcheck
+
+			configFile, err := dockerconfig.LoadFromReader(credentialsFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to load registry credentials")
+			}
+
+			newRegistryClient.keychain = authn.NewMultiKeychain(&dockerConfigKeychain{configFile: configFile},
+				authn.DefaultKeychain)
+		case !os.IsNotExist(err):
+			return nil, errors.Wrap(err, "Failed to open registry credentials")
+		}
+	}
+
+	return newRegistryClient, nil
+}
+
+func (rc *registryClient) pullImage(ctx context.Context, imageName string) (ociv1.Image, error) {
+	return rc.pullPlatformImage(ctx, imageName, "")
+}
+
+// pullPlatformImage pulls the image for a platform (e.g. linux/arm64), or for the local one if it's empty
+func (rc *registryClient) pullPlatformImage(ctx context.Context,
+	imageName string,
+	platform string) (ociv1.Image, error) {
+	reference, err := rc.parseReference(imageName, rc.builderConfiguration.InsecurePullRegistry)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid image name to pull")
+	}
+
+	imagePlatform := ociv1.Platform{OS: "linux", Architecture: goruntime.GOARCH}
+	if platform != "" {
+		parsedPlatform, err := ociv1.ParsePlatform(platform)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid platform %s", platform)
+		}
+
+		imagePlatform = *parsedPlatform
+	}
+
+	rc.logger.DebugWithCtx(ctx, "Pulling image", "image", imageName, "platform", imagePlatform.String())
+
+	return remote.Image(reference, append(rc.getRemoteOptions(ctx), remote.WithPlatform(imagePlatform))...)
+}
+
+func (rc *registryClient) pushImage(ctx context.Context, image ociv1.Image, taggedImage string) error {
+	reference, err := rc.parseReference(taggedImage, rc.builderConfiguration.InsecurePushRegistry)
+	if err != nil {
+		return errors.Wrap(err, "Invalid tagged image name to push")
+	}
+
+	return remote.Write(reference, image, rc.getRemoteOptions(ctx)...)
+}
+
+// pushImageIndex pushes an index of the platform images, each labeled with the platform in its configuration
+func (rc *registryClient) pushImageIndex(ctx context.Context, taggedIndex string, taggedPlatformImages []string) error {
+	var imageIndex ociv1.ImageIndex = empty.Index
+	indexMediaType := types.OCIImageIndex
+
+	for _, taggedPlatformImage := range taggedPlatformImages {
+
+		// the platform images were just pushed, so they're pulled with the push registry's settings
+		reference, err := rc.parseReference(taggedPlatformImage, rc.builderConfiguration.InsecurePushRegistry)
+		if err != nil {
+			return errors.Wrap(err, "Invalid platform image name")
+		}
+
+		image, err := remote.Image(reference, rc.getRemoteOptions(ctx)...)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get platform image %s", taggedPlatformImage)
+		}
+
+		configFile, err := image.ConfigFile()
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read configuration of platform image %s", taggedPlatformImage)
+		}
+
+		imageMediaType, err := image.MediaType()
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get media type of platform image %s", taggedPlatformImage)
+		}
+
+		// docker images are listed in a docker manifest list, which older registries and runtimes expect
+		if imageMediaType == types.DockerManifestSchema2 {
+			indexMediaType = types.DockerManifestList
+		}
+
+		imageIndex = mutate.AppendManifests(imageIndex, mutate.IndexAddendum{
+			Add: image,
+			Descriptor: ociv1.Descriptor{
+				MediaType: imageMediaType,
+				Platform:  configFile.Platform(),
+			},
+		})
+	}
+
+	reference, err := rc.parseReference(taggedIndex, rc.builderConfiguration.InsecurePushRegistry)
+	if err != nil {
+		return errors.Wrap(err, "Invalid tagged image name to push")
+	}
+
+	rc.logger.DebugWithCtx(ctx,
+		"Pushing image index",
+		"image", taggedIndex,
+		"platformImages", taggedPlatformImages)
+
+	return remote.WriteIndex(reference, mutate.IndexMediaType(imageIndex, indexMediaType), rc.getRemoteOptions(ctx)...)
+}
+
+// pushPlatformImageIndex pushes an index of platform images that were pushed to the registry
+func (rc *registryClient) pushPlatformImageIndex(ctx context.Context,
+	image string,
+	registryURL string,
+	platformImages []string) error {
+	if registryURL == "" {
+		return errors.New("Pushing a multi-platform image requires a registry")
+	}
+
+	taggedPlatformImages := make([]string, 0, len(platformImages))
+	for _, platformImage := range platformImages {
+		taggedPlatformImages = append(taggedPlatformImages, common.CompileImageName(registryURL, platformImage))
+	}
+
+	return rc.pushImageIndex(ctx, common.CompileImageName(registryURL, image), taggedPlatformImages)
+}
+
+// resolveImageDigest returns the digest of the image's manifest in the registry
+func (rc *registryClient) resolveImageDigest(ctx context.Context, imageName string) (string, error) {
+	reference, err := rc.parseReference(imageName, rc.builderConfiguration.InsecurePullRegistry)
+	if err != nil {
+		return "", errors.Wrap(err, "Invalid image name to resolve")
+	}
+
+	descriptor, err := remote.Head(reference, rc.getRemoteOptions(ctx)...)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get image manifest")
+	}
+
+	return descriptor.Digest.String(), nil
+}
+
+// getPlatformImageDigests returns the digests of the platform images of a multi-platform image by their platform,
+// or nil if the image is a single-platform image
+func (rc *registryClient) getPlatformImageDigests(ctx context.Context, imageName string) (map[string]string, error) {
+	reference, err := rc.parseReference(imageName, rc.builderConfiguration.InsecurePullRegistry)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid image name to resolve")
+	}
+
+	descriptor, err := remote.Get(reference, rc.getRemoteOptions(ctx)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get image manifest")
+	}
+
+	if !descriptor.MediaType.IsIndex() {
+		return nil, nil
+	}
+
+	imageIndex, err := descriptor.ImageIndex()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read image index")
+	}
+
+	indexManifest, err := imageIndex.IndexManifest()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read image index manifest")
+	}
+
+	platformImageDigests := map[string]string{}
+	for _, manifestDescriptor := range indexManifest.Manifests {
+		if !manifestDescriptor.MediaType.IsImage() || manifestDescriptor.Platform == nil {
+			continue
+		}
+
+		platformImageDigests[manifestDescriptor.Platform.String()] = manifestDescriptor.Digest.String()
+	}
+
+	return platformImageDigests, nil
+}
+
+// getImageLabels returns the labels of an image in the registry, or nil if the image doesn't exist
+func (rc *registryClient) getImageLabels(ctx context.Context, imageName string) (map[string]string, error) {
+	image, err := rc.pullImage(ctx, imageName)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "Failed to get image")
+	}
+
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read image configuration")
+	}
+
+	return configFile.Config.Labels, nil
+}
+
+func (rc *registryClient) parseReference(imageName string, insecure bool) (name.Reference, error) {
+	var nameOptions []name.Option
+	if insecure {
+		nameOptions = append(nameOptions, name.Insecure)
+	}
+
+	return name.ParseReference(imageName, nameOptions...)
+}
+
+func (rc *registryClient) getRemoteOptions(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(rc.keychain),
+	}
+}
+
+func isNotFoundError(err error) bool {
+	transportError, ok := err.(*transport.Error)
+	return ok && transportError.StatusCode == http.StatusNotFound
+}
+
+// dockerConfigKeychain resolves registry credentials from a docker configuration file
+type dockerConfigKeychain struct {
+	configFile *configfile.ConfigFile
+}
+
+func (k *dockerConfigKeychain) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	registry := resource.RegistryStr()
+	if registry == name.DefaultRegistry {
+		registry = authn.DefaultAuthKey
+	}
+
+	authConfig, err := k.configFile.GetAuthConfig(registry)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get registry credentials")
+	}
+
+	if authConfig.Username == "" && authConfig.Auth == "" && authConfig.IdentityToken == "" &&
+		authConfig.RegistryToken == "" {
+		return authn.Anonymous, nil
+	}
+
+	return authn.FromConfig(authn.AuthConfig{
+		Username:      authConfig.Username,
+		Password:      authConfig.Password,
+		Auth:          authConfig.Auth,
+		IdentityToken: authConfig.IdentityToken,
+		RegistryToken: authConfig.RegistryToken,
+	}), nil
+}
+
+// imageHasFingerprint returns whether image labels mark it as the product of a build with the given fingerprint
+func imageHasFingerprint(labels map[string]string, fingerprint string) bool {
+	return fingerprint != "" && labels[BuildFingerprintLabel] == fingerprint
+}
@@ -0,0 +1,500 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerimagebuilderpusher
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	ociv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/nuclio/errors"
+	"github.com/v3io/version-go"
+)
+
+const (
+	spdxMediaType      = "text/spdx+json"
+	cycloneDXMediaType = "application/vnd.cyclonedx+json"
+)
+
+// requirement lines look like "name==1.0", "name[extra]>=1.0; python_version > '3'" or just "name"
+var pythonRequirementRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(\[[^\]]*\])?\s*(?:==\s*([^\s;,]+))?`)
+
+// sbomPackage is a package found in an image or in a dependency manifest
+type sbomPackage struct {
+	Name    string
+	Version string
+
+	// the package type and namespace of its package URL (e.g. "deb" and "debian")
+	Type      string
+	Namespace string
+}
+
+func (p *sbomPackage) purl() string {
+	packageURL := "pkg:" + p.Type + "/"
+	if p.Namespace != "" {
+		packageURL += escapePURLSegment(p.Namespace) + "/"
+	}
+
+	// go module paths and scoped npm packages keep their slashes
+	nameParts := strings.Split(p.Name, "/")
+	for idx, namePart := range nameParts {
+		nameParts[idx] = escapePURLSegment(namePart)
+	}
+
+	packageURL += strings.Join(nameParts, "/")
+	if p.Version != "" {
+		packageURL += "@" + escapePURLSegment(p.Version)
+	}
+
+	return packageURL
+}
+
+// escapePURLSegment escapes a package URL segment, including the "@" separating the version (e.g. of "@scope/lib")
+func escapePURLSegment(segment string) string {
+	return strings.ReplaceAll(url.PathEscape(segment), "@", "%40")
+}
+
+// collectImagePackages returns the packages installed in the image - by the OS package manager, or by pip
+func collectImagePackages(image ociv1.Image) ([]sbomPackage, error) {
+	var dpkgStatus, apkInstalled, osRelease []byte
+	var pythonMetadata [][]byte
+
+	imageFilesystem := mutate.Extract(image)
+	defer imageFilesystem.Close() // nolint: errcheck
+
+	tarReader := tar.NewReader(imageFilesystem)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read image filesystem")
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		var target *[]byte
+		filePath := path.Clean("/" + header.Name)
+
+		switch {
+		case filePath == "/var/lib/dpkg/status":
+			target = &dpkgStatus
+		case filePath == "/lib/apk/db/installed":
+			target = &apkInstalled
+		case filePath == "/etc/os-release" || filePath == "/usr/lib/os-release" && osRelease == nil:
+			target = &osRelease
+		case path.Base(filePath) == "METADATA" && strings.HasSuffix(path.Dir(filePath), ".dist-info"):
+			pythonMetadata = append(pythonMetadata, nil)
+			target = &pythonMetadata[len(pythonMetadata)-1]
+		default:
+			continue
+		}
+
+		if *target, err = io.ReadAll(tarReader); err != nil {
+			return nil, errors.Wrapf(err, "Failed to read %s", filePath)
+		}
+	}
+
+	distribution := parseKeyValueLines(osRelease, "=")["ID"]
+
+	var packages []sbomPackage
+	packages = append(packages, parseControlFilePackages(dpkgStatus, "Package", "Version", "deb", distribution)...)
+	packages = append(packages, parseControlFilePackages(apkInstalled, "P", "V", "apk", distribution)...)
+
+	for _, metadata := range pythonMetadata {
+		fields := parseKeyValueLines(metadata, ":")
+		if fields["Name"] != "" {
+			packages = append(packages, sbomPackage{
+				Name:    normalizePythonPackageName(fields["Name"]),
+				Version: fields["Version"],
+				Type:    "pypi",
+			})
+		}
+	}
+
+	return packages, nil
+}
+
+// collectManifestPackages returns the dependencies declared in the runtime dependency manifests under a directory
+func collectManifestPackages(manifestsDir string) ([]sbomPackage, error) {
+	var packages []sbomPackage
+
+	if manifestsDir == "" {
+		return nil, nil
+	}
+
+	err := filepath.WalkDir(manifestsDir, func(filePath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// dependencies that were vendored are declared by their own manifests
+		if dirEntry.IsDir() {
+			if dirEntry.Name() == "node_modules" || dirEntry.Name() == "vendor" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		var parseManifest func([]byte) ([]sbomPackage, error)
+		switch dirEntry.Name() {
+		case "requirements.txt":
+			parseManifest = parseRequirementsManifest
+		case "package.json":
+			parseManifest = parsePackageJSONManifest
+		case "go.mod":
+			parseManifest = parseGoModManifest
+		default:
+			return nil
+		}
+
+		contents, err := os.ReadFile(filePath)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read %s", filePath)
+		}
+
+		manifestPackages, err := parseManifest(contents)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to parse %s", filePath)
+		}
+
+		packages = append(packages, manifestPackages...)
+		return nil
+	})
+
+	return packages, err
+}
+
+func parseRequirementsManifest(contents []byte) ([]sbomPackage, error) {
+	var packages []sbomPackage
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// skip comments, options (e.g. "-r other.txt", "--index-url") and direct references
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+			continue
+		}
+
+		if match := pythonRequirementRegex.FindStringSubmatch(line); match != nil {
+			packages = append(packages, sbomPackage{
+				Name:    normalizePythonPackageName(match[1]),
+				Version: match[3],
+				Type:    "pypi",
+			})
+		}
+	}
+
+	return packages, scanner.Err()
+}
+
+func parsePackageJSONManifest(contents []byte) ([]sbomPackage, error) {
+	var packageJSON struct {
+		Dependencies map[string]string `json:"dependencies"`
+	}
+
+	if err := json.Unmarshal(contents, &packageJSON); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode package.json")
+	}
+
+	var packages []sbomPackage
+	for name, version := range packageJSON.Dependencies {
+		sbomPackage := sbomPackage{Name: name, Type: "npm"}
+
+		// only exact versions identify a package, ranges are resolved at install time
+		if !strings.ContainsAny(version, "^~<>=*xX |:/") {
+			sbomPackage.Version = strings.TrimPrefix(version, "v")
+		}
+
+		packages = append(packages, sbomPackage)
+	}
+
+	return packages, nil
+}
+
+func parseGoModManifest(contents []byte) ([]sbomPackage, error) {
+	var packages []sbomPackage
+	inRequireBlock := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if commentIndex := strings.Index(line, "//"); commentIndex != -1 {
+			line = strings.TrimSpace(line[:commentIndex])
+		}
+
+		switch {
+		case line == "require (":
+			inRequireBlock = true
+			continue
+		case inRequireBlock && line == ")":
+			inRequireBlock = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case !inRequireBlock:
+			continue
+		}
+
+		if fields := strings.Fields(line); len(fields) == 2 {
+			packages = append(packages, sbomPackage{Name: fields[0], Version: fields[1], Type: "golang"})
+		}
+	}
+
+	return packages, scanner.Err()
+}
+
+// parseControlFilePackages parses package databases made of "Key: value" paragraphs, such as dpkg's status file
+// and apk's installed database
+func parseControlFilePackages(contents []byte,
+	nameKey string,
+	versionKey string,
+	packageType string,
+	namespace string) []sbomPackage {
+	var packages []sbomPackage
+
+	for _, paragraph := range strings.Split(string(contents), "\n\n") {
+		fields := parseKeyValueLines([]byte(paragraph), ":")
+
+		// dpkg keeps removed packages whose configuration files remain
+		if status := fields["Status"]; status != "" && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+
+		if fields[nameKey] != "" {
+			packages = append(packages, sbomPackage{
+				Name:      fields[nameKey],
+				Version:   fields[versionKey],
+				Type:      packageType,
+				Namespace: namespace,
+			})
+		}
+	}
+
+	return packages
+}
+
+// parseKeyValueLines parses the "key<separator>value" lines of a file, ignoring continuation lines
+func parseKeyValueLines(contents []byte, separator string) map[string]string {
+	values := map[string]string{}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+
+		if key, value, found := strings.Cut(line, separator); found {
+			values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+
+	return values
+}
+
+func normalizePythonPackageName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(name))
+}
+
+// sortAndDeduplicatePackages returns the packages ordered by package URL, keeping one of each
+func sortAndDeduplicatePackages(packages []sbomPackage) []sbomPackage {
+	sort.SliceStable(packages, func(i, j int) bool {
+		return packages[i].purl() < packages[j].purl()
+	})
+
+	var uniquePackages []sbomPackage
+	for idx, sbomPackage := range packages {
+		if idx == 0 || sbomPackage.purl() != packages[idx-1].purl() {
+			uniquePackages = append(uniquePackages, sbomPackage)
+		}
+	}
+
+	return uniquePackages
+}
+
+// encodeSBOM returns the SBOM document of an image and its media type
+func encodeSBOM(format functionconfig.SBOMFormat,
+	imageName string,
+	imageDigest string,
+	packages []sbomPackage) ([]byte, string, error) {
+	packages = sortAndDeduplicatePackages(packages)
+	created := time.Now().UTC().Format(time.RFC3339)
+	toolName := "nuclio-" + version.Get().Label
+
+	switch format {
+	case functionconfig.SBOMFormatSPDX:
+		document, err := encodeSPDXDocument(imageName, imageDigest, packages, created, toolName)
+		return document, spdxMediaType, err
+	case functionconfig.SBOMFormatCycloneDX:
+		document, err := encodeCycloneDXDocument(imageName, imageDigest, packages, created, toolName)
+		return document, cycloneDXMediaType, err
+	default:
+		return nil, "", errors.Errorf("Unsupported SBOM format: %s", format)
+	}
+}
+
+func encodeSPDXDocument(imageName string,
+	imageDigest string,
+	packages []sbomPackage,
+	created string,
+	toolName string) ([]byte, error) {
+	type spdxExternalRef struct {
+		ReferenceCategory string `json:"referenceCategory"`
+		ReferenceType     string `json:"referenceType"`
+		ReferenceLocator  string `json:"referenceLocator"`
+	}
+
+	type spdxPackage struct {
+		SPDXID           string            `json:"SPDXID"`
+		Name             string            `json:"name"`
+		VersionInfo      string            `json:"versionInfo,omitempty"`
+		DownloadLocation string            `json:"downloadLocation"`
+		FilesAnalyzed    bool              `json:"filesAnalyzed"`
+		ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+	}
+
+	type spdxRelationship struct {
+		SPDXElementID      string `json:"spdxElementId"`
+		RelationshipType   string `json:"relationshipType"`
+		RelatedSPDXElement string `json:"relatedSpdxElement"`
+	}
+
+	imagePackageID := "SPDXRef-Image"
+	spdxPackages := []spdxPackage{
+		{
+			SPDXID:           imagePackageID,
+			Name:             imageName,
+			VersionInfo:      imageDigest,
+			DownloadLocation: "NOASSERTION",
+		},
+	}
+	relationships := []spdxRelationship{
+		{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: imagePackageID,
+		},
+	}
+
+	for idx, sbomPackage := range packages {
+		packageID := fmt.Sprintf("SPDXRef-Package-%d", idx+1)
+
+		spdxPackages = append(spdxPackages, spdxPackage{
+			SPDXID:           packageID,
+			Name:             sbomPackage.Name,
+			VersionInfo:      sbomPackage.Version,
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs: []spdxExternalRef{
+				{
+					ReferenceCategory: "PACKAGE-MANAGER",
+					ReferenceType:     "purl",
+					ReferenceLocator:  sbomPackage.purl(),
+				},
+			},
+		})
+		relationships = append(relationships, spdxRelationship{
+			SPDXElementID:      imagePackageID,
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: packageID,
+		})
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              imageName,
+		"documentNamespace": fmt.Sprintf("https://nuclio.io/spdx/%s@%s", imageName, imageDigest),
+		"creationInfo": map[string]interface{}{
+			"created":  created,
+			"creators": []string{"Tool: " + toolName},
+		},
+		"packages":      spdxPackages,
+		"relationships": relationships,
+	}, "", "  ")
+}
+
+func encodeCycloneDXDocument(imageName string,
+	imageDigest string,
+	packages []sbomPackage,
+	created string,
+	toolName string) ([]byte, error) {
+	type cycloneDXComponent struct {
+		Type    string `json:"type"`
+		BOMRef  string `json:"bom-ref,omitempty"`
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+		PURL    string `json:"purl,omitempty"`
+	}
+
+	components := make([]cycloneDXComponent, 0, len(packages))
+	for _, sbomPackage := range packages {
+		components = append(components, cycloneDXComponent{
+			Type:    "library",
+			BOMRef:  sbomPackage.purl(),
+			Name:    sbomPackage.Name,
+			Version: sbomPackage.Version,
+			PURL:    sbomPackage.purl(),
+		})
+	}
+
+	// derive the serial number from the image, so that each image has one
+	serialHash := sha256.Sum256([]byte(imageName + "@" + imageDigest))
+	serialHash[6] = serialHash[6]&0x0f | 0x50
+	serialHash[8] = serialHash[8]&0x3f | 0x80
+
+	return json.MarshalIndent(map[string]interface{}{
+		"bomFormat":   "CycloneDX",
+		"specVersion": "1.5",
+		"serialNumber": fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x",
+			serialHash[0:4], serialHash[4:6], serialHash[6:8], serialHash[8:10], serialHash[10:16]),
+		"version": 1,
+		"metadata": map[string]interface{}{
+			"timestamp": created,
+			"tools": map[string]interface{}{
+				"components": []cycloneDXComponent{{Type: "application", Name: toolName}},
+			},
+			"component": cycloneDXComponent{
+				Type:    "container",
+				BOMRef:  imageName + "@" + imageDigest,
+				Name:    imageName,
+				Version: imageDigest,
+			},
+		},
+		"components": components,
+	}, "", "  ")
+}
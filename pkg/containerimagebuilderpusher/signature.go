@@ -0,0 +1,185 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerimagebuilderpusher
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+
+	"github.com/nuclio/errors"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	cosignSignatureAnnotation      = "dev.cosignproject.cosign/signature"
+	cosignSimpleSigningMediaType   = "application/vnd.dev.cosign.simplesigning.v1+json"
+	cosignSignatureType            = "cosign container image signature"
+	cosignPasswordEnvironmentVar   = "COSIGN_PASSWORD"
+	encryptedSigstorePrivateKeyPEM = "ENCRYPTED SIGSTORE PRIVATE KEY"
+	encryptedCosignPrivateKeyPEM   = "ENCRYPTED COSIGN PRIVATE KEY"
+)
+
+// encryptedPrivateKey is the encrypted private key format of cosign's generate-key-pair
+type encryptedPrivateKey struct {
+	KDF struct {
+		Name   string `json:"name"`
+		Params struct {
+			N int `json:"N"`
+			R int `json:"r"`
+			P int `json:"p"`
+		} `json:"params"`
+		Salt []byte `json:"salt"`
+	} `json:"kdf"`
+	Cipher struct {
+		Name  string `json:"name"`
+		Nonce []byte `json:"nonce"`
+	} `json:"cipher"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// loadSigningKey reads a PEM-encoded ECDSA, RSA or Ed25519 private key, which may also be encrypted by cosign
+func loadSigningKey(signingKeyPath string) (crypto.Signer, error) {
+	encodedKey, err := os.ReadFile(signingKeyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read signing key")
+	}
+
+	keyBlock, _ := pem.Decode(encodedKey)
+	if keyBlock == nil {
+		return nil, errors.New("Signing key isn't PEM-encoded")
+	}
+
+	var privateKey interface{}
+
+	switch keyBlock.Type {
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(keyBlock.Bytes)
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	case "PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	case encryptedSigstorePrivateKeyPEM, encryptedCosignPrivateKeyPEM:
+		var decryptedKey []byte
+		if decryptedKey, err = decryptPrivateKey(keyBlock.Bytes, os.Getenv(cosignPasswordEnvironmentVar)); err == nil {
+			privateKey, err = x509.ParsePKCS8PrivateKey(decryptedKey)
+		}
+	default:
+		return nil, errors.Errorf("Unsupported signing key type: %s", keyBlock.Type)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse signing key")
+	}
+
+	switch typedPrivateKey := privateKey.(type) {
+	case *ecdsa.PrivateKey, *rsa.PrivateKey, ed25519.PrivateKey:
+		return typedPrivateKey.(crypto.Signer), nil
+	default:
+		return nil, errors.Errorf("Unsupported signing key algorithm: %T", privateKey)
+	}
+}
+
+func decryptPrivateKey(encodedEncryptedKey []byte, password string) ([]byte, error) {
+	var encryptedKey encryptedPrivateKey
+	if err := json.Unmarshal(encodedEncryptedKey, &encryptedKey); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode encrypted signing key")
+	}
+
+	if encryptedKey.KDF.Name != "scrypt" || encryptedKey.Cipher.Name != "nacl/secretbox" {
+		return nil, errors.Errorf("Unsupported signing key encryption: %s, %s",
+			encryptedKey.KDF.Name,
+			encryptedKey.Cipher.Name)
+	}
+
+	if len(encryptedKey.Cipher.Nonce) != 24 {
+		return nil, errors.New("Invalid signing key encryption nonce")
+	}
+
+	derivedKey, err := scrypt.Key([]byte(password),
+		encryptedKey.KDF.Salt,
+		encryptedKey.KDF.Params.N,
+		encryptedKey.KDF.Params.R,
+		encryptedKey.KDF.Params.P,
+		32)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to derive signing key encryption key")
+	}
+
+	var nonce [24]byte
+	var secretKey [32]byte
+	copy(nonce[:], encryptedKey.Cipher.Nonce)
+	copy(secretKey[:], derivedKey)
+
+	decryptedKey, ok := secretbox.Open(nil, encryptedKey.Ciphertext, &nonce, &secretKey)
+	if !ok {
+		return nil, errors.Errorf("Failed to decrypt signing key, check the %s environment variable",
+			cosignPasswordEnvironmentVar)
+	}
+
+	return decryptedKey, nil
+}
+
+// createSimpleSigningPayload returns the payload that cosign signs for an image
+func createSimpleSigningPayload(repository string, imageDigest string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"critical": map[string]interface{}{
+			"identity": map[string]string{
+				"docker-reference": repository,
+			},
+			"image": map[string]string{
+				"docker-manifest-digest": imageDigest,
+			},
+			"type": cosignSignatureType,
+		},
+		"optional": nil,
+	})
+}
+
+func signPayload(signer crypto.Signer, payload []byte) ([]byte, error) {
+
+	// ed25519 signs the message itself rather than its digest
+	if _, isEd25519 := signer.(ed25519.PrivateKey); isEd25519 {
+		return signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+
+	payloadDigest := sha256.Sum256(payload)
+	return signer.Sign(rand.Reader, payloadDigest[:], crypto.SHA256)
+}
+
+func verifyPayloadSignature(publicKey crypto.PublicKey, payload []byte, signature []byte) bool {
+	payloadDigest := sha256.Sum256(payload)
+
+	switch typedPublicKey := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(typedPublicKey, payloadDigest[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(typedPublicKey, crypto.SHA256, payloadDigest[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(typedPublicKey, payload, signature)
+	default:
+		return false
+	}
+}
@@ -28,6 +28,9 @@ import (
 	"k8s.io/api/core/v1"
 )
 
+// BuildFingerprintLabel labels images with the fingerprint of the build that produced them
+const BuildFingerprintLabel = "nuclio.io/build-fingerprint"
+
 // BuildOptions are options for building a container image
 type BuildOptions struct {
 	Image                   string
@@ -37,6 +40,7 @@ type BuildOptions struct {
 	NoCache                 bool
 	Pull                    bool
 	NoBaseImagePull         bool
+	Platform                string
 	BuildFlags              map[string]bool
 	BuildArgs               map[string]string
 	RegistryURL             string
@@ -76,6 +80,7 @@ type ContainerBuilderConfiguration struct {
 	InsecurePullRegistry                 bool
 	PushImagesRetries                    int
 	ImageFSExtractionRetries             int
+	RegistryCredentialsPath              string
 }
 
 func NewContainerBuilderConfiguration() (*ContainerBuilderConfiguration, error) {
@@ -154,6 +159,9 @@ func NewContainerBuilderConfiguration() (*ContainerBuilderConfiguration, error)
 		return nil, errors.Wrap(err, "Failed to parse job deletion timeout duration")
 	}
 
+	containerBuilderConfiguration.RegistryCredentialsPath = common.GetEnvOrDefaultString(
+		"NUCLIO_REGISTRY_CREDENTIALS_PATH", "/etc/nuclio/dashboard/registry-credentials/.dockerconfigjson")
+
 	containerBuilderConfiguration.DefaultServiceAccount = common.GetEnvOrDefaultString("NUCLIO_KANIKO_DEFAULT_SERVICE_ACCOUNT",
 		"")
 
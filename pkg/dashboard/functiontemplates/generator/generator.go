@@ -399,6 +399,10 @@ func (g *Generator) parseInlineConfiguration(sourcePath string,
 		return nil
 	}
 
+	if configureBlock.Error != nil {
+		return errors.Wrapf(configureBlock.Error, "Invalid configure block at %s", sourcePath)
+	}
+
 	unmarshalledInlineConfigYAML, found := configureBlock.Contents[common.FunctionConfigFileName]
 	if !found {
 		return errors.Errorf("No function.yaml file found inside configure block at %s", sourcePath)
@@ -458,33 +462,32 @@ func newGenerator(logger logger.Logger, examplesDir string, outputPath string) (
 		outputPath:  outputPath,
 	}
 
-	slashSlashParser := inlineparser.NewParser(logger, "//")
-	poundParser := inlineparser.NewParser(logger, "#")
+	commentParser := inlineparser.NewParser(logger, inlineparser.DefaultCommentChars...)
 
 	// TODO: support java parser too i guess
 	newGenerator.runtimes = []*Runtime{
 		{
-			InlineParser:  slashSlashParser,
+			InlineParser:  commentParser,
 			FileExtension: ".go",
 			Name:          "golang",
 		},
 		{
-			InlineParser:  slashSlashParser,
+			InlineParser:  commentParser,
 			FileExtension: ".js",
 			Name:          "nodejs",
 		},
 		{
-			InlineParser:  slashSlashParser,
+			InlineParser:  commentParser,
 			FileExtension: ".cs",
 			Name:          "dotnetcore",
 		},
 		{
-			InlineParser:  poundParser,
+			InlineParser:  commentParser,
 			FileExtension: ".py",
 			Name:          "python",
 		},
 		{
-			InlineParser:  poundParser,
+			InlineParser:  commentParser,
 			FileExtension: ".sh",
 			Name:          "shell",
 		},
@@ -231,6 +231,12 @@ func (fr *functionResource) GetCustomRoutes() ([]restful.CustomRoute, error) {
 			StreamRouteFunc: fr.getFunctionLogs,
 			Stream:          true,
 		},
+		{
+			Pattern:         "/{id}/deploy-events",
+			Method:          http.MethodGet,
+			StreamRouteFunc: fr.getFunctionDeployEvents,
+			Stream:          true,
+		},
 	}, nil
 }
 
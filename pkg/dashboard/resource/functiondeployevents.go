@@ -0,0 +1,203 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/restful"
+
+	"github.com/nuclio/errors"
+	nuclio "github.com/nuclio/nuclio-sdk-go"
+)
+
+const (
+
+	// how often the function's state is checked while following its deployment
+	functionDeployEventsPollInterval = time.Second
+
+	// how long the stream may stay silent before a keep-alive comment is sent, so that proxies don't close it
+	functionDeployEventsKeepAliveInterval = 15 * time.Second
+)
+
+// getFunctionDeployEvents streams the build and deployment events of a function as server-sent events, until the
+// deployment ends. clients that reconnect with a Last-Event-ID header resume after the last event they got
+func (fr *functionResource) getFunctionDeployEvents(request *http.Request) (*restful.CustomRouteFuncStreamResponse, error) {
+
+	// ensure namespace
+	if fr.getNamespaceFromRequest(request) == "" {
+		return nil, nuclio.NewErrBadRequest("Namespace must exist")
+	}
+
+	// ensure function name
+	functionName := fr.GetRouterURLParam(request, "id")
+	if functionName == "" {
+		return nil, nuclio.NewErrBadRequest("Function name must not be empty")
+	}
+
+	offset := 0
+	if lastEventID := request.Header.Get("Last-Event-ID"); lastEventID != "" {
+		lastEventOffset, err := strconv.Atoi(lastEventID)
+		if err != nil {
+			return nil, nuclio.NewErrBadRequest(fmt.Sprintf("Invalid Last-Event-ID header: %s", lastEventID))
+		}
+
+		offset = lastEventOffset + 1
+	}
+
+	// get the function before streaming, so that clients may only follow functions they may read
+	function, err := fr.getFunction(request, functionName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get function")
+	}
+
+	eventsReader, eventsWriter := io.Pipe()
+
+	go func() {
+		err := fr.writeFunctionDeployEvents(request, function, offset, eventsWriter)
+		eventsWriter.CloseWithError(err) // nolint: errcheck
+	}()
+
+	return &restful.CustomRouteFuncStreamResponse{
+		ReadCloser: eventsReader,
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":  "text/event-stream",
+			"Cache-Control": "no-cache, private",
+		},
+		ForceFlush:    true,
+		FlushInternal: time.Second,
+	}, nil
+}
+
+// writeFunctionDeployEvents writes the function's deployment events until the deployment ends or the client goes
+// away. the events are read live if this instance deploys the function, and from its logs otherwise
+func (fr *functionResource) writeFunctionDeployEvents(request *http.Request,
+	function platform.Function,
+	offset int,
+	eventsWriter io.Writer) error {
+	ctx := request.Context()
+	functionName := function.GetConfig().Meta.Name
+	lastWriteTime := time.Now()
+	var lastState functionconfig.FunctionState
+
+	for {
+		readCtx, cancelRead := context.WithTimeout(ctx, functionDeployEventsPollInterval)
+		readResult, err := fr.getPlatform().ReadFunctionDeployEvents(readCtx,
+			&platform.ReadFunctionDeployEventsOptions{
+				FunctionMeta: &function.GetConfig().Meta,
+				Offset:       offset,
+			})
+		cancelRead()
+		if err != nil {
+			return errors.Wrap(err, "Failed to read function deploy events")
+		}
+
+		var events []map[string]interface{}
+		var nextOffset int
+		if readResult != nil {
+			events, nextOffset = readResult.Events, readResult.NextOffset
+		} else {
+			logs := function.GetStatus().Logs
+			events, nextOffset = logs[min(offset, len(logs)):], len(logs)
+		}
+
+		for eventIndex, event := range events {
+			eventName := "log"
+			if _, isBuildOutput := event[common.BuildOutputLogKey]; isBuildOutput {
+				eventName = "output"
+			}
+
+			eventID := strconv.Itoa(nextOffset - len(events) + eventIndex)
+			if err := writeServerSentEvent(eventsWriter, eventID, eventName, event); err != nil {
+				return err
+			}
+
+			lastWriteTime = time.Now()
+		}
+		offset = nextOffset
+
+		// refresh the function's state
+		function, err = fr.getFunction(request, functionName)
+		if err != nil {
+			return errors.Wrap(err, "Failed to get function")
+		}
+
+		functionStatus := map[string]interface{}{
+			"state":   function.GetStatus().State,
+			"message": function.GetStatus().Message,
+		}
+
+		if function.GetStatus().State != lastState {
+			if err := writeServerSentEvent(eventsWriter, "", "state", functionStatus); err != nil {
+				return err
+			}
+
+			lastState = function.GetStatus().State
+			lastWriteTime = time.Now()
+		}
+
+		// a deployment of this instance is done once its events end, and any other once its state is final
+		if (readResult != nil && readResult.Done) ||
+			(readResult == nil && functionconfig.FunctionStateProvisioned(lastState)) {
+			return writeServerSentEvent(eventsWriter, "", "done", functionStatus)
+		}
+
+		if time.Since(lastWriteTime) >= functionDeployEventsKeepAliveInterval {
+			if _, err := io.WriteString(eventsWriter, ": keep-alive\n\n"); err != nil {
+				return err
+			}
+
+			lastWriteTime = time.Now()
+		}
+
+		// logs of deployments of other instances aren't waited on, so wait before checking them again
+		if readResult == nil {
+			select {
+			case <-time.After(functionDeployEventsPollInterval):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func writeServerSentEvent(writer io.Writer, id string, eventName string, data interface{}) error {
+	encodedData, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode event data")
+	}
+
+	var event bytes.Buffer
+	if id != "" {
+		fmt.Fprintf(&event, "id: %s\n", id)
+	}
+	fmt.Fprintf(&event, "event: %s\ndata: %s\n\n", eventName, encodedData)
+
+	_, err = writer.Write(event.Bytes())
+	return err
+}
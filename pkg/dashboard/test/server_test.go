@@ -1128,6 +1128,101 @@ func (suite *functionTestSuite) TestPatchFunctionInvalidDesiredState() {
 		nil)
 }
 
+func (suite *functionTestSuite) TestGetDeployEvents() {
+	returnedFunction := platform.AbstractFunction{}
+	returnedFunction.Config.Meta.Name = "f1"
+	returnedFunction.Config.Meta.Namespace = "f1-namespace"
+	returnedFunction.Status.State = functionconfig.FunctionStateReady
+
+	suite.mockPlatform.
+		On("GetFunctions", mock.Anything, mock.Anything).
+		Return([]platform.Function{&returnedFunction}, nil).
+		Twice()
+
+	// the client resumes after the event it last got
+	verifyReadFunctionDeployEvents := func(readOptions *platform.ReadFunctionDeployEventsOptions) bool {
+		suite.Require().Equal("f1", readOptions.FunctionMeta.Name)
+		suite.Require().Equal(3, readOptions.Offset)
+
+		return true
+	}
+
+	suite.mockPlatform.
+		On("ReadFunctionDeployEvents", mock.Anything, mock.MatchedBy(verifyReadFunctionDeployEvents)).
+		Return(&platform.ReadFunctionDeployEventsResult{
+			Events: []map[string]interface{}{
+				{"level": "info", "message": "Building processor image"},
+				{"level": "debug", "message": "Build output", common.BuildOutputLogKey: "Step 1/4 : FROM alpine"},
+			},
+			NextOffset: 5,
+			Done:       true,
+		}, nil).
+		Once()
+
+	responseBody := suite.getDeployEvents("f1", map[string]string{
+		headers.FunctionNamespace: "f1-namespace",
+		"Last-Event-ID":           "2",
+	})
+
+	suite.Require().Equal(`id: 3
+event: log
+data: {"level":"info","message":"Building processor image"}
+
+id: 4
+event: output
+data: {"buildOutput":"Step 1/4 : FROM alpine","level":"debug","message":"Build output"}
+
+event: state
+data: {"message":"","state":"ready"}
+
+event: done
+data: {"message":"","state":"ready"}
+
+`, responseBody)
+
+	suite.mockPlatform.AssertExpectations(suite.T())
+}
+
+func (suite *functionTestSuite) TestGetDeployEventsFromStatusLogs() {
+	returnedFunction := platform.AbstractFunction{}
+	returnedFunction.Config.Meta.Name = "f1"
+	returnedFunction.Config.Meta.Namespace = "f1-namespace"
+	returnedFunction.Status.State = functionconfig.FunctionStateError
+	returnedFunction.Status.Message = "Failed to build"
+	returnedFunction.Status.Logs = []map[string]interface{}{
+		{"level": "info", "message": "Building processor image"},
+	}
+
+	suite.mockPlatform.
+		On("GetFunctions", mock.Anything, mock.Anything).
+		Return([]platform.Function{&returnedFunction}, nil).
+		Twice()
+
+	// another instance deployed the function
+	suite.mockPlatform.
+		On("ReadFunctionDeployEvents", mock.Anything, mock.Anything).
+		Return((*platform.ReadFunctionDeployEventsResult)(nil), nil).
+		Once()
+
+	responseBody := suite.getDeployEvents("f1", map[string]string{
+		headers.FunctionNamespace: "f1-namespace",
+	})
+
+	suite.Require().Equal(`id: 0
+event: log
+data: {"level":"info","message":"Building processor image"}
+
+event: state
+data: {"message":"Failed to build","state":"error"}
+
+event: done
+data: {"message":"Failed to build","state":"error"}
+
+`, responseBody)
+
+	suite.mockPlatform.AssertExpectations(suite.T())
+}
+
 func (suite *functionTestSuite) TestPatchFunction() {
 	namespace := "some-namespace"
 
@@ -1336,6 +1431,30 @@ func (suite *functionTestSuite) sendRequestWithInvalidBody(method string, body s
 	suite.mockPlatform.AssertExpectations(suite.T())
 }
 
+func (suite *functionTestSuite) getDeployEvents(functionName string, requestHeaders map[string]string) string {
+	request, err := http.NewRequest("GET",
+		fmt.Sprintf("%s/api/functions/%s/deploy-events", suite.httpServer.URL, functionName),
+		nil)
+	suite.Require().NoError(err)
+
+	for headerKey, headerValue := range requestHeaders {
+		request.Header.Set(headerKey, headerValue)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	suite.Require().NoError(err)
+
+	defer response.Body.Close() // nolint: errcheck
+
+	suite.Require().Equal(http.StatusOK, response.StatusCode)
+	suite.Require().Equal("text/event-stream", response.Header.Get("Content-Type"))
+
+	responseBody, err := io.ReadAll(response.Body)
+	suite.Require().NoError(err)
+
+	return string(responseBody)
+}
+
 //
 // Project
 //
@@ -36,7 +36,7 @@ type Client interface {
 	CopyObjectsToContainer(containerName string, objectsToCopy map[string]string) error
 
 	// PushImage pushes a local image to a remote docker repository
-	PushImage(imageName string, registryURL string) error
+	PushImage(imageName string, registryURL string, pushOptions *PushOptions) error
 
 	// PullImage pulls an image from a remote docker repository
 	PullImage(imageURL string) error
@@ -44,6 +44,9 @@ type Client interface {
 	// RemoveImage will remove (delete) a local image
 	RemoveImage(imageName string) error
 
+	// GetImage returns a local image, or nil if it doesn't exist
+	GetImage(imageName string) (*Image, error)
+
 	// RunContainer will run a container based on an image and run options
 	RunContainer(imageName string, runOptions *RunOptions) (string, error)
 
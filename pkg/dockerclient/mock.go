@@ -54,7 +54,7 @@ func (mdc *MockDockerClient) CopyObjectsToContainer(containerName string, object
 }
 
 // PushImage pushes a local image to a remote docker repository
-func (mdc *MockDockerClient) PushImage(imageName string, registryURL string) error {
+func (mdc *MockDockerClient) PushImage(imageName string, registryURL string, pushOptions *PushOptions) error {
 	return nil
 }
 
@@ -68,6 +68,15 @@ func (mdc *MockDockerClient) RemoveImage(imageName string) error {
 	return nil
 }
 
+// GetImage returns a local image, or nil if it doesn't exist
+func (mdc *MockDockerClient) GetImage(imageName string) (*Image, error) {
+	args := mdc.Called(imageName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Image), args.Error(1)
+}
+
 // RunContainer will run a container based on an image and run options
 func (mdc *MockDockerClient) RunContainer(imageName string, runOptions *RunOptions) (string, error) {
 	return "", nil
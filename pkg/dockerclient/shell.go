@@ -114,6 +114,9 @@ func (c *ShellClient) Build(buildOptions *BuildOptions) error {
 	for buildArgName, buildArgValue := range buildOptions.BuildArgs {
 		buildArgs += fmt.Sprintf("--build-arg %s=%s ", buildArgName, buildArgValue)
 	}
+	for secretID, secretPath := range buildOptions.Secrets {
+		buildArgs += fmt.Sprintf("--secret id=%s,src=%s ", secretID, secretPath)
+	}
 
 	if err := c.build(buildOptions, buildArgs); err != nil {
 		return errors.Wrap(err, "Failed to build")
@@ -185,7 +188,7 @@ func (c *ShellClient) CopyObjectsToContainer(containerName string, objectsToCopy
 }
 
 // PushImage pushes a local image to a remote docker repository
-func (c *ShellClient) PushImage(imageName string, registryURL string) error {
+func (c *ShellClient) PushImage(imageName string, registryURL string, pushOptions *PushOptions) error {
 	taggedImage := common.CompileImageName(registryURL, imageName)
 
 	c.logger.InfoWith("Pushing image", "from", imageName, "to", taggedImage)
@@ -203,7 +206,14 @@ func (c *ShellClient) PushImage(imageName string, registryURL string) error {
 		return errors.Wrap(err, "Failed to tag image")
 	}
 
-	_, err = c.runCommand(nil, "docker push %s", taggedImage)
+	pushRunOptions := &cmdrunner.RunOptions{
+		CaptureOutputMode: cmdrunner.CaptureOutputModeStdout,
+	}
+	if pushOptions != nil {
+		pushRunOptions.OutputWriter = pushOptions.OutputWriter
+	}
+
+	_, err = c.runCommand(pushRunOptions, "docker push %s", taggedImage)
 	if err != nil {
 		return errors.Wrap(err, "Failed to push image")
 	}
@@ -235,6 +245,42 @@ func (c *ShellClient) RemoveImage(imageName string) error {
 	return err
 }
 
+// GetImage returns a local image, or nil if it doesn't exist
+func (c *ShellClient) GetImage(imageName string) (*Image, error) {
+	c.logger.DebugWith("Getting image", "imageName", imageName)
+
+	if _, err := reference.Parse(imageName); err != nil {
+		return nil, errors.Wrap(err, "Invalid image name to get")
+	}
+
+	runResult, err := c.runCommand(nil, "docker image ls --quiet %s", imageName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list images")
+	}
+
+	if strings.TrimSpace(runResult.Output) == "" {
+		return nil, nil
+	}
+
+	runResult, err = c.runCommand(nil, "docker image inspect %s", imageName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to inspect image")
+	}
+
+	var images []Image
+
+	// parse the result
+	if err := json.Unmarshal([]byte(runResult.Output), &images); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse inspect response")
+	}
+
+	if len(images) == 0 {
+		return nil, nil
+	}
+
+	return &images[0], nil
+}
+
 // RunContainer will run a container based on an image and run options
 func (c *ShellClient) RunContainer(imageName string, runOptions *RunOptions) (string, error) {
 	c.logger.DebugWith("Running container", "imageName", imageName, "runOptions", runOptions)
@@ -978,12 +1024,27 @@ func (c *ShellClient) build(buildOptions *BuildOptions, buildArgs string) error
 		pullOption = "--pull"
 	}
 
-	buildCommand := fmt.Sprintf("docker build %s --force-rm -t %s -f %s %s %s %s .",
+	// building for a platform other than the local one requires buildx
+	platformOption := ""
+	if buildOptions.Platform != "" {
+		platformOption = fmt.Sprintf("--platform %s", buildOptions.Platform)
+	}
+
+	// build secrets (RUN --mount=type=secret) and other platforms require buildkit, which isn't docker's default
+	// builder everywhere. it's only enabled when required, as docker fails every buildkit build without buildx
+	buildkitOption := ""
+	if len(buildOptions.Secrets) > 0 || buildOptions.Platform != "" {
+		buildkitOption = "DOCKER_BUILDKIT=1 "
+	}
+
+	buildCommand := fmt.Sprintf("%sdocker build %s --force-rm -t %s -f %s %s %s %s %s .",
+		buildkitOption,
 		c.resolveDockerBuildNetwork(),
 		buildOptions.Image,
 		buildOptions.DockerfilePath,
 		cacheOption,
 		pullOption,
+		platformOption,
 		buildArgs)
 
 	retryOnErrorMessages := []string{
@@ -999,11 +1060,17 @@ func (c *ShellClient) build(buildOptions *BuildOptions, buildArgs string) error
 
 		// when trying to reuse a missing nuclio-onbuild between functions
 		"^Unable to find image 'nuclio-onbuild-.*' locally",
+
+		// the same errors, as buildkit reports them after its build progress
+		"(?m)^(ERROR: )?failed to solve: .*(failed to get digest sha256:|content digest sha256:.* not found)",
+		"(?m)^(ERROR: )?failed to solve: failed to export image:",
+		"(?m)^(ERROR: )?failed to solve: nuclio-onbuild-.*",
 	}
 
 	runOptions := &cmdrunner.RunOptions{
 		CaptureOutputMode: cmdrunner.CaptureOutputModeStdout,
 		WorkingDir:        &buildOptions.ContextDir,
+		OutputWriter:      buildOptions.OutputWriter,
 	}
 
 	// retry build on predefined errors that occur during race condition and collisions between
@@ -1080,6 +1147,13 @@ func (c *ShellClient) validateBuildOptions(buildOptions *BuildOptions) error {
 			return errors.New(message)
 		}
 	}
+	for secretID, secretPath := range buildOptions.Secrets {
+		if !restrictedBuildArgRegex.MatchString(secretID) || !restrictedBuildArgRegex.MatchString(secretPath) {
+			message := "Invalid build secret supplied"
+			c.logger.WarnWith(message, "secretID", secretID)
+			return errors.New(message)
+		}
+	}
 	return nil
 }
 
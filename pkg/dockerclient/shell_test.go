@@ -206,6 +206,69 @@ func (suite *ShellClientTestSuite) TestBuildBailOnUnknownError() {
 	suite.mockedCmdRunner.AssertNumberOfCalls(suite.T(), "Run", 2)
 }
 
+func (suite *ShellClientTestSuite) TestBuildWithBuildKit() {
+	for _, testCase := range []struct {
+		name             string
+		buildOptions     BuildOptions
+		expectedBuildKit bool
+	}{
+		{name: "default", buildOptions: BuildOptions{Image: "image"}},
+		{name: "secrets", buildOptions: BuildOptions{Image: "image", Secrets: map[string]string{"pip.conf": "pip.conf"}},
+			expectedBuildKit: true},
+		{name: "platform", buildOptions: BuildOptions{Image: "image", Platform: "linux/arm64"}, expectedBuildKit: true},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.SetupTest()
+
+			var buildCommand string
+			suite.mockedCmdRunner.
+				On("Run",
+					mock.Anything,
+					mock.Anything,
+					mock.MatchedBy(func(vars []interface{}) bool {
+						buildCommand = vars[0].(string)
+						return strings.Contains(buildCommand, "docker build ")
+					})).
+				Return(cmdrunner.RunResult{}, nil).
+				Once()
+
+			err := suite.shellClient.Build(&testCase.buildOptions)
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedBuildKit, strings.HasPrefix(buildCommand, "DOCKER_BUILDKIT=1 "))
+		})
+	}
+}
+
+func (suite *ShellClientTestSuite) TestBuildRetryOnBuildKitErrors() {
+	suite.mockedCmdRunner.
+		On("Run",
+			mock.Anything,
+			mock.Anything,
+			mock.MatchedBy(func(vars []interface{}) bool {
+				command := vars[0].(string)
+				return strings.Contains(command, "docker build")
+			})).
+		Return(cmdrunner.RunResult{
+			Stderr: "#1 [internal] load build definition from Dockerfile\n" +
+				"ERROR: failed to solve: nuclio-onbuild-someid:sometag: pull access denied, repository does not exist",
+		}, errors.New("execution error")).
+		Once()
+
+	// success build
+	suite.mockedCmdRunner.
+		On("Run", mock.Anything, mock.Anything, mock.Anything).
+		Return(cmdrunner.RunResult{}, nil)
+
+	err := suite.shellClient.Build(&BuildOptions{
+		Image:   "nuclio-onbuild-someid:sometag",
+		Secrets: map[string]string{"pip.conf": "pip.conf"},
+	})
+	suite.Require().NoError(err)
+
+	// 1 for docker version + 1 failing build + 1 success build
+	suite.mockedCmdRunner.AssertNumberOfCalls(suite.T(), "Run", 3)
+}
+
 func (suite *ShellClientTestSuite) TestBuildRetryOnErrors() {
 
 	// mock failing
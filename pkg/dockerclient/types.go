@@ -18,6 +18,7 @@ package dockerclient
 
 import (
 	"encoding/json"
+	"io"
 )
 
 type RestartPolicyName string
@@ -52,6 +53,20 @@ type BuildOptions struct {
 	Pull           bool
 	BuildArgs      map[string]string
 	BuildFlags     map[string]bool
+	Platform       string
+
+	// files mounted to the RUN instructions that mount them by id, without being written to the image
+	Secrets map[string]string
+
+	// if set, the build's output is written to it as it's produced
+	OutputWriter io.Writer
+}
+
+// PushOptions are options for pushing a docker image
+type PushOptions struct {
+
+	// if set, the push's output is written to it as it's produced
+	OutputWriter io.Writer
 }
 
 // RunOptions are options for running a docker image
@@ -95,6 +110,16 @@ type GetContainerOptions struct {
 	ID      string
 }
 
+// Image contains response of Engine API:
+// GET "/images/{name:.*}/json"
+type Image struct {
+	ID          string `json:"Id"`
+	RepoTags    []string
+	RepoDigests []string
+	Created     string
+	Config      *Config
+}
+
 // Container contains response of Engine API:
 // GET "/containers/{name:.*}/json"
 type Container struct {
@@ -70,6 +70,16 @@ func (suite *ScrubberTestSuite) TestScrubBasics() {
 
 				// should not be scrubbed
 				Image: "some-image:latest",
+
+				// the password should be scrubbed
+				PackageRegistries: []PackageRegistry{
+					{
+						Kind:     PackageRegistryKindPyPI,
+						URL:      "https://pypi.example.com/simple",
+						Username: "some-user",
+						Password: "some-registry-password",
+					},
+				},
 			},
 			Triggers: map[string]Trigger{
 				"secret-trigger": {
@@ -119,6 +129,9 @@ func (suite *ScrubberTestSuite) TestScrubBasics() {
 	}
 
 	suite.Require().Equal(functionConfig.Spec.Build.Image, scrubbedFunctionConfig.Spec.Build.Image)
+	suite.Require().Contains(scrubbedFunctionConfig.Spec.Build.PackageRegistries[0].Password, ReferencePrefix)
+	suite.Require().Equal(functionConfig.Spec.Build.PackageRegistries[0].Username,
+		scrubbedFunctionConfig.Spec.Build.PackageRegistries[0].Username)
 	suite.Require().NotEqual(functionConfig.Spec.Triggers["secret-trigger"].Password,
 		scrubbedFunctionConfig.Spec.Triggers["secret-trigger"].Password)
 	suite.Require().NotEqual(functionConfig.Spec.Triggers["secret-trigger"].Attributes["password"],
@@ -377,6 +390,7 @@ func (suite *ScrubberTestSuite) getSensitiveFieldsPathsRegex() []*regexp.Regexp
 		"^/spec/build/codeentryattributes/headers/authorization$",
 		"^/spec/build/codeentryattributes/headers/x-v3io-session-key$",
 		// Path nested in an array
+		"^/spec/build/packageregistries\\[\\d+\\]/password$",
 		"^/Spec/Volumes\\[\\d+\\]/Volume/VolumeSource/FlexVolume/Options/accesskey$",
 		"^/Spec/Volumes\\[\\d+\\]/Volume/FlexVolume/Options/accesskey$",
 		// Path for any map element
@@ -87,7 +87,10 @@ type Trigger struct {
 	ExplicitAckMode                       ExplicitAckMode `json:"explicitAckMode,omitempty"`
 	WaitExplicitAckDuringRebalanceTimeout string          `json:"waitExplicitAckDuringRebalanceTimeout,omitempty"`
 
-	Batch *BatchConfiguration `json:"batch,omitempty"`
+	Batch          *BatchConfiguration `json:"batch,omitempty"`
+	AccessLog      *AccessLog          `json:"accessLog,omitempty"`
+	EventRecording *EventRecording     `json:"eventRecording,omitempty"`
+
 	// Dealer Information
 	TotalTasks        int `json:"total_tasks,omitempty"`
 	MaxTaskAllocation int `json:"max_task_allocation,omitempty"`
@@ -106,6 +109,38 @@ type BatchConfiguration struct {
 	Timeout   string    `json:"timeout,omitempty"`
 }
 
+// AccessLog configures writing an entry per handled event
+type AccessLog struct {
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Path of a file to append JSON lines to. If empty, entries are written through the function logger sinks
+	Path string `json:"path,omitempty"`
+
+	// SampleRate is the fraction of events to write entries for, between 0 and 1 (default: 1)
+	SampleRate float64 `json:"sampleRate,omitempty"`
+}
+
+// EventRecording configures recording received events to a file, so that they can later be replayed
+type EventRecording struct {
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Path of a file to append recorded events to, as JSON lines. Must be in the platform's event recordings
+	// dir, relative paths are relative to it. Defaults to a per-trigger file
+	Path string `json:"path,omitempty"`
+
+	// SampleRate is the fraction of events to record, between 0 and 1 (default: 1)
+	SampleRate float64 `json:"sampleRate,omitempty"`
+
+	// PathFilter is a regular expression that event paths must match to be recorded
+	PathFilter string `json:"pathFilter,omitempty"`
+
+	// MaxEvents stops the recording once this many events were recorded (0 - unlimited)
+	MaxEvents int `json:"maxEvents,omitempty"`
+
+	// SensitiveHeaders are scrubbed from recorded events, in addition to the default ones (e.g. Authorization)
+	SensitiveHeaders []string `json:"sensitiveHeaders,omitempty"`
+}
+
 type BatchMode string
 
 const (
@@ -349,6 +384,79 @@ const (
 	AlwaysBuild BuildMode = "alwaysBuild"
 )
 
+// SBOMFormat is the format of the software bill of materials attached to a function's image
+type SBOMFormat string
+
+const (
+	SBOMFormatSPDX      SBOMFormat = "spdx"
+	SBOMFormatCycloneDX SBOMFormat = "cyclonedx"
+)
+
+// PackageRegistryKind is the kind of packages a package registry serves
+type PackageRegistryKind string
+
+const (
+	PackageRegistryKindPyPI     PackageRegistryKind = "pypi"
+	PackageRegistryKindNPM      PackageRegistryKind = "npm"
+	PackageRegistryKindMaven    PackageRegistryKind = "maven"
+	PackageRegistryKindGoProxy  PackageRegistryKind = "goproxy"
+	PackageRegistryKindRubyGems PackageRegistryKind = "rubygems"
+	PackageRegistryKindNuGet    PackageRegistryKind = "nuget"
+)
+
+// PackageRegistry is a registry the function's dependencies are installed from during its build
+type PackageRegistry struct {
+	Kind PackageRegistryKind `json:"kind"`
+	URL  string              `json:"url"`
+
+	// the npm scope (e.g. @my-org) whose packages are installed from the registry. npm registries without a scope
+	// replace the default registry
+	Scope string `json:"scope,omitempty"`
+
+	// the go module path patterns (e.g. github.com/my-org/*) whose checksums aren't verified against the public
+	// checksum database, since it doesn't know about private modules
+	PrivateModules []string `json:"privateModules,omitempty"`
+
+	// the credentials of the registry. registries that authenticate with a token take it as the password
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// a file holding the password, such as a mounted secret. only available to the platform's package registries
+	PasswordPath string `json:"passwordPath,omitempty"`
+}
+
+// Validate validates the package registry
+func (pr *PackageRegistry) Validate() error {
+	switch pr.Kind {
+	case PackageRegistryKindPyPI,
+		PackageRegistryKindNPM,
+		PackageRegistryKindMaven,
+		PackageRegistryKindGoProxy,
+		PackageRegistryKindRubyGems,
+		PackageRegistryKindNuGet:
+	default:
+		return errors.Errorf("Unknown package registry kind %q", pr.Kind)
+	}
+
+	if !strings.HasPrefix(pr.URL, "http://") && !strings.HasPrefix(pr.URL, "https://") {
+		return errors.Errorf("Package registry URL must be an http(s) URL, got %q", pr.URL)
+	}
+
+	if pr.Scope != "" && (pr.Kind != PackageRegistryKindNPM || !strings.HasPrefix(pr.Scope, "@")) {
+		return errors.Errorf("Package registry scope %q must be an npm scope (e.g. @my-org)", pr.Scope)
+	}
+
+	if len(pr.PrivateModules) > 0 && pr.Kind != PackageRegistryKindGoProxy {
+		return errors.New("Private modules are only supported by goproxy package registries")
+	}
+
+	if pr.Password != "" && pr.PasswordPath != "" {
+		return errors.New("Package registry password and password path are mutually exclusive")
+	}
+
+	return nil
+}
+
 // Build holds all configuration parameters related to building a function
 type Build struct {
 	Path                  string                 `json:"path,omitempty"`
@@ -378,6 +486,32 @@ type Build struct {
 	Args                  map[string]string      `json:"args,omitempty"`
 	Flags                 []string               `json:"flags,omitempty"`
 	BuilderServiceAccount string                 `json:"builderServiceAccount,omitempty"`
+
+	// attach a software bill of materials in this format to the built image
+	SBOMFormat SBOMFormat `json:"sbomFormat,omitempty"`
+
+	// attach a signature to the built image
+	Sign bool `json:"sign,omitempty"`
+
+	// the platforms to build the image for (e.g. linux/amd64, linux/arm64). when set, the image is a
+	// multi-platform image index, and the function is scheduled on nodes of these architectures
+	Platforms []string `json:"platforms,omitempty"`
+
+	// the registries the function's dependencies are installed from, in addition to the platform's
+	PackageRegistries []PackageRegistry `json:"packageRegistries,omitempty"`
+}
+
+// GetPlatformArchitectures returns the architectures of the platforms the image is built for
+func (b *Build) GetPlatformArchitectures() []string {
+	var architectures []string
+	for _, platform := range b.Platforms {
+		architecture := strings.Split(platform, "/")[1:]
+		if len(architecture) > 0 && !common.StringSliceContainsString(architectures, architecture[0]) {
+			architectures = append(architectures, architecture[0])
+		}
+	}
+
+	return architectures
 }
 
 // Spec holds all parameters related to a function's configuration
@@ -414,6 +548,7 @@ type Spec struct {
 	SecurityContext         *v1.PodSecurityContext  `json:"securityContext,omitempty"`
 	ServiceAccount          string                  `json:"serviceAccount,omitempty"`
 	ScaleToZero             *ScaleToZeroSpec        `json:"scaleToZero,omitempty"`
+	Lifecycle               *Lifecycle              `json:"lifecycle,omitempty"`
 
 	// If set to nil, the value is taken from the platform configuration. When set explicitly in function config, it has a priority
 	DisableDefaultHTTPTrigger *bool `json:"disableDefaultHTTPTrigger,omitempty"`
@@ -492,6 +627,74 @@ const (
 	RunOnPreemptibleNodesNone RunOnPreemptibleNodeMode = "none"
 )
 
+// Lifecycle configures what every worker goes through between starting and stopping
+type Lifecycle struct {
+
+	// how long a worker's runtime may take to start, including the handler's context initialization
+	InitTimeout string `json:"initTimeout,omitempty"`
+
+	// synthetic events every worker handles once it's initialized, before the processor reports ready
+	Warmup *Warmup `json:"warmup,omitempty"`
+
+	// how long the handler's drain and termination callbacks may take. overrides the triggers'
+	// workerTerminationTimeout
+	ShutdownTimeout string `json:"shutdownTimeout,omitempty"`
+}
+
+type Warmup struct {
+	Events []WarmupEvent `json:"events,omitempty"`
+
+	// how long a worker may take to handle all warmup events
+	Timeout string `json:"timeout,omitempty"`
+
+	// fail the worker if a warmup event fails, rather than only logging it
+	FailOnError bool `json:"failOnError,omitempty"`
+}
+
+type WarmupEvent struct {
+	Method      string            `json:"method,omitempty"`
+	Path        string            `json:"path,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body,omitempty"`
+}
+
+// GetInitTimeout returns the init timeout, or zero if workers may take as long as they need to start
+func (l *Lifecycle) GetInitTimeout() (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	return parseLifecycleTimeout("initTimeout", l.InitTimeout)
+}
+
+// GetShutdownTimeout returns the shutdown timeout, or zero if the triggers' termination timeout applies
+func (l *Lifecycle) GetShutdownTimeout() (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	return parseLifecycleTimeout("shutdownTimeout", l.ShutdownTimeout)
+}
+
+// GetTimeout returns the warmup timeout, or zero if warmup may take as long as it needs
+func (w *Warmup) GetTimeout() (time.Duration, error) {
+	return parseLifecycleTimeout("warmup.timeout", w.Timeout)
+}
+
+func parseLifecycleTimeout(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err == nil && timeout <= 0 {
+		err = fmt.Errorf("%s <= 0 (%s)", name, timeout)
+	}
+
+	return timeout, err
+}
+
 type ScaleToZeroSpec struct {
 	ScaleResources []ScaleResource `json:"scaleResources,omitempty"`
 }
@@ -937,12 +1140,38 @@ type Status struct {
 
 	// node selector from function config enriched with project's and platform node selectors
 	EnrichedNodeSelector map[string]string `json:"enrichedNodeSelector,omitempty"`
+
+	// the SBOM and signature attached to the function's image when it was built
+	ImageAttestation *ImageAttestation `json:"imageAttestation,omitempty"`
+
+	// the SHA of the git commit the function's image was built from
+	SourceCommit string `json:"sourceCommit,omitempty"`
 }
 
 func (s *Status) InvocationURLs() []string {
 	return append(s.InternalInvocationURLs, s.ExternalInvocationURLs...)
 }
 
+// ImageAttestation describes the software bill of materials and signature attached to an image in its registry.
+// a multi-platform image has no SBOM of its own, which is attached to each of its platform images instead
+type ImageAttestation struct {
+	Image          string                     `json:"image,omitempty"`
+	Digest         string                     `json:"digest,omitempty"`
+	SBOMFormat     SBOMFormat                 `json:"sbomFormat,omitempty"`
+	SBOMImage      string                     `json:"sbomImage,omitempty"`
+	SignatureImage string                     `json:"signatureImage,omitempty"`
+	Platforms      []PlatformImageAttestation `json:"platforms,omitempty"`
+}
+
+// PlatformImageAttestation describes the software bill of materials and signature attached to the image of
+// one of the platforms of a multi-platform image
+type PlatformImageAttestation struct {
+	Platform       string `json:"platform,omitempty"`
+	Digest         string `json:"digest,omitempty"`
+	SBOMImage      string `json:"sbomImage,omitempty"`
+	SignatureImage string `json:"signatureImage,omitempty"`
+}
+
 type ScaleToZeroStatus struct {
 	LastScaleEvent     scalertypes.ScaleEvent `json:"lastScaleEvent,omitempty"`
 	LastScaleEventTime *time.Time             `json:"lastScaleEventTime,omitempty"`
@@ -25,6 +25,16 @@ import (
 	"github.com/nuclio/zap"
 )
 
+// labeledLogger is implemented by loggers which can attach labels to their entries
+type labeledLogger interface {
+	WithLabels(labels map[string]string) logger.Logger
+}
+
+// closableLogger is implemented by loggers which buffer entries and must push them before exiting
+type closableLogger interface {
+	Close()
+}
+
 // CreateSystemLogger returns the system loggers
 func CreateSystemLogger(name string, platformConfiguration *platformconfig.Config) (logger.Logger, error) {
 
@@ -51,6 +61,38 @@ func CreateFunctionLogger(name string,
 	return createLoggers(name, functionLoggerSinksByName)
 }
 
+// WithLabels returns a logger that attaches the given labels to entries shipped to sinks that support labels
+// (e.g. loki, otlp). Other sinks are left untouched
+func WithLabels(parentLogger logger.Logger, labels map[string]string) logger.Logger {
+	switch typedLogger := parentLogger.(type) {
+	case labeledLogger:
+		return typedLogger.WithLabels(labels)
+	case *nucliozap.MuxLogger:
+		var loggers []logger.Logger
+		for _, loggerInstance := range typedLogger.GetLoggers() {
+			loggers = append(loggers, WithLabels(loggerInstance, labels))
+		}
+
+		muxLogger, _ := nucliozap.NewMuxLogger(loggers...)
+		return muxLogger
+	default:
+		return parentLogger
+	}
+}
+
+// Close pushes whatever the given logger's sinks buffer and stops them. Sinks that don't buffer are
+// left untouched
+func Close(loggerInstance logger.Logger) {
+	switch typedLogger := loggerInstance.(type) {
+	case closableLogger:
+		typedLogger.Close()
+	case *nucliozap.MuxLogger:
+		for _, childLogger := range typedLogger.GetLoggers() {
+			Close(childLogger)
+		}
+	}
+}
+
 // createLoggers returns the processor logger and the function logger. For now, they are one of the same
 func createLoggers(name string,
 	loggerSinksWithLevel map[string]platformconfig.LoggerSinkWithLevel) (logger.Logger, error) {
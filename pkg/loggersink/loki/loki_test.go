@@ -0,0 +1,221 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loki
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/loggersink"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/logger"
+	"github.com/stretchr/testify/suite"
+)
+
+type LokiTestSuite struct {
+	suite.Suite
+	server          *httptest.Server
+	lock            sync.Mutex
+	pushRequests    []pushRequest
+	receivedHeaders http.Header
+}
+
+func (suite *LokiTestSuite) SetupTest() {
+	suite.pushRequests = nil
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.lock.Lock()
+		defer suite.lock.Unlock()
+
+		suite.Require().Equal(pushPath, r.URL.Path)
+
+		request := pushRequest{}
+		suite.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+
+		suite.pushRequests = append(suite.pushRequests, request)
+		suite.receivedHeaders = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func (suite *LokiTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *LokiTestSuite) TestFunctionLoggerPush() {
+	platformConfiguration := &platformconfig.Config{
+		Logger: platformconfig.Logger{
+			Sinks: map[string]platformconfig.LoggerSink{
+				"myLoki": {
+					Kind: platformconfig.LoggerSinkKindLoki,
+					URL:  suite.server.URL,
+					Attributes: map[string]interface{}{
+						"tenantID": "my-tenant",
+						"labels": map[string]string{
+							"cluster": "my-cluster",
+						},
+					},
+				},
+			},
+			Functions: []platformconfig.LoggerSinkBinding{
+				{Level: "info", Sink: "myLoki"},
+			},
+		},
+	}
+
+	functionConfiguration := &functionconfig.Config{
+		Meta: functionconfig.Meta{
+			Name:      "my-function",
+			Namespace: "my-namespace",
+			Labels: map[string]string{
+				common.NuclioResourceLabelKeyProjectName: "my-project",
+			},
+		},
+	}
+
+	functionLogger, err := loggersink.CreateFunctionLogger("processor", functionConfiguration, platformConfiguration)
+	suite.Require().NoError(err)
+
+	triggerLogger := loggersink.WithLabels(functionLogger, map[string]string{
+		"trigger":      "my-trigger",
+		"trigger_kind": "http",
+	})
+
+	// debug is below the bound level and should be filtered out
+	functionLogger.Debug("Filtered out")
+	functionLogger.InfoWith("Processor info", "key", "value")
+	triggerLogger.Warn("Trigger %s warning", "my-trigger")
+	functionLogger.Flush()
+
+	suite.lock.Lock()
+	defer suite.lock.Unlock()
+
+	suite.Require().Equal("my-tenant", suite.receivedHeaders.Get("X-Scope-OrgID"))
+
+	streamsByTrigger := map[string]*stream{}
+	for _, request := range suite.pushRequests {
+		for _, requestStream := range request.Streams {
+			requestStream := requestStream
+			streamsByTrigger[requestStream.Stream["trigger"]] = requestStream
+		}
+	}
+
+	suite.Require().Len(streamsByTrigger, 2)
+
+	processorStream := streamsByTrigger[""]
+	suite.Require().Equal(map[string]string{
+		"level":     "info",
+		"function":  "my-function",
+		"namespace": "my-namespace",
+		"project":   "my-project",
+		"cluster":   "my-cluster",
+	}, processorStream.Stream)
+	suite.Require().Len(processorStream.Values, 1)
+
+	line := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal([]byte(processorStream.Values[0][1]), &line))
+	suite.Require().Equal("Processor info", line["message"])
+	suite.Require().Equal(map[string]interface{}{"key": "value"}, line["with"])
+
+	triggerStream := streamsByTrigger["my-trigger"]
+	suite.Require().Equal("warn", triggerStream.Stream["level"])
+	suite.Require().Equal("http", triggerStream.Stream["trigger_kind"])
+	suite.Require().Equal("my-function", triggerStream.Stream["function"])
+	suite.Require().Len(triggerStream.Values, 1)
+	suite.Require().Contains(triggerStream.Values[0][1], "Trigger my-trigger warning")
+}
+
+func (suite *LokiTestSuite) TestVarsEncodedOnEmit() {
+	functionLogger := suite.createFunctionLogger()
+
+	// the caller is free to modify its vars once the log call returns
+	mutableVar := map[string]string{"key": "before"}
+	functionLogger.InfoWith("Mutated", "var", mutableVar)
+	mutableVar["key"] = "after"
+
+	// a var which can't be encoded should not take the rest of the batch with it
+	functionLogger.InfoWith("Unencodable", "channel", make(chan int))
+
+	// closing pushes whatever is still buffered
+	loggersink.Close(functionLogger)
+
+	suite.lock.Lock()
+	defer suite.lock.Unlock()
+
+	var lines []map[string]interface{}
+	for _, request := range suite.pushRequests {
+		for _, requestStream := range request.Streams {
+			for _, value := range requestStream.Values {
+				line := map[string]interface{}{}
+				suite.Require().NoError(json.Unmarshal([]byte(value[1]), &line))
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	suite.Require().Len(lines, 2)
+	suite.Require().Equal(map[string]interface{}{"var": map[string]interface{}{"key": "before"}}, lines[0]["with"])
+	suite.Require().Equal("Unencodable", lines[1]["message"])
+	suite.Require().IsType("", lines[1]["with"].(map[string]interface{})["channel"])
+
+	// entries emitted after close are dropped
+	functionLogger.Info("Dropped")
+	suite.Require().Len(suite.pushRequests, 1)
+}
+
+func (suite *LokiTestSuite) TestMissingURL() {
+	_, err := NewConfiguration("processor", &platformconfig.LoggerSinkWithLevel{
+		Sink: platformconfig.LoggerSink{
+			Kind: platformconfig.LoggerSinkKindLoki,
+		},
+	})
+	suite.Require().Error(err)
+}
+
+func (suite *LokiTestSuite) createFunctionLogger() logger.Logger {
+	platformConfiguration := &platformconfig.Config{
+		Logger: platformconfig.Logger{
+			Sinks: map[string]platformconfig.LoggerSink{
+				"myLoki": {
+					Kind: platformconfig.LoggerSinkKindLoki,
+					URL:  suite.server.URL,
+				},
+			},
+			Functions: []platformconfig.LoggerSinkBinding{
+				{Level: "info", Sink: "myLoki"},
+			},
+		},
+	}
+
+	functionLogger, err := loggersink.CreateFunctionLogger("processor",
+		&functionconfig.Config{Meta: functionconfig.Meta{Name: "my-function"}},
+		platformConfiguration)
+	suite.Require().NoError(err)
+
+	return functionLogger
+}
+
+func TestLokiTestSuite(t *testing.T) {
+	suite.Run(t, new(LokiTestSuite))
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loki
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/loggersink/remote"
+
+	"github.com/nuclio/errors"
+)
+
+const pushPath = "/loki/api/v1/push"
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type pushRequest struct {
+	Streams []*stream `json:"streams"`
+}
+
+type pusher struct {
+	configuration *Configuration
+	poster        *remote.HTTPPoster
+	headers       map[string]string
+}
+
+func newPusher(configuration *Configuration) *pusher {
+	headers := map[string]string{}
+	if configuration.TenantID != "" {
+		headers["X-Scope-OrgID"] = configuration.TenantID
+	}
+
+	return &pusher{
+		configuration: configuration,
+		poster:        remote.NewHTTPPoster(&configuration.Configuration),
+		headers:       headers,
+	}
+}
+
+// Push groups the entries into streams by their label set and sends them to loki
+func (p *pusher) Push(entries []*remote.Entry) error {
+	streamsByKey := map[string]*stream{}
+	request := pushRequest{}
+
+	for _, entry := range entries {
+		streamLabels := p.getStreamLabels(entry)
+		streamKey := getStreamKey(streamLabels)
+
+		entryStream, streamFound := streamsByKey[streamKey]
+		if !streamFound {
+			entryStream = &stream{Stream: streamLabels}
+			streamsByKey[streamKey] = entryStream
+			request.Streams = append(request.Streams, entryStream)
+		}
+
+		line, err := encodeLine(entry)
+		if err != nil {
+			return errors.Wrap(err, "Failed to encode log line")
+		}
+
+		entryStream.Values = append(entryStream.Values, [2]string{
+			strconv.FormatInt(entry.Time.UnixNano(), 10),
+			line,
+		})
+	}
+
+	return p.poster.PostJSON(p.configuration.Sink.URL, &request, p.headers)
+}
+
+func (p *pusher) getStreamLabels(entry *remote.Entry) map[string]string {
+	streamLabels := map[string]string{
+		"level": entry.Level,
+	}
+
+	for labelKey, labelValue := range p.configuration.Labels {
+		streamLabels[labelKey] = labelValue
+	}
+
+	for labelKey, labelValue := range entry.Labels {
+		streamLabels[labelKey] = labelValue
+	}
+
+	return streamLabels
+}
+
+func getStreamKey(streamLabels map[string]string) string {
+	var labelPairs []string
+	for labelKey, labelValue := range streamLabels {
+		labelPairs = append(labelPairs, labelKey+"="+labelValue)
+	}
+
+	sort.Strings(labelPairs)
+
+	return strings.Join(labelPairs, ",")
+}
+
+func encodeLine(entry *remote.Entry) (string, error) {
+	line := map[string]interface{}{
+		"name":    entry.Name,
+		"message": entry.Message,
+	}
+
+	if len(entry.Vars) > 0 {
+		line["with"] = entry.Vars
+	}
+
+	encodedLine, err := json.Marshal(line)
+	if err != nil {
+		return "", err
+	}
+
+	return string(encodedLine), nil
+}
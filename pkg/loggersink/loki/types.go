@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loki
+
+import (
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/loggersink"
+	"github.com/nuclio/nuclio/pkg/loggersink/remote"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+type Configuration struct {
+	remote.Configuration `mapstructure:",squash"`
+	TenantID             string
+}
+
+func NewConfiguration(name string, loggerSinkConfiguration *platformconfig.LoggerSinkWithLevel) (*Configuration, error) {
+	newConfiguration := Configuration{}
+
+	// create base
+	newConfiguration.Configuration.Configuration = *loggersink.NewConfiguration(name, loggerSinkConfiguration)
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if err := newConfiguration.Populate(); err != nil {
+		return nil, errors.Wrap(err, "Failed to populate configuration")
+	}
+
+	if newConfiguration.Sink.URL == "" {
+		return nil, errors.New("URL is required for Loki logger sink")
+	}
+
+	// allow passing the base URL of the loki server
+	if !strings.HasSuffix(newConfiguration.Sink.URL, pushPath) {
+		newConfiguration.Sink.URL = strings.TrimSuffix(newConfiguration.Sink.URL, "/") + pushPath
+	}
+
+	return &newConfiguration, nil
+}
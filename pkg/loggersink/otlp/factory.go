@@ -0,0 +1,44 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"github.com/nuclio/nuclio/pkg/loggersink"
+	"github.com/nuclio/nuclio/pkg/loggersink/remote"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+type factory struct{}
+
+func (f *factory) Create(name string,
+	loggerSinkConfiguration *platformconfig.LoggerSinkWithLevel) (logger.Logger, error) {
+
+	configuration, err := NewConfiguration(name, loggerSinkConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create otlp configuration")
+	}
+
+	return remote.NewLogger(name, &configuration.Configuration, newPusher(configuration)), nil
+}
+
+// register factory
+func init() {
+	loggersink.RegistrySingleton.Register(string(platformconfig.LoggerSinkKindOTLP), &factory{})
+}
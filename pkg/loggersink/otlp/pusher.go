@@ -0,0 +1,158 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/nuclio/nuclio/pkg/loggersink/remote"
+)
+
+// OTLP/HTTP with JSON encoding, see https://opentelemetry.io/docs/specs/otlp/#otlphttp
+const logsPath = "/v1/logs"
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type logRecord struct {
+	TimeUnixNano   string     `json:"timeUnixNano"`
+	SeverityNumber int        `json:"severityNumber"`
+	SeverityText   string     `json:"severityText"`
+	Body           anyValue   `json:"body"`
+	Attributes     []keyValue `json:"attributes,omitempty"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type scopeLogs struct {
+	Scope      scope        `json:"scope"`
+	LogRecords []*logRecord `json:"logRecords"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type resourceLogs struct {
+	Resource  resource     `json:"resource"`
+	ScopeLogs []*scopeLogs `json:"scopeLogs"`
+}
+
+type exportLogsServiceRequest struct {
+	ResourceLogs []*resourceLogs `json:"resourceLogs"`
+}
+
+type pusher struct {
+	configuration *Configuration
+	poster        *remote.HTTPPoster
+	resource      resource
+}
+
+func newPusher(configuration *Configuration) *pusher {
+	resourceAttributes := map[string]string{
+		"service.name": configuration.ServiceName,
+	}
+
+	for labelKey, labelValue := range configuration.Labels {
+		resourceAttributes["nuclio."+labelKey] = labelValue
+	}
+
+	return &pusher{
+		configuration: configuration,
+		poster:        remote.NewHTTPPoster(&configuration.Configuration),
+		resource:      resource{Attributes: mapToKeyValues(resourceAttributes)},
+	}
+}
+
+// Push sends the entries as a single resource to the collector
+func (p *pusher) Push(entries []*remote.Entry) error {
+	entriesScopeLogs := &scopeLogs{
+		Scope: scope{Name: "nuclio"},
+	}
+
+	for _, entry := range entries {
+		recordAttributes := map[string]string{
+			"logger.name": entry.Name,
+		}
+
+		for labelKey, labelValue := range entry.Labels {
+			recordAttributes["nuclio."+labelKey] = labelValue
+		}
+
+		for varKey := range entry.Vars {
+			recordAttributes[varKey] = entry.GetVarString(varKey)
+		}
+
+		entriesScopeLogs.LogRecords = append(entriesScopeLogs.LogRecords, &logRecord{
+			TimeUnixNano:   strconv.FormatInt(entry.Time.UnixNano(), 10),
+			SeverityNumber: levelToSeverityNumber(entry.Level),
+			SeverityText:   entry.Level,
+			Body:           anyValue{StringValue: entry.Message},
+			Attributes:     mapToKeyValues(recordAttributes),
+		})
+	}
+
+	request := exportLogsServiceRequest{
+		ResourceLogs: []*resourceLogs{
+			{
+				Resource:  p.resource,
+				ScopeLogs: []*scopeLogs{entriesScopeLogs},
+			},
+		},
+	}
+
+	return p.poster.PostJSON(p.configuration.Sink.URL, &request, nil)
+}
+
+func mapToKeyValues(values map[string]string) []keyValue {
+	var keyValues []keyValue
+	for key, value := range values {
+		keyValues = append(keyValues, keyValue{
+			Key:   key,
+			Value: anyValue{StringValue: value},
+		})
+	}
+
+	// keep the encoding stable
+	sort.Slice(keyValues, func(i, j int) bool {
+		return keyValues[i].Key < keyValues[j].Key
+	})
+
+	return keyValues
+}
+
+func levelToSeverityNumber(level string) int {
+	switch level {
+	case "error":
+		return 17
+	case "warn":
+		return 13
+	case "info":
+		return 9
+	default:
+		return 5
+	}
+}
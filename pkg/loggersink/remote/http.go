@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/nuclio/errors"
+)
+
+// HTTPPoster posts JSON payloads to a remote logging backend
+type HTTPPoster struct {
+	configuration *Configuration
+	client        *http.Client
+}
+
+func NewHTTPPoster(configuration *Configuration) *HTTPPoster {
+	return &HTTPPoster{
+		configuration: configuration,
+		client: &http.Client{
+			Timeout: configuration.GetPushTimeout(),
+		},
+	}
+}
+
+// PostJSON encodes the body as JSON and posts it to the given URL
+func (p *HTTPPoster) PostJSON(url string, body interface{}, headers map[string]string) error {
+	encodedBody, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode body")
+	}
+
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(encodedBody))
+	if err != nil {
+		return errors.Wrap(err, "Failed to create request")
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	for headerKey, headerValue := range p.configuration.Headers {
+		request.Header.Set(headerKey, headerValue)
+	}
+
+	for headerKey, headerValue := range headers {
+		request.Header.Set(headerKey, headerValue)
+	}
+
+	if p.configuration.Username != "" {
+		request.SetBasicAuth(p.configuration.Username, p.configuration.Password)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send request")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode >= http.StatusMultipleChoices {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return errors.Errorf("Got unexpected status code %d: %s", response.StatusCode, string(responseBody))
+	}
+
+	return nil
+}
@@ -0,0 +1,358 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nuclio/logger"
+)
+
+// Logger is a logger.Logger that ships entries to a remote backend through a pusher. Entries are
+// queued on a bounded buffer and pushed in batches from a background goroutine - if the buffer is
+// full, entries are dropped rather than blocking the caller
+type Logger struct {
+	name    string
+	level   logger.Level
+	labels  map[string]string
+	shipper *shipper
+}
+
+type shipper struct {
+	configuration  *Configuration
+	pusher         Pusher
+	entries        chan *Entry
+	flushRequests  chan chan struct{}
+	stop           chan struct{}
+	stopped        chan struct{}
+	stopOnce       sync.Once
+	droppedEntries uint64
+}
+
+// NewLogger creates a remote logger and starts shipping in the background
+func NewLogger(name string, configuration *Configuration, pusher Pusher) *Logger {
+	newShipper := &shipper{
+		configuration: configuration,
+		pusher:        pusher,
+		entries:       make(chan *Entry, configuration.BufferSize),
+		flushRequests: make(chan chan struct{}),
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+
+	go newShipper.ship()
+
+	return &Logger{
+		name:    name,
+		level:   configuration.Level,
+		shipper: newShipper,
+	}
+}
+
+// Error emits an unstructured error log
+func (l *Logger) Error(format interface{}, vars ...interface{}) {
+	l.emitUnstructured(context.Background(), logger.LevelError, format, vars)
+}
+
+// Warn emits an unstructured warning log
+func (l *Logger) Warn(format interface{}, vars ...interface{}) {
+	l.emitUnstructured(context.Background(), logger.LevelWarn, format, vars)
+}
+
+// Info emits an unstructured informational log
+func (l *Logger) Info(format interface{}, vars ...interface{}) {
+	l.emitUnstructured(context.Background(), logger.LevelInfo, format, vars)
+}
+
+// Debug emits an unstructured debug log
+func (l *Logger) Debug(format interface{}, vars ...interface{}) {
+	l.emitUnstructured(context.Background(), logger.LevelDebug, format, vars)
+}
+
+// ErrorCtx emits an unstructured error log with context
+func (l *Logger) ErrorCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.emitUnstructured(ctx, logger.LevelError, format, vars)
+}
+
+// WarnCtx emits an unstructured warning log with context
+func (l *Logger) WarnCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.emitUnstructured(ctx, logger.LevelWarn, format, vars)
+}
+
+// InfoCtx emits an unstructured informational log with context
+func (l *Logger) InfoCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.emitUnstructured(ctx, logger.LevelInfo, format, vars)
+}
+
+// DebugCtx emits an unstructured debug log with context
+func (l *Logger) DebugCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.emitUnstructured(ctx, logger.LevelDebug, format, vars)
+}
+
+// ErrorWith emits a structured error log
+func (l *Logger) ErrorWith(format interface{}, vars ...interface{}) {
+	l.emitStructured(context.Background(), logger.LevelError, format, vars)
+}
+
+// WarnWith emits a structured warning log
+func (l *Logger) WarnWith(format interface{}, vars ...interface{}) {
+	l.emitStructured(context.Background(), logger.LevelWarn, format, vars)
+}
+
+// InfoWith emits a structured info log
+func (l *Logger) InfoWith(format interface{}, vars ...interface{}) {
+	l.emitStructured(context.Background(), logger.LevelInfo, format, vars)
+}
+
+// DebugWith emits a structured debug log
+func (l *Logger) DebugWith(format interface{}, vars ...interface{}) {
+	l.emitStructured(context.Background(), logger.LevelDebug, format, vars)
+}
+
+// ErrorWithCtx emits a structured error log with context
+func (l *Logger) ErrorWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.emitStructured(ctx, logger.LevelError, format, vars)
+}
+
+// WarnWithCtx emits a structured warning log with context
+func (l *Logger) WarnWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.emitStructured(ctx, logger.LevelWarn, format, vars)
+}
+
+// InfoWithCtx emits a structured info log with context
+func (l *Logger) InfoWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.emitStructured(ctx, logger.LevelInfo, format, vars)
+}
+
+// DebugWithCtx emits a structured debug log with context
+func (l *Logger) DebugWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.emitStructured(ctx, logger.LevelDebug, format, vars)
+}
+
+// Flush pushes whatever is buffered, waiting at most the push timeout
+func (l *Logger) Flush() {
+	flushed := make(chan struct{})
+
+	select {
+	case l.shipper.flushRequests <- flushed:
+	case <-l.shipper.stopped:
+		return
+	case <-time.After(l.shipper.configuration.GetPushTimeout()):
+		return
+	}
+
+	select {
+	case <-flushed:
+	case <-time.After(l.shipper.configuration.GetPushTimeout()):
+	}
+}
+
+// Close pushes whatever is buffered and stops shipping. Entries emitted afterwards are dropped. Since
+// children share the parent's shipper, closing any of them closes them all
+func (l *Logger) Close() {
+	l.shipper.stopOnce.Do(func() {
+		close(l.shipper.stop)
+	})
+
+	select {
+	case <-l.shipper.stopped:
+	case <-time.After(l.shipper.configuration.GetPushTimeout()):
+	}
+}
+
+// GetChild returns a child logger, sharing the parent's shipper
+func (l *Logger) GetChild(name string) logger.Logger {
+	return &Logger{
+		name:    l.name + "." + name,
+		level:   l.level,
+		labels:  l.labels,
+		shipper: l.shipper,
+	}
+}
+
+// WithLabels returns a logger whose entries carry the given labels on top of the existing ones
+func (l *Logger) WithLabels(labels map[string]string) logger.Logger {
+	mergedLabels := map[string]string{}
+	for labelKey, labelValue := range l.labels {
+		mergedLabels[labelKey] = labelValue
+	}
+
+	for labelKey, labelValue := range labels {
+		mergedLabels[labelKey] = labelValue
+	}
+
+	return &Logger{
+		name:    l.name,
+		level:   l.level,
+		labels:  mergedLabels,
+		shipper: l.shipper,
+	}
+}
+
+// GetDroppedEntries returns the number of entries dropped due to a full buffer
+func (l *Logger) GetDroppedEntries() uint64 {
+	return atomic.LoadUint64(&l.shipper.droppedEntries)
+}
+
+func (l *Logger) emitUnstructured(ctx context.Context, level logger.Level, format interface{}, vars []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	message := fmt.Sprint(format)
+	if len(vars) > 0 {
+		message = fmt.Sprintf(message, vars...)
+	}
+
+	l.enqueue(ctx, level, message, nil)
+}
+
+func (l *Logger) emitStructured(ctx context.Context, level logger.Level, format interface{}, vars []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	entryVars := map[string]json.RawMessage{}
+	for varIndex := 0; varIndex+1 < len(vars); varIndex += 2 {
+		entryVars[fmt.Sprint(vars[varIndex])] = encodeVar(vars[varIndex+1])
+	}
+
+	l.enqueue(ctx, level, fmt.Sprint(format), entryVars)
+}
+
+func (l *Logger) enqueue(ctx context.Context, level logger.Level, message string, vars map[string]json.RawMessage) {
+	if ctx != nil {
+		if requestID := ctx.Value("RequestID"); requestID != nil && requestID != "" {
+			if vars == nil {
+				vars = map[string]json.RawMessage{}
+			}
+
+			vars["requestID"] = encodeVar(requestID)
+		}
+	}
+
+	entry := &Entry{
+		Time:    time.Now(),
+		Level:   levelToString(level),
+		Name:    l.name,
+		Message: message,
+		Vars:    vars,
+		Labels:  l.labels,
+	}
+
+	// never block the caller - if the shipper can't keep up (or was closed), drop the entry
+	select {
+	case <-l.shipper.stop:
+		atomic.AddUint64(&l.shipper.droppedEntries, 1)
+	case l.shipper.entries <- entry:
+	default:
+		atomic.AddUint64(&l.shipper.droppedEntries, 1)
+	}
+}
+
+func (s *shipper) ship() {
+	batch := make([]*Entry, 0, s.configuration.MaxBatchSize)
+	ticker := time.NewTicker(s.configuration.parsedMaxBatchInterval)
+	defer ticker.Stop()
+	defer close(s.stopped)
+
+	for {
+		select {
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+			if len(batch) >= s.configuration.MaxBatchSize {
+				batch = s.push(batch)
+			}
+
+		case <-ticker.C:
+			batch = s.push(batch)
+
+		case flushed := <-s.flushRequests:
+			batch = s.push(s.drain(batch))
+			close(flushed)
+
+		case <-s.stop:
+			s.push(s.drain(batch))
+			return
+		}
+	}
+}
+
+// drain appends whatever is already queued to the batch
+func (s *shipper) drain(batch []*Entry) []*Entry {
+	for {
+		select {
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+		default:
+			return batch
+		}
+	}
+}
+
+func (s *shipper) push(batch []*Entry) []*Entry {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	// we can't log through ourselves, so report to stderr. this happens at most once per batch
+	if err := s.pusher.Push(batch); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to push %d log entries to %s logger sink: %s\n", // nolint: errcheck
+			len(batch),
+			s.configuration.Name,
+			err.Error())
+	}
+
+	return make([]*Entry, 0, s.configuration.MaxBatchSize)
+}
+
+// encodeVar encodes a var on the caller's goroutine, so that the caller is free to modify it once the
+// log call returns. Vars that can't be encoded are shipped as their string representation rather than
+// failing the whole batch
+func encodeVar(value interface{}) json.RawMessage {
+
+	// errors don't encode into anything meaningful
+	if valueError, isError := value.(error); isError && valueError != nil {
+		value = valueError.Error()
+	}
+
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		encodedValue, _ = json.Marshal(fmt.Sprint(value))
+	}
+
+	return encodedValue
+}
+
+func levelToString(level logger.Level) string {
+	switch level {
+	case logger.LevelError:
+		return "error"
+	case logger.LevelWarn:
+		return "warn"
+	case logger.LevelInfo:
+		return "info"
+	default:
+		return "debug"
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/loggersink"
+
+	"github.com/nuclio/errors"
+)
+
+const (
+	DefaultMaxBatchSize     = 512
+	DefaultMaxBatchInterval = "1s"
+	DefaultBufferSize       = 8192
+	DefaultPushTimeout      = "5s"
+)
+
+// Entry is a single log record, as it is handed to a pusher
+type Entry struct {
+	Time    time.Time
+	Level   string
+	Name    string
+	Message string
+	Vars    map[string]json.RawMessage
+	Labels  map[string]string
+}
+
+// GetVarString returns a var as a string - strings as they are, anything else as its JSON encoding
+func (e *Entry) GetVarString(key string) string {
+	var stringValue string
+	if err := json.Unmarshal(e.Vars[key], &stringValue); err == nil {
+		return stringValue
+	}
+
+	return string(e.Vars[key])
+}
+
+// Pusher ships a batch of entries to a remote backend
+type Pusher interface {
+
+	// Push sends the given entries. Called from a single goroutine
+	Push(entries []*Entry) error
+}
+
+// Configuration holds the attributes common to all remote logger sinks
+type Configuration struct {
+	loggersink.Configuration
+	MaxBatchSize     int
+	MaxBatchInterval string
+	BufferSize       int
+	PushTimeout      string
+	Labels           map[string]string
+	Headers          map[string]string
+	Username         string
+	Password         string
+
+	parsedMaxBatchInterval time.Duration
+	parsedPushTimeout      time.Duration
+}
+
+// Populate enriches and validates the configuration. Attributes are expected to already be decoded
+func (c *Configuration) Populate() error {
+	var err error
+
+	if c.MaxBatchSize == 0 {
+		c.MaxBatchSize = DefaultMaxBatchSize
+	}
+
+	if c.BufferSize == 0 {
+		c.BufferSize = DefaultBufferSize
+	}
+
+	if c.MaxBatchInterval == "" {
+		c.MaxBatchInterval = DefaultMaxBatchInterval
+	}
+
+	if c.PushTimeout == "" {
+		c.PushTimeout = DefaultPushTimeout
+	}
+
+	if c.parsedMaxBatchInterval, err = time.ParseDuration(c.MaxBatchInterval); err != nil {
+		return errors.Wrap(err, "Failed to parse max batch interval")
+	}
+
+	if c.parsedPushTimeout, err = time.ParseDuration(c.PushTimeout); err != nil {
+		return errors.Wrap(err, "Failed to parse push timeout")
+	}
+
+	if c.Labels == nil {
+		c.Labels = map[string]string{}
+	}
+
+	// labels derived from the function the sink is bound to, if any
+	if functionMeta := c.GetFunctionMeta(); functionMeta != nil {
+		for labelKey, labelValue := range getFunctionLabels(functionMeta) {
+			if _, labelExists := c.Labels[labelKey]; !labelExists {
+				c.Labels[labelKey] = labelValue
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetPushTimeout returns the parsed push timeout
+func (c *Configuration) GetPushTimeout() time.Duration {
+	return c.parsedPushTimeout
+}
+
+func getFunctionLabels(functionMeta *functionconfig.Meta) map[string]string {
+	labels := map[string]string{
+		"function": functionMeta.Name,
+	}
+
+	if functionMeta.Namespace != "" {
+		labels["namespace"] = functionMeta.Namespace
+	}
+
+	if projectName := functionMeta.Labels[common.NuclioResourceLabelKeyProjectName]; projectName != "" {
+		labels["project"] = projectName
+	}
+
+	return labels
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package logprocessing
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"regexp"
 	"strings"
 	"time"
@@ -74,6 +76,66 @@ func PrettifyFunctionLogLine(logger logger.Logger, log []byte) (string, string,
 	return res, briefLogLine, nil
 }
 
+// GetUnhealthyFunctionMessage returns the status message of an unhealthy function, which includes the tail
+// of its wrapper's stderr if the given processor logs report that the wrapper is crash looping
+func GetUnhealthyFunctionMessage(processorLogs io.Reader) string {
+	crashLoopMessage := getWrapperCrashLoopMessage(processorLogs)
+	if crashLoopMessage == "" {
+		return string(common.FunctionStateMessageUnhealthy)
+	}
+
+	return fmt.Sprintf("%s: %s", common.FunctionStateMessageUnhealthy, crashLoopMessage)
+}
+
+// IsUnhealthyFunctionMessage returns whether the given status message was set for an unhealthy function
+func IsUnhealthyFunctionMessage(message string) bool {
+	return strings.HasPrefix(message, string(common.FunctionStateMessageUnhealthy))
+}
+
+// getWrapperCrashLoopMessage returns the last report of a crash looping wrapper in the given processor logs,
+// along with the tail of its stderr, or an empty string if there's none
+func getWrapperCrashLoopMessage(processorLogs io.Reader) string {
+	var crashLoopMessage string
+
+	// the stderr tail makes for long log lines
+	scanner := bufio.NewScanner(processorLogs)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+
+	for scanner.Scan() {
+		functionLogLineInstance, err := CreateFunctionLogLine(scanner.Bytes())
+		if err != nil ||
+			functionLogLineInstance.Message == nil ||
+			!strings.Contains(*functionLogLineInstance.Message, string(common.WrapperProcessCrashLooping)) {
+			continue
+		}
+
+		crashLoopMessage = *functionLogLineInstance.Message
+		if stderr := getCrashLoopStderr(functionLogLineInstance); stderr != "" {
+			crashLoopMessage = fmt.Sprintf("%s, stderr:\n%s", crashLoopMessage, common.FixEscapeChars(stderr))
+		}
+	}
+
+	return crashLoopMessage
+}
+
+// getCrashLoopStderr returns the stderr tail of a crash looping wrapper's log line, whose args are either
+// structured or flattened to "key=value || key=value". The stderr tail is logged last, so that when flattened
+// it spans the rest of the args even if its lines contain the delimiter
+func getCrashLoopStderr(functionLogLineInstance *FunctionLogLine) string {
+	switch argsValue := functionLogLineInstance.More.(type) {
+	case map[string]interface{}:
+		if stderr, isString := argsValue["stderr"].(string); isString {
+			return stderr
+		}
+	case string:
+		if _, stderr, found := strings.Cut(argsValue, "stderr="); found {
+			return stderr
+		}
+	}
+
+	return ""
+}
+
 func CreateFunctionLogLine(log []byte) (*FunctionLogLine, error) {
 	functionLogLineInstance := &FunctionLogLine{}
 
@@ -19,6 +19,7 @@ limitations under the License.
 package logprocessing
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -50,6 +51,43 @@ func (suite *LogProcessorTestSuite) TestCreateKeyValuePairs() {
 	}
 }
 
+func (suite *LogProcessorTestSuite) TestGetUnhealthyFunctionMessage() {
+	for _, testCase := range []struct {
+		name            string
+		processorLogs   string
+		expectedMessage string
+	}{
+		{
+			name:            "noCrashLoop",
+			processorLogs:   `{"level":"info","time":"2024-01-01T10:00:00.000Z","name":"processor","message":"Starting"}`,
+			expectedMessage: "Function is not healthy",
+		},
+		{
+			name: "flattenedArgs",
+			processorLogs: `{"level":"info","time":"2024-01-01T10:00:00.000Z","name":"processor","message":"Starting"}
+{"level":"error","time":"2024-01-01T10:00:01.000Z","name":"processor.http.w0.python.logger","message":"Wrapper process is crash looping","more":"wid=0 || crashes=5 || err=exit status 1 || stderr=Traceback:\nImportError: no module || named foo"}`,
+			expectedMessage: "Function is not healthy: Wrapper process is crash looping, stderr:\nTraceback:\nImportError: no module || named foo",
+		},
+		{
+			name: "structuredArgs",
+			processorLogs: `{"level":"error","time":"2024-01-01T10:00:01.000Z","name":"processor.http.w0.python.logger","message":"Wrapper process is crash looping","more":{"wid":0,"stderr":"ImportError: no module"}}
+not a log line`,
+			expectedMessage: "Function is not healthy: Wrapper process is crash looping, stderr:\nImportError: no module",
+		},
+		{
+			name:            "noStderr",
+			processorLogs:   `{"level":"error","time":"2024-01-01T10:00:01.000Z","name":"processor","message":"Wrapper process is crash looping","more":"wid=0 || crashes=5"}`,
+			expectedMessage: "Function is not healthy: Wrapper process is crash looping",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			message := GetUnhealthyFunctionMessage(strings.NewReader(testCase.processorLogs))
+			suite.Require().Equal(testCase.expectedMessage, message)
+			suite.Require().True(IsUnhealthyFunctionMessage(message))
+		})
+	}
+}
+
 func TestLogProcessorTestSuite(t *testing.T) {
 	suite.Run(t, new(LogProcessorTestSuite))
 }
@@ -19,8 +19,12 @@ package command
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 
@@ -39,6 +43,7 @@ type buildCommandeer struct {
 	encodedRuntimeAttributes   string
 	encodedCodeEntryAttributes string
 	outputImageFile            string
+	output                     string
 }
 
 func newBuildCommandeer(rootCommandeer *RootCommandeer) *buildCommandeer {
@@ -86,6 +91,14 @@ func newBuildCommandeer(rootCommandeer *RootCommandeer) *buildCommandeer {
 				return errors.Wrap(err, "Failed to decode code entry attributes")
 			}
 
+			switch commandeer.output {
+			case "", "image":
+			case containerimagebuilderpusher.FunctionArchiveFormat:
+				return commandeer.buildFunctionArchive(context.Background())
+			default:
+				return errors.Errorf("Unsupported build output: %s", commandeer.output)
+			}
+
 			_, err := rootCommandeer.platform.CreateFunctionBuild(
 				context.Background(),
 				&platform.CreateFunctionBuildOptions{
@@ -100,12 +113,86 @@ func newBuildCommandeer(rootCommandeer *RootCommandeer) *buildCommandeer {
 
 	addBuildFlags(cmd, &commandeer.functionConfig.Spec.Build, &commandeer.functionConfigPath, &commandeer.runtime, &commandeer.handler, &commandeer.commands, &commandeer.encodedRuntimeAttributes, &commandeer.encodedCodeEntryAttributes)
 	cmd.Flags().StringVarP(&commandeer.outputImageFile, "output-image-file", "", "", "Path to output container image of the build")
+	cmd.Flags().StringVar(&commandeer.output, "output", "image", "Output of the build - \"image\" or \"oci-archive\" (the image and the function configuration, written to --output-image-file)")
 
 	commandeer.cmd = cmd
 
 	return commandeer
 }
 
+// buildFunctionArchive builds the function's image without pushing it, and writes it along with the function's
+// configuration as an archive, which "nuctl deploy --from-archive" deploys
+func (b *buildCommandeer) buildFunctionArchive(ctx context.Context) error {
+	if err := b.validateFunctionArchiveArgs(b.rootCommandeer.platform.GetContainerBuilderKind()); err != nil {
+		return errors.Wrap(err, "Invalid function archive arguments")
+	}
+
+	tempDir, err := os.MkdirTemp("", "nuctl-build-")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create temporary directory")
+	}
+
+	defer os.RemoveAll(tempDir) // nolint: errcheck
+
+	imageFile := filepath.Join(tempDir, "image.tar")
+
+	buildResult, err := b.rootCommandeer.platform.CreateFunctionBuild(ctx,
+		&platform.CreateFunctionBuildOptions{
+			Logger:          b.rootCommandeer.loggerInstance,
+			FunctionConfig:  b.functionConfig,
+			PlatformName:    b.rootCommandeer.platform.GetName(),
+			OutputImageFile: imageFile,
+		})
+	if err != nil {
+		return errors.Wrap(err, "Failed to build function")
+	}
+
+	if !common.FileExists(imageFile) {
+		return errors.Errorf("The %s platform's image builder can't save images to a file", b.rootCommandeer.platform.GetName())
+	}
+
+	// the function is deployed from the archived image, wherever it's pushed to
+	functionConfig := buildResult.UpdatedFunctionConfig
+	functionConfig.PrepareFunctionForExport(&common.ExportFunctionOptions{CleanupSpec: true})
+	functionConfig.Spec.Image = buildResult.Image
+	functionConfig.Spec.Build.Path = ""
+	functionConfig.Spec.Build.FunctionSourceCode = ""
+	functionConfig.Spec.Build.Image = ""
+	functionConfig.Spec.Build.CodeEntryType = ""
+	functionConfig.Spec.Build.CodeEntryAttributes = nil
+	functionConfig.Spec.Build.FunctionConfigPath = ""
+
+	archivePath := b.outputImageFile
+	if archivePath == "" {
+		archivePath = fmt.Sprintf("%s.tar", functionConfig.Meta.Name)
+	}
+
+	if err := containerimagebuilderpusher.WriteFunctionArchive(ctx,
+		b.rootCommandeer.loggerInstance,
+		imageFile,
+		buildResult.Image,
+		&functionConfig,
+		archivePath); err != nil {
+		return errors.Wrap(err, "Failed to write function archive")
+	}
+
+	return nil
+}
+
+// validateFunctionArchiveArgs fails before building if the archive can't be written - when the image builder
+// can't save images to a file, or when there are several images to save
+func (b *buildCommandeer) validateFunctionArchiveArgs(containerBuilderKind string) error {
+	if !containerimagebuilderpusher.SupportsOutputImageFile(containerBuilderKind) {
+		return errors.Errorf("The %s image builder can't save images to a file", containerBuilderKind)
+	}
+
+	if len(b.functionConfig.Spec.Build.Platforms) > 1 {
+		return errors.New("A function archive holds the image of a single platform")
+	}
+
+	return nil
+}
+
 func addBuildFlags(cmd *cobra.Command, functionBuild *functionconfig.Build, functionConfigPath *string, runtime *string, handler *string, commands *stringSliceFlag, encodedRuntimeAttributes *string, encodedCodeEntryAttributes *string) { // nolint
 	cmd.Flags().StringVarP(&functionBuild.Path, "path", "p", "", "Path to the function's source code")
 	cmd.Flags().StringVarP(&functionBuild.FunctionSourceCode, "source", "", "", "The function's source code (overrides \"path\")")
@@ -123,4 +210,7 @@ func addBuildFlags(cmd *cobra.Command, functionBuild *functionconfig.Build, func
 	cmd.Flags().StringVar(encodedRuntimeAttributes, "build-runtime-attrs", "{}", "JSON-encoded build runtime attributes for the function")
 	cmd.Flags().StringVar(encodedCodeEntryAttributes, "build-code-entry-attrs", "{}", "JSON-encoded build code entry attributes for the function")
 	cmd.Flags().StringVar(&functionBuild.CodeEntryType, "code-entry-type", "", "Type of code entry (for example, \"url\", \"github\", \"image\")")
+	cmd.Flags().StringVar((*string)(&functionBuild.SBOMFormat), "sbom-format", "", "Attach an SBOM in this format to the built image (\"spdx\" or \"cyclonedx\")")
+	cmd.Flags().BoolVar(&functionBuild.Sign, "sign", false, "Sign the built image with the platform's signing key")
+	cmd.Flags().StringSliceVar(&functionBuild.Platforms, "platforms", []string{}, "Platforms to build the image for, as a comma-separated list (for example, \"linux/amd64,linux/arm64\")")
 }
@@ -0,0 +1,46 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type buildTestSuite struct {
+	suite.Suite
+}
+
+func (suite *buildTestSuite) TestValidateFunctionArchiveArgs() {
+	commandeer := &buildCommandeer{}
+	suite.Require().NoError(commandeer.validateFunctionArchiveArgs("docker"))
+	suite.Require().NoError(commandeer.validateFunctionArchiveArgs("oci"))
+	suite.Require().Error(commandeer.validateFunctionArchiveArgs("kaniko"), "Kaniko can't save images to a file")
+
+	commandeer.functionConfig.Spec.Build.Platforms = []string{"linux/arm64"}
+	suite.Require().NoError(commandeer.validateFunctionArchiveArgs("docker"))
+
+	commandeer.functionConfig.Spec.Build.Platforms = []string{"linux/amd64", "linux/arm64"}
+	suite.Require().Error(commandeer.validateFunctionArchiveArgs("docker"), "Archive should hold a single platform")
+}
+
+func TestBuildTestSuite(t *testing.T) {
+	suite.Run(t, new(buildTestSuite))
+}
@@ -24,8 +24,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	nuctlcommon "github.com/nuclio/nuclio/pkg/nuctl/command/common"
 	"github.com/nuclio/nuclio/pkg/platform"
@@ -80,6 +82,8 @@ type deployCommandeer struct {
 	runAsGroup                      int64
 	fsGroup                         int64
 	overrideHTTPTriggerServiceType  string
+	follow                          bool
+	fromArchive                     string
 }
 
 func newDeployCommandeer(ctx context.Context, rootCommandeer *RootCommandeer) *deployCommandeer {
@@ -117,8 +121,26 @@ func newDeployCommandeer(ctx context.Context, rootCommandeer *RootCommandeer) *d
 
 			commandeer.functionConfigPath = commandeer.resolveFunctionConfigPath()
 
+			var functionArchive *containerimagebuilderpusher.FunctionArchive
+			if commandeer.fromArchive != "" {
+				if err := commandeer.validateFromArchiveArgs(importedFunction); err != nil {
+					return errors.Wrap(err, "Invalid arguments")
+				}
+
+				functionArchive, err = containerimagebuilderpusher.OpenFunctionArchive(
+					commandeer.rootCommandeer.loggerInstance,
+					commandeer.fromArchive)
+				if err != nil {
+					return errors.Wrap(err, "Failed to open function archive")
+				}
+
+				defer functionArchive.Close() // nolint: errcheck
+
+				commandeer.functionConfig = functionArchive.FunctionConfig
+			}
+
 			// If config file is provided
-			if importedFunction == nil && commandeer.functionConfigPath != "" {
+			if importedFunction == nil && functionArchive == nil && commandeer.functionConfigPath != "" {
 				commandeer.rootCommandeer.loggerInstance.DebugWithCtx(ctx, "Loading function config from file", "file", commandeer.functionConfigPath)
 				functionConfigFile, err := nuctlcommon.OpenFile(commandeer.functionConfigPath)
 				if err != nil {
@@ -162,10 +184,30 @@ func newDeployCommandeer(ctx context.Context, rootCommandeer *RootCommandeer) *d
 				return errors.Wrap(err, "Failed config with complex args")
 			}
 
+			// make the archived image available to the platform, instead of building one
+			if functionArchive != nil {
+				if err := commandeer.prepareArchivedImage(ctx, functionArchive); err != nil {
+					return errors.Wrap(err, "Failed to prepare archived image")
+				}
+
+				if commandeer.inputImageFile != "" {
+					defer os.Remove(commandeer.inputImageFile) // nolint: errcheck
+				}
+			}
+
 			// Ensure the skip-annotations never exist on deploy
 			commandeer.functionConfig.Meta.RemoveSkipBuildAnnotation()
 			commandeer.functionConfig.Meta.RemoveSkipDeployAnnotation()
 
+			// print the build's output while the function is deployed
+			deployDone := make(chan struct{})
+			followDone := make(chan struct{})
+			if commandeer.follow {
+				go commandeer.followDeployEvents(ctx, commandeer.functionConfig.Meta, deployDone, followDone)
+			} else {
+				close(followDone)
+			}
+
 			commandeer.rootCommandeer.loggerInstance.DebugWithCtx(ctx, "Deploying function", "functionConfig", commandeer.functionConfig)
 			_, deployErr := rootCommandeer.platform.CreateFunction(ctx, &platform.CreateFunctionOptions{
 				Logger:         rootCommandeer.loggerInstance,
@@ -173,6 +215,9 @@ func newDeployCommandeer(ctx context.Context, rootCommandeer *RootCommandeer) *d
 				InputImageFile: commandeer.inputImageFile,
 			})
 
+			close(deployDone)
+			<-followDone
+
 			// don't check deploy error yet, first try to save the logs either way, and then return the error if necessary
 			commandeer.rootCommandeer.loggerInstance.Debug("Saving deployment logs")
 			logSaveErr := rootCommandeer.platform.SaveFunctionDeployLogs(ctx, commandeer.functionName, rootCommandeer.namespace)
@@ -228,6 +273,8 @@ func addDeployFlags(cmd *cobra.Command,
 	cmd.Flags().Var(&commandeer.resourceRequests, "resource-request", "Requested resources of the format '<resource name>=<quantity>' (for example, 'cpu=3')")
 	cmd.Flags().StringVar(&commandeer.loggerLevel, "logger-level", "", "One of debug, info, warn, error. By default, uses platform configuration")
 	cmd.Flags().StringVarP(&commandeer.inputImageFile, "input-image-file", "", "", "Path to an input function-image Docker archive file")
+	cmd.Flags().StringVar(&commandeer.fromArchive, "from-archive", "", "Path to a function archive created by \"nuctl build --output oci-archive\" to deploy, pushing its image to -r/--registry")
+	cmd.Flags().BoolVar(&commandeer.follow, "follow", false, "Print the build output (Dockerfile steps, image push progress) as the function is built")
 }
 func parseResourceAllocations(values stringSliceFlag, resources *v1.ResourceList) error {
 	for _, value := range values {
@@ -296,6 +343,114 @@ func parseVolumes(volumes stringSliceFlag) ([]functionconfig.Volume, error) {
 	return originVolumes, nil
 }
 
+func (d *deployCommandeer) validateFromArchiveArgs(importedFunction platform.Function) error {
+	if importedFunction != nil {
+		return errors.New("An imported function can't be deployed from an archive")
+	}
+
+	for flagName, flagValue := range map[string]string{
+		"path":             d.functionBuild.Path,
+		"source":           d.functionBuild.FunctionSourceCode,
+		"file":             d.functionConfigPath,
+		"run-image":        d.image,
+		"input-image-file": d.inputImageFile,
+	} {
+		if flagValue != "" {
+			return errors.Errorf("--from-archive can't be used with --%s", flagName)
+		}
+	}
+
+	return nil
+}
+
+// prepareArchivedImage makes the archived image available to the platform - loading it into the local docker
+// daemon, or pushing it to the function's registry - and sets the function to deploy it without building
+func (d *deployCommandeer) prepareArchivedImage(ctx context.Context,
+	functionArchive *containerimagebuilderpusher.FunctionArchive) error {
+	d.functionConfig.Spec.Image = functionArchive.ImageName
+	d.functionConfig.Spec.Build.Mode = functionconfig.NeverBuild
+
+	if d.rootCommandeer.platform.GetName() == common.LocalPlatformName {
+		inputImageFile, err := os.CreateTemp("", "nuctl-image-*.tar")
+		if err != nil {
+			return errors.Wrap(err, "Failed to create temporary image file")
+		}
+
+		if err := inputImageFile.Close(); err != nil {
+			return errors.Wrap(err, "Failed to close temporary image file")
+		}
+
+		d.inputImageFile = inputImageFile.Name()
+
+		return functionArchive.SaveDockerArchive(d.inputImageFile)
+	}
+
+	registryURL := d.functionConfig.Spec.Build.Registry
+	if registryURL == "" {
+		return errors.New("Deploying from an archive requires a registry (-r/--registry) to push the image to")
+	}
+
+	builderConfiguration, err := containerimagebuilderpusher.NewContainerBuilderConfiguration()
+	if err != nil {
+		return errors.Wrap(err, "Failed to create container builder configuration")
+	}
+
+	if _, err := functionArchive.PushImage(ctx, builderConfiguration, registryURL); err != nil {
+		return errors.Wrap(err, "Failed to push archived image")
+	}
+
+	// the image is pulled from where it was pushed, unless a run registry was given
+	if d.functionConfig.Spec.RunRegistry == "" {
+		d.functionConfig.Spec.RunRegistry = registryURL
+	}
+
+	return nil
+}
+
+// followDeployEvents prints the build output of the function's deployment as it's produced, until the deployment
+// ends. the deployment's other events are logged by the command's logger
+func (d *deployCommandeer) followDeployEvents(ctx context.Context,
+	functionMeta functionconfig.Meta,
+	deployDone <-chan struct{},
+	followDone chan<- struct{}) {
+	defer close(followDone)
+
+	offset := 0
+	for {
+		readResult, err := d.rootCommandeer.platform.ReadFunctionDeployEvents(ctx,
+			&platform.ReadFunctionDeployEventsOptions{
+				FunctionMeta: &functionMeta,
+				Offset:       offset,
+			})
+		if err != nil {
+			d.rootCommandeer.loggerInstance.WarnWithCtx(ctx, "Failed to follow deployment", "err", err.Error())
+			return
+		}
+
+		// the deployment hasn't started yet, or failed before it could
+		if readResult == nil {
+			select {
+			case <-deployDone:
+				return
+			case <-time.After(100 * time.Millisecond):
+				continue
+			}
+		}
+
+		for _, event := range readResult.Events {
+			if buildOutput, isBuildOutput := event[common.BuildOutputLogKey].(string); isBuildOutput {
+				fmt.Fprintln(d.cmd.OutOrStdout(), buildOutput) // nolint: errcheck
+			}
+		}
+
+		if readResult.Done {
+			return
+		}
+
+		offset = readResult.NextOffset
+	}
+}
+
 // If user runs deploy with a function name of a function that was already imported, this checks if that function
 // exists and is imported. If so, returns that function, otherwise returns nil.
 func (d *deployCommandeer) getImportedFunction(ctx context.Context, functionName string) (platform.Function, error) {
@@ -626,8 +781,17 @@ func (d *deployCommandeer) enrichBuildConfigWithArgs() {
 		&d.functionConfig.Spec.Build.NoBaseImagesPull: d.functionBuild.NoBaseImagesPull,
 		&d.functionConfig.Spec.Build.NoCleanup:        d.functionBuild.NoCleanup,
 		&d.functionConfig.Spec.Build.Offline:          d.functionBuild.Offline,
+		&d.functionConfig.Spec.Build.Sign:             d.functionBuild.Sign,
 	})
 
+	if d.functionConfig.Spec.Build.SBOMFormat == "" {
+		d.functionConfig.Spec.Build.SBOMFormat = d.functionBuild.SBOMFormat
+	}
+
+	if len(d.functionBuild.Platforms) > 0 {
+		d.functionConfig.Spec.Build.Platforms = d.functionBuild.Platforms
+	}
+
 	// enrich build commands
 	if len(d.commands) > 0 {
 		d.functionConfig.Spec.Build.Commands = d.commands
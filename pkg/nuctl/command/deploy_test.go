@@ -75,6 +75,17 @@ func (suite *deployTestSuite) TestParseInvalidVolume() {
 	suite.Require().Error(err, "Parse src is invalid, should not succeed")
 }
 
+func (suite *deployTestSuite) TestValidateFromArchiveArgs() {
+	commandeer := &deployCommandeer{fromArchive: "my-function.tar"}
+	suite.Require().NoError(commandeer.validateFromArchiveArgs(nil))
+
+	commandeer.functionBuild.Path = "/path/to/function"
+	suite.Require().Error(commandeer.validateFromArchiveArgs(nil), "Archive should not be deployed with a path")
+
+	commandeer = &deployCommandeer{fromArchive: "my-function.tar", image: "my-image:latest"}
+	suite.Require().Error(commandeer.validateFromArchiveArgs(nil), "Archive should not be deployed with an image")
+}
+
 func TestDeployTestSuite(t *testing.T) {
 	suite.Run(t, new(deployTestSuite))
 }
@@ -88,6 +88,7 @@ func NewRootCommandeer() *RootCommandeer {
 		newImportCommandeer(ctx, commandeer).cmd,
 		newBetaCommandeer(ctx, commandeer).cmd,
 		newParseCommandeer(ctx, commandeer).cmd,
+		newReplayCommandeer(ctx, commandeer).cmd,
 	)
 
 	commandeer.cmd = cmd
@@ -0,0 +1,286 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+	"github.com/nuclio/nuclio/pkg/processor/eventrecorder"
+
+	"github.com/nuclio/errors"
+	"github.com/spf13/cobra"
+)
+
+type replayCommandeer struct {
+	cmd                 *cobra.Command
+	rootCommandeer      *RootCommandeer
+	recordingPath       string
+	url                 string
+	externalIPAddresses string
+	preserveTiming      bool
+	timeout             time.Duration
+	skipTLSVerification bool
+	raiseOnStatus       bool
+}
+
+func newReplayCommandeer(ctx context.Context, rootCommandeer *RootCommandeer) *replayCommandeer {
+	commandeer := &replayCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "replay [function-name]",
+		Short: "Replay recorded events to a function",
+		Long: `Replay events recorded by a function's triggers (see the trigger "eventRecording" configuration).
+
+Events are sent over HTTP, either to a deployed function (by name) or to any URL
+(e.g. a function running locally), in the order they were recorded.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commandeer.recordingPath == "" {
+				return errors.New("Replay requires a recording file")
+			}
+
+			if len(args) > 1 {
+				return errors.New("Replay accepts at most one function name")
+			}
+
+			if len(args) == 0 && commandeer.url == "" {
+				return errors.New("Replay requires either a function name or a URL")
+			}
+
+			// initialize root, a platform is only required for resolving the function's URL
+			if err := rootCommandeer.initialize(commandeer.url == ""); err != nil {
+				return errors.Wrap(err, "Failed to initialize root")
+			}
+
+			recordedEvents, err := commandeer.readRecording()
+			if err != nil {
+				return errors.Wrap(err, "Failed to read recording")
+			}
+
+			replayURL := commandeer.url
+			if replayURL == "" {
+				replayURL, err = commandeer.resolveFunctionURL(ctx, args[0])
+				if err != nil {
+					return errors.Wrap(err, "Failed to resolve function URL")
+				}
+			}
+
+			return commandeer.replay(ctx, replayURL, recordedEvents, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&commandeer.recordingPath, "file", "f", "", "Path to a recording file")
+	cmd.Flags().StringVarP(&commandeer.url, "url", "u", "", "Replay to this URL instead of to a deployed function")
+	cmd.Flags().BoolVarP(&commandeer.preserveTiming, "preserve-timing", "", false, "Keep the original intervals between events, rather than replaying as fast as possible")
+	cmd.Flags().StringVarP(&commandeer.externalIPAddresses, "external-ips", "", os.Getenv("NUCTL_EXTERNAL_IP_ADDRESSES"), "External IP addresses (comma-delimited) with which to invoke the function")
+	cmd.Flags().DurationVarP(&commandeer.timeout, "timeout", "t", platformconfig.DefaultFunctionInvocationTimeoutSeconds*time.Second, "Per-event request timeout")
+	cmd.Flags().BoolVarP(&commandeer.skipTLSVerification, "skip-tls", "", false, "Skip TLS verification")
+	cmd.Flags().BoolVarP(&commandeer.raiseOnStatus, "raise-on-status", "", false, "Fail nuctl in case any replayed event returns non-200 status code")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (r *replayCommandeer) readRecording() ([]*eventrecorder.RecordedEvent, error) {
+	recordingFile, err := os.Open(r.recordingPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open recording file %s", r.recordingPath)
+	}
+
+	defer recordingFile.Close() // nolint: errcheck
+
+	return eventrecorder.ReadRecording(recordingFile)
+}
+
+func (r *replayCommandeer) resolveFunctionURL(ctx context.Context, functionName string) (string, error) {
+
+	// resolve the url the same way invoke does
+	invoker := &invokeCommandeer{
+		rootCommandeer:      r.rootCommandeer,
+		externalIPAddresses: r.externalIPAddresses,
+		createFunctionInvocationOptions: platform.CreateFunctionInvocationOptions{
+			Name:      functionName,
+			Namespace: r.rootCommandeer.namespace,
+		},
+	}
+
+	if r.externalIPAddresses != "" {
+		if err := r.rootCommandeer.platform.SetExternalIPAddresses(strings.Split(r.externalIPAddresses, ",")); err != nil {
+			return "", errors.Wrap(err, "Failed to set external IP address")
+		}
+	}
+
+	if err := invoker.createFunctionInvocationOptions.EnrichFunction(ctx, r.rootCommandeer.platform); err != nil {
+		return "", errors.Wrap(err, "Failed to get function")
+	}
+
+	invocationURLs := invoker.createFunctionInvocationOptions.FunctionInstance.GetStatus().InvocationURLs()
+	if len(invocationURLs) == 0 {
+		return "", errors.New("Function has no invocation URLs")
+	}
+
+	// if running with platform, invoke internally
+	if common.RunningInContainer() || common.IsInKubernetesCluster() {
+		return invocationURLs[0], nil
+	}
+
+	if err := invoker.enrichOptionsForExternalIP(invocationURLs); err != nil {
+		return "", errors.Wrap(err, "Failed to resolve external IP")
+	}
+
+	return invoker.createFunctionInvocationOptions.URL, nil
+}
+
+func (r *replayCommandeer) replay(ctx context.Context,
+	replayURL string,
+	recordedEvents []*eventrecorder.RecordedEvent,
+	writer io.Writer) error {
+
+	if !strings.HasPrefix(replayURL, "http://") && !strings.HasPrefix(replayURL, "https://") {
+		replayURL = "http://" + replayURL
+	}
+
+	client := &http.Client{
+		Timeout: r.timeout,
+	}
+
+	if r.skipTLSVerification {
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // nolint: gosec
+		}
+	}
+
+	r.rootCommandeer.loggerInstance.InfoWith("Replaying events",
+		"url", replayURL,
+		"numEvents", len(recordedEvents),
+		"preserveTiming", r.preserveTiming)
+
+	failedEvents := 0
+	replayStartTime := time.Now()
+
+	for eventIndex, recordedEvent := range recordedEvents {
+
+		// wait until the event is due, relative to the first one
+		if r.preserveTiming && eventIndex > 0 {
+			dueTime := replayStartTime.Add(recordedEvent.Time.Sub(recordedEvents[0].Time))
+			select {
+			case <-time.After(time.Until(dueTime)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		eventStartTime := time.Now()
+
+		statusCode, err := r.replayEvent(ctx, client, replayURL, recordedEvent)
+		if err != nil {
+			failedEvents++
+			fmt.Fprintf(writer, "%d\t%s\tfailed: %s\n", eventIndex, recordedEvent.ID, err) // nolint: errcheck
+			continue
+		}
+
+		if statusCode < http.StatusOK || statusCode >= 300 {
+			failedEvents++
+		}
+
+		fmt.Fprintf(writer, "%d\t%s\t%d\t%s\n", // nolint: errcheck
+			eventIndex,
+			recordedEvent.ID,
+			statusCode,
+			time.Since(eventStartTime))
+	}
+
+	r.rootCommandeer.loggerInstance.InfoWith("Done replaying events",
+		"numEvents", len(recordedEvents),
+		"failedEvents", failedEvents,
+		"duration", time.Since(replayStartTime).String())
+
+	if failedEvents > 0 && r.raiseOnStatus {
+		return errors.Errorf("%d of %d replayed events failed", failedEvents, len(recordedEvents))
+	}
+
+	return nil
+}
+
+func (r *replayCommandeer) replayEvent(ctx context.Context,
+	client *http.Client,
+	replayURL string,
+	recordedEvent *eventrecorder.RecordedEvent) (int, error) {
+
+	eventURL := strings.TrimSuffix(replayURL, "/") + "/" + strings.TrimPrefix(recordedEvent.Path, "/")
+
+	if len(recordedEvent.Fields) > 0 {
+		query := url.Values{}
+		for fieldName, fieldValue := range recordedEvent.Fields {
+			query.Set(fieldName, fmt.Sprint(fieldValue))
+		}
+
+		eventURL += "?" + query.Encode()
+	}
+
+	method := recordedEvent.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, eventURL, bytes.NewReader(recordedEvent.Body))
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to create request")
+	}
+
+	for headerName, headerValue := range recordedEvent.Headers {
+
+		// scrubbed headers were replaced by references, there's nothing to send
+		if strings.HasPrefix(headerValue, common.ReferencePrefix) {
+			continue
+		}
+
+		request.Header.Set(headerName, headerValue)
+	}
+
+	if request.Header.Get("Content-Type") == "" && recordedEvent.ContentType != "" {
+		request.Header.Set("Content-Type", recordedEvent.ContentType)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to send request")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	// drain the body, so that the connection is reused
+	if _, err := io.Copy(io.Discard, response.Body); err != nil {
+		return 0, errors.Wrap(err, "Failed to read response body")
+	}
+
+	return response.StatusCode, nil
+}
@@ -18,8 +18,9 @@ package abstract
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"time"
+	"sync"
 
 	"github.com/nuclio/nuclio/pkg/common"
 
@@ -28,28 +29,49 @@ import (
 	"github.com/nuclio/zap"
 )
 
+// the number of events a log stream keeps for clients following it. older events are dropped
+const maxLogStreamEvents = 10000
+
 type LogStream struct {
-	bufferLogger *nucliozap.BufferLogger
-	muxLogger    *nucliozap.MuxLogger
+	level     nucliozap.Level
+	zapLogger *nucliozap.NuclioZap
+	muxLogger *nucliozap.MuxLogger
+
+	lock sync.Mutex
+
+	// entries of the stream's level, kept as the function's logs
+	logs bytes.Buffer
+
+	// entries of the stream's level and build output, streamed to clients following the deployment
+	events        []map[string]interface{}
+	droppedEvents int
+	eventsChanged chan struct{}
+	closed        bool
 }
 
 // NewLogStream returns a new log stream
 func NewLogStream(name string, level nucliozap.Level, loggers ...logger.Logger) (*LogStream, error) {
 	var err error
 
-	newLogStream := LogStream{}
+	newLogStream := LogStream{
+		level:         level,
+		eventsChanged: make(chan struct{}),
+	}
 
-	// create a buffer logger
-	redactor := common.GetRedactorInstance(&bytes.Buffer{})
-	newLogStream.bufferLogger, err = nucliozap.NewBufferLoggerWithRedactor(name,
+	// create a logger that records into the stream. it logs at debug level so that build output, which is
+	// logged at debug level, reaches the events
+	redactor := common.GetRedactorInstance(&logStreamWriter{logStream: &newLogStream})
+	newLogStream.zapLogger, err = nucliozap.NewNuclioZap(name,
 		"json",
-		level,
-		redactor)
+		nil,
+		redactor,
+		redactor,
+		nucliozap.DebugLevel)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to create mux logger")
+		return nil, errors.Wrap(err, "Failed to create log stream logger")
 	}
 
-	loggers = append(loggers, newLogStream.bufferLogger.Logger)
+	loggers = append(loggers, newLogStream.zapLogger)
 
 	// wrap a mux logger
 	newLogStream.muxLogger, err = nucliozap.NewMuxLogger(loggers...)
@@ -66,42 +88,119 @@ func (ls *LogStream) GetLogger() logger.Logger {
 }
 
 func (ls *LogStream) GetRedactor() *nucliozap.Redactor {
-	return ls.bufferLogger.Logger.GetRedactor()
+	return ls.zapLogger.GetRedactor()
 }
 
-func (ls *LogStream) ReadLogs(timeout *time.Duration, logs *[]map[string]interface{}) {
-	deadline := time.Now()
-	if timeout != nil {
-		deadline = deadline.Add(*timeout)
-	}
+// ReadLogs reads the entries of the stream's level
+func (ls *LogStream) ReadLogs(logs *[]map[string]interface{}) {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
 
-	// since the logs stream in, we can never know if they make for valid JSON. we can try until it works or unti
-	// the deadline passes. if timeout is nil, we only try once
-	for retryIndex := 0; true; retryIndex++ {
+	// if there are no logs, do nothing
+	if ls.logs.Len() == 0 {
+		return
+	}
 
-		// remove the last comma from the string
-		marshalledLogs := ls.bufferLogger.Buffer.String()
+	// remove the last comma and unmarshal. entries are written whole, so this is always valid json
+	marshalledLogs := "[" + ls.logs.String()[:ls.logs.Len()-1] + "]"
+	json.Unmarshal([]byte(marshalledLogs), logs) // nolint: errcheck
+}
 
-		// if something went wrong and there are no logs, do nothing
-		if len(marshalledLogs) != 0 {
+// ReadEvents returns the events from an offset on, and the offset of the events that follow them. if there are
+// no such events yet, it waits until there are, the stream is closed or the context is done
+func (ls *LogStream) ReadEvents(ctx context.Context, offset int) ([]map[string]interface{}, int, bool) {
+	for {
+		ls.lock.Lock()
 
-			marshalledLogs = "[" + marshalledLogs[:len(marshalledLogs)-1] + "]"
+		// events before the oldest kept one were dropped, and offsets past the last one don't exist yet
+		nextOffset := ls.droppedEvents + len(ls.events)
+		offset = min(max(offset, ls.droppedEvents), nextOffset)
 
-			// try to unmarshal the json
-			err := json.Unmarshal([]byte(marshalledLogs), logs)
+		if offset < nextOffset || ls.closed {
+			events := append([]map[string]interface{}{}, ls.events[offset-ls.droppedEvents:]...)
+			closed := ls.closed
+			ls.lock.Unlock()
 
-			// if we got valid json we're done
-			if err == nil {
-				return
-			}
+			return events, nextOffset, closed
 		}
 
-		// if we're passed the deadline, we're done
-		if time.Now().After(deadline) {
-			return
+		eventsChanged := ls.eventsChanged
+		ls.lock.Unlock()
+
+		select {
+		case <-eventsChanged:
+		case <-ctx.Done():
+			return nil, offset, false
 		}
+	}
+}
+
+// Close marks the end of the stream, once the deployment it logs is done
+func (ls *LogStream) Close() {
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
 
-		// wait a bit and retry
-		time.Sleep(time.Duration(25*retryIndex) * time.Millisecond)
+	if !ls.closed {
+		ls.closed = true
+		ls.notifyEventsChanged()
 	}
 }
+
+func (ls *LogStream) record(entryBody []byte) {
+	var entry map[string]interface{}
+	if err := json.Unmarshal(entryBody, &entry); err != nil {
+		return
+	}
+
+	_, isBuildOutput := entry[common.BuildOutputLogKey]
+	level, _ := entry["level"].(string)
+	if !isBuildOutput && parseLogLevel(level) < ls.level {
+		return
+	}
+
+	ls.lock.Lock()
+	defer ls.lock.Unlock()
+
+	// build output is too long to keep as the function's logs
+	if !isBuildOutput {
+		ls.logs.Write(entryBody)
+		ls.logs.WriteString(",")
+	}
+
+	ls.events = append(ls.events, entry)
+	if len(ls.events) > maxLogStreamEvents {
+		ls.events = ls.events[1:]
+		ls.droppedEvents++
+	}
+
+	ls.notifyEventsChanged()
+}
+
+// notifyEventsChanged wakes up the readers waiting for events. must be called with the lock held
+func (ls *LogStream) notifyEventsChanged() {
+	close(ls.eventsChanged)
+	ls.eventsChanged = make(chan struct{})
+}
+
+func parseLogLevel(level string) nucliozap.Level {
+	switch level {
+	case "debug":
+		return nucliozap.DebugLevel
+	case "info":
+		return nucliozap.InfoLevel
+	case "warn":
+		return nucliozap.WarnLevel
+	default:
+		return nucliozap.ErrorLevel
+	}
+}
+
+// logStreamWriter records the entries written by the log stream's logger, each written whole and followed by a comma
+type logStreamWriter struct {
+	logStream *LogStream
+}
+
+func (lsw *logStreamWriter) Write(p []byte) (int, error) {
+	lsw.logStream.record(bytes.TrimSuffix(p, []byte(",")))
+	return len(p), nil
+}
@@ -0,0 +1,89 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package abstract
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type LogStreamTestSuite struct {
+	suite.Suite
+	logStream *LogStream
+}
+
+func (suite *LogStreamTestSuite) SetupTest() {
+	var err error
+
+	suite.logStream, err = NewLogStream("deployer", nucliozap.InfoLevel)
+	suite.Require().NoError(err)
+}
+
+func (suite *LogStreamTestSuite) TestReadLogsAndEvents() {
+	streamLogger := suite.logStream.GetLogger()
+	streamLogger.InfoWith("Building processor image", "image", "processor-f1:latest")
+	streamLogger.DebugWith("Running command", "command", "docker build")
+	streamLogger.DebugWith("Build output", common.BuildOutputLogKey, "Step 1/4 : FROM alpine")
+	streamLogger.WarnWith("Function state changed", "state", "ready")
+
+	// build output isn't kept as the function's logs
+	var logs []map[string]interface{}
+	suite.logStream.ReadLogs(&logs)
+	suite.Require().Len(logs, 2)
+	suite.Require().Equal("Building processor image", logs[0]["message"])
+	suite.Require().Equal("Function state changed", logs[1]["message"])
+
+	events, nextOffset, done := suite.logStream.ReadEvents(context.Background(), 0)
+	suite.Require().Len(events, 3)
+	suite.Require().Equal("Step 1/4 : FROM alpine", events[1][common.BuildOutputLogKey])
+	suite.Require().Equal(3, nextOffset)
+	suite.Require().False(done)
+
+	// reading past the last event waits for the next one
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	events, nextOffset, _ = suite.logStream.ReadEvents(ctx, 3)
+	suite.Require().Empty(events)
+	suite.Require().Equal(3, nextOffset)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		streamLogger.Info("Function deploy complete")
+		suite.logStream.Close()
+	}()
+
+	events, nextOffset, _ = suite.logStream.ReadEvents(context.Background(), 3)
+	suite.Require().Len(events, 1)
+	suite.Require().Equal(4, nextOffset)
+
+	events, _, done = suite.logStream.ReadEvents(context.Background(), 4)
+	suite.Require().Empty(events)
+	suite.Require().True(done)
+}
+
+func TestLogStreamTestSuite(t *testing.T) {
+	suite.Run(t, new(LogStreamTestSuite))
+}
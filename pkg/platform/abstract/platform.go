@@ -196,6 +196,8 @@ func (ap *Platform) HandleDeployFunction(ctx context.Context,
 
 				// use the function configuration augmented by the builder
 				createFunctionOptions.FunctionConfig.Spec.Image = buildResult.Image
+				createFunctionOptions.ImageAttestation = buildResult.ImageAttestation
+				createFunctionOptions.SourceCommit = buildResult.SourceCommit
 
 				// if run registry isn't set, set it to that of the build
 				if createFunctionOptions.FunctionConfig.Spec.RunRegistry == "" {
@@ -433,11 +435,28 @@ func (ap *Platform) EnrichFunctionsWithDeployLogStream(functions []platform.Func
 	// iterate over functions and enrich with deploy logs
 	for _, function := range functions {
 		if deployLogStream, exists := ap.DeployLogStreams.Load(function.GetConfig().Meta.GetUniqueID()); exists {
-			deployLogStream.(*LogStream).ReadLogs(nil, &function.GetStatus().Logs)
+			deployLogStream.(*LogStream).ReadLogs(&function.GetStatus().Logs)
 		}
 	}
 }
 
+// ReadFunctionDeployEvents returns the build and deployment events of a function deployed by this platform instance
+func (ap *Platform) ReadFunctionDeployEvents(ctx context.Context,
+	readFunctionDeployEventsOptions *platform.ReadFunctionDeployEventsOptions) (*platform.ReadFunctionDeployEventsResult, error) {
+	deployLogStream, exists := ap.DeployLogStreams.Load(readFunctionDeployEventsOptions.FunctionMeta.GetUniqueID())
+	if !exists {
+		return nil, nil
+	}
+
+	events, nextOffset, done := deployLogStream.(*LogStream).ReadEvents(ctx, readFunctionDeployEventsOptions.Offset)
+
+	return &platform.ReadFunctionDeployEventsResult{
+		Events:     events,
+		NextOffset: nextOffset,
+		Done:       done,
+	}, nil
+}
+
 // ValidateFunctionConfig validates and enforces of required function creation logic
 func (ap *Platform) ValidateFunctionConfig(ctx context.Context, functionConfig *functionconfig.Config) error {
 
@@ -1050,6 +1069,44 @@ func (ap *Platform) BuildAndPushContainerImage(ctx context.Context, buildOptions
 		ap.DefaultNamespace)
 }
 
+// ResolveImageDigest returns the digest of an image used by builds
+func (ap *Platform) ResolveImageDigest(ctx context.Context, image string) (string, error) {
+	return ap.ContainerBuilder.ResolveImageDigest(ctx, image)
+}
+
+// ReuseCachedImage reuses the image of a previous build with the same fingerprint, if there is one
+func (ap *Platform) ReuseCachedImage(ctx context.Context,
+	buildOptions *containerimagebuilderpusher.BuildOptions,
+	fingerprint string) (bool, error) {
+	return ap.ContainerBuilder.ReuseCachedImage(ctx, buildOptions, fingerprint)
+}
+
+// PushImageIndex pushes an index of images built for different platforms
+func (ap *Platform) PushImageIndex(ctx context.Context,
+	image string,
+	registryURL string,
+	platformImages []string) error {
+	return ap.ContainerBuilder.PushImageIndex(ctx, image, registryURL, platformImages)
+}
+
+// AttestContainerImage attaches an SBOM and a signature to a pushed container image
+func (ap *Platform) AttestContainerImage(ctx context.Context,
+	attestationOptions *containerimagebuilderpusher.AttestationOptions) (*functionconfig.ImageAttestation, error) {
+	return containerimagebuilderpusher.AttestContainerImage(ctx,
+		ap.Logger,
+		ap.Config.ContainerBuilderConfiguration,
+		attestationOptions)
+}
+
+// PullArtifact writes the files of an OCI artifact in a registry to a directory, and returns their paths
+func (ap *Platform) PullArtifact(ctx context.Context, artifactReference string, outputDir string) ([]string, error) {
+	return containerimagebuilderpusher.PullArtifact(ctx,
+		ap.Logger,
+		ap.Config.ContainerBuilderConfiguration,
+		artifactReference,
+		outputDir)
+}
+
 // GetOnbuildStages get onbuild multistage builds
 func (ap *Platform) GetOnbuildStages(onbuildArtifacts []runtime.Artifact) ([]string, error) {
 	return ap.ContainerBuilder.GetOnbuildStages(onbuildArtifacts)
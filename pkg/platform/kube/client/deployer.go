@@ -81,6 +81,22 @@ func (d *Deployer) CreateOrUpdateFunction(ctx context.Context,
 		functionStatus.HTTPPort = functionInstance.Status.HTTPPort
 	}
 
+	// record the attestation and source commit of a newly built image, or keep those of the image when it wasn't
+	// rebuilt
+	functionStatus.ImageAttestation = createFunctionOptions.ImageAttestation
+	functionStatus.SourceCommit = createFunctionOptions.SourceCommit
+	if functionExists &&
+		functionInstance.Spec.Image == createFunctionOptions.FunctionConfig.Spec.Image &&
+		functionInstance.Spec.Build.Timestamp == createFunctionOptions.FunctionConfig.Spec.Build.Timestamp {
+		if functionStatus.ImageAttestation == nil {
+			functionStatus.ImageAttestation = functionInstance.Status.ImageAttestation
+		}
+
+		if functionStatus.SourceCommit == "" {
+			functionStatus.SourceCommit = functionInstance.Status.SourceCommit
+		}
+	}
+
 	// scrub the function config if enabled
 	if d.platform.GetConfig().SensitiveFields.MaskSensitiveFields && !functionInstance.Spec.DisableSensitiveFieldsMasking && d.scrubber != nil {
 
@@ -145,7 +161,12 @@ func (d *Deployer) Deploy(ctx context.Context,
 	}
 
 	// wait for the function to be ready
+	createFunctionOptions.Logger.InfoWithCtx(ctx,
+		"Waiting for function to be ready",
+		"name", functionInstance.Name,
+		"namespace", functionInstance.Namespace)
 	updatedFunctionInstance, err := waitForFunctionReadiness(ctx,
+		createFunctionOptions.Logger,
 		d.consumer,
 		functionInstance.Namespace,
 		functionInstance.Name)
@@ -300,11 +321,13 @@ func (d *Deployer) getLastCreatedPod(pods []v1.Pod) v1.Pod {
 }
 
 func waitForFunctionReadiness(ctx context.Context,
+	loggerInstance logger.Logger,
 	consumer *Consumer,
 	namespace string,
 	name string) (*nuclioio.NuclioFunction, error) {
 	var err error
 	var function *nuclioio.NuclioFunction
+	var lastState functionconfig.FunctionState
 
 	// gets the function, checks if ready
 	conditionFunc := func(conditionCtx context.Context) (bool, error) {
@@ -317,6 +340,15 @@ func waitForFunctionReadiness(ctx context.Context,
 			return true, err
 		}
 
+		// let clients following the deployment know how far the controller got
+		if function.Status.State != lastState {
+			loggerInstance.InfoWithCtx(ctx,
+				"Function state changed",
+				"name", name,
+				"state", function.Status.State)
+			lastState = function.Status.State
+		}
+
 		switch function.Status.State {
 		case functionconfig.FunctionStateScaledToZero:
 			return true, nil
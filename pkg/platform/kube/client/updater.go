@@ -110,6 +110,7 @@ func (u *Updater) Update(ctx context.Context, updateFunctionOptions *platform.Up
 
 	// wait for the function to be ready
 	if _, err := waitForFunctionReadiness(ctx,
+		u.logger,
 		u.consumer,
 		updatedFunction.Namespace,
 		updatedFunction.Name); err != nil {
@@ -320,6 +320,8 @@ func (fo *functionOperator) CreateOrUpdate(ctx context.Context, object runtime.O
 			Logs:                 function.Status.Logs,
 			ContainerImage:       function.Spec.Image,
 			EnrichedNodeSelector: function.Status.EnrichedNodeSelector,
+			ImageAttestation:     function.Status.ImageAttestation,
+			SourceCommit:         function.Status.SourceCommit,
 		}
 
 		if err := fo.populateFunctionInvocationStatus(function, functionStatus, resources); err != nil {
@@ -401,6 +403,8 @@ func (fo *functionOperator) setFunctionError(ctx context.Context,
 		InternalInvocationURLs: []string{},
 		ExternalInvocationURLs: []string{},
 		EnrichedNodeSelector:   function.Status.EnrichedNodeSelector,
+		ImageAttestation:       function.Status.ImageAttestation,
+		SourceCommit:           function.Status.SourceCommit,
 	}); setStatusErr != nil {
 		fo.logger.WarnWithCtx(detachedContext,
 			"Failed to update function on error",
@@ -1134,7 +1134,7 @@ func (lc *lazyClient) createOrUpdateDeployment(ctx context.Context,
 					Volumes:            volumes,
 					ServiceAccountName: function.Spec.ServiceAccount,
 					SecurityContext:    function.Spec.SecurityContext,
-					Affinity:           function.Spec.Affinity,
+					Affinity:           lc.getFunctionAffinity(function),
 					Tolerations:        function.Spec.Tolerations,
 					NodeSelector:       function.Status.EnrichedNodeSelector,
 					NodeName:           function.Spec.NodeName,
@@ -1216,7 +1216,7 @@ func (lc *lazyClient) createOrUpdateDeployment(ctx context.Context,
 		}
 
 		deployment.Spec.Template.Spec.Tolerations = function.Spec.Tolerations
-		deployment.Spec.Template.Spec.Affinity = function.Spec.Affinity
+		deployment.Spec.Template.Spec.Affinity = lc.getFunctionAffinity(function)
 		deployment.Spec.Template.Spec.NodeSelector = function.Status.EnrichedNodeSelector
 		deployment.Spec.Template.Spec.NodeName = function.Spec.NodeName
 		deployment.Spec.Template.Spec.PriorityClassName = function.Spec.PriorityClassName
@@ -1254,6 +1254,48 @@ func (lc *lazyClient) createOrUpdateDeployment(ctx context.Context,
 	return resource.(*appsv1.Deployment), err
 }
 
+// getFunctionAffinity returns the function's affinity, restricted to nodes of the architectures its image was
+// built for
+func (lc *lazyClient) getFunctionAffinity(function *nuclioio.NuclioFunction) *v1.Affinity {
+	architectures := function.Spec.Build.GetPlatformArchitectures()
+	if len(architectures) == 0 {
+		return function.Spec.Affinity
+	}
+
+	architectureRequirement := v1.NodeSelectorRequirement{
+		Key:      v1.LabelArchStable,
+		Operator: v1.NodeSelectorOpIn,
+		Values:   architectures,
+	}
+
+	affinity := &v1.Affinity{}
+	if function.Spec.Affinity != nil {
+		affinity = function.Spec.Affinity.DeepCopy()
+	}
+
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &v1.NodeAffinity{}
+	}
+
+	if affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{}
+	}
+
+	// node selector terms are ORed, so each of them must require the architecture
+	nodeSelector := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(nodeSelector.NodeSelectorTerms) == 0 {
+		nodeSelector.NodeSelectorTerms = []v1.NodeSelectorTerm{{}}
+	}
+
+	for termIndex := range nodeSelector.NodeSelectorTerms {
+		nodeSelector.NodeSelectorTerms[termIndex].MatchExpressions = append(
+			nodeSelector.NodeSelectorTerms[termIndex].MatchExpressions,
+			architectureRequirement)
+	}
+
+	return affinity
+}
+
 func (lc *lazyClient) populateSupplementaryContainers(ctx context.Context,
 	function *nuclioio.NuclioFunction,
 	deploymentSpec *appsv1.DeploymentSpec,
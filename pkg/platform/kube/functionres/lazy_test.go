@@ -126,6 +126,58 @@ func (suite *lazyTestSuite) TestNodeConstrains() {
 	deployment.Spec.Template.Spec.Affinity = functionInstance.Spec.Affinity
 }
 
+func (suite *lazyTestSuite) TestBuildPlatformsAffinity() {
+	functionInstance := &nuclioio.NuclioFunction{}
+	functionInstance.Name = "func-name"
+
+	// functions built for the local platform aren't restricted
+	suite.Require().Nil(suite.client.getFunctionAffinity(functionInstance))
+
+	functionInstance.Spec.Build.Platforms = []string{"linux/amd64", "linux/arm64", "linux/arm64/v8"}
+	architectureRequirement := v1.NodeSelectorRequirement{
+		Key:      v1.LabelArchStable,
+		Operator: v1.NodeSelectorOpIn,
+		Values:   []string{"amd64", "arm64"},
+	}
+
+	affinity := suite.client.getFunctionAffinity(functionInstance)
+	suite.Require().Equal([]v1.NodeSelectorTerm{
+		{MatchExpressions: []v1.NodeSelectorRequirement{architectureRequirement}},
+	}, affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms)
+
+	// every term of the function's own affinity requires the architecture
+	userRequirement := v1.NodeSelectorRequirement{
+		Key:      "req-key",
+		Operator: v1.NodeSelectorOpIn,
+		Values:   []string{"a"},
+	}
+	functionInstance.Spec.Affinity = &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{
+					{MatchExpressions: []v1.NodeSelectorRequirement{userRequirement}},
+					{MatchFields: []v1.NodeSelectorRequirement{{Key: "metadata.name", Operator: v1.NodeSelectorOpIn}}},
+				},
+			},
+		},
+	}
+
+	resources, err := suite.client.CreateOrUpdate(suite.ctx, functionInstance, "")
+	suite.Require().NoError(err)
+	deployment, err := resources.Deployment()
+	suite.Require().NoError(err)
+
+	nodeSelectorTerms := deployment.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	suite.Require().Len(nodeSelectorTerms, 2)
+	suite.Require().Equal([]v1.NodeSelectorRequirement{userRequirement, architectureRequirement},
+		nodeSelectorTerms[0].MatchExpressions)
+	suite.Require().Equal([]v1.NodeSelectorRequirement{architectureRequirement}, nodeSelectorTerms[1].MatchExpressions)
+
+	// the function's spec is left as is
+	suite.Require().Len(functionInstance.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.
+		NodeSelectorTerms[0].MatchExpressions, 1)
+}
+
 func (suite *lazyTestSuite) TestEnrichIngressWithDefaultAnnotations() {
 	defaultIngressAnnotations := map[string]string{
 		"a": "b",
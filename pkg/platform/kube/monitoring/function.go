@@ -26,6 +26,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/errgroup"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/logprocessing"
 	"github.com/nuclio/nuclio/pkg/platform/kube"
 	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
 	nuclioioclient "github.com/nuclio/nuclio/pkg/platform/kube/client/clientset/versioned"
@@ -42,6 +43,9 @@ var (
 	PostDeploymentMonitoringBlockingInterval = 60 * time.Second
 )
 
+// the number of processor log lines looked through for why an unhealthy function's wrapper is crash looping
+const unhealthyFunctionPodLogLines = 100
+
 type FunctionMonitor struct {
 	logger                          logger.Logger
 	namespace                       string
@@ -199,7 +203,7 @@ func (fm *FunctionMonitor) updateFunctionStatus(ctx context.Context, function *n
 		stateChanged = true
 	} else if !functionIsAvailable && function.Status.State == functionconfig.FunctionStateReady {
 		function.Status.State = functionconfig.FunctionStateUnhealthy
-		function.Status.Message = string(common.FunctionStateMessageUnhealthy)
+		function.Status.Message = fm.getUnhealthyFunctionMessage(ctx, function)
 		stateChanged = true
 	}
 
@@ -232,6 +236,55 @@ func (fm *FunctionMonitor) updateFunctionStatus(ctx context.Context, function *n
 	return nil
 }
 
+// getUnhealthyFunctionMessage returns the status message of an unhealthy function, which includes why its
+// wrapper is crash looping if the logs of its last created pod report that it is
+func (fm *FunctionMonitor) getUnhealthyFunctionMessage(ctx context.Context,
+	function *nuclioio.NuclioFunction) string {
+	var lastCreatedPod *v1.Pod
+
+	functionPods, err := fm.kubeClientSet.
+		CoreV1().
+		Pods(function.Namespace).
+		List(ctx, metav1.ListOptions{
+			LabelSelector: common.CompileListFunctionPodsLabelSelector(function.Name),
+		})
+	if err != nil {
+		fm.logger.WarnWithCtx(ctx,
+			"Failed to list unhealthy function pods",
+			"functionName", function.Name,
+			"err", err.Error())
+		return string(common.FunctionStateMessageUnhealthy)
+	}
+
+	for podIndex, pod := range functionPods.Items {
+		if lastCreatedPod == nil || lastCreatedPod.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			lastCreatedPod = &functionPods.Items[podIndex]
+		}
+	}
+
+	if lastCreatedPod == nil {
+		return string(common.FunctionStateMessageUnhealthy)
+	}
+
+	tailLines := int64(unhealthyFunctionPodLogLines)
+	podLogs, err := fm.kubeClientSet.
+		CoreV1().
+		Pods(function.Namespace).
+		GetLogs(lastCreatedPod.Name, &v1.PodLogOptions{TailLines: &tailLines}).
+		Stream(ctx)
+	if err != nil {
+		fm.logger.WarnWithCtx(ctx,
+			"Failed to get unhealthy function pod logs",
+			"functionName", function.Name,
+			"podName", lastCreatedPod.Name,
+			"err", err.Error())
+		return string(common.FunctionStateMessageUnhealthy)
+	}
+	defer podLogs.Close() // nolint: errcheck
+
+	return logprocessing.GetUnhealthyFunctionMessage(podLogs)
+}
+
 func (fm *FunctionMonitor) isAvailable(deployment *appsv1.Deployment) bool {
 
 	// require at least one replica
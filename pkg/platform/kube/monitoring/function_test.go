@@ -24,6 +24,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform/kube"
 	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
@@ -215,6 +216,7 @@ func (suite *FunctionMonitoringTestSuite) TestStatusNotChangeWhileScaling() {
 	suite.Require().NoError(err)
 	suite.Require().NotNil(function)
 	suite.Require().Equal(functionconfig.FunctionStateUnhealthy, function.Status.State)
+	suite.Require().Equal(string(common.FunctionStateMessageUnhealthy), function.Status.Message)
 }
 
 func TestFunctionMonitoringTestSuite(t *testing.T) {
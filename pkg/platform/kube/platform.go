@@ -242,6 +242,9 @@ func (p *Platform) CreateFunction(ctx context.Context, createFunctionOptions *pl
 	// save the log stream for the name
 	p.DeployLogStreams.Store(createFunctionOptions.FunctionConfig.Meta.GetUniqueID(), logStream)
 
+	// let clients following the deployment know it has ended
+	defer logStream.Close()
+
 	// replace logger
 	createFunctionOptions.Logger = logStream.GetLogger()
 
@@ -1373,13 +1376,19 @@ func (p *Platform) InitializeContainerBuilder() error {
 	containerBuilderConfiguration := p.GetConfig().ContainerBuilderConfiguration
 
 	// create container builder
-	if containerBuilderConfiguration.Kind == "kaniko" {
+	switch containerBuilderConfiguration.Kind {
+	case "kaniko":
 		p.ContainerBuilder, err = containerimagebuilderpusher.NewKaniko(p.Logger,
 			p.consumer.KubeClientSet, containerBuilderConfiguration)
 		if err != nil {
 			return errors.Wrap(err, "Failed to create a kaniko builder")
 		}
-	} else {
+	case "oci":
+		p.ContainerBuilder, err = containerimagebuilderpusher.NewOCI(p.Logger, containerBuilderConfiguration)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create an oci builder")
+		}
+	default:
 
 		// Default container image builder
 		p.ContainerBuilder, err = containerimagebuilderpusher.NewDocker(p.Logger,
@@ -36,6 +36,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/containerimagebuilderpusher"
 	"github.com/nuclio/nuclio/pkg/dockerclient"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/logprocessing"
 	"github.com/nuclio/nuclio/pkg/opa"
 	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/platform/abstract"
@@ -115,8 +116,15 @@ func NewPlatform(ctx context.Context,
 		newPlatform.storeImageName = "gcr.io/iguazio/alpine:3.20"
 	}
 
-	if newPlatform.ContainerBuilder, err = containerimagebuilderpusher.NewDocker(newPlatform.Logger,
-		platformConfiguration.ContainerBuilderConfiguration); err != nil {
+	if platformConfiguration.ContainerBuilderConfiguration != nil &&
+		platformConfiguration.ContainerBuilderConfiguration.Kind == "oci" {
+		newPlatform.ContainerBuilder, err = containerimagebuilderpusher.NewOCI(newPlatform.Logger,
+			platformConfiguration.ContainerBuilderConfiguration)
+	} else {
+		newPlatform.ContainerBuilder, err = containerimagebuilderpusher.NewDocker(newPlatform.Logger,
+			platformConfiguration.ContainerBuilderConfiguration)
+	}
+	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create container image builder pusher")
 	}
 
@@ -230,6 +238,9 @@ func (p *Platform) CreateFunction(ctx context.Context, createFunctionOptions *pl
 	// save the log stream for the name
 	p.DeployLogStreams.Store(createFunctionOptions.FunctionConfig.Meta.GetUniqueID(), logStream)
 
+	// let clients following the deployment know it has ended
+	defer logStream.Close()
+
 	// replace logger
 	createFunctionOptions.Logger = logStream.GetLogger()
 
@@ -300,7 +311,10 @@ func (p *Platform) CreateFunction(ctx context.Context, createFunctionOptions *pl
 
 		var createFunctionResult *platform.CreateFunctionResult
 		var deployErr error
-		var functionStatus functionconfig.Status
+		functionStatus := functionconfig.Status{
+			ImageAttestation: createFunctionOptions.ImageAttestation,
+			SourceCommit:     createFunctionOptions.SourceCommit,
+		}
 
 		// delete existing function containers
 		previousHTTPPort, err := p.deleteOrStopFunctionContainers(createFunctionOptions)
@@ -843,7 +857,7 @@ func (p *Platform) ValidateFunctionContainersHealthiness(ctx context.Context) {
 				[]functionconfig.FunctionState{
 					functionconfig.FunctionStateError,
 					functionconfig.FunctionStateUnhealthy,
-				}) && functionStatus.Message == string(common.FunctionStateMessageUnhealthy)
+				}) && logprocessing.IsUnhealthyFunctionMessage(functionStatus.Message)
 			if !(functionIsReady || functionWasSetAsUnhealthy) || functionConfig.Spec.Disable {
 
 				// cannot be monitored
@@ -874,7 +888,7 @@ func (p *Platform) ValidateFunctionContainersHealthiness(ctx context.Context) {
 				p.Logger.WarnWithCtx(ctx, "No containers were found", "functionName", functionName)
 
 				// no running containers were found for function, set function unhealthy
-				if err := p.setFunctionUnhealthy(function); err != nil {
+				if err := p.setFunctionUnhealthy(function, ""); err != nil {
 					p.Logger.ErrorWithCtx(ctx, "Failed to mark a function as unhealthy",
 						"err", err,
 						"functionName", functionName,
@@ -992,7 +1006,8 @@ func (p *Platform) deployFunction(createFunctionOptions *platform.CreateFunction
 			return nil, errors.Wrap(err, "Failed to run a Docker container")
 		}
 
-		if err := p.waitForContainer(containerID,
+		if err := p.waitForContainer(createFunctionOptions.Logger,
+			containerID,
 			createFunctionOptions.FunctionConfig.Spec.ReadinessTimeoutSeconds); err != nil {
 			return nil, err
 		}
@@ -1242,19 +1257,29 @@ func (p *Platform) deleteOrStopFunctionContainers(createFunctionOptions *platfor
 func (p *Platform) checkAndSetFunctionUnhealthy(containerID string, function platform.Function) error {
 	if err := p.dockerClient.AwaitContainerHealth(containerID,
 		&p.Config.Local.FunctionContainersHealthinessTimeout); err != nil {
-		return p.setFunctionUnhealthy(function)
+		return p.setFunctionUnhealthy(function, containerID)
 	}
 	return nil
 }
 
-func (p *Platform) setFunctionUnhealthy(function platform.Function) error {
+func (p *Platform) setFunctionUnhealthy(function platform.Function, containerID string) error {
 	functionStatus := function.GetStatus()
 
 	// set function state to error
 	functionStatus.State = functionconfig.FunctionStateUnhealthy
 
-	// set unhealthy error message
-	functionStatus.Message = string(common.FunctionStateMessageUnhealthy)
+	// set unhealthy error message, including why the function's wrapper is crash looping if it is
+	var containerLogs string
+	if containerID != "" {
+		var err error
+		if containerLogs, err = p.dockerClient.GetContainerLogs(containerID); err != nil {
+			p.Logger.WarnWith("Failed to get unhealthy function container logs",
+				"functionName", function.GetConfig().Meta.Name,
+				"containerID", containerID,
+				"err", err.Error())
+		}
+	}
+	functionStatus.Message = logprocessing.GetUnhealthyFunctionMessage(strings.NewReader(containerLogs))
 
 	p.Logger.WarnWith("Setting function state as unhealthy",
 		"functionName", function.GetConfig().Meta.Name,
@@ -1291,8 +1316,8 @@ func (p *Platform) checkAndSetFunctionHealthy(containerID string, function platf
 	})
 }
 
-func (p *Platform) waitForContainer(containerID string, timeout int) error {
-	p.Logger.InfoWith("Waiting for function to be ready",
+func (p *Platform) waitForContainer(loggerInstance logger.Logger, containerID string, timeout int) error {
+	loggerInstance.InfoWith("Waiting for function to be ready",
 		"timeout", timeout)
 
 	readinessTimeout := time.Duration(timeout) * time.Second
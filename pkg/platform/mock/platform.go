@@ -339,6 +339,32 @@ func (mp *Platform) BuildAndPushContainerImage(ctx context.Context, buildOptions
 	return nil
 }
 
+func (mp *Platform) ResolveImageDigest(ctx context.Context, image string) (string, error) {
+	return "", nil
+}
+
+func (mp *Platform) ReuseCachedImage(ctx context.Context,
+	buildOptions *containerimagebuilderpusher.BuildOptions,
+	fingerprint string) (bool, error) {
+	return false, nil
+}
+
+func (mp *Platform) PushImageIndex(ctx context.Context,
+	image string,
+	registryURL string,
+	platformImages []string) error {
+	return nil
+}
+
+func (mp *Platform) AttestContainerImage(ctx context.Context,
+	attestationOptions *containerimagebuilderpusher.AttestationOptions) (*functionconfig.ImageAttestation, error) {
+	return nil, nil
+}
+
+func (mp *Platform) PullArtifact(ctx context.Context, artifactReference string, outputDir string) ([]string, error) {
+	return nil, nil
+}
+
 func (mp *Platform) GetOnbuildStages(onbuildArtifacts []runtime.Artifact) ([]string, error) {
 	return []string{}, nil
 }
@@ -363,6 +389,12 @@ func (mp *Platform) GetDefaultRegistryCredentialsSecretName() string {
 	return "nuclio-registry-credentials"
 }
 
+func (mp *Platform) ReadFunctionDeployEvents(ctx context.Context,
+	readFunctionDeployEventsOptions *platform.ReadFunctionDeployEventsOptions) (*platform.ReadFunctionDeployEventsResult, error) {
+	args := mp.Called(ctx, readFunctionDeployEventsOptions)
+	return args.Get(0).(*platform.ReadFunctionDeployEventsResult), args.Error(1)
+}
+
 func (mp *Platform) SaveFunctionDeployLogs(ctx context.Context, functionName, namespace string) error {
 	return nil
 }
@@ -195,6 +195,22 @@ type Platform interface {
 	// BuildAndPushContainerImage builds container image and pushes it into container registry
 	BuildAndPushContainerImage(ctx context.Context, buildOptions *containerimagebuilderpusher.BuildOptions) error
 
+	// ResolveImageDigest returns the digest of an image used by builds, or an empty string if it can't be resolved
+	ResolveImageDigest(ctx context.Context, image string) (string, error)
+
+	// ReuseCachedImage reuses the image of a previous build with the same fingerprint, if there is one
+	ReuseCachedImage(ctx context.Context, buildOptions *containerimagebuilderpusher.BuildOptions, fingerprint string) (bool, error)
+
+	// PushImageIndex pushes an index of images built for different platforms
+	PushImageIndex(ctx context.Context, image string, registryURL string, platformImages []string) error
+
+	// AttestContainerImage attaches an SBOM and a signature to a pushed container image
+	AttestContainerImage(ctx context.Context,
+		attestationOptions *containerimagebuilderpusher.AttestationOptions) (*functionconfig.ImageAttestation, error)
+
+	// PullArtifact writes the files of an OCI artifact in a registry to a directory, and returns their paths
+	PullArtifact(ctx context.Context, artifactReference string, outputDir string) ([]string, error)
+
 	// GetOnbuildStages Get Onbuild stage for multistage builds
 	GetOnbuildStages(onbuildArtifacts []runtime.Artifact) ([]string, error)
 
@@ -216,6 +232,10 @@ type Platform interface {
 	// SaveFunctionDeployLogs Save build logs from platform logger to function store or k8s
 	SaveFunctionDeployLogs(ctx context.Context, functionName, namespace string) error
 
+	// ReadFunctionDeployEvents returns the build and deployment events of a function deployed by this platform
+	// instance, waiting for new events if there are none yet. returns nil if this instance didn't deploy the function
+	ReadFunctionDeployEvents(ctx context.Context, readFunctionDeployEventsOptions *ReadFunctionDeployEventsOptions) (*ReadFunctionDeployEventsResult, error)
+
 	// GetProcessorLogsAndBriefError Parse and construct a function processor logs and brief error
 	GetProcessorLogsAndBriefError(scanner *bufio.Scanner) (string, string)
 
@@ -67,6 +67,10 @@ type CreateFunctionOptions struct {
 	PermissionOptions          opa.PermissionOptions
 	AuthSession                auth.Session
 	AutofixConfiguration       bool
+
+	// set after the function is built, to be recorded in its status
+	ImageAttestation *functionconfig.ImageAttestation
+	SourceCommit     string
 }
 
 type UpdateFunctionOptions struct {
@@ -108,6 +112,12 @@ type CreateFunctionBuildResult struct {
 
 	// the function configuration read by the builder either from function.yaml or inline configuration
 	UpdatedFunctionConfig functionconfig.Config
+
+	// the SBOM and signature attached to the image, if any
+	ImageAttestation *functionconfig.ImageAttestation
+
+	// the SHA of the git commit the image was built from, if any
+	SourceCommit string
 }
 
 // CreateFunctionResult holds the results of a deployment
@@ -541,6 +551,25 @@ func (s *APIGatewaySpec) DeepCopyInto(out *APIGatewaySpec) {
 	*out = *s
 }
 
+type ReadFunctionDeployEventsOptions struct {
+	FunctionMeta *functionconfig.Meta
+
+	// The offset of the first event to read - 0, or the next offset returned by a previous read
+	Offset int
+}
+
+type ReadFunctionDeployEventsResult struct {
+
+	// The events - log entries of the deployment, and lines of build output
+	Events []map[string]interface{}
+
+	// The offset of the events that follow
+	NextOffset int
+
+	// Whether the deployment has ended, and no events will follow
+	Done bool
+}
+
 type GetFunctionReplicaLogsStreamOptions struct {
 
 	// The replica (pod / container) name
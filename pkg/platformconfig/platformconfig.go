@@ -19,6 +19,7 @@ package platformconfig
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
@@ -61,6 +62,8 @@ type Config struct {
 	StreamMonitoring          StreamMonitoringConfig           `json:"streamMonitoring,omitempty"`
 	SensitiveFields           SensitiveFieldsConfig            `json:"sensitiveFields,omitempty"`
 	DisableDefaultHTTPTrigger bool                             `json:"disableDefaultHTTPTrigger,omitempty"`
+	ImageAttestation          ImageAttestationConfig           `json:"imageAttestation,omitempty"`
+	EventRecording            EventRecordingConfig             `json:"eventRecording,omitempty"`
 
 	ContainerBuilderConfiguration *containerimagebuilderpusher.ContainerBuilderConfiguration `json:"containerBuilderConfiguration,omitempty"`
 
@@ -201,7 +204,18 @@ func (c *Config) GetFunctionLoggerSinks(functionConfig *functionconfig.Config) (
 		loggerSinkBindings = c.Logger.Functions
 	}
 
-	return c.getLoggerSinksWithLevel(loggerSinkBindings)
+	loggerSinksWithLevel, err := c.getLoggerSinksWithLevel(loggerSinkBindings)
+	if err != nil {
+		return nil, err
+	}
+
+	// let sinks derive labels from the function they're bound to
+	for sinkName, loggerSinkWithLevel := range loggerSinksWithLevel {
+		loggerSinkWithLevel.functionMeta = &functionConfig.Meta
+		loggerSinksWithLevel[sinkName] = loggerSinkWithLevel
+	}
+
+	return loggerSinksWithLevel, nil
 }
 
 func (c *Config) GetDefaultFunctionReadinessTimeout() time.Duration {
@@ -215,6 +229,15 @@ func (c *Config) GetDefaultFunctionReadinessTimeout() time.Duration {
 	return DefaultFunctionReadinessTimeoutSeconds * time.Second
 }
 
+// GetEventRecordingsDir returns the directory trigger event recordings are written to
+func (c *Config) GetEventRecordingsDir() string {
+	if c.EventRecording.Dir != "" {
+		return c.EventRecording.Dir
+	}
+
+	return filepath.Join(os.TempDir(), DefaultEventRecordingsDirName)
+}
+
 func (c *Config) GetDefaultFunctionInvocationTimeout() time.Duration {
 
 	// provided by the platform-c
@@ -34,6 +34,7 @@ import (
 const (
 	DefaultFunctionReadinessTimeoutSeconds  = 120
 	DefaultFunctionInvocationTimeoutSeconds = 60
+	DefaultEventRecordingsDirName           = "nuclio-recordings"
 )
 
 type LoggerSinkKind string
@@ -41,6 +42,8 @@ type LoggerSinkKind string
 const (
 	LoggerSinkKindStdout      LoggerSinkKind = "stdout"
 	LoggerSinkKindAppInsights LoggerSinkKind = "appinsights"
+	LoggerSinkKindLoki        LoggerSinkKind = "loki"
+	LoggerSinkKindOTLP        LoggerSinkKind = "otlp"
 
 	// LoggerSinkKindElasticsearch is not supported
 	LoggerSinkKindElasticsearch LoggerSinkKind = "elasticsearch"
@@ -56,13 +59,19 @@ type LoggerSinkWithLevel struct {
 	Level string
 	Sink  LoggerSink
 
-	redactor *nucliozap.Redactor
+	redactor     *nucliozap.Redactor
+	functionMeta *functionconfig.Meta
 }
 
 func (l *LoggerSinkWithLevel) GetRedactingLogger() *nucliozap.Redactor {
 	return l.redactor
 }
 
+// GetFunctionMeta returns the meta of the function the sink is bound to, or nil for system sinks
+func (l *LoggerSinkWithLevel) GetFunctionMeta() *functionconfig.Meta {
+	return l.functionMeta
+}
+
 type LoggerSinkBinding struct {
 	Level string `json:"level,omitempty"`
 	Sink  string `json:"sink,omitempty"`
@@ -292,6 +301,28 @@ type StreamMonitoringConfig struct {
 	V3ioRequestConcurrency uint   `json:"v3ioRequestConcurrency,omitempty"`
 }
 
+// EventRecordingConfig configures the recording of events received by triggers
+type EventRecordingConfig struct {
+
+	// recordings may only be written to files in this directory. Defaults to a directory in the temp dir
+	Dir string `json:"dir,omitempty"`
+}
+
+// ImageAttestationConfig configures the SBOMs and signatures attached to built function images
+type ImageAttestationConfig struct {
+
+	// attach an SBOM to every built image, in the function's format or in DefaultSBOMFormat
+	RequireSBOM       bool                      `json:"requireSBOM,omitempty"`
+	DefaultSBOMFormat functionconfig.SBOMFormat `json:"defaultSBOMFormat,omitempty"`
+
+	// sign every built image
+	RequireSignature bool `json:"requireSignature,omitempty"`
+
+	// path to the PEM-encoded private key that signs images. keys generated by cosign are decrypted with the
+	// password in the COSIGN_PASSWORD environment variable
+	SigningKeyPath string `json:"signingKeyPath,omitempty"`
+}
+
 type SensitiveFieldPath string
 
 type SensitiveFieldsConfig struct {
@@ -307,10 +338,13 @@ func (sfc *SensitiveFieldsConfig) GetDefaultSensitiveFields() []string {
 
 		// build
 		"^/spec/build/codeentryattributes/password$",
+		"^/spec/build/codeentryattributes/sshprivatekey$",
+		"^/spec/build/codeentryattributes/sshprivatekeypassphrase$",
 		"^/spec/build/codeentryattributes/s3secretaccesskey$",
 		"^/spec/build/codeentryattributes/s3sessiontoken$",
 		"^/spec/build/codeentryattributes/headers/authorization$",
 		"^/spec/build/codeentryattributes/headers/x-v3io-session-key$",
+		"^/spec/build/packageregistries\\[\\d+\\]/password$",
 
 		// volumes
 		"^/spec/volumes\\[\\d+\\]/volume/volumesource/flexvolume/options/accesskey$",
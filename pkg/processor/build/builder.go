@@ -19,7 +19,9 @@ package build
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"math"
 	"net/http"
@@ -52,11 +54,13 @@ import (
 	// load runtimes so that they register to runtime registry
 	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/dotnetcore"
 	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/golang"
+	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/httpsidecar"
 	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/java"
 	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/nodejs"
 	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/python"
 	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/ruby"
 	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/shell"
+	_ "github.com/nuclio/nuclio/pkg/processor/build/runtime/wasm"
 )
 
 const (
@@ -66,6 +70,7 @@ const (
 	S3EntryType         = "s3"
 	ImageEntryType      = "image"
 	SourceCodeEntryType = "sourceCode"
+	OCIEntryType        = "oci"
 	// TODO: Remove in 1.16.0
 	GithubEntryType = "github"
 
@@ -131,6 +136,12 @@ type Builder struct {
 	versionInfo *version.Info
 
 	gitClient gitcommon.Client
+
+	// the platform the processor image is being built for, when building for several platforms
+	targetPlatform string
+
+	// the SHA of the git commit the function was cloned from
+	sourceCommit string
 }
 
 // NewBuilder returns a new builder
@@ -217,9 +228,10 @@ func (b *Builder) Build(ctx context.Context, options *platform.CreateFunctionBui
 		b.parseInlineBlocks() // nolint: errcheck
 
 		// don't fail on parseInlineBlocks so that if the parser fails on something we won't block deployments. the only
-		// exception is if the user provided a block with improper contents
+		// exception is if the user provided a block with improper contents, which is reported before anything is built
 		if b.inlineConfigurationBlock.Error != nil {
-			return nil, errors.Wrap(b.inlineConfigurationBlock.Error, "Failed to parse inline configuration")
+			return nil, nuclio.WrapErrBadRequest(errors.Wrap(b.inlineConfigurationBlock.Error,
+				"Failed to parse inline configuration"))
 		}
 
 		// populate function source code only if needed
@@ -248,6 +260,18 @@ func (b *Builder) Build(ctx context.Context, options *platform.CreateFunctionBui
 		return nil, errors.Wrap(err, "Failed to enrich configuration")
 	}
 
+	if err := b.enrichAndValidateImageAttestation(); err != nil {
+		return nil, errors.Wrap(err, "Failed to validate image attestation")
+	}
+
+	if err := b.validateBuildPlatforms(); err != nil {
+		return nil, errors.Wrap(err, "Failed to validate build platforms")
+	}
+
+	if err := b.validatePackageRegistries(); err != nil {
+		return nil, errors.Wrap(err, "Failed to validate package registries")
+	}
+
 	// copy the configuration we enriched, restoring any fields that should not be leaked externally
 	enrichedConfiguration := b.options.FunctionConfig
 
@@ -280,9 +304,17 @@ func (b *Builder) Build(ctx context.Context, options *platform.CreateFunctionBui
 		enrichedConfiguration.Spec.Image = processorImage
 	}
 
+	// attach an SBOM and a signature to the pushed image, if required
+	imageAttestation, err := b.attestProcessorImage(ctx, processorImage)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to attest processor image")
+	}
+
 	buildResult := &platform.CreateFunctionBuildResult{
 		Image:                 processorImage,
 		UpdatedFunctionConfig: enrichedConfiguration,
+		ImageAttestation:      imageAttestation,
+		SourceCommit:          b.sourceCommit,
 	}
 
 	// info log only the image name, so that the un-scrubbed function config won't be logged to the user
@@ -427,20 +459,22 @@ CMD [ "processor" ]
 func (b *Builder) initializeSupportedRuntimes() {
 	b.runtimeInfo = map[string]runtimeInfo{}
 
-	// create a few shared parsers
-	slashSlashParser := inlineparser.NewParser(b.logger, "//")
-	poundParser := inlineparser.NewParser(b.logger, "#")
+	// a shared parser, recognizing both "//" and "#" comments so that every runtime accepts either style
+	commentParser := inlineparser.NewParser(b.logger, inlineparser.DefaultCommentChars...)
 
-	b.runtimeInfo["shell"] = runtimeInfo{"sh", poundParser, 0}
-	b.runtimeInfo["golang"] = runtimeInfo{"go", slashSlashParser, 0}
-	b.runtimeInfo["python"] = runtimeInfo{"py", poundParser, 10}
-	b.runtimeInfo["python:3.9"] = runtimeInfo{"py", poundParser, 5}
-	b.runtimeInfo["python:3.10"] = runtimeInfo{"py", poundParser, 5}
-	b.runtimeInfo["python:3.11"] = runtimeInfo{"py", poundParser, 5}
-	b.runtimeInfo["nodejs"] = runtimeInfo{"js", slashSlashParser, 0}
-	b.runtimeInfo["java"] = runtimeInfo{"java", slashSlashParser, 0}
-	b.runtimeInfo["ruby"] = runtimeInfo{"rb", poundParser, 0}
-	b.runtimeInfo["dotnetcore"] = runtimeInfo{"cs", slashSlashParser, 0}
+	b.runtimeInfo["shell"] = runtimeInfo{"sh", commentParser, 0}
+	b.runtimeInfo["golang"] = runtimeInfo{"go", commentParser, 0}
+	b.runtimeInfo["python"] = runtimeInfo{"py", commentParser, 10}
+	b.runtimeInfo["python:3.9"] = runtimeInfo{"py", commentParser, 5}
+	b.runtimeInfo["python:3.10"] = runtimeInfo{"py", commentParser, 5}
+	b.runtimeInfo["python:3.11"] = runtimeInfo{"py", commentParser, 5}
+	b.runtimeInfo["nodejs"] = runtimeInfo{"js", commentParser, 0}
+	b.runtimeInfo["java"] = runtimeInfo{"java", commentParser, 0}
+	b.runtimeInfo["ruby"] = runtimeInfo{"rb", commentParser, 0}
+	b.runtimeInfo["dotnetcore"] = runtimeInfo{"cs", commentParser, 0}
+
+	// modules are binary, there are no inline blocks to parse
+	b.runtimeInfo["wasm"] = runtimeInfo{"wasm", nil, 0}
 }
 
 func (b *Builder) readConfiguration() (string, error) {
@@ -684,6 +718,13 @@ func (b *Builder) resolveFunctionPath(ctx context.Context, functionPath string)
 		}
 	}
 
+	// the function is an artifact in a registry, pulled into a directory or a file (e.g. an archive)
+	if codeEntryType == OCIEntryType {
+		if functionPath, err = b.pullFunctionArtifact(ctx, functionPath); err != nil {
+			return "", "", errors.Wrap(err, "Failed to pull function artifact")
+		}
+	}
+
 	// user has to provide valid url when code entry type is github or archive
 	isURL := common.IsURL(functionPath)
 	if !isURL && (codeEntryType == GithubEntryType || codeEntryType == ArchiveEntryType) {
@@ -706,6 +747,13 @@ func (b *Builder) resolveFunctionPath(ctx context.Context, functionPath string)
 		return "", "", errors.Errorf("Function path doesn't exist: %s", resolvedPath)
 	}
 
+	// downloaded files are verified as they're downloaded
+	if !isURL && codeEntryType != S3EntryType {
+		if err := b.verifyFunctionChecksum(resolvedPath); err != nil {
+			return "", "", errors.Wrap(err, "Failed to verify function file")
+		}
+	}
+
 	// when no code entry type was passed and it's an archive or jar
 	if codeEntryType == "" && (util.IsArchive(resolvedPath) || util.IsJar(resolvedPath) || common.IsDir(resolvedPath)) {
 
@@ -1042,8 +1090,6 @@ func (b *Builder) cleanupTempDir() error {
 }
 
 func (b *Builder) buildProcessorImage(ctx context.Context) (string, error) {
-	buildArgs := b.getBuildArgs()
-	buildFlags := b.getBuildFlags()
 
 	// get override base and onbuild image registries from the platform configuration
 
@@ -1071,59 +1117,270 @@ func (b *Builder) buildProcessorImage(ctx context.Context) (string, error) {
 		}
 	}
 
+	taggedImageName := fmt.Sprintf("%s:%s", b.processorImage.imageName, b.processorImage.imageTag)
+	registryURL := b.options.FunctionConfig.Spec.Build.Registry
+
+	if len(b.options.FunctionConfig.Spec.Build.Platforms) == 0 {
+		return taggedImageName, b.buildPlatformProcessorImage(ctx,
+			baseImageRegistry,
+			onbuildImageRegistry,
+			taggedImageName)
+	}
+
+	// the image of a single platform needs no index
+	if len(b.options.FunctionConfig.Spec.Build.Platforms) == 1 {
+		b.setTargetPlatform(b.options.FunctionConfig.Spec.Build.Platforms[0])
+		defer b.setTargetPlatform("")
+
+		return taggedImageName, b.buildPlatformProcessorImage(ctx,
+			baseImageRegistry,
+			onbuildImageRegistry,
+			taggedImageName)
+	}
+
+	// build an image per platform, then push an index of them under the function's image, from which each
+	// node pulls the image of its own platform
+	var platformImages []string
+	for _, targetPlatform := range b.options.FunctionConfig.Spec.Build.Platforms {
+		platformImage := fmt.Sprintf("%s-%s", taggedImageName, strings.ReplaceAll(targetPlatform, "/", "-"))
+
+		b.setTargetPlatform(targetPlatform)
+		err := b.buildPlatformProcessorImage(ctx, baseImageRegistry, onbuildImageRegistry, platformImage)
+		b.setTargetPlatform("")
+
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to build processor image for platform %s", targetPlatform)
+		}
+
+		platformImages = append(platformImages, platformImage)
+	}
+
+	b.logger.InfoWithCtx(ctx,
+		"Pushing multi-platform processor image",
+		"registryURL", registryURL,
+		"taggedImageName", taggedImageName,
+		"platformImages", platformImages)
+
+	if err := b.platform.PushImageIndex(ctx, taggedImageName, registryURL, platformImages); err != nil {
+		return "", errors.Wrap(err, "Failed to push multi-platform image index")
+	}
+
+	return taggedImageName, nil
+}
+
+// buildPlatformProcessorImage builds the processor image for the target platform, or for the builder's own platform
+// if there is none
+func (b *Builder) buildPlatformProcessorImage(ctx context.Context,
+	baseImageRegistry string,
+	onbuildImageRegistry string,
+	taggedImageName string) error {
+	buildArgs := b.getBuildArgs()
+	buildFlags := b.getBuildFlags()
+
 	processorDockerfileInfo, err := b.createProcessorDockerfile(ctx, baseImageRegistry, onbuildImageRegistry)
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to create processor dockerfile")
+		return errors.Wrap(err, "Failed to create processor dockerfile")
 	}
 
-	taggedImageName := fmt.Sprintf("%s:%s", b.processorImage.imageName, b.processorImage.imageTag)
 	registryURL := b.options.FunctionConfig.Spec.Build.Registry
 
 	enrichedNodeSelector, err := b.resolveNodeSelector(ctx)
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to enrich NodeSelector for image builder")
+		return errors.Wrap(err, "Failed to enrich NodeSelector for image builder")
+	}
+
+	buildOptions := &containerimagebuilderpusher.BuildOptions{
+		ContextDir:     b.stagingDir,
+		Image:          taggedImageName,
+		TempDir:        b.tempDir,
+		DockerfileInfo: processorDockerfileInfo,
+		Platform:       b.targetPlatform,
+
+		// Conjunct Pull with NoCache
+		// To ensure that when forcing a function build, the base images would be pulled as well.
+		Pull:                b.options.FunctionConfig.Spec.Build.NoCache,
+		NoCache:             b.options.FunctionConfig.Spec.Build.NoCache,
+		NoBaseImagePull:     b.GetNoBaseImagePull(),
+		BuildFlags:          buildFlags,
+		BuildArgs:           buildArgs,
+		RegistryURL:         registryURL,
+		RepoName:            b.resolveRepoName(registryURL),
+		SecretName:          b.options.FunctionConfig.Spec.ImagePullSecrets,
+		OutputImageFile:     b.options.OutputImageFile,
+		BuildTimeoutSeconds: b.resolveBuildTimeoutSeconds(),
+
+		// kaniko pod attributes
+		NodeSelector:           enrichedNodeSelector,
+		NodeName:               b.options.FunctionConfig.Spec.NodeName,
+		Affinity:               b.options.FunctionConfig.Spec.Affinity,
+		PriorityClassName:      b.options.FunctionConfig.Spec.PriorityClassName,
+		Tolerations:            b.options.FunctionConfig.Spec.Tolerations,
+		FunctionServiceAccount: b.options.FunctionConfig.Spec.ServiceAccount,
+		BuilderServiceAccount:  b.options.FunctionConfig.Spec.Build.BuilderServiceAccount,
+		ReadinessTimeoutSeconds: b.platform.GetConfig().GetFunctionReadinessTimeoutOrDefault(
+			b.options.FunctionConfig.Spec.ReadinessTimeoutSeconds),
+		SecurityContext: b.options.FunctionConfig.Spec.SecurityContext,
+		BuildLogger:     b.logger,
+	}
+
+	// skip the build if an image of an identical build already exists
+	fingerprint := processorDockerfileInfo.Labels[containerimagebuilderpusher.BuildFingerprintLabel]
+	if fingerprint != "" {
+		reused, err := b.platform.ReuseCachedImage(ctx, buildOptions, fingerprint)
+		if err != nil {
+			b.logger.WarnWithCtx(ctx,
+				"Failed to look up cached image, building it",
+				"taggedImageName", taggedImageName,
+				"err", errors.RootCause(err).Error())
+		} else if reused {
+			b.logger.InfoWithCtx(ctx,
+				"Reusing cached image, skipping build",
+				"registryURL", registryURL,
+				"taggedImageName", taggedImageName,
+				"fingerprint", fingerprint)
+			return nil
+		}
 	}
 
 	b.logger.InfoWithCtx(ctx,
 		"Building processor image",
 		"registryURL", registryURL,
-		"taggedImageName", taggedImageName)
-
-	err = b.platform.BuildAndPushContainerImage(ctx,
-		&containerimagebuilderpusher.BuildOptions{
-			ContextDir:     b.stagingDir,
-			Image:          taggedImageName,
-			TempDir:        b.tempDir,
-			DockerfileInfo: processorDockerfileInfo,
-
-			// Conjunct Pull with NoCache
-			// To ensure that when forcing a function build, the base images would be pulled as well.
-			Pull:                b.options.FunctionConfig.Spec.Build.NoCache,
-			NoCache:             b.options.FunctionConfig.Spec.Build.NoCache,
-			NoBaseImagePull:     b.GetNoBaseImagePull(),
-			BuildFlags:          buildFlags,
-			BuildArgs:           buildArgs,
-			RegistryURL:         registryURL,
-			RepoName:            b.resolveRepoName(registryURL),
-			SecretName:          b.options.FunctionConfig.Spec.ImagePullSecrets,
-			OutputImageFile:     b.options.OutputImageFile,
-			BuildTimeoutSeconds: b.resolveBuildTimeoutSeconds(),
-
-			// kaniko pod attributes
-			NodeSelector:           enrichedNodeSelector,
-			NodeName:               b.options.FunctionConfig.Spec.NodeName,
-			Affinity:               b.options.FunctionConfig.Spec.Affinity,
-			PriorityClassName:      b.options.FunctionConfig.Spec.PriorityClassName,
-			Tolerations:            b.options.FunctionConfig.Spec.Tolerations,
-			FunctionServiceAccount: b.options.FunctionConfig.Spec.ServiceAccount,
-			BuilderServiceAccount:  b.options.FunctionConfig.Spec.Build.BuilderServiceAccount,
-			ReadinessTimeoutSeconds: b.platform.GetConfig().GetFunctionReadinessTimeoutOrDefault(
-				b.options.FunctionConfig.Spec.ReadinessTimeoutSeconds),
-			SecurityContext: b.options.FunctionConfig.Spec.SecurityContext,
-			BuildLogger:     b.logger,
-		})
+		"taggedImageName", taggedImageName,
+		"platform", b.targetPlatform)
+
+	return b.platform.BuildAndPushContainerImage(ctx, buildOptions)
+}
+
+// setTargetPlatform sets the platform to build the processor image for, from the onbuild images of its
+// architecture. an empty platform restores the builder's own
+func (b *Builder) setTargetPlatform(targetPlatform string) {
+	arch := version.Get().Arch
+	if platformParts := strings.Split(targetPlatform, "/"); len(platformParts) > 1 {
+		arch = platformParts[1]
+	}
 
-	return taggedImageName, err
+	b.targetPlatform = targetPlatform
+	b.versionInfo.Arch = arch
+	b.runtime.SetArch(arch)
+}
+
+// validateBuildPlatforms validates the platforms to build the processor image for
+func (b *Builder) validateBuildPlatforms() error {
+	functionBuild := b.options.FunctionConfig.Spec.Build
+	if len(functionBuild.Platforms) == 0 {
+		return nil
+	}
+
+	for platformIndex, targetPlatform := range functionBuild.Platforms {
+		platformParts := strings.Split(targetPlatform, "/")
+		if len(platformParts) < 2 || len(platformParts) > 3 || platformParts[0] != "linux" || platformParts[1] == "" {
+			return nuclio.NewErrBadRequest(fmt.Sprintf("Invalid build platform %s, expected linux/<arch>[/<variant>]",
+				targetPlatform))
+		}
+
+		if common.StringSliceContainsString(functionBuild.Platforms[:platformIndex], targetPlatform) {
+			return nuclio.NewErrBadRequest(fmt.Sprintf("Build platform %s is listed more than once", targetPlatform))
+		}
+	}
+
+	// a single platform's image is built as is
+	if len(functionBuild.Platforms) == 1 {
+		return nil
+	}
+
+	// the platform images are combined into an index in the registry
+	if functionBuild.Registry == "" {
+		return nuclio.NewErrBadRequest("Building for several platforms requires a registry")
+	}
+
+	if b.options.OutputImageFile != "" {
+		return nuclio.NewErrBadRequest("Building for several platforms doesn't support saving the image to a file")
+	}
+
+	return nil
+}
+
+// validatePackageRegistries validates the function's package registries. their passwords are given in the function
+// configuration (and kept in secrets), since reading files is reserved to the platform's registries
+func (b *Builder) validatePackageRegistries() error {
+	for _, packageRegistry := range b.options.FunctionConfig.Spec.Build.PackageRegistries {
+		if err := packageRegistry.Validate(); err != nil {
+			return nuclio.WrapErrBadRequest(err)
+		}
+
+		if packageRegistry.PasswordPath != "" {
+			return nuclio.NewErrBadRequest(fmt.Sprintf("Package registry %s can't read its password from a file",
+				packageRegistry.URL))
+		}
+	}
+
+	return nil
+}
+
+// enrichAndValidateImageAttestation applies the platform's SBOM and signature requirements to the function, and
+// fails early if the image can't be attested after it's built
+func (b *Builder) enrichAndValidateImageAttestation() error {
+	attestationConfig := b.platform.GetConfig().ImageAttestation
+	functionBuild := &b.options.FunctionConfig.Spec.Build
+
+	if functionBuild.SBOMFormat == "" && attestationConfig.RequireSBOM {
+		functionBuild.SBOMFormat = attestationConfig.DefaultSBOMFormat
+		if functionBuild.SBOMFormat == "" {
+			functionBuild.SBOMFormat = functionconfig.SBOMFormatSPDX
+		}
+	}
+
+	if attestationConfig.RequireSignature {
+		functionBuild.Sign = true
+	}
+
+	if functionBuild.SBOMFormat == "" && !functionBuild.Sign {
+		return nil
+	}
+
+	switch functionBuild.SBOMFormat {
+	case "", functionconfig.SBOMFormatSPDX, functionconfig.SBOMFormatCycloneDX:
+	default:
+		return nuclio.NewErrBadRequest(fmt.Sprintf("Unsupported SBOM format: %s", functionBuild.SBOMFormat))
+	}
+
+	// SBOMs and signatures are attached to the image in its registry
+	if functionBuild.Registry == "" {
+		return nuclio.NewErrBadRequest("Attaching an SBOM or a signature to an image requires a registry")
+	}
+
+	if functionBuild.Sign && attestationConfig.SigningKeyPath == "" {
+		return nuclio.NewErrBadRequest("Signing images requires a signing key in the platform configuration")
+	}
+
+	return nil
+}
+
+func (b *Builder) attestProcessorImage(ctx context.Context,
+	processorImage string) (*functionconfig.ImageAttestation, error) {
+	functionBuild := b.options.FunctionConfig.Spec.Build
+
+	if functionBuild.SBOMFormat == "" && !functionBuild.Sign {
+		return nil, nil
+	}
+
+	attestationOptions := &containerimagebuilderpusher.AttestationOptions{
+		Image:        common.CompileImageName(functionBuild.Registry, processorImage),
+		SBOMFormat:   functionBuild.SBOMFormat,
+		ManifestsDir: b.getHandlerDir(b.stagingDir),
+	}
+
+	if functionBuild.Sign {
+		attestationOptions.SigningKeyPath = b.platform.GetConfig().ImageAttestation.SigningKeyPath
+	}
+
+	b.logger.InfoWithCtx(ctx,
+		"Attesting processor image",
+		"image", attestationOptions.Image,
+		"sbomFormat", functionBuild.SBOMFormat,
+		"sign", functionBuild.Sign)
+
+	return b.platform.AttestContainerImage(ctx, attestationOptions)
 }
 
 func (b *Builder) resolveRepoName(registryURL string) string {
@@ -1158,6 +1415,21 @@ func (b *Builder) createProcessorDockerfile(ctx context.Context,
 		"baseImageRegistry", baseImageRegistry,
 		"onbuildImageRegistry", onbuildImageRegistry)
 
+	// label the image with the build fingerprint, so that identical builds can reuse it
+	if !b.options.FunctionConfig.Spec.Build.NoCache {
+		fingerprint, err := b.computeBuildFingerprint(ctx, processorDockerfileInfo)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to compute build fingerprint")
+		}
+
+		processorDockerfileInfo.Labels = map[string]string{
+			containerimagebuilderpusher.BuildFingerprintLabel: fingerprint,
+		}
+		processorDockerfileInfo.DockerfileContents += fmt.Sprintf("\nLABEL %s=%s\n",
+			containerimagebuilderpusher.BuildFingerprintLabel,
+			fingerprint)
+	}
+
 	// write the contents to the path
 	if err := os.WriteFile(processorDockerfileInfo.DockerfilePath,
 		[]byte(processorDockerfileInfo.DockerfileContents),
@@ -1268,6 +1540,10 @@ func (b *Builder) getRuntimeCommentParser(logger logger.Logger, runtimeName stri
 		return nil, errors.Errorf("Unsupported runtime name: %s", runtimeName)
 	}
 
+	if runtimeInfo.inlineParser == nil {
+		return nil, errors.Errorf("Runtime %s does not support inline configuration", runtimeName)
+	}
+
 	return runtimeInfo.inlineParser, nil
 }
 
@@ -1299,11 +1575,20 @@ func (b *Builder) getRuntimeProcessorDockerfileInfo(baseImageRegistry string, on
 		}
 	}
 
+	// keep the function's own directives apart, for builders that apply them without a dockerfile
+	processorDockerfileInfo.FunctionDirectives = directives
+
 	// merge directives passed by user with directives passed by runtime
 	// let the directives dictated by runtime to comes first to allow pre-configuration such as
 	// installing ca-certs before executing build commands such as `pip install x`
 	directives = b.mergeDirectives(processorDockerfileInfo.Directives, directives)
 
+	// docker mounts the build secrets for each command. kaniko mounts them to its own filesystem, which it doesn't
+	// snapshot
+	if b.platform.GetContainerBuilderKind() == "docker" {
+		directives = b.mountBuildSecrets(directives, processorDockerfileInfo.BuildSecrets)
+	}
+
 	// path where generated dockerfile should reside (staging)
 	processorDockerfileInfo.DockerfilePath = filepath.Join(b.stagingDir, "Dockerfile.processor")
 
@@ -1342,6 +1627,7 @@ func (b *Builder) resolveProcessorDockerfileInfo(baseImageRegistry string,
 		OnbuildArtifacts:   runtimeProcessorDockerfileInfo.OnbuildArtifacts,
 		ImageArtifactPaths: runtimeProcessorDockerfileInfo.ImageArtifactPaths,
 		Directives:         runtimeProcessorDockerfileInfo.Directives,
+		BuildSecrets:       runtimeProcessorDockerfileInfo.BuildSecrets,
 	}
 
 	// set the base image
@@ -1577,6 +1863,35 @@ func (b *Builder) mergeDirectives(first map[string][]functionconfig.Directive,
 	return merged
 }
 
+// mountBuildSecrets has the RUN directives mount the build secrets as docker build secrets, so that they're
+// available to the commands without being written to the image
+func (b *Builder) mountBuildSecrets(directives map[string][]functionconfig.Directive,
+	buildSecrets []runtime.BuildSecret) map[string][]functionconfig.Directive {
+	if len(buildSecrets) == 0 {
+		return directives
+	}
+
+	var mountFlags []string
+	for _, buildSecret := range buildSecrets {
+		mountFlags = append(mountFlags, fmt.Sprintf("--mount=type=secret,id=%s,target=%s",
+			buildSecret.FileName,
+			buildSecret.Path))
+	}
+
+	mountedDirectives := map[string][]functionconfig.Directive{}
+	for key, keyDirectives := range directives {
+		for _, directive := range keyDirectives {
+			if strings.ToUpper(directive.Kind) == "RUN" {
+				directive.Value = fmt.Sprintf("%s %s", strings.Join(mountFlags, " "), directive.Value)
+			}
+
+			mountedDirectives[key] = append(mountedDirectives[key], directive)
+		}
+	}
+
+	return mountedDirectives
+}
+
 func (b *Builder) getSourceCodeFromFilePath() (string, error) {
 
 	// if the file path is after resolving function source code, do nothing
@@ -1645,6 +1960,10 @@ func (b *Builder) resolveFunctionPathFromURL(ctx context.Context, functionPath s
 				return "", errors.Wrap(err, "Failed to download function from git")
 			}
 
+			if err := b.verifyFunctionChecksum(tempDir); err != nil {
+				return "", errors.Wrap(err, "Failed to verify cloned repository")
+			}
+
 			return b.resolveUserSpecifiedWorkdir(tempDir)
 		}
 
@@ -1676,6 +1995,10 @@ func (b *Builder) resolveFunctionPathFromURL(ctx context.Context, functionPath s
 			return "", errors.Wrap(err, "Failed to download file")
 		}
 
+		if err := b.verifyFunctionChecksum(tempFile.Name()); err != nil {
+			return "", errors.Wrap(err, "Failed to verify downloaded file")
+		}
+
 		if isArchive && !util.IsArchive(tempFile.Name()) {
 			return "", errors.New("Downloaded file type is not supported. (expected an archive)")
 		}
@@ -1686,6 +2009,64 @@ func (b *Builder) resolveFunctionPathFromURL(ctx context.Context, functionPath s
 	return functionPath, nil
 }
 
+// verifyFunctionChecksum fails if the code entry's "sha256" attribute doesn't match the file's SHA256 digest
+func (b *Builder) verifyFunctionChecksum(filePath string) error {
+	expectedChecksumValue, found := b.options.FunctionConfig.Spec.Build.CodeEntryAttributes["sha256"]
+	if !found {
+		return nil
+	}
+
+	expectedChecksum, ok := expectedChecksumValue.(string)
+	if !ok {
+		return nuclio.NewErrBadRequest("The given field - 'sha256' is not of type string")
+	}
+
+	if common.IsDir(filePath) {
+		return nuclio.NewErrBadRequest("The given field - 'sha256' can only verify a file, but the function is a directory")
+	}
+
+	expectedChecksum = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(expectedChecksum), "sha256:"))
+
+	fileHash := sha256.New()
+	if err := hashFile(fileHash, filePath); err != nil {
+		return errors.Wrap(err, "Failed to compute file checksum")
+	}
+
+	if checksum := hex.EncodeToString(fileHash.Sum(nil)); checksum != expectedChecksum {
+		return nuclio.NewErrBadRequest(fmt.Sprintf("Function checksum mismatch: expected sha256 %s, got %s",
+			expectedChecksum,
+			checksum))
+	}
+
+	b.logger.DebugWith("Verified function checksum", "path", filePath, "sha256", expectedChecksum)
+
+	return nil
+}
+
+// pullFunctionArtifact pulls the function from an OCI artifact, returning the path of its single file or of the
+// directory of its files
+func (b *Builder) pullFunctionArtifact(ctx context.Context, artifactReference string) (string, error) {
+	if artifactReference == "" {
+		return "", nuclio.NewErrBadRequest("Must provide an artifact reference when code entry type is oci")
+	}
+
+	artifactDir, err := b.mkDirUnderTemp("artifact")
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create temporary dir for artifact")
+	}
+
+	artifactPaths, err := b.platform.PullArtifact(ctx, artifactReference, artifactDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to pull artifact %s", artifactReference)
+	}
+
+	if len(artifactPaths) == 1 && common.IsFile(artifactPaths[0]) {
+		return artifactPaths[0], nil
+	}
+
+	return b.resolveUserSpecifiedWorkdir(artifactDir)
+}
+
 func (b *Builder) getS3FunctionItemKey() (string, error) {
 	s3Attributes, err := b.validateAndParseS3Attributes(b.options.FunctionConfig.Spec.Build.CodeEntryAttributes)
 	if err != nil {
@@ -1716,9 +2097,22 @@ func (b *Builder) cloneFunctionFromGit(outputDir, repositoryURL string) error {
 		return errors.Wrap(err, "Failed to parse git attributes")
 	}
 
-	b.logger.DebugWith("Parsed git attributes", "gitAttributes", gitAttributes)
+	b.logger.DebugWith("Parsed git attributes",
+		"branch", gitAttributes.Branch,
+		"tag", gitAttributes.Tag,
+		"reference", gitAttributes.Reference,
+		"commit", gitAttributes.Commit,
+		"submodules", gitAttributes.Submodules,
+		"sparseCheckout", gitAttributes.SparseCheckout)
 
-	return b.gitClient.Clone(outputDir, repositoryURL, gitAttributes)
+	commitSHA, err := b.gitClient.Clone(outputDir, repositoryURL, gitAttributes)
+	if err != nil {
+		return err
+	}
+
+	b.sourceCommit = commitSHA
+
+	return nil
 }
 
 func (b *Builder) downloadFunctionFromS3(tempFile *os.File) error {
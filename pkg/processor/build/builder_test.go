@@ -20,7 +20,9 @@ package build
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"os"
@@ -33,6 +35,8 @@ import (
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform"
 	mockplatform "github.com/nuclio/nuclio/pkg/platform/mock"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/nuclio/errors"
@@ -245,6 +249,185 @@ func (suite *testSuite) TestGetImage() {
 	suite.Require().Equal(fmt.Sprintf("nuclio/%sprocessor", imageNamePrefix), imageName)
 }
 
+func (suite *testSuite) TestComputeBuildFingerprint() {
+	suite.builder.stagingDir = suite.T().TempDir()
+	suite.builder.options.FunctionConfig.Spec.Runtime = "python:3.9"
+	suite.mockPlatform.On("GetContainerBuilderKind").Return("docker")
+
+	handlerPath := filepath.Join(suite.builder.stagingDir, "handler", "main.py")
+	suite.Require().NoError(os.MkdirAll(filepath.Dir(handlerPath), 0755))
+	suite.Require().NoError(os.WriteFile(handlerPath, []byte("def handler(context, event): pass"), 0644))
+
+	processorDockerfileInfo := &runtime.ProcessorDockerfileInfo{
+		BaseImage:          "python:3.9",
+		DockerfileContents: "FROM python:3.9",
+	}
+
+	fingerprint, err := suite.builder.computeBuildFingerprint(suite.ctx, processorDockerfileInfo)
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(fingerprint)
+
+	// the same build has the same fingerprint, regardless of the generated dockerfile being in staging
+	suite.Require().NoError(os.WriteFile(filepath.Join(suite.builder.stagingDir, "Dockerfile.processor"),
+		[]byte(processorDockerfileInfo.DockerfileContents),
+		0644))
+	sameFingerprint, err := suite.builder.computeBuildFingerprint(suite.ctx, processorDockerfileInfo)
+	suite.Require().NoError(err)
+	suite.Require().Equal(fingerprint, sameFingerprint)
+
+	// changing the build configuration changes the fingerprint
+	suite.builder.options.FunctionConfig.Spec.Build.Commands = []string{"pip install requests"}
+	commandsFingerprint, err := suite.builder.computeBuildFingerprint(suite.ctx, processorDockerfileInfo)
+	suite.Require().NoError(err)
+	suite.Require().NotEqual(fingerprint, commandsFingerprint)
+
+	// building offline changes the fingerprint, as the onbuild stages resolve dependencies differently
+	suite.builder.options.FunctionConfig.Spec.Build.Commands = nil
+	suite.builder.options.FunctionConfig.Spec.Build.Offline = true
+	offlineFingerprint, err := suite.builder.computeBuildFingerprint(suite.ctx, processorDockerfileInfo)
+	suite.Require().NoError(err)
+	suite.Require().NotEqual(fingerprint, offlineFingerprint)
+
+	// changing the source changes the fingerprint
+	suite.builder.options.FunctionConfig.Spec.Build.Offline = false
+	suite.Require().NoError(os.WriteFile(handlerPath, []byte("def handler(context, event): return 1"), 0644))
+	sourceFingerprint, err := suite.builder.computeBuildFingerprint(suite.ctx, processorDockerfileInfo)
+	suite.Require().NoError(err)
+	suite.Require().NotEqual(fingerprint, sourceFingerprint)
+}
+
+func (suite *testSuite) TestEnrichAndValidateImageAttestation() {
+	platformConfig := &platformconfig.Config{}
+	suite.mockPlatform.On("GetConfig").Return(platformConfig)
+	functionBuild := &suite.builder.options.FunctionConfig.Spec.Build
+
+	// nothing to attest
+	suite.Require().NoError(suite.builder.enrichAndValidateImageAttestation())
+
+	// attesting requires a registry
+	functionBuild.SBOMFormat = functionconfig.SBOMFormatCycloneDX
+	suite.Require().Error(suite.builder.enrichAndValidateImageAttestation())
+
+	functionBuild.Registry = "localhost:5000"
+	suite.Require().NoError(suite.builder.enrichAndValidateImageAttestation())
+
+	functionBuild.SBOMFormat = "unknown"
+	suite.Require().Error(suite.builder.enrichAndValidateImageAttestation())
+
+	// the platform enforces an SBOM in its default format, and a signature
+	functionBuild.SBOMFormat = ""
+	platformConfig.ImageAttestation.RequireSBOM = true
+	platformConfig.ImageAttestation.RequireSignature = true
+	suite.Require().Error(suite.builder.enrichAndValidateImageAttestation())
+
+	platformConfig.ImageAttestation.SigningKeyPath = "/etc/nuclio/signing-key/cosign.key"
+	suite.Require().NoError(suite.builder.enrichAndValidateImageAttestation())
+	suite.Require().Equal(functionconfig.SBOMFormatSPDX, functionBuild.SBOMFormat)
+	suite.Require().True(functionBuild.Sign)
+}
+
+func (suite *testSuite) TestValidateBuildPlatforms() {
+	functionBuild := &suite.builder.options.FunctionConfig.Spec.Build
+
+	// the builder's own platform
+	suite.Require().NoError(suite.builder.validateBuildPlatforms())
+
+	// a single platform's image needs neither a registry nor an index, so it can be saved to a file
+	functionBuild.Platforms = []string{"linux/arm64"}
+	suite.builder.options.OutputImageFile = "image.tar"
+	suite.Require().NoError(suite.builder.validateBuildPlatforms())
+	suite.builder.options.OutputImageFile = ""
+
+	functionBuild.Platforms = []string{"linux/amd64", "linux/arm/v7"}
+
+	// the platform images are combined in a registry
+	suite.Require().Error(suite.builder.validateBuildPlatforms())
+
+	functionBuild.Registry = "localhost:5000"
+	suite.Require().NoError(suite.builder.validateBuildPlatforms())
+
+	for _, invalidPlatforms := range [][]string{
+		{"amd64"},
+		{"windows/amd64"},
+		{"linux/"},
+		{"linux/arm/v7/extra"},
+		{"linux/amd64", "linux/amd64"},
+	} {
+		functionBuild.Platforms = invalidPlatforms
+		suite.Require().Error(suite.builder.validateBuildPlatforms(), "platforms: %v", invalidPlatforms)
+	}
+}
+
+func (suite *testSuite) TestMountBuildSecrets() {
+	directives := map[string][]functionconfig.Directive{
+		"preCopy": {
+			{Kind: "ARG", Value: "PIP_CONFIG_FILE=/etc/nuclio/pip.conf"},
+		},
+		"postCopy": {
+			{Kind: "RUN", Value: "pip install -r requirements.txt"},
+		},
+	}
+
+	mountedDirectives := suite.builder.mountBuildSecrets(directives, []runtime.BuildSecret{
+		{FileName: "pip.conf", Path: "/etc/nuclio/pip.conf"},
+	})
+
+	// only commands mount the secrets, which aren't copied to the image
+	suite.Require().Equal(map[string][]functionconfig.Directive{
+		"preCopy": {
+			{Kind: "ARG", Value: "PIP_CONFIG_FILE=/etc/nuclio/pip.conf"},
+		},
+		"postCopy": {
+			{
+				Kind:  "RUN",
+				Value: "--mount=type=secret,id=pip.conf,target=/etc/nuclio/pip.conf pip install -r requirements.txt",
+			},
+		},
+	}, mountedDirectives)
+
+	// the given directives are left as is
+	suite.Require().Equal("pip install -r requirements.txt", directives["postCopy"][0].Value)
+}
+
+func (suite *testSuite) TestValidatePackageRegistries() {
+	functionBuild := &suite.builder.options.FunctionConfig.Spec.Build
+
+	functionBuild.PackageRegistries = []functionconfig.PackageRegistry{
+		{
+			Kind:     functionconfig.PackageRegistryKindPyPI,
+			URL:      "https://pypi.example.com/simple",
+			Username: "user",
+			Password: "password",
+		},
+		{
+			Kind:     functionconfig.PackageRegistryKindNPM,
+			URL:      "https://npm.example.com",
+			Scope:    "@my-org",
+			Password: "token",
+		},
+		{
+			Kind:           functionconfig.PackageRegistryKindGoProxy,
+			URL:            "https://goproxy.example.com",
+			PrivateModules: []string{"github.com/my-org/*"},
+		},
+	}
+	suite.Require().NoError(suite.builder.validatePackageRegistries())
+
+	for _, invalidPackageRegistry := range []functionconfig.PackageRegistry{
+		{Kind: "conda", URL: "https://conda.example.com"},
+		{Kind: functionconfig.PackageRegistryKindMaven, URL: "maven.example.com"},
+		{Kind: functionconfig.PackageRegistryKindPyPI, URL: "https://pypi.example.com", Scope: "@my-org"},
+		{Kind: functionconfig.PackageRegistryKindNPM, URL: "https://npm.example.com", Scope: "my-org"},
+		{Kind: functionconfig.PackageRegistryKindNPM, URL: "https://npm.example.com", PrivateModules: []string{"x"}},
+
+		// functions can't read the dashboard's files
+		{Kind: functionconfig.PackageRegistryKindNuGet, URL: "https://nuget.example.com", PasswordPath: "/etc/passwd"},
+	} {
+		functionBuild.PackageRegistries = []functionconfig.PackageRegistry{invalidPackageRegistry}
+		suite.Require().Error(suite.builder.validatePackageRegistries(), "registry: %+v", invalidPackageRegistry)
+	}
+}
+
 func (suite *testSuite) TestMergeDirectives() {
 
 	mergeDirectivesCases := []struct {
@@ -536,6 +719,93 @@ func (suite *testSuite) TestResolveFunctionPathRemoteCodeFile() {
 	}
 }
 
+func (suite *testSuite) TestResolveFunctionPathArchiveChecksum() {
+	archiveFileURL := "http://some-address.com/test_function_archive"
+
+	functionArchiveFileBytes, err := os.ReadFile(FunctionsArchiveFilePath)
+	suite.Require().NoError(err)
+
+	archiveChecksum := sha256.Sum256(functionArchiveFileBytes)
+
+	// matching checksums, with or without the algorithm prefix
+	for _, checksum := range []string{
+		hex.EncodeToString(archiveChecksum[:]),
+		"sha256:" + strings.ToUpper(hex.EncodeToString(archiveChecksum[:])),
+	} {
+		suite.testResolveFunctionPathArchive(functionconfig.Build{
+			CodeEntryType: ArchiveEntryType,
+			Path:          archiveFileURL,
+			CodeEntryAttributes: map[string]interface{}{
+				"workDir": "/funcs/my-python-func",
+				"sha256":  checksum,
+			},
+		}, archiveFileURL)
+	}
+
+	// a mismatching checksum fails the build
+	buildConfiguration := functionconfig.Build{
+		CodeEntryType: ArchiveEntryType,
+		Path:          archiveFileURL,
+		CodeEntryAttributes: map[string]interface{}{
+			"sha256": strings.Repeat("0", 64),
+		},
+	}
+
+	suite.mockArchiveFileURLEndpoint(buildConfiguration, archiveFileURL)
+	defer httpmock.DeactivateAndReset()
+
+	err = suite.builder.createTempDir()
+	suite.Require().NoError(err)
+	defer suite.builder.cleanupTempDir() // nolint: errcheck
+
+	suite.builder.options.FunctionConfig.Spec.Build = buildConfiguration
+
+	_, _, err = suite.builder.resolveFunctionPath(suite.ctx, buildConfiguration.Path)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.RootCause(err).Error(), "Function checksum mismatch")
+}
+
+func (suite *testSuite) TestResolveFunctionPathLocalChecksum() {
+	functionArchiveFileBytes, err := os.ReadFile(FunctionsArchiveFilePath)
+	suite.Require().NoError(err)
+
+	archiveChecksum := sha256.Sum256(functionArchiveFileBytes)
+
+	err = suite.builder.createTempDir()
+	suite.Require().NoError(err)
+	defer suite.builder.cleanupTempDir() // nolint: errcheck
+
+	for _, testCase := range []struct {
+		name          string
+		path          string
+		checksum      string
+		expectedError string
+	}{
+		{name: "matchingArchive", path: FunctionsArchiveFilePath, checksum: hex.EncodeToString(archiveChecksum[:])},
+		{name: "mismatchingArchive", path: FunctionsArchiveFilePath, checksum: strings.Repeat("0", 64),
+			expectedError: "Function checksum mismatch"},
+		{name: "directory", path: filepath.Dir(FunctionsArchiveFilePath), checksum: strings.Repeat("0", 64),
+			expectedError: "can only verify a file"},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.builder.options.FunctionConfig.Spec.Build = functionconfig.Build{
+				Path: testCase.path,
+				CodeEntryAttributes: map[string]interface{}{
+					"sha256": testCase.checksum,
+				},
+			}
+
+			_, _, err := suite.builder.resolveFunctionPath(suite.ctx, testCase.path)
+			if testCase.expectedError == "" {
+				suite.Require().NoError(err)
+			} else {
+				suite.Require().Error(err)
+				suite.Require().Contains(errors.RootCause(err).Error(), testCase.expectedError)
+			}
+		})
+	}
+}
+
 func (suite *testSuite) TestResolveFunctionPathArchiveCodeEntry() {
 	archiveFileURL := "http://some-address.com/test_function_archive"
 	buildConfiguration := functionconfig.Build{
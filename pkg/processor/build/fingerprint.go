@@ -0,0 +1,214 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+
+	"github.com/nuclio/errors"
+)
+
+// bump whenever the inputs of the fingerprint change, so that images of older builds aren't reused
+const buildFingerprintVersion = "2"
+
+// buildFingerprintInputs are the parts of the build configuration that affect the built image
+type buildFingerprintInputs struct {
+	BaseImage         string                                `json:"baseImage,omitempty"`
+	BaseImageRegistry string                                `json:"baseImageRegistry,omitempty"`
+	OnbuildImage      string                                `json:"onbuildImage,omitempty"`
+	Commands          []string                              `json:"commands,omitempty"`
+	Directives        map[string][]functionconfig.Directive `json:"directives,omitempty"`
+	ScriptPaths       []string                              `json:"scriptPaths,omitempty"`
+	AddedObjectPaths  map[string]string                     `json:"addedPaths,omitempty"`
+	Dependencies      []string                              `json:"dependencies,omitempty"`
+	RuntimeAttributes map[string]interface{}                `json:"runtimeAttributes,omitempty"`
+	Args              map[string]string                     `json:"args,omitempty"`
+	Flags             []string                              `json:"flags,omitempty"`
+
+	// the build arguments passed to the image builder, which the onbuild stages build by (e.g. offline)
+	BuildArgs map[string]string `json:"buildArgs,omitempty"`
+}
+
+// computeBuildFingerprint returns a digest of everything that goes into the processor image - the staged
+// directory, the build configuration, the generated dockerfile and the digests of the images it builds from.
+// two builds with the same fingerprint produce equivalent images
+func (b *Builder) computeBuildFingerprint(ctx context.Context,
+	processorDockerfileInfo *runtime.ProcessorDockerfileInfo) (string, error) {
+	fingerprintHash := sha256.New()
+
+	functionBuild := b.options.FunctionConfig.Spec.Build
+	encodedBuild, err := json.Marshal(buildFingerprintInputs{
+		BaseImage:         functionBuild.BaseImage,
+		BaseImageRegistry: functionBuild.BaseImageRegistry,
+		OnbuildImage:      functionBuild.OnbuildImage,
+		Commands:          functionBuild.Commands,
+		Directives:        functionBuild.Directives,
+		ScriptPaths:       functionBuild.ScriptPaths,
+		AddedObjectPaths:  functionBuild.AddedObjectPaths,
+		Dependencies:      functionBuild.Dependencies,
+		RuntimeAttributes: functionBuild.RuntimeAttributes,
+		Args:              functionBuild.Args,
+		Flags:             functionBuild.Flags,
+		BuildArgs:         b.getBuildArgs(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to encode build configuration")
+	}
+
+	writeFingerprintField(fingerprintHash, "version", buildFingerprintVersion)
+	writeFingerprintField(fingerprintHash, "builder", b.platform.GetContainerBuilderKind())
+	writeFingerprintField(fingerprintHash, "runtime", b.options.FunctionConfig.Spec.Runtime)
+	writeFingerprintField(fingerprintHash, "build", string(encodedBuild))
+	writeFingerprintField(fingerprintHash, "dockerfile", processorDockerfileInfo.DockerfileContents)
+
+	// base images are fingerprinted by the digest of their index, which is the same for all platforms
+	if b.targetPlatform != "" {
+		writeFingerprintField(fingerprintHash, "platform", b.targetPlatform)
+	}
+
+	// image names are mutable, so builds from the same name are the same only if it points at the same digest
+	baseImageDigest, err := b.resolveFingerprintImageDigest(ctx, processorDockerfileInfo.BaseImage)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to resolve base image digest")
+	}
+
+	writeFingerprintField(fingerprintHash, "baseImage", baseImageDigest)
+
+	onbuildArtifacts := append([]runtime.Artifact{}, processorDockerfileInfo.OnbuildArtifacts...)
+	sort.SliceStable(onbuildArtifacts, func(i, j int) bool {
+		return onbuildArtifacts[i].Image < onbuildArtifacts[j].Image
+	})
+
+	for _, onbuildArtifact := range onbuildArtifacts {
+		onbuildImageDigest, err := b.resolveFingerprintImageDigest(ctx, onbuildArtifact.Image)
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to resolve onbuild image digest of %s", onbuildArtifact.Name)
+		}
+
+		encodedPaths, err := json.Marshal(onbuildArtifact.Paths)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to encode onbuild artifact paths")
+		}
+
+		writeFingerprintField(fingerprintHash, "onbuildImage", onbuildImageDigest)
+		writeFingerprintField(fingerprintHash, "onbuildPaths", string(encodedPaths))
+	}
+
+	if err := b.writeStagingDirFingerprint(fingerprintHash); err != nil {
+		return "", errors.Wrap(err, "Failed to fingerprint staging directory")
+	}
+
+	return hex.EncodeToString(fingerprintHash.Sum(nil)), nil
+}
+
+// resolveFingerprintImageDigest returns the image digest, falling back to its name when the digest is unknown
+func (b *Builder) resolveFingerprintImageDigest(ctx context.Context, image string) (string, error) {
+	if image == "" {
+		return "", nil
+	}
+
+	digest, err := b.platform.ResolveImageDigest(ctx, image)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to resolve digest of image %s", image)
+	}
+
+	if digest == "" {
+		b.logger.DebugWithCtx(ctx, "Image digest is unknown, fingerprinting image name", "image", image)
+		return image, nil
+	}
+
+	return digest, nil
+}
+
+// writeStagingDirFingerprint hashes the path, mode and contents of every file in the staging directory
+func (b *Builder) writeStagingDirFingerprint(fingerprintHash hash.Hash) error {
+	return filepath.WalkDir(b.stagingDir, func(filePath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(b.stagingDir, filePath)
+		if err != nil {
+			return errors.Wrap(err, "Failed to get relative path")
+		}
+
+		// the dockerfile is fingerprinted from its contents, before labels are added to it
+		if relativePath == "." || relativePath == "Dockerfile.processor" {
+			return nil
+		}
+
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			return errors.Wrapf(err, "Failed to stat %s", relativePath)
+		}
+
+		writeFingerprintField(fingerprintHash, "path", filepath.ToSlash(relativePath))
+		writeFingerprintField(fingerprintHash, "mode", fileInfo.Mode().String())
+
+		switch {
+		case fileInfo.Mode()&os.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(filePath)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to read link %s", relativePath)
+			}
+
+			writeFingerprintField(fingerprintHash, "link", linkTarget)
+		case fileInfo.Mode().IsRegular():
+			fileHash := sha256.New()
+			if err := hashFile(fileHash, filePath); err != nil {
+				return errors.Wrapf(err, "Failed to hash %s", relativePath)
+			}
+
+			writeFingerprintField(fingerprintHash, "content", hex.EncodeToString(fileHash.Sum(nil)))
+		}
+
+		return nil
+	})
+}
+
+func hashFile(fileHash hash.Hash, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to open file")
+	}
+
+	defer file.Close() // nolint: errcheck
+
+	if _, err := io.Copy(fileHash, file); err != nil {
+		return errors.Wrap(err, "Failed to read file")
+	}
+
+	return nil
+}
+
+// writeFingerprintField writes a length-prefixed field, so that adjacent fields can't run into one another
+func writeFingerprintField(fingerprintHash hash.Hash, name string, value string) {
+	fmt.Fprintf(fingerprintHash, "%s:%d:%s\n", name, len(value), value) // nolint: errcheck
+}
@@ -18,8 +18,12 @@ package inlineparser
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/nuclio/errors"
@@ -29,11 +33,28 @@ import (
 
 const StartBlockKeyword = "@nuclio."
 
+const (
+	BlockFormatYAML = "yaml"
+	BlockFormatJSON = "json"
+)
+
+// DefaultCommentChars are the comment styles recognized when none are passed to NewParser
+var DefaultCommentChars = []string{"//", "#"}
+
+// yaml errors carry a line number relative to the block contents, e.g. "yaml: line 3: could not find expected ':'"
+var yamlErrorLineRegex = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
 // Block represents a block
 type Block struct {
 	Contents    map[string]interface{}
 	RawContents string
-	Error       error
+
+	// the format of the contents - yaml or json
+	Format string
+
+	// the line in the source file where the block contents start
+	StartLine int
+	Error     error
 }
 
 // ConfigParser parsers inline configuration in files
@@ -47,20 +68,29 @@ type InlineParser struct {
 	currentStateLineHandler func(line string) error
 	currentBlockName        string
 	currentBlockContents    string
+	currentBlockStartLine   int
 	currentCommentChar      string
-	startBlockPattern       string
+	currentFileName         string
+	currentLine             int
+	commentChars            []string
 	currentBlocks           map[string]Block
 }
 
-func NewParser(parentLogger logger.Logger, commentChar string) *InlineParser {
+// NewParser creates a parser which recognizes blocks in any of the given comment styles (e.g. "//", "#").
+// If no comment styles are given, DefaultCommentChars are used
+func NewParser(parentLogger logger.Logger, commentChars ...string) *InlineParser {
+	if len(commentChars) == 0 {
+		commentChars = DefaultCommentChars
+	}
+
 	return &InlineParser{
-		logger:             parentLogger.GetChild("inlineparser"),
-		currentCommentChar: commentChar,
+		logger:       parentLogger.GetChild("inlineparser"),
+		commentChars: commentChars,
 	}
 }
 
 // Parse looks for a block starting with a comment character and "@nuclio.". It then adds this
-// to the list of inline configuration blocks. For example:
+// to the list of inline configuration blocks. The block contents may be YAML or JSON. For example:
 //
 //	@nuclio.configure
 //
@@ -73,24 +103,34 @@ func NewParser(parentLogger logger.Logger, commentChar string) *InlineParser {
 //	      http:
 //	        numWorkers: 8
 //	        kind: http
+//
+// The function configuration in a "configure" block is validated against the function config schema, and
+// any error is reported with the line in the source file it relates to
 func (p *InlineParser) Parse(path string) (map[string]Block, error) {
 	reader, err := os.OpenFile(path, os.O_RDONLY, os.FileMode(0644))
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to open function file")
 	}
+
+	defer reader.Close() // nolint: errcheck
+
 	scanner := bufio.NewScanner(reader)
 
 	// prepare stuff for states
 	p.currentBlocks = map[string]Block{}
-	p.startBlockPattern = fmt.Sprintf("%s%s", p.currentCommentChar, StartBlockKeyword)
+	p.currentFileName = filepath.Base(path)
+	p.currentLine = 0
 
 	// init state to looking for start block
 	p.currentStateLineHandler = p.lookingForStartBlockStateHandleLine
 
-	p.logger.DebugWith("Starting to look for block pattern", "pattern", p.startBlockPattern)
+	p.logger.DebugWith("Starting to look for block pattern",
+		"keyword", StartBlockKeyword,
+		"commentChars", p.commentChars)
 
 	// read a line
 	for scanner.Scan() {
+		p.currentLine++
 
 		// handle the current line in the state machine
 		if err := p.currentStateLineHandler(scanner.Text()); err != nil {
@@ -98,21 +138,43 @@ func (p *InlineParser) Parse(path string) (map[string]Block, error) {
 		}
 	}
 
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Failed to read function file")
+	}
+
+	// a block may run until the end of the file
+	if p.currentBlockName != "" {
+		p.closeBlock()
+	}
+
 	return p.currentBlocks, nil
 }
 
 func (p *InlineParser) lookingForStartBlockStateHandleLine(line string) error {
-	spacelessLine := strings.ReplaceAll(line, " ", "")
+	spacelessLine := strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+
+	for _, commentChar := range p.commentChars {
+		startBlockPattern := commentChar + StartBlockKeyword
+
+		// if the string starts with <commandChar><space>@nuclio. - we found a match
+		if strings.HasPrefix(spacelessLine, startBlockPattern) {
+
+			// set current block name: `// @nuclio.createFiles` -> `createFiles`
+			p.currentBlockName = strings.Trim(spacelessLine[len(startBlockPattern):], " ")
+			p.currentBlockStartLine = p.currentLine + 1
 
-	// if the string starts with <commandChar><space>@nuclio. - we found a match
-	if strings.HasPrefix(spacelessLine, p.startBlockPattern) {
+			// the rest of the block must use the same comment style
+			p.currentCommentChar = commentChar
 
-		// set current block name: `// @nuclio.createFiles` -> `createFiles`
-		p.currentBlockName = strings.Trim(spacelessLine[len(p.startBlockPattern):], " ")
-		p.logger.DebugWith("Found block start", "block name", p.currentBlockName)
+			p.logger.DebugWith("Found block start",
+				"block name", p.currentBlockName,
+				"line", p.currentLine)
 
-		// switch state
-		p.currentStateLineHandler = p.readingBlockStateHandleLine
+			// switch state
+			p.currentStateLineHandler = p.readingBlockStateHandleLine
+
+			return nil
+		}
 	}
 
 	return nil
@@ -120,27 +182,12 @@ func (p *InlineParser) lookingForStartBlockStateHandleLine(line string) error {
 
 func (p *InlineParser) readingBlockStateHandleLine(line string) error {
 
+	// comments may be indented (e.g. inside a class or namespace)
+	line = strings.TrimLeft(line, " \t")
+
 	// if the line doesn't start with a comment character, close the block
 	if !strings.HasPrefix(line, p.currentCommentChar) {
-		block := Block{
-			RawContents: p.currentBlockContents,
-		}
-
-		p.logger.DebugWith("Found block end", "contentsLen", len(p.currentBlockContents))
-
-		// parse yaml
-		if err := yaml.Unmarshal([]byte(p.currentBlockContents), &block.Contents); err != nil {
-			block.Error = errors.Wrapf(err, "Failed to unmarshal inline block: %s", p.currentBlockName)
-		}
-
-		// add block to current blocks
-		p.currentBlocks[p.currentBlockName] = block
-
-		// clear current block
-		p.currentBlockContents = ""
-
-		// go back to looking for blocks
-		p.currentStateLineHandler = p.lookingForStartBlockStateHandleLine
+		p.closeBlock()
 
 		// and we're done
 		return nil
@@ -158,3 +205,110 @@ func (p *InlineParser) readingBlockStateHandleLine(line string) error {
 
 	return nil
 }
+
+func (p *InlineParser) closeBlock() {
+	block := Block{
+		RawContents: p.currentBlockContents,
+		Format:      p.resolveBlockFormat(p.currentBlockContents),
+		StartLine:   p.currentBlockStartLine,
+	}
+
+	p.logger.DebugWith("Found block end",
+		"contentsLen", len(p.currentBlockContents),
+		"format", block.Format)
+
+	block.Contents, block.Error = p.parseBlockContents(&block)
+	if block.Error != nil {
+		block.Error = errors.Wrapf(block.Error, "Failed to unmarshal inline block: %s", p.currentBlockName)
+	}
+
+	// add block to current blocks
+	p.currentBlocks[p.currentBlockName] = block
+
+	// clear current block
+	p.currentBlockName = ""
+	p.currentBlockContents = ""
+
+	// go back to looking for blocks
+	p.currentStateLineHandler = p.lookingForStartBlockStateHandleLine
+}
+
+func (p *InlineParser) resolveBlockFormat(contents string) string {
+	if strings.HasPrefix(strings.TrimSpace(contents), "{") {
+		return BlockFormatJSON
+	}
+
+	return BlockFormatYAML
+}
+
+func (p *InlineParser) parseBlockContents(block *Block) (map[string]interface{}, error) {
+
+	// yaml is lenient with things json isn't (e.g. trailing commas, unquoted keys), so check json blocks
+	// are valid json first
+	if block.Format == BlockFormatJSON {
+		var jsonContents map[string]interface{}
+		if err := json.Unmarshal([]byte(block.RawContents), &jsonContents); err != nil {
+			return nil, p.jsonError(block, err)
+		}
+	}
+
+	// json is valid yaml, so both formats are read as yaml nodes to keep the line numbers
+	var rootNode yaml.Node
+	if err := yaml.Unmarshal([]byte(block.RawContents), &rootNode); err != nil {
+		return nil, p.yamlError(block, err)
+	}
+
+	var contents map[string]interface{}
+	if err := rootNode.Decode(&contents); err != nil {
+		return nil, p.yamlError(block, err)
+	}
+
+	if p.currentBlockName == "configure" {
+		if err := validateFunctionConfig(&rootNode, p.currentFileName, block.StartLine); err != nil {
+			return nil, err
+		}
+	}
+
+	return contents, nil
+}
+
+func (p *InlineParser) jsonError(block *Block, err error) error {
+	var offset int64
+
+	switch typedErr := err.(type) {
+	case *json.SyntaxError:
+		offset = typedErr.Offset
+	case *json.UnmarshalTypeError:
+		offset = typedErr.Offset
+	default:
+		return err
+	}
+
+	// the offset points right past the offending character
+	if offset > 0 {
+		offset--
+	}
+
+	if offset > int64(len(block.RawContents)) {
+		offset = int64(len(block.RawContents))
+	}
+
+	line := strings.Count(block.RawContents[:offset], "\n")
+
+	return errors.New(formatBlockError(p.currentFileName, block.StartLine+line, err.Error()))
+}
+
+func (p *InlineParser) yamlError(block *Block, err error) error {
+	matches := yamlErrorLineRegex.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return err
+	}
+
+	line, _ := strconv.Atoi(matches[1])
+
+	return errors.New(formatBlockError(p.currentFileName, block.StartLine+line-1, matches[2]))
+}
+
+func formatBlockError(fileName string, line int, message string) string {
+	return fmt.Sprintf("%s:%d: %s", fileName, line, message)
+}
@@ -22,6 +22,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
 	"github.com/stretchr/testify/suite"
@@ -161,6 +162,216 @@ function.yaml:
 `)
 }
 
+func (suite *InlineParserTestSuite) TestSlashSlashAndPoundBlocks() {
+	parser := NewParser(suite.logger)
+
+	goContent := `package main
+
+// @nuclio.configure
+//
+// function.yaml:
+//   spec:
+//     runtime: golang
+//     handler: main:Handler
+func Handler(context *nuclio.Context, event nuclio.Event) (interface{}, error) {
+	return nil, nil
+}
+`
+	rubyContent := `# @nuclio.configure
+#
+# function.yaml:
+#   spec:
+#     runtime: ruby
+#     handler: handler:main
+def main(context, event)
+end
+`
+	for _, content := range []string{goContent, rubyContent} {
+		blocks := suite.parseContent(parser, content)
+		suite.Require().NoError(blocks["configure"].Error)
+		suite.Require().Equal(BlockFormatYAML, blocks["configure"].Format)
+		suite.Require().Contains(blocks["configure"].Contents, "function.yaml")
+	}
+}
+
+func (suite *InlineParserTestSuite) TestIndentedBlockAtEndOfFile() {
+	parser := NewParser(suite.logger)
+
+	blocks := suite.parseContent(parser, `namespace Functions
+{
+    // @nuclio.configure
+    //
+    // function.yaml:
+    //   spec:
+    //     runtime: dotnetcore
+    //     handler: nuclio:Handler`)
+
+	suite.Require().NoError(blocks["configure"].Error)
+	suite.Require().Equal(4, blocks["configure"].StartLine)
+
+	functionConfig := blocks["configure"].Contents["function.yaml"].(map[string]interface{})
+	suite.Require().Equal("dotnetcore", functionConfig["spec"].(map[string]interface{})["runtime"])
+}
+
+func (suite *InlineParserTestSuite) TestJSONBlock() {
+	blocks := suite.parseContent(suite.parser, `# @nuclio.configure
+# {
+#   "function.yaml": {
+#     "spec": {
+#       "runtime": "python",
+#       "triggers": {"http": {"kind": "http", "numWorkers": 4}}
+#     }
+#   }
+# }
+def handler(context, event):
+    pass
+`)
+
+	suite.Require().NoError(blocks["configure"].Error)
+	suite.Require().Equal(BlockFormatJSON, blocks["configure"].Format)
+
+	functionConfig := blocks["configure"].Contents["function.yaml"].(map[string]interface{})
+	suite.Require().Equal("python", functionConfig["spec"].(map[string]interface{})["runtime"])
+}
+
+func (suite *InlineParserTestSuite) TestInvalidJSONBlock() {
+	blocks := suite.parseContent(suite.parser, `import os
+
+# @nuclio.configure
+# {
+#   "function.yaml": {
+#     "spec": {
+#       "runtime": "python",
+#     }
+#   }
+# }
+`)
+
+	suite.Require().Error(blocks["configure"].Error)
+	suite.Require().Contains(errors.RootCause(blocks["configure"].Error).Error(), ":8: invalid character '}'")
+}
+
+func (suite *InlineParserTestSuite) TestInvalidYAMLBlockLine() {
+	blocks := suite.parseContent(suite.parser, `import os
+
+# @nuclio.configure
+#
+# function.yaml:
+#   spec:
+#     runtime: "python"
+#     handler:parser:handler
+`)
+
+	suite.Require().Error(blocks["configure"].Error)
+	suite.Require().Regexp(`:8: `, errors.RootCause(blocks["configure"].Error).Error())
+}
+
+func (suite *InlineParserTestSuite) TestSchemaValidation() {
+	for _, testCase := range []struct {
+		name            string
+		content         string
+		expectedErrors  []string
+		expectedNoError bool
+	}{
+		{
+			name: "Valid",
+			content: `# @nuclio.configure
+#
+# function.yaml:
+#   metadata:
+#     name: my-function
+#     labels:
+#       app: test
+#   spec:
+#     runtime: python
+#     minReplicas: 1
+#     env:
+#     - name: MY_ENV
+#       value: "1"
+#     resources:
+#       limits:
+#         cpu: 500m
+#     triggers:
+#       http:
+#         kind: http
+#         numWorkers: 4
+#         attributes:
+#           port: 8080
+`,
+			expectedNoError: true,
+		},
+		{
+			name: "UnknownField",
+			content: `# @nuclio.configure
+#
+# function.yaml:
+#   spec:
+#     runtime: python
+#     triggers:
+#       http:
+#         kind: http
+#         numWorker: 4
+`,
+			expectedErrors: []string{`:9: unknown field "numWorker" (at spec.triggers.http)`},
+		},
+		{
+			name: "WrongTypes",
+			content: `import os
+
+# @nuclio.configure
+#
+# function.yaml:
+#   spec:
+#     minReplicas: "one"
+#     env:
+#       name: MY_ENV
+`,
+			expectedErrors: []string{
+				`:7: expected an integer (at spec.minReplicas)`,
+				`:9: expected a list (at spec.env)`,
+			},
+		},
+		{
+			name: "JSON",
+			content: `// @nuclio.configure
+// {"function.yaml": {
+//   "spec": {"runtime": "golang", "handlr": "main:Handler"}
+// }}
+`,
+			expectedErrors: []string{`:3: unknown field "handlr" (at spec)`},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			blocks := suite.parseContent(NewParser(suite.logger), testCase.content)
+			if testCase.expectedNoError {
+				suite.Require().NoError(blocks["configure"].Error)
+				return
+			}
+
+			suite.Require().Error(blocks["configure"].Error)
+			for _, expectedError := range testCase.expectedErrors {
+				suite.Require().Contains(errors.RootCause(blocks["configure"].Error).Error(), expectedError)
+			}
+		})
+	}
+}
+
+func (suite *InlineParserTestSuite) parseContent(parser *InlineParser, content string) map[string]Block {
+	tmpFile, err := os.CreateTemp("", "nuclio-parser-test")
+	suite.Require().NoError(err)
+	suite.Require().NoError(tmpFile.Close())
+
+	defer os.Remove(tmpFile.Name()) // nolint: errcheck
+
+	err = os.WriteFile(tmpFile.Name(), []byte(content), 0600)
+	suite.Require().NoError(err)
+
+	blocks, err := parser.Parse(tmpFile.Name())
+	suite.Require().NoError(err)
+
+	return blocks
+}
+
 func TestInlineParserTestSuite(t *testing.T) {
 	suite.Run(t, new(InlineParserTestSuite))
 }
@@ -0,0 +1,264 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inlineparser
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// function.yaml files may carry these, though they aren't part of the function config itself
+var functionConfigHeaderFields = []string{"apiVersion", "kind"}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// schemaValidator walks a yaml node tree alongside the type it will be decoded into, reporting fields the
+// type doesn't have and values of the wrong kind with the line they appear in
+type schemaValidator struct {
+	fileName  string
+	startLine int
+	messages  []string
+}
+
+// validateFunctionConfig validates the function configuration in a block (under function.yaml) against the
+// function config schema. Line numbers in errors are relative to the source file, given the line the block
+// contents start in
+func validateFunctionConfig(rootNode *yaml.Node, fileName string, startLine int) error {
+	contentsNode := resolveNode(rootNode)
+	if contentsNode == nil || contentsNode.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	validator := schemaValidator{
+		fileName:  fileName,
+		startLine: startLine,
+	}
+
+	for keyIdx := 0; keyIdx+1 < len(contentsNode.Content); keyIdx += 2 {
+		if contentsNode.Content[keyIdx].Value == common.FunctionConfigFileName {
+			validator.validate(contentsNode.Content[keyIdx+1], reflect.TypeOf(functionconfig.Config{}), "")
+		}
+	}
+
+	if len(validator.messages) != 0 {
+		return errors.Errorf("Invalid function configuration:\n%s", strings.Join(validator.messages, "\n"))
+	}
+
+	return nil
+}
+
+func (sv *schemaValidator) validate(node *yaml.Node, valueType reflect.Type, path string) {
+	node = resolveNode(node)
+
+	// null is acceptable anywhere
+	if node == nil || node.Tag == "!!null" {
+		return
+	}
+
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	// types that decode themselves (e.g. resource quantities) or may hold anything can't be checked structurally
+	if valueType.Kind() == reflect.Interface ||
+		reflect.PtrTo(valueType).Implements(jsonUnmarshalerType) ||
+		reflect.PtrTo(valueType).Implements(textUnmarshalerType) {
+		return
+	}
+
+	switch valueType.Kind() {
+	case reflect.Struct:
+		if !sv.expectKind(node, yaml.MappingNode, "a mapping", path) {
+			return
+		}
+
+		fields := map[string]reflect.Type{}
+		collectStructFields(valueType, fields)
+
+		for keyIdx := 0; keyIdx+1 < len(node.Content); keyIdx += 2 {
+			keyNode, valueNode := node.Content[keyIdx], node.Content[keyIdx+1]
+
+			if path == "" && common.StringSliceContainsString(functionConfigHeaderFields, keyNode.Value) {
+				continue
+			}
+
+			fieldType, found := lookupField(fields, keyNode.Value)
+			if !found {
+				sv.addError(keyNode, fmt.Sprintf("unknown field %q%s", keyNode.Value, sv.describePath(path)))
+				continue
+			}
+
+			sv.validate(valueNode, fieldType, joinPath(path, keyNode.Value))
+		}
+
+	case reflect.Map:
+		if !sv.expectKind(node, yaml.MappingNode, "a mapping", path) {
+			return
+		}
+
+		for keyIdx := 0; keyIdx+1 < len(node.Content); keyIdx += 2 {
+			sv.validate(node.Content[keyIdx+1], valueType.Elem(), joinPath(path, node.Content[keyIdx].Value))
+		}
+
+	case reflect.Slice, reflect.Array:
+
+		// byte slices are encoded as strings
+		if valueType.Elem().Kind() == reflect.Uint8 {
+			sv.expectKind(node, yaml.ScalarNode, "a string", path)
+			return
+		}
+
+		if !sv.expectKind(node, yaml.SequenceNode, "a list", path) {
+			return
+		}
+
+		for itemIdx, itemNode := range node.Content {
+			sv.validate(itemNode, valueType.Elem(), fmt.Sprintf("%s[%d]", path, itemIdx))
+		}
+
+	case reflect.String:
+		sv.expectKind(node, yaml.ScalarNode, "a string", path)
+
+	case reflect.Bool:
+		sv.expectScalar(node, []string{"!!bool"}, "a boolean", path)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		sv.expectScalar(node, []string{"!!int"}, "an integer", path)
+
+	case reflect.Float32, reflect.Float64:
+		sv.expectScalar(node, []string{"!!int", "!!float"}, "a number", path)
+	}
+}
+
+func (sv *schemaValidator) expectKind(node *yaml.Node, kind yaml.Kind, description string, path string) bool {
+	if node.Kind == kind {
+		return true
+	}
+
+	sv.addError(node, fmt.Sprintf("expected %s%s", description, sv.describePath(path)))
+
+	return false
+}
+
+func (sv *schemaValidator) expectScalar(node *yaml.Node, tags []string, description string, path string) {
+	if node.Kind == yaml.ScalarNode && common.StringSliceContainsString(tags, node.ShortTag()) {
+		return
+	}
+
+	sv.addError(node, fmt.Sprintf("expected %s%s", description, sv.describePath(path)))
+}
+
+func (sv *schemaValidator) addError(node *yaml.Node, message string) {
+	sv.messages = append(sv.messages, formatBlockError(sv.fileName, sv.startLine+node.Line-1, message))
+}
+
+func (sv *schemaValidator) describePath(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" (at %s)", path)
+}
+
+// collectStructFields maps the json names of a struct's fields to their types, following the same rules
+// the function config is decoded with
+func collectStructFields(structType reflect.Type, fields map[string]reflect.Type) {
+	for fieldIdx := 0; fieldIdx < structType.NumField(); fieldIdx++ {
+		field := structType.Field(fieldIdx)
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		// untagged embedded structs are flattened into their parent
+		if field.Anonymous && name == "" {
+			embeddedType := field.Type
+			if embeddedType.Kind() == reflect.Ptr {
+				embeddedType = embeddedType.Elem()
+			}
+
+			if embeddedType.Kind() == reflect.Struct {
+				collectStructFields(embeddedType, fields)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields[name] = field.Type
+	}
+}
+
+// lookupField finds a field by name, falling back to a case-insensitive match like json decoding does
+func lookupField(fields map[string]reflect.Type, name string) (reflect.Type, bool) {
+	if fieldType, found := fields[name]; found {
+		return fieldType, true
+	}
+
+	for fieldName, fieldType := range fields {
+		if strings.EqualFold(fieldName, name) {
+			return fieldType, true
+		}
+	}
+
+	return nil, false
+}
+
+func resolveNode(node *yaml.Node) *yaml.Node {
+	for node != nil && (node.Kind == yaml.DocumentNode || node.Kind == yaml.AliasNode) {
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+			continue
+		}
+
+		if len(node.Content) == 0 {
+			return nil
+		}
+
+		node = node.Content[0]
+	}
+
+	return node
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
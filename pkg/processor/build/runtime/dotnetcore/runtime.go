@@ -17,12 +17,41 @@ limitations under the Licensd.
 package dotnetcore
 
 import (
+	"encoding/xml"
 	"fmt"
+	"path"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtimeconfig"
+
+	"github.com/nuclio/errors"
 )
 
+// restoring the handler in the onbuild image picks up the nuget configuration in its directory
+const nugetConfigFileName = "nuget.config"
+
+type nugetSetting struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type nugetPackageSources struct {
+	Clear struct{}       `xml:"clear"`
+	Add   []nugetSetting `xml:"add"`
+}
+
+type nugetPackageSourceCredentials struct {
+	XMLName xml.Name
+	Add     []nugetSetting `xml:"add"`
+}
+
+type nugetConfig struct {
+	XMLName                  xml.Name                        `xml:"configuration"`
+	PackageSources           nugetPackageSources             `xml:"packageSources"`
+	PackageSourceCredentials []nugetPackageSourceCredentials `xml:"packageSourceCredentials>source,omitempty"`
+}
+
 type dotnetcore struct {
 	*runtime.AbstractRuntime
 }
@@ -57,3 +86,58 @@ func (d *dotnetcore) GetProcessorDockerfileInfo(runtimeConfig *runtimeconfig.Con
 	processorDockerfileInfo.BaseImage = "mcr.microsoft.com/dotnet/runtime:7.0"
 	return &processorDockerfileInfo, nil
 }
+
+// OnAfterStagingDirCreated writes a nuget configuration that restores packages from the package registries instead
+// of nuget.org, unless the function brings its own
+func (d *dotnetcore) OnAfterStagingDirCreated(runtimeConfig *runtimeconfig.Config, stagingDir string) error {
+	packageRegistries, err := d.GetPackageRegistries(runtimeConfig, functionconfig.PackageRegistryKindNuGet)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get package registries")
+	}
+
+	if len(packageRegistries) == 0 {
+		return d.AbstractRuntime.OnAfterStagingDirCreated(runtimeConfig, stagingDir)
+	}
+
+	if d.FindHandlerFile(nugetConfigFileName, "NuGet.Config") != "" {
+		d.Logger.WarnWith("Function has its own nuget configuration, ignoring package registries",
+			"packageRegistries", len(packageRegistries))
+		return nil
+	}
+
+	renderedNugetConfig, err := renderNugetConfig(packageRegistries)
+	if err != nil {
+		return errors.Wrap(err, "Failed to render nuget configuration")
+	}
+
+	return d.WritePackageRegistryFile(path.Join(stagingDir, "handler"), nugetConfigFileName, renderedNugetConfig)
+}
+
+func renderNugetConfig(packageRegistries []functionconfig.PackageRegistry) (string, error) {
+	config := nugetConfig{}
+
+	for packageRegistryIndex, packageRegistry := range packageRegistries {
+		sourceName := fmt.Sprintf("nuclio-%d", packageRegistryIndex)
+		config.PackageSources.Add = append(config.PackageSources.Add, nugetSetting{
+			Key:   sourceName,
+			Value: packageRegistry.URL,
+		})
+
+		if packageRegistry.Username != "" || packageRegistry.Password != "" {
+			config.PackageSourceCredentials = append(config.PackageSourceCredentials, nugetPackageSourceCredentials{
+				XMLName: xml.Name{Local: sourceName},
+				Add: []nugetSetting{
+					{Key: "Username", Value: packageRegistry.Username},
+					{Key: "ClearTextPassword", Value: packageRegistry.Password},
+				},
+			})
+		}
+	}
+
+	encodedConfig, err := xml.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to encode nuget configuration")
+	}
+
+	return xml.Header + string(encodedConfig) + "\n", nil
+}
@@ -25,7 +25,18 @@ set -o errexit
 # show command before execute
 set -o xtrace
 
-if [ ! -f "go.mod" ]; then
+if [ -f "nuclio_wrapper_main.go" ]; then
+
+	# the handler runs out of process, so it's built with its own go.mod and only requires the wrapper module
+	if [ ! -f "go.mod" ]; then
+		go mod init handler
+	fi
+
+	go mod edit \
+		-require=github.com/nuclio/nuclio@v0.0.0-00010101000000-000000000000 \
+		-replace=github.com/nuclio/nuclio=/nuclio-wrapper
+
+elif [ ! -f "go.mod" ]; then
 	mv /processor_go.mod go.mod
 	mv /processor_go.sum go.sum
 fi
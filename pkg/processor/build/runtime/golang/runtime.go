@@ -18,19 +18,67 @@ package golang
 
 import (
 	"fmt"
+	"net/url"
+	"path"
 	"strings"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime/golang/eventhandlerparser"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtimeconfig"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/nuclio/errors"
 )
 
+const (
+
+	// modeProcess builds the handler as a standalone binary, which the processor runs as a separate process
+	modeProcess = "process"
+
+	defaultEntrypoint = "Handler"
+
+	// the onbuild image points GOENV and NETRC at these files in the handler directory
+	goEnvFileName = ".nuclio-go.env"
+	netrcFileName = ".nuclio-netrc"
+)
+
 type golang struct {
 	*runtime.AbstractRuntime
 }
 
+// OnAfterStagingDirCreated configures the package registries and generates the main function of the handler
+// binary, when running out of process
+func (g *golang) OnAfterStagingDirCreated(runtimeConfig *runtimeconfig.Config, stagingDir string) error {
+	if err := g.writeGoProxyConfig(runtimeConfig, stagingDir); err != nil {
+		return errors.Wrap(err, "Failed to write go proxy configuration")
+	}
+
+	processMode, err := g.isProcessMode()
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve mode")
+	}
+
+	if !processMode {
+		return g.AbstractRuntime.OnAfterStagingDirCreated(runtimeConfig, stagingDir)
+	}
+
+	_, entrypoint, err := functionconfig.ParseHandler(g.FunctionConfig.Spec.Handler)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse handler")
+	}
+
+	if entrypoint == "" {
+		entrypoint = defaultEntrypoint
+	}
+
+	if err := writeWrapperMain(path.Join(stagingDir, "handler"), entrypoint); err != nil {
+		return errors.Wrap(err, "Failed to write wrapper main")
+	}
+
+	return nil
+}
+
 // DetectFunctionHandlers returns a list of all the handlers
 // in that directory given a path holding a function (or functions)
 func (g *golang) DetectFunctionHandlers(functionPath string) ([]string, error) {
@@ -79,6 +127,11 @@ func (g *golang) GetProcessorDockerfileInfo(runtimeConfig *runtimeconfig.Config,
 		onbuildImage = "%s/nuclio/handler-builder-golang-onbuild:%s-%s-alpine"
 	}
 
+	processMode, err := g.isProcessMode()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve mode")
+	}
+
 	// fill onbuild artifact
 	artifact := runtime.Artifact{
 		Image: fmt.Sprintf(onbuildImage, onbuildImageRegistry, g.VersionInfo.Label, g.VersionInfo.Arch),
@@ -88,7 +141,75 @@ func (g *golang) GetProcessorDockerfileInfo(runtimeConfig *runtimeconfig.Config,
 			"/home/nuclio/bin/handler.so": "/opt/nuclio/handler.so",
 		},
 	}
+
+	// the onbuild image builds a binary instead of a plugin
+	if processMode {
+		artifact.Paths = map[string]string{
+			"/home/nuclio/bin/processor": "/usr/local/bin/processor",
+			"/home/nuclio/bin/handler":   "/opt/nuclio/handler",
+		}
+	}
+
 	processorDockerfileInfo.OnbuildArtifacts = []runtime.Artifact{artifact}
 
 	return &processorDockerfileInfo, nil
 }
+
+func (g *golang) isProcessMode() (bool, error) {
+	var attributes struct {
+		Mode string
+	}
+
+	if err := mapstructure.Decode(g.FunctionConfig.Spec.RuntimeAttributes, &attributes); err != nil {
+		return false, errors.Wrap(err, "Failed to decode runtime attributes")
+	}
+
+	return attributes.Mode == modeProcess, nil
+}
+
+// writeGoProxyConfig writes a go environment that downloads modules from the package registries, and a netrc
+// holding their credentials
+func (g *golang) writeGoProxyConfig(runtimeConfig *runtimeconfig.Config, stagingDir string) error {
+	packageRegistries, err := g.GetPackageRegistries(runtimeConfig, functionconfig.PackageRegistryKindGoProxy)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get package registries")
+	}
+
+	if len(packageRegistries) == 0 {
+		return nil
+	}
+
+	var proxyURLs, privateModules, netrcLines []string
+	for _, packageRegistry := range packageRegistries {
+		proxyURLs = append(proxyURLs, packageRegistry.URL)
+		privateModules = append(privateModules, packageRegistry.PrivateModules...)
+
+		if packageRegistry.Username != "" || packageRegistry.Password != "" {
+			proxyURL, err := url.Parse(packageRegistry.URL)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to parse package registry URL %s", packageRegistry.URL)
+			}
+
+			netrcLines = append(netrcLines, fmt.Sprintf("machine %s login %s password %s",
+				proxyURL.Hostname(),
+				packageRegistry.Username,
+				packageRegistry.Password))
+		}
+	}
+
+	goEnv := fmt.Sprintf("GOPROXY=%s\n", strings.Join(proxyURLs, ","))
+	if len(privateModules) > 0 {
+		goEnv += fmt.Sprintf("GONOSUMDB=%s\n", strings.Join(privateModules, ","))
+	}
+
+	handlerDir := path.Join(stagingDir, "handler")
+	if err := g.WritePackageRegistryFile(handlerDir, goEnvFileName, goEnv); err != nil {
+		return errors.Wrap(err, "Failed to write go environment")
+	}
+
+	if len(netrcLines) == 0 {
+		return nil
+	}
+
+	return g.WritePackageRegistryFile(handlerDir, netrcFileName, strings.Join(netrcLines, "\n")+"\n")
+}
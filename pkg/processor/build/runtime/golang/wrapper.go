@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/nuclio/errors"
+)
+
+const (
+
+	// the onbuild image builds a binary rather than a plugin when this file exists in the handler dir
+	wrapperMainFileName = "nuclio_wrapper_main.go"
+
+	contextInitializerName = "InitContext"
+	contextShutdownName    = "ShutdownContext"
+)
+
+var wrapperMainTemplate = template.Must(template.New("wrapperMain").Parse(`// Code generated by nuclio. DO NOT EDIT.
+
+package main
+
+import "github.com/nuclio/nuclio/pkg/processor/runtime/golang/wrapper"
+
+func main() {
+	wrapper.Run({{ .Entrypoint }}, {{ .ContextInitializer }}, {{ .ContextShutdown }})
+}
+`))
+
+// writeWrapperMain generates a main function in the handler's package, which runs the given entrypoint with
+// the wrapper package
+func writeWrapperMain(handlerDir string, entrypoint string) error {
+	packageName, functionNames, err := parseHandlerPackage(handlerDir)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse handler package")
+	}
+
+	if packageName != "main" {
+		return errors.Errorf("Handler package must be main, got %s", packageName)
+	}
+
+	if _, found := functionNames["main"]; found {
+		return errors.New("Handler package must not declare a main function")
+	}
+
+	if _, found := functionNames[entrypoint]; !found {
+		return errors.Errorf("Handler package does not declare %s", entrypoint)
+	}
+
+	// the context initializer and shutdown are optional, like in plugin mode
+	contextInitializer := "nil"
+	if _, found := functionNames[contextInitializerName]; found {
+		contextInitializer = contextInitializerName
+	}
+
+	contextShutdown := "nil"
+	if _, found := functionNames[contextShutdownName]; found {
+		contextShutdown = contextShutdownName
+	}
+
+	var wrapperMain bytes.Buffer
+	if err := wrapperMainTemplate.Execute(&wrapperMain, map[string]string{
+		"Entrypoint":         entrypoint,
+		"ContextInitializer": contextInitializer,
+		"ContextShutdown":    contextShutdown,
+	}); err != nil {
+		return errors.Wrap(err, "Failed to render wrapper main")
+	}
+
+	return os.WriteFile(filepath.Join(handlerDir, wrapperMainFileName), wrapperMain.Bytes(), 0644)
+}
+
+// parseHandlerPackage returns the name of the package in the given dir and the names of its top level functions
+func parseHandlerPackage(handlerDir string) (string, map[string]struct{}, error) {
+	sourcePaths, err := filepath.Glob(filepath.Join(handlerDir, "*.go"))
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Failed to look for Go sources")
+	}
+
+	packageName := ""
+	functionNames := map[string]struct{}{}
+	fileSet := token.NewFileSet()
+
+	for _, sourcePath := range sourcePaths {
+		if strings.HasSuffix(sourcePath, "_test.go") {
+			continue
+		}
+
+		parsedFile, err := parser.ParseFile(fileSet, sourcePath, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "Failed to parse %s", sourcePath)
+		}
+
+		if packageName != "" && packageName != parsedFile.Name.Name {
+			return "", nil, errors.Errorf("Expected one package, found %s and %s", packageName, parsedFile.Name.Name)
+		}
+
+		packageName = parsedFile.Name.Name
+
+		for _, declaration := range parsedFile.Decls {
+			if functionDeclaration, isFunction := declaration.(*ast.FuncDecl); isFunction &&
+				functionDeclaration.Recv == nil {
+				functionNames[functionDeclaration.Name.Name] = struct{}{}
+			}
+		}
+	}
+
+	if packageName == "" {
+		return "", nil, errors.Errorf("No Go sources found in %s", handlerDir)
+	}
+
+	return packageName, functionNames, nil
+}
@@ -0,0 +1,114 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type WrapperMainTestSuite struct {
+	suite.Suite
+	handlerDir string
+}
+
+func (suite *WrapperMainTestSuite) SetupTest() {
+	suite.handlerDir = suite.T().TempDir()
+}
+
+func (suite *WrapperMainTestSuite) TestWithContextInitializer() {
+	suite.writeSource("handler.go", `package main
+
+import "github.com/nuclio/nuclio-sdk-go"
+
+func Handler(context *nuclio.Context, event nuclio.Event) (interface{}, error) {
+	return nil, nil
+}
+
+func InitContext(context *nuclio.Context) error {
+	return nil
+}
+
+func ShutdownContext(context *nuclio.Context) error {
+	return nil
+}
+`)
+
+	suite.Require().NoError(writeWrapperMain(suite.handlerDir, "Handler"))
+
+	wrapperMain, err := os.ReadFile(filepath.Join(suite.handlerDir, wrapperMainFileName))
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(wrapperMain), "wrapper.Run(Handler, InitContext, ShutdownContext)")
+}
+
+func (suite *WrapperMainTestSuite) TestWithoutContextInitializer() {
+	suite.writeSource("handler.go", `package main
+
+import "github.com/nuclio/nuclio-sdk-go"
+
+func MyHandler(context *nuclio.Context, event nuclio.Event) (interface{}, error) {
+	return nil, nil
+}
+`)
+
+	suite.Require().NoError(writeWrapperMain(suite.handlerDir, "MyHandler"))
+
+	wrapperMain, err := os.ReadFile(filepath.Join(suite.handlerDir, wrapperMainFileName))
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(wrapperMain), "wrapper.Run(MyHandler, nil, nil)")
+}
+
+func (suite *WrapperMainTestSuite) TestInvalidPackage() {
+	for _, testCase := range []struct {
+		name   string
+		source string
+	}{
+		{
+			name:   "notMain",
+			source: "package handler\n\nfunc Handler() {}\n",
+		},
+		{
+			name:   "declaresMain",
+			source: "package main\n\nfunc Handler() {}\n\nfunc main() {}\n",
+		},
+		{
+			name:   "missingEntrypoint",
+			source: "package main\n\nfunc Other() {}\n",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.handlerDir = suite.T().TempDir()
+			suite.writeSource("handler.go", testCase.source)
+
+			suite.Require().Error(writeWrapperMain(suite.handlerDir, "Handler"))
+			suite.Require().NoFileExists(filepath.Join(suite.handlerDir, wrapperMainFileName))
+		})
+	}
+}
+
+func (suite *WrapperMainTestSuite) writeSource(fileName string, source string) {
+	suite.Require().NoError(os.WriteFile(filepath.Join(suite.handlerDir, fileName), []byte(source), 0644))
+}
+
+func TestWrapperMainTestSuite(t *testing.T) {
+	suite.Run(t, new(WrapperMainTestSuite))
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpsidecar
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtimeconfig"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+// the handler of functions whose server isn't started by the processor
+const externalServerHandler = "server"
+
+type httpSidecar struct {
+	*runtime.AbstractRuntime
+}
+
+type attributes struct {
+	Entrypoint string
+	Port       int
+}
+
+// OnAfterStagingDirCreated verifies the processor will be able to reach the server, before building anything
+func (h *httpSidecar) OnAfterStagingDirCreated(runtimeConfig *runtimeconfig.Config, stagingDir string) error {
+	runtimeAttributes, err := h.getAttributes()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get runtime attributes")
+	}
+
+	if runtimeAttributes.Entrypoint == "" && runtimeAttributes.Port == 0 {
+		return errors.New("Either an entrypoint or the port of an externally started server must be provided")
+	}
+
+	return h.AbstractRuntime.OnAfterStagingDirCreated(runtimeConfig, stagingDir)
+}
+
+// DetectFunctionHandlers returns a list of all the handlers
+// in that directory given a path holding a function (or functions)
+func (h *httpSidecar) DetectFunctionHandlers(functionPath string) ([]string, error) {
+	runtimeAttributes, err := h.getAttributes()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get runtime attributes")
+	}
+
+	// the server handles all events, so the handler only names the executable that runs it
+	entrypointFields := strings.Fields(runtimeAttributes.Entrypoint)
+	if len(entrypointFields) == 0 {
+		return []string{externalServerHandler}, nil
+	}
+
+	return []string{path.Base(entrypointFields[0])}, nil
+}
+
+// GetName returns the name of the runtime, including version if applicable
+func (h *httpSidecar) GetName() string {
+	return "httpsidecar"
+}
+
+// GetProcessorDockerfileInfo returns information required to build the processor Dockerfile
+func (h *httpSidecar) GetProcessorDockerfileInfo(runtimeConfig *runtimeconfig.Config, onbuildImageRegistry string) (*runtime.ProcessorDockerfileInfo, error) {
+
+	// the server's image is expected to be passed as the base image, the default is for servers deployed as files
+	processorDockerfileInfo := runtime.ProcessorDockerfileInfo{
+		BaseImage: "alpine:3.20",
+	}
+
+	// the processor is copied into the base image, and runs the server as its child
+	artifact := runtime.Artifact{
+		Name: "nuclio-processor",
+		Image: fmt.Sprintf("%s/nuclio/processor:%s-%s",
+			onbuildImageRegistry,
+			h.VersionInfo.Label,
+			h.VersionInfo.Arch),
+		Paths: map[string]string{
+			"/home/nuclio/bin/processor": "/usr/local/bin/processor",
+		},
+	}
+	processorDockerfileInfo.OnbuildArtifacts = []runtime.Artifact{artifact}
+
+	processorDockerfileInfo.ImageArtifactPaths = map[string]string{
+		"handler": "/opt/nuclio",
+	}
+
+	return &processorDockerfileInfo, nil
+}
+
+// GetHandlerDirObjectPaths returns the paths of all objects that should reside in the handler
+// directory
+func (h *httpSidecar) GetHandlerDirObjectPaths() []string {
+	if h.FunctionConfig.Spec.Build.Path != "/dev/null" {
+		return h.AbstractRuntime.GetHandlerDirObjectPaths()
+	}
+
+	return []string{}
+}
+
+func (h *httpSidecar) getAttributes() (*attributes, error) {
+	runtimeAttributes := attributes{}
+
+	if err := mapstructure.Decode(h.FunctionConfig.Spec.RuntimeAttributes, &runtimeAttributes); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode runtime attributes")
+	}
+
+	runtimeAttributes.Entrypoint = strings.TrimSpace(runtimeAttributes.Entrypoint)
+
+	return &runtimeAttributes, nil
+}
@@ -22,9 +22,11 @@ import (
 	"io"
 	"os"
 	"path"
+	"strings"
 	"text/template"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtimeconfig"
 
@@ -44,7 +46,7 @@ func (j *java) GetName() string {
 // It will set generatedJarPath field
 func (j *java) OnAfterStagingDirCreated(runtimeConfig *runtimeconfig.Config, stagingDir string) error {
 	// create a build script alongside the user's code. if user provided a script, it'll use that
-	return j.createGradleBuildScript(stagingDir)
+	return j.createGradleBuildScript(runtimeConfig, stagingDir)
 }
 
 // GetProcessorDockerfileInfo returns information required to build the processor Dockerfile
@@ -70,7 +72,7 @@ func (j *java) GetProcessorDockerfileInfo(runtimeConfig *runtimeconfig.Config, o
 	return &processorDockerfileInfo, nil
 }
 
-func (j *java) createGradleBuildScript(stagingBuildDir string) error {
+func (j *java) createGradleBuildScript(runtimeConfig *runtimeconfig.Config, stagingBuildDir string) error {
 	handlerPath := path.Join(stagingBuildDir, "handler")
 
 	// if user supplied gradle build script - use it
@@ -111,14 +113,26 @@ func (j *java) createGradleBuildScript(stagingBuildDir string) error {
 		return errors.Wrap(err, "Failed to parse dependencies")
 	}
 
-	buildAttributes, err := newBuildAttributes(j.FunctionConfig.Spec.Build.RuntimeAttributes)
+	repositories, err := j.getPackageRegistryRepositories(runtimeConfig, handlerPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get package registry repositories")
+	}
+
+	// the package registries replace maven central, unless the function lists its repositories
+	defaultRepositories := []string{"mavenCentral()"}
+	if len(repositories) > 0 {
+		defaultRepositories = nil
+	}
+
+	buildAttributes, err := newBuildAttributes(j.FunctionConfig.Spec.Build.RuntimeAttributes, defaultRepositories)
 	if err != nil {
 		return errors.Wrap(err, "Failed to get build attributes repositories")
 	}
 
 	data := map[string]interface{}{
-		"Dependencies": dependencies,
-		"Repositories": buildAttributes.Repositories,
+		"Dependencies":      dependencies,
+		"Repositories":      append(repositories, buildAttributes.Repositories...),
+		"DependencyLocking": j.FindHandlerFile(path.Join("gradle", "dependency-locks")) != "",
 	}
 
 	var gradleBuildScriptTemplateBuffer bytes.Buffer
@@ -143,7 +157,11 @@ repositories {
 	{{ . }}
 	{{ end }}
 }
-
+{{ if .DependencyLocking }}
+dependencyLocking {
+	lockAllConfigurations()
+}
+{{ end }}
 dependencies {
 	{{ range .Dependencies }}
 	compile group: '{{.Group}}', name: '{{.Name}}', version: '{{.Version}}'
@@ -161,6 +179,59 @@ task userHandler(dependsOn: shadowJar)
 `
 }
 
+// getPackageRegistryRepositories returns the gradle repositories of the maven package registries. their credentials
+// are written to the project's gradle properties, which the repositories read them from
+func (j *java) getPackageRegistryRepositories(runtimeConfig *runtimeconfig.Config,
+	handlerPath string) ([]string, error) {
+	packageRegistries, err := j.GetPackageRegistries(runtimeConfig, functionconfig.PackageRegistryKindMaven)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get package registries")
+	}
+
+	var repositories, gradleProperties []string
+	for packageRegistryIndex, packageRegistry := range packageRegistries {
+		repository := fmt.Sprintf("maven {\n\t\turl '%s'\n", escapeGroovyString(packageRegistry.URL))
+
+		if packageRegistry.Username != "" || packageRegistry.Password != "" {
+			propertyPrefix := fmt.Sprintf("nuclioPackageRegistry%d", packageRegistryIndex)
+			gradleProperties = append(gradleProperties,
+				fmt.Sprintf("%sUsername=%s", propertyPrefix, escapeGradleProperty(packageRegistry.Username)),
+				fmt.Sprintf("%sPassword=%s", propertyPrefix, escapeGradleProperty(packageRegistry.Password)))
+
+			repository += fmt.Sprintf("\t\tcredentials {\n"+
+				"\t\t\tusername %[1]sUsername\n"+
+				"\t\t\tpassword %[1]sPassword\n"+
+				"\t\t}\n", propertyPrefix)
+		}
+
+		repositories = append(repositories, repository+"\t}")
+	}
+
+	if len(gradleProperties) == 0 {
+		return repositories, nil
+	}
+
+	// keep the function's own gradle properties
+	gradlePropertiesPath := path.Join(handlerPath, "gradle.properties")
+	existingGradleProperties, err := os.ReadFile(gradlePropertiesPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "Failed to read gradle properties")
+	}
+
+	if len(existingGradleProperties) > 0 {
+		gradleProperties = append([]string{strings.TrimSuffix(string(existingGradleProperties), "\n")},
+			gradleProperties...)
+	}
+
+	if err := j.WritePackageRegistryFile(handlerPath,
+		"gradle.properties",
+		strings.Join(gradleProperties, "\n")+"\n"); err != nil {
+		return nil, errors.Wrap(err, "Failed to write gradle properties")
+	}
+
+	return repositories, nil
+}
+
 func (j *java) parseDependencies(rawDependencies []string) ([]dependency, error) {
 	var dependencies []dependency
 
@@ -175,3 +246,11 @@ func (j *java) parseDependencies(rawDependencies []string) ([]dependency, error)
 
 	return dependencies, nil
 }
+
+func escapeGroovyString(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+}
+
+func escapeGradleProperty(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(value)
+}
@@ -47,7 +47,8 @@ type buildAttributes struct {
 	Repositories []string
 }
 
-func newBuildAttributes(encodedBuildAttributes map[string]interface{}) (*buildAttributes, error) {
+func newBuildAttributes(encodedBuildAttributes map[string]interface{},
+	defaultRepositories []string) (*buildAttributes, error) {
 	newBuildAttributes := buildAttributes{}
 
 	// parse attributes
@@ -56,9 +57,7 @@ func newBuildAttributes(encodedBuildAttributes map[string]interface{}) (*buildAt
 	}
 
 	if len(newBuildAttributes.Repositories) == 0 {
-		newBuildAttributes.Repositories = []string{
-			"mavenCentral()",
-		}
+		newBuildAttributes.Repositories = defaultRepositories
 	}
 
 	return &newBuildAttributes, nil
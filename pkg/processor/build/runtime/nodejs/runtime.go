@@ -17,13 +17,32 @@ limitations under the License.
 package nodejs
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net/url"
+	"path"
+	"strings"
 
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtimeconfig"
+
+	"github.com/nuclio/errors"
 )
 
+const npmConfigFileLocation = "/etc/nuclio/npmrc"
+
+// the commands installing the dependencies locked by each supported lockfile, in order of precedence
+var lockfileInstallCommands = []struct {
+	lockfileName   string
+	installCommand string
+}{
+	{lockfileName: "npm-shrinkwrap.json", installCommand: "cd /opt/nuclio && npm ci --production"},
+	{lockfileName: "package-lock.json", installCommand: "cd /opt/nuclio && npm ci --production"},
+	{lockfileName: "yarn.lock", installCommand: "cd /opt/nuclio && yarn install --frozen-lockfile --production"},
+}
+
 type nodejs struct {
 	*runtime.AbstractRuntime
 }
@@ -65,5 +84,85 @@ func (n *nodejs) GetProcessorDockerfileInfo(runtimeConfig *runtimeconfig.Config,
 		},
 	}
 
+	// install the dependencies of the function's lockfile, once it's copied to the image
+	for _, lockfileInstallCommand := range lockfileInstallCommands {
+		if n.FindHandlerFile(lockfileInstallCommand.lockfileName) != "" {
+			processorDockerfileInfo.Directives["postCopy"] = append(processorDockerfileInfo.Directives["postCopy"],
+				functionconfig.Directive{
+					Kind:  "RUN",
+					Value: lockfileInstallCommand.installCommand,
+				})
+			break
+		}
+	}
+
+	// have npm install from the package registries. the configuration holds their credentials, so it's only
+	// available to the build's commands rather than copied to the image
+	if common.IsFile(path.Join(n.StagingDir, path.Base(npmConfigFileLocation))) {
+		processorDockerfileInfo.BuildSecrets = []runtime.BuildSecret{
+			{FileName: path.Base(npmConfigFileLocation), Path: npmConfigFileLocation},
+		}
+		processorDockerfileInfo.Directives["preCopy"] = []functionconfig.Directive{
+			{Kind: "ARG", Value: fmt.Sprintf("NPM_CONFIG_USERCONFIG=%s", npmConfigFileLocation)},
+		}
+	}
+
 	return &processorDockerfileInfo, nil
 }
+
+// OnAfterStagingDirCreated writes an npm configuration that installs packages from the package registries
+func (n *nodejs) OnAfterStagingDirCreated(runtimeConfig *runtimeconfig.Config, stagingDir string) error {
+	packageRegistries, err := n.GetPackageRegistries(runtimeConfig, functionconfig.PackageRegistryKindNPM)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get package registries")
+	}
+
+	if len(packageRegistries) == 0 {
+		return n.AbstractRuntime.OnAfterStagingDirCreated(runtimeConfig, stagingDir)
+	}
+
+	npmConfig, err := renderNPMConfig(packageRegistries)
+	if err != nil {
+		return errors.Wrap(err, "Failed to render npm configuration")
+	}
+
+	return n.WritePackageRegistryFile(stagingDir, path.Base(npmConfigFileLocation), npmConfig)
+}
+
+// renderNPMConfig renders the registries as an .npmrc - each scope is installed from its registry, and the
+// credentials of a registry are keyed by its URL without the scheme
+func renderNPMConfig(packageRegistries []functionconfig.PackageRegistry) (string, error) {
+	var npmConfigLines []string
+	scopes := map[string]bool{}
+
+	for _, packageRegistry := range packageRegistries {
+		if scopes[packageRegistry.Scope] {
+			return "", errors.Errorf("npm scope %q has more than one package registry", packageRegistry.Scope)
+		}
+
+		scopes[packageRegistry.Scope] = true
+
+		registryURL, err := url.Parse(strings.TrimSuffix(packageRegistry.URL, "/") + "/")
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to parse package registry URL %s", packageRegistry.URL)
+		}
+
+		if packageRegistry.Scope == "" {
+			npmConfigLines = append(npmConfigLines, fmt.Sprintf("registry=%s", registryURL))
+		} else {
+			npmConfigLines = append(npmConfigLines, fmt.Sprintf("%s:registry=%s", packageRegistry.Scope, registryURL))
+		}
+
+		authPrefix := fmt.Sprintf("//%s%s:", registryURL.Host, registryURL.Path)
+		switch {
+		case packageRegistry.Username != "":
+			npmConfigLines = append(npmConfigLines,
+				authPrefix+"username="+packageRegistry.Username,
+				authPrefix+"_password="+base64.StdEncoding.EncodeToString([]byte(packageRegistry.Password)))
+		case packageRegistry.Password != "":
+			npmConfigLines = append(npmConfigLines, authPrefix+"_authToken="+packageRegistry.Password)
+		}
+	}
+
+	return strings.Join(npmConfigLines, "\n") + "\n", nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtimeconfig"
+
+	"github.com/nuclio/errors"
+)
+
+// GetPackageRegistries returns the registries of a kind that the function's dependencies are installed from - the
+// function's own, followed by the platform's registries of scopes the function doesn't set. passwords held in files
+// are read, so that the registries can be rendered as is
+func (ar *AbstractRuntime) GetPackageRegistries(runtimeConfig *runtimeconfig.Config,
+	kind functionconfig.PackageRegistryKind) ([]functionconfig.PackageRegistry, error) {
+	var packageRegistries []functionconfig.PackageRegistry
+	functionScopes := map[string]bool{}
+
+	for _, packageRegistry := range ar.FunctionConfig.Spec.Build.PackageRegistries {
+		if packageRegistry.Kind == kind {
+			packageRegistries = append(packageRegistries, packageRegistry)
+			functionScopes[packageRegistry.Scope] = true
+		}
+	}
+
+	if runtimeConfig == nil {
+		return packageRegistries, nil
+	}
+
+	for _, packageRegistry := range runtimeConfig.PackageRegistries {
+		if packageRegistry.Kind != kind || functionScopes[packageRegistry.Scope] {
+			continue
+		}
+
+		if err := packageRegistry.Validate(); err != nil {
+			return nil, errors.Wrap(err, "Invalid platform package registry")
+		}
+
+		if packageRegistry.PasswordPath != "" {
+			password, err := os.ReadFile(packageRegistry.PasswordPath)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to read password of package registry %s", packageRegistry.URL)
+			}
+
+			packageRegistry.Password = strings.TrimSpace(string(password))
+			packageRegistry.PasswordPath = ""
+		}
+
+		packageRegistries = append(packageRegistries, packageRegistry)
+	}
+
+	return packageRegistries, nil
+}
+
+// FindHandlerFile returns the first of the given file names found in the staged handler directory, if any
+func (ar *AbstractRuntime) FindHandlerFile(fileNames ...string) string {
+	for _, fileName := range fileNames {
+		if common.FileExists(path.Join(ar.StagingDir, "handler", fileName)) {
+			return fileName
+		}
+	}
+
+	return ""
+}
+
+// WritePackageRegistryFile writes a rendered package manager configuration to a directory in staging. it holds
+// credentials, so only its owner may read it
+func (ar *AbstractRuntime) WritePackageRegistryFile(dir string, fileName string, contents string) error {
+	filePath := path.Join(dir, fileName)
+
+	ar.Logger.DebugWith("Writing package registry configuration", "path", filePath)
+
+	if err := os.WriteFile(filePath, []byte(contents), 0600); err != nil {
+		return errors.Wrapf(err, "Failed to write package registry configuration %s", fileName)
+	}
+
+	return nil
+}
+
+// GetPackageRegistryURL returns the URL of the registry, with its credentials if it has any
+func GetPackageRegistryURL(packageRegistry *functionconfig.PackageRegistry) (string, error) {
+	registryURL, err := url.Parse(packageRegistry.URL)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to parse package registry URL %s", packageRegistry.URL)
+	}
+
+	if packageRegistry.Username != "" || packageRegistry.Password != "" {
+		registryURL.User = url.UserPassword(packageRegistry.Username, packageRegistry.Password)
+	}
+
+	return registryURL.String(), nil
+}
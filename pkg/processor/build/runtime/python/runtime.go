@@ -30,7 +30,26 @@ import (
 	"github.com/nuclio/errors"
 )
 
-const pipCAFileLocation = "/etc/ssl/certs/nuclio/pip-ca-certificates.crt"
+const (
+	pipCAFileLocation     = "/etc/ssl/certs/nuclio/pip-ca-certificates.crt"
+	pipConfigFileLocation = "/etc/nuclio/pip.conf"
+)
+
+// the commands installing the dependencies locked by each supported lockfile, in order of precedence
+var lockfileInstallCommands = []struct {
+	lockfileName   string
+	installCommand string
+}{
+	{
+		lockfileName:   "Pipfile.lock",
+		installCommand: "python -m pip install pipenv && cd /opt/nuclio && pipenv install --system --deploy",
+	},
+	{
+		lockfileName: "poetry.lock",
+		installCommand: "python -m pip install poetry && cd /opt/nuclio && " +
+			"poetry config virtualenvs.create false && poetry install --no-root --no-interaction",
+	},
+}
 
 type python struct {
 	*runtime.AbstractRuntime
@@ -121,6 +140,31 @@ func (p *python) GetProcessorDockerfileInfo(runtimeConfig *runtimeconfig.Config,
 		},
 	}
 
+	// install the dependencies of the function's lockfile, once it's copied to the image
+	for _, lockfileInstallCommand := range lockfileInstallCommands {
+		if p.FindHandlerFile(lockfileInstallCommand.lockfileName) != "" {
+			processorDockerfileInfo.Directives["postCopy"] = append(processorDockerfileInfo.Directives["postCopy"],
+				functionconfig.Directive{
+					Kind:  "RUN",
+					Value: lockfileInstallCommand.installCommand,
+				})
+			break
+		}
+	}
+
+	// have pip install from the package registries. the configuration holds their credentials, so it's only
+	// available to the build's commands rather than copied to the image
+	if common.IsFile(path.Join(p.StagingDir, path.Base(pipConfigFileLocation))) {
+		processorDockerfileInfo.BuildSecrets = []runtime.BuildSecret{
+			{FileName: path.Base(pipConfigFileLocation), Path: pipConfigFileLocation},
+		}
+		processorDockerfileInfo.Directives["preCopy"] = append(processorDockerfileInfo.Directives["preCopy"],
+			functionconfig.Directive{
+				Kind:  "ARG",
+				Value: fmt.Sprintf("PIP_CONFIG_FILE=%s", pipConfigFileLocation),
+			})
+	}
+
 	// copy pip ca artifact to function image
 	if runtimeConfig != nil && runtimeConfig.Python != nil {
 		if runtimeConfig.Python.PipCAPath != "" {
@@ -155,6 +199,10 @@ func (p *python) GetRuntimeBuildArgs(runtimeConfig *runtimeconfig.Config) map[st
 }
 
 func (p *python) OnAfterStagingDirCreated(runtimeConfig *runtimeconfig.Config, stagingDir string) error {
+	if err := p.writePipConfig(runtimeConfig, stagingDir); err != nil {
+		return errors.Wrap(err, "Failed to write pip configuration")
+	}
+
 	if runtimeConfig != nil && runtimeConfig.Python != nil {
 		PipCAContents, err := runtimeConfig.Python.GetPipCAContents()
 		if err != nil {
@@ -169,3 +217,33 @@ func (p *python) OnAfterStagingDirCreated(runtimeConfig *runtimeconfig.Config, s
 	}
 	return p.AbstractRuntime.OnAfterStagingDirCreated(runtimeConfig, stagingDir)
 }
+
+// writePipConfig writes a pip configuration that installs packages from the package registries. the first registry
+// replaces the package index, and the rest are searched in addition to it
+func (p *python) writePipConfig(runtimeConfig *runtimeconfig.Config, stagingDir string) error {
+	packageRegistries, err := p.GetPackageRegistries(runtimeConfig, functionconfig.PackageRegistryKindPyPI)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get package registries")
+	}
+
+	if len(packageRegistries) == 0 {
+		return nil
+	}
+
+	var indexURLs []string
+	for packageRegistryIndex := range packageRegistries {
+		indexURL, err := runtime.GetPackageRegistryURL(&packageRegistries[packageRegistryIndex])
+		if err != nil {
+			return errors.Wrap(err, "Failed to get package registry URL")
+		}
+
+		indexURLs = append(indexURLs, indexURL)
+	}
+
+	pipConfig := fmt.Sprintf("[global]\nindex-url = %s\n", indexURLs[0])
+	if len(indexURLs) > 1 {
+		pipConfig += fmt.Sprintf("extra-index-url = %s\n", strings.Join(indexURLs[1:], " "))
+	}
+
+	return p.WritePackageRegistryFile(stagingDir, path.Base(pipConfigFileLocation), pipConfig)
+}
@@ -18,9 +18,26 @@ package ruby
 
 import (
 	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
 
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtimeconfig"
+
+	"github.com/nuclio/errors"
+)
+
+const (
+	gemConfigFileLocation    = "/etc/nuclio/gemrc"
+	bundlerConfigDirLocation = "/etc/nuclio/bundle"
+	bundlerConfigFileName    = "bundle-config"
+	defaultRubyGemsRegistry  = "https://rubygems.org/"
+	bundlerLockfileName      = "Gemfile.lock"
+	bundlerInstallCommand    = "cd /opt/nuclio && bundle install --frozen"
 )
 
 type ruby struct {
@@ -57,5 +74,87 @@ func (r *ruby) GetProcessorDockerfileInfo(runtimeConfig *runtimeconfig.Config, o
 	}
 	processorDockerfileInfo.OnbuildArtifacts = []runtime.Artifact{artifact}
 
+	// have gem and bundler install from the package registries. the configurations hold their credentials, so
+	// they're only available to the build's commands rather than copied to the image
+	if common.IsFile(path.Join(r.StagingDir, path.Base(gemConfigFileLocation))) {
+		processorDockerfileInfo.BuildSecrets = []runtime.BuildSecret{
+			{FileName: path.Base(gemConfigFileLocation), Path: gemConfigFileLocation},
+			{FileName: bundlerConfigFileName, Path: path.Join(bundlerConfigDirLocation, "config")},
+		}
+		processorDockerfileInfo.Directives = map[string][]functionconfig.Directive{
+			"preCopy": {
+				{Kind: "ARG", Value: fmt.Sprintf("GEMRC=%s", gemConfigFileLocation)},
+				{Kind: "ARG", Value: fmt.Sprintf("BUNDLE_APP_CONFIG=%s", bundlerConfigDirLocation)},
+			},
+		}
+	}
+
+	// install the dependencies of the function's lockfile, once it's copied to the image
+	if r.FindHandlerFile(bundlerLockfileName) != "" {
+		if processorDockerfileInfo.Directives == nil {
+			processorDockerfileInfo.Directives = map[string][]functionconfig.Directive{}
+		}
+
+		processorDockerfileInfo.Directives["postCopy"] = []functionconfig.Directive{
+			{Kind: "RUN", Value: bundlerInstallCommand},
+		}
+	}
+
 	return &processorDockerfileInfo, nil
 }
+
+// OnAfterStagingDirCreated writes gem and bundler configurations that install gems from the package registries.
+// the first registry mirrors rubygems.org for bundler, and each registry's credentials are keyed by its host
+func (r *ruby) OnAfterStagingDirCreated(runtimeConfig *runtimeconfig.Config, stagingDir string) error {
+	packageRegistries, err := r.GetPackageRegistries(runtimeConfig, functionconfig.PackageRegistryKindRubyGems)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get package registries")
+	}
+
+	if len(packageRegistries) == 0 {
+		return r.AbstractRuntime.OnAfterStagingDirCreated(runtimeConfig, stagingDir)
+	}
+
+	gemConfigLines := []string{"---", ":sources:"}
+	bundlerConfigLines := []string{
+		"---",
+		fmt.Sprintf("BUNDLE_MIRROR__%s: %s",
+			bundlerConfigKey(defaultRubyGemsRegistry),
+			strconv.Quote(packageRegistries[0].URL)),
+	}
+
+	for packageRegistryIndex, packageRegistry := range packageRegistries {
+		sourceURL, err := runtime.GetPackageRegistryURL(&packageRegistries[packageRegistryIndex])
+		if err != nil {
+			return errors.Wrap(err, "Failed to get package registry URL")
+		}
+
+		gemConfigLines = append(gemConfigLines, fmt.Sprintf("- %s", strconv.Quote(sourceURL)))
+
+		if packageRegistry.Username != "" || packageRegistry.Password != "" {
+			registryURL, err := url.Parse(packageRegistry.URL)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to parse package registry URL %s", packageRegistry.URL)
+			}
+
+			bundlerConfigLines = append(bundlerConfigLines, fmt.Sprintf("BUNDLE_%s: %s",
+				bundlerConfigKey(registryURL.Host),
+				strconv.Quote(packageRegistry.Username+":"+packageRegistry.Password)))
+		}
+	}
+
+	if err := r.WritePackageRegistryFile(stagingDir,
+		path.Base(gemConfigFileLocation),
+		strings.Join(gemConfigLines, "\n")+"\n"); err != nil {
+		return errors.Wrap(err, "Failed to write gem configuration")
+	}
+
+	return r.WritePackageRegistryFile(stagingDir, bundlerConfigFileName, strings.Join(bundlerConfigLines, "\n")+"\n")
+}
+
+// bundlerConfigKey returns the key bundler configures the given host or URL by
+func bundlerConfigKey(hostOrURL string) string {
+	key := strings.ReplaceAll(hostOrURL, "-", "___")
+	key = strings.ReplaceAll(key, ".", "__")
+	return strings.ToUpper(key)
+}
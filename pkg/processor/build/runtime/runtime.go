@@ -37,9 +37,19 @@ type ProcessorDockerfileInfo struct {
 	ImageArtifactPaths map[string]string
 	OnbuildArtifacts   []Artifact
 	Directives         map[string][]functionconfig.Directive
+	FunctionDirectives map[string][]functionconfig.Directive
 	DockerfileContents string
 	DockerfilePath     string
 	BuildArgs          map[string]string
+	Labels             map[string]string
+	BuildSecrets       []BuildSecret
+}
+
+// BuildSecret is a file in the staging directory that RUN directives read at a path in the image, without it
+// being written to the image - e.g. a package manager configuration holding registry credentials
+type BuildSecret struct {
+	FileName string
+	Path     string
 }
 
 type Artifact struct {
@@ -73,6 +83,9 @@ type Runtime interface {
 
 	// GetRuntimeBuildArgs returns building arguments
 	GetRuntimeBuildArgs(runtimeConfig *runtimeconfig.Config) map[string]string
+
+	// SetArch sets the architecture whose onbuild images the processor is built from
+	SetArch(arch string)
 }
 
 type Factory interface {
@@ -117,6 +130,10 @@ func NewAbstractRuntime(logger logger.Logger,
 	return newRuntime, nil
 }
 
+func (ar *AbstractRuntime) SetArch(arch string) {
+	ar.VersionInfo.Arch = arch
+}
+
 func (ar *AbstractRuntime) OnAfterStagingDirCreated(runtimeConfig *runtimeconfig.Config, stagingDir string) error {
 	return nil
 }
@@ -0,0 +1,161 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/build/runtimeconfig"
+
+	"github.com/nuclio/errors"
+)
+
+const (
+	moduleExtension   = ".wasm"
+	defaultModuleName = "handler"
+	defaultEntrypoint = "handler"
+)
+
+type wasm struct {
+	*runtime.AbstractRuntime
+}
+
+// DetectFunctionHandlers returns a list of all the handlers
+// in that directory given a path holding a function (or functions)
+func (w *wasm) DetectFunctionHandlers(functionPath string) ([]string, error) {
+
+	// a prebuilt module is named after its file: /some/path/func.wasm -> func
+	if common.IsFile(w.FunctionConfig.Spec.Build.Path) {
+		return w.AbstractRuntime.DetectFunctionHandlers(functionPath)
+	}
+
+	// sources are compiled to handler.wasm
+	return []string{fmt.Sprintf("%s:%s", defaultModuleName, defaultEntrypoint)}, nil
+}
+
+// GetName returns the name of the runtime, including version if applicable
+func (w *wasm) GetName() string {
+	return "wasm"
+}
+
+// GetProcessorDockerfileInfo returns information required to build the processor Dockerfile
+func (w *wasm) GetProcessorDockerfileInfo(runtimeConfig *runtimeconfig.Config, onbuildImageRegistry string) (*runtime.ProcessorDockerfileInfo, error) {
+
+	processorDockerfileInfo := runtime.ProcessorDockerfileInfo{
+		BaseImage: "alpine:3.20",
+	}
+
+	prebuilt, err := w.isPrebuiltModule()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve whether the module is prebuilt")
+	}
+
+	// prebuilt modules are copied as is, next to the processor
+	if prebuilt {
+		artifact := runtime.Artifact{
+			Name: "nuclio-processor",
+			Image: fmt.Sprintf("%s/nuclio/processor:%s-%s",
+				onbuildImageRegistry,
+				w.VersionInfo.Label,
+				w.VersionInfo.Arch),
+			Paths: map[string]string{
+				"/home/nuclio/bin/processor": "/usr/local/bin/processor",
+			},
+		}
+		processorDockerfileInfo.OnbuildArtifacts = []runtime.Artifact{artifact}
+
+		processorDockerfileInfo.ImageArtifactPaths = map[string]string{
+			"handler": "/opt/nuclio",
+		}
+
+		return &processorDockerfileInfo, nil
+	}
+
+	moduleName, err := w.getModuleName()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get module name")
+	}
+
+	// otherwise, the onbuild image compiles the sources to a module
+	artifact := runtime.Artifact{
+		Name: "wasm-onbuild",
+		Image: fmt.Sprintf("%s/nuclio/handler-builder-wasm-onbuild:%s-%s",
+			onbuildImageRegistry,
+			w.VersionInfo.Label,
+			w.VersionInfo.Arch),
+		Paths: map[string]string{
+			"/home/nuclio/bin/processor":    "/usr/local/bin/processor",
+			"/home/nuclio/bin/handler.wasm": path.Join("/opt/nuclio", moduleName),
+		},
+	}
+	processorDockerfileInfo.OnbuildArtifacts = []runtime.Artifact{artifact}
+
+	return &processorDockerfileInfo, nil
+}
+
+// isPrebuiltModule returns true if the build path is a module, or a directory holding modules and no Go sources
+func (w *wasm) isPrebuiltModule() (bool, error) {
+	buildPath := w.FunctionConfig.Spec.Build.Path
+
+	if !common.IsDir(buildPath) {
+		return strings.HasSuffix(buildPath, moduleExtension), nil
+	}
+
+	sourcePaths, err := filepath.Glob(filepath.Join(buildPath, "*.go"))
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to look for Go sources")
+	}
+
+	if len(sourcePaths) > 0 {
+		return false, nil
+	}
+
+	modulePaths, err := filepath.Glob(filepath.Join(buildPath, "*"+moduleExtension))
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to look for modules")
+	}
+
+	return len(modulePaths) > 0, nil
+}
+
+// getModuleName returns the file name the processor expects the module in, the same way the processor resolves it
+func (w *wasm) getModuleName() (string, error) {
+	moduleName, entrypoint, err := functionconfig.ParseHandler(w.FunctionConfig.Spec.Handler)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse handler")
+	}
+
+	if moduleName == "" {
+		moduleName = entrypoint
+	}
+
+	if moduleName == "" {
+		moduleName = defaultModuleName
+	}
+
+	if !strings.HasSuffix(moduleName, moduleExtension) {
+		moduleName += moduleExtension
+	}
+
+	return moduleName, nil
+}
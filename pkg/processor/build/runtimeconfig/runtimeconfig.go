@@ -19,6 +19,8 @@ package runtimeconfig
 import (
 	"os"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
 	"github.com/nuclio/errors"
 	"k8s.io/api/core/v1"
 )
@@ -26,6 +28,10 @@ import (
 type Config struct {
 	Common *Common `json:"common,omitempty"`
 	Python *Python `json:"python,omitempty"`
+
+	// the default registries functions install their dependencies from. each runtime uses the registries of
+	// its kind, unless the function has its own registries of that kind and scope
+	PackageRegistries []functionconfig.PackageRegistry `json:"packageRegistries,omitempty"`
 }
 
 type Common struct {
@@ -27,12 +27,21 @@ type ControlMessageEvent struct {
 	resolvedBody *ControlMessage
 }
 
+type controlTriggerInfoProvider struct{}
+
+func (p *controlTriggerInfoProvider) GetClass() string { return "control" }
+func (p *controlTriggerInfoProvider) GetKind() string  { return "control" }
+func (p *controlTriggerInfoProvider) GetName() string  { return "control" }
+
 func NewControlMessageEvent(message *ControlMessage) *ControlMessageEvent {
 	event := &ControlMessageEvent{
 		AbstractEvent: nuclio.AbstractEvent{},
 		resolvedBody:  message,
 	}
 
+	// encoders expect every event to come from a trigger
+	event.SetTriggerInfoProvider(&controlTriggerInfoProvider{})
+
 	return event
 }
 
@@ -41,6 +50,20 @@ func (cme *ControlMessageEvent) GetID() nuclio.ID {
 	return nuclio.ID(cme.resolvedBody.Kind)
 }
 
+// GetBody returns the JSON encoded control message, for encoders that only send event bodies
+func (cme *ControlMessageEvent) GetBody() []byte {
+	if cme.resolvedBody == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(cme.resolvedBody)
+	if err != nil {
+		return nil
+	}
+
+	return body
+}
+
 // GetBodyObject returns the control message body of the event
 func (cme *ControlMessageEvent) GetBodyObject() interface{} {
 	eventBody := cme.GetBody()
@@ -27,12 +27,16 @@ type ControlMessageKind string
 
 const (
 	StreamMessageAckKind ControlMessageKind = "streamMessageAck"
+
+	// sent by wrappers to call other functions, and answered by the processor with the call's result
+	InvokeFunctionKind       ControlMessageKind = "invokeFunction"
+	InvokeFunctionResultKind ControlMessageKind = "invokeFunctionResult"
 )
 
 // TODO: move to nuclio-sdk-go
 type ControlMessage struct {
-	Kind       ControlMessageKind
-	Attributes map[string]interface{}
+	Kind       ControlMessageKind     `json:"kind"`
+	Attributes map[string]interface{} `json:"attributes"`
 }
 
 type ControlMessageAttributesExplicitAck struct {
@@ -41,6 +45,31 @@ type ControlMessageAttributesExplicitAck struct {
 	Offset    int64  `json:"offset"`
 }
 
+type ControlMessageAttributesInvokeFunction struct {
+	ID           string                 `json:"id"`
+	FunctionName string                 `json:"functionName"`
+	Method       string                 `json:"method"`
+	Path         string                 `json:"path"`
+	ContentType  string                 `json:"contentType"`
+	Headers      map[string]interface{} `json:"headers"`
+
+	// base64 encoded
+	Body string `json:"body"`
+
+	// queue the call and respond with its correlation ID, instead of waiting for the called function
+	Async bool `json:"async"`
+
+	CorrelationID string `json:"correlationId"`
+
+	// RFC 3339, inherited from the event the call is made on behalf of
+	Deadline string `json:"deadline"`
+
+	// in seconds
+	Timeout      float64 `json:"timeout"`
+	Retries      int     `json:"retries"`
+	RetryBackoff float64 `json:"retryBackoff"`
+}
+
 type ControlConsumer struct {
 	channels []chan *ControlMessage
 	kind     ControlMessageKind
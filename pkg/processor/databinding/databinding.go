@@ -16,7 +16,11 @@ limitations under the License.
 
 package databinding
 
-import "github.com/nuclio/logger"
+import (
+	"time"
+
+	"github.com/nuclio/logger"
+)
 
 type DataBinding interface {
 
@@ -48,3 +52,41 @@ func (adb *AbstractDataBinding) Stop() error {
 func (adb *AbstractDataBinding) GetContextObject() (interface{}, error) {
 	return nil, nil
 }
+
+// MessageProducer is implemented by context objects of data bindings that send messages (e.g. kafka).
+// It allows runtimes to send messages on behalf of functions that can't access the context object directly
+type MessageProducer interface {
+
+	// Send sends a message and waits for it to be acknowledged, returning the partition and offset it was written to.
+	// If topic is empty, the data binding's default topic is used
+	Send(topic string, key []byte, value []byte, headers map[string]string) (int32, int64, error)
+
+	// SendAsync queues a message for sending without waiting for it to be acknowledged
+	SendAsync(topic string, key []byte, value []byte, headers map[string]string) error
+}
+
+// ObjectInfo describes an object in an object store
+type ObjectInfo struct {
+	Key          string    `json:"key" msgpack:"key"`
+	Size         int64     `json:"size" msgpack:"size"`
+	LastModified time.Time `json:"lastModified" msgpack:"lastModified"`
+	ETag         string    `json:"etag,omitempty" msgpack:"etag,omitempty"`
+}
+
+// ObjectStore is implemented by context objects of data bindings that store objects (e.g. s3). Like MessageProducer,
+// it allows runtimes to access objects on behalf of functions
+type ObjectStore interface {
+
+	// Get returns the contents of an object
+	Get(key string) ([]byte, error)
+
+	// Put creates or replaces an object
+	Put(key string, body []byte, contentType string) error
+
+	// List returns the objects whose keys start with prefix
+	List(prefix string) ([]ObjectInfo, error)
+
+	// GetPresignedURL returns a URL through which the object can be accessed with the given HTTP method
+	// (GET or PUT) without credentials, until it expires. A zero expiration uses the data binding's default
+	GetPresignedURL(key string, method string, expiration time.Duration) (string, error)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"github.com/nuclio/nuclio/pkg/processor/databinding"
+
+	"github.com/Shopify/sarama"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+type kafka struct {
+	databinding.AbstractDataBinding
+	configuration *Configuration
+	producer      *sharedProducer
+}
+
+func newDataBinding(parentLogger logger.Logger, configuration *Configuration) (databinding.DataBinding, error) {
+	newKafka := kafka{
+		AbstractDataBinding: databinding.AbstractDataBinding{
+			Logger: parentLogger,
+		},
+		configuration: configuration,
+	}
+
+	newKafka.Logger.InfoWith("Creating",
+		"brokers", configuration.brokers,
+		"topic", configuration.Topic)
+
+	return &newKafka, nil
+}
+
+func (k *kafka) Start() error {
+	var err error
+
+	k.producer, err = acquireProducer(k.Logger, k.configuration)
+	if err != nil {
+		return errors.Wrap(err, "Failed to acquire producer")
+	}
+
+	return nil
+}
+
+func (k *kafka) Stop() error {
+	if k.producer == nil {
+		return nil
+	}
+
+	producer := k.producer
+	k.producer = nil
+
+	return releaseProducer(producer)
+}
+
+// GetContextObject will return the object that is injected into the context
+func (k *kafka) GetContextObject() (interface{}, error) {
+	return k, nil
+}
+
+// Send sends a message and waits for it to be acknowledged, returning the partition and offset it was written to
+func (k *kafka) Send(topic string, key []byte, value []byte, headers map[string]string) (int32, int64, error) {
+	message, err := k.createProducerMessage(topic, key, value, headers)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "Failed to create message")
+	}
+
+	partition, offset, err := k.producer.send(message)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "Failed to send message to topic %s", message.Topic)
+	}
+
+	return partition, offset, nil
+}
+
+// SendAsync queues a message for sending. Send failures are logged
+func (k *kafka) SendAsync(topic string, key []byte, value []byte, headers map[string]string) error {
+	message, err := k.createProducerMessage(topic, key, value, headers)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create message")
+	}
+
+	k.producer.sendAsync(message)
+
+	return nil
+}
+
+func (k *kafka) createProducerMessage(topic string,
+	key []byte,
+	value []byte,
+	headers map[string]string) (*sarama.ProducerMessage, error) {
+
+	if k.producer == nil {
+		return nil, errors.New("Data binding is not started")
+	}
+
+	if topic == "" {
+		topic = k.configuration.Topic
+	}
+
+	if topic == "" {
+		return nil, errors.New("Topic must be passed either when sending or in attributes.topic")
+	}
+
+	message := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(value),
+	}
+
+	// a nil key lets the partitioner pick a partition
+	if key != nil {
+		message.Key = sarama.ByteEncoder(key)
+	}
+
+	for headerName, headerValue := range headers {
+		message.Headers = append(message.Headers, sarama.RecordHeader{
+			Key:   []byte(headerName),
+			Value: []byte(headerValue),
+		})
+	}
+
+	return message, nil
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"sync"
+
+	"github.com/nuclio/nuclio/pkg/processor/trigger/kafka/scram"
+	"github.com/nuclio/nuclio/pkg/processor/trigger/kafka/tokenprovider/oauth"
+
+	"github.com/Shopify/sarama"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// data bindings are created per worker, but all the workers of a processor share a producer per data binding
+var producerPool = struct {
+	lock      sync.Mutex
+	producers map[string]*sharedProducer
+}{
+	producers: map[string]*sharedProducer{},
+}
+
+type sharedProducer struct {
+	logger        logger.Logger
+	key           string
+	client        sarama.Client
+	syncProducer  sarama.SyncProducer
+	asyncProducer sarama.AsyncProducer
+	references    int
+	drainDone     sync.WaitGroup
+}
+
+// acquireProducer returns the shared producer of the configuration, creating it if this is its first user
+func acquireProducer(parentLogger logger.Logger, configuration *Configuration) (*sharedProducer, error) {
+	producerPool.lock.Lock()
+	defer producerPool.lock.Unlock()
+
+	key := configuration.getProducerKey()
+
+	if producer, found := producerPool.producers[key]; found {
+		producer.references++
+		return producer, nil
+	}
+
+	producer, err := newSharedProducer(parentLogger, key, configuration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create producer")
+	}
+
+	producerPool.producers[key] = producer
+
+	return producer, nil
+}
+
+// releaseProducer closes the shared producer once its last user releases it
+func releaseProducer(producer *sharedProducer) error {
+	producerPool.lock.Lock()
+	defer producerPool.lock.Unlock()
+
+	producer.references--
+	if producer.references > 0 {
+		return nil
+	}
+
+	delete(producerPool.producers, producer.key)
+
+	return producer.close()
+}
+
+func newSharedProducer(parentLogger logger.Logger, key string, configuration *Configuration) (*sharedProducer, error) {
+	producer := &sharedProducer{
+		logger:     parentLogger,
+		key:        key,
+		references: 1,
+	}
+
+	config, err := producer.newKafkaConfig(configuration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create kafka config")
+	}
+
+	producer.logger.DebugWith("Creating producer", "brokers", configuration.brokers)
+
+	producer.client, err = sarama.NewClient(configuration.brokers, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create client")
+	}
+
+	producer.syncProducer, err = sarama.NewSyncProducerFromClient(producer.client)
+	if err != nil {
+		producer.client.Close() // nolint: errcheck
+		return nil, errors.Wrap(err, "Failed to create sync producer")
+	}
+
+	producer.asyncProducer, err = sarama.NewAsyncProducerFromClient(producer.client)
+	if err != nil {
+		producer.syncProducer.Close() // nolint: errcheck
+		producer.client.Close()       // nolint: errcheck
+		return nil, errors.Wrap(err, "Failed to create async producer")
+	}
+
+	// async sends aren't waited on, so results must be drained for the producer not to block
+	producer.drainDone.Add(2)
+	go producer.drainAsyncSuccesses()
+	go producer.drainAsyncErrors()
+
+	return producer, nil
+}
+
+func (sp *sharedProducer) send(message *sarama.ProducerMessage) (int32, int64, error) {
+	return sp.syncProducer.SendMessage(message)
+}
+
+func (sp *sharedProducer) sendAsync(message *sarama.ProducerMessage) {
+	sp.asyncProducer.Input() <- message
+}
+
+func (sp *sharedProducer) close() error {
+	sp.logger.DebugWith("Closing producer", "key", sp.key)
+
+	// closing the async producer flushes the queued messages and closes the result channels
+	asyncProducerErr := sp.asyncProducer.Close()
+	sp.drainDone.Wait()
+
+	syncProducerErr := sp.syncProducer.Close()
+	clientErr := sp.client.Close()
+
+	for _, err := range []error{asyncProducerErr, syncProducerErr, clientErr} {
+		if err != nil {
+			return errors.Wrap(err, "Failed to close producer")
+		}
+	}
+
+	return nil
+}
+
+func (sp *sharedProducer) drainAsyncSuccesses() {
+	defer sp.drainDone.Done()
+
+	for range sp.asyncProducer.Successes() { // nolint: revive
+	}
+}
+
+func (sp *sharedProducer) drainAsyncErrors() {
+	defer sp.drainDone.Done()
+
+	for producerError := range sp.asyncProducer.Errors() {
+		sp.logger.WarnWith("Failed to send message",
+			"topic", producerError.Msg.Topic,
+			"err", producerError.Err.Error())
+	}
+}
+
+func (sp *sharedProducer) newKafkaConfig(configuration *Configuration) (*sarama.Config, error) {
+	var err error
+	config := sarama.NewConfig()
+
+	config.ClientID = configuration.ID
+	config.ChannelBufferSize = configuration.ChannelBufferSize
+	config.Producer.RequiredAcks = configuration.requiredAcks
+	config.Producer.Compression = configuration.compression
+	config.Producer.Timeout = configuration.timeout
+	config.Producer.Retry.Max = configuration.RetryMax
+	config.Producer.Retry.Backoff = configuration.retryBackoff
+
+	// required by the sync producer, which shares the client's configuration
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+
+	if configuration.MaxMessageBytes != 0 {
+		config.Producer.MaxMessageBytes = configuration.MaxMessageBytes
+	}
+
+	// configure TLS if applicable
+	config.Net.TLS.Enable = configuration.CACert != "" || configuration.TLS.Enable
+	if config.Net.TLS.Enable {
+		sp.logger.DebugWith("Enabling TLS",
+			"minimumVersion", configuration.TLS.MinimumVersion,
+			"calen", len(configuration.CACert))
+
+		minimumVersion := uint16(tls.VersionTLS12)
+		if configuration.TLS.MinimumVersion == "1.3" {
+			minimumVersion = tls.VersionTLS13
+		}
+
+		config.Net.TLS.Config = &tls.Config{
+			InsecureSkipVerify: configuration.TLS.InsecureSkipVerify, // nolint: gosec
+			MinVersion:         minimumVersion,
+		}
+
+		if configuration.CACert != "" {
+			caCertPool := x509.NewCertPool()
+			caCertPool.AppendCertsFromPEM([]byte(configuration.CACert))
+			config.Net.TLS.Config.RootCAs = caCertPool
+
+			if configuration.AccessKey != "" && configuration.AccessCertificate != "" {
+				keypair, err := tls.X509KeyPair([]byte(configuration.AccessCertificate), []byte(configuration.AccessKey))
+				if err != nil {
+					return nil, errors.Wrap(err, "Failed to create X.509 key pair")
+				}
+
+				config.Net.TLS.Config.Certificates = []tls.Certificate{keypair}
+			}
+		}
+	}
+
+	// configure SASL if applicable
+	if configuration.SASL.Enable {
+		sp.logger.DebugWith("Configuring SASL authentication",
+			"username", configuration.SASL.User,
+			"mechanism", configuration.SASL.Mechanism)
+
+		config.Net.SASL.Enable = true
+		config.Net.SASL.User = configuration.SASL.User
+		config.Net.SASL.Password = configuration.SASL.Password
+		config.Net.SASL.Mechanism = sarama.SASLMechanism(configuration.SASL.Mechanism)
+
+		if configuration.SASL.Handshake != nil {
+			config.Net.SASL.Handshake = *configuration.SASL.Handshake
+		}
+
+		switch config.Net.SASL.Mechanism {
+		case sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+			mechanism := config.Net.SASL.Mechanism
+			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return scram.NewClient(mechanism) }
+		case sarama.SASLTypeOAuth:
+			config.Net.SASL.TokenProvider = oauth.NewTokenProvider(context.TODO(),
+				configuration.SASL.OAuth.ClientID,
+				configuration.SASL.OAuth.ClientSecret,
+				configuration.SASL.OAuth.TokenURL,
+				configuration.SASL.OAuth.Scopes)
+		}
+	}
+
+	// V0_11_0_0 is the minimum version that supports headers
+	config.Version = sarama.V0_11_0_0
+
+	if configuration.Version != "" {
+		config.Version, err = sarama.ParseKafkaVersion(configuration.Version)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse kafka version - %s", configuration.Version)
+		}
+
+		if !config.Version.IsAtLeast(sarama.V0_11_0_0) {
+			return nil, errors.Errorf("Minimum version of 0.11.0 is required, got - %s", config.Version.String())
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, errors.Wrap(err, "Kafka config is invalid")
+	}
+
+	return config, nil
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/databinding"
+
+	"github.com/Shopify/sarama"
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+type Configuration struct {
+	databinding.Configuration
+
+	Brokers []string
+	Topic   string
+	SASL    struct {
+		Enable    bool
+		Handshake *bool
+		User      string
+		Password  string
+		Mechanism string
+
+		// oauth
+		OAuth struct {
+			ClientID     string
+			ClientSecret string
+			TokenURL     string
+			Scopes       []string
+		}
+	}
+
+	TLS struct {
+		Enable             bool
+		InsecureSkipVerify bool
+		MinimumVersion     string
+	}
+
+	SecretPath        string
+	CACert            string
+	AccessKey         string
+	AccessCertificate string
+	Version           string
+
+	// producer options
+	RequiredAcks      string
+	Compression       string
+	MaxMessageBytes   int
+	Timeout           string
+	RetryMax          int
+	RetryBackoff      string
+	ChannelBufferSize int
+
+	// resolved fields
+	brokers      []string
+	requiredAcks sarama.RequiredAcks
+	compression  sarama.CompressionCodec
+	timeout      time.Duration
+	retryBackoff time.Duration
+}
+
+func NewConfiguration(id string, databindingConfiguration *functionconfig.DataBinding) (*Configuration, error) {
+	var err error
+	newConfiguration := Configuration{}
+
+	// create base
+	newConfiguration.Configuration = *databinding.NewConfiguration(id, databindingConfiguration)
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	// the data binding's secret is the sasl password, unless one was given explicitly
+	if newConfiguration.SASL.Password == "" {
+		newConfiguration.SASL.Password = newConfiguration.Secret
+	}
+
+	if err := newConfiguration.populateValuesFromMountedSecrets(); err != nil {
+		return nil, errors.Wrap(err, "Failed to populate configuration from secrets")
+	}
+
+	newConfiguration.brokers, err = newConfiguration.resolveBrokers(newConfiguration.Brokers)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve brokers")
+	}
+
+	newConfiguration.requiredAcks, err = newConfiguration.resolveRequiredAcks(newConfiguration.RequiredAcks)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve required acks")
+	}
+
+	newConfiguration.compression, err = newConfiguration.resolveCompression(newConfiguration.Compression)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve compression")
+	}
+
+	for _, durationField := range []struct {
+		name         string
+		value        string
+		field        *time.Duration
+		defaultValue time.Duration
+	}{
+		{name: "timeout", value: newConfiguration.Timeout, field: &newConfiguration.timeout, defaultValue: 10 * time.Second},
+		{name: "retry backoff", value: newConfiguration.RetryBackoff, field: &newConfiguration.retryBackoff, defaultValue: 100 * time.Millisecond},
+	} {
+		if durationField.value == "" {
+			*durationField.field = durationField.defaultValue
+			continue
+		}
+
+		if *durationField.field, err = time.ParseDuration(durationField.value); err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse %s", durationField.name)
+		}
+	}
+
+	if newConfiguration.RetryMax == 0 {
+		newConfiguration.RetryMax = 3
+	}
+
+	if newConfiguration.ChannelBufferSize == 0 {
+		newConfiguration.ChannelBufferSize = 256
+	}
+
+	// for certificates, replace spaces with newlines to allow passing in places like annotations
+	for _, cert := range []*string{
+		&newConfiguration.CACert,
+		&newConfiguration.AccessKey,
+		&newConfiguration.AccessCertificate,
+	} {
+		*cert = common.UnflattenCertificate(*cert)
+	}
+
+	return &newConfiguration, nil
+}
+
+// getProducerKey returns a key that identifies producers that can be shared between data bindings
+func (c *Configuration) getProducerKey() string {
+	return fmt.Sprintf("%s/%s", c.ID, strings.Join(c.brokers, ","))
+}
+
+func (c *Configuration) resolveBrokers(brokers []string) ([]string, error) {
+	if len(brokers) > 0 {
+		return brokers, nil
+	}
+
+	if c.URL != "" {
+		return strings.Split(c.URL, ","), nil
+	}
+
+	return nil, errors.New("Brokers must be passed either in url or attributes.brokers")
+}
+
+func (c *Configuration) resolveRequiredAcks(requiredAcks string) (sarama.RequiredAcks, error) {
+	switch strings.ToLower(requiredAcks) {
+	case "", "local":
+		return sarama.WaitForLocal, nil
+	case "all":
+		return sarama.WaitForAll, nil
+	case "none":
+		return sarama.NoResponse, nil
+	default:
+		return 0, errors.Errorf("RequiredAcks must be either 'none', 'local' or 'all', not '%s'", requiredAcks)
+	}
+}
+
+func (c *Configuration) resolveCompression(compression string) (sarama.CompressionCodec, error) {
+	switch strings.ToLower(compression) {
+	case "", "none":
+		return sarama.CompressionNone, nil
+	case "gzip":
+		return sarama.CompressionGZIP, nil
+	case "snappy":
+		return sarama.CompressionSnappy, nil
+	case "lz4":
+		return sarama.CompressionLZ4, nil
+	case "zstd":
+		return sarama.CompressionZSTD, nil
+	default:
+		return 0, errors.Errorf("Compression must be either 'none', 'gzip', 'snappy', 'lz4' or 'zstd', not '%s'", compression)
+	}
+}
+
+// populateValuesFromMountedSecrets will populate sensitive configuration fields from mounted secrets, if the field is a path
+func (c *Configuration) populateValuesFromMountedSecrets() error {
+
+	// for each of the sensitive fields, check if it is a path to a file.
+	// if it is, read the file and populate the field with its contents
+	for _, sensitiveField := range []*string{
+		&c.AccessKey,
+		&c.AccessCertificate,
+		&c.CACert,
+		&c.SASL.Password,
+		&c.SASL.OAuth.ClientSecret,
+	} {
+		filePath := filepath.Join(c.SecretPath, *sensitiveField)
+
+		// we check if the file exists, because if it doesn't, we assume it's a string and not a path
+		if *sensitiveField != "" && common.FileExists(filePath) {
+			contents, err := os.ReadFile(filePath)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to read file %s", filePath)
+			}
+			*sensitiveField = strings.TrimSpace(string(contents))
+		}
+	}
+
+	return nil
+}
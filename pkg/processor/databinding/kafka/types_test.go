@@ -0,0 +1,113 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigurationTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ConfigurationTestSuite) TestDefaults() {
+	configuration, err := NewConfiguration("output", &functionconfig.DataBinding{
+		Kind: "kafka",
+		URL:  "broker-1:9092,broker-2:9092",
+	})
+	suite.Require().NoError(err)
+
+	suite.Require().Equal([]string{"broker-1:9092", "broker-2:9092"}, configuration.brokers)
+	suite.Require().Equal(sarama.WaitForLocal, configuration.requiredAcks)
+	suite.Require().Equal(sarama.CompressionNone, configuration.compression)
+	suite.Require().Equal(3, configuration.RetryMax)
+	suite.Require().Equal(256, configuration.ChannelBufferSize)
+}
+
+func (suite *ConfigurationTestSuite) TestAttributes() {
+	configuration, err := NewConfiguration("output", &functionconfig.DataBinding{
+		Kind: "kafka",
+		URL:  "ignored:9092",
+		Attributes: map[string]interface{}{
+			"brokers":      []string{"broker:9092"},
+			"topic":        "results",
+			"requiredAcks": "all",
+			"compression":  "zstd",
+			"timeout":      "3s",
+		},
+	})
+	suite.Require().NoError(err)
+
+	suite.Require().Equal([]string{"broker:9092"}, configuration.brokers)
+	suite.Require().Equal("results", configuration.Topic)
+	suite.Require().Equal(sarama.WaitForAll, configuration.requiredAcks)
+	suite.Require().Equal(sarama.CompressionZSTD, configuration.compression)
+	suite.Require().Equal("3s", configuration.timeout.String())
+}
+
+func (suite *ConfigurationTestSuite) TestSecret() {
+
+	// the data binding's secret is used as the sasl password
+	configuration, err := NewConfiguration("output", &functionconfig.DataBinding{
+		Kind:   "kafka",
+		URL:    "broker:9092",
+		Secret: "my-password",
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("my-password", configuration.SASL.Password)
+
+	// unless it's a path to a mounted secret, which is read
+	secretDir := suite.T().TempDir()
+	err = os.WriteFile(filepath.Join(secretDir, "password"), []byte("mounted-password\n"), 0600)
+	suite.Require().NoError(err)
+
+	configuration, err = NewConfiguration("output", &functionconfig.DataBinding{
+		Kind:   "kafka",
+		URL:    "broker:9092",
+		Secret: "password",
+		Attributes: map[string]interface{}{
+			"secretPath": secretDir,
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("mounted-password", configuration.SASL.Password)
+}
+
+func (suite *ConfigurationTestSuite) TestInvalid() {
+	for _, databindingConfiguration := range []*functionconfig.DataBinding{
+		{Kind: "kafka"},
+		{Kind: "kafka", URL: "broker:9092", Attributes: map[string]interface{}{"requiredAcks": "some"}},
+		{Kind: "kafka", URL: "broker:9092", Attributes: map[string]interface{}{"compression": "rar"}},
+		{Kind: "kafka", URL: "broker:9092", Attributes: map[string]interface{}{"timeout": "soon"}},
+	} {
+		_, err := NewConfiguration("output", databindingConfiguration)
+		suite.Require().Error(err)
+	}
+}
+
+func TestConfigurationTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigurationTestSuite))
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/nuclio/errors"
+)
+
+// data bindings are created per worker, but all the workers of a processor share a client per data binding
+var clientPool = struct {
+	lock    sync.Mutex
+	clients map[string]*sharedClient
+}{
+	clients: map[string]*sharedClient{},
+}
+
+type sharedClient struct {
+	*s3.S3
+	key        string
+	references int
+}
+
+// acquireClient returns the shared client of the configuration, creating it if this is its first user
+func acquireClient(configuration *Configuration) (*sharedClient, error) {
+	clientPool.lock.Lock()
+	defer clientPool.lock.Unlock()
+
+	key := configuration.getClientKey()
+
+	if client, found := clientPool.clients[key]; found {
+		client.references++
+		return client, nil
+	}
+
+	awsConfig := &aws.Config{
+		Region:           aws.String(configuration.Region),
+		DisableSSL:       aws.Bool(configuration.disableSSL),
+		S3ForcePathStyle: aws.Bool(configuration.forcePathStyle),
+	}
+
+	if configuration.endpoint != "" {
+		awsConfig.Endpoint = aws.String(configuration.endpoint)
+	}
+
+	// without static credentials, the default chain is used (e.g. environment or instance role)
+	if configuration.AccessKeyID != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(configuration.AccessKeyID,
+			configuration.SecretAccessKey,
+			configuration.SessionToken)
+	}
+
+	awsSession, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create AWS session")
+	}
+
+	client := &sharedClient{
+		S3:         s3.New(awsSession),
+		key:        key,
+		references: 1,
+	}
+
+	clientPool.clients[key] = client
+
+	return client, nil
+}
+
+// releaseClient forgets the shared client once its last user releases it. Clients hold no connections of their
+// own, idle connections are closed by the HTTP transport
+func releaseClient(client *sharedClient) {
+	clientPool.lock.Lock()
+	defer clientPool.lock.Unlock()
+
+	client.references--
+	if client.references <= 0 {
+		delete(clientPool.clients, client.key)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/databinding"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+type s3DataBinding struct {
+	databinding.AbstractDataBinding
+	configuration *Configuration
+	client        *sharedClient
+}
+
+func newDataBinding(parentLogger logger.Logger, configuration *Configuration) (databinding.DataBinding, error) {
+	newS3DataBinding := s3DataBinding{
+		AbstractDataBinding: databinding.AbstractDataBinding{
+			Logger: parentLogger,
+		},
+		configuration: configuration,
+	}
+
+	newS3DataBinding.Logger.InfoWith("Creating",
+		"endpoint", configuration.endpoint,
+		"region", configuration.Region,
+		"bucket", configuration.Bucket)
+
+	return &newS3DataBinding, nil
+}
+
+func (s *s3DataBinding) Start() error {
+	var err error
+
+	s.client, err = acquireClient(s.configuration)
+	if err != nil {
+		return errors.Wrap(err, "Failed to acquire client")
+	}
+
+	return nil
+}
+
+func (s *s3DataBinding) Stop() error {
+	if s.client == nil {
+		return nil
+	}
+
+	releaseClient(s.client)
+	s.client = nil
+
+	return nil
+}
+
+// GetContextObject will return the object that is injected into the context
+func (s *s3DataBinding) GetContextObject() (interface{}, error) {
+	return s, nil
+}
+
+// Get returns the contents of an object
+func (s *s3DataBinding) Get(key string) ([]byte, error) {
+	if s.client == nil {
+		return nil, errors.New("Data binding is not started")
+	}
+
+	getObjectOutput, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.configuration.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get object %s", key)
+	}
+
+	defer getObjectOutput.Body.Close() // nolint: errcheck
+
+	body, err := io.ReadAll(getObjectOutput.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read object %s", key)
+	}
+
+	return body, nil
+}
+
+// Put creates or replaces an object
+func (s *s3DataBinding) Put(key string, body []byte, contentType string) error {
+	if s.client == nil {
+		return errors.New("Data binding is not started")
+	}
+
+	putObjectInput := &s3.PutObjectInput{
+		Bucket: aws.String(s.configuration.Bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	}
+
+	if contentType != "" {
+		putObjectInput.ContentType = aws.String(contentType)
+	}
+
+	if _, err := s.client.PutObject(putObjectInput); err != nil {
+		return errors.Wrapf(err, "Failed to put object %s", key)
+	}
+
+	return nil
+}
+
+// List returns the objects whose keys start with prefix
+func (s *s3DataBinding) List(prefix string) ([]databinding.ObjectInfo, error) {
+	if s.client == nil {
+		return nil, errors.New("Data binding is not started")
+	}
+
+	objects := []databinding.ObjectInfo{}
+
+	if err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.configuration.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(listObjectsOutput *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range listObjectsOutput.Contents {
+			objects = append(objects, databinding.ObjectInfo{
+				Key:          aws.StringValue(object.Key),
+				Size:         aws.Int64Value(object.Size),
+				LastModified: aws.TimeValue(object.LastModified),
+				ETag:         strings.Trim(aws.StringValue(object.ETag), `"`),
+			})
+		}
+
+		return true
+	}); err != nil {
+		return nil, errors.Wrapf(err, "Failed to list objects with prefix %s", prefix)
+	}
+
+	return objects, nil
+}
+
+// GetPresignedURL returns a URL through which the object can be accessed with the given HTTP method without credentials
+func (s *s3DataBinding) GetPresignedURL(key string, method string, expiration time.Duration) (string, error) {
+	var presignRequest *request.Request
+
+	if s.client == nil {
+		return "", errors.New("Data binding is not started")
+	}
+
+	if expiration == 0 {
+		expiration = s.configuration.presignExpiration
+	}
+
+	switch strings.ToUpper(method) {
+	case "", http.MethodGet:
+		presignRequest, _ = s.client.GetObjectRequest(&s3.GetObjectInput{
+			Bucket: aws.String(s.configuration.Bucket),
+			Key:    aws.String(key),
+		})
+	case http.MethodPut:
+		presignRequest, _ = s.client.PutObjectRequest(&s3.PutObjectInput{
+			Bucket: aws.String(s.configuration.Bucket),
+			Key:    aws.String(key),
+		})
+	default:
+		return "", errors.Errorf("Presigned URL method must be either GET or PUT, not %s", method)
+	}
+
+	presignedURL, err := presignRequest.Presign(expiration)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to presign URL for object %s", key)
+	}
+
+	return presignedURL, nil
+}
@@ -0,0 +1,275 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/databinding"
+
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// objectStoreServer is a minimal path-style S3-compatible server, storing objects in memory
+type objectStoreServer struct {
+	lock    sync.Mutex
+	bucket  string
+	objects map[string][]byte
+}
+
+func (oss *objectStoreServer) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	oss.lock.Lock()
+	defer oss.lock.Unlock()
+
+	bucketAndKey := strings.SplitN(strings.TrimPrefix(request.URL.Path, "/"), "/", 2)
+	if bucketAndKey[0] != oss.bucket {
+		responseWriter.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case request.Method == http.MethodPut:
+		body, _ := io.ReadAll(request.Body)
+		oss.objects[bucketAndKey[1]] = body
+	case request.Method == http.MethodGet && len(bucketAndKey) == 2 && bucketAndKey[1] != "":
+		body, found := oss.objects[bucketAndKey[1]]
+		if !found {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+		responseWriter.Write(body) // nolint: errcheck
+	case request.Method == http.MethodGet:
+		type content struct {
+			Key          string
+			Size         int
+			LastModified string
+			ETag         string
+		}
+		listBucketResult := struct {
+			XMLName  xml.Name `xml:"ListBucketResult"`
+			Name     string
+			Contents []content
+		}{Name: oss.bucket}
+
+		var keys []string
+		for key := range oss.objects {
+			if strings.HasPrefix(key, request.URL.Query().Get("prefix")) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			listBucketResult.Contents = append(listBucketResult.Contents, content{
+				Key:          key,
+				Size:         len(oss.objects[key]),
+				LastModified: time.Now().UTC().Format(time.RFC3339),
+				ETag:         fmt.Sprintf(`"%x"`, len(key)),
+			})
+		}
+		xml.NewEncoder(responseWriter).Encode(listBucketResult) // nolint: errcheck
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+type DataBindingTestSuite struct {
+	suite.Suite
+	server      *httptest.Server
+	dataBinding databinding.DataBinding
+	objectStore databinding.ObjectStore
+}
+
+func (suite *DataBindingTestSuite) SetupTest() {
+	suite.server = httptest.NewServer(&objectStoreServer{
+		bucket:  "my-bucket",
+		objects: map[string][]byte{},
+	})
+
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.dataBinding, err = databinding.RegistrySingleton.NewDataBinding(loggerInstance,
+		"s3",
+		"store",
+		&functionconfig.DataBinding{
+			Kind:   "s3",
+			URL:    suite.server.URL,
+			Secret: "my-secret-key",
+			Attributes: map[string]interface{}{
+				"bucket":      "my-bucket",
+				"accessKeyID": "my-access-key",
+			},
+		})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.dataBinding.Start())
+
+	contextObject, err := suite.dataBinding.GetContextObject()
+	suite.Require().NoError(err)
+	suite.objectStore = contextObject.(databinding.ObjectStore)
+}
+
+func (suite *DataBindingTestSuite) TearDownTest() {
+	suite.Require().NoError(suite.dataBinding.Stop())
+	suite.server.Close()
+}
+
+func (suite *DataBindingTestSuite) TestPutGetList() {
+	suite.Require().NoError(suite.objectStore.Put("reports/1.json", []byte(`{"a": 1}`), "application/json"))
+	suite.Require().NoError(suite.objectStore.Put("reports/2.json", []byte(`{"a": 2}`), ""))
+	suite.Require().NoError(suite.objectStore.Put("other", []byte("x"), ""))
+
+	body, err := suite.objectStore.Get("reports/1.json")
+	suite.Require().NoError(err)
+	suite.Require().Equal(`{"a": 1}`, string(body))
+
+	_, err = suite.objectStore.Get("missing")
+	suite.Require().Error(err)
+
+	objects, err := suite.objectStore.List("reports/")
+	suite.Require().NoError(err)
+	suite.Require().Len(objects, 2)
+	suite.Require().Equal("reports/1.json", objects[0].Key)
+	suite.Require().Equal(int64(8), objects[0].Size)
+	suite.Require().Equal("reports/2.json", objects[1].Key)
+}
+
+func (suite *DataBindingTestSuite) TestGetPresignedURL() {
+	presignedURL, err := suite.objectStore.GetPresignedURL("reports/1.json", "", 0)
+	suite.Require().NoError(err)
+
+	parsedURL, err := url.Parse(presignedURL)
+	suite.Require().NoError(err)
+	suite.Require().Equal("/my-bucket/reports/1.json", parsedURL.Path)
+	suite.Require().Equal("900", parsedURL.Query().Get("X-Amz-Expires"))
+	suite.Require().NotEmpty(parsedURL.Query().Get("X-Amz-Signature"))
+
+	presignedURL, err = suite.objectStore.GetPresignedURL("reports/1.json", http.MethodPut, time.Minute)
+	suite.Require().NoError(err)
+	suite.Require().Contains(presignedURL, "X-Amz-Expires=60")
+
+	_, err = suite.objectStore.GetPresignedURL("reports/1.json", http.MethodDelete, 0)
+	suite.Require().Error(err)
+}
+
+func (suite *DataBindingTestSuite) TestSharedClient() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	configuration, err := NewConfiguration("store", &functionconfig.DataBinding{
+		URL:        suite.server.URL,
+		Attributes: map[string]interface{}{"bucket": "my-bucket"},
+	})
+	suite.Require().NoError(err)
+
+	otherDataBinding, err := newDataBinding(loggerInstance, configuration)
+	suite.Require().NoError(err)
+	suite.Require().NoError(otherDataBinding.Start())
+
+	// both data bindings of the processor use the same client
+	suite.Require().Same(suite.dataBinding.(*s3DataBinding).client, otherDataBinding.(*s3DataBinding).client)
+	suite.Require().Equal(2, otherDataBinding.(*s3DataBinding).client.references)
+	suite.Require().NoError(otherDataBinding.Stop())
+}
+
+type ConfigurationTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ConfigurationTestSuite) TestEndpoint() {
+	for _, testCase := range []struct {
+		name                   string
+		url                    string
+		attributes             map[string]interface{}
+		expectedEndpoint       string
+		expectedRegion         string
+		expectedDisableSSL     bool
+		expectedForcePathStyle bool
+	}{
+		{
+			name:                   "minio",
+			url:                    "http://minio:9000",
+			expectedEndpoint:       "http://minio:9000",
+			expectedRegion:         defaultRegion,
+			expectedDisableSSL:     true,
+			expectedForcePathStyle: true,
+		},
+		{
+			name:                   "noScheme",
+			attributes:             map[string]interface{}{"endpoint": "storage.example.com", "region": "eu-west-1"},
+			expectedEndpoint:       "https://storage.example.com",
+			expectedRegion:         "eu-west-1",
+			expectedForcePathStyle: true,
+		},
+		{
+			name:           "aws",
+			attributes:     map[string]interface{}{"region": "us-west-2"},
+			expectedRegion: "us-west-2",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			attributes := map[string]interface{}{"bucket": "my-bucket"}
+			for key, value := range testCase.attributes {
+				attributes[key] = value
+			}
+
+			configuration, err := NewConfiguration("store", &functionconfig.DataBinding{
+				URL:        testCase.url,
+				Attributes: attributes,
+			})
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedEndpoint, configuration.endpoint)
+			suite.Require().Equal(testCase.expectedRegion, configuration.Region)
+			suite.Require().Equal(testCase.expectedDisableSSL, configuration.disableSSL)
+			suite.Require().Equal(testCase.expectedForcePathStyle, configuration.forcePathStyle)
+		})
+	}
+}
+
+func (suite *ConfigurationTestSuite) TestInvalid() {
+	for _, databindingConfiguration := range []*functionconfig.DataBinding{
+		{URL: "http://minio:9000"},
+		{Attributes: map[string]interface{}{"bucket": "my-bucket"}},
+		{URL: "ftp://minio", Attributes: map[string]interface{}{"bucket": "my-bucket"}},
+		{URL: "http://minio:9000", Attributes: map[string]interface{}{"bucket": "my-bucket", "presignExpiration": "later"}},
+	} {
+		_, err := NewConfiguration("store", databindingConfiguration)
+		suite.Require().Error(err)
+	}
+}
+
+func TestDataBindingTestSuite(t *testing.T) {
+	suite.Run(t, new(DataBindingTestSuite))
+}
+
+func TestConfigurationTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigurationTestSuite))
+}
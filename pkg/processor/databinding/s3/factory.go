@@ -0,0 +1,47 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/databinding"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+type factory struct{}
+
+func (f *factory) Create(parentLogger logger.Logger,
+	id string,
+	databindingConfiguration *functionconfig.DataBinding) (databinding.DataBinding, error) {
+
+	// create logger parent
+	s3Logger := parentLogger.GetChild("s3")
+
+	configuration, err := NewConfiguration(id, databindingConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create configuration")
+	}
+
+	return newDataBinding(s3Logger, configuration)
+}
+
+// register factory
+func init() {
+	databinding.RegistrySingleton.Register("s3", &factory{})
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/databinding"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+// used when an endpoint is given without a region, which S3-compatible stores (e.g. MinIO) don't care about
+const defaultRegion = "us-east-1"
+
+type Configuration struct {
+	databinding.Configuration
+
+	Endpoint          string
+	Bucket            string
+	Region            string
+	AccessKeyID       string
+	SecretAccessKey   string
+	SessionToken      string
+	ForcePathStyle    *bool
+	SecretPath        string
+	PresignExpiration string
+
+	// resolved fields
+	endpoint          string
+	disableSSL        bool
+	forcePathStyle    bool
+	presignExpiration time.Duration
+}
+
+func NewConfiguration(id string, databindingConfiguration *functionconfig.DataBinding) (*Configuration, error) {
+	var err error
+	newConfiguration := Configuration{}
+
+	// create base
+	newConfiguration.Configuration = *databinding.NewConfiguration(id, databindingConfiguration)
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	// the data binding's secret is the secret access key, unless one was given explicitly
+	if newConfiguration.SecretAccessKey == "" {
+		newConfiguration.SecretAccessKey = newConfiguration.Secret
+	}
+
+	if err := newConfiguration.populateValuesFromMountedSecrets(); err != nil {
+		return nil, errors.Wrap(err, "Failed to populate configuration from secrets")
+	}
+
+	if newConfiguration.Bucket == "" {
+		return nil, errors.New("Bucket must be set in attributes.bucket")
+	}
+
+	if err := newConfiguration.resolveEndpoint(); err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve endpoint")
+	}
+
+	if newConfiguration.Region == "" {
+		if newConfiguration.endpoint == "" {
+			return nil, errors.New("Region must be set when not using a custom endpoint")
+		}
+
+		newConfiguration.Region = defaultRegion
+	}
+
+	// S3-compatible stores are usually addressed by path, as their buckets don't have DNS names
+	newConfiguration.forcePathStyle = newConfiguration.endpoint != ""
+	if newConfiguration.ForcePathStyle != nil {
+		newConfiguration.forcePathStyle = *newConfiguration.ForcePathStyle
+	}
+
+	newConfiguration.presignExpiration = 15 * time.Minute
+	if newConfiguration.PresignExpiration != "" {
+		newConfiguration.presignExpiration, err = time.ParseDuration(newConfiguration.PresignExpiration)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to parse presign expiration")
+		}
+	}
+
+	return &newConfiguration, nil
+}
+
+// getClientKey returns a key that identifies clients that can be shared between data bindings
+func (c *Configuration) getClientKey() string {
+	return fmt.Sprintf("%s/%s/%s/%s", c.ID, c.endpoint, c.Region, c.Bucket)
+}
+
+// resolveEndpoint resolves the endpoint from attributes.endpoint or the url. An endpoint without a scheme uses https
+func (c *Configuration) resolveEndpoint() error {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = c.URL
+	}
+
+	// no endpoint means AWS
+	if endpoint == "" {
+		return nil
+	}
+
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	parsedEndpoint, err := url.Parse(endpoint)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to parse endpoint %s", endpoint)
+	}
+
+	switch parsedEndpoint.Scheme {
+	case "http":
+		c.disableSSL = true
+	case "https":
+	default:
+		return errors.Errorf("Endpoint scheme must be either http or https, not %s", parsedEndpoint.Scheme)
+	}
+
+	c.endpoint = endpoint
+
+	return nil
+}
+
+// populateValuesFromMountedSecrets will populate sensitive configuration fields from mounted secrets, if the field is a path
+func (c *Configuration) populateValuesFromMountedSecrets() error {
+	for _, sensitiveField := range []*string{
+		&c.AccessKeyID,
+		&c.SecretAccessKey,
+		&c.SessionToken,
+	} {
+		filePath := filepath.Join(c.SecretPath, *sensitiveField)
+
+		// we check if the file exists, because if it doesn't, we assume it's a string and not a path
+		if *sensitiveField != "" && common.FileExists(filePath) {
+			contents, err := os.ReadFile(filePath)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to read file %s", filePath)
+			}
+			*sensitiveField = strings.TrimSpace(string(contents))
+		}
+	}
+
+	return nil
+}
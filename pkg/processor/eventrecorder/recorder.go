@@ -0,0 +1,316 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventrecorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Recorder writes events received by a trigger to a file, while a recording is active. Recordings are
+// kept in a single directory, since they may be started by anyone with access to the webadmin
+type Recorder struct {
+	logger        logger.Logger
+	triggerName   string
+	triggerKind   string
+	recordingsDir string
+
+	// loaded on every event without locking, so that an inactive recorder costs nothing
+	activeRecording atomic.Pointer[recording]
+
+	// guards writing to the recording file and the last recording
+	lock          sync.Mutex
+	lastRecording *recording
+}
+
+type recording struct {
+	configuration *functionconfig.EventRecording
+	pathFilter    *regexp.Regexp
+	scrubber      *scrubber
+
+	// guarded by the recorder lock
+	file           *os.File
+	recordedEvents int
+}
+
+// NewRecorder creates an inactive recorder for a trigger, writing recordings to the given directory
+func NewRecorder(parentLogger logger.Logger, triggerName string, triggerKind string, recordingsDir string) *Recorder {
+	return &Recorder{
+		logger:        parentLogger.GetChild("recorder"),
+		triggerName:   triggerName,
+		triggerKind:   triggerKind,
+		recordingsDir: recordingsDir,
+	}
+}
+
+// Start starts recording, stopping the current recording if there is one
+func (r *Recorder) Start(configuration *functionconfig.EventRecording) error {
+	if configuration.SampleRate < 0 || configuration.SampleRate > 1 {
+		return errors.Errorf("Event recording sample rate must be between 0 and 1, got %f", configuration.SampleRate)
+	}
+
+	if configuration.MaxEvents < 0 {
+		return errors.Errorf("Event recording max events must not be negative, got %d", configuration.MaxEvents)
+	}
+
+	// copy, so that defaults aren't written back to the caller's configuration
+	recordingConfiguration := *configuration
+
+	// default to recording all events
+	if recordingConfiguration.SampleRate == 0 {
+		recordingConfiguration.SampleRate = 1
+	}
+
+	if recordingConfiguration.Path == "" {
+		recordingConfiguration.Path = fmt.Sprintf("recording-%s.jsonl", r.triggerName)
+	}
+
+	recordingPath, err := r.resolveRecordingPath(recordingConfiguration.Path)
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve event recording path")
+	}
+
+	recordingConfiguration.Path = recordingPath
+
+	newRecording := &recording{
+		configuration: &recordingConfiguration,
+		scrubber:      newScrubber(r.logger, recordingConfiguration.SensitiveHeaders),
+	}
+
+	if recordingConfiguration.PathFilter != "" {
+		newRecording.pathFilter, err = regexp.Compile(recordingConfiguration.PathFilter)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to compile event recording path filter %s", recordingConfiguration.PathFilter)
+		}
+	}
+
+	if err := os.MkdirAll(r.recordingsDir, 0755); err != nil {
+		return errors.Wrapf(err, "Failed to create event recordings dir %s", r.recordingsDir)
+	}
+
+	// don't write through a symlink planted in the recordings dir
+	if fileInfo, err := os.Lstat(recordingConfiguration.Path); err == nil && !fileInfo.Mode().IsRegular() {
+		return errors.Errorf("Event recording path %s exists and is not a regular file", recordingConfiguration.Path)
+	}
+
+	recordingFile, err := os.OpenFile(recordingConfiguration.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to open event recording file %s", recordingConfiguration.Path)
+	}
+
+	newRecording.file = recordingFile
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.stop()
+
+	r.lastRecording = newRecording
+	r.activeRecording.Store(newRecording)
+
+	r.logger.InfoWith("Started recording events",
+		"path", recordingConfiguration.Path,
+		"sampleRate", recordingConfiguration.SampleRate,
+		"pathFilter", recordingConfiguration.PathFilter,
+		"maxEvents", recordingConfiguration.MaxEvents)
+
+	return nil
+}
+
+// Stop stops the current recording, if there is one
+func (r *Recorder) Stop() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.stop()
+}
+
+// GetStatus returns the state of the current recording, or of the last one if none is active
+func (r *Recorder) GetStatus() *Status {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	status := &Status{
+		Recording: r.activeRecording.Load() != nil,
+	}
+
+	if r.lastRecording != nil {
+		status.Path = r.lastRecording.configuration.Path
+		status.RecordedEvents = r.lastRecording.recordedEvents
+	}
+
+	return status
+}
+
+// OpenRecording opens the current recording's file, or the last one's if none is active, for reading
+func (r *Recorder) OpenRecording() (io.ReadCloser, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.lastRecording == nil {
+		return nil, errors.New("No events were recorded")
+	}
+
+	recordingFile, err := os.Open(r.lastRecording.configuration.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open event recording file %s", r.lastRecording.configuration.Path)
+	}
+
+	return recordingFile, nil
+}
+
+// Record writes an event to the recording, if there's an active recording and the event is sampled and
+// passes the filter
+func (r *Recorder) Record(event nuclio.Event) {
+	activeRecording := r.activeRecording.Load()
+	if activeRecording == nil {
+		return
+	}
+
+	sampleRate := activeRecording.configuration.SampleRate
+	if sampleRate < 1 && rand.Float64() >= sampleRate { // nolint: gosec
+		return
+	}
+
+	if activeRecording.pathFilter != nil && !activeRecording.pathFilter.MatchString(event.GetPath()) {
+		return
+	}
+
+	recordedEvent, err := activeRecording.scrubber.scrubRecordedEvent(r.createRecordedEvent(event))
+	if err != nil {
+		r.logger.WarnWith("Failed to scrub recorded event", "err", err.Error())
+		return
+	}
+
+	encodedRecordedEvent, err := json.Marshal(recordedEvent)
+	if err != nil {
+		r.logger.WarnWith("Failed to encode recorded event", "err", err.Error())
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// the recording may have been stopped while we were preparing the event
+	if activeRecording.file == nil {
+		return
+	}
+
+	if _, err := activeRecording.file.Write(append(encodedRecordedEvent, '\n')); err != nil {
+		r.logger.WarnWith("Failed to write recorded event", "err", err.Error())
+		return
+	}
+
+	activeRecording.recordedEvents++
+
+	if maxEvents := activeRecording.configuration.MaxEvents; maxEvents > 0 && activeRecording.recordedEvents >= maxEvents {
+		r.logger.InfoWith("Recorded max events, stopping", "maxEvents", maxEvents)
+		r.stop()
+	}
+}
+
+func (r *Recorder) createRecordedEvent(event nuclio.Event) *RecordedEvent {
+	recordedEvent := &RecordedEvent{
+		Time:        time.Now().UTC(),
+		TriggerName: r.triggerName,
+		TriggerKind: r.triggerKind,
+		ID:          string(event.GetID()),
+		Method:      event.GetMethod(),
+		Path:        event.GetPath(),
+		ContentType: event.GetContentType(),
+		Fields:      event.GetFields(),
+		Topic:       event.GetTopic(),
+		ShardID:     event.GetShardID(),
+		Offset:      event.GetOffset(),
+		Body:        event.GetBody(),
+	}
+
+	if eventHeaders := event.GetHeaders(); len(eventHeaders) > 0 {
+		recordedEvent.Headers = map[string]string{}
+
+		for headerName, headerValue := range eventHeaders {
+			switch typedHeaderValue := headerValue.(type) {
+			case string:
+				recordedEvent.Headers[headerName] = typedHeaderValue
+			case []byte:
+				recordedEvent.Headers[headerName] = string(typedHeaderValue)
+			default:
+				recordedEvent.Headers[headerName] = fmt.Sprint(typedHeaderValue)
+			}
+		}
+	}
+
+	return recordedEvent
+}
+
+// resolveRecordingPath resolves a recording path relative to the recordings dir, refusing paths that
+// lead out of it
+func (r *Recorder) resolveRecordingPath(recordingPath string) (string, error) {
+	recordingsDir, err := filepath.Abs(r.recordingsDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to resolve event recordings dir %s", r.recordingsDir)
+	}
+
+	if !filepath.IsAbs(recordingPath) {
+		recordingPath = filepath.Join(recordingsDir, recordingPath)
+	}
+
+	recordingPath = filepath.Clean(recordingPath)
+
+	relativePath, err := filepath.Rel(recordingsDir, recordingPath)
+	if err != nil ||
+		relativePath == "." ||
+		relativePath == ".." ||
+		strings.ContainsRune(relativePath, filepath.Separator) {
+		return "", errors.Errorf("Event recording path %s must be a file in %s", recordingPath, recordingsDir)
+	}
+
+	return recordingPath, nil
+}
+
+// stop closes the active recording's file. Must be called with the lock held
+func (r *Recorder) stop() {
+	activeRecording := r.activeRecording.Swap(nil)
+	if activeRecording == nil {
+		return
+	}
+
+	if err := activeRecording.file.Close(); err != nil {
+		r.logger.WarnWith("Failed to close event recording file", "err", err.Error())
+	}
+
+	activeRecording.file = nil
+
+	r.logger.InfoWith("Stopped recording events",
+		"path", activeRecording.configuration.Path,
+		"recordedEvents", activeRecording.recordedEvents)
+}
@@ -0,0 +1,211 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventrecorder
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type RecorderTestSuite struct {
+	suite.Suite
+	logger        logger.Logger
+	recorder      *Recorder
+	recordingsDir string
+	recordingPath string
+}
+
+func (suite *RecorderTestSuite) SetupTest() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+	suite.recordingsDir = filepath.Join(suite.T().TempDir(), "recordings")
+	suite.recorder = NewRecorder(suite.logger, "my-trigger", "http", suite.recordingsDir)
+	suite.recordingPath = filepath.Join(suite.recordingsDir, "recording.jsonl")
+}
+
+func (suite *RecorderTestSuite) TearDownTest() {
+	suite.recorder.Stop()
+}
+
+func (suite *RecorderTestSuite) TestRecordScrubsSensitiveHeaders() {
+	err := suite.recorder.Start(&functionconfig.EventRecording{
+		Path:             suite.recordingPath,
+		SensitiveHeaders: []string{"X-Api-Key"},
+	})
+	suite.Require().NoError(err)
+
+	suite.recorder.Record(&nuclio.MemoryEvent{
+		Method: "POST",
+		Path:   "/orders",
+		Body:   []byte(`{"id": 1}`),
+		Headers: map[string]interface{}{
+			"Authorization": "Bearer secret",
+			"x-api-key":     "my-key",
+			"X-Request-Id":  "abc",
+		},
+	})
+
+	recordedEvents := suite.readRecording()
+	suite.Require().Len(recordedEvents, 1)
+
+	recordedEvent := recordedEvents[0]
+	suite.Require().Equal("my-trigger", recordedEvent.TriggerName)
+	suite.Require().Equal("http", recordedEvent.TriggerKind)
+	suite.Require().Equal("POST", recordedEvent.Method)
+	suite.Require().Equal("/orders", recordedEvent.Path)
+	suite.Require().Equal(`{"id": 1}`, string(recordedEvent.Body))
+	suite.Require().Equal("abc", recordedEvent.Headers["X-Request-Id"])
+	suite.Require().Contains(recordedEvent.Headers["Authorization"], common.ReferencePrefix)
+	suite.Require().NotContains(recordedEvent.Headers["Authorization"], "secret")
+	suite.Require().Contains(recordedEvent.Headers["x-api-key"], common.ReferencePrefix)
+}
+
+func (suite *RecorderTestSuite) TestRecordPathFilterAndMaxEvents() {
+	err := suite.recorder.Start(&functionconfig.EventRecording{
+		Path:       suite.recordingPath,
+		PathFilter: "^/orders",
+		MaxEvents:  2,
+	})
+	suite.Require().NoError(err)
+
+	for _, path := range []string{"/orders/1", "/health", "/orders/2", "/orders/3"} {
+		suite.recorder.Record(&nuclio.MemoryEvent{Path: path})
+	}
+
+	recordedEvents := suite.readRecording()
+	suite.Require().Len(recordedEvents, 2)
+	suite.Require().Equal("/orders/1", recordedEvents[0].Path)
+	suite.Require().Equal("/orders/2", recordedEvents[1].Path)
+
+	status := suite.recorder.GetStatus()
+	suite.Require().False(status.Recording)
+	suite.Require().Equal(2, status.RecordedEvents)
+	suite.Require().Equal(suite.recordingPath, status.Path)
+}
+
+func (suite *RecorderTestSuite) TestRecordWhenStopped() {
+	suite.recorder.Record(&nuclio.MemoryEvent{Path: "/before"})
+
+	err := suite.recorder.Start(&functionconfig.EventRecording{
+		Path: suite.recordingPath,
+	})
+	suite.Require().NoError(err)
+	suite.Require().True(suite.recorder.GetStatus().Recording)
+
+	suite.recorder.Record(&nuclio.MemoryEvent{Path: "/during"})
+	suite.recorder.Stop()
+	suite.recorder.Record(&nuclio.MemoryEvent{Path: "/after"})
+
+	recordedEvents := suite.readRecording()
+	suite.Require().Len(recordedEvents, 1)
+	suite.Require().Equal("/during", recordedEvents[0].Path)
+}
+
+func (suite *RecorderTestSuite) TestStartInvalidConfiguration() {
+	for _, configuration := range []*functionconfig.EventRecording{
+		{Path: suite.recordingPath, SampleRate: 1.5},
+		{Path: suite.recordingPath, MaxEvents: -1},
+		{Path: suite.recordingPath, PathFilter: "("},
+	} {
+		suite.Require().Error(suite.recorder.Start(configuration))
+	}
+
+	suite.Require().False(suite.recorder.GetStatus().Recording)
+}
+
+func (suite *RecorderTestSuite) TestStartRestrictsPathToRecordingsDir() {
+
+	// relative paths are relative to the recordings dir
+	err := suite.recorder.Start(&functionconfig.EventRecording{Path: "relative.jsonl"})
+	suite.Require().NoError(err)
+	suite.Require().Equal(filepath.Join(suite.recordingsDir, "relative.jsonl"), suite.recorder.GetStatus().Path)
+
+	// as is the default path
+	err = suite.recorder.Start(&functionconfig.EventRecording{})
+	suite.Require().NoError(err)
+	suite.Require().Equal(filepath.Join(suite.recordingsDir, "recording-my-trigger.jsonl"), suite.recorder.GetStatus().Path)
+	suite.recorder.Stop()
+
+	outsidePath := filepath.Join(suite.T().TempDir(), "outside.jsonl")
+
+	for _, recordingPath := range []string{
+		outsidePath,
+		"../outside.jsonl",
+		"nested/recording.jsonl",
+		suite.recordingsDir,
+	} {
+		err := suite.recorder.Start(&functionconfig.EventRecording{Path: recordingPath})
+		suite.Require().Error(err, recordingPath)
+	}
+
+	// nor through a symlink in the recordings dir
+	suite.Require().NoError(os.Symlink(outsidePath, filepath.Join(suite.recordingsDir, "symlink.jsonl")))
+	suite.Require().Error(suite.recorder.Start(&functionconfig.EventRecording{Path: "symlink.jsonl"}))
+
+	suite.Require().False(suite.recorder.GetStatus().Recording)
+	suite.Require().NoFileExists(outsidePath)
+}
+
+func (suite *RecorderTestSuite) TestOpenRecording() {
+	_, err := suite.recorder.OpenRecording()
+	suite.Require().Error(err)
+
+	err = suite.recorder.Start(&functionconfig.EventRecording{Path: suite.recordingPath})
+	suite.Require().NoError(err)
+
+	suite.recorder.Record(&nuclio.MemoryEvent{Path: "/orders"})
+	suite.recorder.Stop()
+
+	recordingReader, err := suite.recorder.OpenRecording()
+	suite.Require().NoError(err)
+
+	defer recordingReader.Close() // nolint: errcheck
+
+	recordingContents, err := io.ReadAll(recordingReader)
+	suite.Require().NoError(err)
+
+	expectedContents, err := os.ReadFile(suite.recordingPath)
+	suite.Require().NoError(err)
+	suite.Require().Equal(expectedContents, recordingContents)
+}
+
+func (suite *RecorderTestSuite) readRecording() []*RecordedEvent {
+	recordingFile, err := os.Open(suite.recordingPath)
+	suite.Require().NoError(err)
+
+	defer recordingFile.Close() // nolint: errcheck
+
+	recordedEvents, err := ReadRecording(recordingFile)
+	suite.Require().NoError(err)
+
+	return recordedEvents
+}
+
+func TestRecorderTestSuite(t *testing.T) {
+	suite.Run(t, new(RecorderTestSuite))
+}
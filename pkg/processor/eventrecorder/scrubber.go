@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventrecorder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/common/headers"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// headers that are always scrubbed from recorded events
+var defaultSensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	headers.V3IOSessionKey,
+}
+
+// scrubber replaces the values of sensitive headers with references. Recorded events are never restored,
+// so the scrubbed values are simply dropped
+type scrubber struct {
+	*common.AbstractScrubber
+}
+
+func newScrubber(parentLogger logger.Logger, sensitiveHeaders []string) *scrubber {
+	var sensitiveFields []*regexp.Regexp
+	for _, sensitiveHeader := range append(defaultSensitiveHeaders, sensitiveHeaders...) {
+		sensitiveFields = append(sensitiveFields,
+			regexp.MustCompile(fmt.Sprintf("(?i)^/headers/%s$", regexp.QuoteMeta(sensitiveHeader))))
+	}
+
+	// there's no kube client, as nothing is ever stored in secrets
+	abstractScrubber := common.NewAbstractScrubber(parentLogger,
+		sensitiveFields,
+		nil,
+		common.ReferencePrefix,
+		"",
+		"",
+		nil)
+	newScrubber := &scrubber{
+		AbstractScrubber: abstractScrubber,
+	}
+	abstractScrubber.Scrubber = newScrubber
+
+	return newScrubber
+}
+
+func (s *scrubber) scrubRecordedEvent(recordedEvent *RecordedEvent) (*RecordedEvent, error) {
+	if len(recordedEvent.Headers) == 0 {
+		return recordedEvent, nil
+	}
+
+	scrubbedRecordedEvent, _, _, err := s.Scrub(context.Background(), recordedEvent, "", "")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to scrub recorded event")
+	}
+
+	return scrubbedRecordedEvent.(*RecordedEvent), nil
+}
+
+// ValidateReference accepts header values that already look like references, there's nothing to validate them against
+func (s *scrubber) ValidateReference(objectToScrub interface{},
+	existingSecretMap map[string]string,
+	fieldPath,
+	secretKey,
+	stringValue string) error {
+	return nil
+}
+
+// ConvertMapToConfig converts a scrubbed map back to a recorded event
+func (s *scrubber) ConvertMapToConfig(mapConfig interface{}) (interface{}, error) {
+	encodedRecordedEvent, err := json.Marshal(mapConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal scrubbed recorded event")
+	}
+
+	recordedEvent := &RecordedEvent{}
+	if err := json.Unmarshal(encodedRecordedEvent, recordedEvent); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal scrubbed recorded event")
+	}
+
+	return recordedEvent, nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventrecorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/nuclio/errors"
+)
+
+// maximum size of a single recorded event line
+const maxRecordedEventSize = 64 * 1024 * 1024
+
+// RecordedEvent is a received event, as written to a recording (one per line)
+type RecordedEvent struct {
+	Time        time.Time              `json:"time"`
+	TriggerName string                 `json:"triggerName"`
+	TriggerKind string                 `json:"triggerKind"`
+	ID          string                 `json:"id,omitempty"`
+	Method      string                 `json:"method,omitempty"`
+	Path        string                 `json:"path,omitempty"`
+	ContentType string                 `json:"contentType,omitempty"`
+	Headers     map[string]string      `json:"headers,omitempty"`
+	Fields      map[string]interface{} `json:"fields,omitempty"`
+	Topic       string                 `json:"topic,omitempty"`
+	ShardID     int                    `json:"shardID,omitempty"`
+	Offset      int                    `json:"offset,omitempty"`
+	Body        []byte                 `json:"body,omitempty"`
+}
+
+// Status describes the state of a trigger's recording
+type Status struct {
+	Recording      bool   `json:"recording"`
+	Path           string `json:"path,omitempty"`
+	RecordedEvents int    `json:"recordedEvents"`
+}
+
+// ReadRecording reads all events from a recording
+func ReadRecording(reader io.Reader) ([]*RecordedEvent, error) {
+	var recordedEvents []*RecordedEvent
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordedEventSize)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		recordedEvent := &RecordedEvent{}
+		if err := json.Unmarshal(line, recordedEvent); err != nil {
+			return nil, errors.Wrapf(err, "Failed to decode recorded event at line %d", lineNumber)
+		}
+
+		recordedEvents = append(recordedEvents, recordedEvent)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Failed to read recording")
+	}
+
+	return recordedEvents, nil
+}
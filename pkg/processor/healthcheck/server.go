@@ -52,8 +52,8 @@ func (s *ProcessorServer) Start() error {
 
 	// register the processor's status check as its readiness check
 	s.Handler.AddReadinessCheck("processor_readiness", func() error {
-		if s.StatusProvider.GetStatus() != status.Ready {
-			return errors.New("Processor not ready yet")
+		if processorStatus := s.StatusProvider.GetStatus(); processorStatus != status.Ready {
+			return errors.Errorf("Processor not ready yet (status: %s)", processorStatus)
 		}
 
 		return nil
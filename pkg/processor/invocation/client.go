@@ -0,0 +1,468 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package invocation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// the clients of the contexts passed to handlers, kept apart from the context's data bindings so that
+// they aren't mistaken for user data bindings (e.g. passed on to wrapper processes)
+var contextClients sync.Map
+
+// Register makes the client available to handlers through FromContext
+func Register(context *nuclio.Context, client *Client) {
+	contextClients.Store(context, client)
+}
+
+// Unregister removes the client of the context, once the context is no longer used
+func Unregister(context *nuclio.Context) {
+	contextClients.Delete(context)
+}
+
+// FromContext returns the client of the context passed to a handler, or nil if the context has none
+func FromContext(context *nuclio.Context) *Client {
+	client, found := contextClients.Load(context)
+	if !found {
+		return nil
+	}
+
+	return client.(*Client)
+}
+
+// Client calls other functions through their HTTP triggers, either waiting for their response or
+// queueing the call in the background
+type Client struct {
+	logger        logger.Logger
+	configuration *Configuration
+	httpClient    *http.Client
+	asyncCalls    chan *asyncCall
+
+	// cancelled when the client is closed, abandoning the async calls being made
+	ctx          context.Context
+	cancel       context.CancelFunc
+	asyncWorkers sync.WaitGroup
+}
+
+type asyncCall struct {
+	functionName  string
+	event         nuclio.Event
+	correlationID string
+	deadline      time.Time
+	retries       int
+	retryBackoff  time.Duration
+}
+
+// NewClient creates a client and starts the workers that make its async calls
+func NewClient(parentLogger logger.Logger, configuration *Configuration) (*Client, error) {
+	if err := configuration.resolve(); err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve configuration")
+	}
+
+	newClient := &Client{
+		logger:        parentLogger.GetChild("invocation"),
+		configuration: configuration,
+
+		// a call's deadline bounds it, and redirects are returned to the caller like any other response
+		httpClient: &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		asyncCalls: make(chan *asyncCall, configuration.AsyncQueueSize),
+	}
+
+	newClient.ctx, newClient.cancel = context.WithCancel(context.Background())
+
+	for workerIndex := 0; workerIndex < configuration.AsyncWorkers; workerIndex++ {
+		newClient.asyncWorkers.Add(1)
+		go newClient.makeAsyncCalls()
+	}
+
+	return newClient, nil
+}
+
+// Invoke calls a function with an event and returns its response. Responses of any status are returned,
+// after failed calls (connection errors, 429, 502, 503 and 504) are retried as the options allow
+func (c *Client) Invoke(ctx context.Context,
+	functionName string,
+	event nuclio.Event,
+	options *Options) (nuclio.Response, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	deadlineCtx, cancel := context.WithDeadline(ctx, c.resolveDeadline(options))
+	defer cancel()
+
+	return c.invoke(deadlineCtx,
+		functionName,
+		event,
+		c.resolveCorrelationID(options),
+		c.resolveRetries(options, c.configuration.Retries),
+		c.resolveRetryBackoff(options))
+}
+
+// InvokeAsync queues a call to a function, and returns its correlation ID without waiting for it to be
+// made. The call's response is only logged
+func (c *Client) InvokeAsync(functionName string, event nuclio.Event, options *Options) (string, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	// the caller may reuse its event (and the parent) once this returns, so whatever is needed is resolved now
+	call := &asyncCall{
+		functionName:  functionName,
+		event:         copyEvent(event),
+		correlationID: c.resolveCorrelationID(options),
+		deadline:      c.resolveDeadline(options),
+		retries:       c.resolveRetries(options, c.configuration.AsyncRetries),
+		retryBackoff:  c.resolveRetryBackoff(options),
+	}
+
+	if c.ctx.Err() != nil {
+		return "", errors.New("Client is closed")
+	}
+
+	select {
+	case c.asyncCalls <- call:
+		return call.correlationID, nil
+	default:
+		return "", errors.Errorf("Async call queue is full (%d calls)", c.configuration.AsyncQueueSize)
+	}
+}
+
+// Close stops making async calls and waits for the workers that make them to exit. Queued calls are dropped,
+// and calls being made are abandoned
+func (c *Client) Close() {
+	c.cancel()
+	c.asyncWorkers.Wait()
+
+	if droppedCalls := len(c.asyncCalls); droppedCalls > 0 {
+		c.logger.WarnWith("Dropped queued async calls", "droppedCalls", droppedCalls)
+	}
+}
+
+// DeadlineFromEvent returns the deadline the caller of a function set for the event, if any
+func DeadlineFromEvent(event nuclio.Event) (time.Time, bool) {
+	if event == nil {
+		return time.Time{}, false
+	}
+
+	deadline, err := time.Parse(time.RFC3339Nano, getHeaderString(event, HeaderDeadline))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return deadline, true
+}
+
+// CorrelationIDFromEvent returns the correlation ID the caller of a function set for the event, if any
+func CorrelationIDFromEvent(event nuclio.Event) string {
+	if event == nil {
+		return ""
+	}
+
+	return getHeaderString(event, HeaderCorrelationID)
+}
+
+func (c *Client) makeAsyncCalls() {
+	defer c.asyncWorkers.Done()
+
+	for {
+		select {
+		case call := <-c.asyncCalls:
+			c.makeAsyncCall(call)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) makeAsyncCall(call *asyncCall) {
+	ctx, cancel := context.WithDeadline(c.ctx, call.deadline)
+	defer cancel()
+
+	response, err := c.invoke(ctx,
+		call.functionName,
+		call.event,
+		call.correlationID,
+		call.retries,
+		call.retryBackoff)
+
+	switch {
+	case err != nil:
+		c.logger.WarnWith("Async call failed",
+			"functionName", call.functionName,
+			"correlationID", call.correlationID,
+			"err", errors.RootCause(err).Error())
+	case response.StatusCode >= http.StatusBadRequest:
+		c.logger.WarnWith("Async call responded with an error",
+			"functionName", call.functionName,
+			"correlationID", call.correlationID,
+			"statusCode", response.StatusCode)
+	default:
+		c.logger.DebugWith("Async call succeeded",
+			"functionName", call.functionName,
+			"correlationID", call.correlationID,
+			"statusCode", response.StatusCode)
+	}
+}
+
+func (c *Client) invoke(ctx context.Context,
+	functionName string,
+	event nuclio.Event,
+	correlationID string,
+	retries int,
+	retryBackoff time.Duration) (nuclio.Response, error) {
+	var response nuclio.Response
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		response, err = c.call(ctx, functionName, event, correlationID)
+		if !shouldRetry(response, err) || attempt >= retries {
+			break
+		}
+
+		// don't wait for a retry there's no time left for
+		if deadline, _ := ctx.Deadline(); time.Until(deadline) <= retryBackoff {
+			break
+		}
+
+		c.logger.DebugWith("Retrying call",
+			"functionName", functionName,
+			"correlationID", correlationID,
+			"attempt", attempt+1,
+			"backoff", retryBackoff)
+
+		select {
+		case <-time.After(retryBackoff):
+		case <-ctx.Done():
+			return nuclio.Response{}, errors.Wrapf(ctx.Err(), "Call to function %s was abandoned", functionName)
+		}
+
+		retryBackoff = min(retryBackoff*2, c.configuration.MaxRetryBackoff)
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return nuclio.Response{}, nuclio.NewErrRequestTimeout(
+				fmt.Sprintf("Call to function %s did not complete in time", functionName))
+		}
+
+		return nuclio.Response{}, errors.Wrapf(err, "Failed to call function %s", functionName)
+	}
+
+	return response, nil
+}
+
+func (c *Client) call(ctx context.Context,
+	functionName string,
+	event nuclio.Event,
+	correlationID string) (nuclio.Response, error) {
+	request, err := http.NewRequestWithContext(ctx,
+		event.GetMethod(),
+		c.resolveFunctionURL(functionName)+event.GetPath(),
+		bytes.NewReader(event.GetBody()))
+	if err != nil {
+		return nuclio.Response{}, errors.Wrap(err, "Failed to create request")
+	}
+
+	for headerKey, headerValue := range event.GetHeaders() {
+		switch typedHeaderValue := headerValue.(type) {
+		case string:
+			request.Header.Set(headerKey, typedHeaderValue)
+		case []byte:
+			request.Header.Set(headerKey, string(typedHeaderValue))
+		case int:
+			request.Header.Set(headerKey, strconv.Itoa(typedHeaderValue))
+		case bool:
+			request.Header.Set(headerKey, strconv.FormatBool(typedHeaderValue))
+		default:
+			c.logger.WarnWith("Header value is of an unsupported type. Ignoring it",
+				"headerKey", headerKey,
+				"headerValue", headerValue)
+		}
+	}
+
+	if contentType := event.GetContentType(); contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	request.Header.Set(HeaderCorrelationID, correlationID)
+
+	if deadline, hasDeadline := ctx.Deadline(); hasDeadline {
+		request.Header.Set(HeaderDeadline, deadline.UTC().Format(time.RFC3339Nano))
+	}
+
+	httpResponse, err := c.httpClient.Do(request)
+	if err != nil {
+		return nuclio.Response{}, err
+	}
+
+	defer httpResponse.Body.Close() // nolint: errcheck
+
+	body, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nuclio.Response{}, errors.Wrap(err, "Failed to read response")
+	}
+
+	response := nuclio.Response{
+		StatusCode:  httpResponse.StatusCode,
+		ContentType: httpResponse.Header.Get("Content-Type"),
+		Headers:     make(map[string]interface{}, len(httpResponse.Header)),
+		Body:        body,
+	}
+
+	for headerKey := range httpResponse.Header {
+		response.Headers[headerKey] = httpResponse.Header.Get(headerKey)
+	}
+
+	return response, nil
+}
+
+func (c *Client) resolveFunctionURL(functionName string) string {
+	if c.configuration.ResolveFunctionURL != nil {
+		return c.configuration.ResolveFunctionURL(functionName)
+	}
+
+	// the same addresses the platform passed to handlers calls functions on
+	if c.configuration.PlatformKind == "local" {
+		return fmt.Sprintf("http://nuclio-%s-%s:%d", c.configuration.Namespace, functionName, functionPort)
+	}
+
+	return fmt.Sprintf("http://nuclio-%s:%d", functionName, functionPort)
+}
+
+func (c *Client) resolveCorrelationID(options *Options) string {
+	if options.CorrelationID != "" {
+		return options.CorrelationID
+	}
+
+	if correlationID := CorrelationIDFromEvent(options.Parent); correlationID != "" {
+		return correlationID
+	}
+
+	return uuid.New().String()
+}
+
+func (c *Client) resolveDeadline(options *Options) time.Time {
+	var deadlines []time.Time
+
+	if options.Timeout > 0 {
+		deadlines = append(deadlines, time.Now().Add(options.Timeout))
+	}
+
+	if !options.Deadline.IsZero() {
+		deadlines = append(deadlines, options.Deadline)
+	}
+
+	if parentDeadline, hasDeadline := DeadlineFromEvent(options.Parent); hasDeadline {
+		deadlines = append(deadlines, parentDeadline)
+	}
+
+	if len(deadlines) == 0 {
+		return time.Now().Add(c.configuration.Timeout)
+	}
+
+	deadline := deadlines[0]
+	for _, otherDeadline := range deadlines[1:] {
+		if otherDeadline.Before(deadline) {
+			deadline = otherDeadline
+		}
+	}
+
+	return deadline
+}
+
+func (c *Client) resolveRetries(options *Options, defaultRetries int) int {
+	switch {
+	case options.Retries < 0:
+		return 0
+	case options.Retries == 0:
+		return defaultRetries
+	default:
+		return options.Retries
+	}
+}
+
+func (c *Client) resolveRetryBackoff(options *Options) time.Duration {
+	if options.RetryBackoff > 0 {
+		return options.RetryBackoff
+	}
+
+	return c.configuration.RetryBackoff
+}
+
+func shouldRetry(response nuclio.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch response.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// getHeaderString returns a header of any event. memory events only hold headers as values of any type
+func getHeaderString(event nuclio.Event, key string) string {
+	switch typedHeaderValue := event.GetHeader(key).(type) {
+	case string:
+		return typedHeaderValue
+	case []byte:
+		return string(typedHeaderValue)
+	default:
+		return event.GetHeaderString(key)
+	}
+}
+
+func copyEvent(event nuclio.Event) nuclio.Event {
+	headers := make(map[string]interface{}, len(event.GetHeaders()))
+	for headerKey, headerValue := range event.GetHeaders() {
+		if bytesHeaderValue, isBytes := headerValue.([]byte); isBytes {
+			headerValue = string(bytesHeaderValue)
+		}
+
+		headers[headerKey] = headerValue
+	}
+
+	return &nuclio.MemoryEvent{
+		Method:      event.GetMethod(),
+		Path:        event.GetPath(),
+		ContentType: event.GetContentType(),
+		Headers:     headers,
+		Body:        bytes.Clone(event.GetBody()),
+	}
+}
@@ -0,0 +1,324 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package invocation
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *ClientTestSuite) SetupSuite() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *ClientTestSuite) TestInvoke() {
+	parentDeadline := time.Now().Add(10 * time.Second).UTC()
+
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		suite.Require().NoError(err)
+
+		suite.Require().Equal(http.MethodPut, request.Method)
+		suite.Require().Equal("/my-function/some/path", request.URL.Path)
+		suite.Require().Equal("application/json", request.Header.Get("Content-Type"))
+		suite.Require().Equal("hv", request.Header.Get("X-Custom"))
+
+		// the correlation ID and deadline are inherited from the parent
+		suite.Require().Equal("parent-correlation-id", request.Header.Get(HeaderCorrelationID))
+		suite.Require().Equal(parentDeadline.Format(time.RFC3339Nano), request.Header.Get(HeaderDeadline))
+
+		responseWriter.Header().Set("Content-Type", "text/plain")
+		responseWriter.Header().Set("X-Response", "rv")
+		responseWriter.WriteHeader(http.StatusCreated)
+		responseWriter.Write(append([]byte("got "), body...)) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	client := suite.createClient(server, &Configuration{})
+
+	parent := &nuclio.MemoryEvent{
+		Headers: map[string]interface{}{
+			HeaderCorrelationID: "parent-correlation-id",
+			HeaderDeadline:      parentDeadline.Format(time.RFC3339Nano),
+		},
+	}
+
+	response, err := client.Invoke(context.Background(), "my-function", &nuclio.MemoryEvent{
+		Method:      http.MethodPut,
+		Path:        "/some/path",
+		ContentType: "application/json",
+		Headers:     map[string]interface{}{"X-Custom": []byte("hv")},
+		Body:        []byte(`{"a": 1}`),
+	}, &Options{Parent: parent, Timeout: time.Minute})
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(http.StatusCreated, response.StatusCode)
+	suite.Require().Equal("text/plain", response.ContentType)
+	suite.Require().Equal("rv", response.Headers["X-Response"])
+	suite.Require().Equal(`got {"a": 1}`, string(response.Body))
+}
+
+func (suite *ClientTestSuite) TestRetries() {
+	for _, testCase := range []struct {
+		name               string
+		statusCodes        []int
+		options            *Options
+		expectedStatusCode int
+		expectedCalls      int32
+	}{
+		{
+			name:               "recovers",
+			statusCodes:        []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			options:            &Options{Retries: 2},
+			expectedStatusCode: http.StatusOK,
+			expectedCalls:      3,
+		},
+		{
+			name:               "exhausted",
+			statusCodes:        []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			options:            &Options{Retries: 1},
+			expectedStatusCode: http.StatusBadGateway,
+			expectedCalls:      2,
+		},
+		{
+			name:               "clientError",
+			statusCodes:        []int{http.StatusBadRequest, http.StatusOK},
+			options:            &Options{Retries: 2},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCalls:      1,
+		},
+		{
+			name:               "disabled",
+			statusCodes:        []int{http.StatusServiceUnavailable, http.StatusOK},
+			options:            &Options{Retries: -1},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedCalls:      1,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			var calls int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+				call := atomic.AddInt32(&calls, 1)
+				responseWriter.WriteHeader(testCase.statusCodes[call-1])
+			}))
+			defer server.Close()
+
+			client := suite.createClient(server, &Configuration{
+				Retries:      5,
+				RetryBackoff: time.Millisecond,
+			})
+
+			response, err := client.Invoke(context.Background(),
+				"my-function",
+				&nuclio.MemoryEvent{},
+				testCase.options)
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedStatusCode, response.StatusCode)
+			suite.Require().Equal(testCase.expectedCalls, atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func (suite *ClientTestSuite) TestDeadline() {
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		select {
+		case <-time.After(10 * time.Second):
+		case <-request.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := suite.createClient(server, &Configuration{Retries: 10})
+
+	startTime := time.Now()
+
+	// the parent's deadline is earlier than the call's timeout
+	_, err := client.Invoke(context.Background(), "my-function", &nuclio.MemoryEvent{}, &Options{
+		Timeout: time.Minute,
+		Parent: &nuclio.MemoryEvent{
+			Headers: map[string]interface{}{
+				HeaderDeadline: time.Now().Add(200 * time.Millisecond).Format(time.RFC3339Nano),
+			},
+		},
+	})
+	suite.Require().Error(err)
+	suite.Require().Equal(http.StatusRequestTimeout, err.(*nuclio.ErrorWithStatusCode).StatusCode())
+	suite.Require().Less(time.Since(startTime), 5*time.Second)
+}
+
+func (suite *ClientTestSuite) TestInvokeAsync() {
+	var calls int32
+	requests := make(chan *http.Request, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+
+		// fail the first call, so that it is retried
+		if atomic.AddInt32(&calls, 1) == 1 {
+			responseWriter.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		requests <- request
+	}))
+	defer server.Close()
+
+	client := suite.createClient(server, &Configuration{RetryBackoff: time.Millisecond})
+
+	event := &nuclio.MemoryEvent{
+		Method:  http.MethodPost,
+		Headers: map[string]interface{}{"X-Custom": "hv"},
+		Body:    []byte("body"),
+	}
+
+	correlationID, err := client.InvokeAsync("my-function", event, nil)
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(correlationID)
+
+	// the event may be reused once the call is queued
+	event.Headers["X-Custom"] = "changed"
+
+	select {
+	case request := <-requests:
+		suite.Require().Equal(correlationID, request.Header.Get(HeaderCorrelationID))
+		suite.Require().Equal("hv", request.Header.Get("X-Custom"))
+	case <-time.After(5 * time.Second):
+		suite.Fail("Async call wasn't made")
+	}
+}
+
+func (suite *ClientTestSuite) TestAsyncQueueFull() {
+	client, err := NewClient(suite.logger, &Configuration{AsyncQueueSize: 1, AsyncWorkers: -1})
+	suite.Require().Error(err)
+	suite.Require().Nil(client)
+
+	// a client whose workers are all busy, so that nothing is taken off the queue
+	client = &Client{
+		logger:        suite.logger,
+		configuration: &Configuration{AsyncQueueSize: 1},
+		asyncCalls:    make(chan *asyncCall, 1),
+		ctx:           context.Background(),
+	}
+
+	_, err = client.InvokeAsync("my-function", &nuclio.MemoryEvent{}, nil)
+	suite.Require().NoError(err)
+
+	_, err = client.InvokeAsync("my-function", &nuclio.MemoryEvent{}, nil)
+	suite.Require().Error(err)
+}
+
+func (suite *ClientTestSuite) TestClose() {
+	requestReceived := make(chan struct{}, 1)
+
+	// a function that never responds, so that the async call is only abandoned by closing the client
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		requestReceived <- struct{}{}
+		<-request.Context().Done()
+	}))
+	defer server.Close()
+
+	client := suite.createClient(server, &Configuration{AsyncWorkers: 1})
+
+	_, err := client.InvokeAsync("my-function", &nuclio.MemoryEvent{}, &Options{Timeout: time.Hour})
+	suite.Require().NoError(err)
+
+	select {
+	case <-requestReceived:
+	case <-time.After(5 * time.Second):
+		suite.Fail("Async call wasn't made")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		client.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		suite.Fail("Close didn't abandon the async call")
+	}
+
+	_, err = client.InvokeAsync("my-function", &nuclio.MemoryEvent{}, nil)
+	suite.Require().Error(err)
+
+	// closing again does nothing
+	client.Close()
+}
+
+func (suite *ClientTestSuite) TestFromContext() {
+	client := suite.createClient(httptest.NewUnstartedServer(nil), &Configuration{})
+	context := &nuclio.Context{}
+
+	suite.Require().Nil(FromContext(context))
+
+	Register(context, client)
+	suite.Require().Same(client, FromContext(context))
+	suite.Require().Nil(FromContext(&nuclio.Context{}))
+
+	// the client isn't one of the context's data bindings
+	suite.Require().Empty(context.DataBinding)
+
+	Unregister(context)
+	suite.Require().Nil(FromContext(context))
+}
+
+func (suite *ClientTestSuite) TestResolveFunctionURL() {
+	client, err := NewClient(suite.logger, &Configuration{PlatformKind: "local", Namespace: "nuclio"})
+	suite.Require().NoError(err)
+	suite.Require().Equal("http://nuclio-nuclio-my-function:8080", client.resolveFunctionURL("my-function"))
+
+	client, err = NewClient(suite.logger, &Configuration{PlatformKind: "kube", Namespace: "nuclio"})
+	suite.Require().NoError(err)
+	suite.Require().Equal("http://nuclio-my-function:8080", client.resolveFunctionURL("my-function"))
+}
+
+func (suite *ClientTestSuite) createClient(server *httptest.Server, configuration *Configuration) *Client {
+	configuration.ResolveFunctionURL = func(functionName string) string {
+		return server.URL + "/" + functionName
+	}
+
+	client, err := NewClient(suite.logger, configuration)
+	suite.Require().NoError(err)
+
+	return client
+}
+
+func TestClientTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientTestSuite))
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package invocation
+
+import (
+	"time"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+const (
+
+	// HeaderCorrelationID identifies all the calls made on behalf of the same originating event
+	HeaderCorrelationID = "X-Nuclio-Correlation-Id"
+
+	// HeaderDeadline holds the time (RFC 3339) by which the caller stops waiting for a response
+	HeaderDeadline = "X-Nuclio-Deadline"
+
+	DefaultTimeout         = time.Minute
+	DefaultRetryBackoff    = 100 * time.Millisecond
+	DefaultMaxRetryBackoff = 5 * time.Second
+	DefaultAsyncRetries    = 3
+	DefaultAsyncQueueSize  = 1024
+	DefaultAsyncWorkers    = 4
+
+	// the port the HTTP trigger of the called functions listens on
+	functionPort = 8080
+)
+
+type Configuration struct {
+
+	// the platform kind and namespace, from which the address of called functions is resolved
+	PlatformKind string
+	Namespace    string
+
+	// how long a call may take, when neither the call nor the event it's made on behalf of set a deadline
+	Timeout time.Duration
+
+	// how many times a failed call is retried, unless the call sets otherwise. async calls are retried
+	// AsyncRetries times
+	Retries      int
+	AsyncRetries int
+
+	// how long to wait before the first retry. the wait doubles with every retry, up to MaxRetryBackoff
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+
+	// how many async calls may be queued, and how many are made at a time
+	AsyncQueueSize int
+	AsyncWorkers   int
+
+	// returns the base URL of a function. if nil, the URL of the function's service in the platform is used
+	ResolveFunctionURL func(functionName string) string
+}
+
+func (c *Configuration) resolve() error {
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+
+	if c.RetryBackoff == 0 {
+		c.RetryBackoff = DefaultRetryBackoff
+	}
+
+	if c.MaxRetryBackoff == 0 {
+		c.MaxRetryBackoff = DefaultMaxRetryBackoff
+	}
+
+	if c.AsyncRetries == 0 {
+		c.AsyncRetries = DefaultAsyncRetries
+	}
+
+	if c.AsyncQueueSize == 0 {
+		c.AsyncQueueSize = DefaultAsyncQueueSize
+	}
+
+	if c.AsyncWorkers == 0 {
+		c.AsyncWorkers = DefaultAsyncWorkers
+	}
+
+	if c.Timeout < 0 || c.RetryBackoff < 0 || c.MaxRetryBackoff < 0 {
+		return errors.New("Timeout and retry backoff must be positive")
+	}
+
+	if c.Retries < 0 || c.AsyncRetries < 0 || c.AsyncQueueSize < 0 || c.AsyncWorkers < 0 {
+		return errors.New("Retries, async queue size and async workers must not be negative")
+	}
+
+	return nil
+}
+
+// Options control a single call. The zero value uses the client's defaults
+type Options struct {
+
+	// the event being handled, which the call is made on behalf of. the call inherits its correlation
+	// ID and deadline
+	Parent nuclio.Event
+
+	// identifies the call. if empty, it is inherited from the parent or generated
+	CorrelationID string
+
+	// how long the call may take, including retries. the call's deadline is the earliest of now + Timeout,
+	// Deadline and the parent's deadline
+	Timeout  time.Duration
+	Deadline time.Time
+
+	// how many times a failed call is retried. zero uses the client's default and a negative value
+	// disables retries
+	Retries int
+
+	// how long to wait before the first retry. zero uses the client's default
+	RetryBackoff time.Duration
+}
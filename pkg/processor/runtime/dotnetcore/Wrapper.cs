@@ -14,7 +14,9 @@
 
 using System;
 using System.Collections.Generic;
+using System.Runtime.InteropServices;
 using System.Text;
+using System.Threading;
 using Nuclio.Sdk;
 
 namespace processor
@@ -25,9 +27,19 @@ namespace processor
         private delegate object MethodDelegate(Context context, Event eve);
         private delegate void InitContextDelegate(Context context);
         private static MethodDelegate methodDelegate;
+        private delegate void ShutdownContextDelegate(Context context);
         private static string initContextFunctionName = "InitContext";
+        private static string shutdownContextFunctionName = "ShutdownContext";
         private Type methodType;
 
+        // the signals the processor sends to drain or terminate the wrapper (SIGUSR1 and SIGUSR2, which .NET
+        // only accepts as raw values), and to continue once drained
+        private static readonly PosixSignal[] shutdownSignals = { (PosixSignal)10, (PosixSignal)12 };
+        private List<PosixSignalRegistration> signalRegistrations = new List<PosixSignalRegistration>();
+
+        // ShutdownContext is called once per drain or termination, and again only after continuing
+        private int shutdownContextCalled;
+
         private ISocketHandler socketHandler;
         private Context context;
 
@@ -51,6 +63,9 @@ namespace processor
             //Run the InitContext method on the function implementation
             try
             {
+                // the processor may signal the wrapper as soon as it connects, and the signals would otherwise
+                // terminate it
+                HandleShutdownSignals();
                 ExecuteInitContext();
                 socketHandler.SendMessage("{ 'kind': 'wrapperInitialized', 'attributes': {'ready': 'true'}");
             }
@@ -85,6 +100,52 @@ namespace processor
             }
         }
 
+        private void HandleShutdownSignals()
+        {
+            foreach (var signal in shutdownSignals)
+            {
+                signalRegistrations.Add(PosixSignalRegistration.Create(signal, signalContext =>
+                {
+                    // the default action of the signals is to terminate the wrapper
+                    signalContext.Cancel = true;
+                    ExecuteShutdownContext();
+                }));
+            }
+
+            signalRegistrations.Add(PosixSignalRegistration.Create(PosixSignal.SIGCONT, signalContext =>
+            {
+                Interlocked.Exchange(ref shutdownContextCalled, 0);
+            }));
+        }
+
+        private void ExecuteShutdownContext()
+        {
+            if (Interlocked.Exchange(ref shutdownContextCalled, 1) == 1)
+            {
+                return;
+            }
+
+            var shutdownMethod = methodType.GetMethod(shutdownContextFunctionName);
+            if (shutdownMethod == null)
+            {
+                return;
+            }
+
+            try
+            {
+                var shutdownDelegate = (ShutdownContextDelegate)Delegate.CreateDelegate(typeof(ShutdownContextDelegate),
+                    null, shutdownMethod, false);
+                if (shutdownDelegate != null)
+                {
+                    shutdownDelegate.Invoke(context);
+                }
+            }
+            catch (Exception e)
+            {
+                context.Logger.Error("Failed to execute " + shutdownContextFunctionName + "(): " + e.Message);
+            }
+        }
+
         private void InitUnixSocketHandler(string socketPath)
         {
             socketHandler = new UnixSocketHandler(socketPath);
@@ -83,7 +83,7 @@ func (d *dotnetcore) RunWrapper(socketPaths []string, controlSocketPath string)
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Env = env
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stdout
+	cmd.Stderr = d.CaptureStderr(os.Stdout)
 
 	return cmd.Process, cmd.Start()
 }
@@ -105,3 +105,8 @@ func (d *dotnetcore) getWrapperDLLPath() string {
 func (d *dotnetcore) GetEventEncoder(writer io.Writer) encoder.EventEncoder {
 	return encoder.NewEventJSONEncoder(d.Logger, writer)
 }
+
+// SupportsShutdownSignals returns true, as the wrapper calls the handler's ShutdownContext when signaled
+func (d *dotnetcore) SupportsShutdownSignals() bool {
+	return true
+}
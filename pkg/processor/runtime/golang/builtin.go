@@ -44,3 +44,9 @@ func InitContext(context *nuclio.Context) error {
 	// just a stub
 	return nil
 }
+
+func ShutdownContext(context *nuclio.Context) error {
+
+	// just a stub
+	return nil
+}
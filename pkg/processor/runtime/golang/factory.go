@@ -21,6 +21,8 @@ import (
 
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 )
 
@@ -28,6 +30,18 @@ type factory struct{}
 
 func (f *factory) Create(parentLogger logger.Logger,
 	runtimeConfiguration *runtime.Configuration) (runtime.Runtime, error) {
+	var attributes struct {
+		Mode string
+	}
+
+	if err := mapstructure.Decode(runtimeConfiguration.Spec.RuntimeAttributes, &attributes); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode runtime attributes")
+	}
+
+	// the handler was built as a standalone binary, which speaks the RPC protocol
+	if attributes.Mode == ModeProcess {
+		return NewProcessRuntime(parentLogger, runtimeConfiguration)
+	}
 
 	// temporarily, for backwards compatibility until this is injected from builder
 	runtimeConfiguration.Spec.Build.Path = f.handlerPluginPath()
@@ -30,6 +30,9 @@ type entrypoint func(*nuclio.Context, nuclio.Event) (interface{}, error)
 // context initializer is the function which is called per runtime to initialize context
 type contextInitializer func(*nuclio.Context) error
 
+// context shutdown is the function which is called per runtime when it's drained or terminated
+type contextShutdown func(*nuclio.Context) error
+
 type handler interface {
 
 	// load will load a handler, given a runtime configuration
@@ -40,12 +43,16 @@ type handler interface {
 
 	// getContextInitializer returns the context initializer (if applicable) of the handler
 	getContextInitializer() contextInitializer
+
+	// getContextShutdown returns the context shutdown (if applicable) of the handler
+	getContextShutdown() contextShutdown
 }
 
 type abstractHandler struct {
 	logger             logger.Logger
 	entrypoint         entrypoint
 	contextInitializer contextInitializer
+	contextShutdown    contextShutdown
 }
 
 func (ah *abstractHandler) load(configuration *runtime.Configuration) error {
@@ -56,6 +63,7 @@ func (ah *abstractHandler) load(configuration *runtime.Configuration) error {
 
 		ah.entrypoint = builtInHandler
 		ah.contextInitializer = InitContext
+		ah.contextShutdown = ShutdownContext
 	}
 
 	return nil
@@ -71,6 +79,11 @@ func (ah *abstractHandler) getContextInitializer() contextInitializer {
 	return ah.contextInitializer
 }
 
+// getContextShutdown returns the context shutdown (if applicable) of the handler
+func (ah *abstractHandler) getContextShutdown() contextShutdown {
+	return ah.contextShutdown
+}
+
 func (ah *abstractHandler) parseName(handlerName string) (string, string, error) {
 
 	// if handler is empty, replace with default
@@ -70,16 +70,19 @@ func (phl *pluginHandlerLoader) load(configuration *runtime.Configuration) error
 			handlerSymbol)
 	}
 
-	contextInitializerSymbol, err := handlerPlugin.Lookup("InitContext")
-
-	// if we can't find it, just carry on - it's not mandatory
-	if err != nil {
-		return nil
+	// if we can't find the context initializer or shutdown, just carry on - they're not mandatory
+	if contextInitializerSymbol, err := handlerPlugin.Lookup("InitContext"); err == nil {
+		phl.contextInitializer, ok = contextInitializerSymbol.(func(*nuclio.Context) error)
+		if !ok {
+			return fmt.Errorf("InitContext is of wrong type - %T", contextInitializerSymbol)
+		}
 	}
 
-	phl.contextInitializer, ok = contextInitializerSymbol.(func(*nuclio.Context) error)
-	if !ok {
-		return fmt.Errorf("InitContext is of wrong type - %T", contextInitializerSymbol)
+	if contextShutdownSymbol, err := handlerPlugin.Lookup("ShutdownContext"); err == nil {
+		phl.contextShutdown, ok = contextShutdownSymbol.(func(*nuclio.Context) error)
+		if !ok {
+			return fmt.Errorf("ShutdownContext is of wrong type - %T", contextShutdownSymbol)
+		}
 	}
 
 	return nil
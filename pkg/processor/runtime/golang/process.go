@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/encoder"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// ModeProcess runs the handler as a separate process, built against the function's own go.mod,
+// rather than loading it as a plugin
+const ModeProcess = "process"
+
+// process runs a handler binary built with the wrapper package, which speaks the RPC protocol
+type process struct {
+	*rpc.AbstractRuntime
+	Logger        logger.Logger
+	configuration *runtime.Configuration
+}
+
+// NewProcessRuntime returns a new golang runtime, which runs the handler out of process
+func NewProcessRuntime(parentLogger logger.Logger, configuration *runtime.Configuration) (runtime.Runtime, error) {
+	var err error
+
+	newProcessRuntime := &process{
+		configuration: configuration,
+		Logger:        parentLogger.GetChild("golang"),
+	}
+
+	newProcessRuntime.AbstractRuntime, err = rpc.NewAbstractRuntime(newProcessRuntime.Logger,
+		configuration,
+		newProcessRuntime)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create runtime")
+	}
+
+	return newProcessRuntime, nil
+}
+
+// RunWrapper runs the handler binary, which connects to the event socket
+func (p *process) RunWrapper(eventSocketPaths []string, controlSocketPath string) (*os.Process, error) {
+	if len(eventSocketPaths) != 1 {
+		return nil, errors.New("Golang process mode doesn't support multiple socket processing")
+	}
+
+	handlerPath := p.getHandlerPath()
+	p.Logger.DebugWith("Using handler binary path", "path", handlerPath)
+	if !common.IsFile(handlerPath) {
+		return nil, errors.Errorf("Can't find handler binary at %q", handlerPath)
+	}
+
+	// pass global environment onto the process, and sprinkle in some added env vars
+	env := os.Environ()
+	env = append(env, p.GetEnvFromConfiguration()...)
+
+	args := []string{
+		handlerPath,
+		"--handler", p.configuration.Spec.Handler,
+		"--event-socket-path", eventSocketPaths[0],
+		"--control-socket-path", controlSocketPath,
+		"--platform-kind", p.configuration.PlatformConfig.Kind,
+		"--namespace", p.configuration.Meta.Namespace,
+		"--worker-id", strconv.Itoa(p.configuration.WorkerID),
+		"--trigger-kind", p.configuration.TriggerKind,
+		"--trigger-name", p.configuration.TriggerName,
+	}
+
+	p.Logger.DebugWith("Running wrapper", "command", strings.Join(args, " "))
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = p.CaptureStderr(os.Stderr)
+
+	return cmd.Process, cmd.Start()
+}
+
+// GetEventEncoder returns the encoder the wrapper package decodes events with
+func (p *process) GetEventEncoder(writer io.Writer) encoder.EventEncoder {
+	return encoder.NewEventJSONEncoder(p.Logger, writer)
+}
+
+// WaitForStart returns true, as the wrapper indicates it started once the context is initialized
+func (p *process) WaitForStart() bool {
+	return true
+}
+
+// SupportsShutdownSignals returns true, as the wrapper calls the handler's ShutdownContext when signaled
+func (p *process) SupportsShutdownSignals() bool {
+	return true
+}
+
+// ProcessBatch is not supported, as plugin mode doesn't support batching either
+func (p *process) ProcessBatch(batch []nuclio.Event, functionLogger logger.Logger) ([]*runtime.ResponseWithErrors, error) {
+	return nil, nuclio.ErrNotImplemented
+}
+
+func (p *process) getHandlerPath() string {
+	handlerPath := os.Getenv("NUCLIO_GOLANG_HANDLER_PATH")
+	if handlerPath != "" {
+		return handlerPath
+	}
+
+	return "/opt/nuclio/handler"
+}
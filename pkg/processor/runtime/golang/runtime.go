@@ -19,6 +19,7 @@ package golang
 import (
 	"fmt"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common/status"
@@ -31,8 +32,13 @@ import (
 
 type golang struct {
 	*runtime.AbstractRuntime
-	configuration *runtime.Configuration
-	entrypoint    entrypoint
+	configuration   *runtime.Configuration
+	entrypoint      entrypoint
+	contextShutdown contextShutdown
+
+	// the context shutdown is called once per drain, until the runtime is told to continue
+	shutdownLock   sync.Mutex
+	shutdownCalled bool
 }
 
 // NewRuntime returns a new golang runtime
@@ -59,6 +65,7 @@ func NewRuntime(parentLogger logger.Logger,
 		AbstractRuntime: abstractRuntime,
 		configuration:   configuration,
 		entrypoint:      handler.getEntrypoint(),
+		contextShutdown: handler.getContextShutdown(),
 	}
 
 	// try to initialize the context, if applicable
@@ -100,6 +107,71 @@ func (g *golang) ProcessBatch(batch []nuclio.Event, functionLogger logger.Logger
 	return nil, nuclio.ErrNotImplemented
 }
 
+// Drain calls the context shutdown, waiting for it up to the worker termination timeout
+func (g *golang) Drain() error {
+	return g.shutdownContext()
+}
+
+// Terminate calls the context shutdown, waiting for it up to the worker termination timeout
+func (g *golang) Terminate() error {
+	return g.shutdownContext()
+}
+
+// Continue allows the context shutdown to be called on the next drain
+func (g *golang) Continue() error {
+	g.shutdownLock.Lock()
+	defer g.shutdownLock.Unlock()
+
+	g.shutdownCalled = false
+
+	return nil
+}
+
+func (g *golang) shutdownContext() error {
+	g.shutdownLock.Lock()
+	defer g.shutdownLock.Unlock()
+
+	if g.contextShutdown == nil || g.shutdownCalled {
+		return nil
+	}
+
+	g.shutdownCalled = true
+
+	g.Logger.DebugWith("Calling context shutdown", "timeout", g.configuration.WorkerTerminationTimeout)
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				shutdownDone <- fmt.Errorf("Caught panic: %s", err)
+			}
+		}()
+
+		shutdownDone <- g.contextShutdown(g.Context)
+	}()
+
+	// without a termination timeout, the context shutdown may take as long as it needs
+	var timeoutChan <-chan time.Time
+	if g.configuration.WorkerTerminationTimeout != 0 {
+		timer := time.NewTimer(g.configuration.WorkerTerminationTimeout)
+		defer timer.Stop()
+
+		timeoutChan = timer.C
+	}
+
+	select {
+	case err := <-shutdownDone:
+		if err != nil {
+			return errors.Wrap(err, "Failed to shut down context")
+		}
+	case <-timeoutChan:
+		g.Logger.WarnWith("Timed out waiting for context shutdown",
+			"timeout", g.configuration.WorkerTerminationTimeout)
+	}
+
+	return nil
+}
+
 func (g *golang) callEntrypoint(event nuclio.Event, functionLogger logger.Logger) (response interface{}, responseErr error) {
 	defer func() {
 		if err := recover(); err != nil {
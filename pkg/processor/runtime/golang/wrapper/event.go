@@ -0,0 +1,231 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wrapper
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// encodedEvent is an event, as encoded by the processor's JSON event encoder
+type encodedEvent struct {
+	ID          nuclio.ID              `json:"id"`
+	ContentType string                 `json:"content_type"`
+	Trigger     triggerInfo            `json:"trigger"`
+	Fields      map[string]interface{} `json:"fields"`
+	Headers     map[string]interface{} `json:"headers"`
+	Method      string                 `json:"method"`
+	Path        string                 `json:"path"`
+	URL         string                 `json:"url"`
+	Timestamp   int64                  `json:"timestamp"`
+	ShardID     int                    `json:"shard_id"`
+	NumShards   int                    `json:"num_shards"`
+	Type        string                 `json:"type"`
+	TypeVersion string                 `json:"type_version"`
+	Version     string                 `json:"version"`
+	Offset      int                    `json:"offset"`
+	Topic       string                 `json:"topic"`
+	Body        json.RawMessage        `json:"body"`
+	RequestID   string                 `json:"request_id"`
+}
+
+type triggerInfo struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+func (ti *triggerInfo) GetClass() string { return "" }
+func (ti *triggerInfo) GetKind() string  { return ti.Kind }
+func (ti *triggerInfo) GetName() string  { return ti.Name }
+
+// event is an event received from the processor
+type event struct {
+	nuclio.AbstractEvent
+	encodedEvent
+	body       []byte
+	bodyObject interface{}
+}
+
+func decodeEvent(data []byte) (*event, error) {
+	decodedEvent := &event{}
+
+	if err := json.Unmarshal(data, &decodedEvent.encodedEvent); err != nil {
+		return nil, fmt.Errorf("Failed to decode event: %w", err)
+	}
+
+	// the body is base64 encoded, unless it's a structured object (e.g. the data of a cloud event)
+	if len(decodedEvent.Body) > 0 && decodedEvent.Body[0] == '"' {
+		var encodedBody string
+		if err := json.Unmarshal(decodedEvent.Body, &encodedBody); err != nil {
+			return nil, fmt.Errorf("Failed to decode event body: %w", err)
+		}
+
+		body, err := base64.StdEncoding.DecodeString(encodedBody)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode event body: %w", err)
+		}
+
+		decodedEvent.body = body
+	} else if len(decodedEvent.Body) > 0 && string(decodedEvent.Body) != "null" {
+		decodedEvent.body = decodedEvent.Body
+
+		if err := json.Unmarshal(decodedEvent.Body, &decodedEvent.bodyObject); err != nil {
+			return nil, fmt.Errorf("Failed to decode event body object: %w", err)
+		}
+	}
+
+	decodedEvent.SetTriggerInfoProvider(&decodedEvent.Trigger)
+
+	return decodedEvent, nil
+}
+
+func (e *event) GetID() nuclio.ID {
+	return e.ID
+}
+
+func (e *event) GetContentType() string {
+	return e.ContentType
+}
+
+func (e *event) GetBody() []byte {
+	return e.body
+}
+
+func (e *event) GetBodyObject() interface{} {
+	if e.bodyObject != nil {
+		return e.bodyObject
+	}
+
+	return e.body
+}
+
+func (e *event) GetHeader(key string) interface{} {
+	return e.Headers[key]
+}
+
+func (e *event) GetHeaderByteSlice(key string) []byte {
+	return toByteSlice(e.Headers[key])
+}
+
+func (e *event) GetHeaderString(key string) string {
+	return string(toByteSlice(e.Headers[key]))
+}
+
+func (e *event) GetHeaderInt(key string) (int, error) {
+	return toInt(e.Headers[key])
+}
+
+func (e *event) GetHeaders() map[string]interface{} {
+	return e.Headers
+}
+
+func (e *event) GetField(key string) interface{} {
+	return e.Fields[key]
+}
+
+func (e *event) GetFieldByteSlice(key string) []byte {
+	return toByteSlice(e.Fields[key])
+}
+
+func (e *event) GetFieldString(key string) string {
+	return string(toByteSlice(e.Fields[key]))
+}
+
+func (e *event) GetFieldInt(key string) (int, error) {
+	return toInt(e.Fields[key])
+}
+
+func (e *event) GetFields() map[string]interface{} {
+	return e.Fields
+}
+
+func (e *event) GetTimestamp() time.Time {
+	return time.Unix(e.Timestamp, 0)
+}
+
+func (e *event) GetPath() string {
+	return e.Path
+}
+
+func (e *event) GetURL() string {
+	return e.URL
+}
+
+func (e *event) GetMethod() string {
+	return e.Method
+}
+
+func (e *event) GetShardID() int {
+	return e.ShardID
+}
+
+func (e *event) GetTotalNumShards() int {
+	return e.NumShards
+}
+
+func (e *event) GetType() string {
+	return e.Type
+}
+
+func (e *event) GetTypeVersion() string {
+	return e.TypeVersion
+}
+
+func (e *event) GetVersion() string {
+	return e.Version
+}
+
+func (e *event) GetOffset() int {
+	return e.Offset
+}
+
+func (e *event) GetTopic() string {
+	return e.Topic
+}
+
+func toByteSlice(value interface{}) []byte {
+	switch typedValue := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return []byte(typedValue)
+	case []byte:
+		return typedValue
+	default:
+		return []byte(fmt.Sprint(typedValue))
+	}
+}
+
+func toInt(value interface{}) (int, error) {
+	switch typedValue := value.(type) {
+	case float64:
+		return int(typedValue), nil
+	case int:
+		return typedValue, nil
+	case int64:
+		return int(typedValue), nil
+	case string:
+		return strconv.Atoi(typedValue)
+	default:
+		return 0, nuclio.ErrTypeConversion
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wrapper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nuclio/logger"
+)
+
+// logRecord is a log message, as sent to the processor
+type logRecord struct {
+	DateTime string                 `json:"datetime"`
+	Level    string                 `json:"level"`
+	Message  string                 `json:"message"`
+	With     map[string]interface{} `json:"with"`
+
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// rpcLogger sends log messages to the processor, which writes them to the function logger
+type rpcLogger struct {
+	wrapper *wrapper
+
+	// the request the messages are logged for, when events are handled concurrently
+	requestID string
+}
+
+func newRPCLogger(wrapperInstance *wrapper, requestID string) logger.Logger {
+	return &rpcLogger{
+		wrapper:   wrapperInstance,
+		requestID: requestID,
+	}
+}
+
+func (l *rpcLogger) Error(format interface{}, vars ...interface{}) {
+	l.log("error", format, vars)
+}
+
+func (l *rpcLogger) Warn(format interface{}, vars ...interface{}) {
+	l.log("warning", format, vars)
+}
+
+func (l *rpcLogger) Info(format interface{}, vars ...interface{}) {
+	l.log("info", format, vars)
+}
+
+func (l *rpcLogger) Debug(format interface{}, vars ...interface{}) {
+	l.log("debug", format, vars)
+}
+
+func (l *rpcLogger) ErrorCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.Error(format, vars...)
+}
+
+func (l *rpcLogger) WarnCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.Warn(format, vars...)
+}
+
+func (l *rpcLogger) InfoCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.Info(format, vars...)
+}
+
+func (l *rpcLogger) DebugCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.Debug(format, vars...)
+}
+
+func (l *rpcLogger) ErrorWith(format interface{}, vars ...interface{}) {
+	l.logWith("error", format, vars)
+}
+
+func (l *rpcLogger) WarnWith(format interface{}, vars ...interface{}) {
+	l.logWith("warning", format, vars)
+}
+
+func (l *rpcLogger) InfoWith(format interface{}, vars ...interface{}) {
+	l.logWith("info", format, vars)
+}
+
+func (l *rpcLogger) DebugWith(format interface{}, vars ...interface{}) {
+	l.logWith("debug", format, vars)
+}
+
+func (l *rpcLogger) ErrorWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.ErrorWith(format, vars...)
+}
+
+func (l *rpcLogger) WarnWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.WarnWith(format, vars...)
+}
+
+func (l *rpcLogger) InfoWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.InfoWith(format, vars...)
+}
+
+func (l *rpcLogger) DebugWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
+	l.DebugWith(format, vars...)
+}
+
+// Flush does nothing, log messages are written as they are emitted
+func (l *rpcLogger) Flush() {}
+
+// GetChild returns the same logger, the processor names the function logger
+func (l *rpcLogger) GetChild(name string) logger.Logger {
+	return l
+}
+
+func (l *rpcLogger) log(level string, format interface{}, vars []interface{}) {
+	message := fmt.Sprint(format)
+	if formatString, isString := format.(string); isString && len(vars) > 0 {
+		message = fmt.Sprintf(formatString, vars...)
+	}
+
+	l.write(level, message, nil)
+}
+
+func (l *rpcLogger) logWith(level string, format interface{}, vars []interface{}) {
+	with := map[string]interface{}{}
+
+	for varIndex := 0; varIndex+1 < len(vars); varIndex += 2 {
+		with[fmt.Sprint(vars[varIndex])] = vars[varIndex+1]
+	}
+
+	l.write(level, fmt.Sprint(format), with)
+}
+
+func (l *rpcLogger) write(level string, message string, with map[string]interface{}) {
+	l.wrapper.writeMessage(messageTypeLog, &logRecord{
+		DateTime:  time.Now().UTC().Format(time.RFC3339Nano),
+		Level:     level,
+		Message:   message,
+		With:      with,
+		RequestID: l.requestID,
+	})
+}
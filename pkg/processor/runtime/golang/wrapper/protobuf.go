@@ -0,0 +1,427 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wrapper
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// the messages of pkg/processor/runtime/rpc/rpcproto/rpc.proto, exchanged with the processor once the
+// protobuf encoding is negotiated. They're encoded here rather than through the processor's helpers, so that
+// the wrapper only depends on the standard library and the SDK. Field numbers must match the schema
+const (
+	wireTypeVarint  = 0
+	wireTypeFixed64 = 1
+	wireTypeBytes   = 2
+	wireTypeFixed32 = 5
+
+	mapEntryKey   = 1
+	mapEntryValue = 2
+
+	valueString = 1
+	valueInt    = 2
+	valueDouble = 3
+	valueBool   = 4
+	valueBytes  = 5
+	valueJSON   = 6
+
+	triggerInfoKind = 1
+	triggerInfoName = 2
+
+	eventID          = 1
+	eventContentType = 2
+	eventTrigger     = 3
+	eventFields      = 4
+	eventHeaders     = 5
+	eventMethod      = 6
+	eventPath        = 7
+	eventURL         = 8
+	eventTimestamp   = 9
+	eventShardID     = 10
+	eventNumShards   = 11
+	eventType        = 12
+	eventTypeVersion = 13
+	eventVersion     = 14
+	eventOffset      = 15
+	eventTopic       = 16
+	eventBody        = 17
+	eventBodyJSON    = 18
+	eventRequestID   = 19
+
+	processorMessageEvent = 1
+
+	resultsResults = 1
+
+	resultStatusCode  = 1
+	resultContentType = 2
+	resultBody        = 3
+	resultHeaders     = 4
+	resultRequestID   = 6
+
+	logRecordDateTime  = 1
+	logRecordLevel     = 2
+	logRecordMessage   = 3
+	logRecordWith      = 4
+	logRecordRequestID = 5
+
+	metricDuration = 1
+)
+
+// encodeProtobufMessage encodes the protobuf message of a message the wrapper sends
+func encodeProtobufMessage(message interface{}) ([]byte, error) {
+	switch typedMessage := message.(type) {
+	case *logRecord:
+		encodedRecord := appendString(nil, logRecordDateTime, typedMessage.DateTime)
+		encodedRecord = appendString(encodedRecord, logRecordLevel, typedMessage.Level)
+		encodedRecord = appendString(encodedRecord, logRecordMessage, typedMessage.Message)
+
+		encodedRecord, err := appendValueMap(encodedRecord, logRecordWith, typedMessage.With)
+		if err != nil {
+			return nil, err
+		}
+
+		return appendString(encodedRecord, logRecordRequestID, typedMessage.RequestID), nil
+	case *metric:
+		return appendDouble(nil, metricDuration, typedMessage.DurationSec), nil
+	case *response:
+		encodedResult := appendInt(nil, resultStatusCode, int64(typedMessage.StatusCode))
+		encodedResult = appendString(encodedResult, resultContentType, typedMessage.ContentType)
+		encodedResult = appendBytes(encodedResult, resultBody, typedMessage.body)
+
+		encodedResult, err := appendValueMap(encodedResult, resultHeaders, typedMessage.Headers)
+		if err != nil {
+			return nil, err
+		}
+
+		encodedResult = appendString(encodedResult, resultRequestID, typedMessage.RequestID)
+
+		return appendMessage(nil, resultsResults, encodedResult), nil
+	}
+
+	return nil, fmt.Errorf("Can't encode %T as protobuf", message)
+}
+
+// decodeProtobufEvent decodes the event a ProcessorMessage holds
+func decodeProtobufEvent(data []byte) (*event, error) {
+	var encodedEvent []byte
+
+	if err := consumeFields(data, func(number int, _ uint64, fieldData []byte) error {
+		if number == processorMessageEvent {
+			encodedEvent = fieldData
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("Failed to decode processor message: %w", err)
+	}
+
+	// batches aren't sent to wrappers that handle events one at a time, nor to this one
+	if encodedEvent == nil {
+		return nil, errors.New("Processor message holds no event")
+	}
+
+	decodedEvent := &event{}
+	decodedEvent.Fields = map[string]interface{}{}
+	decodedEvent.Headers = map[string]interface{}{}
+
+	if err := consumeFields(encodedEvent, func(number int, value uint64, fieldData []byte) error {
+		switch number {
+		case eventID:
+			decodedEvent.ID = nuclio.ID(fieldData)
+		case eventContentType:
+			decodedEvent.ContentType = string(fieldData)
+		case eventTrigger:
+			return consumeFields(fieldData, func(number int, _ uint64, fieldData []byte) error {
+				switch number {
+				case triggerInfoKind:
+					decodedEvent.Trigger.Kind = string(fieldData)
+				case triggerInfoName:
+					decodedEvent.Trigger.Name = string(fieldData)
+				}
+
+				return nil
+			})
+		case eventFields:
+			return decodeValueMapEntry(fieldData, decodedEvent.Fields)
+		case eventHeaders:
+			return decodeValueMapEntry(fieldData, decodedEvent.Headers)
+		case eventMethod:
+			decodedEvent.Method = string(fieldData)
+		case eventPath:
+			decodedEvent.Path = string(fieldData)
+		case eventURL:
+			decodedEvent.URL = string(fieldData)
+		case eventTimestamp:
+			decodedEvent.Timestamp = int64(value)
+		case eventShardID:
+			decodedEvent.ShardID = int(int64(value))
+		case eventNumShards:
+			decodedEvent.NumShards = int(int64(value))
+		case eventType:
+			decodedEvent.Type = string(fieldData)
+		case eventTypeVersion:
+			decodedEvent.TypeVersion = string(fieldData)
+		case eventVersion:
+			decodedEvent.Version = string(fieldData)
+		case eventOffset:
+			decodedEvent.Offset = int(int64(value))
+		case eventTopic:
+			decodedEvent.Topic = string(fieldData)
+		case eventBody:
+			decodedEvent.body = fieldData
+		case eventBodyJSON:
+			decodedEvent.body = fieldData
+			return json.Unmarshal(fieldData, &decodedEvent.bodyObject)
+		case eventRequestID:
+			decodedEvent.RequestID = string(fieldData)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("Failed to decode event: %w", err)
+	}
+
+	decodedEvent.SetTriggerInfoProvider(&decodedEvent.Trigger)
+
+	return decodedEvent, nil
+}
+
+// consumeFields calls handleField with every field of the encoded message. Varint and fixed size fields are
+// passed as a uint64, and length-delimited fields as their bytes
+func consumeFields(data []byte, handleField func(number int, value uint64, fieldData []byte) error) error {
+	for len(data) > 0 {
+		tag, tagLength := binary.Uvarint(data)
+		if tagLength <= 0 {
+			return errors.New("Failed to decode field tag")
+		}
+
+		data = data[tagLength:]
+		number := int(tag >> 3)
+
+		var value uint64
+		var fieldData []byte
+
+		switch tag & 0x7 {
+		case wireTypeVarint:
+			var valueLength int
+			if value, valueLength = binary.Uvarint(data); valueLength <= 0 {
+				return fmt.Errorf("Failed to decode field %d", number)
+			}
+
+			data = data[valueLength:]
+		case wireTypeFixed64:
+			if len(data) < 8 {
+				return fmt.Errorf("Failed to decode field %d", number)
+			}
+
+			value, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wireTypeFixed32:
+			if len(data) < 4 {
+				return fmt.Errorf("Failed to decode field %d", number)
+			}
+
+			value, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		case wireTypeBytes:
+			length, lengthLength := binary.Uvarint(data)
+			if lengthLength <= 0 || uint64(len(data)-lengthLength) < length {
+				return fmt.Errorf("Failed to decode field %d", number)
+			}
+
+			fieldData, data = data[lengthLength:lengthLength+int(length)], data[lengthLength+int(length):]
+		default:
+			return fmt.Errorf("Unsupported wire type %d of field %d", tag&0x7, number)
+		}
+
+		if err := handleField(number, value, fieldData); err != nil {
+			return fmt.Errorf("Failed to handle field %d: %w", number, err)
+		}
+	}
+
+	return nil
+}
+
+func decodeValueMapEntry(data []byte, values map[string]interface{}) error {
+	var key string
+	var encodedValue []byte
+
+	if err := consumeFields(data, func(number int, _ uint64, fieldData []byte) error {
+		switch number {
+		case mapEntryKey:
+			key = string(fieldData)
+		case mapEntryValue:
+			encodedValue = fieldData
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var decodedValue interface{}
+
+	err := consumeFields(encodedValue, func(number int, value uint64, fieldData []byte) error {
+		switch number {
+		case valueString:
+			decodedValue = string(fieldData)
+		case valueInt:
+			decodedValue = int64(value)
+		case valueDouble:
+			decodedValue = math.Float64frombits(value)
+		case valueBool:
+			decodedValue = value != 0
+		case valueBytes:
+			decodedValue = fieldData
+		case valueJSON:
+			return json.Unmarshal(fieldData, &decodedValue)
+		}
+
+		return nil
+	})
+
+	values[key] = decodedValue
+	return err
+}
+
+func appendTag(b []byte, number int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(number)<<3|uint64(wireType))
+}
+
+func appendString(b []byte, number int, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	return appendMessage(b, number, []byte(value))
+}
+
+func appendBytes(b []byte, number int, value []byte) []byte {
+	if len(value) == 0 {
+		return b
+	}
+
+	return appendMessage(b, number, value)
+}
+
+// appendMessage appends a length-delimited field. Empty messages are appended too, since they may be set
+// members of a oneof
+func appendMessage(b []byte, number int, message []byte) []byte {
+	b = appendTag(b, number, wireTypeBytes)
+	b = binary.AppendUvarint(b, uint64(len(message)))
+
+	return append(b, message...)
+}
+
+func appendInt(b []byte, number int, value int64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	return binary.AppendUvarint(appendTag(b, number, wireTypeVarint), uint64(value))
+}
+
+func appendDouble(b []byte, number int, value float64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	return binary.LittleEndian.AppendUint64(appendTag(b, number, wireTypeFixed64), math.Float64bits(value))
+}
+
+// appendValueMap appends a map<string, Value> field, ordered by key so that encoding is deterministic
+func appendValueMap(b []byte, number int, values map[string]interface{}) ([]byte, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		encodedValue, err := encodeValue(values[key])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to encode value of %s: %w", key, err)
+		}
+
+		entry := appendString(nil, mapEntryKey, key)
+		entry = appendMessage(entry, mapEntryValue, encodedValue)
+		b = appendMessage(b, number, entry)
+	}
+
+	return b, nil
+}
+
+// encodeValue encodes a Value message, holding the given value according to its type
+func encodeValue(value interface{}) ([]byte, error) {
+	switch typedValue := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return appendMessage(nil, valueString, []byte(typedValue)), nil
+	case []byte:
+		return appendMessage(nil, valueBytes, typedValue), nil
+	case bool:
+		var encodedBool uint64
+		if typedValue {
+			encodedBool = 1
+		}
+
+		return binary.AppendUvarint(appendTag(nil, valueBool, wireTypeVarint), encodedBool), nil
+	case int:
+		return encodeIntValue(int64(typedValue)), nil
+	case int8:
+		return encodeIntValue(int64(typedValue)), nil
+	case int16:
+		return encodeIntValue(int64(typedValue)), nil
+	case int32:
+		return encodeIntValue(int64(typedValue)), nil
+	case int64:
+		return encodeIntValue(typedValue), nil
+	case uint8:
+		return encodeIntValue(int64(typedValue)), nil
+	case uint16:
+		return encodeIntValue(int64(typedValue)), nil
+	case uint32:
+		return encodeIntValue(int64(typedValue)), nil
+	case float32:
+		return encodeDoubleValue(float64(typedValue)), nil
+	case float64:
+		return encodeDoubleValue(typedValue), nil
+	}
+
+	// anything else (e.g. slices, maps and unsigned integers that may not fit) is encoded as JSON
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode value as JSON: %w", err)
+	}
+
+	return appendMessage(nil, valueJSON, encodedValue), nil
+}
+
+func encodeIntValue(value int64) []byte {
+	return binary.AppendUvarint(appendTag(nil, valueInt, wireTypeVarint), uint64(value))
+}
+
+func encodeDoubleValue(value float64) []byte {
+	return binary.LittleEndian.AppendUint64(appendTag(nil, valueDouble, wireTypeFixed64), math.Float64bits(value))
+}
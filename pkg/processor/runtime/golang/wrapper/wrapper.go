@@ -0,0 +1,567 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wrapper runs a Go handler as a separate process, which speaks the processor's RPC protocol over
+// the event socket. It only depends on the standard library and the SDK, so that it can be built with the
+// function's own go.mod
+package wrapper
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+const (
+	messageTypeResult   = 'r'
+	messageTypeMetric   = 'm'
+	messageTypeLog      = 'l'
+	messageTypeStarted  = 's'
+	messageTypeProtocol = 'p'
+
+	// in the lines protocol every message is a line, in the frames protocol every message is prefixed with its
+	// type and length, and response bodies are sent raw
+	protocolVersionLines  = 1
+	protocolVersionFrames = 2
+
+	// events and the wrapper's messages are encoded as the messages of rpc.proto, rather than as JSON
+	encodingProtobuf = "protobuf"
+
+	connectTimeout = 60 * time.Second
+)
+
+// Entrypoint is the signature of a Go handler
+type Entrypoint func(*nuclio.Context, nuclio.Event) (interface{}, error)
+
+// ContextInitializer is the signature of a Go handler's optional InitContext
+type ContextInitializer func(*nuclio.Context) error
+
+// ContextShutdown is the signature of a Go handler's optional ShutdownContext
+type ContextShutdown func(*nuclio.Context) error
+
+type arguments struct {
+	handler           string
+	eventSocketPath   string
+	controlSocketPath string
+	platformKind      string
+	namespace         string
+	workerID          int
+	triggerKind       string
+	triggerName       string
+
+	// the newest protocol version the processor speaks, and the most events it may send at a time
+	protocolVersion     int
+	maxConcurrentEvents int
+
+	// the encoding the processor offers, if any
+	encoding string
+}
+
+type wrapper struct {
+	connection          io.ReadWriter
+	writeLock           sync.Mutex
+	entrypoint          Entrypoint
+	context             *nuclio.Context
+	protocolVersion     int
+	maxConcurrentEvents int
+	protobuf            bool
+
+	// the context shutdown is called once per drain or termination, until the processor tells the wrapper to
+	// continue. Only the signal handling goroutine accesses it
+	contextShutdown       ContextShutdown
+	contextShutdownCalled bool
+}
+
+type handshake struct {
+	Version             int    `json:"version"`
+	MaxConcurrentEvents int    `json:"max_concurrent_events"`
+	Encoding            string `json:"encoding,omitempty"`
+}
+
+type response struct {
+	StatusCode   int                    `json:"status_code"`
+	ContentType  string                 `json:"content_type"`
+	Body         string                 `json:"body"`
+	BodyEncoding string                 `json:"body_encoding"`
+	BodyLength   int                    `json:"body_length,omitempty"`
+	Headers      map[string]interface{} `json:"headers"`
+	RequestID    string                 `json:"request_id,omitempty"`
+
+	body []byte
+}
+
+type metric struct {
+	DurationSec float64 `json:"duration"`
+}
+
+// Run connects to the processor and handles events with the given entrypoint until the processor closes the
+// connection. It's called from the main package generated at build time, and exits the process on failure
+func Run(entrypoint Entrypoint, contextInitializer ContextInitializer, contextShutdown ContextShutdown) {
+	if err := run(os.Args[1:], entrypoint, contextInitializer, contextShutdown); err != nil {
+		fmt.Fprintf(os.Stderr, "Wrapper failed: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(commandLineArgs []string,
+	entrypoint Entrypoint,
+	contextInitializer ContextInitializer,
+	contextShutdown ContextShutdown) error {
+	args, err := parseArguments(commandLineArgs)
+	if err != nil {
+		return err
+	}
+
+	connection, err := connect(args.eventSocketPath)
+	if err != nil {
+		return err
+	}
+
+	defer connection.Close() // nolint: errcheck
+
+	wrapperInstance, err := newWrapper(connection, entrypoint, args)
+	if err != nil {
+		return err
+	}
+
+	wrapperInstance.contextShutdown = contextShutdown
+
+	return wrapperInstance.serve(contextInitializer)
+}
+
+func parseArguments(commandLineArgs []string) (*arguments, error) {
+	args := arguments{}
+
+	flagSet := flag.NewFlagSet("wrapper", flag.ContinueOnError)
+	flagSet.StringVar(&args.handler, "handler", "", "Handler name")
+	flagSet.StringVar(&args.eventSocketPath, "event-socket-path", "", "Path to the event socket")
+	flagSet.StringVar(&args.controlSocketPath, "control-socket-path", "", "Path to the control socket")
+	flagSet.StringVar(&args.platformKind, "platform-kind", "", "Platform kind")
+	flagSet.StringVar(&args.namespace, "namespace", "", "Namespace")
+	flagSet.IntVar(&args.workerID, "worker-id", 0, "Worker ID")
+	flagSet.StringVar(&args.triggerKind, "trigger-kind", "", "Trigger kind")
+	flagSet.StringVar(&args.triggerName, "trigger-name", "", "Trigger name")
+
+	if err := flagSet.Parse(commandLineArgs); err != nil {
+		return nil, fmt.Errorf("Failed to parse arguments: %w", err)
+	}
+
+	if args.eventSocketPath == "" {
+		return nil, errors.New("Event socket path must be provided")
+	}
+
+	// processors that don't set these only speak the lines protocol, and send one event at a time
+	args.protocolVersion, _ = strconv.Atoi(os.Getenv("NUCLIO_RPC_PROTOCOL_VERSION"))
+	args.maxConcurrentEvents, _ = strconv.Atoi(os.Getenv("NUCLIO_RPC_MAX_CONCURRENT_EVENTS"))
+	args.encoding = os.Getenv("NUCLIO_RPC_ENCODING")
+
+	return &args, nil
+}
+
+// connect dials the processor, which may not be listening yet
+func connect(socketPath string) (net.Conn, error) {
+	var err error
+	var connection net.Conn
+
+	deadline := time.Now().Add(connectTimeout)
+	for time.Now().Before(deadline) {
+		connection, err = net.Dial("unix", socketPath)
+		if err == nil {
+			return connection, nil
+		}
+
+		time.Sleep(time.Second)
+	}
+
+	return nil, fmt.Errorf("Failed to connect to %s: %w", socketPath, err)
+}
+
+func newWrapper(connection io.ReadWriter, entrypoint Entrypoint, args *arguments) (*wrapper, error) {
+	newWrapper := &wrapper{
+		connection:          connection,
+		entrypoint:          entrypoint,
+		protocolVersion:     protocolVersionLines,
+		maxConcurrentEvents: max(args.maxConcurrentEvents, 1),
+	}
+
+	// protobuf is only negotiated along with frames
+	if args.protocolVersion >= protocolVersionFrames {
+		newWrapper.protocolVersion = protocolVersionFrames
+		newWrapper.protobuf = args.encoding == encodingProtobuf
+	}
+
+	functionVersion, _ := strconv.Atoi(os.Getenv("NUCLIO_FUNCTION_VERSION"))
+
+	newWrapper.context = &nuclio.Context{
+		Logger:          newRPCLogger(newWrapper, ""),
+		DataBinding:     map[string]nuclio.DataBinding{},
+		WorkerID:        args.workerID,
+		FunctionName:    os.Getenv("NUCLIO_FUNCTION_NAME"),
+		FunctionVersion: functionVersion,
+		TriggerKind:     args.triggerKind,
+		TriggerName:     args.triggerName,
+	}
+
+	platform, err := nuclio.NewPlatform(newWrapper.context.Logger, args.platformKind, args.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create platform: %w", err)
+	}
+
+	newWrapper.context.Platform = platform
+
+	return newWrapper, nil
+}
+
+// serve initializes the context, tells the processor it may send events, and handles them until the
+// connection is closed
+func (w *wrapper) serve(contextInitializer ContextInitializer) error {
+
+	// the handshake is a line, which the processor understands before the protocol is negotiated. handlers
+	// may process as many events at a time as the processor allows
+	if w.protocolVersion != protocolVersionLines || w.maxConcurrentEvents > 1 {
+		protocolHandshake := handshake{
+			Version:             w.protocolVersion,
+			MaxConcurrentEvents: w.maxConcurrentEvents,
+		}
+
+		if w.protobuf {
+			protocolHandshake.Encoding = encodingProtobuf
+		}
+
+		encodedHandshake, err := json.Marshal(&protocolHandshake)
+		if err != nil {
+			return fmt.Errorf("Failed to encode protocol handshake: %w", err)
+		}
+
+		encodedHandshake = append([]byte{messageTypeProtocol}, encodedHandshake...)
+		if err := w.writeRaw(append(encodedHandshake, '\n')); err != nil {
+			return fmt.Errorf("Failed to write protocol handshake: %w", err)
+		}
+	}
+
+	if contextInitializer != nil {
+		if err := contextInitializer(w.context); err != nil {
+			return fmt.Errorf("Failed to initialize context: %w", err)
+		}
+	}
+
+	// the processor only signals wrappers that started. Handlers without a context shutdown must handle the
+	// signals too, since they'd otherwise terminate the process
+	go w.handleShutdownSignals()
+
+	if err := w.writeRaw(w.encodeMessage(messageTypeStarted, nil)); err != nil {
+		return fmt.Errorf("Failed to write start indication: %w", err)
+	}
+
+	reader := bufio.NewReader(w.connection)
+
+	// the processor sends no more events than negotiated, so handling events concurrently is bounded
+	var eventsInFlight sync.WaitGroup
+	defer eventsInFlight.Wait()
+
+	for {
+		encodedEvent, err := w.readEvent(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return fmt.Errorf("Failed to read event: %w", err)
+		}
+
+		if w.maxConcurrentEvents == 1 {
+			w.handleEvent(encodedEvent)
+			continue
+		}
+
+		eventsInFlight.Add(1)
+		go func() {
+			defer eventsInFlight.Done()
+			w.handleEvent(encodedEvent)
+		}()
+	}
+}
+
+// handleShutdownSignals handles the signals the processor sends to drain, terminate and continue the wrapper
+func (w *wrapper) handleShutdownSignals() {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGCONT)
+
+	for receivedSignal := range signalChan {
+		w.handleShutdownSignal(receivedSignal)
+	}
+}
+
+func (w *wrapper) handleShutdownSignal(receivedSignal os.Signal) {
+	if receivedSignal == syscall.SIGCONT {
+		w.contextShutdownCalled = false
+		return
+	}
+
+	if w.contextShutdown == nil || w.contextShutdownCalled {
+		return
+	}
+
+	w.contextShutdownCalled = true
+
+	if err := w.callContextShutdown(); err != nil {
+		w.context.Logger.WarnWith("Failed to shut down context", "err", err.Error())
+	}
+}
+
+func (w *wrapper) callContextShutdown() (err error) {
+
+	// a panicking context shutdown is only logged, like a failing one
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("Context shutdown panicked: %v", recovered)
+		}
+	}()
+
+	return w.contextShutdown(w.context)
+}
+
+// readEvent reads an encoded event, which is a line or a length-prefixed protobuf message
+func (w *wrapper) readEvent(reader *bufio.Reader) ([]byte, error) {
+	if !w.protobuf {
+		return reader.ReadBytes('\n')
+	}
+
+	encodedEventLength := make([]byte, 4)
+	if _, err := io.ReadFull(reader, encodedEventLength); err != nil {
+		return nil, err
+	}
+
+	encodedEvent := make([]byte, binary.BigEndian.Uint32(encodedEventLength))
+	if _, err := io.ReadFull(reader, encodedEvent); err != nil {
+		return nil, err
+	}
+
+	return encodedEvent, nil
+}
+
+func (w *wrapper) handleEvent(encodedEvent []byte) {
+	var eventResponse *response
+	var decodedEvent *event
+	var err error
+
+	if w.protobuf {
+		decodedEvent, err = decodeProtobufEvent(encodedEvent)
+	} else {
+		decodedEvent, err = decodeEvent(encodedEvent)
+	}
+
+	if err != nil {
+		w.context.Logger.ErrorWith("Failed to decode event", "err", err.Error())
+		eventResponse = errorToResponse(err)
+	} else {
+		startTime := time.Now()
+		eventResponse = w.callEntrypoint(w.getEventContext(decodedEvent.RequestID), decodedEvent)
+
+		w.writeMessage(messageTypeMetric, &metric{
+			DurationSec: time.Since(startTime).Seconds(),
+		})
+
+		// results are matched back to their events by the request ID
+		eventResponse.RequestID = decodedEvent.RequestID
+	}
+
+	w.writeResponse(eventResponse)
+}
+
+// getEventContext returns the context to handle the event of the given request with. Events sent along
+// with others get a copy, whose logger tells the processor which event the log messages belong to
+func (w *wrapper) getEventContext(requestID string) *nuclio.Context {
+	if requestID == "" {
+		return w.context
+	}
+
+	eventContext := *w.context
+	eventContext.Logger = newRPCLogger(w, requestID)
+
+	return &eventContext
+}
+
+func (w *wrapper) callEntrypoint(eventContext *nuclio.Context, event nuclio.Event) (eventResponse *response) {
+
+	// a panicking handler fails the event, rather than the process
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			eventContext.Logger.ErrorWith("Handler panicked",
+				"panic", fmt.Sprint(recovered),
+				"stack", string(debug.Stack()))
+
+			eventResponse = errorToResponse(fmt.Errorf("Handler panicked: %v", recovered))
+		}
+	}()
+
+	output, err := w.entrypoint(eventContext, event)
+	if err != nil {
+		return errorToResponse(err)
+	}
+
+	return outputToResponse(output)
+}
+
+// writeMessage writes a message of the given type. Failures are written to stderr, since the processor
+// can't be told about them
+func (w *wrapper) writeMessage(messageType byte, message interface{}) {
+	var encodedMessage []byte
+	var err error
+
+	if w.protobuf {
+		encodedMessage, err = encodeProtobufMessage(message)
+	} else {
+		encodedMessage, err = json.Marshal(message)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode message: %s\n", err)
+		return
+	}
+
+	if err := w.writeRaw(w.encodeMessage(messageType, encodedMessage)); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write message: %s\n", err)
+	}
+}
+
+// writeResponse writes the response to an event. Frames carry the body raw, after the response's metadata
+func (w *wrapper) writeResponse(eventResponse *response) {
+	if w.protobuf {
+		w.writeMessage(messageTypeResult, eventResponse)
+		return
+	}
+
+	if w.protocolVersion == protocolVersionLines {
+		eventResponse.Body = string(eventResponse.body)
+		eventResponse.BodyEncoding = "text"
+
+		// bodies that can't be carried as JSON strings are base64 encoded
+		if !utf8.Valid(eventResponse.body) {
+			eventResponse.Body = base64.StdEncoding.EncodeToString(eventResponse.body)
+			eventResponse.BodyEncoding = "base64"
+		}
+
+		w.writeMessage(messageTypeResult, eventResponse)
+		return
+	}
+
+	eventResponse.BodyEncoding = "raw"
+	eventResponse.BodyLength = len(eventResponse.body)
+
+	encodedMetadata, err := json.Marshal(eventResponse)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode response: %s\n", err)
+		return
+	}
+
+	payload := make([]byte, 0, 4+len(encodedMetadata)+len(eventResponse.body))
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(encodedMetadata)))
+	payload = append(payload, encodedMetadata...)
+	payload = append(payload, eventResponse.body...)
+
+	if err := w.writeRaw(w.encodeMessage(messageTypeResult, payload)); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write response: %s\n", err)
+	}
+}
+
+// encodeMessage encodes a message as a line or as a frame, according to the negotiated protocol
+func (w *wrapper) encodeMessage(messageType byte, payload []byte) []byte {
+	if w.protocolVersion == protocolVersionLines {
+		line := make([]byte, 0, len(payload)+2)
+		line = append(line, messageType)
+		line = append(line, payload...)
+
+		return append(line, '\n')
+	}
+
+	frame := make([]byte, 0, len(payload)+5)
+	frame = append(frame, messageType)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+
+	return append(frame, payload...)
+}
+
+func (w *wrapper) writeRaw(data []byte) error {
+	w.writeLock.Lock()
+	defer w.writeLock.Unlock()
+
+	_, err := w.connection.Write(data)
+	return err
+}
+
+// outputToResponse converts a handler's output the same way the plugin mode does
+func outputToResponse(output interface{}) *response {
+	switch typedOutput := output.(type) {
+	case nuclio.Response:
+		return newResponse(typedOutput.StatusCode, typedOutput.ContentType, typedOutput.Headers, typedOutput.Body)
+	case *nuclio.Response:
+		return newResponse(typedOutput.StatusCode, typedOutput.ContentType, typedOutput.Headers, typedOutput.Body)
+	case []byte:
+		return newResponse(http.StatusOK, "", nil, typedOutput)
+	case string:
+		return newResponse(http.StatusOK, "", nil, []byte(typedOutput))
+	default:
+		return newResponse(http.StatusOK, "", nil, nil)
+	}
+}
+
+func errorToResponse(err error) *response {
+	statusCode := http.StatusInternalServerError
+
+	switch typedError := err.(type) {
+	case nuclio.ErrorWithStatusCode:
+		statusCode = typedError.StatusCode()
+	case *nuclio.ErrorWithStatusCode:
+		statusCode = typedError.StatusCode()
+	}
+
+	return newResponse(statusCode, "", nil, []byte(err.Error()))
+}
+
+func newResponse(statusCode int, contentType string, headers map[string]interface{}, body []byte) *response {
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	return &response{
+		StatusCode:  statusCode,
+		ContentType: contentType,
+		Headers:     headers,
+		body:        body,
+	}
+}
@@ -0,0 +1,455 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wrapper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/encoder"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
+
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type WrapperTestSuite struct {
+	suite.Suite
+	processorConnection net.Conn
+	processorReader     *bufio.Reader
+	serveErrChan        chan error
+	protocolVersion     int
+	maxConcurrentEvents int
+	encoding            string
+}
+
+func (suite *WrapperTestSuite) SetupTest() {
+	suite.protocolVersion = protocolVersionLines
+	suite.maxConcurrentEvents = 1
+	suite.encoding = ""
+}
+
+func (suite *WrapperTestSuite) TearDownTest() {
+	if suite.processorConnection != nil {
+		suite.processorConnection.Close() // nolint: errcheck
+		suite.Require().NoError(<-suite.serveErrChan)
+		suite.processorConnection = nil
+	}
+}
+
+func (suite *WrapperTestSuite) TestParseArguments() {
+	args, err := parseArguments([]string{
+		"--handler", "main:Handler",
+		"--event-socket-path", "/tmp/event.sock",
+		"--control-socket-path", "",
+		"--platform-kind", "local",
+		"--namespace", "nuclio",
+		"--worker-id", "3",
+		"--trigger-kind", "http",
+		"--trigger-name", "default-http",
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("/tmp/event.sock", args.eventSocketPath)
+	suite.Require().Equal(3, args.workerID)
+	suite.Require().Equal("default-http", args.triggerName)
+
+	_, err = parseArguments([]string{"--handler", "main:Handler"})
+	suite.Require().Error(err)
+}
+
+func (suite *WrapperTestSuite) TestProcessEvent() {
+	var initialized bool
+
+	suite.startWrapper(func(context *nuclio.Context, event nuclio.Event) (interface{}, error) {
+		context.Logger.InfoWith("Handling event", "workerID", context.WorkerID)
+
+		return nuclio.Response{
+			StatusCode:  http.StatusCreated,
+			ContentType: "application/json",
+			Headers:     map[string]interface{}{"x-trigger": event.GetTriggerInfo().GetName()},
+			Body:        []byte(event.GetMethod() + " " + string(event.GetBody())),
+		}, nil
+	}, func(context *nuclio.Context) error {
+		initialized = true
+		return nil
+	})
+
+	suite.Require().True(initialized)
+
+	suite.writeEvent(map[string]interface{}{
+		"method":  "POST",
+		"body":    base64.StdEncoding.EncodeToString([]byte("hello")),
+		"trigger": map[string]string{"kind": "http", "name": "default-http"},
+	})
+
+	logMessage := suite.readMessage('l')
+	suite.Require().Equal("Handling event", logMessage["message"])
+	suite.Require().Equal("info", logMessage["level"])
+	suite.Require().Equal(float64(2), logMessage["with"].(map[string]interface{})["workerID"])
+
+	suite.readMessage('m')
+
+	response := suite.readMessage('r')
+	suite.Require().Equal(float64(http.StatusCreated), response["status_code"])
+	suite.Require().Equal("application/json", response["content_type"])
+	suite.Require().Equal("POST hello", response["body"])
+	suite.Require().Equal("text", response["body_encoding"])
+	suite.Require().Equal("default-http", response["headers"].(map[string]interface{})["x-trigger"])
+}
+
+func (suite *WrapperTestSuite) TestBinaryResponse() {
+	body := []byte{0xff, 0x00, 0xfe}
+
+	suite.startWrapper(func(context *nuclio.Context, event nuclio.Event) (interface{}, error) {
+		return body, nil
+	}, nil)
+
+	suite.writeEvent(map[string]interface{}{})
+	suite.readMessage('m')
+
+	response := suite.readMessage('r')
+	suite.Require().Equal(float64(http.StatusOK), response["status_code"])
+	suite.Require().Equal("base64", response["body_encoding"])
+	suite.Require().Equal(base64.StdEncoding.EncodeToString(body), response["body"])
+}
+
+func (suite *WrapperTestSuite) TestErrorResponse() {
+	suite.startWrapper(func(context *nuclio.Context, event nuclio.Event) (interface{}, error) {
+		if event.GetPath() == "/missing" {
+			return nil, nuclio.NewErrNotFound("not here")
+		}
+
+		return nil, nuclio.ErrBadRequest
+	}, nil)
+
+	suite.writeEvent(map[string]interface{}{"path": "/missing"})
+	suite.readMessage('m')
+
+	response := suite.readMessage('r')
+	suite.Require().Equal(float64(http.StatusNotFound), response["status_code"])
+	suite.Require().Equal("not here", response["body"])
+
+	suite.writeEvent(map[string]interface{}{})
+	suite.readMessage('m')
+
+	response = suite.readMessage('r')
+	suite.Require().Equal(float64(http.StatusBadRequest), response["status_code"])
+}
+
+func (suite *WrapperTestSuite) TestPanicRecovered() {
+	suite.startWrapper(func(context *nuclio.Context, event nuclio.Event) (interface{}, error) {
+		if event.GetPath() == "/panic" {
+			panic("boom")
+		}
+
+		return "ok", nil
+	}, nil)
+
+	suite.writeEvent(map[string]interface{}{"path": "/panic"})
+
+	logMessage := suite.readMessage('l')
+	suite.Require().Equal("Handler panicked", logMessage["message"])
+	suite.readMessage('m')
+
+	response := suite.readMessage('r')
+	suite.Require().Equal(float64(http.StatusInternalServerError), response["status_code"])
+	suite.Require().Contains(response["body"], "boom")
+
+	// the wrapper keeps handling events
+	suite.writeEvent(map[string]interface{}{})
+	suite.readMessage('m')
+
+	response = suite.readMessage('r')
+	suite.Require().Equal("ok", response["body"])
+}
+
+func (suite *WrapperTestSuite) TestContextShutdown() {
+	wrapperInstance, err := newWrapper(&bytes.Buffer{}, nil, &arguments{platformKind: "local"})
+	suite.Require().NoError(err)
+
+	contextShutdownCalls := 0
+	wrapperInstance.contextShutdown = func(context *nuclio.Context) error {
+		contextShutdownCalls++
+		panic("boom")
+	}
+
+	// the context shutdown is called once per drain or termination, whether it fails or not
+	wrapperInstance.handleShutdownSignal(syscall.SIGUSR2)
+	wrapperInstance.handleShutdownSignal(syscall.SIGUSR1)
+	suite.Require().Equal(1, contextShutdownCalls)
+
+	// and again once the wrapper is told to continue
+	wrapperInstance.handleShutdownSignal(syscall.SIGCONT)
+	wrapperInstance.handleShutdownSignal(syscall.SIGUSR1)
+	suite.Require().Equal(2, contextShutdownCalls)
+}
+
+func (suite *WrapperTestSuite) TestObjectBody() {
+	suite.startWrapper(func(context *nuclio.Context, event nuclio.Event) (interface{}, error) {
+		return event.GetBodyObject().(map[string]interface{})["greeting"], nil
+	}, nil)
+
+	suite.writeEvent(map[string]interface{}{
+		"body": map[string]interface{}{"greeting": "hi"},
+	})
+	suite.readMessage('m')
+
+	response := suite.readMessage('r')
+	suite.Require().Equal("hi", response["body"])
+}
+
+func (suite *WrapperTestSuite) TestFramedProtocol() {
+	body := []byte{0xff, '\n', 0x00}
+	suite.protocolVersion = protocolVersionFrames
+
+	suite.startWrapper(func(context *nuclio.Context, event nuclio.Event) (interface{}, error) {
+		context.Logger.Debug("Handling event")
+
+		return nuclio.Response{
+			StatusCode:  http.StatusOK,
+			ContentType: "application/octet-stream",
+			Body:        body,
+		}, nil
+	}, nil)
+
+	suite.writeEvent(map[string]interface{}{})
+
+	logMessage := suite.readMessage('l')
+	suite.Require().Equal("Handling event", logMessage["message"])
+	suite.readMessage('m')
+
+	messageType, payload := suite.readFrame()
+	suite.Require().Equal(byte('r'), messageType)
+
+	metadataLength := binary.BigEndian.Uint32(payload)
+	response := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(payload[4:4+metadataLength], &response))
+	suite.Require().Equal("raw", response["body_encoding"])
+	suite.Require().Equal(float64(len(body)), response["body_length"])
+	suite.Require().Equal("application/octet-stream", response["content_type"])
+	suite.Require().Equal(body, payload[4+metadataLength:])
+}
+
+func (suite *WrapperTestSuite) TestConcurrentEvents() {
+	suite.maxConcurrentEvents = 2
+	firstEventHandling := make(chan struct{})
+
+	// the first event is only done once the second one is handled, so they must be handled concurrently
+	suite.startWrapper(func(context *nuclio.Context, event nuclio.Event) (interface{}, error) {
+		context.Logger.Info("Handling event")
+
+		if string(event.GetBody()) == "first" {
+			<-firstEventHandling
+		} else {
+			close(firstEventHandling)
+		}
+
+		return event.GetBody(), nil
+	}, nil)
+
+	for requestID, body := range []string{"first", "second"} {
+		suite.writeEvent(map[string]interface{}{
+			"body":       base64.StdEncoding.EncodeToString([]byte(body)),
+			"request_id": strconv.Itoa(requestID),
+		})
+	}
+
+	// every message of an event holds its request ID
+	responseBodies := map[string]string{}
+	for len(responseBodies) < 2 {
+		message, err := suite.processorReader.ReadBytes('\n')
+		suite.Require().NoError(err)
+
+		decodedMessage := map[string]interface{}{}
+		suite.Require().NoError(json.Unmarshal(message[1:], &decodedMessage))
+
+		switch message[0] {
+		case 'l':
+			suite.Require().Contains([]interface{}{"0", "1"}, decodedMessage["request_id"])
+		case 'r':
+			responseBodies[decodedMessage["request_id"].(string)] = decodedMessage["body"].(string)
+		}
+	}
+
+	suite.Require().Equal(map[string]string{"0": "first", "1": "second"}, responseBodies)
+}
+
+func (suite *WrapperTestSuite) TestProtobufEncoding() {
+	suite.protocolVersion = protocolVersionFrames
+	suite.encoding = encodingProtobuf
+
+	suite.startWrapper(func(context *nuclio.Context, event nuclio.Event) (interface{}, error) {
+		context.Logger.InfoWith("Handling event", "header", event.GetHeader("h"))
+
+		return nuclio.Response{
+			StatusCode: http.StatusCreated,
+			Headers:    map[string]interface{}{"x": 1},
+			Body:       append(event.GetBody(), 0xff),
+		}, nil
+	}, nil)
+
+	// the event is encoded, and the wrapper's messages decoded, the way the processor does
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	testEvent := &nuclio.MemoryEvent{
+		Body:    []byte("body"),
+		Headers: map[string]interface{}{"h": 5},
+	}
+	testEvent.SetTriggerInfoProvider(&triggerInfo{Kind: "http"})
+
+	eventEncoder := encoder.NewEventProtobufEncoder(loggerInstance, suite.processorConnection)
+	suite.Require().NoError(eventEncoder.Encode(&encoder.Request{ID: "7", Item: testEvent}))
+
+	messageType, payload := suite.readFrame()
+	suite.Require().Equal(byte('l'), messageType)
+
+	logRecord := result.RpcLogRecord{}
+	suite.Require().NoError(logRecord.UnmarshalProtobuf(payload))
+	suite.Require().Equal("Handling event", logRecord.Message)
+	suite.Require().Equal(map[string]interface{}{"header": int64(5)}, logRecord.With)
+	suite.Require().Equal("7", logRecord.RequestID)
+
+	messageType, payload = suite.readFrame()
+	suite.Require().Equal(byte('m'), messageType)
+
+	eventMetric := result.Metric{}
+	suite.Require().NoError(eventMetric.UnmarshalProtobuf(payload))
+	suite.Require().Greater(eventMetric.DurationSec, float64(0))
+
+	messageType, payload = suite.readFrame()
+	suite.Require().Equal(byte('r'), messageType)
+
+	results := result.NewBatchedResults()
+	results.UnmarshalProtobufResponseData(loggerInstance, payload)
+	suite.Require().NoError(results.Err)
+	suite.Require().Len(results.Results, 1)
+	suite.Require().Equal(http.StatusCreated, results.Results[0].StatusCode)
+	suite.Require().Equal("text/plain", results.Results[0].ContentType)
+	suite.Require().Equal([]byte("body\xff"), results.Results[0].DecodedBody)
+	suite.Require().Equal(map[string]interface{}{"x": int64(1)}, results.Results[0].Headers)
+	suite.Require().Equal("7", results.Results[0].RequestID)
+}
+
+func (suite *WrapperTestSuite) startWrapper(entrypoint Entrypoint, contextInitializer ContextInitializer) {
+	var wrapperConnection net.Conn
+
+	suite.processorConnection, wrapperConnection = net.Pipe()
+	suite.processorReader = bufio.NewReader(suite.processorConnection)
+	suite.serveErrChan = make(chan error, 1)
+
+	wrapperInstance, err := newWrapper(wrapperConnection, entrypoint, &arguments{
+		platformKind:        "local",
+		namespace:           "nuclio",
+		workerID:            2,
+		protocolVersion:     suite.protocolVersion,
+		maxConcurrentEvents: suite.maxConcurrentEvents,
+		encoding:            suite.encoding,
+	})
+	suite.Require().NoError(err)
+
+	go func() {
+		suite.serveErrChan <- wrapperInstance.serve(contextInitializer)
+	}()
+
+	if suite.protocolVersion != protocolVersionLines || suite.maxConcurrentEvents > 1 {
+		handshake, err := suite.processorReader.ReadBytes('\n')
+		suite.Require().NoError(err)
+
+		expectedHandshake := fmt.Sprintf(`{"version": %d, "max_concurrent_events": %d}`,
+			suite.protocolVersion,
+			suite.maxConcurrentEvents)
+		if suite.encoding != "" {
+			expectedHandshake = fmt.Sprintf(`{"version": %d, "max_concurrent_events": %d, "encoding": %q}`,
+				suite.protocolVersion,
+				suite.maxConcurrentEvents,
+				suite.encoding)
+		}
+
+		suite.Require().JSONEq(expectedHandshake, string(handshake[1:]))
+	}
+
+	if suite.protocolVersion == protocolVersionFrames {
+		messageType, payload := suite.readFrame()
+		suite.Require().Equal(byte('s'), messageType)
+		suite.Require().Empty(payload)
+
+		return
+	}
+
+	startMessage, err := suite.processorReader.ReadBytes('\n')
+	suite.Require().NoError(err)
+	suite.Require().Equal("s\n", string(startMessage))
+}
+
+func (suite *WrapperTestSuite) writeEvent(event map[string]interface{}) {
+	encodedEvent, err := json.Marshal(event)
+	suite.Require().NoError(err)
+
+	_, err = suite.processorConnection.Write(append(encodedEvent, '\n'))
+	suite.Require().NoError(err)
+}
+
+func (suite *WrapperTestSuite) readMessage(messageType byte) map[string]interface{} {
+	var receivedMessageType byte
+	var payload []byte
+
+	if suite.protocolVersion == protocolVersionFrames {
+		receivedMessageType, payload = suite.readFrame()
+	} else {
+		message, err := suite.processorReader.ReadBytes('\n')
+		suite.Require().NoError(err)
+
+		receivedMessageType, payload = message[0], message[1:]
+	}
+
+	suite.Require().Equal(string(messageType), string(receivedMessageType), string(payload))
+
+	decodedMessage := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(payload, &decodedMessage))
+
+	return decodedMessage
+}
+
+func (suite *WrapperTestSuite) readFrame() (byte, []byte) {
+	header := make([]byte, 5)
+	_, err := io.ReadFull(suite.processorReader, header)
+	suite.Require().NoError(err)
+
+	payload := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	_, err = io.ReadFull(suite.processorReader, payload)
+	suite.Require().NoError(err)
+
+	return header[0], payload
+}
+
+func TestWrapperTestSuite(t *testing.T) {
+	suite.Run(t, new(WrapperTestSuite))
+}
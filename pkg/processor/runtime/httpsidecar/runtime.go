@@ -0,0 +1,442 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpsidecar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/common/status"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// headers set by the runtime on every forwarded event
+const (
+	headerEventID     = "X-Nuclio-Event-Id"
+	headerTriggerKind = "X-Nuclio-Trigger-Kind"
+	headerTriggerName = "X-Nuclio-Trigger-Name"
+)
+
+// response headers that describe the server's connection rather than the response, and are not passed on
+var hopByHopHeaders = map[string]struct{}{
+	"Connection":        {},
+	"Content-Length":    {},
+	"Content-Type":      {},
+	"Keep-Alive":        {},
+	"Transfer-Encoding": {},
+}
+
+type httpSidecar struct {
+	*runtime.AbstractRuntime
+	configuration *Configuration
+	client        *http.Client
+
+	// guards the server process and the base URL, which are replaced on restart
+	lock    sync.Mutex
+	cmd     *exec.Cmd
+	exited  chan struct{}
+	baseURL *url.URL
+
+	// cancelled when the runtime stops, failing events that are being forwarded
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewRuntime returns a new HTTP sidecar runtime
+func NewRuntime(parentLogger logger.Logger, configuration *Configuration) (runtime.Runtime, error) {
+	runtimeLogger := parentLogger.GetChild("httpsidecar")
+
+	// create base
+	abstractRuntime, err := runtime.NewAbstractRuntime(runtimeLogger, configuration.Configuration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create abstract runtime")
+	}
+
+	newHTTPSidecarRuntime := &httpSidecar{
+		AbstractRuntime: abstractRuntime,
+		configuration:   configuration,
+		client: &http.Client{
+			Transport: &http.Transport{
+				MaxIdleConns:        configuration.MaxConcurrentEvents,
+				MaxIdleConnsPerHost: configuration.MaxConcurrentEvents,
+			},
+
+			// redirects are the caller's to follow
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	newHTTPSidecarRuntime.SetStatus(status.Initializing)
+
+	return newHTTPSidecarRuntime, nil
+}
+
+// Start starts the server, if the runtime runs it, and waits for it to become ready
+func (h *httpSidecar) Start() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+
+	port := h.configuration.Port
+	if h.configuration.Entrypoint != "" {
+		var err error
+
+		if port == 0 {
+			if port, err = allocatePort(); err != nil {
+				return errors.Wrap(err, "Failed to allocate port")
+			}
+		}
+
+		if err := h.startServer(port); err != nil {
+			return errors.Wrap(err, "Failed to start server")
+		}
+	}
+
+	h.baseURL = &url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(DefaultHost, strconv.Itoa(port)),
+	}
+
+	if err := h.waitForReadiness(); err != nil {
+		h.stopServer()
+		h.SetStatus(status.Error)
+		return errors.Wrap(err, "Server did not become ready")
+	}
+
+	h.Logger.InfoWith("Server is ready", "url", h.baseURL.String())
+
+	h.SetStatus(status.Ready)
+	return nil
+}
+
+// Stop stops the server, if the runtime runs it, failing events that are being forwarded
+func (h *httpSidecar) Stop() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.stopServer()
+
+	return h.AbstractRuntime.Stop()
+}
+
+// Restart restarts the server, so that it recovers from events that timed out
+func (h *httpSidecar) Restart() error {
+	h.Logger.Warn("Restarting")
+
+	if err := h.Stop(); err != nil {
+		return errors.Wrap(err, "Failed to stop runtime")
+	}
+
+	return h.Start()
+}
+
+// SupportsRestart returns true, as the server can be restarted
+func (h *httpSidecar) SupportsRestart() bool {
+	return true
+}
+
+// GetMaxConcurrentEvents returns the number of events forwarded to the server at a time
+func (h *httpSidecar) GetMaxConcurrentEvents() int {
+	return h.configuration.MaxConcurrentEvents
+}
+
+func (h *httpSidecar) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	h.lock.Lock()
+	ctx, baseURL := h.ctx, h.baseURL
+	h.lock.Unlock()
+
+	if baseURL == nil {
+		return nil, nuclio.NewErrServiceUnavailable("Server is not running")
+	}
+
+	request, err := h.createRequest(ctx, baseURL, event)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create request")
+	}
+
+	h.Logger.DebugWith("Forwarding event",
+		"name", h.configuration.Meta.Name,
+		"eventID", event.GetID(),
+		"bodyLen", len(event.GetBody()),
+		"method", request.Method,
+		"url", request.URL.String())
+
+	startTime := time.Now()
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			h.Logger.Warn("Cancelling execution due to an ongoing restart")
+			return nil, nuclio.NewErrRequestTimeout("Failed waiting for function execution")
+		}
+
+		h.Logger.WarnWith("Failed to forward event", "eventID", event.GetID(), "err", err.Error())
+		return nil, nuclio.NewErrBadGateway(fmt.Sprintf("Failed to forward event to server: %s", err.Error()))
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nuclio.NewErrBadGateway(fmt.Sprintf("Failed to read server response: %s", err.Error()))
+	}
+
+	callDuration := time.Since(startTime)
+
+	// add duration to sum
+	atomic.AddUint64(&h.Statistics.DurationMilliSecondsSum, uint64(callDuration.Milliseconds()))
+	atomic.AddUint64(&h.Statistics.DurationMilliSecondsCount, 1)
+
+	return nuclio.Response{
+		StatusCode:  response.StatusCode,
+		ContentType: response.Header.Get("Content-Type"),
+		Headers:     h.getResponseHeaders(response.Header),
+		Body:        responseBody,
+	}, nil
+}
+
+func (h *httpSidecar) ProcessBatch(batch []nuclio.Event, functionLogger logger.Logger) ([]*runtime.ResponseWithErrors, error) {
+	return nil, nuclio.ErrNotImplemented
+}
+
+func (h *httpSidecar) createRequest(ctx context.Context, baseURL *url.URL, event nuclio.Event) (*http.Request, error) {
+	requestURL := *baseURL
+	requestURL.Path = event.GetPath()
+
+	query := url.Values{}
+	for fieldName, fieldValue := range event.GetFields() {
+		query.Set(fieldName, headerValueToString(fieldValue))
+	}
+	requestURL.RawQuery = query.Encode()
+
+	// events of non HTTP triggers have no method, and are posted
+	method := event.GetMethod()
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, requestURL.String(), bytes.NewReader(event.GetBody()))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create request")
+	}
+
+	for headerKey, headerValue := range event.GetHeaders() {
+		if _, hopByHop := hopByHopHeaders[http.CanonicalHeaderKey(headerKey)]; hopByHop {
+			continue
+		}
+
+		request.Header.Set(headerKey, headerValueToString(headerValue))
+	}
+
+	if contentType := event.GetContentType(); contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	request.Header.Set(headerEventID, string(event.GetID()))
+	request.Header.Set(headerTriggerKind, event.GetTriggerInfo().GetKind())
+	request.Header.Set(headerTriggerName, event.GetTriggerInfo().GetName())
+
+	return request, nil
+}
+
+func (h *httpSidecar) getResponseHeaders(responseHeaders http.Header) map[string]interface{} {
+	headers := map[string]interface{}{}
+
+	for headerKey, headerValues := range responseHeaders {
+		if _, hopByHop := hopByHopHeaders[headerKey]; hopByHop {
+			continue
+		}
+
+		// the server mustn't make the processor stream its files
+		if strings.HasPrefix(strings.ToLower(headerKey), "x-nuclio-filestream") {
+			continue
+		}
+
+		headers[headerKey] = strings.Join(headerValues, ", ")
+	}
+
+	return headers
+}
+
+func (h *httpSidecar) startServer(port int) error {
+	cmd := exec.Command("sh", "-c", h.configuration.Entrypoint)
+
+	// the handler directory holds the function's files, if any were deployed
+	handlerDir := os.Getenv("NUCLIO_HTTPSIDECAR_HANDLER_DIR")
+	if handlerDir == "" {
+		handlerDir = "/opt/nuclio"
+	}
+
+	if common.IsDir(handlerDir) {
+		cmd.Dir = handlerDir
+	}
+
+	cmd.Env = append(os.Environ(), h.GetEnvFromConfiguration()...)
+	cmd.Env = append(cmd.Env, fmt.Sprintf("PORT=%d", port))
+	for _, configEnv := range h.configuration.Spec.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", configEnv.Name, configEnv.Value))
+	}
+
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "Failed to run entrypoint")
+	}
+
+	h.Logger.InfoWith("Started server",
+		"entrypoint", h.configuration.Entrypoint,
+		"pid", cmd.Process.Pid,
+		"port", port)
+
+	exited := make(chan struct{})
+	h.cmd, h.exited = cmd, exited
+
+	go h.watchServer(cmd, exited)
+
+	return nil
+}
+
+// watchServer waits for the server to exit, and fails the runtime if it exited while it was expected to run
+func (h *httpSidecar) watchServer(cmd *exec.Cmd, exited chan struct{}) {
+	err := cmd.Wait()
+	close(exited)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// the runtime stopped or replaced the server
+	if h.cmd != cmd {
+		return
+	}
+
+	h.Logger.ErrorWith("Server exited unexpectedly", "pid", cmd.Process.Pid, "err", err)
+
+	h.cmd = nil
+	h.baseURL = nil
+	h.SetStatus(status.Error)
+}
+
+func (h *httpSidecar) stopServer() {
+	if h.cancel != nil {
+		h.cancel()
+	}
+
+	h.baseURL = nil
+
+	if h.cmd == nil {
+		return
+	}
+
+	cmd, exited := h.cmd, h.exited
+	h.cmd = nil
+
+	if err := killProcessGroup(cmd); err != nil && !errors.Is(err, syscall.ESRCH) {
+		h.Logger.WarnWith("Failed to kill server", "pid", cmd.Process.Pid, "err", err.Error())
+	}
+
+	<-exited
+}
+
+// waitForReadiness probes the server until it's ready, it exits or the readiness timeout passes
+func (h *httpSidecar) waitForReadiness() error {
+	readinessURL := *h.baseURL
+	readinessURL.Path = h.configuration.ReadinessPath
+
+	probeClient := &http.Client{Timeout: time.Second}
+	deadline := time.Now().Add(h.configuration.readinessTimeout)
+
+	var lastErr error
+	for time.Now().Before(deadline) {
+		if h.exited != nil {
+			select {
+			case <-h.exited:
+				return errors.New("Server exited before becoming ready")
+			default:
+			}
+		}
+
+		if lastErr = h.probe(probeClient, readinessURL.String()); lastErr == nil {
+			return nil
+		}
+
+		time.Sleep(readinessProbeInterval)
+	}
+
+	return errors.Wrapf(lastErr, "Timed out after %s", h.configuration.readinessTimeout)
+}
+
+func (h *httpSidecar) probe(probeClient *http.Client, readinessURL string) error {
+	response, err := probeClient.Get(readinessURL)
+	if err != nil {
+		return errors.Wrap(err, "Failed to probe server")
+	}
+
+	response.Body.Close() // nolint: errcheck
+
+	// without a readiness path, any response means the server is up
+	if h.configuration.ReadinessPath != "" &&
+		(response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices) {
+		return errors.Errorf("Readiness probe returned status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+func allocatePort() (int, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(DefaultHost, "0"))
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to listen")
+	}
+
+	defer listener.Close() // nolint: errcheck
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func headerValueToString(value interface{}) string {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue
+	case []byte:
+		return string(typedValue)
+	default:
+		return fmt.Sprint(typedValue)
+	}
+}
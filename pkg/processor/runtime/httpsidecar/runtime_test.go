@@ -0,0 +1,235 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpsidecar
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/common/status"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type testTriggerInfoProvider struct{}
+
+func (ti *testTriggerInfoProvider) GetClass() string { return "sync" }
+func (ti *testTriggerInfoProvider) GetKind() string  { return "http" }
+func (ti *testTriggerInfoProvider) GetName() string  { return "my-trigger" }
+
+// testEvent is a memory event with an ID and query fields
+type testEvent struct {
+	nuclio.MemoryEvent
+	fields map[string]interface{}
+}
+
+func (e *testEvent) GetID() nuclio.ID                  { return "1234" }
+func (e *testEvent) GetFields() map[string]interface{} { return e.fields }
+
+type RuntimeTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *RuntimeTestSuite) SetupSuite() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *RuntimeTestSuite) TestForwardEvent() {
+	var readinessProbes int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/ready" {
+
+			// not ready on the first probe
+			if atomic.AddInt32(&readinessProbes, 1) == 1 {
+				responseWriter.WriteHeader(http.StatusServiceUnavailable)
+			}
+
+			return
+		}
+
+		body, err := io.ReadAll(request.Body)
+		suite.Require().NoError(err)
+
+		suite.Require().Equal(http.MethodPut, request.Method)
+		suite.Require().Equal("/some/path", request.URL.Path)
+		suite.Require().Equal("v", request.URL.Query().Get("q"))
+		suite.Require().Equal("application/json", request.Header.Get("Content-Type"))
+		suite.Require().Equal("hv", request.Header.Get("X-Custom"))
+		suite.Require().Equal("1234", request.Header.Get(headerEventID))
+		suite.Require().Equal("my-trigger", request.Header.Get(headerTriggerName))
+
+		responseWriter.Header().Set("Content-Type", "text/plain")
+		responseWriter.Header().Set("X-Response", "rv")
+		responseWriter.Header().Set("X-Nuclio-Filestream-Path", "/etc/passwd")
+		responseWriter.WriteHeader(http.StatusCreated)
+		responseWriter.Write(append([]byte("got "), body...)) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	runtimeInstance := suite.createRuntime(map[string]interface{}{
+		"port":                suite.getPort(server),
+		"readinessPath":       "ready",
+		"maxConcurrentEvents": 4,
+	})
+	suite.Require().NoError(runtimeInstance.Start())
+	defer runtimeInstance.Stop() // nolint: errcheck
+
+	suite.Require().Equal(status.Ready, runtimeInstance.GetStatus())
+	suite.Require().Equal(4, runtimeInstance.GetMaxConcurrentEvents())
+	suite.Require().Equal(int32(2), atomic.LoadInt32(&readinessProbes))
+
+	event := &testEvent{
+		MemoryEvent: nuclio.MemoryEvent{
+			Method:      http.MethodPut,
+			Path:        "/some/path",
+			ContentType: "application/json",
+			Body:        []byte(`{"a": 1}`),
+			Headers:     map[string]interface{}{"X-Custom": "hv"},
+		},
+		fields: map[string]interface{}{"q": "v"},
+	}
+	event.SetTriggerInfoProvider(&testTriggerInfoProvider{})
+
+	response, err := runtimeInstance.ProcessEvent(event, suite.logger)
+	suite.Require().NoError(err)
+
+	typedResponse := response.(nuclio.Response)
+	suite.Require().Equal(http.StatusCreated, typedResponse.StatusCode)
+	suite.Require().Equal("text/plain", typedResponse.ContentType)
+	suite.Require().Equal(`got {"a": 1}`, string(typedResponse.Body))
+	suite.Require().Equal("rv", typedResponse.Headers["X-Response"])
+	suite.Require().NotContains(typedResponse.Headers, "X-Nuclio-Filestream-Path")
+	suite.Require().NotContains(typedResponse.Headers, "Content-Length")
+}
+
+func (suite *RuntimeTestSuite) TestServerUnreachable() {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	runtimeInstance := suite.createRuntime(map[string]interface{}{
+		"port": suite.getPort(server),
+	})
+	suite.Require().NoError(runtimeInstance.Start())
+	defer runtimeInstance.Stop() // nolint: errcheck
+
+	server.Close()
+
+	event := &nuclio.MemoryEvent{}
+	event.SetTriggerInfoProvider(&testTriggerInfoProvider{})
+
+	_, err := runtimeInstance.ProcessEvent(event, suite.logger)
+	suite.Require().Error(err)
+	suite.Require().Equal(http.StatusBadGateway, err.(*nuclio.ErrorWithStatusCode).StatusCode())
+}
+
+func (suite *RuntimeTestSuite) TestEntrypointNotReady() {
+	for _, testCase := range []struct {
+		name       string
+		entrypoint string
+	}{
+
+		// the entrypoint exits, so there's no point waiting for the timeout
+		{name: "exits", entrypoint: "exit 3"},
+
+		// the entrypoint runs, but never listens on $PORT
+		{name: "neverListens", entrypoint: "sleep 10"},
+	} {
+		suite.Run(testCase.name, func() {
+			runtimeInstance := suite.createRuntime(map[string]interface{}{
+				"entrypoint":       testCase.entrypoint,
+				"readinessTimeout": "500ms",
+			})
+
+			suite.Require().Error(runtimeInstance.Start())
+			suite.Require().Equal(status.Error, runtimeInstance.GetStatus())
+		})
+	}
+}
+
+func (suite *RuntimeTestSuite) TestInvalidConfiguration() {
+	for _, testCase := range []struct {
+		name       string
+		attributes map[string]interface{}
+	}{
+		{name: "noEntrypointOrPort", attributes: map[string]interface{}{}},
+		{name: "invalidPort", attributes: map[string]interface{}{"port": 70000}},
+		{name: "invalidReadinessTimeout", attributes: map[string]interface{}{"port": 8080, "readinessTimeout": "soon"}},
+		{name: "negativeConcurrency", attributes: map[string]interface{}{"port": 8080, "maxConcurrentEvents": -1}},
+	} {
+		suite.Run(testCase.name, func() {
+			_, err := NewConfiguration(suite.getRuntimeConfiguration(testCase.attributes))
+			suite.Require().Error(err)
+		})
+	}
+}
+
+func (suite *RuntimeTestSuite) createRuntime(attributes map[string]interface{}) runtime.Runtime {
+	configuration, err := NewConfiguration(suite.getRuntimeConfiguration(attributes))
+	suite.Require().NoError(err)
+
+	runtimeInstance, err := NewRuntime(suite.logger, configuration)
+	suite.Require().NoError(err)
+
+	return runtimeInstance
+}
+
+func (suite *RuntimeTestSuite) getRuntimeConfiguration(attributes map[string]interface{}) *runtime.Configuration {
+	return &runtime.Configuration{
+		FunctionLogger: suite.logger,
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{
+				Spec: functionconfig.Spec{
+					Runtime:           "httpsidecar",
+					RuntimeAttributes: attributes,
+				},
+			},
+			PlatformConfig: &platformconfig.Config{},
+		},
+	}
+}
+
+func (suite *RuntimeTestSuite) getPort(server *httptest.Server) int {
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	suite.Require().NoError(err)
+
+	portNumber, err := strconv.Atoi(port)
+	suite.Require().NoError(err)
+
+	return portNumber
+}
+
+func TestRuntimeTestSuite(t *testing.T) {
+	suite.Run(t, new(RuntimeTestSuite))
+}
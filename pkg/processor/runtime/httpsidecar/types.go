@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpsidecar
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+const (
+	DefaultReadinessTimeout = time.Minute
+	DefaultHost             = "127.0.0.1"
+
+	// how often the server is probed while waiting for it to become ready
+	readinessProbeInterval = 100 * time.Millisecond
+)
+
+type Configuration struct {
+	*runtime.Configuration
+
+	// the command line that starts the server, run with sh -c. if empty, the server is expected to be started
+	// by something else (e.g. a sidecar container) and listen on Port
+	Entrypoint string
+
+	// the port the server listens on. if empty, a free port is allocated for every worker's server, which
+	// is passed to it through the PORT environment variable
+	Port int
+
+	// a path that responds with a 2xx status once the server is ready. if empty, the server is ready once
+	// it responds at all
+	ReadinessPath string
+
+	// how long to wait for the server to become ready
+	ReadinessTimeout string
+
+	// the number of events a worker forwards to its server at a time
+	MaxConcurrentEvents int
+
+	// resolved
+	readinessTimeout time.Duration
+}
+
+func NewConfiguration(runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
+	newConfiguration := Configuration{
+		Configuration: runtimeConfiguration,
+	}
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Spec.RuntimeAttributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if err := newConfiguration.resolve(); err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve configuration")
+	}
+
+	return &newConfiguration, nil
+}
+
+func (c *Configuration) resolve() error {
+	var err error
+
+	if strings.TrimSpace(c.Entrypoint) == "" && c.Port == 0 {
+		return errors.New("Either an entrypoint or the port of an externally started server must be provided")
+	}
+
+	if c.Port < 0 || c.Port > 65535 {
+		return errors.Errorf("Invalid port %d", c.Port)
+	}
+
+	if c.ReadinessPath != "" && !strings.HasPrefix(c.ReadinessPath, "/") {
+		c.ReadinessPath = "/" + c.ReadinessPath
+	}
+
+	c.readinessTimeout = DefaultReadinessTimeout
+	if c.ReadinessTimeout != "" {
+		if c.readinessTimeout, err = time.ParseDuration(c.ReadinessTimeout); err != nil {
+			return errors.Wrap(err, "Failed to parse readiness timeout")
+		}
+	}
+
+	if c.readinessTimeout <= 0 {
+		return errors.Errorf("Readiness timeout must be positive, got %s", c.readinessTimeout)
+	}
+
+	if c.MaxConcurrentEvents < 0 {
+		return errors.Errorf("Invalid max concurrent events %d", c.MaxConcurrentEvents)
+	}
+
+	c.MaxConcurrentEvents = max(c.MaxConcurrentEvents, 1)
+
+	return nil
+}
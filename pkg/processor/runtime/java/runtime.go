@@ -84,7 +84,7 @@ func (j *java) RunWrapper(ports []string, controlPort string) (*os.Process, erro
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Env = env
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stderr = j.CaptureStderr(os.Stderr)
 	j.Logger.InfoWith("Running wrapper jar", "command", strings.Join(cmd.Args, " "))
 
 	return cmd.Process, cmd.Start()
@@ -19,6 +19,7 @@ const events = require('events')
 
 const jsonCtype = 'application/json'
 const initContextFunctionName = 'initContext'
+const shutdownContextFunctionName = 'shutdownContext'
 const isValidPathRegex = /^[a-zA-Z0-9_\-/.\\]+$/
 
 const messageTypes = {
@@ -26,8 +27,53 @@ const messageTypes = {
     RESPONSE: 'r',
     METRIC: 'm',
     START: 's',
+    DATA_BINDING: 'd',
+    PROTOCOL_HANDSHAKE: 'p',
 }
 
+// the protocol versions of the messages the wrapper sends. frames hold the message type, followed by the
+// big-endian length of the payload, so that response bodies can be sent as raw bytes
+const protocolVersions = {
+    LINES: 1,
+    FRAMES: 2,
+}
+
+const frameHeaderLength = 5
+
+// the encoding the processor offers for messages on the event socket, if any
+const protobufEncoding = 'protobuf'
+
+// protobuf wire types
+const wireTypes = {
+    VARINT: 0,
+    FIXED64: 1,
+    LENGTH_DELIMITED: 2,
+    FIXED32: 5,
+}
+
+// the signals the processor sends to drain or terminate the wrapper, and to continue once drained
+const shutdownSignals = ['SIGUSR1', 'SIGUSR2']
+const continueSignal = 'SIGCONT'
+
+// the handler's shutdown callback is called once per drain or termination, and again only after continuing
+let shutdownContextCalled = false
+
+const dataBindingResponseKind = 'dataBindingResponse'
+
+// pending synchronous data binding requests, by id
+const dataBindingRequests = new Map()
+let lastDataBindingRequestId = 0
+
+// the messages exchanged with the processor on the control socket
+const controlMessageKinds = {
+    INVOKE_FUNCTION: 'invokeFunction',
+    INVOKE_FUNCTION_RESULT: 'invokeFunctionResult',
+}
+
+// pending calls to other functions, by id
+const functionInvocations = new Map()
+let lastFunctionInvocationId = 0
+
 const logLevels = {
     DEBUG: 'debug',
     INFO: 'info',
@@ -41,20 +87,210 @@ let context = {
         context._eventEmitter.emit('callback', handlerResponse)
     },
     Response: Response,
-    logger: {
-        error: logWithLevel(logLevels.ERROR),
-        warn: logWithLevel(logLevels.WARNING),
-        info: logWithLevel(logLevels.INFO),
-        debug: logWithLevel(logLevels.DEBUG),
-        errorWith: logWithLevel(logLevels.ERROR),
-        warnWith: logWithLevel(logLevels.WARNING),
-        infoWith: logWithLevel(logLevels.INFO),
-        debugWith: logWithLevel(logLevels.DEBUG),
-    },
+    logger: createLogger(),
+    dataBinding: createDataBindings(process.env.NUCLIO_DATA_BINDINGS),
+    functions: createFunctionInvoker(),
     _socket: undefined,
+    _controlSocket: undefined,
+    _frames: false,
+    _protobuf: false,
+    _maxConcurrentEvents: 1,
     _eventEmitter: new events.EventEmitter(),
 }
 
+// events handled concurrently get a context of their own, sharing everything but the callback, the logger
+// and the data bindings, which are tagged with the ID of the request the event was sent with
+function createEventContext(requestId) {
+    if (!requestId) {
+        return context
+    }
+
+    const eventEmitter = new events.EventEmitter()
+    return Object.assign(Object.create(context), {
+        callback: async (handlerResponse) => {
+            eventEmitter.emit('callback', handlerResponse)
+        },
+        logger: createLogger(requestId),
+        dataBinding: createDataBindings(process.env.NUCLIO_DATA_BINDINGS, requestId),
+        _eventEmitter: eventEmitter,
+    })
+}
+
+function createLogger(requestId = '') {
+    return {
+        error: logWithLevel(logLevels.ERROR, requestId),
+        warn: logWithLevel(logLevels.WARNING, requestId),
+        info: logWithLevel(logLevels.INFO, requestId),
+        debug: logWithLevel(logLevels.DEBUG, requestId),
+        errorWith: logWithLevel(logLevels.ERROR, requestId),
+        warnWith: logWithLevel(logLevels.WARNING, requestId),
+        infoWith: logWithLevel(logLevels.INFO, requestId),
+        debugWith: logWithLevel(logLevels.DEBUG, requestId),
+    }
+}
+
+// data binding operations are proxied to the processor, which holds the connections shared by all workers.
+// operations the data binding's kind doesn't support (e.g. get on kafka) are rejected
+function createDataBindings(dataBindingNames = '', requestId = '') {
+    const dataBindings = {}
+    for (const name of dataBindingNames.split(',').filter(Boolean)) {
+        const send = (operation, fields, isAsync = false) =>
+            sendDataBindingRequest(name, operation, fields, isAsync, requestId)
+
+        dataBindings[name] = {
+            send: (value, { key, topic, headers } = {}) =>
+                send('send', { key, value, topic, headers })
+                    .then(({ partition, offset }) => ({ partition, offset })),
+            sendAsync: (value, { key, topic, headers } = {}) =>
+                send('send', { key, value, topic, headers }, true),
+            get: key => send('get', { key })
+                .then(({ value }) => Buffer.from(value || '', 'base64')),
+            put: (key, value, { contentType } = {}) =>
+                send('put', { key, value, content_type: contentType })
+                    .then(() => undefined),
+            list: (prefix = '') => send('list', { prefix })
+                .then(({ objects }) => objects || []),
+            presign: (key, { method = 'GET', expirationSeconds = 0 } = {}) =>
+                send('presign', { key, method, expiration_seconds: expirationSeconds })
+                    .then(({ url }) => url),
+        }
+    }
+    return dataBindings
+}
+
+function encodeDataBindingPayload(payload) {
+    if (payload === undefined || payload === null) {
+        return null
+    }
+    if (!isString(payload) && !(payload instanceof Buffer)) {
+        payload = JSON.stringify(payload)
+    }
+    return Buffer.from(payload).toString('base64')
+}
+
+// resolves with the processor's response, or immediately when sent asynchronously
+function sendDataBindingRequest(name, operation, { key, value, ...fields } = {}, isAsync = false, requestId = '') {
+    const request = {
+        ...fields,
+        id: String(++lastDataBindingRequestId),
+        name,
+        operation,
+        key: encodeDataBindingPayload(key),
+        value: encodeDataBindingPayload(value),
+        async: isAsync,
+    }
+    if (requestId) {
+        request.request_id = requestId
+    }
+
+    const responseWaiter = isAsync ? Promise.resolve() : new Promise((resolve, reject) => {
+        dataBindingRequests.set(request.id, { resolve, reject })
+    })
+    writeMessageToProcessor(messageTypes.DATA_BINDING,
+        context._protobuf ? encodeDataBindingRequest(request) : JSON.stringify(request))
+    return responseWaiter
+}
+
+function handleDataBindingResponse(response) {
+    const request = dataBindingRequests.get(response.id)
+    if (request === undefined) {
+        return
+    }
+    dataBindingRequests.delete(response.id)
+
+    if (response.error) {
+        request.reject(new Error(`Data binding request failed: ${response.error}`))
+    } else {
+        request.resolve(response)
+    }
+}
+
+// calls other functions through the processor, which retries failed calls and propagates the deadline and
+// correlation ID of the event the call is made on behalf of. timeouts and retry backoffs are in seconds, and
+// negative retries disable retries
+function createFunctionInvoker() {
+    return {
+
+        // resolves with the called function's response and the call's correlation ID
+        invoke: (functionName, options = {}) => sendFunctionInvocationRequest(functionName, options)
+            .then(result => {
+                const response = new Response('', result.headers || {}, result.contentType, result.statusCode)
+                response.body = Buffer.from(result.body || '', 'base64')
+                return { response, correlationId: result.correlationId }
+            }),
+
+        // resolves with the call's correlation ID once the call is queued
+        invokeAsync: (functionName, options = {}) => sendFunctionInvocationRequest(functionName, options, true)
+            .then(({ correlationId }) => correlationId),
+    }
+}
+
+function sendFunctionInvocationRequest(functionName, {
+    body,
+    method = 'POST',
+    path = '/',
+    headers = {},
+    contentType = '',
+    parentEvent,
+    timeout = 0,
+    retries = 0,
+    retryBackoff = 0,
+    correlationId = '',
+} = {}, isAsync = false) {
+    if (context._controlSocket === undefined) {
+        return Promise.reject(new Error('Function invocation is not supported'))
+    }
+
+    // the call inherits the deadline and correlation ID of the event it's made on behalf of
+    const parentHeaders = (parentEvent && parentEvent.headers) || {}
+
+    const request = {
+        id: String(++lastFunctionInvocationId),
+        functionName,
+        method,
+        path,
+        headers,
+        contentType,
+        body: encodeDataBindingPayload(body) || '',
+        async: isAsync,
+        correlationId: correlationId || parentHeaders['X-Nuclio-Correlation-Id'] || '',
+        deadline: parentHeaders['X-Nuclio-Deadline'] || '',
+        timeout,
+        retries,
+        retryBackoff,
+    }
+
+    const resultWaiter = new Promise((resolve, reject) => {
+        functionInvocations.set(request.id, { functionName, resolve, reject })
+    })
+    writeControlMessage(controlMessageKinds.INVOKE_FUNCTION, request)
+    return resultWaiter
+}
+
+function handleControlMessage(controlMessage) {
+    if (controlMessage.kind !== controlMessageKinds.INVOKE_FUNCTION_RESULT) {
+        return
+    }
+
+    const attributes = controlMessage.attributes || {}
+    const invocation = functionInvocations.get(attributes.id)
+    if (invocation === undefined) {
+        return
+    }
+    functionInvocations.delete(attributes.id)
+
+    if (attributes.error) {
+        invocation.reject(new Error(`Failed to call function ${invocation.functionName}: ${attributes.error}`))
+    } else {
+        invocation.resolve(attributes)
+    }
+}
+
+// control messages are sent to the processor as JSON lines
+function writeControlMessage(kind, attributes) {
+    context._controlSocket.write(`${JSON.stringify({ kind, attributes })}\n`)
+}
+
 function Response(body = null,
                   headers = null,
                   contentType = 'text/plain',
@@ -73,14 +309,487 @@ function Response(body = null,
 }
 
 function writeMessageToProcessor(messageType, messageContents) {
-    context._socket.write(`${messageType}${messageContents}\n`)
+    if (!context._frames) {
+        context._socket.write(`${messageType}${messageContents}\n`)
+        return
+    }
+
+    const payload = Buffer.isBuffer(messageContents) ? messageContents : Buffer.from(messageContents)
+    const header = Buffer.alloc(frameHeaderLength)
+    header.write(messageType, 0, 'ascii')
+    header.writeUInt32BE(payload.length, 1)
+    context._socket.write(Buffer.concat([header, payload]))
+}
+
+// the newest protocol version the processor speaks
+function resolveProtocolVersion() {
+    return Number.parseInt(process.env.NUCLIO_RPC_PROTOCOL_VERSION) || protocolVersions.LINES
+}
+
+// the most events the processor may send before their replies
+function resolveMaxConcurrentEvents() {
+    return Math.max(Number.parseInt(process.env.NUCLIO_RPC_MAX_CONCURRENT_EVENTS) || 1, 1)
+}
+
+// everything following the handshake is sent in the negotiated version
+function negotiateProtocolVersion() {
+    const protocolVersion = resolveProtocolVersion()
+    const maxConcurrentEvents = resolveMaxConcurrentEvents()
+    if (protocolVersion < protocolVersions.FRAMES && maxConcurrentEvents === 1) {
+        return
+    }
+
+    const handshake = { version: Math.min(protocolVersion, protocolVersions.FRAMES) }
+    if (process.env.NUCLIO_RPC_ENCODING === protobufEncoding && handshake.version === protocolVersions.FRAMES) {
+        handshake.encoding = protobufEncoding
+    }
+    if (maxConcurrentEvents > 1) {
+        handshake.max_concurrent_events = maxConcurrentEvents
+    }
+
+    writeMessageToProcessor(messageTypes.PROTOCOL_HANDSHAKE, JSON.stringify(handshake))
+    context._frames = handshake.version === protocolVersions.FRAMES
+    context._protobuf = handshake.encoding === protobufEncoding
+    context._maxConcurrentEvents = maxConcurrentEvents
+}
+
+// framed responses hold the length of the response JSON, the response JSON and then the raw body
+function encodeResponse(response) {
+    if (context._protobuf) {
+        return encodeResults([response])
+    }
+
+    if (!context._frames) {
+        if (Buffer.isBuffer(response.body)) {
+            response.body = response.body.toString('base64')
+            response.body_encoding = 'base64'
+        }
+        return JSON.stringify(response)
+    }
+
+    let rawBody = Buffer.alloc(0)
+    if (Buffer.isBuffer(response.body)) {
+        rawBody = response.body
+        response.body = ''
+        response.body_encoding = 'raw'
+        response.body_length = rawBody.length
+    }
+
+    const encodedResponse = Buffer.from(JSON.stringify(response))
+    const encodedResponseLength = Buffer.alloc(4)
+    encodedResponseLength.writeUInt32BE(encodedResponse.length)
+    return Buffer.concat([encodedResponseLength, encodedResponse, rawBody])
 }
 
-function logWithLevel(level) {
-    return (...args) => log(level, ...args)
+// writes the messages of pkg/processor/runtime/rpc/rpcproto/rpc.proto. as in the processor, scalar fields
+// holding their default value are omitted
+class ProtobufWriter {
+    constructor() {
+        this._chunks = []
+    }
+
+    tag(number, wireType) {
+        return this.varint((number << 3) | wireType)
+    }
+
+    // negative integers are encoded as their 64 bit two's complement
+    varint(value) {
+        let remaining = BigInt.asUintN(64, BigInt(value))
+        const bytes = []
+        while (remaining >= 0x80n) {
+            bytes.push(Number(remaining & 0x7fn) | 0x80)
+            remaining >>= 7n
+        }
+        bytes.push(Number(remaining))
+        this._chunks.push(Buffer.from(bytes))
+        return this
+    }
+
+    int(number, value) {
+        return value ? this.tag(number, wireTypes.VARINT).varint(Math.trunc(value)) : this
+    }
+
+    double(number, value) {
+        if (!value) {
+            return this
+        }
+        const encodedValue = Buffer.alloc(8)
+        encodedValue.writeDoubleLE(value)
+        this.tag(number, wireTypes.FIXED64)
+        this._chunks.push(encodedValue)
+        return this
+    }
+
+    // strings are encoded as UTF-8
+    bytes(number, value) {
+        return value && value.length ? this.message(number, Buffer.from(value)) : this
+    }
+
+    // empty messages are appended too, since they may be set members of a oneof
+    message(number, message) {
+        this.tag(number, wireTypes.LENGTH_DELIMITED).varint(message.length)
+        this._chunks.push(message)
+        return this
+    }
+
+    // entries are ordered by key, so that encoding is deterministic
+    valueMap(number, values) {
+        for (const key of Object.keys(values || {}).sort()) {
+            this.message(number, new ProtobufWriter()
+                .bytes(1, key)
+                .message(2, encodeValue(values[key]))
+                .finish())
+        }
+        return this
+    }
+
+    stringMap(number, values) {
+        for (const key of Object.keys(values || {}).sort()) {
+            this.message(number, new ProtobufWriter()
+                .bytes(1, key)
+                .bytes(2, String(values[key]))
+                .finish())
+        }
+        return this
+    }
+
+    finish() {
+        return Buffer.concat(this._chunks)
+    }
 }
 
-function log(level, message, withData = {}) {
+// a Value holds the given value according to its type. anything else (e.g. arrays and objects) is
+// encoded as JSON
+function encodeValue(value) {
+    const writer = new ProtobufWriter()
+    if (value === undefined || value === null) {
+        return writer.finish()
+    }
+    if (isString(value)) {
+        return writer.message(1, Buffer.from(value)).finish()
+    }
+    if (typeof value === 'boolean') {
+        return writer.tag(4, wireTypes.VARINT).varint(value ? 1 : 0).finish()
+    }
+    if (Number.isSafeInteger(value)) {
+        return writer.tag(2, wireTypes.VARINT).varint(value).finish()
+    }
+    if (typeof value === 'number') {
+        const encodedValue = Buffer.alloc(8)
+        encodedValue.writeDoubleLE(value)
+        return Buffer.concat([writer.tag(3, wireTypes.FIXED64).finish(), encodedValue])
+    }
+    if (Buffer.isBuffer(value)) {
+        return writer.message(5, value).finish()
+    }
+    return writer.message(6, Buffer.from(JSON.stringify(value))).finish()
+}
+
+function encodeResults(responses) {
+    const writer = new ProtobufWriter()
+    for (const response of responses) {
+        let body = response.body || ''
+        if (!Buffer.isBuffer(body)) {
+            body = Buffer.from(body, response.body_encoding === 'base64' ? 'base64' : 'utf8')
+        }
+
+        writer.message(1, new ProtobufWriter()
+            .int(1, response.status_code)
+            .bytes(2, response.content_type)
+            .bytes(3, body)
+            .valueMap(4, response.headers)
+            .bytes(5, response.event_id)
+            .bytes(6, response.request_id)
+            .finish())
+    }
+    return writer.finish()
+}
+
+function encodeLogRecord(record) {
+    return new ProtobufWriter()
+        .bytes(1, record.datetime)
+        .bytes(2, record.level)
+        .bytes(3, record.message)
+        .valueMap(4, record.with)
+        .bytes(5, record.request_id)
+        .finish()
+}
+
+function encodeMetric(duration) {
+    return new ProtobufWriter()
+        .double(1, duration)
+        .finish()
+}
+
+// keys and values are base64 encoded for JSON, and sent as is in protobuf
+function encodeDataBindingRequest(request) {
+    return new ProtobufWriter()
+        .bytes(1, request.id)
+        .bytes(2, request.name)
+        .bytes(3, request.operation)
+        .bytes(4, request.topic)
+        .bytes(5, Buffer.from(request.key || '', 'base64'))
+        .bytes(6, Buffer.from(request.value || '', 'base64'))
+        .stringMap(7, request.headers)
+        .int(8, request.async ? 1 : 0)
+        .bytes(9, request.prefix)
+        .bytes(10, request.content_type)
+        .bytes(11, request.method)
+        .int(12, request.expiration_seconds)
+        .bytes(13, request.request_id)
+        .finish()
+}
+
+// yields the number and value of every field. varints are yielded as BigInts, and other fields as their bytes
+function* consumeFields(data) {
+    let offset = 0
+    while (offset < data.length) {
+        let tag
+        [tag, offset] = consumeVarint(data, offset)
+        const number = Number(tag >> 3n)
+        const wireType = Number(tag & 0x7n)
+
+        let value
+        if (wireType === wireTypes.VARINT) {
+            [value, offset] = consumeVarint(data, offset)
+        } else if (wireType === wireTypes.LENGTH_DELIMITED ||
+            wireType === wireTypes.FIXED64 ||
+            wireType === wireTypes.FIXED32) {
+
+            let length = wireType === wireTypes.FIXED64 ? 8 : 4
+            if (wireType === wireTypes.LENGTH_DELIMITED) {
+                [length, offset] = consumeVarint(data, offset)
+                length = Number(length)
+            }
+            if (offset + length > data.length) {
+                throw new Error(`Field ${number} exceeds the message`)
+            }
+            value = data.subarray(offset, offset + length)
+            offset += length
+        } else {
+            throw new Error(`Unsupported wire type ${wireType} of field ${number}`)
+        }
+
+        yield [number, value]
+    }
+}
+
+function consumeVarint(data, offset) {
+    let value = 0n
+    let shift = 0n
+    for (; ;) {
+        if (offset >= data.length) {
+            throw new Error('Truncated varint')
+        }
+        const byte = data[offset++]
+        value |= BigInt(byte & 0x7f) << shift
+        if (!(byte & 0x80)) {
+            return [value, offset]
+        }
+        shift += 7n
+    }
+}
+
+function toInt64(value) {
+    return Number(BigInt.asIntN(64, value))
+}
+
+function decodeValue(data) {
+    let decodedValue = null
+    for (const [number, value] of consumeFields(data)) {
+        switch (number) {
+            case 1:
+                decodedValue = value.toString()
+                break
+            case 2:
+                decodedValue = toInt64(value)
+                break
+            case 3:
+                decodedValue = value.readDoubleLE()
+                break
+            case 4:
+                decodedValue = value !== 0n
+                break
+            case 5:
+                decodedValue = Buffer.from(value)
+                break
+            case 6:
+                decodedValue = JSON.parse(value.toString())
+                break
+        }
+    }
+    return decodedValue
+}
+
+function decodeValueMapEntry(data, values) {
+    let key = ''
+    let value = Buffer.alloc(0)
+    for (const [number, fieldValue] of consumeFields(data)) {
+        if (number === 1) {
+            key = fieldValue.toString()
+        } else if (number === 2) {
+            value = fieldValue
+        }
+    }
+    values[key] = decodeValue(value)
+}
+
+// decodes an event into the same shape as the JSON the processor sends, other than its body which is kept
+// as bytes rather than base64 encoded
+function decodeEvent(data) {
+    const event = {
+        id: '',
+        content_type: '',
+        trigger: { kind: '', name: '' },
+        fields: {},
+        headers: {},
+        method: '',
+        path: '',
+        url: '',
+        timestamp: 0,
+        shard_id: 0,
+        num_shards: 0,
+        type: '',
+        type_version: '',
+        version: '',
+        offset: 0,
+        topic: '',
+        body: Buffer.alloc(0),
+    }
+
+    const stringFields = {
+        1: 'id', 2: 'content_type', 6: 'method', 7: 'path', 8: 'url', 12: 'type',
+        13: 'type_version', 14: 'version', 16: 'topic', 19: 'request_id',
+    }
+    const intFields = { 9: 'timestamp', 10: 'shard_id', 11: 'num_shards', 15: 'offset' }
+
+    for (const [number, value] of consumeFields(data)) {
+        if (number in stringFields) {
+            event[stringFields[number]] = value.toString()
+        } else if (number in intFields) {
+            event[intFields[number]] = toInt64(value)
+        } else if (number === 3) {
+            for (const [triggerNumber, triggerValue] of consumeFields(value)) {
+                if (triggerNumber === 1) {
+                    event.trigger.kind = triggerValue.toString()
+                } else if (triggerNumber === 2) {
+                    event.trigger.name = triggerValue.toString()
+                }
+            }
+        } else if (number === 4) {
+            decodeValueMapEntry(value, event.fields)
+        } else if (number === 5) {
+            decodeValueMapEntry(value, event.headers)
+        } else if (number === 17) {
+            event.body = Buffer.from(value)
+        } else if (number === 18) {
+            event.body = JSON.parse(value.toString())
+        }
+    }
+
+    event['content-type'] = event.content_type
+    event.size = Buffer.isBuffer(event.body) ? event.body.length : 0
+    return event
+}
+
+// decodes a data binding response into the same shape as the JSON the processor sends
+function decodeDataBindingResponse(data) {
+    const response = { kind: '', id: '', partition: 0, offset: 0 }
+    for (const [number, value] of consumeFields(data)) {
+        switch (number) {
+            case 1:
+                response.kind = value.toString()
+                break
+            case 2:
+                response.id = value.toString()
+                break
+            case 3:
+                response.partition = toInt64(value)
+                break
+            case 4:
+                response.offset = toInt64(value)
+                break
+            case 5:
+                response.value = value.toString('base64')
+                break
+            case 6:
+                response.objects = response.objects || []
+                response.objects.push(decodeObjectInfo(value))
+                break
+            case 7:
+                response.url = value.toString()
+                break
+            case 8:
+                response.error = value.toString()
+                break
+        }
+    }
+    return response
+}
+
+function decodeObjectInfo(data) {
+    const objectInfo = { key: '', size: 0, lastModified: '', etag: '' }
+    for (const [number, value] of consumeFields(data)) {
+        if (number === 1) {
+            objectInfo.key = value.toString()
+        } else if (number === 2) {
+            objectInfo.size = toInt64(value)
+        } else if (number === 3) {
+            objectInfo.lastModified = new Date(Number(BigInt.asIntN(64, value) / 1000000n)).toISOString()
+        } else if (number === 4) {
+            objectInfo.etag = value.toString()
+        }
+    }
+    return objectInfo
+}
+
+// returns the event, the events of a batch or the data binding response a ProcessorMessage holds
+function decodeProcessorMessage(data) {
+    for (const [number, value] of consumeFields(data)) {
+        if (number === 1) {
+            return decodeEvent(value)
+        }
+        if (number === 2) {
+            return Array.from(consumeFields(value))
+                .filter(([eventNumber]) => eventNumber === 1)
+                .map(([, event]) => decodeEvent(event))
+        }
+        if (number === 4) {
+            return decodeDataBindingResponse(value)
+        }
+    }
+    throw new Error('Processor message holds no known message')
+}
+
+// the processor sends JSON lines or, once protobuf is negotiated, length-prefixed messages. returns the
+// messages received in full, and the bytes of the one that's yet to be
+function readMessages(received) {
+    const messages = []
+    if (context._protobuf) {
+        while (received.length >= 4 && received.length >= 4 + received.readUInt32BE(0)) {
+            const messageLength = received.readUInt32BE(0)
+            messages.push(decodeProcessorMessage(received.subarray(4, 4 + messageLength)))
+            received = received.subarray(4 + messageLength)
+        }
+        return [messages, received]
+    }
+
+    let lineEnd
+    while ((lineEnd = received.indexOf('\n')) !== -1) {
+        const line = received.subarray(0, lineEnd)
+        if (line.length > 0) {
+            messages.push(JSON.parse(line.toString()))
+        }
+        received = received.subarray(lineEnd + 1)
+    }
+    return [messages, received]
+}
+
+function logWithLevel(level, requestId = '') {
+    return (message, withData) => log(level, message, withData, requestId)
+}
+
+function log(level, message, withData = {}, requestId = '') {
     const datetime = (new Date()).toISOString()
     const record = {
         datetime,
@@ -88,7 +797,10 @@ function log(level, message, withData = {}) {
         message,
         with: withData,
     }
-    writeMessageToProcessor(messageTypes.LOG, JSON.stringify(record))
+    if (requestId) {
+        record.request_id = requestId
+    }
+    writeMessageToProcessor(messageTypes.LOG, context._protobuf ? encodeLogRecord(record) : JSON.stringify(record))
 }
 
 function isString(obj) {
@@ -132,11 +844,6 @@ function responseFromOutput(handlerOutput) {
         response.content_type = jsonCtype
     }
 
-    if (Buffer.isBuffer(response.body)) {
-        response.body = response.body.toString('base64')
-        response.body_encoding = 'base64'
-    }
-
     return response
 }
 
@@ -144,24 +851,30 @@ function writeDuration(start, end) {
     const duration = {
         duration: Math.max(0.00000000001, (end.getTime() - start.getTime()) / 1000)
     }
-    writeMessageToProcessor(messageTypes.METRIC, JSON.stringify(duration))
+    writeMessageToProcessor(messageTypes.METRIC,
+        context._protobuf ? encodeMetric(duration.duration) : JSON.stringify(duration))
 }
 
 async function handleEvent(handlerFunction, incomingEvent) {
+    const eventContext = createEventContext(incomingEvent.request_id)
     let response = {}
     try {
-        incomingEvent.body = new Buffer.from(incomingEvent['body'], 'base64')
+
+        // bodies are base64 encoded in JSON, and raw in protobuf
+        if (!Buffer.isBuffer(incomingEvent['body'])) {
+            incomingEvent.body = new Buffer.from(incomingEvent['body'], 'base64')
+        }
         incomingEvent.timestamp = new Date(incomingEvent['timestamp'] * 1000)
 
         const start = new Date()
 
         // listening on response before executing, to avoid deadlock
-        const responseWaiter = new Promise(resolve => context
+        const responseWaiter = new Promise(resolve => eventContext
             ._eventEmitter
             .once('callback', resolve))
 
         // call the handler
-        handlerFunction(context, incomingEvent)
+        handlerFunction(eventContext, incomingEvent)
 
         // wait for callback
         const handlerResponse = await responseWaiter
@@ -189,12 +902,17 @@ async function handleEvent(handlerFunction, incomingEvent) {
         }
     } finally {
 
+        // responses to events handled concurrently may be sent in any order
+        if (incomingEvent.request_id) {
+            response.request_id = incomingEvent.request_id
+        }
+
         // write response
-        writeMessageToProcessor(messageTypes.RESPONSE, JSON.stringify(response))
+        writeMessageToProcessor(messageTypes.RESPONSE, encodeResponse(response))
     }
 }
 
-function connectSocket(socketPath, handlerFunction) {
+function createSocket(socketPath) {
     const socket = new net.Socket()
     console.log(`socketPath = ${socketPath}`)
     if (socketPath.includes(':')) {
@@ -208,13 +926,68 @@ function connectSocket(socketPath, handlerFunction) {
         // UNIX
         socket.connect(socketPath)
     }
+    return socket
+}
+
+function connectSocket(socketPath, handlerFunction) {
+    const socket = createSocket(socketPath)
     context._socket = socket
     socket.on('ready', () => {
+        negotiateProtocolVersion()
         writeMessageToProcessor(messageTypes.START, '')
     })
+
+    // a message may arrive in several chunks, and a chunk may hold several messages
+    let received = Buffer.alloc(0)
     socket.on('data', async data => {
-        let incomingEvent = JSON.parse(data)
-        await handleEvent(handlerFunction, incomingEvent)
+        let incomingMessages
+        [incomingMessages, received] = readMessages(Buffer.concat([received, data]))
+        for (const incomingEvent of incomingMessages) {
+            if (incomingEvent.kind === dataBindingResponseKind) {
+                handleDataBindingResponse(incomingEvent)
+                continue
+            }
+
+            // the processor sends no more events than negotiated, so these needn't be bounded here
+            if (context._maxConcurrentEvents > 1) {
+                handleEvent(handlerFunction, incomingEvent)
+                continue
+            }
+            await handleEvent(handlerFunction, incomingEvent)
+        }
+    })
+}
+
+// the processor sends control messages as the body of JSON events, a line each
+function connectControlSocket(controlSocketPath) {
+    const socket = createSocket(controlSocketPath)
+    context._controlSocket = socket
+
+    let received = Buffer.alloc(0)
+    socket.on('data', data => {
+        received = Buffer.concat([received, data])
+
+        let lineEnd
+        while ((lineEnd = received.indexOf('\n')) !== -1) {
+            const line = received.subarray(0, lineEnd)
+            received = received.subarray(lineEnd + 1)
+            if (line.length === 0) {
+                continue
+            }
+
+            const controlMessageEvent = JSON.parse(line.toString())
+            handleControlMessage(JSON.parse(Buffer.from(controlMessageEvent.body || '', 'base64').toString() || '{}'))
+        }
+    })
+
+    socket.on('error', err => console.warn(`Control connection failed: ${err}`))
+
+    // nothing will answer the calls in flight
+    socket.on('close', () => {
+        for (const invocation of functionInvocations.values()) {
+            invocation.reject(new Error('Control connection was closed'))
+        }
+        functionInvocations.clear()
     })
 }
 
@@ -225,6 +998,32 @@ function executeInitContext(functionModule) {
     }
 }
 
+function executeShutdownContext(functionModule) {
+    if (shutdownContextCalled) {
+        return Promise.resolve()
+    }
+    shutdownContextCalled = true
+
+    const shutdownContextFunction = functionModule[shutdownContextFunctionName]
+    if (typeof shutdownContextFunction !== 'function') {
+        return Promise.resolve()
+    }
+
+    // the processor waits for the callback up to its shutdown timeout, whether it fails or not
+    return Promise.resolve()
+        .then(() => shutdownContextFunction(context))
+        .catch(err => console.error(`Failed to shut down context: ${err}`))
+}
+
+function handleShutdownSignals(functionModule) {
+    for (const signal of shutdownSignals) {
+        process.on(signal, () => executeShutdownContext(functionModule))
+    }
+    process.on(continueSignal, () => {
+        shutdownContextCalled = false
+    })
+}
+
 function sleep(ms) {
     return new Promise(resolve => setTimeout(resolve, ms))
 }
@@ -250,7 +1049,7 @@ async function findFunction(functionModule, name) {
     return functionToFind
 }
 
-function run(socketPath, handlerPath, handlerName) {
+function run(socketPath, handlerPath, handlerName, controlSocketPath) {
     if (!isValidPathRegex.test(handlerPath)) {
         throw `Invalid handler path: ${handlerPath}`
     }
@@ -263,6 +1062,10 @@ function run(socketPath, handlerPath, handlerName) {
                 console.error(`Failed to init context: ${err}`)
                 throw err
             }
+            handleShutdownSignals(functionModule)
+            if (controlSocketPath) {
+                connectControlSocket(controlSocketPath)
+            }
             return connectSocket(socketPath, handlerFunction)
         })
 }
@@ -272,8 +1075,8 @@ if (require.main === module) {
     // First two arguments are ['node', '/path/to/wrapper.js']
     const args = process.argv.slice(2)
 
-    // ['/path/to/socket', '/path/to/handler.js', 'handler']
-    if (args.length !== 3) {
+    // ['/path/to/socket', '/path/to/handler.js', 'handler', '/path/to/control/socket'], the last being optional
+    if (args.length !== 3 && args.length !== 4) {
         console.error('error: wrong number of arguments')
         process.exit(1)
     }
@@ -281,8 +1084,9 @@ if (require.main === module) {
     const socketPath = args[0]
     const handlerPath = args[1]
     const handlerName = args[2]
+    const controlSocketPath = args[3]
 
-    run(socketPath, handlerPath, handlerName)
+    run(socketPath, handlerPath, handlerName, controlSocketPath)
         .catch((err) => {
             console.error('Error occurred during running. Error:', err)
             process.exit(1)
@@ -109,6 +109,465 @@ describe('Wrapper', () => {
             assert.strictEqual(context._eventEmitter.listenerCount('callback'), 0)
         })
     })
+    describe('context.dataBinding', () => {
+        it('should send through data binding and resolve with response', async () => {
+            const context = wrapper.__get__('context')
+            const createDataBindings = wrapper.__get__('createDataBindings')
+            const handleDataBindingResponse = wrapper.__get__('handleDataBindingResponse')
+            const writtenData = []
+            context._socket = {
+                write: (message) => {
+                    writtenData.push(message)
+                }
+            }
+            const dataBindings = createDataBindings('out')
+            const responseWaiter = dataBindings.out.send('hello', { key: 'k', topic: 'results' })
+
+            assert.strictEqual(writtenData[0][0], 'd')
+            const request = JSON.parse(writtenData[0].substring(1))
+            assert.strictEqual(request.name, 'out')
+            assert.strictEqual(request.topic, 'results')
+            assert.strictEqual(request.async, false)
+            assert.strictEqual(Buffer.from(request.value, 'base64').toString(), 'hello')
+            assert.strictEqual(Buffer.from(request.key, 'base64').toString(), 'k')
+
+            handleDataBindingResponse({ kind: 'dataBindingResponse', id: request.id, partition: 1, offset: 7 })
+            assert.deepStrictEqual(await responseWaiter, { partition: 1, offset: 7 })
+        })
+        it('should reject on data binding error', async () => {
+            const context = wrapper.__get__('context')
+            const createDataBindings = wrapper.__get__('createDataBindings')
+            const handleDataBindingResponse = wrapper.__get__('handleDataBindingResponse')
+            const writtenData = []
+            context._socket = {
+                write: (message) => {
+                    writtenData.push(message)
+                }
+            }
+            const responseWaiter = createDataBindings('out').out.send({ a: 1 })
+            const request = JSON.parse(writtenData[0].substring(1))
+            handleDataBindingResponse({ kind: 'dataBindingResponse', id: request.id, error: 'boom' })
+            await assert.rejects(responseWaiter, /boom/)
+        })
+        it('should get object through data binding', async () => {
+            const context = wrapper.__get__('context')
+            const createDataBindings = wrapper.__get__('createDataBindings')
+            const handleDataBindingResponse = wrapper.__get__('handleDataBindingResponse')
+            const writtenData = []
+            context._socket = {
+                write: (message) => {
+                    writtenData.push(message)
+                }
+            }
+            const responseWaiter = createDataBindings('store').store.get('reports/1.json')
+            const request = JSON.parse(writtenData[0].substring(1))
+            assert.strictEqual(request.operation, 'get')
+            assert.strictEqual(Buffer.from(request.key, 'base64').toString(), 'reports/1.json')
+
+            handleDataBindingResponse({
+                kind: 'dataBindingResponse',
+                id: request.id,
+                value: Buffer.from('{}').toString('base64'),
+            })
+            assert.strictEqual((await responseWaiter).toString(), '{}')
+        })
+    })
+    describe('context.functions', () => {
+        const mockControlSocket = () => {
+            const writtenControlMessages = []
+            wrapper.__get__('context')._controlSocket = {
+                write: (message) => {
+                    writtenControlMessages.push(JSON.parse(message))
+                }
+            }
+            return writtenControlMessages
+        }
+        afterEach(() => {
+            wrapper.__get__('context')._controlSocket = undefined
+        })
+        it('should call a function and resolve with its response', async () => {
+            const context = wrapper.__get__('context')
+            const handleControlMessage = wrapper.__get__('handleControlMessage')
+            const writtenControlMessages = mockControlSocket()
+            const parentEvent = {
+                headers: {
+                    'X-Nuclio-Correlation-Id': 'parent-correlation-id',
+                    'X-Nuclio-Deadline': '2030-01-01T00:00:00Z',
+                },
+            }
+            const resultWaiter = context.functions.invoke('enrich', { body: 'hello', parentEvent, retries: 2 })
+
+            const { kind, attributes } = writtenControlMessages[0]
+            assert.strictEqual(kind, 'invokeFunction')
+            assert.strictEqual(attributes.functionName, 'enrich')
+            assert.strictEqual(attributes.method, 'POST')
+            assert.strictEqual(attributes.async, false)
+            assert.strictEqual(attributes.retries, 2)
+            assert.strictEqual(attributes.correlationId, 'parent-correlation-id')
+            assert.strictEqual(attributes.deadline, '2030-01-01T00:00:00Z')
+            assert.strictEqual(Buffer.from(attributes.body, 'base64').toString(), 'hello')
+
+            handleControlMessage({
+                kind: 'invokeFunctionResult',
+                attributes: {
+                    id: attributes.id,
+                    correlationId: 'parent-correlation-id',
+                    statusCode: 201,
+                    contentType: 'text/plain',
+                    headers: { 'X-Enriched': 'true' },
+                    body: Buffer.from('olleh').toString('base64'),
+                },
+            })
+
+            const { response, correlationId } = await resultWaiter
+            assert.strictEqual(correlationId, 'parent-correlation-id')
+            assert.strictEqual(response.status_code, 201)
+            assert.strictEqual(response.body.toString(), 'olleh')
+            assert.deepStrictEqual(response.headers, { 'X-Enriched': 'true' })
+        })
+        it('should queue an async call and resolve with its correlation ID', async () => {
+            const context = wrapper.__get__('context')
+            const handleControlMessage = wrapper.__get__('handleControlMessage')
+            const writtenControlMessages = mockControlSocket()
+            const correlationIdWaiter = context.functions.invokeAsync('audit')
+
+            const { attributes } = writtenControlMessages[0]
+            assert.strictEqual(attributes.async, true)
+
+            handleControlMessage({
+                kind: 'invokeFunctionResult',
+                attributes: { id: attributes.id, correlationId: 'generated' },
+            })
+            assert.strictEqual(await correlationIdWaiter, 'generated')
+        })
+        it('should reject when the call fails', async () => {
+            const context = wrapper.__get__('context')
+            const handleControlMessage = wrapper.__get__('handleControlMessage')
+            const writtenControlMessages = mockControlSocket()
+            const resultWaiter = context.functions.invoke('enrich')
+
+            handleControlMessage({
+                kind: 'invokeFunctionResult',
+                attributes: { id: writtenControlMessages[0].attributes.id, error: 'deadline exceeded' },
+            })
+            await assert.rejects(resultWaiter, /Failed to call function enrich: deadline exceeded/)
+        })
+        it('should read results from the control socket', async () => {
+            const controlSocketPath = '/tmp/just-a-control-socket'
+            const connectControlSocket = wrapper.__get__('connectControlSocket')
+            const context = wrapper.__get__('context')
+
+            // the processor answers calls with control messages sent as the body of JSON events
+            const server = net.createServer(socket => {
+                socket.on('data', data => {
+                    const { attributes } = JSON.parse(data.toString())
+                    const result = { kind: 'invokeFunctionResult', attributes: { id: attributes.id, correlationId: 'c' } }
+                    socket.write(`${JSON.stringify({ body: Buffer.from(JSON.stringify(result)).toString('base64') })}\n`)
+                })
+            })
+            await new Promise(resolve => server.listen(controlSocketPath, resolve))
+
+            try {
+                connectControlSocket(controlSocketPath)
+                assert.strictEqual(await context.functions.invokeAsync('audit'), 'c')
+            } finally {
+                context._controlSocket.destroy()
+                server.close()
+            }
+        })
+        it('should reject without a control connection', async () => {
+            const context = wrapper.__get__('context')
+            await assert.rejects(context.functions.invoke('enrich'), /not supported/)
+        })
+    })
+    describe('frames', () => {
+        const readFrames = data => {
+            const frames = []
+            for (let offset = 0; offset < data.length;) {
+                const payloadLength = data.readUInt32BE(offset + 1)
+                frames.push({
+                    type: String.fromCharCode(data[offset]),
+                    payload: data.subarray(offset + 5, offset + 5 + payloadLength),
+                })
+                offset += 5 + payloadLength
+            }
+            return frames
+        }
+        afterEach(() => {
+            wrapper.__get__('context')._frames = false
+            delete process.env.NUCLIO_RPC_PROTOCOL_VERSION
+        })
+        it('should negotiate frames when the processor speaks them', () => {
+            const context = wrapper.__get__('context')
+            const writtenData = []
+            context._socket = {
+                write: (message) => {
+                    writtenData.push(message)
+                }
+            }
+            process.env.NUCLIO_RPC_PROTOCOL_VERSION = '2'
+            wrapper.__get__('negotiateProtocolVersion')()
+            context.logger.info('framed')
+
+            assert.deepStrictEqual(JSON.parse(writtenData[0].substring(1)), { version: 2 })
+            const [logFrame] = readFrames(writtenData[1])
+            assert.strictEqual(logFrame.type, 'l')
+            assert.strictEqual(JSON.parse(logFrame.payload).message, 'framed')
+        })
+        it('should keep sending lines when the processor speaks them', () => {
+            const context = wrapper.__get__('context')
+            const writtenData = []
+            context._socket = {
+                write: (message) => {
+                    writtenData.push(message)
+                }
+            }
+            wrapper.__get__('negotiateProtocolVersion')()
+            assert.strictEqual(writtenData.length, 0)
+            assert.strictEqual(context._frames, false)
+        })
+        it('should send raw response bodies', async () => {
+            const context = wrapper.__get__('context')
+            const handleEvent = wrapper.__get__('handleEvent')
+            const writtenData = []
+            context._socket = {
+                write: (message) => {
+                    writtenData.push(message)
+                }
+            }
+            context._frames = true
+            const body = Buffer.from([0x00, 0x0a, 0xff])
+            await handleEvent((context) => context.callback(body), { body: '' })
+
+            const [responseFrame] = readFrames(writtenData[1])
+            assert.strictEqual(responseFrame.type, 'r')
+            const responseLength = responseFrame.payload.readUInt32BE(0)
+            const response = JSON.parse(responseFrame.payload.subarray(4, 4 + responseLength))
+            assert.strictEqual(response.body_encoding, 'raw')
+            assert.strictEqual(response.body_length, 3)
+            assert.deepStrictEqual(responseFrame.payload.subarray(4 + responseLength), body)
+        })
+    })
+    describe('protobuf', () => {
+        const readFrames = data => {
+            const frames = []
+            for (let offset = 0; offset < data.length;) {
+                const payloadLength = data.readUInt32BE(offset + 1)
+                frames.push({
+                    type: String.fromCharCode(data[offset]),
+                    payload: data.subarray(offset + 5, offset + 5 + payloadLength),
+                })
+                offset += 5 + payloadLength
+            }
+            return frames
+        }
+        const lengthPrefixed = message => {
+            const length = Buffer.alloc(4)
+            length.writeUInt32BE(message.length)
+            return Buffer.concat([length, message])
+        }
+        afterEach(() => {
+            const context = wrapper.__get__('context')
+            context._frames = false
+            context._protobuf = false
+            delete process.env.NUCLIO_RPC_PROTOCOL_VERSION
+            delete process.env.NUCLIO_RPC_ENCODING
+        })
+        it('should negotiate protobuf when the processor offers it', () => {
+            const context = wrapper.__get__('context')
+            const writtenData = []
+            context._socket = {
+                write: (message) => {
+                    writtenData.push(message)
+                }
+            }
+            process.env.NUCLIO_RPC_PROTOCOL_VERSION = '2'
+            process.env.NUCLIO_RPC_ENCODING = 'protobuf'
+            wrapper.__get__('negotiateProtocolVersion')()
+            context.logger.infoWith('logged', { key: 'value' })
+
+            assert.deepStrictEqual(JSON.parse(writtenData[0].substring(1)), { version: 2, encoding: 'protobuf' })
+            const [logFrame] = readFrames(writtenData[1])
+            assert.strictEqual(logFrame.type, 'l')
+
+            // level, message and with, following the datetime
+            const datetimeLength = logFrame.payload[1]
+            assert.deepStrictEqual(logFrame.payload.subarray(2 + datetimeLength), Buffer.concat([
+                Buffer.from([0x12, 0x04]), Buffer.from('info'),
+                Buffer.from([0x1a, 0x06]), Buffer.from('logged'),
+                Buffer.from([0x22, 0x0e, 0x0a, 0x03]), Buffer.from('key'),
+                Buffer.from([0x12, 0x07, 0x0a, 0x05]), Buffer.from('value'),
+            ]))
+        })
+        it('should read events and send results', async () => {
+            const context = wrapper.__get__('context')
+            const readMessages = wrapper.__get__('readMessages')
+            const handleEvent = wrapper.__get__('handleEvent')
+            const writtenData = []
+            context._socket = {
+                write: (message) => {
+                    writtenData.push(message)
+                }
+            }
+            context._frames = true
+            context._protobuf = true
+
+            // an event with an id, an http trigger, an "h: 5" header and an "abc" body
+            const encodedEvent = Buffer.concat([
+                Buffer.from([0x0a, 0x01]), Buffer.from('1'),
+                Buffer.from([0x1a, 0x06, 0x0a, 0x04]), Buffer.from('http'),
+                Buffer.from([0x2a, 0x07, 0x0a, 0x01]), Buffer.from('h'), Buffer.from([0x12, 0x02, 0x10, 0x05]),
+                Buffer.from([0x8a, 0x01, 0x03]), Buffer.from('abc'),
+            ])
+            const message = lengthPrefixed(Buffer.concat([Buffer.from([0x0a, encodedEvent.length]), encodedEvent]))
+
+            // messages may arrive in several chunks
+            let [messages, remaining] = readMessages(message.subarray(0, 10))
+            assert.strictEqual(messages.length, 0)
+            ;[messages, remaining] = readMessages(Buffer.concat([remaining, message.subarray(10)]))
+            assert.strictEqual(remaining.length, 0)
+
+            const [event] = messages
+            assert.strictEqual(event.id, '1')
+            assert.deepStrictEqual(event.trigger, { kind: 'http', name: '' })
+            assert.deepStrictEqual(event.headers, { h: 5 })
+
+            await handleEvent((context, event) => context.callback(new context.Response(
+                event.body.toString().split('').reverse().join(''), { x: 1 }, 'text/plain', 201)), event)
+
+            const [metricFrame] = readFrames(writtenData[0])
+            assert.strictEqual(metricFrame.type, 'm')
+            assert.strictEqual(metricFrame.payload[0], 0x09)
+            assert.ok(metricFrame.payload.readDoubleLE(1) > 0)
+
+            const [responseFrame] = readFrames(writtenData[1])
+            assert.strictEqual(responseFrame.type, 'r')
+            const encodedResult = Buffer.concat([
+                Buffer.from([0x08, 0xc9, 0x01]),
+                Buffer.from([0x12, 0x0a]), Buffer.from('text/plain'),
+                Buffer.from([0x1a, 0x03]), Buffer.from('cba'),
+                Buffer.from([0x22, 0x07, 0x0a, 0x01]), Buffer.from('x'), Buffer.from([0x12, 0x02, 0x10, 0x01]),
+            ])
+            assert.deepStrictEqual(responseFrame.payload,
+                Buffer.concat([Buffer.from([0x0a, encodedResult.length]), encodedResult]))
+        })
+        it('should send data binding requests and resolve their responses', async () => {
+            const context = wrapper.__get__('context')
+            const readMessages = wrapper.__get__('readMessages')
+            const sendDataBindingRequest = wrapper.__get__('sendDataBindingRequest')
+            const handleDataBindingResponse = wrapper.__get__('handleDataBindingResponse')
+            const writtenData = []
+            context._socket = {
+                write: (message) => {
+                    writtenData.push(message)
+                }
+            }
+            context._frames = true
+            context._protobuf = true
+
+            const responseWaiter = sendDataBindingRequest('db', 'get', { key: 'k' })
+            const [requestFrame] = readFrames(writtenData[0])
+            assert.strictEqual(requestFrame.type, 'd')
+
+            let requestId
+            for (const [number, value] of wrapper.__get__('consumeFields')(requestFrame.payload)) {
+                if (number === 1) {
+                    requestId = value.toString()
+                }
+            }
+            assert.deepStrictEqual(requestFrame.payload, Buffer.concat([
+                Buffer.from([0x0a, requestId.length]), Buffer.from(requestId),
+                Buffer.from([0x12, 0x02]), Buffer.from('db'),
+                Buffer.from([0x1a, 0x03]), Buffer.from('get'),
+                Buffer.from([0x2a, 0x01]), Buffer.from('k'),
+            ]))
+
+            // a response holding a value and an object whose last modification is at 1s
+            const encodedResponse = Buffer.concat([
+                Buffer.from([0x0a, 0x13]), Buffer.from('dataBindingResponse'),
+                Buffer.from([0x12, requestId.length]), Buffer.from(requestId),
+                Buffer.from([0x2a, 0x02, 0x00, 0xff]),
+                Buffer.from([0x32, 0x0b, 0x0a, 0x03]), Buffer.from('key'),
+                Buffer.from([0x18, 0x80, 0x94, 0xeb, 0xdc, 0x03]),
+            ])
+            const [[response]] = readMessages(lengthPrefixed(
+                Buffer.concat([Buffer.from([0x22, encodedResponse.length]), encodedResponse])))
+            assert.deepStrictEqual(response.objects, [
+                { key: 'key', size: 0, lastModified: '1970-01-01T00:00:01.000Z', etag: '' },
+            ])
+
+            handleDataBindingResponse(response)
+            assert.strictEqual((await responseWaiter).value, Buffer.from([0x00, 0xff]).toString('base64'))
+        })
+    })
+    describe('concurrent events', () => {
+        afterEach(() => {
+            wrapper.__get__('context')._maxConcurrentEvents = 1
+            delete process.env.NUCLIO_RPC_MAX_CONCURRENT_EVENTS
+        })
+        it('should negotiate concurrent events', () => {
+            const context = wrapper.__get__('context')
+            const writtenData = []
+            context._socket = {
+                write: (message) => {
+                    writtenData.push(message)
+                }
+            }
+            process.env.NUCLIO_RPC_MAX_CONCURRENT_EVENTS = '4'
+            wrapper.__get__('negotiateProtocolVersion')()
+
+            assert.deepStrictEqual(JSON.parse(writtenData[0].substring(1)), { version: 1, max_concurrent_events: 4 })
+            assert.strictEqual(context._frames, false)
+            assert.strictEqual(context._maxConcurrentEvents, 4)
+        })
+        it('should tag responses, logs and data binding requests with the request ID', async () => {
+            const context = wrapper.__get__('context')
+            const handleEvent = wrapper.__get__('handleEvent')
+            const handleDataBindingResponse = wrapper.__get__('handleDataBindingResponse')
+            const writtenData = []
+            context._socket = {
+                write: (message) => {
+                    writtenData.push(message)
+                }
+            }
+            process.env.NUCLIO_DATA_BINDINGS = 'store'
+
+            // the first event is only replied to once the second one is
+            let replyToFirstEvent
+            const firstEventReplied = new Promise(resolve => {
+                replyToFirstEvent = resolve
+            })
+            const handler = async (context, event) => {
+                context.logger.info(`handling ${event.id}`)
+                if (event.id === '1') {
+                    await firstEventReplied
+                    context.callback(await context.dataBinding.store.presign('key'))
+                } else {
+                    context.callback(event.id)
+                    replyToFirstEvent()
+                }
+            }
+            const firstEventHandled = handleEvent(handler, { id: '1', body: '', request_id: 'a' })
+            await handleEvent(handler, { id: '2', body: '', request_id: 'b' })
+            delete process.env.NUCLIO_DATA_BINDINGS
+
+            // the first event waits for its data binding request to be answered
+            await new Promise(resolve => setImmediate(resolve))
+            const request = JSON.parse(writtenData.find(message => message[0] === 'd').substring(1))
+            assert.strictEqual(request.request_id, 'a')
+            handleDataBindingResponse({ kind: 'dataBindingResponse', id: request.id, url: 'https://bucket/key' })
+            await firstEventHandled
+
+            const messagesOfType = type => writtenData
+                .filter(message => message[0] === type)
+                .map(message => JSON.parse(message.substring(1)))
+            assert.deepStrictEqual(messagesOfType('l').map(record => [record.request_id, record.message]),
+                [['a', 'handling 1'], ['b', 'handling 2']])
+            assert.deepStrictEqual(messagesOfType('r').map(response => [response.request_id, response.body]),
+                [['b', '2'], ['a', 'https://bucket/key']])
+            assert.strictEqual(context._eventEmitter.listenerCount('callback'), 0)
+        })
+    })
     describe('initContext()', () => {
         it('should mutate context object', async () => {
             const functionModulePath = `${testFunctionsDirPath}/common/context-init/nodejs/contextinit.js`
@@ -137,6 +596,43 @@ describe('Wrapper', () => {
             }, Error)
         })
     })
+    describe('shutdownContext()', () => {
+        afterEach(() => {
+            wrapper.__set__('shutdownContextCalled', false)
+        })
+        it('should call shutdownContext once until continued', async () => {
+            let calls = 0
+            const functionModule = {
+                shutdownContext: async context => {
+                    calls++
+                    context.userData.shutDown = true
+                },
+            }
+            const executeShutdownContext = wrapper.__get__('executeShutdownContext')
+            await executeShutdownContext(functionModule)
+            await executeShutdownContext(functionModule)
+            assert.strictEqual(calls, 1)
+            assert.strictEqual(wrapper.__get__('context').userData.shutDown, true)
+
+            // continuing allows the next drain to call it again
+            wrapper.__set__('shutdownContextCalled', false)
+            await executeShutdownContext(functionModule)
+            assert.strictEqual(calls, 2)
+        })
+        it('should skip shutdownContext when function not exposed', async () => {
+            const functionModulePath = `${testFunctionsDirPath}/common/reverser/nodejs/handler.js`
+            const functionModule = require(functionModulePath)
+            await wrapper.__get__('executeShutdownContext')(functionModule)
+        })
+        it('should not fail when shutdownContext fails', async () => {
+            const functionModule = {
+                shutdownContext: () => {
+                    throw new Error('not loaded')
+                },
+            }
+            await wrapper.__get__('executeShutdownContext')(functionModule)
+        })
+    })
     describe('run()', function () {
         const socketPath = '/tmp/just-a-socket'
         it('should run wrapper', function (done) {
@@ -152,7 +648,9 @@ describe('Wrapper', () => {
                 const requestBody = {
                     body: (new Buffer.from(number.toString())).toString('base64')
                 }
-                socket.write(new Buffer.from(JSON.stringify(requestBody)))
+
+                // the processor terminates events with a newline
+                socket.write(new Buffer.from(`${JSON.stringify(requestBody)}\n`))
                 socket.on('data', data => {
                     if (data.toString().trim() === 's') {
 
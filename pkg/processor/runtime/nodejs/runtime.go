@@ -88,12 +88,17 @@ func (n *nodejs) RunWrapper(socketPaths []string, controlSocketPath string) (*os
 
 	args := []string{nodeExePath, wrapperScriptPath, socketPaths[0], handlerFilePath, handlerName}
 
+	// the wrapper calls other functions over the control socket
+	if controlSocketPath != "" {
+		args = append(args, controlSocketPath)
+	}
+
 	n.Logger.DebugWith("Running wrapper", "command", strings.Join(args, " "))
 
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Env = env
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stdout
+	cmd.Stderr = n.CaptureStderr(os.Stdout)
 
 	return cmd.Process, cmd.Start()
 }
@@ -155,3 +160,13 @@ func (n *nodejs) GetEventEncoder(writer io.Writer) encoder.EventEncoder {
 func (n *nodejs) WaitForStart() bool {
 	return true
 }
+
+// SupportsControlCommunication returns true, as the wrapper calls other functions over the control socket
+func (n *nodejs) SupportsControlCommunication() bool {
+	return true
+}
+
+// SupportsShutdownSignals returns true, as the wrapper calls the handler's shutdownContext when signaled
+func (n *nodejs) SupportsShutdownSignals() bool {
+	return true
+}
@@ -14,12 +14,17 @@
 
 import argparse
 import asyncio
+import base64
+import contextvars
+import datetime
 import functools
 import json
 import logging
+import os
 import re
 import signal
 import socket
+import struct
 import sys
 import time
 import traceback
@@ -36,11 +41,22 @@ class Constants:
     # in msgpack protoctol, binary messages' length is 4 bytes long
     msgpack_message_length_bytes = 4
 
+    # frames hold the message type, followed by the big-endian length of the payload
+    frame_header_format = '>cI'
+
+    # the protocol versions of the messages the wrapper sends on the event socket
+    protocol_version_lines = 1
+    protocol_version_frames = 2
+
     termination_signal = signal.SIGUSR1
     drain_signal = signal.SIGUSR2
     continue_signal = signal.SIGCONT
 
 
+# the ID of the request the processor sent the event being handled with, while events are handled concurrently
+request_id_context = contextvars.ContextVar('request_id', default='')
+
+
 class WrapperFatalException(Exception):
     """
     Wrapper fatal is an exception the wrapper can not (perhaps should not) recover from
@@ -56,6 +72,552 @@ class JSONFormatterOverSocket(nuclio_sdk.logger.JSONFormatter):
         return 'l' + super(JSONFormatterOverSocket, self).format(record)
 
 
+class Protobuf(object):
+    """
+    Encodes and decodes the messages of pkg/processor/runtime/rpc/rpcproto/rpc.proto, exchanged with the processor
+    once the protobuf encoding is negotiated. Field numbers must match the schema
+    """
+
+    # wire types
+    varint = 0
+    fixed64 = 1
+    length_delimited = 2
+    fixed32 = 5
+
+    @classmethod
+    def encode_results(cls, responses):
+        buf = bytearray()
+        for response in responses:
+            cls.append_message(buf, 1, cls._encode_result(response))
+
+        return bytes(buf)
+
+    @classmethod
+    def encode_log_record(cls, record):
+        buf = bytearray()
+        cls.append_bytes(buf, 1, record.get('datetime'))
+        cls.append_bytes(buf, 2, record.get('level'))
+        cls.append_bytes(buf, 3, record.get('message'))
+        cls.append_value_map(buf, 4, record.get('with'))
+        cls.append_bytes(buf, 5, record.get('request_id'))
+
+        return bytes(buf)
+
+    @classmethod
+    def encode_metric(cls, duration):
+        buf = bytearray()
+        cls.append_double(buf, 1, duration)
+
+        return bytes(buf)
+
+    @classmethod
+    def encode_data_binding_request(cls, request):
+        buf = bytearray()
+        cls.append_bytes(buf, 1, request.get('id'))
+        cls.append_bytes(buf, 2, request.get('name'))
+        cls.append_bytes(buf, 3, request.get('operation'))
+        cls.append_bytes(buf, 4, request.get('topic'))
+
+        # keys and values are base64 encoded for JSON, and sent as is in protobuf
+        cls.append_bytes(buf, 5, base64.b64decode(request.get('key') or ''))
+        cls.append_bytes(buf, 6, base64.b64decode(request.get('value') or ''))
+
+        for key, value in (request.get('headers') or {}).items():
+            entry = bytearray()
+            cls.append_bytes(entry, 1, key)
+            cls.append_bytes(entry, 2, str(value))
+            cls.append_message(buf, 7, entry)
+
+        cls.append_int(buf, 8, int(request.get('async') or False))
+        cls.append_bytes(buf, 9, request.get('prefix'))
+        cls.append_bytes(buf, 10, request.get('content_type'))
+        cls.append_bytes(buf, 11, request.get('method'))
+        cls.append_int(buf, 12, request.get('expiration_seconds'))
+        cls.append_bytes(buf, 13, request.get('request_id'))
+
+        return bytes(buf)
+
+    @classmethod
+    def decode_processor_message(cls, data):
+        """
+        Returns the event, the list of events of a batch, the control message or the data binding response
+        the ProcessorMessage holds, as they're decoded from msgpack
+        """
+        for number, value in cls.consume_fields(data):
+            if number == 1:
+                return cls._decode_event(value)
+            if number == 2:
+                return [cls._decode_event(event) for event_number, event in cls.consume_fields(value)
+                        if event_number == 1]
+            if number == 3:
+                return cls._decode_control_message(value)
+            if number == 4:
+                return cls._decode_data_binding_response(value)
+
+        raise ValueError('Processor message holds no known message')
+
+    @classmethod
+    def append_varint(cls, buf, value):
+
+        # negative integers are encoded as their 64 bit two's complement
+        value &= 0xFFFFFFFFFFFFFFFF
+        while value >= 0x80:
+            buf.append((value & 0x7F) | 0x80)
+            value >>= 7
+
+        buf.append(value)
+
+    @classmethod
+    def append_tag(cls, buf, number, wire_type):
+        cls.append_varint(buf, (number << 3) | wire_type)
+
+    @classmethod
+    def append_int(cls, buf, number, value):
+        if value:
+            cls.append_tag(buf, number, cls.varint)
+            cls.append_varint(buf, int(value))
+
+    @classmethod
+    def append_double(cls, buf, number, value):
+        if value:
+            cls.append_tag(buf, number, cls.fixed64)
+            buf += struct.pack('<d', value)
+
+    @classmethod
+    def append_bytes(cls, buf, number, value):
+        if value:
+            cls.append_message(buf, number, value.encode('utf-8') if isinstance(value, str) else value)
+
+    @classmethod
+    def append_message(cls, buf, number, message):
+
+        # empty messages are appended too, since they may be set members of a oneof
+        cls.append_tag(buf, number, cls.length_delimited)
+        cls.append_varint(buf, len(message))
+        buf += message
+
+    @classmethod
+    def append_value_map(cls, buf, number, values):
+        for key, value in sorted((values or {}).items()):
+            entry = bytearray()
+            cls.append_bytes(entry, 1, key)
+            cls.append_message(entry, 2, cls._encode_value(value))
+            cls.append_message(buf, number, entry)
+
+    @classmethod
+    def consume_fields(cls, data):
+        """
+        Yields the number and value of every field. Varints are yielded as ints, and other fields as their bytes
+        """
+        offset = 0
+        while offset < len(data):
+            tag, offset = cls._consume_varint(data, offset)
+            number, wire_type = tag >> 3, tag & 0x7
+
+            if wire_type == cls.varint:
+                value, offset = cls._consume_varint(data, offset)
+            elif wire_type in (cls.fixed64, cls.fixed32, cls.length_delimited):
+                if wire_type == cls.length_delimited:
+                    length, offset = cls._consume_varint(data, offset)
+                else:
+                    length = 8 if wire_type == cls.fixed64 else 4
+
+                if offset + length > len(data):
+                    raise ValueError('Field {0} exceeds the message'.format(number))
+
+                value, offset = bytes(data[offset:offset + length]), offset + length
+            else:
+                raise ValueError('Unsupported wire type {0} of field {1}'.format(wire_type, number))
+
+            yield number, value
+
+    @classmethod
+    def _consume_varint(cls, data, offset):
+        value = 0
+        shift = 0
+        while True:
+            if offset >= len(data):
+                raise ValueError('Truncated varint')
+
+            byte = data[offset]
+            offset += 1
+            value |= (byte & 0x7F) << shift
+            if not byte & 0x80:
+                return value, offset
+
+            shift += 7
+
+    @classmethod
+    def _to_int64(cls, value):
+        return value - (1 << 64) if value >= (1 << 63) else value
+
+    @classmethod
+    def _encode_result(cls, response):
+        body = response.get('body') or ''
+        if response.get('body_encoding') == 'base64':
+            body = base64.b64decode(body)
+        elif not isinstance(body, (bytes, bytearray)):
+            body = str(body).encode('utf-8')
+
+        buf = bytearray()
+        cls.append_int(buf, 1, response.get('status_code'))
+        cls.append_bytes(buf, 2, response.get('content_type'))
+        cls.append_bytes(buf, 3, body)
+        cls.append_value_map(buf, 4, response.get('headers'))
+        cls.append_bytes(buf, 5, response.get('event_id'))
+        cls.append_bytes(buf, 6, response.get('request_id'))
+
+        return bytes(buf)
+
+    @classmethod
+    def _encode_value(cls, value):
+        buf = bytearray()
+        if value is None:
+            pass
+        elif isinstance(value, str):
+            cls.append_message(buf, 1, value.encode('utf-8'))
+        elif isinstance(value, bool):
+            cls.append_tag(buf, 4, cls.varint)
+            cls.append_varint(buf, int(value))
+        elif isinstance(value, int) and -(1 << 63) <= value < (1 << 63):
+            cls.append_tag(buf, 2, cls.varint)
+            cls.append_varint(buf, value)
+        elif isinstance(value, float):
+            cls.append_tag(buf, 3, cls.fixed64)
+            buf += struct.pack('<d', value)
+        elif isinstance(value, (bytes, bytearray)):
+            cls.append_message(buf, 5, value)
+        else:
+
+            # anything else (e.g. lists and dicts) is encoded as JSON
+            cls.append_message(buf, 6, nuclio_sdk.json_encoder.Encoder().encode(value).encode('utf-8'))
+
+        return bytes(buf)
+
+    @classmethod
+    def _decode_value(cls, data):
+        decoded_value = None
+        for number, value in cls.consume_fields(data):
+            if number == 1:
+                decoded_value = value.decode('utf-8')
+            elif number == 2:
+                decoded_value = cls._to_int64(value)
+            elif number == 3:
+                decoded_value = struct.unpack('<d', value)[0]
+            elif number == 4:
+                decoded_value = bool(value)
+            elif number == 5:
+                decoded_value = value
+            elif number == 6:
+                decoded_value = json.loads(value)
+
+        return decoded_value
+
+    @classmethod
+    def _decode_map_entry(cls, data):
+        key, value = '', b''
+        for number, field_value in cls.consume_fields(data):
+            if number == 1:
+                key = field_value.decode('utf-8')
+            elif number == 2:
+                value = field_value
+
+        return key, value
+
+    @classmethod
+    def _decode_event(cls, data):
+        event = {
+            'id': '',
+            'content_type': '',
+            'trigger': {'kind': '', 'name': ''},
+            'fields': {},
+            'headers': {},
+            'method': '',
+            'path': '',
+            'url': '',
+            'timestamp': 0,
+            'shard_id': 0,
+            'num_shards': 0,
+            'type': '',
+            'type_version': '',
+            'version': '',
+            'offset': 0,
+            'topic': '',
+            'body': b'',
+        }
+
+        string_fields = {1: 'id', 2: 'content_type', 6: 'method', 7: 'path', 8: 'url', 12: 'type',
+                         13: 'type_version', 14: 'version', 16: 'topic', 19: 'request_id'}
+        int_fields = {9: 'timestamp', 10: 'shard_id', 11: 'num_shards', 15: 'offset'}
+
+        for number, value in cls.consume_fields(data):
+            if number in string_fields:
+                event[string_fields[number]] = value.decode('utf-8')
+            elif number in int_fields:
+                event[int_fields[number]] = cls._to_int64(value)
+            elif number == 3:
+                for trigger_number, trigger_value in cls.consume_fields(value):
+                    if trigger_number in (1, 2):
+                        event['trigger']['kind' if trigger_number == 1 else 'name'] = trigger_value.decode('utf-8')
+            elif number in (4, 5):
+                key, encoded_value = cls._decode_map_entry(value)
+                event['fields' if number == 4 else 'headers'][key] = cls._decode_value(encoded_value)
+            elif number == 17:
+                event['body'] = value
+            elif number == 18:
+                event['body'] = json.loads(value)
+
+        # same as the events the processor encodes with msgpack
+        event['content-type'] = event['content_type']
+        event['size'] = len(event['body']) if isinstance(event['body'], bytes) else 0
+
+        return event
+
+    @classmethod
+    def _decode_control_message(cls, data):
+        control_message = {'kind': '', 'attributes': {}}
+        for number, value in cls.consume_fields(data):
+            if number == 1:
+                control_message['kind'] = value.decode('utf-8')
+            elif number == 2:
+                key, encoded_value = cls._decode_map_entry(value)
+                control_message['attributes'][key] = cls._decode_value(encoded_value)
+
+        return control_message
+
+    @classmethod
+    def _decode_data_binding_response(cls, data):
+        response = {'kind': '', 'id': '', 'partition': 0, 'offset': 0, 'value': b'', 'objects': [], 'url': '',
+                    'error': ''}
+        string_fields = {1: 'kind', 2: 'id', 7: 'url', 8: 'error'}
+
+        for number, value in cls.consume_fields(data):
+            if number in string_fields:
+                response[string_fields[number]] = value.decode('utf-8')
+            elif number in (3, 4):
+                response['partition' if number == 3 else 'offset'] = cls._to_int64(value)
+            elif number == 5:
+                response['value'] = value
+            elif number == 6:
+                response['objects'].append(cls._decode_object_info(value))
+
+        return response
+
+    @classmethod
+    def _decode_object_info(cls, data):
+        object_info = {'key': '', 'size': 0, 'lastModified': None, 'etag': ''}
+        for number, value in cls.consume_fields(data):
+            if number == 1:
+                object_info['key'] = value.decode('utf-8')
+            elif number == 2:
+                object_info['size'] = cls._to_int64(value)
+            elif number == 3:
+                object_info['lastModified'] = datetime.datetime.fromtimestamp(cls._to_int64(value) / 1e9,
+                                                                              tz=datetime.timezone.utc)
+            elif number == 4:
+                object_info['etag'] = value.decode('utf-8')
+
+        return object_info
+
+
+class EventSocketWriter(object):
+    """
+    Writes messages on the event socket - as lines starting with their type or, once the processor agreed,
+    as frames holding their type and length, so that payloads may hold anything
+    """
+
+    def __init__(self, sock):
+        self._file = sock.makefile('wb')
+        self.frames = False
+        self.protobuf = False
+
+    def write(self, line):
+        """
+        Write a message formatted as a line (e.g. a log record), starting with its type
+        """
+        line = line.rstrip('\n')
+        payload = line[1:]
+
+        # log records are formatted as JSON, tagged with the request of the event they were logged while
+        # handling, and converted once the processor agreed on protobuf
+        request_id = request_id_context.get()
+        if line[0] == 'l' and (self.protobuf or request_id):
+            record = json.loads(payload)
+            if request_id:
+                record['request_id'] = request_id
+
+            payload = Protobuf.encode_log_record(record) if self.protobuf else json.dumps(record)
+
+        self._file.write(self.encode(line[0], payload))
+
+    def flush(self):
+        self._file.flush()
+
+    def encode(self, message_type, payload):
+        if isinstance(payload, str):
+            payload = payload.encode('utf-8')
+
+        if self.frames:
+            return struct.pack(Constants.frame_header_format, message_type.encode('ascii'), len(payload)) + payload
+
+        return message_type.encode('ascii') + payload + b'\n'
+
+
+class DataBindingError(Exception):
+    """
+    Raised when the processor fails to send a message through a data binding
+    """
+    pass
+
+
+class DataBinding(object):
+    """
+    Proxies operations on one of the function's data bindings (e.g. kafka, s3) to the processor, which holds the
+    connections shared by all workers. Operations the data binding's kind doesn't support raise DataBindingError
+    """
+
+    def __init__(self, name, send_request):
+        self.name = name
+        self._send_request = send_request
+
+    async def send(self, value, key=None, topic=None, headers=None):
+        """
+        Send a message and wait for it to be acknowledged. Returns the (partition, offset) it was written to
+        """
+        response = await self._send_request(self.name, 'send', key=key, value=value, topic=topic, headers=headers)
+        return response['partition'], response['offset']
+
+    async def send_async(self, value, key=None, topic=None, headers=None):
+        """
+        Queue a message for sending, without waiting for it to be acknowledged
+        """
+        await self._send_request(self.name,
+                                 'send',
+                                 key=key,
+                                 value=value,
+                                 topic=topic,
+                                 headers=headers,
+                                 is_async=True)
+
+    async def get(self, key):
+        """
+        Return the contents of an object
+        """
+        response = await self._send_request(self.name, 'get', key=key)
+        return response.get('value') or b''
+
+    async def put(self, key, value, content_type=None):
+        """
+        Create or replace an object
+        """
+        await self._send_request(self.name, 'put', key=key, value=value, content_type=content_type)
+
+    async def list(self, prefix=''):
+        """
+        Return the objects whose keys start with prefix, as dicts of key, size, lastModified and etag
+        """
+        response = await self._send_request(self.name, 'list', prefix=prefix)
+        objects = response.get('objects') or []
+        for obj in objects:
+            if hasattr(obj.get('lastModified'), 'to_datetime'):
+                obj['lastModified'] = obj['lastModified'].to_datetime()
+
+        return objects
+
+    async def presign(self, key, method='GET', expiration_seconds=0):
+        """
+        Return a URL through which the object can be accessed without credentials until it expires
+        """
+        response = await self._send_request(self.name,
+                                            'presign',
+                                            key=key,
+                                            method=method,
+                                            expiration_seconds=expiration_seconds)
+        return response['url']
+
+
+class FunctionInvocationError(Exception):
+    """
+    Raised when the processor fails to call a function
+    """
+    pass
+
+
+class FunctionInvoker(object):
+    """
+    Calls other functions through the processor, which retries failed calls and propagates the deadline and
+    correlation ID of the event the call is made on behalf of
+    """
+
+    def __init__(self, send_request):
+        self._send_request = send_request
+
+    async def invoke(self,
+                     function_name,
+                     body=None,
+                     method='POST',
+                     path='/',
+                     headers=None,
+                     content_type=None,
+                     parent_event=None,
+                     timeout=None,
+                     retries=0,
+                     retry_backoff=None,
+                     correlation_id=None):
+        """
+        Call a function and wait for its response. Returns a nuclio_sdk.Response and the call's correlation ID.
+        timeout and retry_backoff are in seconds, and a negative retries disables retries
+        """
+        result = await self._send_request(function_name,
+                                          body=body,
+                                          method=method,
+                                          path=path,
+                                          headers=headers,
+                                          content_type=content_type,
+                                          parent_event=parent_event,
+                                          timeout=timeout,
+                                          retries=retries,
+                                          retry_backoff=retry_backoff,
+                                          correlation_id=correlation_id)
+
+        response = nuclio_sdk.Response(headers=result.get('headers') or {},
+                                       body=base64.b64decode(result.get('body') or ''),
+                                       content_type=result.get('contentType'),
+                                       status_code=result.get('statusCode'))
+
+        return response, result.get('correlationId')
+
+    async def invoke_async(self,
+                           function_name,
+                           body=None,
+                           method='POST',
+                           path='/',
+                           headers=None,
+                           content_type=None,
+                           parent_event=None,
+                           timeout=None,
+                           retries=0,
+                           retry_backoff=None,
+                           correlation_id=None):
+        """
+        Queue a call to a function, without waiting for its response. Returns the call's correlation ID
+        """
+        result = await self._send_request(function_name,
+                                          body=body,
+                                          method=method,
+                                          path=path,
+                                          headers=headers,
+                                          content_type=content_type,
+                                          parent_event=parent_event,
+                                          timeout=timeout,
+                                          retries=retries,
+                                          retry_backoff=retry_backoff,
+                                          correlation_id=correlation_id,
+                                          is_async=True)
+
+        return result.get('correlationId')
+
+
 class Wrapper(object):
     def __init__(self,
                  logger,
@@ -92,7 +654,7 @@ class Wrapper(object):
         self._control_sock = self._connect_to_processor(self._control_socket_path)
 
         # make a writeable file from processor
-        self._event_sock_wfile = self._event_sock.makefile('w')
+        self._event_writer = EventSocketWriter(self._event_sock)
         self._control_sock_wfile = self._control_sock.makefile('w')
 
         # set socket to nonblocking to allow the asyncio event loop to run while we're waiting on a socket, and so
@@ -118,8 +680,34 @@ class Wrapper(object):
                                            worker_id,
                                            nuclio_sdk.TriggerInfo(trigger_kind, trigger_name))
 
+        # expose the data bindings the processor created for this function
+        self._data_binding_request_id = 0
+        self._context.data_binding = {
+            name: DataBinding(name, self._send_data_binding_request)
+            for name in filter(None, os.environ.get('NUCLIO_DATA_BINDINGS', '').split(','))
+        }
+
+        # calls to other functions are answered on the control socket, which is read once the first call is made
+        self._function_invocation_request_id = 0
+        self._pending_function_invocations = {}
+        self._control_messages_task = None
+        self._context.functions = FunctionInvoker(self._send_function_invocation_request)
+
         # replace the default output with the process socket
-        self._logger.set_handler('default', self._event_sock_wfile, JSONFormatterOverSocket())
+        self._logger.set_handler('default', self._event_writer, JSONFormatterOverSocket())
+
+        # the newest protocol version the processor speaks
+        self._protocol_version = self._resolve_protocol_version()
+
+        # the encoding the processor offers for messages on the event socket, if any (e.g. protobuf)
+        self._encoding = os.environ.get('NUCLIO_RPC_ENCODING', '')
+
+        # the most events the processor may send before their replies. these are handled concurrently, and
+        # the responses to their data binding requests are read by the serving loop
+        self._max_concurrent_events = self._resolve_max_concurrent_events()
+        self._pending_data_binding_requests = {}
+        self._requests_in_flight = set()
+        self._write_lock = asyncio.Lock()
 
         # initialize flags
         self._is_drain_needed = False
@@ -142,20 +730,17 @@ class Wrapper(object):
                 self._event_message_length_task = None
 
                 # resolve event message
-                event = await self._resolve_event(self._event_sock, event_message_length)
-
-                # do not handle an event if a worker is drained
-                if not self._discard_events:
-                    try:
-                        # handle event
-                        await self._handle_event(event)
-                    except BaseException as exc:
-                        await self._on_handle_event_error(exc)
+                event_message = await self._resolve_event_message(self._event_sock, event_message_length)
+
+                # while events are handled concurrently, the responses to their data binding requests are
+                # read here rather than by the events waiting for them
+                if self._is_data_binding_response(event_message):
+                    self._resolve_data_binding_response(event_message)
                 else:
-                    self._logger.debug_with('Event has been discarded', event=event)
+                    await self._serve_event_message(event_message)
 
                 # allow event to be garbage collected by deleting the reference
-                del event
+                del event_message
 
             except WrapperFatalException as exc:
                 await self._on_serving_error(exc)
@@ -191,8 +776,27 @@ class Wrapper(object):
             if num_requests is not None:
                 num_requests -= 1
                 if num_requests <= 0:
+                    await asyncio.gather(*self._requests_in_flight)
                     break
 
+    async def _serve_event_message(self, event_message):
+        request_id = self._resolve_request_id(event_message)
+        event = self._deserialize_event(event_message)
+
+        # do not handle an event if a worker is drained
+        if self._discard_events:
+            self._logger.debug_with('Event has been discarded', event=event)
+            return
+
+        if self._max_concurrent_events <= 1:
+            await self._handle_request(event, request_id)
+            return
+
+        # the processor sends no more events than negotiated, so these needn't be bounded here
+        request = asyncio.create_task(self._handle_request(event, request_id))
+        self._requests_in_flight.add(request)
+        request.add_done_callback(self._requests_in_flight.discard)
+
     async def initialize(self):
 
         # call init_context
@@ -201,20 +805,55 @@ class Wrapper(object):
         # register to the SIGUSR1 and SIGUSR2 signals, used to signal termination/draining respectively
         self._register_to_signal()
 
+        # frames let responses hold raw bodies. everything following a version 2 handshake is sent as frames
+        if self._protocol_version >= Constants.protocol_version_frames or self._max_concurrent_events > 1:
+            handshake = {'version': min(self._protocol_version, Constants.protocol_version_frames)}
+            if self._encoding == 'protobuf' and handshake['version'] >= Constants.protocol_version_frames:
+                handshake['encoding'] = 'protobuf'
+            if self._max_concurrent_events > 1:
+                handshake['max_concurrent_events'] = self._max_concurrent_events
+
+            await self._write_message('p', json.dumps(handshake))
+            self._event_writer.frames = handshake['version'] >= Constants.protocol_version_frames
+            self._event_writer.protobuf = 'encoding' in handshake
+
         # indicate that we're ready
-        await self._write_packet_to_processor(self._event_sock, 's')
+        await self._write_message('s', '')
         await self._send_data_on_control_socket({
             'kind': 'wrapperInitialized',
             'attributes': {'ready': 'true'}
         })
 
     async def receive_control_messages(self):
+        """Read control messages from the processor, and resolve the function calls they answer"""
 
-        control_message_event_length = await self._resolve_event_message_length(self._control_sock)
+        try:
+            while True:
+                control_message_event_length = await self._resolve_event_message_length(self._control_sock)
+                control_message_event = msgpack.unpackb(await self._read_from_socket(self._control_sock,
+                                                                                     control_message_event_length),
+                                                        raw=False)
+
+                # the control message is the event's body
+                control_message = json.loads(control_message_event.get('body') or '{}')
+
+                self._logger.debug_with('Received control message', kind=control_message.get('kind'))
+
+                if control_message.get('kind') == 'invokeFunctionResult':
+                    attributes = control_message.get('attributes') or {}
+                    future = self._pending_function_invocations.pop(attributes.get('id'), None)
+                    if future is not None and not future.done():
+                        future.set_result(attributes)
+
+        except Exception as exc:
+            self._logger.warn_with('Stopped receiving control messages', exc=str(exc))
 
-        control_message_event = await self._resolve_event(self._control_sock, control_message_event_length)
+            # nothing will answer the calls in flight
+            for future in self._pending_function_invocations.values():
+                if not future.done():
+                    future.set_exception(FunctionInvocationError('Control connection failed: {0}'.format(exc)))
 
-        self._logger.debug_with('Received control message', control_message=control_message_event.body)
+            self._pending_function_invocations.clear()
 
     async def _initialize_context(self):
 
@@ -299,6 +938,141 @@ class Wrapper(object):
 
         # TODO: wait for response that processor received data
 
+    async def _send_data_binding_request(self,
+                                         name,
+                                         operation,
+                                         key=None,
+                                         value=None,
+                                         topic=None,
+                                         headers=None,
+                                         prefix=None,
+                                         content_type=None,
+                                         method=None,
+                                         expiration_seconds=0,
+                                         is_async=False):
+        self._data_binding_request_id += 1
+        request = {
+            'id': str(self._data_binding_request_id),
+            'request_id': request_id_context.get(),
+            'name': name,
+            'operation': operation,
+            'topic': topic or '',
+            'key': self._encode_data_binding_payload(key),
+            'value': self._encode_data_binding_payload(value),
+            'headers': headers or {},
+            'async': is_async,
+            'prefix': prefix or '',
+            'content_type': content_type or '',
+            'method': method or '',
+            'expiration_seconds': int(expiration_seconds),
+        }
+
+        # the processor writes the response on the event socket. while events are handled concurrently, the
+        # serving loop reads it, so it's waited for before the request may be answered
+        pending_response = None
+        if self._max_concurrent_events > 1 and not is_async:
+            pending_response = self._loop.create_future()
+            self._pending_data_binding_requests[request['id']] = pending_response
+
+        if self._event_writer.protobuf:
+            await self._write_message('d', Protobuf.encode_data_binding_request(request))
+        else:
+            await self._write_message('d', json.dumps(request))
+
+        if is_async:
+            return None
+
+        if pending_response is not None:
+            response = await pending_response
+        else:
+
+            # otherwise the event socket isn't read from while handling an event
+            response_length = await self._resolve_event_message_length(self._event_sock)
+            encoded_response = await self._read_from_socket(self._event_sock, response_length)
+            if self._event_writer.protobuf:
+                response = Protobuf.decode_processor_message(encoded_response)
+            else:
+                response = msgpack.unpackb(encoded_response, raw=False)
+
+        if response.get('error'):
+            raise DataBindingError('Failed to {0} through data binding {1}: {2}'.format(operation,
+                                                                                        name,
+                                                                                        response['error']))
+
+        return response
+
+    async def _send_function_invocation_request(self,
+                                                function_name,
+                                                body=None,
+                                                method=None,
+                                                path=None,
+                                                headers=None,
+                                                content_type=None,
+                                                parent_event=None,
+                                                timeout=None,
+                                                retries=0,
+                                                retry_backoff=None,
+                                                correlation_id=None,
+                                                is_async=False):
+        if self._control_messages_task is None or self._control_messages_task.done():
+            self._control_messages_task = asyncio.create_task(self.receive_control_messages())
+
+        self._function_invocation_request_id += 1
+        request_id = str(self._function_invocation_request_id)
+
+        # the call inherits the deadline and correlation ID of the event it's made on behalf of
+        parent_headers = getattr(parent_event, 'headers', None) or {}
+
+        future = self._loop.create_future()
+        self._pending_function_invocations[request_id] = future
+
+        await self._send_data_on_control_socket({
+            'kind': 'invokeFunction',
+            'attributes': {
+                'id': request_id,
+                'functionName': function_name,
+                'method': method or '',
+                'path': path or '',
+                'headers': headers or {},
+                'contentType': content_type or '',
+                'body': self._encode_data_binding_payload(body) or '',
+                'async': is_async,
+                'correlationId': correlation_id or parent_headers.get('X-Nuclio-Correlation-Id', ''),
+                'deadline': parent_headers.get('X-Nuclio-Deadline', ''),
+                'timeout': float(timeout or 0),
+                'retries': int(retries),
+                'retryBackoff': float(retry_backoff or 0),
+            },
+        })
+
+        result = await future
+        if result.get('error'):
+            raise FunctionInvocationError('Failed to call function {0}: {1}'.format(function_name, result['error']))
+
+        return result
+
+    def _encode_data_binding_payload(self, payload):
+        if payload is None:
+            return None
+
+        if isinstance(payload, str):
+            payload = payload.encode('utf-8')
+        elif not isinstance(payload, (bytes, bytearray)):
+            payload = self._json_encoder.encode(payload).encode('utf-8')
+
+        return base64.b64encode(payload).decode('ascii')
+
+    async def _read_from_socket(self, sock, expected_bytes_length):
+        message = bytearray()
+        while len(message) < expected_bytes_length:
+            bytes_read = await self._loop.sock_recv(sock, expected_bytes_length - len(message))
+            if not bytes_read:
+                raise WrapperFatalException('Client disconnected')
+
+            message += bytes_read
+
+        return bytes(message)
+
     def _resolve_unpacker(self):
         """
         Since this wrapper is behind the nuclio processor, in which pre-handle the traffic & request
@@ -388,24 +1162,67 @@ class Wrapper(object):
         """
         Reading the expected event length from socket and instantiate an event message
         """
+        return self._deserialize_event(await self._resolve_event_message(sock, expected_event_bytes_length))
 
-        cumulative_bytes_read = 0
-        while cumulative_bytes_read < expected_event_bytes_length:
-            bytes_to_read_now = expected_event_bytes_length - cumulative_bytes_read
-            bytes_read = await self._loop.sock_recv(sock, bytes_to_read_now)
-
-            if not bytes_read:
-                raise WrapperFatalException('Client disconnected')
-
-            self._unpacker.feed(bytes_read)
-            cumulative_bytes_read += len(bytes_read)
+    async def _resolve_event_message(self, sock, expected_event_bytes_length):
+        """
+        Reading the expected message length from socket - an event, the events of a batch or, while events are
+        handled concurrently, a response to a data binding request
+        """
+        encoded_message = await self._read_from_socket(sock, expected_event_bytes_length)
+        if self._event_writer.protobuf:
+            return Protobuf.decode_processor_message(encoded_message)
 
         # resolve msgpack event message
+        self._unpacker.feed(encoded_message)
         event_message = next(self._unpacker)
 
-        # instantiate event message
+        # data binding responses are decoded with their strings decoded, even if events aren't
+        if isinstance(event_message, dict) and event_message.get(b'kind') == b'dataBindingResponse':
+            return msgpack.unpackb(encoded_message, raw=False)
+
+        return event_message
+
+    def _deserialize_event(self, event_message):
+        """
+        Instantiate an event message
+        """
+        if self._event_writer.protobuf:
+
+            # events are decoded with their strings decoded, as msgpack does when asked to
+            if isinstance(event_message, list):
+                return [nuclio_sdk.Event.deserialize(message, kind=nuclio_sdk.event.EventDeserializerKinds.msgpack)
+                        for message in event_message]
+
+            return nuclio_sdk.Event.deserialize(event_message, kind=nuclio_sdk.event.EventDeserializerKinds.msgpack)
+
         return nuclio_sdk.Event.deserialize(event_message, kind=self._event_deserializer_kind)
 
+    def _is_data_binding_response(self, event_message):
+        return isinstance(event_message, dict) and event_message.get('kind') == 'dataBindingResponse'
+
+    def _resolve_data_binding_response(self, response):
+        pending_response = self._pending_data_binding_requests.pop(response.get('id'), None)
+        if pending_response is None or pending_response.done():
+            self._logger.warn_with('Received a response to an unknown data binding request', id=response.get('id'))
+            return
+
+        pending_response.set_result(response)
+
+    def _resolve_request_id(self, event_message):
+        """
+        Returns the ID of the request the processor sent the event with, or an empty string if it waits for
+        the reply of each event before sending the next one. Events of a batch are sent with the same ID
+        """
+        if isinstance(event_message, list):
+            event_message = event_message[0] if event_message else {}
+
+        request_id = event_message.get('request_id', event_message.get(b'request_id')) or ''
+        if isinstance(request_id, bytes):
+            request_id = request_id.decode('utf-8')
+
+        return request_id
+
     async def _on_serving_error(self, exc):
         await self._log_and_response_error(exc, 'Exception caught while serving')
 
@@ -421,19 +1238,36 @@ class Wrapper(object):
 
     async def _write_response_error(self, body):
         try:
-            encoded_response = self._json_encoder.encode({
+            response = self._tag_response({
                 'body': body,
                 'body_encoding': 'text',
                 'content_type': 'text/plain',
                 'status_code': 500,
             })
 
+            if self._event_writer.protobuf:
+                response_payload = Protobuf.encode_results([response])
+            else:
+                response_payload = self._encode_response_payload(self._json_encoder.encode(response))
+
             # try write the formatted exception back to processor
-            await self._write_packet_to_processor(self._event_sock, 'r' + encoded_response)
+            await self._write_message('r', response_payload)
         except Exception as exc:
             print('Failed to write message to processor after serving error detected, is socket open?\n'
                   'Exception: {0}'.format(str(exc)))
 
+    async def _handle_request(self, event, request_id):
+        """
+        Handle an event, replying with an error if its handler fails. Everything written while handling it is
+        tagged with the ID of the request it was sent with
+        """
+        request_id_context.set(request_id)
+
+        try:
+            await self._handle_event(event)
+        except BaseException as exc:
+            await self._on_handle_event_error(exc)
+
     async def _handle_event(self, event):
 
         # take call time
@@ -447,26 +1281,111 @@ class Wrapper(object):
         # measure duration, set to minimum float in case execution was too fast
         duration = time.time() - start_time or sys.float_info.min
 
-        await self._write_packet_to_processor(self._event_sock, 'm' + json.dumps({'duration': duration}))
+        if self._event_writer.protobuf:
+            await self._write_message('m', Protobuf.encode_metric(duration))
+        else:
+            await self._write_message('m', json.dumps({'duration': duration}))
 
-        # try to json encode the response
-        encoded_response = self._encode_entrypoint_output(entrypoint_output)
+        # try to encode the response
+        if self._event_writer.protobuf:
+            response_payload = self._encode_protobuf_entrypoint_output(entrypoint_output)
+        elif self._event_writer.frames:
+            response_payload = self._encode_framed_entrypoint_output(entrypoint_output)
+        else:
+            response_payload = self._encode_entrypoint_output(entrypoint_output)
 
         # write response to the socket
-        await self._write_packet_to_processor(self._event_sock, 'r' + encoded_response)
+        await self._write_message('r', response_payload)
 
     def _encode_entrypoint_output(self, entrypoint_output):
 
         # processing entrypoint output if response is batched
         if isinstance(entrypoint_output, list):
-            response = [nuclio_sdk.Response.from_entrypoint_output(self._json_encoder.encode,
-                                                                   _output) for _output in entrypoint_output]
+            response = [self._tag_response(nuclio_sdk.Response.from_entrypoint_output(self._json_encoder.encode,
+                                                                                      _output))
+                        for _output in entrypoint_output]
         else:
-            response = nuclio_sdk.Response.from_entrypoint_output(self._json_encoder.encode, entrypoint_output)
+            response = self._tag_response(nuclio_sdk.Response.from_entrypoint_output(self._json_encoder.encode,
+                                                                                     entrypoint_output))
 
         # try to json encode the response
         return self._json_encoder.encode(response)
 
+    def _encode_framed_entrypoint_output(self, entrypoint_output):
+        """
+        Encode the entrypoint output as the payload of a framed response - the length of the response JSON,
+        the response JSON and then the bodies of the responses whose body_encoding is raw, in order
+        """
+        if not isinstance(entrypoint_output, list):
+            entrypoint_outputs = [entrypoint_output]
+        else:
+            entrypoint_outputs = entrypoint_output
+
+        responses = [self._tag_response(nuclio_sdk.Response.from_entrypoint_output(self._json_encoder.encode,
+                                                                                   _output))
+                     for _output in entrypoint_outputs]
+
+        # binary bodies are sent as is, rather than base64 encoded
+        raw_bodies = []
+        for response in responses:
+            if response.get('body_encoding') == 'base64':
+                raw_body = base64.b64decode(response['body'])
+                response.update(body='', body_encoding='raw', body_length=len(raw_body))
+                raw_bodies.append(raw_body)
+
+        if not isinstance(entrypoint_output, list):
+            responses = responses[0]
+
+        return self._encode_response_payload(self._json_encoder.encode(responses), raw_bodies)
+
+    def _encode_protobuf_entrypoint_output(self, entrypoint_output):
+        if not isinstance(entrypoint_output, list):
+            entrypoint_output = [entrypoint_output]
+
+        return Protobuf.encode_results([
+            self._tag_response(nuclio_sdk.Response.from_entrypoint_output(self._json_encoder.encode, _output))
+            for _output in entrypoint_output
+        ])
+
+    def _tag_response(self, response):
+        """
+        Responses hold the ID of the request they reply to, so that they may be sent in any order
+        """
+        request_id = request_id_context.get()
+        if request_id:
+            response['request_id'] = request_id
+
+        return response
+
+    def _encode_response_payload(self, encoded_response, raw_bodies=None):
+        """
+        Framed responses are prefixed by the length of their JSON, and followed by their raw bodies
+        """
+        if not self._event_writer.frames:
+            return encoded_response
+
+        encoded_response = encoded_response.encode('utf-8')
+
+        return struct.pack('>I', len(encoded_response)) + encoded_response + b''.join(raw_bodies or [])
+
+    async def _write_message(self, message_type, payload):
+
+        # messages of events handled concurrently may not interleave
+        async with self._write_lock:
+            await self._loop.sock_sendall(self._event_sock, self._event_writer.encode(message_type, payload))
+
+    def _resolve_protocol_version(self):
+        try:
+            return int(os.environ.get('NUCLIO_RPC_PROTOCOL_VERSION') or Constants.protocol_version_lines)
+        except ValueError:
+            return Constants.protocol_version_lines
+
+    def _resolve_max_concurrent_events(self):
+        try:
+            return max(int(os.environ.get('NUCLIO_RPC_MAX_CONCURRENT_EVENTS') or 1), 1)
+        except ValueError:
+            return 1
+
     def _shutdown(self, error_code=0):
         print('Shutting down')
         try:
@@ -12,6 +12,7 @@
 # See the License for the specific language governing permissions and
 # limitations under the License.
 import asyncio
+import base64
 import functools
 import http.client
 import json
@@ -34,7 +35,7 @@ import nuclio_sdk.helpers
 import _nuclio_wrapper as wrapper
 
 
-class TestSubmitEvents(unittest.TestCase):
+class _WrapperTestCase(unittest.TestCase):
 
     @classmethod
     def setUpClass(cls):
@@ -99,6 +100,92 @@ class TestSubmitEvents(unittest.TestCase):
             unix_stream_server.shutdown()
             unix_stream_server_thread.join()
 
+    def _send_events(self, events):
+        self._wait_for_socket_creation()
+        for event in events:
+            self._send_event(event)
+
+    def _send_event(self, event):
+        if not isinstance(event, dict):
+            event = self._event_to_dict(event)
+
+        # event to a msgpack body message
+        body = msgpack.Packer().pack(event)
+
+        # big endian body len
+        body_len = struct.pack(">I", len(body))
+
+        # first write body length
+        self._unix_stream_server._connection_socket.sendall(body_len)
+
+        # then write body content
+        self._unix_stream_server._connection_socket.sendall(body)
+
+    def _get_packed_event_body_len(self, event):
+        return len(msgpack.Packer().pack(self._event_to_dict(event)))
+
+    def _event_to_dict(self, event):
+        return json.loads(event.to_json())
+
+    def _wait_for_socket_creation(self, timeout=10, interval=0.1):
+
+        # wait for socket connection
+        while self._unix_stream_server._connection_socket is None and timeout > 0:
+            time.sleep(interval)
+            timeout -= interval
+
+    def _wait_until_received_messages(self, minimum_messages_length, timeout=10, interval=1):
+        while timeout > 0:
+            time.sleep(interval)
+            current_messages_length = len(self._unix_stream_server._messages)
+            if current_messages_length >= minimum_messages_length:
+                return
+            self._logger.debug_with('Waiting for messages to arrive',
+                                    current_messages_length=current_messages_length,
+                                    minimum_messages_length=minimum_messages_length)
+            timeout -= interval
+        raise RuntimeError('Failed waiting for messages')
+
+    def _create_unix_stream_server(self, socket_path):
+        unix_stream_server = _SingleConnectionUnixStreamServer(socket_path, _Connection)
+
+        # create a thread and listen forever on server
+        unix_stream_server_thread = threading.Thread(target=unix_stream_server.serve_forever)
+        unix_stream_server_thread.daemon = True
+        unix_stream_server_thread.start()
+        return unix_stream_server, unix_stream_server_thread
+
+    def _ensure_str(self, s, encoding='utf-8', errors='strict'):
+
+        # Optimization: Fast return for the common case.
+        if type(s) is str:
+            return s
+        if isinstance(s, bytes):
+            return s.decode(encoding, errors)
+        raise TypeError(f"not expecting type '{type(s)}'")
+
+    def _write_handler(self, temp_path):
+        handler_code = '''import sys
+
+def handler(ctx, event):
+    """Return reversed body as string"""
+    body = event.body
+    if isinstance(event.body, bytes):
+        body = event.body.decode('utf-8')
+    ctx.logger.warn('the end is nigh')
+    return body[::-1]
+'''
+
+        handler_path = os.path.join(temp_path, 'reverser.py')
+
+        with open(handler_path, 'w') as out:
+            out.write(handler_code)
+
+        return handler_path
+
+
+class TestSubmitEvents(_WrapperTestCase):
+
     def test_async_handler(self):
         """Test function decorated with async and running an event loop"""
 
@@ -338,95 +425,262 @@ class TestSubmitEvents(unittest.TestCase):
     #         profiled_serve_requests_func(num_requests=num_of_events)
     #     self.assertEqual(num_of_events, self._wrapper._entrypoint.call_count, 'Received unexpected number of events')
 
-    def _send_events(self, events):
-        self._wait_for_socket_creation()
-        for event in events:
-            self._send_event(event)
 
-    def _send_event(self, event):
-        if not isinstance(event, dict):
-            event = self._event_to_dict(event)
+class TestSubmitEventsDecoded(TestSubmitEvents):
+    @classmethod
+    def setUpClass(cls):
+        super(TestSubmitEventsDecoded, cls).setUpClass()
+        cls._decode_incoming_event_messages = True
 
-        # event to a msgpack body message
-        body = msgpack.Packer().pack(event)
 
-        # big endian body len
-        body_len = struct.pack(">I", len(body))
+class TestSubmitEventsFramed(TestSubmitEvents):
+    """Same as TestSubmitEvents, with the wrapper negotiating frames"""
 
-        # first write body length
-        self._unix_stream_server._connection_socket.sendall(body_len)
+    def setUp(self):
+        os.environ['NUCLIO_RPC_PROTOCOL_VERSION'] = '2'
+        super(TestSubmitEventsFramed, self).setUp()
 
-        # then write body content
-        self._unix_stream_server._connection_socket.sendall(body)
+    def tearDown(self):
+        super(TestSubmitEventsFramed, self).tearDown()
+        del os.environ['NUCLIO_RPC_PROTOCOL_VERSION']
 
-    def _get_packed_event_body_len(self, event):
-        return len(msgpack.Packer().pack(self._event_to_dict(event)))
+    def test_handshake(self):
+        self._wait_until_received_messages(2)
+        self.assertEqual({'type': 'p', 'body': {'version': 2}}, self._unix_stream_server._messages[0])
+        self.assertEqual('s', self._unix_stream_server._messages[1]['type'])
 
-    def _event_to_dict(self, event):
-        return json.loads(event.to_json())
+    def test_raw_body(self):
+        body = b'\x00\n\xff'
 
-    def _wait_for_socket_creation(self, timeout=10, interval=0.1):
+        self._wait_for_socket_creation()
+        t = threading.Thread(target=self._send_event, args=(nuclio_sdk.Event(_id=1),))
+        t.start()
 
-        # wait for socket connection
-        while self._unix_stream_server._connection_socket is None and timeout > 0:
-            time.sleep(interval)
-            timeout -= interval
+        self._wrapper._entrypoint = lambda context, event: body
+        asyncio.get_event_loop().run_until_complete(self._wrapper.serve_requests(num_requests=1))
+        t.join()
 
-    def _wait_until_received_messages(self, minimum_messages_length, timeout=10, interval=1):
-        while timeout > 0:
-            time.sleep(interval)
-            current_messages_length = len(self._unix_stream_server._messages)
-            if current_messages_length >= minimum_messages_length:
-                return
-            self._logger.debug_with('Waiting for messages to arrive',
-                                    current_messages_length=current_messages_length,
-                                    minimum_messages_length=minimum_messages_length)
-            timeout -= interval
-        raise RuntimeError('Failed waiting for messages')
+        # handshake, processor start, duration, response
+        self._wait_until_received_messages(4)
 
-    def _create_unix_stream_server(self, socket_path):
-        unix_stream_server = _SingleConnectionUnixStreamServer(socket_path, _Connection)
+        response = next(message['body']
+                        for message in self._unix_stream_server._messages
+                        if message['type'] == 'r')
+        self.assertEqual('raw', response['body_encoding'])
+        self.assertEqual(len(body), response['body_length'])
+        self.assertEqual(body, response['raw_body'])
 
-        # create a thread and listen forever on server
-        unix_stream_server_thread = threading.Thread(target=unix_stream_server.serve_forever)
-        unix_stream_server_thread.daemon = True
-        unix_stream_server_thread.start()
-        return unix_stream_server, unix_stream_server_thread
 
-    def _ensure_str(self, s, encoding='utf-8', errors='strict'):
+class TestSubmitEventsProtobuf(_WrapperTestCase):
+    """Events and responses encoded as the messages of rpc.proto"""
 
-        # Optimization: Fast return for the common case.
-        if type(s) is str:
-            return s
-        if isinstance(s, bytes):
-            return s.decode(encoding, errors)
-        raise TypeError(f"not expecting type '{type(s)}'")
+    def setUp(self):
+        os.environ['NUCLIO_RPC_PROTOCOL_VERSION'] = '2'
+        os.environ['NUCLIO_RPC_ENCODING'] = 'protobuf'
+        super(TestSubmitEventsProtobuf, self).setUp()
 
-    def _write_handler(self, temp_path):
-        handler_code = '''import sys
+    def tearDown(self):
+        super(TestSubmitEventsProtobuf, self).tearDown()
+        del os.environ['NUCLIO_RPC_PROTOCOL_VERSION']
+        del os.environ['NUCLIO_RPC_ENCODING']
 
-def handler(ctx, event):
-    """Return reversed body as string"""
-    body = event.body
-    if isinstance(event.body, bytes):
-        body = event.body.decode('utf-8')
-    ctx.logger.warn('the end is nigh')
-    return body[::-1]
-'''
+    def test_handshake(self):
+        self._wait_until_received_messages(2)
+        self.assertEqual({'type': 'p', 'body': {'version': 2, 'encoding': 'protobuf'}},
+                         self._unix_stream_server._messages[0])
 
-        handler_path = os.path.join(temp_path, 'reverser.py')
+    def test_single_event(self):
+        recorded_events = []
 
-        with open(handler_path, 'w') as out:
-            out.write(handler_code)
+        def event_recorder(context, event):
+            recorded_events.append(event)
+            return nuclio_sdk.Response(body=event.body[::-1].decode('utf-8'),
+                                       headers={'x': 1},
+                                       content_type='text/plain',
+                                       status_code=201)
+
+        # an event with an id, an http trigger, an "h: 5" header and an "abc" body
+        encoded_event = b'\x0a\x01\x31' \
+                        b'\x1a\x06\x0a\x04http' \
+                        b'\x2a\x07\x0a\x01h\x12\x02\x10\x05' \
+                        b'\x8a\x01\x03abc'
+        processor_message = b'\x0a' + bytes([len(encoded_event)]) + encoded_event
 
-        return handler_path
+        self._wait_for_socket_creation()
+        t = threading.Thread(target=self._send_encoded_event, args=(processor_message,))
+        t.start()
 
+        self._wrapper._entrypoint = event_recorder
+        asyncio.get_event_loop().run_until_complete(self._wrapper.serve_requests(num_requests=1))
+        t.join()
+
+        self.assertEqual('1', recorded_events[0].id)
+        self.assertEqual({'h': 5}, recorded_events[0].headers)
+        self.assertEqual({'kind': 'http', 'name': ''}, recorded_events[0].trigger)
+        self.assertEqual(b'abc', recorded_events[0].body)
+
+        # handshake, processor start, duration, response
+        self._wait_until_received_messages(4)
+
+        duration = next(message['body'] for message in self._unix_stream_server._messages if message['type'] == 'm')
+        self.assertEqual(0x09, duration[0])
+        self.assertGreater(struct.unpack('<d', duration[1:])[0], 0)
+
+        response = next(message['body'] for message in self._unix_stream_server._messages if message['type'] == 'r')
+        encoded_result = b'\x08\xc9\x01' \
+                         b'\x12\x0atext/plain' \
+                         b'\x1a\x03cba' \
+                         b'\x22\x07\x0a\x01x\x12\x02\x10\x01'
+        self.assertEqual(b'\x0a' + bytes([len(encoded_result)]) + encoded_result, response)
+
+    def test_log_record(self):
+        self._wrapper._logger.info_with('Logged', key='value')
+
+        # the wrapper logs too, so look for the record among the others
+        deadline = time.time() + 10
+        while True:
+            log_records = [{number: value for number, value in wrapper.Protobuf.consume_fields(message['body'])}
+                           for message in list(self._unix_stream_server._messages)
+                           if message['type'] == 'l']
+            fields = next((log_record for log_record in log_records if log_record.get(3) == b'Logged'), None)
+            if fields is not None or time.time() > deadline:
+                break
+
+            time.sleep(0.1)
+
+        self.assertIsNotNone(fields)
+        self.assertEqual(b'info', fields[2])
+        self.assertEqual(b'Logged', fields[3])
+        self.assertEqual(b'\x0a\x03key\x12\x07\x0a\x05value', fields[4])
+
+    def test_data_binding(self):
+        encoded_request = wrapper.Protobuf.encode_data_binding_request({
+            'id': '1',
+            'name': 'db',
+            'operation': 'put',
+            'key': base64.b64encode(b'k').decode('ascii'),
+            'value': base64.b64encode(b'\x00\xff').decode('ascii'),
+            'headers': {'h': 'v'},
+            'async': True,
+            'expiration_seconds': 0,
+        })
+        self.assertEqual(b'\x0a\x011'
+                         b'\x12\x02db'
+                         b'\x1a\x03put'
+                         b'\x2a\x01k'
+                         b'\x32\x02\x00\xff'
+                         b'\x3a\x06\x0a\x01h\x12\x01v'
+                         b'\x40\x01', encoded_request)
+
+        # a response holding a value and an object whose last modification is at 1s
+        encoded_response = b'\x12\x011' \
+                           b'\x2a\x02\x00\xff' \
+                           b'\x32\x0b\x0a\x03key\x18\x80\x94\xeb\xdc\x03'
+        response = wrapper.Protobuf.decode_processor_message(
+            b'\x22' + bytes([len(encoded_response)]) + encoded_response)
+
+        self.assertEqual('1', response['id'])
+        self.assertEqual(b'\x00\xff', response['value'])
+        self.assertEqual('key', response['objects'][0]['key'])
+        self.assertEqual(1, response['objects'][0]['lastModified'].timestamp())
+
+    def _send_encoded_event(self, processor_message):
+        self._unix_stream_server._connection_socket.sendall(struct.pack('>I', len(processor_message)))
+        self._unix_stream_server._connection_socket.sendall(processor_message)
+
+
+class TestSubmitEventsConcurrently(_WrapperTestCase):
+    """Events the processor sends without waiting for the replies to those sent before them"""
 
-class TestSubmitEventsDecoded(TestSubmitEvents):
     @classmethod
     def setUpClass(cls):
-        super(TestSubmitEventsDecoded, cls).setUpClass()
-        cls._decode_incoming_event_messages = True
+        super(TestSubmitEventsConcurrently, cls).setUpClass()
+        cls._decode_event_strings = True
+
+    def setUp(self):
+        os.environ['NUCLIO_RPC_MAX_CONCURRENT_EVENTS'] = '2'
+        os.environ['NUCLIO_DATA_BINDINGS'] = 'db'
+        super(TestSubmitEventsConcurrently, self).setUp()
+
+    def tearDown(self):
+        super(TestSubmitEventsConcurrently, self).tearDown()
+        del os.environ['NUCLIO_RPC_MAX_CONCURRENT_EVENTS']
+        del os.environ['NUCLIO_DATA_BINDINGS']
+
+    def test_handshake(self):
+        self._wait_until_received_messages(2)
+        self.assertEqual({'type': 'p', 'body': {'version': 1, 'max_concurrent_events': 2}},
+                         self._unix_stream_server._messages[0])
+
+    def test_concurrent_events(self):
+        second_event_handled = asyncio.Event()
+
+        async def handler(context, event):
+
+            # the first event is only replied to once the second one is
+            if event.body == 'first':
+                await second_event_handled.wait()
+            else:
+                second_event_handled.set()
+
+            context.logger.info('Handled ' + event.body)
+            return event.body
+
+        self._wait_for_socket_creation()
+        t = threading.Thread(target=self._send_events, args=([
+            {'id': '1', 'body': 'first', 'request_id': '1'},
+            {'id': '2', 'body': 'second', 'request_id': '2'},
+        ],))
+        t.start()
+
+        self._wrapper._entrypoint = handler
+        asyncio.get_event_loop().run_until_complete(self._wrapper.serve_requests(num_requests=2))
+        t.join()
+
+        responses = self._wait_for_messages_of_type('r', 2)
+        self.assertEqual([('2', 'second'), ('1', 'first')],
+                         [(response['request_id'], response['body']) for response in responses])
+
+        log_records = [log_record for log_record in self._wait_for_messages_of_type('l', 1)
+                       if log_record['message'].startswith('Handled')]
+        self.assertEqual([('2', 'Handled second'), ('1', 'Handled first')],
+                         [(log_record['request_id'], log_record['message']) for log_record in log_records])
+
+    def test_data_binding(self):
+
+        async def handler(context, event):
+            return await context.data_binding['db'].presign('key')
+
+        def respond_to_data_binding_request():
+            self._send_event({'id': '1', 'body': '', 'request_id': '7'})
+            request = self._wait_for_messages_of_type('d', 1)[0]
+            self.assertEqual('7', request['request_id'])
+
+            # the response is read by the serving loop, rather than by the event waiting for it
+            self._send_event({'kind': 'dataBindingResponse', 'id': request['id'], 'url': 'https://bucket/key'})
+
+        self._wait_for_socket_creation()
+        t = threading.Thread(target=respond_to_data_binding_request)
+        t.start()
+
+        self._wrapper._entrypoint = handler
+        asyncio.get_event_loop().run_until_complete(self._wrapper.serve_requests(num_requests=2))
+        t.join()
+
+        response = self._wait_for_messages_of_type('r', 1)[0]
+        self.assertEqual('7', response['request_id'])
+        self.assertEqual('https://bucket/key', response['body'])
+
+    def _wait_for_messages_of_type(self, message_type, minimum_messages_length, timeout=10):
+        deadline = time.time() + timeout
+        while True:
+            messages = [message['body']
+                        for message in list(self._unix_stream_server._messages)
+                        if message['type'] == message_type]
+            if len(messages) >= minimum_messages_length or time.time() > deadline:
+                return messages
+
+            time.sleep(0.1)
 
 
 class _SingleConnectionUnixStreamServer(socketserver.UnixStreamServer):
@@ -444,29 +698,62 @@ class _Connection(socketserver.BaseRequestHandler):
         self.request.settimeout(1)
 
         # make a file from the socket so we can readln
-        socket_file = self.request.makefile('r')
+        socket_file = self.request.makefile('rb')
 
         # save the connection socket
         self.server._connection_socket = self.request
 
+        # messages are lines, until the wrapper negotiates frames
+        frames = False
+        protobuf = False
+
         # while the server isn't shut down
         while not self.server._BaseServer__shutdown_request:
 
             try:
-                line = socket_file.readline()
-                if not line:
+                if frames:
+                    message_type, payload = self._read_frame(socket_file)
+                else:
+                    line = socket_file.readline()
+                    if not line:
+                        continue
+
+                    message_type, payload = chr(line[0]), line[1:]
+
+                # protobuf messages are recorded as sent
+                if protobuf and message_type in 'rlmd':
+                    self.server._messages.append({'type': message_type, 'body': payload})
                     continue
 
+                # framed responses are prefixed by the length of their JSON, and followed by their raw bodies
+                raw_bodies = None
+                if frames and message_type == 'r':
+                    response_length = struct.unpack('>I', payload[:4])[0]
+                    payload, raw_bodies = payload[4:4 + response_length], payload[4 + response_length:]
+
                 message = {
-                    'type': line[0],
-                    'body': json.loads(line[1:]) if line[0] != 's' else ''
+                    'type': message_type,
+                    'body': json.loads(payload) if message_type != 's' else ''
                 }
 
+                if raw_bodies and message['body'].get('body_encoding') == 'raw':
+                    message['body']['raw_body'] = raw_bodies
+
+                if message_type == 'p':
+                    frames = message['body']['version'] == 2
+                    protobuf = message['body'].get('encoding') == 'protobuf'
+
                 self.server._messages.append(message)
 
             except:
                 pass
 
+    def _read_frame(self, socket_file):
+        header = socket_file.read(5)
+        message_type, payload_length = struct.unpack('>cI', header)
+
+        return message_type.decode('ascii'), socket_file.read(payload_length)
+
 
 class TestCallFunction(unittest.TestCase):
 
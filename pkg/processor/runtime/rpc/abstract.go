@@ -18,7 +18,10 @@ package rpc
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -29,11 +32,19 @@ import (
 	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
 	"github.com/nuclio/nuclio/pkg/processwaiter"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
+// the signals wrappers that support shutdown signals handle
+const (
+	terminationSignal = syscall.SIGUSR1
+	drainSignal       = syscall.SIGUSR2
+	continueSignal    = syscall.SIGCONT
+)
+
 // AbstractRuntime is a runtime that communicates via unix domain socket
 type AbstractRuntime struct {
 	runtime.AbstractRuntime
@@ -43,6 +54,28 @@ type AbstractRuntime struct {
 	stopChan          chan struct{}
 	processWaiter     *processwaiter.ProcessWaiter
 	connectionManager connection.ConnectionManager
+
+	// the most events the wrapper may process at a time, and the number the running wrapper negotiated.
+	// events in flight are bounded by the latter, since a restarted wrapper may negotiate fewer than the
+	// workers allocated when the runtime first started
+	maxConcurrentEvents        int
+	negotiatedConcurrentEvents int
+	eventSlots                 chan struct{}
+	eventSlotsRenegotiated     chan struct{}
+	eventSlotsLock             sync.RWMutex
+
+	// the encoding the wrapper may negotiate, other than the runtime's own
+	encoding string
+
+	// crashed wrappers are restarted with a backoff, until they crash loop. Starting, restarting and stopping
+	// the wrapper are serialized, and every start is numbered so that crashes of replaced wrappers are ignored.
+	// the restart lock is released while backing off, and stopping the wrapper cancels the pending restart
+	crashLoopTracker         *crashLoopTracker
+	stderrTail               stderrTail
+	restartLock              sync.Mutex
+	restartBackoffCancelChan chan struct{}
+	wrapperGeneration        int
+	wrapperStartTime         time.Time
 }
 
 // NewAbstractRuntime returns a new RPC runtime
@@ -56,20 +89,71 @@ func NewAbstractRuntime(logger logger.Logger,
 		return nil, errors.Wrap(err, "Can't create AbstractRuntime")
 	}
 
+	var attributes struct {
+		MaxConcurrentEvents int
+		Encoding            string
+		CrashLoopThreshold  int
+		RestartBackoff      string
+		MaxRestartBackoff   string
+	}
+
+	if err := mapstructure.Decode(configuration.Spec.RuntimeAttributes, &attributes); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode runtime attributes")
+	}
+
+	if attributes.MaxConcurrentEvents < 0 {
+		return nil, errors.Errorf("Invalid max concurrent events %d", attributes.MaxConcurrentEvents)
+	}
+
+	switch attributes.Encoding {
+	case connection.EncodingDefault, connection.EncodingProtobuf:
+	default:
+		return nil, errors.Errorf("Unsupported encoding %s", attributes.Encoding)
+	}
+
+	crashLoopConfiguration, err := newCrashLoopConfiguration(attributes.CrashLoopThreshold,
+		attributes.RestartBackoff,
+		attributes.MaxRestartBackoff)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve crash loop configuration")
+	}
+
 	newRuntime := &AbstractRuntime{
-		AbstractRuntime: *abstractRuntime,
-		configuration:   configuration,
-		runtime:         runtimeInstance,
-		stopChan:        make(chan struct{}, 1),
+		AbstractRuntime:            *abstractRuntime,
+		configuration:              configuration,
+		runtime:                    runtimeInstance,
+		stopChan:                   make(chan struct{}, 1),
+		maxConcurrentEvents:        max(attributes.MaxConcurrentEvents, 1),
+		negotiatedConcurrentEvents: 1,
+		eventSlots:                 make(chan struct{}, 1),
+		eventSlotsRenegotiated:     make(chan struct{}),
+		encoding:                   attributes.Encoding,
+		crashLoopTracker:           &crashLoopTracker{configuration: crashLoopConfiguration},
 	}
 
 	return newRuntime, nil
 }
 
 func (r *AbstractRuntime) Start() error {
+	r.restartLock.Lock()
+	defer r.restartLock.Unlock()
+
 	if err := r.startWrapper(); err != nil {
-		r.SetStatus(status.Error)
-		return errors.Wrap(err, "Failed to run wrapper")
+		if errors.RootCause(err) != errWrapperExited {
+			r.SetStatus(status.Error)
+			return errors.Wrap(err, "Failed to run wrapper")
+		}
+
+		// a wrapper that crashes while starting is restarted like one that crashed after starting. If it's
+		// crash looping, the processor is kept up so that the crashes are visible through its health check
+		// and web admin
+		if err := r.restartCrashedWrapper(err); err != nil {
+			if err == errWrapperCrashLooping {
+				return nil
+			}
+
+			return errors.Wrap(err, "Failed to restart crashed wrapper process")
+		}
 	}
 
 	r.SetStatus(status.Ready)
@@ -121,6 +205,19 @@ func (r *AbstractRuntime) ProcessBatch(batch []nuclio.Event, functionLogger logg
 
 // Stop stops the runtime
 func (r *AbstractRuntime) Stop() error {
+	r.restartLock.Lock()
+	defer r.restartLock.Unlock()
+
+	if err := r.stop(); err != nil {
+		return err
+	}
+
+	// restarts keep the context, and with it an invocation client of the runtime's own, which is only closed
+	// once stopped for good
+	return r.AbstractRuntime.Stop()
+}
+
+func (r *AbstractRuntime) stop() error {
 	r.Logger.WarnWith("Stopping",
 		"status", r.GetStatus(),
 		"wrapperProcess", r.wrapperProcess)
@@ -129,6 +226,12 @@ func (r *AbstractRuntime) Stop() error {
 	// to avoid sending any events while stopping
 	r.SetStatus(status.Stopped)
 
+	// a crashed wrapper waiting to be restarted is stopped as well
+	if r.restartBackoffCancelChan != nil {
+		close(r.restartBackoffCancelChan)
+		r.restartBackoffCancelChan = nil
+	}
+
 	if err := r.connectionManager.Stop(); err != nil {
 		return errors.Wrap(err, "Failed to stop connection manager")
 	}
@@ -145,9 +248,9 @@ func (r *AbstractRuntime) Stop() error {
 			r.SetStatus(status.Error)
 			return errors.Wrap(err, "Can't kill wrapper process")
 		}
-	}
 
-	r.WaitForProcessTermination(10 * time.Second)
+		r.WaitForProcessTermination(10 * time.Second)
+	}
 
 	r.wrapperProcess = nil
 	r.Logger.Warn("Successfully stopped wrapper process")
@@ -156,19 +259,65 @@ func (r *AbstractRuntime) Stop() error {
 
 // Restart restarts the runtime
 func (r *AbstractRuntime) Restart() error {
-	if err := r.Stop(); err != nil {
+	r.restartLock.Lock()
+	defer r.restartLock.Unlock()
+
+	if err := r.stop(); err != nil {
 		return err
 	}
 
 	if err := r.startWrapper(); err != nil {
-		r.SetStatus(status.Error)
-		return errors.Wrap(err, "Can't start wrapper process")
+		if errors.RootCause(err) != errWrapperExited {
+			r.SetStatus(status.Error)
+			return errors.Wrap(err, "Can't start wrapper process")
+		}
+
+		if err := r.restartCrashedWrapper(err); err != nil {
+			return errors.Wrap(err, "Failed to restart crashed wrapper process")
+		}
 	}
 
 	r.SetStatus(status.Ready)
 	return nil
 }
 
+// GetEnvFromConfiguration returns the environment variables wrappers are run with, including the newest
+// protocol version they may negotiate
+func (r *AbstractRuntime) GetEnvFromConfiguration() []string {
+	env := append(r.AbstractRuntime.GetEnvFromConfiguration(),
+		fmt.Sprintf("%s=%d", connection.ProtocolVersionEnvName, connection.LatestProtocolVersion))
+
+	// let wrappers that can process several events at a time know how many they may negotiate
+	if r.maxConcurrentEvents > 1 {
+		env = append(env, fmt.Sprintf("%s=%d", connection.MaxConcurrentEventsEnvName, r.maxConcurrentEvents))
+	}
+
+	if r.encoding != connection.EncodingDefault {
+		env = append(env, fmt.Sprintf("%s=%s", connection.EncodingEnvName, r.encoding))
+	}
+
+	return env
+}
+
+// GetMaxConcurrentEvents returns the number of events the wrapper negotiated processing at a time
+func (r *AbstractRuntime) GetMaxConcurrentEvents() int {
+	r.eventSlotsLock.RLock()
+	defer r.eventSlotsLock.RUnlock()
+
+	return r.negotiatedConcurrentEvents
+}
+
+// GetCrashLoopStatus returns the crashes of the wrapper process
+func (r *AbstractRuntime) GetCrashLoopStatus() *runtime.CrashLoopStatus {
+	return r.crashLoopTracker.getStatus()
+}
+
+// CaptureStderr returns a writer that writes the wrapper's stderr to the given writer, keeping its last lines
+// to report when the wrapper crashes
+func (r *AbstractRuntime) CaptureStderr(writer io.Writer) io.Writer {
+	return io.MultiWriter(writer, &r.stderrTail)
+}
+
 // GetSocketType returns the type of socket the runtime works with (unix/tcp)
 func (r *AbstractRuntime) GetSocketType() connection.SocketType {
 	return connection.UnixSocket
@@ -189,6 +338,65 @@ func (r *AbstractRuntime) SupportsControlCommunication() bool {
 	return false
 }
 
+// SupportsShutdownSignals returns false, as wrappers may not handle the shutdown signals, whose default
+// action is to terminate the process
+func (r *AbstractRuntime) SupportsShutdownSignals() bool {
+	return false
+}
+
+// Drain signals the wrapper to drain its events and call the handler's shutdown callback, and waits for it
+func (r *AbstractRuntime) Drain() error {
+
+	// do not send a signal if the runtime isn't ready,
+	// because the signal handler may not be initialized yet.
+	// if the process receives a signal before the handler is set up,
+	// the default behaviour will cause the Linux process to terminate.
+	if !r.runtime.SupportsShutdownSignals() || r.GetStatus() != status.Ready {
+		return nil
+	}
+
+	if err := r.Signal(drainSignal); err != nil {
+		return errors.Wrap(err, "Failed to signal wrapper process to drain")
+	}
+
+	// wait for process to finish event handling or timeout
+	// TODO: replace the following function with one that waits for a control communication message or timeout
+	r.WaitForProcessTermination(r.configuration.WorkerTerminationTimeout)
+
+	return nil
+}
+
+// Terminate signals the wrapper that the processor is about to stop, which calls the handler's shutdown
+// callback, and waits for it
+func (r *AbstractRuntime) Terminate() error {
+	if !r.runtime.SupportsShutdownSignals() {
+		return nil
+	}
+
+	if err := r.Signal(terminationSignal); err != nil {
+		return errors.Wrap(err, "Failed to signal wrapper process to terminate")
+	}
+
+	// wait for process to finish event handling or timeout
+	// TODO: replace the following function with one that waits for a control communication message or timeout
+	r.WaitForProcessTermination(r.configuration.WorkerTerminationTimeout)
+
+	return nil
+}
+
+// Continue signals the wrapper to continue event processing
+func (r *AbstractRuntime) Continue() error {
+	if !r.runtime.SupportsShutdownSignals() {
+		return nil
+	}
+
+	if err := r.Signal(continueSignal); err != nil {
+		return errors.Wrap(err, "Failed to signal wrapper process to continue")
+	}
+
+	return nil
+}
+
 func (r *AbstractRuntime) Signal(signal syscall.Signal) error {
 
 	if r.wrapperProcess != nil {
@@ -237,6 +445,11 @@ func (r *AbstractRuntime) processItemAndWaitForResult(item interface{}, function
 		return nil, errors.Errorf("Processor not ready (current status: %s)", currentStatus)
 	}
 
+	eventSlots := r.acquireEventSlot()
+	defer func() {
+		<-eventSlots
+	}()
+
 	connection, err := r.connectionManager.Allocate()
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to allocate connection")
@@ -246,6 +459,54 @@ func (r *AbstractRuntime) processItemAndWaitForResult(item interface{}, function
 	return processingResult, err
 }
 
+// acquireEventSlot waits until the wrapper may process another event, and returns the slots to release it to
+func (r *AbstractRuntime) acquireEventSlot() chan struct{} {
+	for {
+		r.eventSlotsLock.RLock()
+		eventSlots, eventSlotsRenegotiated := r.eventSlots, r.eventSlotsRenegotiated
+		r.eventSlotsLock.RUnlock()
+
+		// the wrapper may be restarted while waiting, and process a different number of events
+		select {
+		case eventSlots <- struct{}{}:
+		case <-eventSlotsRenegotiated:
+			continue
+		}
+
+		r.eventSlotsLock.RLock()
+		negotiatedAnew := eventSlots != r.eventSlots
+		r.eventSlotsLock.RUnlock()
+
+		if !negotiatedAnew {
+			return eventSlots
+		}
+
+		<-eventSlots
+	}
+}
+
+// setNegotiatedConcurrentEvents bounds the events in flight by the number the wrapper negotiated. Events
+// already in flight release the slots of the wrapper they were sent to
+func (r *AbstractRuntime) setNegotiatedConcurrentEvents(negotiatedConcurrentEvents int) {
+	r.eventSlotsLock.Lock()
+	defer r.eventSlotsLock.Unlock()
+
+	if negotiatedConcurrentEvents == r.negotiatedConcurrentEvents {
+		return
+	}
+
+	r.Logger.DebugWith("Wrapper negotiated concurrent events",
+		"previous", r.negotiatedConcurrentEvents,
+		"negotiated", negotiatedConcurrentEvents)
+
+	r.negotiatedConcurrentEvents = negotiatedConcurrentEvents
+	r.eventSlots = make(chan struct{}, negotiatedConcurrentEvents)
+
+	// wake events waiting for a slot of the previous wrapper
+	close(r.eventSlotsRenegotiated)
+	r.eventSlotsRenegotiated = make(chan struct{})
+}
+
 func (r *AbstractRuntime) startWrapper() error {
 	connectionManagerConfiguration := &connection.ManagerConfigration{
 		Kind:                        connection.SocketAllocatorManagerKind,
@@ -254,6 +515,10 @@ func (r *AbstractRuntime) startWrapper() error {
 		SocketType:                  r.runtime.GetSocketType(),
 		GetEventEncoderFunc:         r.runtime.GetEventEncoder,
 		Statistics:                  r.Statistics,
+		DataBindings:                r.Context.DataBinding,
+		InvocationClient:            r.InvocationClient,
+		MaxConcurrentEvents:         r.maxConcurrentEvents,
+		Encoding:                    r.encoding,
 	}
 	var err error
 	r.connectionManager, err = connection.NewConnectionManager(r.Logger, *r.configuration, connectionManagerConfiguration)
@@ -268,6 +533,16 @@ func (r *AbstractRuntime) startWrapper() error {
 		return errors.Wrap(err, "Failed to create process waiter")
 	}
 
+	// forget a termination signaled by a previous wrapper that nothing waited for
+	select {
+	case <-r.stopChan:
+	default:
+	}
+
+	r.wrapperGeneration++
+	r.wrapperStartTime = time.Now()
+	r.stderrTail.reset()
+
 	wrapperProcess, err := r.runtime.RunWrapper(r.connectionManager.GetAddressesForWrapperStart())
 	if err != nil {
 		return errors.Wrap(err, "Can't run wrapper")
@@ -275,36 +550,173 @@ func (r *AbstractRuntime) startWrapper() error {
 
 	r.wrapperProcess = wrapperProcess
 
-	go r.watchWrapperProcess()
+	wrapperExitChan := make(chan error, 1)
+	go r.watchWrapperProcess(wrapperProcess, r.processWaiter, wrapperExitChan)
+
+	// the wrapper may exit before it starts (e.g. when the handler fails to initialize), in which case it
+	// never connects or reports that it started
+	connectionManager := r.connectionManager
+	connectionManagerStartErrChan := make(chan error, 1)
+	go func() {
+
+		// the wrapper's own connection manager, since a restarted wrapper replaces it
+		connectionManagerStartErrChan <- connectionManager.Start()
+	}()
+
+	select {
+	case err := <-connectionManagerStartErrChan:
+		if err != nil {
+			return errors.Wrap(err, "Failed to start connection manager")
+		}
+	case exitErr := <-wrapperExitChan:
+		if exitErr == nil {
+			exitErr = errors.Wrap(errWrapperExited, "Wrapper process exited before starting")
+		}
 
-	if err := r.connectionManager.Start(); err != nil {
-		return errors.Wrap(err, "Failed to start connection manager")
+		r.wrapperProcess = nil
+		r.connectionManager.Stop() // nolint: errcheck
+		return exitErr
 	}
 
+	// every wrapper negotiates anew, including restarted ones
+	r.setNegotiatedConcurrentEvents(r.connectionManager.GetMaxConcurrentEvents())
+
+	go r.superviseWrapper(r.wrapperGeneration, wrapperExitChan)
+
 	return nil
 }
 
-func (r *AbstractRuntime) watchWrapperProcess() {
+// superviseWrapper restarts the wrapper of the given generation if it crashes
+func (r *AbstractRuntime) superviseWrapper(generation int, wrapperExitChan <-chan error) {
+	exitErr := <-wrapperExitChan
+	if exitErr == nil {
+		return
+	}
+
+	r.restartLock.Lock()
+	defer r.restartLock.Unlock()
+
+	// the wrapper may have been replaced or stopped while waiting
+	if generation != r.wrapperGeneration || r.GetStatus() == status.Stopped {
+		return
+	}
+
+	r.wrapperProcess = nil
+	if err := r.restartCrashedWrapper(exitErr); err != nil {
+		return
+	}
+
+	// the wrapper restarted by itself, so whoever warmed up the previous one should warm up this one too
+	r.NotifyRestarted()
+}
+
+// restartCrashedWrapper restarts a wrapper that crashed, backing off between consecutive crashes. It returns
+// errWrapperCrashLooping if the wrapper is crash looping, after which the runtime is in error until restarted,
+// and errRestartCancelled if the runtime was stopped or restarted while backing off. Must be called while
+// holding the restart lock
+func (r *AbstractRuntime) restartCrashedWrapper(crashErr error) error {
+	for {
+		stderrLines := r.stderrTail.getLines()
+
+		backoff, restart := r.crashLoopTracker.recordCrash(crashErr, stderrLines, time.Since(r.wrapperStartTime))
+		if !restart {
+			r.Logger.ErrorWith(string(common.WrapperProcessCrashLooping),
+				"wid", r.Context.WorkerID,
+				"crashes", r.crashLoopTracker.getStatus().Crashes,
+				"err", crashErr.Error(),
+				"stderr", strings.Join(stderrLines, "\n"))
+
+			r.SetStatus(status.Error)
+			return errWrapperCrashLooping
+		}
+
+		r.Logger.WarnWith("Restarting crashed wrapper process",
+			"wid", r.Context.WorkerID,
+			"err", crashErr.Error(),
+			"backoff", backoff.String())
+
+		r.SetStatus(status.Initializing)
+		r.connectionManager.Stop() // nolint: errcheck
+
+		if !r.waitForRestartBackoff(backoff) {
+			r.Logger.DebugWith("Restarting crashed wrapper process was cancelled", "wid", r.Context.WorkerID)
+			return errRestartCancelled
+		}
+
+		err := r.startWrapper()
+		if err == nil {
+			r.SetStatus(status.Ready)
+			return nil
+		}
 
-	// whatever happens, clear wrapper process
+		if errors.RootCause(err) != errWrapperExited {
+			r.Logger.ErrorWith("Failed to restart crashed wrapper process", "err", err.Error())
+			r.SetStatus(status.Error)
+			return err
+		}
+
+		crashErr = err
+	}
+}
+
+// waitForRestartBackoff waits for the given backoff without holding the restart lock, so that the runtime
+// may be stopped or restarted meanwhile. It returns false if it was, in which case the crashed wrapper
+// mustn't be restarted
+func (r *AbstractRuntime) waitForRestartBackoff(backoff time.Duration) bool {
+	restartBackoffCancelChan := make(chan struct{})
+	r.restartBackoffCancelChan = restartBackoffCancelChan
+
+	backoffTimer := time.NewTimer(backoff)
+	defer backoffTimer.Stop()
+
+	r.restartLock.Unlock()
+
+	select {
+	case <-backoffTimer.C:
+	case <-restartBackoffCancelChan:
+	}
+
+	r.restartLock.Lock()
+
+	select {
+	case <-restartBackoffCancelChan:
+		return false
+	default:
+		r.restartBackoffCancelChan = nil
+		return true
+	}
+}
+
+// watchWrapperProcess waits for the wrapper process to exit, and sends the reason to wrapperExitChan if it
+// exited unexpectedly, or nil otherwise
+func (r *AbstractRuntime) watchWrapperProcess(wrapperProcess *os.Process,
+	processWaiter *processwaiter.ProcessWaiter,
+	wrapperExitChan chan<- error) {
+
+	// whatever happens, let those waiting for the process know it terminated
 	defer func() {
-		r.stopChan <- struct{}{}
+		select {
+		case r.stopChan <- struct{}{}:
+		default:
+		}
 	}()
 
 	// wait for the process
-	processWaitResult := <-r.processWaiter.Wait(r.wrapperProcess, nil)
+	processWaitResult := <-processWaiter.Wait(wrapperProcess, nil)
 
 	// if we were simply canceled, do nothing
 	if processWaitResult.Err == processwaiter.ErrCancelled {
 		r.Logger.DebugWith("Process watch cancelled. Returning",
-			"pid", r.wrapperProcess.Pid,
+			"pid", wrapperProcess.Pid,
 			"wid", r.Context.WorkerID)
+		wrapperExitChan <- nil
 		return
 	}
 
 	// if process exited gracefully (i.e. wasn't force killed), do nothing
 	if processWaitResult.Err == nil && processWaitResult.ProcessState.Success() {
 		r.Logger.DebugWith("Process watch done - process exited successfully")
+		wrapperExitChan <- nil
 		return
 	}
 
@@ -317,15 +729,16 @@ func (r *AbstractRuntime) watchWrapperProcess() {
 		"error", errorMsg,
 		"status", processWaitResult.ProcessState.String(),
 		"exitCode", processWaitResult.ProcessState.ExitCode(),
-		"pid", r.wrapperProcess.Pid,
+		"pid", wrapperProcess.Pid,
 	)
 
-	var panicMessage string
-	if processWaitResult.Err != nil {
-		panicMessage = processWaitResult.Err.Error()
-	} else {
-		panicMessage = processWaitResult.ProcessState.String()
+	exitMessage := errorMsg
+	if processWaitResult.Err == nil {
+		exitMessage = processWaitResult.ProcessState.String()
 	}
 
-	panic(fmt.Sprintf("Wrapper process for worker %d exited unexpectedly with: %s", r.Context.WorkerID, panicMessage))
+	wrapperExitChan <- errors.Wrapf(errWrapperExited,
+		"Wrapper process for worker %d exited with: %s",
+		r.Context.WorkerID,
+		exitMessage)
 }
@@ -27,9 +27,11 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/common/status"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor"
@@ -47,8 +49,12 @@ import (
 type testRuntime struct {
 	*AbstractRuntime
 	wrapperProcess *os.Process
-	eventConn      net.Conn
-	controlConn    net.Conn
+	wrapperCommand []string
+
+	// whether the wrapper doesn't connect to the runtime, as when it crashes while starting
+	noConnection bool
+	eventConn    net.Conn
+	controlConn  net.Conn
 }
 
 // NewRuntime returns a new Python runtime
@@ -76,11 +82,20 @@ func (r *testRuntime) RunWrapper(eventSocketPaths []string, controlSocketPath st
 	}
 	var err error
 	cmd := exec.Command("sleep", "999999")
+	if len(r.wrapperCommand) > 0 {
+		cmd = exec.Command(r.wrapperCommand[0], r.wrapperCommand[1:]...)
+	}
+
+	cmd.Stderr = r.CaptureStderr(io.Discard)
 	if err = cmd.Start(); err != nil {
 		return nil, err
 	}
 	r.wrapperProcess = cmd.Process
 
+	if r.noConnection {
+		return cmd.Process, nil
+	}
+
 	var eventSocketPath string
 	if len(eventSocketPaths) == 1 {
 		eventSocketPath = eventSocketPaths[0]
@@ -131,6 +146,151 @@ func (suite *RuntimeSuite) TestRestart() {
 	suite.Require().NotEqual(oldPid, suite.testRuntimeInstance.wrapperProcess.Pid, "Wrapper process didn't change")
 }
 
+func (suite *RuntimeSuite) TestCrashLoop() {
+	var err error
+
+	loggerInstance := suite.createLogger()
+	configInstance := suite.createConfig(loggerInstance)
+	configInstance.Spec.RuntimeAttributes = map[string]interface{}{
+		"crashLoopThreshold": 3,
+		"restartBackoff":     "10ms",
+	}
+
+	suite.testRuntimeInstance, err = newTestRuntime(loggerInstance, configInstance)
+	suite.Require().NoError(err, "Can't create runtime")
+
+	// a wrapper that crashes shortly after starting
+	suite.testRuntimeInstance.wrapperCommand = []string{"sh", "-c", "echo 'ImportError: no module' >&2; sleep 0.2; exit 3"}
+
+	var restarts atomic.Int32
+	suite.testRuntimeInstance.SetRestartListener(func() {
+		restarts.Add(1)
+	})
+
+	err = suite.testRuntimeInstance.Start()
+	suite.Require().NoError(err, "Can't start runtime")
+	suite.Require().Equal(status.Ready, suite.testRuntimeInstance.GetStatus())
+
+	// the wrapper is restarted until it's considered crash looping
+	suite.Require().Eventually(func() bool {
+		return suite.testRuntimeInstance.GetStatus() == status.Error
+	}, 10*time.Second, 50*time.Millisecond, "Runtime didn't enter error state")
+
+	crashLoopStatus := suite.testRuntimeInstance.GetCrashLoopStatus()
+	suite.Require().True(crashLoopStatus.CrashLooping)
+	suite.Require().Equal(3, crashLoopStatus.Crashes)
+	suite.Require().Contains(crashLoopStatus.LastError, "exit status 3")
+	suite.Require().Equal([]string{"ImportError: no module"}, crashLoopStatus.LastStderrLines)
+
+	// the listener is told about every restart, but not about the crash that made the wrapper crash loop
+	suite.Require().Equal(int32(2), restarts.Load())
+
+	// the crash looping wrapper isn't restarted anymore, so stopping the runtime has nothing to kill
+	suite.Require().NoError(suite.testRuntimeInstance.Stop())
+	suite.testRuntimeInstance = nil
+}
+
+func (suite *RuntimeSuite) TestStopWhileBackingOff() {
+	var err error
+
+	loggerInstance := suite.createLogger()
+	configInstance := suite.createConfig(loggerInstance)
+	configInstance.Spec.RuntimeAttributes = map[string]interface{}{
+		"restartBackoff":    "1m",
+		"maxRestartBackoff": "1m",
+	}
+
+	suite.testRuntimeInstance, err = newTestRuntime(loggerInstance, configInstance)
+	suite.Require().NoError(err, "Can't create runtime")
+
+	suite.testRuntimeInstance.wrapperCommand = []string{"sh", "-c", "sleep 0.2; exit 3"}
+
+	err = suite.testRuntimeInstance.Start()
+	suite.Require().NoError(err, "Can't start runtime")
+
+	// wait for the crashed wrapper to back off from restarting
+	suite.Require().Eventually(func() bool {
+		return suite.testRuntimeInstance.GetStatus() == status.Initializing
+	}, 10*time.Second, 50*time.Millisecond, "Runtime didn't back off")
+
+	// the restart lock isn't held while backing off, so stopping doesn't wait for the backoff
+	stopErrChan := make(chan error, 1)
+	go func() {
+		stopErrChan <- suite.testRuntimeInstance.Stop()
+	}()
+
+	select {
+	case err := <-stopErrChan:
+		suite.Require().NoError(err)
+	case <-time.After(5 * time.Second):
+		suite.Fail("Stopping the runtime waited for the restart backoff")
+	}
+
+	// and the crashed wrapper isn't restarted once stopped
+	suite.Require().Never(func() bool {
+		return suite.testRuntimeInstance.GetStatus() != status.Stopped
+	}, 500*time.Millisecond, 50*time.Millisecond, "Runtime was restarted after stopping")
+	suite.Require().Equal(1, suite.testRuntimeInstance.GetCrashLoopStatus().Crashes)
+
+	suite.testRuntimeInstance = nil
+}
+
+func (suite *RuntimeSuite) TestStartCrashingWrapper() {
+	var err error
+
+	loggerInstance := suite.createLogger()
+	configInstance := suite.createConfig(loggerInstance)
+	configInstance.Spec.RuntimeAttributes = map[string]interface{}{
+		"crashLoopThreshold": 2,
+		"restartBackoff":     "10ms",
+	}
+
+	suite.testRuntimeInstance, err = newTestRuntime(loggerInstance, configInstance)
+	suite.Require().NoError(err, "Can't create runtime")
+
+	// a wrapper that crash loops while starting keeps the processor up, in error
+	suite.testRuntimeInstance.wrapperCommand = []string{"sh", "-c", "exit 3"}
+	suite.testRuntimeInstance.noConnection = true
+
+	err = suite.testRuntimeInstance.Start()
+	suite.Require().NoError(err, "Crash looping wrapper shouldn't fail the start")
+	suite.Require().Equal(status.Error, suite.testRuntimeInstance.GetStatus())
+	suite.Require().True(suite.testRuntimeInstance.GetCrashLoopStatus().CrashLooping)
+
+	// but a start whose restart is cancelled fails
+	configInstance.Spec.RuntimeAttributes = map[string]interface{}{
+		"restartBackoff":    "1m",
+		"maxRestartBackoff": "1m",
+	}
+
+	suite.testRuntimeInstance, err = newTestRuntime(loggerInstance, configInstance)
+	suite.Require().NoError(err, "Can't create runtime")
+	suite.testRuntimeInstance.wrapperCommand = []string{"sh", "-c", "exit 3"}
+	suite.testRuntimeInstance.noConnection = true
+
+	startErrChan := make(chan error, 1)
+	go func() {
+		startErrChan <- suite.testRuntimeInstance.Start()
+	}()
+
+	suite.Require().Eventually(func() bool {
+		return suite.testRuntimeInstance.GetStatus() == status.Initializing &&
+			suite.testRuntimeInstance.GetCrashLoopStatus().Crashes == 1
+	}, 10*time.Second, 50*time.Millisecond, "Runtime didn't back off")
+
+	suite.Require().NoError(suite.testRuntimeInstance.Stop())
+
+	select {
+	case err := <-startErrChan:
+		suite.Require().Error(err, "Cancelled restart should fail the start")
+		suite.Require().Equal(errRestartCancelled, errors.RootCause(err))
+	case <-time.After(5 * time.Second):
+		suite.Fail("Start didn't return once the runtime was stopped")
+	}
+
+	suite.testRuntimeInstance = nil
+}
+
 func (suite *RuntimeSuite) TestSubscribeToControlMessage() {
 	var err error
 	messageKind := controlcommunication.ControlMessageKind("test")
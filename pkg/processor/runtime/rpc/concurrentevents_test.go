@@ -0,0 +1,108 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConcurrentEventsTestSuite struct {
+	suite.Suite
+	runtime *AbstractRuntime
+}
+
+func (suite *ConcurrentEventsTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.runtime = &AbstractRuntime{
+		AbstractRuntime:            runtime.AbstractRuntime{Logger: loggerInstance},
+		negotiatedConcurrentEvents: 1,
+		eventSlots:                 make(chan struct{}, 1),
+		eventSlotsRenegotiated:     make(chan struct{}),
+	}
+}
+
+func (suite *ConcurrentEventsTestSuite) TestRenegotiate() {
+	suite.runtime.setNegotiatedConcurrentEvents(3)
+	suite.Require().Equal(3, suite.runtime.GetMaxConcurrentEvents())
+
+	for slot := 0; slot < 3; slot++ {
+		suite.runtime.acquireEventSlot()
+	}
+
+	acquired := make(chan chan struct{})
+	go func() {
+		acquired <- suite.runtime.acquireEventSlot()
+	}()
+
+	// all slots are taken
+	select {
+	case <-acquired:
+		suite.Fail("Acquired more slots than negotiated")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// a restarted wrapper negotiates fewer events, and the waiting event takes a slot of its own
+	suite.runtime.setNegotiatedConcurrentEvents(1)
+	suite.Require().Equal(1, suite.runtime.GetMaxConcurrentEvents())
+
+	var eventSlots chan struct{}
+	select {
+	case eventSlots = <-acquired:
+	case <-time.After(5 * time.Second):
+		suite.FailNow("Didn't acquire a slot after renegotiating")
+	}
+
+	suite.Require().Len(eventSlots, 1)
+	suite.Require().Equal(1, cap(eventSlots))
+}
+
+func (suite *ConcurrentEventsTestSuite) TestWaitForRelease() {
+	eventSlots := suite.runtime.acquireEventSlot()
+
+	acquired := make(chan chan struct{})
+	go func() {
+		acquired <- suite.runtime.acquireEventSlot()
+	}()
+
+	select {
+	case <-acquired:
+		suite.Fail("Acquired more slots than negotiated")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	<-eventSlots
+
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		suite.Fail("Didn't acquire a released slot")
+	}
+}
+
+func TestConcurrentEventsTestSuite(t *testing.T) {
+	suite.Run(t, new(ConcurrentEventsTestSuite))
+}
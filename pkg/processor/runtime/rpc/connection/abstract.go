@@ -19,22 +19,31 @@ package connection
 import (
 	"bufio"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"io"
 	"net"
 	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/common/status"
 	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/databinding"
+	"github.com/nuclio/nuclio/pkg/processor/invocation"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/controlmessagebroker"
 	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/encoder"
 	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
 
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 )
 
 type AbstractConnectionManager struct {
@@ -92,8 +101,37 @@ type AbstractEventConnection struct {
 	resultChan chan *result.BatchedResults
 	startChan  chan struct{}
 
+	// closed when the connection is stopped, releasing those waiting for it to start
+	stoppedChan chan struct{}
+	stopOnce    sync.Once
+
 	connectionManager ConnectionManager
 	functionLogger    logger.Logger
+	dataBindings      map[string]nuclio.DataBinding
+
+	// the most events the wrapper may negotiate processing at a time, and the number it negotiated
+	// (accessed atomically)
+	maxConcurrentEvents        int
+	negotiatedConcurrentEvents int32
+
+	// the encoding the wrapper may negotiate, and the encoding of its messages once negotiated
+	encoding        string
+	payloadEncoding string
+
+	// encoders aren't safe for concurrent use, and data binding responses may be written while events are
+	encodeLock sync.Mutex
+
+	// the requests in flight, when the wrapper processes several events at a time
+	requestsLock  sync.Mutex
+	requests      map[string]*pendingRequest
+	lastRequestID uint64
+	closed        bool
+}
+
+// pendingRequest is an event, or a batch of events, sent to a wrapper that processes several events at a time
+type pendingRequest struct {
+	resultChan     chan *result.BatchedResults
+	functionLogger logger.Logger
 }
 
 func NewAbstractEventConnection(parentLogger logger.Logger, connectionManager ConnectionManager) *AbstractEventConnection {
@@ -102,19 +140,63 @@ func NewAbstractEventConnection(parentLogger logger.Logger, connectionManager Co
 		cancelChan: make(chan struct{}, 1),
 	}
 	return &AbstractEventConnection{
-		AbstractConnection: abstractConnection,
-		resultChan:         make(chan *result.BatchedResults),
-		startChan:          make(chan struct{}, 1),
-		connectionManager:  connectionManager,
+		AbstractConnection:  abstractConnection,
+		resultChan:          make(chan *result.BatchedResults),
+		startChan:           make(chan struct{}, 1),
+		stoppedChan:         make(chan struct{}),
+		connectionManager:   connectionManager,
+		maxConcurrentEvents: 1,
+		requests:            map[string]*pendingRequest{},
 	}
 }
+
+// SetDataBindings sets the data bindings through which the wrapper may send messages
+func (be *AbstractEventConnection) SetDataBindings(dataBindings map[string]nuclio.DataBinding) {
+	be.dataBindings = dataBindings
+}
+
+// SetMaxConcurrentEvents sets the most events the wrapper may negotiate processing at a time
+func (be *AbstractEventConnection) SetMaxConcurrentEvents(maxConcurrentEvents int) {
+	be.maxConcurrentEvents = maxConcurrentEvents
+}
+
+// SetEncoding sets the encoding the wrapper may negotiate, other than the runtime's own
+func (be *AbstractEventConnection) SetEncoding(encoding string) {
+	be.encoding = encoding
+}
+
+// GetMaxConcurrentEvents returns the number of events the wrapper negotiated processing at a time
+func (be *AbstractEventConnection) GetMaxConcurrentEvents() int {
+	if negotiatedConcurrentEvents := atomic.LoadInt32(&be.negotiatedConcurrentEvents); negotiatedConcurrentEvents > 1 {
+		return int(negotiatedConcurrentEvents)
+	}
+
+	return 1
+}
+
+// WaitForStart waits for the wrapper to report that it started, or for the connection to be stopped
 func (be *AbstractEventConnection) WaitForStart() {
-	<-be.startChan
+	select {
+	case <-be.startChan:
+	case <-be.stoppedChan:
+	}
+}
+
+// Stop stops the event connection. Stopping it again does nothing
+func (be *AbstractEventConnection) Stop() {
+	be.stopOnce.Do(func() {
+		close(be.stoppedChan)
+		be.AbstractConnection.Stop()
+	})
 }
 
 func (be *AbstractEventConnection) ProcessEvent(item interface{}, functionLogger logger.Logger) (*result.BatchedResults, error) {
+	if be.GetMaxConcurrentEvents() > 1 {
+		return be.processRequest(item, functionLogger)
+	}
+
 	be.functionLogger = functionLogger
-	if err := be.encoder.Encode(item); err != nil {
+	if err := be.encode(item); err != nil {
 		be.functionLogger = nil
 		return nil, errors.Wrapf(err, "Can't encode item: %+v", item)
 	}
@@ -123,6 +205,31 @@ func (be *AbstractEventConnection) ProcessEvent(item interface{}, functionLogger
 	// We don't use defer to reset be.functionLogger since it decreases performance
 	be.functionLogger = nil
 
+	return be.resolveProcessingResults(processingResults, ok)
+}
+
+// processRequest sends the item tagged with a request ID, and waits for the results the wrapper tags with it.
+// Other requests may be sent while waiting, up to the number of events the wrapper negotiated
+func (be *AbstractEventConnection) processRequest(item interface{}, functionLogger logger.Logger) (*result.BatchedResults, error) {
+	requestID, request, err := be.addRequest(functionLogger)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to add request")
+	}
+
+	if err := be.encode(&encoder.Request{ID: requestID, Item: item}); err != nil {
+		be.removeRequest(requestID)
+		return nil, errors.Wrapf(err, "Can't encode item: %+v", item)
+	}
+
+	// the channel is never closed - requests are failed with a result instead
+	processingResults := <-request.resultChan
+
+	return be.resolveProcessingResults(processingResults, true)
+}
+
+func (be *AbstractEventConnection) resolveProcessingResults(processingResults *result.BatchedResults,
+	ok bool) (*result.BatchedResults, error) {
+
 	if !ok {
 		msg := "Client disconnected"
 		be.Logger.Error(msg)
@@ -138,7 +245,100 @@ func (be *AbstractEventConnection) ProcessEvent(item interface{}, functionLogger
 	return processingResults, nil
 }
 
-func (be *AbstractEventConnection) resolveFunctionLogger() logger.Logger {
+func (be *AbstractEventConnection) encode(item interface{}) error {
+	be.encodeLock.Lock()
+	defer be.encodeLock.Unlock()
+
+	return be.encoder.Encode(item)
+}
+
+func (be *AbstractEventConnection) addRequest(functionLogger logger.Logger) (string, *pendingRequest, error) {
+	be.requestsLock.Lock()
+	defer be.requestsLock.Unlock()
+
+	if be.closed {
+		return "", nil, errors.New("Event connection is closed")
+	}
+
+	be.lastRequestID++
+	requestID := strconv.FormatUint(be.lastRequestID, 10)
+
+	// buffered, so that results never block reading the wrapper's output
+	request := &pendingRequest{
+		resultChan:     make(chan *result.BatchedResults, 1),
+		functionLogger: functionLogger,
+	}
+
+	be.requests[requestID] = request
+
+	return requestID, request, nil
+}
+
+func (be *AbstractEventConnection) removeRequest(requestID string) (*pendingRequest, bool) {
+	be.requestsLock.Lock()
+	defer be.requestsLock.Unlock()
+
+	request, found := be.requests[requestID]
+	if !found && requestID == "" && len(be.requests) == 1 {
+
+		// wrappers may not tag results they failed to produce properly (e.g. when an event can't be decoded).
+		// these can only be matched when a single request is in flight
+		for requestID, request = range be.requests {
+			found = true
+		}
+	}
+
+	delete(be.requests, requestID)
+
+	return request, found
+}
+
+// failRequests fails the requests in flight, as well as any request added later, since no more results
+// will be read
+func (be *AbstractEventConnection) failRequests(processingResults *result.BatchedResults) {
+	be.requestsLock.Lock()
+	defer be.requestsLock.Unlock()
+
+	be.closed = true
+
+	for requestID, request := range be.requests {
+		request.resultChan <- processingResults
+		delete(be.requests, requestID)
+	}
+}
+
+func (be *AbstractEventConnection) handleResults(processingResults *result.BatchedResults) {
+	if be.GetMaxConcurrentEvents() == 1 {
+		be.resultChan <- processingResults
+		return
+	}
+
+	requestID := processingResults.GetRequestID()
+
+	request, found := be.removeRequest(requestID)
+	if !found {
+		be.Logger.WarnWith("Dropping results of an unknown request", "requestID", requestID)
+		return
+	}
+
+	request.resultChan <- processingResults
+}
+
+// resolveFunctionLogger returns the logger of the request with the given ID, or of the event being processed
+// if the wrapper processes one event at a time
+func (be *AbstractEventConnection) resolveFunctionLogger(requestID string) logger.Logger {
+	if requestID != "" {
+		be.requestsLock.Lock()
+		request, found := be.requests[requestID]
+		be.requestsLock.Unlock()
+
+		if found && request.functionLogger != nil {
+			return request.functionLogger
+		}
+
+		return be.Logger
+	}
+
 	if be.functionLogger == nil {
 		return be.Logger
 	}
@@ -156,13 +356,17 @@ func (be *AbstractEventConnection) RunHandler() {
 			CustomHandler: nil,
 		})
 	defer func() {
-		select {
-		case be.resultChan <- &result.BatchedResults{
+		restartedResults := &result.BatchedResults{
 			Results: []*result.Result{{
 				StatusCode: http.StatusRequestTimeout,
 				Err:        errors.New("Runtime restarted"),
 			}},
-		}:
+		}
+
+		be.failRequests(restartedResults)
+
+		select {
+		case be.resultChan <- restartedResults:
 
 		default:
 			be.Logger.Warn("Nothing waiting on result channel during restart. Continuing")
@@ -172,6 +376,9 @@ func (be *AbstractEventConnection) RunHandler() {
 
 	outReader := bufio.NewReader(be.Conn)
 
+	// wrappers speak the line protocol, unless they negotiate another version
+	var outMessageReader messageReader = &lineMessageReader{reader: outReader}
+
 	// Read logs & output
 	for {
 		select {
@@ -184,40 +391,141 @@ func (be *AbstractEventConnection) RunHandler() {
 		default:
 
 			unmarshalledResults := result.NewBatchedResults()
+			var messageType byte
 			var data []byte
-			data, unmarshalledResults.Err = outReader.ReadBytes('\n')
+			messageType, data, unmarshalledResults.Err = outMessageReader.readMessage()
 
 			if unmarshalledResults.Err != nil {
 				be.Logger.WarnWith(string(common.FailedReadFromEventConnection),
 					"err", unmarshalledResults.Err.Error())
+
+				// the requests in flight can't be matched with their results anymore
+				if be.GetMaxConcurrentEvents() > 1 {
+					be.failRequests(unmarshalledResults)
+					return
+				}
+
 				be.resultChan <- unmarshalledResults
 				continue
 			}
 
-			switch data[0] {
+			switch messageType {
 			case 'r':
-				unmarshalledResults.UnmarshalResponseData(be.Logger, data[1:])
+				if be.payloadEncoding == EncodingProtobuf {
+					unmarshalledResults.UnmarshalProtobufResponseData(be.Logger, data)
+				} else if outMessageReader.getProtocolVersion() == ProtocolVersionFrames {
+					unmarshalledResults.UnmarshalFramedResponseData(be.Logger, data)
+				} else {
+					unmarshalledResults.UnmarshalResponseData(be.Logger, data)
+				}
 
-				// write back to result channel
-				be.resultChan <- unmarshalledResults
+				// write back to the request's result channel
+				be.handleResults(unmarshalledResults)
 			case 'm':
-				be.handleResponseMetric(data[1:])
+				be.handleResponseMetric(data)
 			case 'l':
-				be.handleResponseLog(data[1:])
+				be.handleResponseLog(data)
 			case 's':
 				be.handleStart()
+			case 'd':
+
+				// when several events are processed at a time, a slow data binding mustn't hold back the others
+				if be.GetMaxConcurrentEvents() > 1 {
+					go be.handleDataBindingRequest(data)
+				} else {
+					be.handleDataBindingRequest(data)
+				}
+			case 'p':
+				negotiatedMessageReader, err := be.handleProtocolHandshake(data, outReader)
+				if err != nil {
+
+					// the wrapper sends whatever follows in the version it asked for, which can't be read.
+					// close the connection so that it exits and is restarted, rather than misreading it
+					be.Logger.ErrorWith("Failed to negotiate protocol version", "err", err.Error())
+					be.failConnection()
+					return
+				}
+
+				outMessageReader = negotiatedMessageReader
 			}
 		}
 	}
 }
 
-func (be *AbstractEventConnection) handleResponseMetric(response []byte) {
-	var metrics struct {
-		DurationSec float64 `json:"duration"`
+// handleProtocolHandshake returns a reader of the protocol version the wrapper negotiated. The wrapper sends
+// everything after the handshake in that version
+func (be *AbstractEventConnection) handleProtocolHandshake(handshake []byte,
+	outReader *bufio.Reader) (messageReader, error) {
+
+	decodedHandshake, err := decodeProtocolHandshake(handshake)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to decode protocol handshake")
+	}
+
+	if decodedHandshake.Encoding != EncodingDefault && decodedHandshake.Encoding != be.encoding {
+		return nil, errors.Errorf("Encoding %s wasn't offered to the wrapper", decodedHandshake.Encoding)
 	}
 
-	loggerInstance := be.resolveFunctionLogger()
-	if err := json.Unmarshal(response, &metrics); err != nil {
+	negotiatedMessageReader, err := newMessageReader(outReader, decodedHandshake.Version)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create message reader")
+	}
+
+	if decodedHandshake.Encoding == EncodingProtobuf {
+		be.payloadEncoding = EncodingProtobuf
+
+		// events are sent in the negotiated encoding too, and may not be sent while it's being replaced
+		be.encodeLock.Lock()
+		be.encoder = encoder.NewEventProtobufEncoder(be.Logger, be.Conn)
+		be.encodeLock.Unlock()
+	}
+
+	// wrappers may process fewer events at a time than allowed, but not more
+	if concurrentEvents := min(decodedHandshake.MaxConcurrentEvents, be.maxConcurrentEvents); concurrentEvents > 1 {
+		atomic.StoreInt32(&be.negotiatedConcurrentEvents, int32(concurrentEvents))
+	}
+
+	be.Logger.DebugWith("Negotiated protocol version",
+		"version", decodedHandshake.Version,
+		"encoding", decodedHandshake.Encoding,
+		"maxConcurrentEvents", be.GetMaxConcurrentEvents())
+
+	return negotiatedMessageReader, nil
+}
+
+// failConnection closes the connection to the wrapper and stops it, releasing those waiting for the wrapper
+// to start. Events sent afterwards fail
+func (be *AbstractEventConnection) failConnection() {
+	if err := be.Conn.Close(); err != nil {
+		be.Logger.WarnWith("Failed to close event connection", "err", err.Error())
+	}
+
+	if be.connectionManager != nil {
+		be.connectionManager.SetStatus(status.Error)
+	}
+
+	be.Stop()
+}
+
+// protobufUnmarshaler is implemented by the messages wrappers send, which may be encoded as protobuf
+type protobufUnmarshaler interface {
+	UnmarshalProtobuf(data []byte) error
+}
+
+// unmarshalPayload decodes the payload of a wrapper message, in the encoding the wrapper negotiated
+func (be *AbstractEventConnection) unmarshalPayload(payload []byte, message protobufUnmarshaler) error {
+	if be.payloadEncoding == EncodingProtobuf {
+		return message.UnmarshalProtobuf(payload)
+	}
+
+	return json.Unmarshal(payload, message)
+}
+
+func (be *AbstractEventConnection) handleResponseMetric(response []byte) {
+	var metrics result.Metric
+
+	loggerInstance := be.resolveFunctionLogger("")
+	if err := be.unmarshalPayload(response, &metrics); err != nil {
 		loggerInstance.ErrorWith("Can't decode metric", "error", err)
 		return
 	}
@@ -232,12 +540,12 @@ func (be *AbstractEventConnection) handleResponseMetric(response []byte) {
 func (be *AbstractEventConnection) handleResponseLog(response []byte) {
 	var logRecord result.RpcLogRecord
 
-	if err := json.Unmarshal(response, &logRecord); err != nil {
+	if err := be.unmarshalPayload(response, &logRecord); err != nil {
 		be.Logger.ErrorWith("Can't decode log", "error", err)
 		return
 	}
 
-	loggerInstance := be.resolveFunctionLogger()
+	loggerInstance := be.resolveFunctionLogger(logRecord.RequestID)
 	logFunc := loggerInstance.DebugWith
 
 	switch logRecord.Level {
@@ -253,6 +561,99 @@ func (be *AbstractEventConnection) handleResponseLog(response []byte) {
 	logFunc(logRecord.Message, vars...)
 }
 
+func (be *AbstractEventConnection) handleDataBindingRequest(request []byte) {
+	var dataBindingRequest result.DataBindingRequest
+
+	if err := be.unmarshalPayload(request, &dataBindingRequest); err != nil {
+		be.resolveFunctionLogger("").ErrorWith("Can't decode data binding request", "error", err)
+		return
+	}
+
+	loggerInstance := be.resolveFunctionLogger(dataBindingRequest.RequestID)
+
+	dataBindingResponse, err := be.invokeDataBinding(&dataBindingRequest)
+	if dataBindingRequest.Async {
+		if err != nil {
+			loggerInstance.WarnWith("Failed to send message to data binding",
+				"name", dataBindingRequest.Name,
+				"err", err.Error())
+		}
+		return
+	}
+
+	dataBindingResponse.Kind = result.DataBindingResponseKind
+	dataBindingResponse.ID = dataBindingRequest.ID
+
+	if err != nil {
+		dataBindingResponse.Error = err.Error()
+	}
+
+	if err := be.encode(dataBindingResponse); err != nil {
+		loggerInstance.WarnWith("Failed to write data binding response", "err", err.Error())
+	}
+}
+
+func (be *AbstractEventConnection) invokeDataBinding(dataBindingRequest *result.DataBindingRequest) (*result.DataBindingResponse, error) {
+	var err error
+	dataBindingResponse := &result.DataBindingResponse{}
+
+	dataBinding, found := be.dataBindings[dataBindingRequest.Name]
+	if !found {
+		return dataBindingResponse, errors.Errorf("Data binding %s does not exist", dataBindingRequest.Name)
+	}
+
+	switch dataBindingRequest.Operation {
+	case "", result.DataBindingOperationSend:
+		messageProducer, isMessageProducer := dataBinding.(databinding.MessageProducer)
+		if !isMessageProducer {
+			return dataBindingResponse, errors.Errorf("Data binding %s does not support sending messages",
+				dataBindingRequest.Name)
+		}
+
+		if dataBindingRequest.Async {
+			return dataBindingResponse, messageProducer.SendAsync(dataBindingRequest.Topic,
+				dataBindingRequest.Key,
+				dataBindingRequest.Value,
+				dataBindingRequest.Headers)
+		}
+
+		dataBindingResponse.Partition, dataBindingResponse.Offset, err = messageProducer.Send(dataBindingRequest.Topic,
+			dataBindingRequest.Key,
+			dataBindingRequest.Value,
+			dataBindingRequest.Headers)
+
+	case result.DataBindingOperationGet,
+		result.DataBindingOperationPut,
+		result.DataBindingOperationList,
+		result.DataBindingOperationGetPresignedURL:
+		objectStore, isObjectStore := dataBinding.(databinding.ObjectStore)
+		if !isObjectStore {
+			return dataBindingResponse, errors.Errorf("Data binding %s does not support object operations",
+				dataBindingRequest.Name)
+		}
+
+		objectKey := string(dataBindingRequest.Key)
+
+		switch dataBindingRequest.Operation {
+		case result.DataBindingOperationGet:
+			dataBindingResponse.Value, err = objectStore.Get(objectKey)
+		case result.DataBindingOperationPut:
+			err = objectStore.Put(objectKey, dataBindingRequest.Value, dataBindingRequest.ContentType)
+		case result.DataBindingOperationList:
+			dataBindingResponse.Objects, err = objectStore.List(dataBindingRequest.Prefix)
+		case result.DataBindingOperationGetPresignedURL:
+			dataBindingResponse.URL, err = objectStore.GetPresignedURL(objectKey,
+				dataBindingRequest.Method,
+				time.Duration(dataBindingRequest.ExpirationSeconds)*time.Second)
+		}
+
+	default:
+		return dataBindingResponse, errors.Errorf("Unknown data binding operation %s", dataBindingRequest.Operation)
+	}
+
+	return dataBindingResponse, err
+}
+
 func (be *AbstractEventConnection) handleStart() {
 	be.startChan <- struct{}{}
 }
@@ -260,7 +661,12 @@ func (be *AbstractEventConnection) handleStart() {
 type AbstractControlMessageConnection struct {
 	*AbstractConnection
 
-	broker controlcommunication.ControlMessageBroker
+	broker           controlcommunication.ControlMessageBroker
+	invocationClient *invocation.Client
+
+	// invocation results are written while control messages are read, and encoders aren't safe for
+	// concurrent use
+	writeLock sync.Mutex
 }
 
 func NewAbstractControlMessageConnection(parentLogger logger.Logger, broker controlcommunication.ControlMessageBroker) *AbstractControlMessageConnection {
@@ -286,6 +692,11 @@ func (bc *AbstractControlMessageConnection) GetBroker() controlcommunication.Con
 	return bc.broker
 }
 
+// SetInvocationClient sets the client through which the wrapper may call other functions
+func (bc *AbstractControlMessageConnection) SetInvocationClient(invocationClient *invocation.Client) {
+	bc.invocationClient = invocationClient
+}
+
 func (bc *AbstractControlMessageConnection) RunHandler() {
 
 	// recover from panic in case of error
@@ -344,6 +755,12 @@ func (bc *AbstractControlMessageConnection) RunHandler() {
 
 			bc.Logger.DebugWith("Received control message", "messageKind", controlMessage.Kind)
 
+			// calls may take a while, and are answered to the wrapper rather than to consumers
+			if controlMessage.Kind == controlcommunication.InvokeFunctionKind {
+				go bc.handleInvokeFunction(controlMessage)
+				continue
+			}
+
 			// send message to control consumers
 			if err := bc.broker.SendToConsumers(controlMessage); err != nil {
 				bc.Logger.WarnWith("Failed to send control message to consumers", "err", err.Error())
@@ -353,3 +770,108 @@ func (bc *AbstractControlMessageConnection) RunHandler() {
 		}
 	}
 }
+
+func (bc *AbstractControlMessageConnection) handleInvokeFunction(controlMessage *controlcommunication.ControlMessage) {
+	invokeFunctionAttributes := &controlcommunication.ControlMessageAttributesInvokeFunction{}
+
+	if err := mapstructure.Decode(controlMessage.Attributes, invokeFunctionAttributes); err != nil {
+		bc.Logger.WarnWith("Failed decoding control message attributes", "err", err.Error())
+
+		// the wrapper waits for the result of its call regardless, which it matches by the raw id
+		bc.writeInvokeFunctionResult(map[string]interface{}{
+			"id":    controlMessage.Attributes["id"],
+			"error": errors.Wrap(err, "Failed to decode invocation attributes").Error(),
+		})
+		return
+	}
+
+	resultAttributes, err := bc.invokeFunction(invokeFunctionAttributes)
+	if err != nil {
+		resultAttributes["error"] = err.Error()
+
+		if errorWithStatusCode, isErrorWithStatusCode := err.(*nuclio.ErrorWithStatusCode); isErrorWithStatusCode {
+			resultAttributes["statusCode"] = errorWithStatusCode.StatusCode()
+		}
+	}
+
+	resultAttributes["id"] = invokeFunctionAttributes.ID
+
+	bc.writeInvokeFunctionResult(resultAttributes)
+}
+
+func (bc *AbstractControlMessageConnection) writeInvokeFunctionResult(resultAttributes map[string]interface{}) {
+	bc.writeLock.Lock()
+	defer bc.writeLock.Unlock()
+
+	if err := bc.broker.WriteControlMessage(&controlcommunication.ControlMessage{
+		Kind:       controlcommunication.InvokeFunctionResultKind,
+		Attributes: resultAttributes,
+	}); err != nil {
+		bc.Logger.WarnWith("Failed to write invocation result", "err", err.Error())
+	}
+}
+
+func (bc *AbstractControlMessageConnection) invokeFunction(
+	invokeFunctionAttributes *controlcommunication.ControlMessageAttributesInvokeFunction) (map[string]interface{}, error) {
+	resultAttributes := map[string]interface{}{}
+
+	if bc.invocationClient == nil {
+		return resultAttributes, errors.New("Function invocation is not supported")
+	}
+
+	body, err := base64.StdEncoding.DecodeString(invokeFunctionAttributes.Body)
+	if err != nil {
+		return resultAttributes, errors.Wrap(err, "Failed to decode body")
+	}
+
+	event := &nuclio.MemoryEvent{
+		Method:      invokeFunctionAttributes.Method,
+		Path:        invokeFunctionAttributes.Path,
+		ContentType: invokeFunctionAttributes.ContentType,
+		Headers:     invokeFunctionAttributes.Headers,
+		Body:        body,
+	}
+
+	options := &invocation.Options{
+		CorrelationID: invokeFunctionAttributes.CorrelationID,
+		Timeout:       time.Duration(invokeFunctionAttributes.Timeout * float64(time.Second)),
+		Retries:       invokeFunctionAttributes.Retries,
+		RetryBackoff:  time.Duration(invokeFunctionAttributes.RetryBackoff * float64(time.Second)),
+	}
+
+	if invokeFunctionAttributes.Deadline != "" {
+		if options.Deadline, err = time.Parse(time.RFC3339Nano, invokeFunctionAttributes.Deadline); err != nil {
+			return resultAttributes, errors.Wrap(err, "Failed to parse deadline")
+		}
+	}
+
+	if invokeFunctionAttributes.Async {
+		resultAttributes["correlationId"], err = bc.invocationClient.InvokeAsync(invokeFunctionAttributes.FunctionName,
+			event,
+			options)
+
+		return resultAttributes, err
+	}
+
+	// the correlation ID is resolved here, so that it can be returned along with the response
+	if options.CorrelationID == "" {
+		options.CorrelationID = uuid.New().String()
+	}
+
+	resultAttributes["correlationId"] = options.CorrelationID
+
+	response, err := bc.invocationClient.Invoke(context.Background(),
+		invokeFunctionAttributes.FunctionName,
+		event,
+		options)
+	if err != nil {
+		return resultAttributes, err
+	}
+
+	resultAttributes["statusCode"] = response.StatusCode
+	resultAttributes["contentType"] = response.ContentType
+	resultAttributes["headers"] = response.Headers
+	resultAttributes["body"] = base64.StdEncoding.EncodeToString(response.Body)
+
+	return resultAttributes, nil
+}
@@ -0,0 +1,340 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connection
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/invocation"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/encoder"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
+
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type testTriggerInfo struct{}
+
+func (ti *testTriggerInfo) GetClass() string { return "test class" }
+func (ti *testTriggerInfo) GetKind() string  { return "test kind" }
+func (ti *testTriggerInfo) GetName() string  { return "test name" }
+
+type processEventResult struct {
+	results *result.BatchedResults
+	err     error
+}
+
+type EventConnectionTestSuite struct {
+	suite.Suite
+	eventConnection *AbstractEventConnection
+	wrapperConn     net.Conn
+	wrapperReader   *bufio.Reader
+}
+
+func (suite *EventConnectionTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("event-connection-test")
+	suite.Require().NoError(err)
+
+	suite.eventConnection = NewAbstractEventConnection(loggerInstance, nil)
+	suite.eventConnection.Conn, suite.wrapperConn = net.Pipe()
+	suite.eventConnection.SetEncoder(encoder.NewEventJSONEncoder(loggerInstance, suite.eventConnection.Conn))
+	suite.eventConnection.SetMaxConcurrentEvents(4)
+	suite.wrapperReader = bufio.NewReader(suite.wrapperConn)
+
+	go suite.eventConnection.RunHandler()
+}
+
+func (suite *EventConnectionTestSuite) TearDownTest() {
+	suite.wrapperConn.Close() // nolint: errcheck
+}
+
+func (suite *EventConnectionTestSuite) TestConcurrentEvents() {
+
+	// the wrapper may process fewer events at a time than allowed
+	suite.start(`{"version": 1, "max_concurrent_events": 2}`)
+	suite.Require().Equal(2, suite.eventConnection.GetMaxConcurrentEvents())
+
+	firstResultChan := suite.processEvent("first")
+	secondResultChan := suite.processEvent("second")
+
+	// read both events before replying to any of them
+	firstEvent := suite.readEvent()
+	secondEvent := suite.readEvent()
+	suite.Require().NotEqual(firstEvent["request_id"], secondEvent["request_id"])
+
+	// reply out of order, with each event's body
+	for _, event := range []map[string]interface{}{secondEvent, firstEvent} {
+		suite.write(fmt.Sprintf(`r{"status_code": 200, "body_encoding": "base64", "body": "%s", "request_id": "%s"}`,
+			event["body"],
+			event["request_id"]))
+	}
+
+	for expectedBody, resultChan := range map[string]chan processEventResult{
+		"first":  firstResultChan,
+		"second": secondResultChan,
+	} {
+		processedEvent := suite.waitForResult(resultChan)
+		suite.Require().NoError(processedEvent.err)
+		suite.Require().Equal(expectedBody, string(processedEvent.results.Results[0].DecodedBody))
+	}
+}
+
+func (suite *EventConnectionTestSuite) TestOneEventAtATime() {
+
+	// the wrapper doesn't negotiate processing several events at a time, so events aren't tagged
+	suite.start(`{"version": 1}`)
+	suite.Require().Equal(1, suite.eventConnection.GetMaxConcurrentEvents())
+
+	resultChan := suite.processEvent("body")
+	suite.Require().NotContains(suite.readEvent(), "request_id")
+
+	suite.write(`r{"status_code": 200, "body_encoding": "text", "body": "reply"}`)
+
+	processedEvent := suite.waitForResult(resultChan)
+	suite.Require().NoError(processedEvent.err)
+	suite.Require().Equal("reply", string(processedEvent.results.Results[0].DecodedBody))
+}
+
+func (suite *EventConnectionTestSuite) TestDisconnectFailsEventsInFlight() {
+	suite.start(`{"version": 1, "max_concurrent_events": 8}`)
+	suite.Require().Equal(4, suite.eventConnection.GetMaxConcurrentEvents())
+
+	firstResultChan := suite.processEvent("first")
+	secondResultChan := suite.processEvent("second")
+	suite.readEvent()
+	suite.readEvent()
+
+	suite.Require().NoError(suite.wrapperConn.Close())
+
+	for _, resultChan := range []chan processEventResult{firstResultChan, secondResultChan} {
+		suite.Require().Error(suite.waitForResult(resultChan).err)
+	}
+
+	// events can't be processed once the connection is closed
+	_, err := suite.eventConnection.ProcessEvent(suite.newEvent("third"), nil)
+	suite.Require().Error(err)
+}
+
+func (suite *EventConnectionTestSuite) start(handshake string) {
+	suite.write("p" + handshake)
+	suite.write("s")
+
+	select {
+	case <-suite.eventConnection.startChan:
+	case <-time.After(5 * time.Second):
+		suite.Require().Fail("Timed out waiting for start")
+	}
+}
+
+func (suite *EventConnectionTestSuite) processEvent(body string) chan processEventResult {
+	resultChan := make(chan processEventResult, 1)
+
+	go func() {
+		results, err := suite.eventConnection.ProcessEvent(suite.newEvent(body), nil)
+		resultChan <- processEventResult{results, err}
+	}()
+
+	return resultChan
+}
+
+func (suite *EventConnectionTestSuite) newEvent(body string) nuclio.Event {
+	event := &nuclio.MemoryEvent{Body: []byte(body)}
+	event.SetTriggerInfoProvider(&testTriggerInfo{})
+
+	return event
+}
+
+func (suite *EventConnectionTestSuite) readEvent() map[string]interface{} {
+	line, err := suite.wrapperReader.ReadBytes('\n')
+	suite.Require().NoError(err)
+
+	event := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(line, &event))
+
+	return event
+}
+
+func (suite *EventConnectionTestSuite) write(message string) {
+	_, err := suite.wrapperConn.Write([]byte(message + "\n"))
+	suite.Require().NoError(err)
+}
+
+func (suite *EventConnectionTestSuite) waitForResult(resultChan chan processEventResult) processEventResult {
+	select {
+	case processedEvent := <-resultChan:
+		return processedEvent
+	case <-time.After(5 * time.Second):
+		suite.Require().Fail("Timed out waiting for result")
+	}
+
+	return processEventResult{}
+}
+
+type ControlConnectionTestSuite struct {
+	suite.Suite
+	controlConnection *AbstractControlMessageConnection
+	wrapperConn       net.Conn
+	wrapperReader     *bufio.Reader
+	server            *httptest.Server
+}
+
+func (suite *ControlConnectionTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("control-connection-test")
+	suite.Require().NoError(err)
+
+	// the called function echoes the body and correlation ID
+	suite.server = httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		suite.Require().NoError(err)
+
+		suite.Require().Equal("/other-function/path", request.URL.Path)
+		suite.Require().NotEmpty(request.Header.Get(invocation.HeaderDeadline))
+
+		responseWriter.Header().Set("X-Correlation-Id", request.Header.Get(invocation.HeaderCorrelationID))
+		responseWriter.WriteHeader(http.StatusAccepted)
+		responseWriter.Write(body) // nolint: errcheck
+	}))
+
+	invocationClient, err := invocation.NewClient(loggerInstance, &invocation.Configuration{
+		ResolveFunctionURL: func(functionName string) string {
+			return suite.server.URL + "/" + functionName
+		},
+	})
+	suite.Require().NoError(err)
+
+	suite.controlConnection = NewAbstractControlMessageConnection(loggerInstance, nil)
+	suite.controlConnection.Conn, suite.wrapperConn = net.Pipe()
+	suite.controlConnection.SetEncoder(encoder.NewEventJSONEncoder(loggerInstance, suite.controlConnection.Conn))
+	suite.controlConnection.SetBroker(nil)
+	suite.controlConnection.SetInvocationClient(invocationClient)
+	suite.wrapperReader = bufio.NewReader(suite.wrapperConn)
+
+	go suite.controlConnection.RunHandler()
+}
+
+func (suite *ControlConnectionTestSuite) TearDownTest() {
+	suite.wrapperConn.Close() // nolint: errcheck
+	suite.server.Close()
+}
+
+func (suite *ControlConnectionTestSuite) TestInvokeFunction() {
+	suite.write(controlcommunication.InvokeFunctionKind, map[string]interface{}{
+		"id":            "1",
+		"functionName":  "other-function",
+		"method":        http.MethodPost,
+		"path":          "/path",
+		"body":          base64.StdEncoding.EncodeToString([]byte("hello")),
+		"correlationId": "my-correlation-id",
+		"timeout":       5,
+	})
+
+	attributes := suite.readResult()
+	suite.Require().Equal("1", attributes["id"])
+	suite.Require().Equal("my-correlation-id", attributes["correlationId"])
+	suite.Require().EqualValues(http.StatusAccepted, attributes["statusCode"])
+	suite.Require().Equal(base64.StdEncoding.EncodeToString([]byte("hello")), attributes["body"])
+	suite.Require().Equal("my-correlation-id", attributes["headers"].(map[string]interface{})["X-Correlation-Id"])
+	suite.Require().NotContains(attributes, "error")
+}
+
+func (suite *ControlConnectionTestSuite) TestInvokeFunctionAsync() {
+	suite.write(controlcommunication.InvokeFunctionKind, map[string]interface{}{
+		"id":           "2",
+		"functionName": "other-function",
+		"path":         "/path",
+		"async":        true,
+	})
+
+	// async calls are answered as soon as they're queued, with a generated correlation ID
+	attributes := suite.readResult()
+	suite.Require().Equal("2", attributes["id"])
+	suite.Require().NotEmpty(attributes["correlationId"])
+	suite.Require().NotContains(attributes, "statusCode")
+}
+
+func (suite *ControlConnectionTestSuite) TestInvokeFunctionInvalidBody() {
+	suite.write(controlcommunication.InvokeFunctionKind, map[string]interface{}{
+		"id":           "3",
+		"functionName": "other-function",
+		"body":         "not base64!",
+	})
+
+	attributes := suite.readResult()
+	suite.Require().Equal("3", attributes["id"])
+	suite.Require().Contains(attributes["error"], "Failed to decode body")
+}
+
+func (suite *ControlConnectionTestSuite) TestInvokeFunctionInvalidAttributes() {
+	suite.write(controlcommunication.InvokeFunctionKind, map[string]interface{}{
+		"id":           "4",
+		"functionName": "other-function",
+		"timeout":      "not a number",
+	})
+
+	// the call is still answered, so that the wrapper doesn't wait for it
+	attributes := suite.readResult()
+	suite.Require().Equal("4", attributes["id"])
+	suite.Require().Contains(attributes["error"], "Failed to decode invocation attributes")
+}
+
+func (suite *ControlConnectionTestSuite) write(kind controlcommunication.ControlMessageKind,
+	attributes map[string]interface{}) {
+	message, err := json.Marshal(&controlcommunication.ControlMessage{Kind: kind, Attributes: attributes})
+	suite.Require().NoError(err)
+
+	_, err = suite.wrapperConn.Write(append(message, '\n'))
+	suite.Require().NoError(err)
+}
+
+func (suite *ControlConnectionTestSuite) readResult() map[string]interface{} {
+	suite.Require().NoError(suite.wrapperConn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+
+	line, err := suite.wrapperReader.ReadBytes('\n')
+	suite.Require().NoError(err)
+
+	event := struct {
+		Body []byte `json:"body"`
+	}{}
+	suite.Require().NoError(json.Unmarshal(line, &event))
+
+	controlMessage := controlcommunication.ControlMessage{}
+	suite.Require().NoError(json.Unmarshal(event.Body, &controlMessage))
+	suite.Require().Equal(controlcommunication.InvokeFunctionResultKind, controlMessage.Kind)
+
+	return controlMessage.Attributes
+}
+
+func TestEventConnectionTestSuite(t *testing.T) {
+	suite.Run(t, new(EventConnectionTestSuite))
+}
+
+func TestControlConnectionTestSuite(t *testing.T) {
+	suite.Run(t, new(ControlConnectionTestSuite))
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connection
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/nuclio/errors"
+)
+
+// wrapper output protocol versions
+const (
+
+	// ProtocolVersionLines is the original protocol, where every message is a line starting with its type
+	ProtocolVersionLines = 1
+
+	// ProtocolVersionFrames is the protocol where every message is a frame holding its type and length,
+	// so that response bodies can be sent as raw bytes
+	ProtocolVersionFrames = 2
+
+	// LatestProtocolVersion is the newest version the processor speaks
+	LatestProtocolVersion = ProtocolVersionFrames
+
+	// ProtocolVersionEnvName is the name of the environment variable through which wrappers are told the
+	// newest version they may negotiate
+	ProtocolVersionEnvName = "NUCLIO_RPC_PROTOCOL_VERSION"
+
+	// MaxConcurrentEventsEnvName is the name of the environment variable through which wrappers are told the
+	// maximum number of events they may negotiate processing at a time
+	MaxConcurrentEventsEnvName = "NUCLIO_RPC_MAX_CONCURRENT_EVENTS"
+
+	// EncodingEnvName is the name of the environment variable through which wrappers are told the encoding
+	// they may negotiate, if other than the runtime's own
+	EncodingEnvName = "NUCLIO_RPC_ENCODING"
+
+	// a frame header holds the message type followed by the big-endian length of the payload
+	frameHeaderLength = 5
+
+	// same as the limit wrappers use when reading events
+	maxFramePayloadLength = 1024 * 1024 * 1024
+)
+
+// message encodings
+const (
+
+	// EncodingDefault encodes events with the runtime's event encoder, and wrapper messages as JSON
+	EncodingDefault = ""
+
+	// EncodingProtobuf encodes events and wrapper messages as the protobuf messages of rpcproto/rpc.proto.
+	// It requires frames, since the messages are binary
+	EncodingProtobuf = "protobuf"
+)
+
+// messageReader reads messages the wrapper sends on the event connection
+type messageReader interface {
+
+	// readMessage returns the type and the payload of the next message
+	readMessage() (byte, []byte, error)
+
+	// getProtocolVersion returns the protocol version of the messages
+	getProtocolVersion() int
+}
+
+type lineMessageReader struct {
+	reader *bufio.Reader
+}
+
+func (r *lineMessageReader) readMessage() (byte, []byte, error) {
+	data, err := r.reader.ReadBytes('\n')
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return data[0], data[1:], nil
+}
+
+func (r *lineMessageReader) getProtocolVersion() int {
+	return ProtocolVersionLines
+}
+
+type frameMessageReader struct {
+	reader *bufio.Reader
+	header [frameHeaderLength]byte
+}
+
+func (r *frameMessageReader) readMessage() (byte, []byte, error) {
+	if _, err := io.ReadFull(r.reader, r.header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	payloadLength := binary.BigEndian.Uint32(r.header[1:])
+	if payloadLength > maxFramePayloadLength {
+		return 0, nil, errors.Errorf("Frame payload length %d exceeds limit", payloadLength)
+	}
+
+	// allocate a payload per frame, since raw response bodies reference it
+	payload := make([]byte, payloadLength)
+	if _, err := io.ReadFull(r.reader, payload); err != nil {
+		return 0, nil, errors.Wrap(err, "Failed to read frame payload")
+	}
+
+	return r.header[0], payload, nil
+}
+
+func (r *frameMessageReader) getProtocolVersion() int {
+	return ProtocolVersionFrames
+}
+
+// newMessageReader returns a reader of the messages of the given protocol version
+func newMessageReader(reader *bufio.Reader, protocolVersion int) (messageReader, error) {
+	switch protocolVersion {
+	case ProtocolVersionLines:
+		return &lineMessageReader{reader: reader}, nil
+	case ProtocolVersionFrames:
+		return &frameMessageReader{reader: reader}, nil
+	default:
+		return nil, errors.Errorf("Unsupported protocol version %d", protocolVersion)
+	}
+}
+
+// protocolHandshake is sent by wrappers that negotiate a protocol version, as their first message
+type protocolHandshake struct {
+	Version int `json:"version"`
+
+	// the number of events the wrapper accepts at a time, tagged with request IDs. Zero or one mean
+	// the wrapper processes events one by one
+	MaxConcurrentEvents int `json:"max_concurrent_events"`
+
+	// the encoding of the messages following the handshake, in both directions
+	Encoding string `json:"encoding"`
+}
+
+func decodeProtocolHandshake(payload []byte) (*protocolHandshake, error) {
+	var handshake protocolHandshake
+
+	if err := json.Unmarshal(payload, &handshake); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode protocol handshake")
+	}
+
+	if handshake.Version < ProtocolVersionLines || handshake.Version > LatestProtocolVersion {
+		return nil, errors.Errorf("Unsupported protocol version %d", handshake.Version)
+	}
+
+	if handshake.MaxConcurrentEvents < 0 {
+		return nil, errors.Errorf("Invalid max concurrent events %d", handshake.MaxConcurrentEvents)
+	}
+
+	switch handshake.Encoding {
+	case EncodingDefault:
+	case EncodingProtobuf:
+		if handshake.Version < ProtocolVersionFrames {
+			return nil, errors.Errorf("Encoding %s requires protocol version %d", handshake.Encoding, ProtocolVersionFrames)
+		}
+	default:
+		return nil, errors.Errorf("Unsupported encoding %s", handshake.Encoding)
+	}
+
+	return &handshake, nil
+}
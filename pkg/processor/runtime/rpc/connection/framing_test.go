@@ -0,0 +1,176 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connection
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/encoder"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/rpcproto"
+
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type FramingTestSuite struct {
+	suite.Suite
+	eventConnection *AbstractEventConnection
+	wrapperConn     net.Conn
+}
+
+func (suite *FramingTestSuite) SetupTest() {
+	loggerInstance, err := nucliozap.NewNuclioZapTest("framing-test")
+	suite.Require().NoError(err)
+
+	suite.eventConnection = NewAbstractEventConnection(loggerInstance, nil)
+	suite.eventConnection.Conn, suite.wrapperConn = net.Pipe()
+
+	go suite.eventConnection.RunHandler()
+}
+
+func (suite *FramingTestSuite) TearDownTest() {
+	suite.wrapperConn.Close() // nolint: errcheck
+}
+
+func (suite *FramingTestSuite) TestLines() {
+	suite.write([]byte("s\n"))
+	suite.waitForStart()
+
+	suite.write([]byte(`r{"status_code": 200, "body_encoding": "base64", "body": "AP8="}` + "\n"))
+
+	results := suite.readResults()
+	suite.Require().Equal([]byte{0x00, 0xff}, results.Results[0].DecodedBody)
+}
+
+func (suite *FramingTestSuite) TestFrames() {
+	suite.write([]byte(`p{"version": 2}` + "\n"))
+	suite.write(suite.frame('s', nil))
+	suite.waitForStart()
+
+	// bodies may hold anything, including newlines
+	body := []byte{0x00, '\n', 0xff}
+	metadata := []byte(`{"status_code": 201, "content_type": "image/png", "body_encoding": "raw", "body_length": 3}`)
+
+	payload := binary.BigEndian.AppendUint32(nil, uint32(len(metadata)))
+	payload = append(payload, metadata...)
+	payload = append(payload, body...)
+
+	suite.write(suite.frame('r', payload))
+
+	results := suite.readResults()
+	suite.Require().Equal(201, results.Results[0].StatusCode)
+	suite.Require().Equal("image/png", results.Results[0].ContentType)
+	suite.Require().Equal(body, results.Results[0].DecodedBody)
+}
+
+func (suite *FramingTestSuite) TestUnsupportedVersion() {
+	suite.write([]byte(`p{"version": 99}` + "\n"))
+	suite.waitForFailure()
+}
+
+func (suite *FramingTestSuite) TestProtobufEncoding() {
+	suite.eventConnection.SetEncoding(EncodingProtobuf)
+
+	suite.write([]byte(`p{"version": 2, "encoding": "protobuf"}` + "\n"))
+	suite.write(suite.frame('s', nil))
+	suite.waitForStart()
+
+	// events are sent as protobuf from now on
+	suite.eventConnection.encodeLock.Lock()
+	suite.Require().IsType(&encoder.EventProtobufEncoder{}, suite.eventConnection.encoder)
+	suite.eventConnection.encodeLock.Unlock()
+
+	body := []byte{0x00, '\n', 0xff}
+	encodedResult := rpcproto.AppendInt(nil, rpcproto.ResultStatusCode, 201)
+	encodedResult = rpcproto.AppendBytes(encodedResult, rpcproto.ResultBody, body)
+
+	suite.write(suite.frame('r', rpcproto.AppendMessage(nil, rpcproto.ResultsResults, encodedResult)))
+
+	results := suite.readResults()
+	suite.Require().Equal(201, results.Results[0].StatusCode)
+	suite.Require().Equal(body, results.Results[0].DecodedBody)
+}
+
+func (suite *FramingTestSuite) TestProtobufEncodingNotOffered() {
+
+	// the runtime didn't offer the encoding
+	suite.write([]byte(`p{"version": 2, "encoding": "protobuf"}` + "\n"))
+	suite.waitForFailure()
+}
+
+func (suite *FramingTestSuite) TestProtobufEncodingRequiresFrames() {
+	suite.eventConnection.SetEncoding(EncodingProtobuf)
+
+	suite.write([]byte(`p{"version": 1, "encoding": "protobuf"}` + "\n"))
+	suite.waitForFailure()
+}
+
+func (suite *FramingTestSuite) frame(messageType byte, payload []byte) []byte {
+	frame := []byte{messageType}
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+
+	return append(frame, payload...)
+}
+
+func (suite *FramingTestSuite) write(data []byte) {
+	_, err := suite.wrapperConn.Write(data)
+	suite.Require().NoError(err)
+}
+
+func (suite *FramingTestSuite) waitForStart() {
+	select {
+	case <-suite.eventConnection.startChan:
+	case <-time.After(5 * time.Second):
+		suite.Require().Fail("Timed out waiting for start")
+	}
+}
+
+// waitForFailure waits for the connection to fail, since the wrapper would send whatever follows a rejected
+// handshake in a version that can't be read
+func (suite *FramingTestSuite) waitForFailure() {
+	select {
+	case <-suite.eventConnection.stoppedChan:
+	case <-time.After(5 * time.Second):
+		suite.Require().Fail("Timed out waiting for the connection to fail")
+	}
+
+	// the wrapper sees the connection closed
+	_, err := suite.wrapperConn.Read(make([]byte, 1))
+	suite.Require().Error(err)
+}
+
+func (suite *FramingTestSuite) readResults() *result.BatchedResults {
+	select {
+	case results := <-suite.eventConnection.resultChan:
+		suite.Require().NoError(results.Err)
+		return results
+	case <-time.After(5 * time.Second):
+		suite.Require().Fail("Timed out waiting for results")
+	}
+
+	return nil
+}
+
+func TestFramingTestSuite(t *testing.T) {
+	suite.Run(t, new(FramingTestSuite))
+}
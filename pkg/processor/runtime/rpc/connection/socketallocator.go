@@ -114,6 +114,12 @@ func (sa *SocketAllocator) Allocate() (EventConnection, error) {
 	return sa.eventSockets[0], nil
 }
 
+// GetMaxConcurrentEvents returns the number of events the wrapper negotiated processing at a time
+func (sa *SocketAllocator) GetMaxConcurrentEvents() int {
+	// TODO: sum up the sockets when support multiple sockets
+	return sa.eventSockets[0].GetMaxConcurrentEvents()
+}
+
 func (sa *SocketAllocator) GetAddressesForWrapperStart() ([]string, string) {
 	eventAddresses := make([]string, 0)
 
@@ -139,6 +145,9 @@ func (sa *SocketAllocator) startSockets() error {
 			return errors.Wrap(err, "Can't get connection from wrapper")
 		}
 		socket.SetEncoder(sa.Configuration.GetEventEncoderFunc(socket.Conn))
+		socket.SetDataBindings(sa.Configuration.DataBindings)
+		socket.SetMaxConcurrentEvents(sa.Configuration.MaxConcurrentEvents)
+		socket.SetEncoding(sa.Configuration.Encoding)
 		go socket.AbstractEventConnection.RunHandler()
 	}
 	sa.Logger.Debug("Successfully established connection for event sockets")
@@ -152,6 +161,7 @@ func (sa *SocketAllocator) startSockets() error {
 
 		// initialize control message broker
 		sa.controlMessageSocket.SetBroker(sa.RuntimeConfiguration.ControlMessageBroker)
+		sa.controlMessageSocket.SetInvocationClient(sa.Configuration.InvocationClient)
 		go sa.controlMessageSocket.RunHandler()
 		sa.Logger.Debug("Successfully established connection for control socket")
 	}
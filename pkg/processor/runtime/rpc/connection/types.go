@@ -20,11 +20,13 @@ import (
 	"io"
 
 	"github.com/nuclio/nuclio/pkg/common/status"
+	"github.com/nuclio/nuclio/pkg/processor/invocation"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/encoder"
 	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
 
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 )
 
 type ConnectionManager interface {
@@ -50,6 +52,10 @@ type ConnectionManager interface {
 
 	// SetStatus updates the operational status of the ConnectionManager
 	SetStatus(status.Status)
+
+	// GetMaxConcurrentEvents returns the number of events that may be processed at a time, as negotiated
+	// with the wrapper
+	GetMaxConcurrentEvents() int
 }
 
 type EventConnection interface {
@@ -64,6 +70,9 @@ type EventConnection interface {
 
 	// RunHandler starts the main event handler loop, managing incoming responses until the connection is stopped
 	RunHandler()
+
+	// GetMaxConcurrentEvents returns the number of events the wrapper negotiated processing at a time
+	GetMaxConcurrentEvents() int
 }
 
 type ManagerConfigration struct {
@@ -73,6 +82,10 @@ type ManagerConfigration struct {
 	SocketType                  SocketType
 	GetEventEncoderFunc         func(writer io.Writer) encoder.EventEncoder
 	Statistics                  runtime.Statistics
+	DataBindings                map[string]nuclio.DataBinding
+	InvocationClient            *invocation.Client
+	MaxConcurrentEvents         int
+	Encoding                    string
 }
 
 type ManagerKind string
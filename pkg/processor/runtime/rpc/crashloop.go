@@ -0,0 +1,209 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/nuclio/errors"
+)
+
+// crash loop defaults, which may be overridden through runtime attributes
+const (
+	DefaultCrashLoopThreshold = 5
+	DefaultRestartBackoff     = time.Second
+	DefaultMaxRestartBackoff  = 30 * time.Second
+
+	// a wrapper that ran this long before crashing is considered stable, so its earlier crashes are forgotten
+	crashLoopResetDuration = time.Minute
+
+	// how much of the wrapper's stderr is kept for crash reports
+	stderrTailMaxLines      = 20
+	stderrTailMaxLineLength = 1024
+)
+
+// errWrapperExited is returned when the wrapper exits before it starts (e.g. when the handler fails to
+// initialize), so that it's restarted like a wrapper that crashed after starting
+var errWrapperExited = errors.New("Wrapper process exited unexpectedly")
+
+// errWrapperCrashLooping is returned when a crashed wrapper isn't restarted since it's crash looping, and
+// errRestartCancelled when the runtime was stopped or restarted while backing off from restarting it
+var (
+	errWrapperCrashLooping = errors.New(string(common.WrapperProcessCrashLooping))
+	errRestartCancelled    = errors.New("Restarting crashed wrapper process was cancelled")
+)
+
+type crashLoopConfiguration struct {
+	threshold         int
+	restartBackoff    time.Duration
+	maxRestartBackoff time.Duration
+}
+
+func newCrashLoopConfiguration(threshold int,
+	restartBackoff string,
+	maxRestartBackoff string) (*crashLoopConfiguration, error) {
+	var err error
+
+	configuration := &crashLoopConfiguration{
+		threshold:         threshold,
+		restartBackoff:    DefaultRestartBackoff,
+		maxRestartBackoff: DefaultMaxRestartBackoff,
+	}
+
+	if configuration.threshold < 0 {
+		return nil, errors.Errorf("Invalid crash loop threshold %d", configuration.threshold)
+	}
+
+	if configuration.threshold == 0 {
+		configuration.threshold = DefaultCrashLoopThreshold
+	}
+
+	if restartBackoff != "" {
+		if configuration.restartBackoff, err = time.ParseDuration(restartBackoff); err != nil {
+			return nil, errors.Wrap(err, "Failed to parse restart backoff")
+		}
+	}
+
+	if maxRestartBackoff != "" {
+		if configuration.maxRestartBackoff, err = time.ParseDuration(maxRestartBackoff); err != nil {
+			return nil, errors.Wrap(err, "Failed to parse max restart backoff")
+		}
+	}
+
+	if configuration.restartBackoff < 0 || configuration.maxRestartBackoff < configuration.restartBackoff {
+		return nil, errors.Errorf("Invalid restart backoff %s (max %s)",
+			configuration.restartBackoff,
+			configuration.maxRestartBackoff)
+	}
+
+	return configuration, nil
+}
+
+// crashLoopTracker counts the consecutive crashes of a wrapper, and resolves how long to wait before
+// restarting it, if at all
+type crashLoopTracker struct {
+	lock          sync.Mutex
+	configuration *crashLoopConfiguration
+	status        runtime.CrashLoopStatus
+}
+
+// recordCrash records a crash of a wrapper that ran for the given duration, and returns the time to wait
+// before restarting it. It returns false if the wrapper is crash looping and shouldn't be restarted
+func (t *crashLoopTracker) recordCrash(crashErr error,
+	stderrLines []string,
+	uptime time.Duration) (time.Duration, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if uptime >= crashLoopResetDuration {
+		t.status.Crashes = 0
+	}
+
+	t.status.Crashes++
+	t.status.LastCrashTime = time.Now()
+	t.status.LastError = crashErr.Error()
+	t.status.LastStderrLines = stderrLines
+
+	if t.status.Crashes >= t.configuration.threshold {
+		t.status.CrashLooping = true
+		return 0, false
+	}
+
+	// double the backoff with every consecutive crash
+	backoff := t.configuration.restartBackoff
+	for crash := 1; crash < t.status.Crashes && backoff < t.configuration.maxRestartBackoff; crash++ {
+		backoff *= 2
+	}
+
+	return min(backoff, t.configuration.maxRestartBackoff), true
+}
+
+func (t *crashLoopTracker) getStatus() *runtime.CrashLoopStatus {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	status := t.status
+	status.LastStderrLines = append([]string(nil), t.status.LastStderrLines...)
+
+	return &status
+}
+
+// stderrTail keeps the last lines written to it
+type stderrTail struct {
+	lock        sync.Mutex
+	lines       []string
+	partialLine []byte
+}
+
+func (t *stderrTail) Write(data []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	written := len(data)
+
+	for len(data) > 0 {
+		lineEnd := bytes.IndexByte(data, '\n')
+		if lineEnd < 0 {
+			t.partialLine = appendTruncated(t.partialLine, data)
+			break
+		}
+
+		t.addLine(string(appendTruncated(t.partialLine, data[:lineEnd])))
+		t.partialLine = t.partialLine[:0]
+		data = data[lineEnd+1:]
+	}
+
+	return written, nil
+}
+
+// getLines returns the last lines written, including a line that wasn't terminated yet
+func (t *stderrTail) getLines() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	lines := append([]string(nil), t.lines...)
+	if len(t.partialLine) > 0 {
+		lines = append(lines, string(t.partialLine))
+	}
+
+	return lines
+}
+
+func (t *stderrTail) reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.lines = nil
+	t.partialLine = t.partialLine[:0]
+}
+
+func (t *stderrTail) addLine(line string) {
+	if len(t.lines) == stderrTailMaxLines {
+		t.lines = append(t.lines[:0], t.lines[1:]...)
+	}
+
+	t.lines = append(t.lines, line)
+}
+
+func appendTruncated(line []byte, data []byte) []byte {
+	return append(line, data[:min(len(data), max(stderrTailMaxLineLength-len(line), 0))]...)
+}
@@ -0,0 +1,112 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CrashLoopTestSuite struct {
+	suite.Suite
+}
+
+func (suite *CrashLoopTestSuite) TestRecordCrash() {
+	configuration, err := newCrashLoopConfiguration(4, "1s", "3s")
+	suite.Require().NoError(err)
+
+	tracker := &crashLoopTracker{configuration: configuration}
+	crashErr := fmt.Errorf("exit status 1")
+
+	// the backoff doubles with every consecutive crash, up to the max
+	for _, expectedBackoff := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		backoff, restart := tracker.recordCrash(crashErr, []string{"ImportError"}, time.Second)
+		suite.Require().True(restart)
+		suite.Require().Equal(expectedBackoff, backoff)
+	}
+
+	// a wrapper that ran long enough is considered stable, so earlier crashes are forgotten
+	backoff, restart := tracker.recordCrash(crashErr, nil, crashLoopResetDuration)
+	suite.Require().True(restart)
+	suite.Require().Equal(time.Second, backoff)
+
+	for crash := 2; crash < 4; crash++ {
+		_, restart = tracker.recordCrash(crashErr, nil, time.Second)
+		suite.Require().True(restart)
+	}
+
+	_, restart = tracker.recordCrash(crashErr, []string{"ImportError"}, time.Second)
+	suite.Require().False(restart)
+
+	crashLoopStatus := tracker.getStatus()
+	suite.Require().True(crashLoopStatus.CrashLooping)
+	suite.Require().Equal(4, crashLoopStatus.Crashes)
+	suite.Require().Equal(crashErr.Error(), crashLoopStatus.LastError)
+	suite.Require().Equal([]string{"ImportError"}, crashLoopStatus.LastStderrLines)
+}
+
+func (suite *CrashLoopTestSuite) TestInvalidConfiguration() {
+	for _, testCase := range []struct {
+		name              string
+		threshold         int
+		restartBackoff    string
+		maxRestartBackoff string
+	}{
+		{name: "negativeThreshold", threshold: -1},
+		{name: "invalidBackoff", restartBackoff: "soon"},
+		{name: "maxBelowBackoff", restartBackoff: "10s", maxRestartBackoff: "1s"},
+	} {
+		suite.Run(testCase.name, func() {
+			_, err := newCrashLoopConfiguration(testCase.threshold, testCase.restartBackoff, testCase.maxRestartBackoff)
+			suite.Require().Error(err)
+		})
+	}
+}
+
+func (suite *CrashLoopTestSuite) TestStderrTail() {
+	tail := &stderrTail{}
+
+	// lines may be split across writes
+	for line := 0; line < stderrTailMaxLines+5; line++ {
+		_, err := fmt.Fprintf(tail, "line %d\n", line)
+		suite.Require().NoError(err)
+	}
+
+	_, err := tail.Write([]byte("partial "))
+	suite.Require().NoError(err)
+	_, err = tail.Write([]byte(strings.Repeat("x", 2*stderrTailMaxLineLength)))
+	suite.Require().NoError(err)
+
+	lines := tail.getLines()
+	suite.Require().Len(lines, stderrTailMaxLines+1)
+	suite.Require().Equal("line 5", lines[0])
+	suite.Require().Equal(fmt.Sprintf("line %d", stderrTailMaxLines+4), lines[stderrTailMaxLines-1])
+	suite.Require().Len(lines[stderrTailMaxLines], stderrTailMaxLineLength)
+
+	tail.reset()
+	suite.Require().Empty(tail.getLines())
+}
+
+func TestCrashLoopTestSuite(t *testing.T) {
+	suite.Run(t, new(CrashLoopTestSuite))
+}
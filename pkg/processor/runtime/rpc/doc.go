@@ -28,6 +28,61 @@ The wite protocol is line oriented where every line is a JSON object.
     - 'r' Handler reply
     - 'l' Log messages
 	- 'm' Metric messages
+	- 'd' Data binding requests (e.g. sending a message, or getting an object). Unless
+	  the request is async, Go replies with a data binding response on the event connection
+
+# Framed Protocol
+The processor sets NUCLIO_RPC_PROTOCOL_VERSION to the newest protocol version it
+speaks. A wrapper may negotiate version 2 by sending `p{"version": 2}` as its
+first line. Every message after that is a frame: the message type byte, the
+big-endian uint32 length of the payload, and the payload. Payloads are the same
+JSON objects, except for replies, which hold the big-endian uint32 length of
+the reply JSON, the reply JSON, and then the raw bodies of the replies whose
+`body_encoding` is `raw`, in order, each `body_length` bytes long. Events are
+encoded the same way in both versions. A handshake the processor can't accept
+fails the connection, so that the wrapper exits and is restarted rather than
+having its messages misread. The Python and Node.js wrappers negotiate frames,
+and send binary response bodies raw.
+
+# Concurrent Events
+When the `maxConcurrentEvents` runtime attribute is greater than 1, the processor
+sets NUCLIO_RPC_MAX_CONCURRENT_EVENTS to it. A wrapper that can process several
+events at a time negotiates how many by adding `max_concurrent_events` to the
+handshake, e.g. `p{"version": 1, "max_concurrent_events": 10}`, before sending
+'s'. The processor then sends up to that many events without waiting for their
+replies, each holding a `request_id`. Replies, log messages and data binding
+requests hold the `request_id` of the event they belong to, and replies may be
+sent in any order. Each event the runtime may process at a time gets a worker
+of its own, sharing the runtime. The Go, Python and Node.js wrappers negotiate
+it, and wrappers that don't are sent one event at a time. Wrappers negotiate
+anew whenever they're restarted, and events in flight are bounded by what the
+running wrapper negotiated.
+
+# Protobuf Encoding
+Runtimes with the `encoding` runtime attribute set to `protobuf` let wrappers
+know through NUCLIO_RPC_ENCODING that they may negotiate it, by adding
+`"encoding": "protobuf"` to a version 2 handshake. From then on, events are
+sent as length-prefixed ProcessorMessage messages, and the payloads of the
+wrapper's 'r', 'l', 'm' and 'd' frames are Results, LogRecord, Metric and
+DataBindingRequest messages respectively. The schema is in rpcproto/rpc.proto,
+from which wrapper SDKs for other languages can be generated. The Go, Python
+and Node.js wrappers negotiate it too, and encode the messages with codecs of
+their own, so that they need nothing beyond their SDKs. Fields are only ever
+added to the schema, so processors and wrappers of different versions
+understand each other.
+
+# Crash Loops
+A wrapper that exits unexpectedly, whether before or after it started, is
+restarted after a backoff that doubles with every consecutive crash, from the
+`restartBackoff` runtime attribute (1s by default) up to `maxRestartBackoff`
+(30s). The runtime may be stopped or restarted while backing off, which cancels
+the pending restart. Once it crashes `crashLoopThreshold` times in a row (5),
+it's no longer restarted and the runtime's status is set to error, failing the
+processor's readiness check. Crashes are forgotten once a wrapper runs for a
+minute. The last lines the wrapper wrote to stderr are kept, logged once it
+crash loops and served with its crashes by the web admin, at
+/triggers/{id}/workers. Platforms that find the function unhealthy add them
+from the log to the function's status message.
 
 # Event Encoding
 - Body is encoded in base64 (to allow binary data)
@@ -24,7 +24,23 @@ type EventEncoder interface {
 	Encode(event interface{}) error
 }
 
-func eventAsMap(event nuclio.Event) map[string]interface{} {
+// Request tags an event, or a batch of events, with the ID the wrapper replies with. It is sent to wrappers
+// that process several events at a time, so that their results can be matched back to their requests
+type Request struct {
+	ID   string
+	Item interface{}
+}
+
+// untagRequest returns the item to encode and the ID of the request it belongs to, if any
+func untagRequest(object interface{}) (interface{}, string) {
+	if request, isRequest := object.(*Request); isRequest {
+		return request.Item, request.ID
+	}
+
+	return object, ""
+}
+
+func eventAsMap(event nuclio.Event, requestID string) map[string]interface{} {
 	triggerInfo := event.GetTriggerInfo()
 	eventToEncode := map[string]interface{}{
 		"content_type": event.GetContentType(),
@@ -49,5 +65,10 @@ func eventAsMap(event nuclio.Event) map[string]interface{} {
 		"offset":       event.GetOffset(),
 		"topic":        event.GetTopic(),
 	}
+
+	if requestID != "" {
+		eventToEncode["request_id"] = requestID
+	}
+
 	return eventToEncode
 }
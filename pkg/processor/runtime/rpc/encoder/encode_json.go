@@ -21,6 +21,8 @@ import (
 	"encoding/json"
 	"io"
 
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
+
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
@@ -39,9 +41,10 @@ func NewEventJSONEncoder(logger logger.Logger, writer io.Writer) *EventJSONEncod
 
 // Encode writes the JSON encoding of event to the stream, followed by a newline character
 func (e *EventJSONEncoder) Encode(object interface{}) error {
+	object, requestID := untagRequest(object)
 
 	encodeOneEvent := func(event nuclio.Event) map[string]interface{} {
-		eventToEncode := eventAsMap(event)
+		eventToEncode := eventAsMap(event, requestID)
 
 		// if the body is map[string]interface{} we probably got a cloud object with a structured data member
 		if bodyObject, isMapStringInterface := event.GetBodyObject().(map[string]interface{}); isMapStringInterface {
@@ -64,7 +67,8 @@ func (e *EventJSONEncoder) Encode(object interface{}) error {
 
 		}
 		return json.NewEncoder(e.writer).Encode(eventsToEncode)
-
+	case *result.DataBindingResponse:
+		return json.NewEncoder(e.writer).Encode(typedEvent)
 	}
 	return errors.New("Wrong input type")
 }
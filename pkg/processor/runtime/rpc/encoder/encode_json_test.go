@@ -24,6 +24,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
+
 	"github.com/google/uuid"
 	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/nuclio/zap"
@@ -221,6 +223,72 @@ func (suite *EventJSONEncoderSuite) TestEncode() {
 	require.Equal(testEvent.GetVersion(), out["version"], "bad version")
 }
 
+func (suite *EventJSONEncoderSuite) TestEncodeDataBindingResponse() {
+	require := suite.Require()
+	logger, err := nucliozap.NewNuclioZapTest("test")
+	require.NoError(err, "Can't create logger")
+
+	var buf bytes.Buffer
+	enc := NewEventJSONEncoder(logger, &buf)
+	err = enc.Encode(&result.DataBindingResponse{
+		Kind:      result.DataBindingResponseKind,
+		ID:        "1",
+		Partition: 3,
+		Offset:    42,
+	})
+	require.NoError(err, "Can't encode data binding response")
+
+	out := make(map[string]interface{})
+	err = json.NewDecoder(&buf).Decode(&out)
+	require.NoError(err, "Can't decode data binding response")
+
+	require.Equal(result.DataBindingResponseKind, out["kind"], "bad kind")
+	require.Equal("1", out["id"], "bad id")
+	require.Equal(float64(3), out["partition"], "bad partition")
+	require.Equal(float64(42), out["offset"], "bad offset")
+	require.NotContains(out, "error")
+}
+
+func (suite *EventJSONEncoderSuite) TestEncodeRequest() {
+	require := suite.Require()
+	logger, err := nucliozap.NewNuclioZapTest("test")
+	require.NoError(err, "Can't create logger")
+
+	var buf bytes.Buffer
+	enc := NewEventJSONEncoder(logger, &buf)
+
+	// a single event
+	err = enc.Encode(&Request{ID: "7", Item: &TestEvent{}})
+	require.NoError(err, "Can't encode request")
+
+	out := make(map[string]interface{})
+	err = json.NewDecoder(&buf).Decode(&out)
+	require.NoError(err, "Can't decode request")
+	require.Equal("7", out["request_id"], "bad request id")
+	require.Equal(testID, nuclio.ID(out["id"].(string)), "bad id")
+
+	// a batch, where every event is tagged
+	err = enc.Encode(&Request{ID: "8", Item: []nuclio.Event{&TestEvent{}, &TestEvent{}}})
+	require.NoError(err, "Can't encode batch request")
+
+	var batchOut []map[string]interface{}
+	err = json.NewDecoder(&buf).Decode(&batchOut)
+	require.NoError(err, "Can't decode batch request")
+	require.Len(batchOut, 2)
+	for _, eventOut := range batchOut {
+		require.Equal("8", eventOut["request_id"], "bad request id")
+	}
+
+	// untagged events don't hold a request id
+	err = enc.Encode(&TestEvent{})
+	require.NoError(err, "Can't encode event")
+
+	out = make(map[string]interface{})
+	err = json.NewDecoder(&buf).Decode(&out)
+	require.NoError(err, "Can't decode event")
+	require.NotContains(out, "request_id")
+}
+
 func TestEventJSONEncoder(t *testing.T) {
 	suite.Run(t, new(EventJSONEncoderSuite))
 }
@@ -21,6 +21,8 @@ import (
 	"encoding/binary"
 	"io"
 
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
+
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
@@ -44,8 +46,10 @@ func NewEventMsgPackEncoder(logger logger.Logger, writer io.Writer) *EventMsgPac
 
 // Encode writes the JSON encoding of event to the stream, followed by a newline character
 func (e *EventMsgPackEncoder) Encode(object interface{}) error {
+	object, requestID := untagRequest(object)
+
 	prepareOneEvent := func(event nuclio.Event) map[string]interface{} {
-		eventToEncode := eventAsMap(event)
+		eventToEncode := eventAsMap(event, requestID)
 
 		// if the body is map[string]interface{} we probably got a cloud event with a structured data member
 		if bodyObject, isMapStringInterface := event.GetBodyObject().(map[string]interface{}); isMapStringInterface {
@@ -65,6 +69,8 @@ func (e *EventMsgPackEncoder) Encode(object interface{}) error {
 			eventSlice = append(eventSlice, prepareOneEvent(event))
 		}
 		preparedEvent = eventSlice
+	case *result.DataBindingResponse:
+		preparedEvent = typedEvent
 	}
 
 	e.buf.Reset()
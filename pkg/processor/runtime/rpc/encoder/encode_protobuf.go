@@ -0,0 +1,184 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoder
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/rpcproto"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// EventProtobufEncoder encodes nuclio events as ProcessorMessage protobuf messages (see rpcproto/rpc.proto)
+type EventProtobufEncoder struct {
+	logger logger.Logger
+	writer io.Writer
+	buf    []byte
+}
+
+// NewEventProtobufEncoder returns a new EventProtobufEncoder
+func NewEventProtobufEncoder(logger logger.Logger, writer io.Writer) *EventProtobufEncoder {
+	return &EventProtobufEncoder{logger: logger, writer: writer}
+}
+
+// Encode writes the big-endian length of the encoded message to the stream, followed by the message
+func (e *EventProtobufEncoder) Encode(object interface{}) error {
+	object, requestID := untagRequest(object)
+
+	var message []byte
+	var err error
+
+	switch typedEvent := object.(type) {
+
+	// control message events are events too, so they must be matched first
+	case *controlcommunication.ControlMessageEvent:
+		message, err = encodeControlMessage(typedEvent)
+		message = rpcproto.AppendMessage(nil, rpcproto.ProcessorMessageControlMessage, message)
+	case nuclio.Event:
+		message, err = encodeEvent(typedEvent, requestID)
+		message = rpcproto.AppendMessage(nil, rpcproto.ProcessorMessageEvent, message)
+	case []nuclio.Event:
+		message, err = encodeEventBatch(typedEvent, requestID)
+		message = rpcproto.AppendMessage(nil, rpcproto.ProcessorMessageEventBatch, message)
+	case *result.DataBindingResponse:
+		message = rpcproto.AppendMessage(nil,
+			rpcproto.ProcessorMessageDataBindingResponse,
+			encodeDataBindingResponse(typedEvent))
+	default:
+		return errors.New("Wrong input type")
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode message")
+	}
+
+	// write the length and the message at once, so that they're never interleaved with other writes
+	e.buf = binary.BigEndian.AppendUint32(e.buf[:0], uint32(len(message)))
+	e.buf = append(e.buf, message...)
+
+	if _, err := e.writer.Write(e.buf); err != nil {
+		return errors.Wrap(err, "Failed to write message to socket")
+	}
+
+	return nil
+}
+
+func encodeEvent(event nuclio.Event, requestID string) ([]byte, error) {
+	var err error
+
+	triggerInfo := event.GetTriggerInfo()
+	encodedTriggerInfo := rpcproto.AppendString(nil, rpcproto.TriggerInfoKind, triggerInfo.GetKind())
+	encodedTriggerInfo = rpcproto.AppendString(encodedTriggerInfo, rpcproto.TriggerInfoName, triggerInfo.GetName())
+
+	encodedEvent := rpcproto.AppendString(nil, rpcproto.EventID, string(event.GetID()))
+	encodedEvent = rpcproto.AppendString(encodedEvent, rpcproto.EventContentType, event.GetContentType())
+	encodedEvent = rpcproto.AppendMessage(encodedEvent, rpcproto.EventTrigger, encodedTriggerInfo)
+
+	if encodedEvent, err = rpcproto.AppendValueMap(encodedEvent, rpcproto.EventFields, event.GetFields()); err != nil {
+		return nil, errors.Wrap(err, "Failed to encode fields")
+	}
+
+	if encodedEvent, err = rpcproto.AppendValueMap(encodedEvent, rpcproto.EventHeaders, event.GetHeaders()); err != nil {
+		return nil, errors.Wrap(err, "Failed to encode headers")
+	}
+
+	encodedEvent = rpcproto.AppendString(encodedEvent, rpcproto.EventMethod, event.GetMethod())
+	encodedEvent = rpcproto.AppendString(encodedEvent, rpcproto.EventPath, event.GetPath())
+	encodedEvent = rpcproto.AppendString(encodedEvent, rpcproto.EventURL, event.GetURL())
+	encodedEvent = rpcproto.AppendInt(encodedEvent, rpcproto.EventTimestamp, event.GetTimestamp().UTC().Unix())
+	encodedEvent = rpcproto.AppendInt(encodedEvent, rpcproto.EventShardID, int64(event.GetShardID()))
+	encodedEvent = rpcproto.AppendInt(encodedEvent, rpcproto.EventNumShards, int64(event.GetTotalNumShards()))
+	encodedEvent = rpcproto.AppendString(encodedEvent, rpcproto.EventType, event.GetType())
+	encodedEvent = rpcproto.AppendString(encodedEvent, rpcproto.EventTypeVersion, event.GetTypeVersion())
+	encodedEvent = rpcproto.AppendString(encodedEvent, rpcproto.EventVersion, event.GetVersion())
+	encodedEvent = rpcproto.AppendInt(encodedEvent, rpcproto.EventOffset, int64(event.GetOffset()))
+	encodedEvent = rpcproto.AppendString(encodedEvent, rpcproto.EventTopic, event.GetTopic())
+
+	// if the body is map[string]interface{} we probably got a cloud event with a structured data member
+	if bodyObject, isMapStringInterface := event.GetBodyObject().(map[string]interface{}); isMapStringInterface {
+		encodedBodyObject, err := json.Marshal(bodyObject)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to encode body object")
+		}
+
+		encodedEvent = rpcproto.AppendMessage(encodedEvent, rpcproto.EventBodyJSON, encodedBodyObject)
+	} else {
+		encodedEvent = rpcproto.AppendBytes(encodedEvent, rpcproto.EventBody, event.GetBody())
+	}
+
+	return rpcproto.AppendString(encodedEvent, rpcproto.EventRequestID, requestID), nil
+}
+
+func encodeEventBatch(events []nuclio.Event, requestID string) ([]byte, error) {
+	var encodedBatch []byte
+
+	for _, event := range events {
+		encodedEvent, err := encodeEvent(event, requestID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to encode event %s", event.GetID())
+		}
+
+		encodedBatch = rpcproto.AppendMessage(encodedBatch, rpcproto.EventBatchEvents, encodedEvent)
+	}
+
+	return encodedBatch, nil
+}
+
+func encodeControlMessage(event *controlcommunication.ControlMessageEvent) ([]byte, error) {
+	controlMessage, isControlMessage := event.GetBodyObject().(*controlcommunication.ControlMessage)
+	if !isControlMessage {
+		return nil, errors.New("Control message event holds no control message")
+	}
+
+	encodedControlMessage := rpcproto.AppendString(nil, rpcproto.ControlMessageKind, string(controlMessage.Kind))
+
+	return rpcproto.AppendValueMap(encodedControlMessage,
+		rpcproto.ControlMessageAttributes,
+		controlMessage.Attributes)
+}
+
+func encodeDataBindingResponse(response *result.DataBindingResponse) []byte {
+	encodedResponse := rpcproto.AppendString(nil, rpcproto.DataBindingResponseKind, response.Kind)
+	encodedResponse = rpcproto.AppendString(encodedResponse, rpcproto.DataBindingResponseID, response.ID)
+	encodedResponse = rpcproto.AppendInt(encodedResponse, rpcproto.DataBindingResponsePartition, int64(response.Partition))
+	encodedResponse = rpcproto.AppendInt(encodedResponse, rpcproto.DataBindingResponseOffset, response.Offset)
+	encodedResponse = rpcproto.AppendBytes(encodedResponse, rpcproto.DataBindingResponseValue, response.Value)
+
+	for _, object := range response.Objects {
+		encodedObject := rpcproto.AppendString(nil, rpcproto.ObjectInfoKey, object.Key)
+		encodedObject = rpcproto.AppendInt(encodedObject, rpcproto.ObjectInfoSize, object.Size)
+		if !object.LastModified.IsZero() {
+			encodedObject = rpcproto.AppendInt(encodedObject,
+				rpcproto.ObjectInfoLastModified,
+				object.LastModified.UnixNano())
+		}
+		encodedObject = rpcproto.AppendString(encodedObject, rpcproto.ObjectInfoETag, object.ETag)
+
+		encodedResponse = rpcproto.AppendMessage(encodedResponse, rpcproto.DataBindingResponseObjects, encodedObject)
+	}
+
+	encodedResponse = rpcproto.AppendString(encodedResponse, rpcproto.DataBindingResponseURL, response.URL)
+
+	return rpcproto.AppendString(encodedResponse, rpcproto.DataBindingResponseError, response.Error)
+}
@@ -0,0 +1,235 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/databinding"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/rpcproto"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/rpcproto/rpcprototest"
+
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type EventProtobufEncoderSuite struct {
+	suite.Suite
+	buf     bytes.Buffer
+	encoder *EventProtobufEncoder
+}
+
+func (suite *EventProtobufEncoderSuite) SetupTest() {
+	logger, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err, "Can't create logger")
+
+	suite.buf.Reset()
+	suite.encoder = NewEventProtobufEncoder(logger, &suite.buf)
+}
+
+func (suite *EventProtobufEncoderSuite) TestEncode() {
+	testEvent := &TestEvent{}
+	suite.Require().NoError(suite.encoder.Encode(&Request{ID: "7", Item: testEvent}), "Can't encode event")
+
+	out := suite.decodeFields(suite.readMessage(rpcproto.ProcessorMessageEvent))
+
+	suite.Require().Equal(string(testID), string(out[rpcproto.EventID].data), "bad id")
+	suite.Require().Equal(testEvent.GetContentType(), string(out[rpcproto.EventContentType].data), "bad content type")
+	suite.Require().Equal(testEvent.GetBody(), out[rpcproto.EventBody].data, "bad body")
+	suite.Require().Equal(testEvent.GetMethod(), string(out[rpcproto.EventMethod].data), "bad method")
+	suite.Require().Equal(uint64(testEvent.GetShardID()), out[rpcproto.EventShardID].value, "bad shard ID")
+	suite.Require().Equal(uint64(testEvent.GetTimestamp().Unix()), out[rpcproto.EventTimestamp].value, "bad timestamp")
+	suite.Require().Equal("7", string(out[rpcproto.EventRequestID].data), "bad request id")
+
+	triggerInfo := suite.decodeFields(out[rpcproto.EventTrigger].data)
+	suite.Require().Equal(testTriggerInfoProvider.GetKind(), string(triggerInfo[rpcproto.TriggerInfoKind].data))
+	suite.Require().Equal(testTriggerInfoProvider.GetName(), string(triggerInfo[rpcproto.TriggerInfoName].data))
+}
+
+func (suite *EventProtobufEncoderSuite) TestEncodeHeaders() {
+	suite.Require().NoError(suite.encoder.Encode(&TestEvent{}), "Can't encode event")
+
+	headers := map[string]interface{}{}
+	err := rpcproto.ConsumeFields(suite.readMessage(rpcproto.ProcessorMessageEvent),
+		func(number protowire.Number, _ protowire.Type, _ uint64, data []byte) error {
+			if number != rpcproto.EventHeaders {
+				return nil
+			}
+
+			return rpcproto.DecodeValueMapEntry(data, headers)
+		})
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]interface{}{"h1": "hv1", "h2": int64(2)}, headers)
+}
+
+func (suite *EventProtobufEncoderSuite) TestEncodeBatch() {
+	suite.Require().NoError(suite.encoder.Encode([]nuclio.Event{&TestEvent{}, &TestEvent{}}), "Can't encode batch")
+
+	var numEvents int
+	err := rpcproto.ConsumeFields(suite.readMessage(rpcproto.ProcessorMessageEventBatch),
+		func(number protowire.Number, _ protowire.Type, _ uint64, data []byte) error {
+			suite.Require().Equal(rpcproto.EventBatchEvents, number)
+			suite.Require().Equal(string(testID), string(suite.decodeFields(data)[rpcproto.EventID].data))
+			numEvents++
+			return nil
+		})
+	suite.Require().NoError(err)
+	suite.Require().Equal(2, numEvents)
+}
+
+func (suite *EventProtobufEncoderSuite) TestEncodeControlMessage() {
+	err := suite.encoder.Encode(controlcommunication.NewControlMessageEvent(&controlcommunication.ControlMessage{
+		Kind:       controlcommunication.StreamMessageAckKind,
+		Attributes: map[string]interface{}{"offset": 42},
+	}))
+	suite.Require().NoError(err, "Can't encode control message")
+
+	out := suite.decodeFields(suite.readMessage(rpcproto.ProcessorMessageControlMessage))
+	suite.Require().Equal(string(controlcommunication.StreamMessageAckKind), string(out[rpcproto.ControlMessageKind].data))
+
+	attributes := map[string]interface{}{}
+	suite.Require().NoError(rpcproto.DecodeValueMapEntry(out[rpcproto.ControlMessageAttributes].data, attributes))
+	suite.Require().Equal(map[string]interface{}{"offset": int64(42)}, attributes)
+}
+
+func (suite *EventProtobufEncoderSuite) TestEncodeDataBindingResponse() {
+	err := suite.encoder.Encode(&result.DataBindingResponse{
+		Kind:      result.DataBindingResponseKind,
+		ID:        "1",
+		Partition: 3,
+		Offset:    42,
+	})
+	suite.Require().NoError(err, "Can't encode data binding response")
+
+	out := suite.decodeFields(suite.readMessage(rpcproto.ProcessorMessageDataBindingResponse))
+	suite.Require().Equal(result.DataBindingResponseKind, string(out[rpcproto.DataBindingResponseKind].data))
+	suite.Require().Equal("1", string(out[rpcproto.DataBindingResponseID].data))
+	suite.Require().Equal(uint64(3), out[rpcproto.DataBindingResponsePartition].value)
+	suite.Require().Equal(uint64(42), out[rpcproto.DataBindingResponseOffset].value)
+	suite.Require().NotContains(out, rpcproto.DataBindingResponseError)
+}
+
+func (suite *EventProtobufEncoderSuite) TestEncodeAgainstSchema() {
+	schema, err := rpcprototest.LoadSchema()
+	suite.Require().NoError(err)
+
+	testEvent := &TestEvent{}
+	suite.Require().NoError(suite.encoder.Encode(&Request{ID: "7", Item: testEvent}), "Can't encode event")
+
+	processorMessage := suite.unmarshalProcessorMessage(schema)
+	event := suite.getField(processorMessage, "event").Message()
+
+	suite.Require().Equal(string(testID), suite.getField(event, "id").String())
+	suite.Require().Equal(testEvent.GetContentType(), suite.getField(event, "content_type").String())
+	suite.Require().Equal(testEvent.GetBody(), suite.getField(event, "body").Bytes())
+	suite.Require().Equal(testEvent.GetMethod(), suite.getField(event, "method").String())
+	suite.Require().Equal(int64(testEvent.GetShardID()), suite.getField(event, "shard_id").Int())
+	suite.Require().Equal(testEvent.GetTimestamp().Unix(), suite.getField(event, "timestamp").Int())
+	suite.Require().Equal("7", suite.getField(event, "request_id").String())
+
+	trigger := suite.getField(event, "trigger").Message()
+	suite.Require().Equal(testTriggerInfoProvider.GetKind(), suite.getField(trigger, "kind").String())
+	suite.Require().Equal(testTriggerInfoProvider.GetName(), suite.getField(trigger, "name").String())
+
+	headers := suite.getField(event, "headers").Map()
+	suite.Require().Equal(len(testEvent.GetHeaders()), headers.Len())
+
+	err = suite.encoder.Encode(&result.DataBindingResponse{
+		Kind:      result.DataBindingResponseKind,
+		ID:        "1",
+		Partition: 3,
+		Offset:    42,
+		Objects:   []databinding.ObjectInfo{{Key: "key", Size: 10}},
+	})
+	suite.Require().NoError(err, "Can't encode data binding response")
+
+	processorMessage = suite.unmarshalProcessorMessage(schema)
+	dataBindingResponse := suite.getField(processorMessage, "data_binding_response").Message()
+
+	suite.Require().Equal("1", suite.getField(dataBindingResponse, "id").String())
+	suite.Require().Equal(int64(3), suite.getField(dataBindingResponse, "partition").Int())
+	suite.Require().Equal(int64(42), suite.getField(dataBindingResponse, "offset").Int())
+
+	objects := suite.getField(dataBindingResponse, "objects").List()
+	suite.Require().Equal(1, objects.Len())
+	suite.Require().Equal("key", suite.getField(objects.Get(0).Message(), "key").String())
+	suite.Require().Equal(int64(10), suite.getField(objects.Get(0).Message(), "size").Int())
+}
+
+// unmarshalProcessorMessage reads a length-prefixed ProcessorMessage into a message built from the schema
+func (suite *EventProtobufEncoderSuite) unmarshalProcessorMessage(schema *rpcprototest.Schema) protoreflect.Message {
+	messageLength := binary.BigEndian.Uint32(suite.buf.Next(4))
+
+	processorMessage := schema.NewMessage("ProcessorMessage")
+	suite.Require().NoError(proto.Unmarshal(suite.buf.Next(int(messageLength)), processorMessage))
+
+	return processorMessage
+}
+
+func (suite *EventProtobufEncoderSuite) getField(message protoreflect.Message, name string) protoreflect.Value {
+	field := message.Descriptor().Fields().ByName(protoreflect.Name(name))
+	suite.Require().NotNil(field, "Missing field %s", name)
+	suite.Require().True(field.IsList() || field.IsMap() || message.Has(field), "Field %s isn't set", name)
+
+	return message.Get(field)
+}
+
+type decodedField struct {
+	value uint64
+	data  []byte
+}
+
+// readMessage reads a length-prefixed ProcessorMessage and returns its only member, which must be the expected one
+func (suite *EventProtobufEncoderSuite) readMessage(expectedNumber protowire.Number) []byte {
+	suite.Require().GreaterOrEqual(suite.buf.Len(), 4)
+
+	messageLength := binary.BigEndian.Uint32(suite.buf.Next(4))
+	suite.Require().Equal(int(messageLength), suite.buf.Len(), "bad message length")
+
+	out := suite.decodeFields(suite.buf.Next(int(messageLength)))
+	suite.Require().Len(out, 1)
+	suite.Require().Contains(out, expectedNumber)
+
+	return out[expectedNumber].data
+}
+
+func (suite *EventProtobufEncoderSuite) decodeFields(message []byte) map[protowire.Number]decodedField {
+	out := map[protowire.Number]decodedField{}
+
+	err := rpcproto.ConsumeFields(message,
+		func(number protowire.Number, _ protowire.Type, value uint64, data []byte) error {
+			out[number] = decodedField{value: value, data: data}
+			return nil
+		})
+	suite.Require().NoError(err, "Can't decode message")
+
+	return out
+}
+
+func TestEventProtobufEncoder(t *testing.T) {
+	suite.Run(t, new(EventProtobufEncoderSuite))
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package result
+
+import (
+	"math"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/rpcproto"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Metric is sent by the wrapper once it's done processing an event
+type Metric struct {
+	DurationSec float64 `json:"duration"`
+}
+
+// UnmarshalProtobuf decodes a Metric protobuf message
+func (m *Metric) UnmarshalProtobuf(data []byte) error {
+	return rpcproto.ConsumeFields(data, func(number protowire.Number, _ protowire.Type, value uint64, _ []byte) error {
+		if number == rpcproto.MetricDuration {
+			m.DurationSec = math.Float64frombits(value)
+		}
+
+		return nil
+	})
+}
+
+// UnmarshalProtobuf decodes a LogRecord protobuf message
+func (r *RpcLogRecord) UnmarshalProtobuf(data []byte) error {
+	return rpcproto.ConsumeFields(data, func(number protowire.Number, _ protowire.Type, _ uint64, data []byte) error {
+		switch number {
+		case rpcproto.LogRecordDateTime:
+			r.DateTime = string(data)
+		case rpcproto.LogRecordLevel:
+			r.Level = string(data)
+		case rpcproto.LogRecordMessage:
+			r.Message = string(data)
+		case rpcproto.LogRecordWith:
+			if r.With == nil {
+				r.With = map[string]interface{}{}
+			}
+
+			return rpcproto.DecodeValueMapEntry(data, r.With)
+		case rpcproto.LogRecordRequestID:
+			r.RequestID = string(data)
+		}
+
+		return nil
+	})
+}
+
+// UnmarshalProtobuf decodes a DataBindingRequest protobuf message
+func (r *DataBindingRequest) UnmarshalProtobuf(data []byte) error {
+	return rpcproto.ConsumeFields(data, func(number protowire.Number, _ protowire.Type, value uint64, data []byte) error {
+		switch number {
+		case rpcproto.DataBindingRequestID:
+			r.ID = string(data)
+		case rpcproto.DataBindingRequestName:
+			r.Name = string(data)
+		case rpcproto.DataBindingRequestOperation:
+			r.Operation = string(data)
+		case rpcproto.DataBindingRequestTopic:
+			r.Topic = string(data)
+		case rpcproto.DataBindingRequestKey:
+			r.Key = data
+		case rpcproto.DataBindingRequestValue:
+			r.Value = data
+		case rpcproto.DataBindingRequestHeaders:
+			if r.Headers == nil {
+				r.Headers = map[string]string{}
+			}
+
+			return rpcproto.DecodeStringMapEntry(data, r.Headers)
+		case rpcproto.DataBindingRequestAsync:
+			r.Async = protowire.DecodeBool(value)
+		case rpcproto.DataBindingRequestPrefix:
+			r.Prefix = string(data)
+		case rpcproto.DataBindingRequestContentType:
+			r.ContentType = string(data)
+		case rpcproto.DataBindingRequestMethod:
+			r.Method = string(data)
+		case rpcproto.DataBindingRequestExpirationSeconds:
+			r.ExpirationSeconds = int(int64(value))
+		case rpcproto.DataBindingRequestRequestID:
+			r.RequestID = string(data)
+		}
+
+		return nil
+	})
+}
+
+// UnmarshalProtobufResponseData unmarshals a response sent as a Results protobuf message. Bodies are always
+// raw bytes, so they need no decoding
+func (br *BatchedResults) UnmarshalProtobufResponseData(logger logger.Logger, data []byte) {
+	br.Err = rpcproto.ConsumeFields(data, func(number protowire.Number, _ protowire.Type, _ uint64, data []byte) error {
+		if number != rpcproto.ResultsResults {
+			return nil
+		}
+
+		unmarshalledResult := &Result{
+			BodyEncoding: BodyEncodingRaw,
+		}
+
+		if err := unmarshalProtobufResult(unmarshalledResult, data); err != nil {
+			return errors.Wrap(err, "Failed to decode result")
+		}
+
+		br.Results = append(br.Results, unmarshalledResult)
+		return nil
+	})
+
+	if br.Err != nil {
+		logger.DebugWith("Failed to unmarshal result", "err", br.Err.Error())
+	}
+}
+
+func unmarshalProtobufResult(unmarshalledResult *Result, data []byte) error {
+	return rpcproto.ConsumeFields(data, func(number protowire.Number, _ protowire.Type, value uint64, data []byte) error {
+		switch number {
+		case rpcproto.ResultStatusCode:
+			unmarshalledResult.StatusCode = int(int32(value))
+		case rpcproto.ResultContentType:
+			unmarshalledResult.ContentType = string(data)
+		case rpcproto.ResultBody:
+
+			// the body references the frame, which isn't reused, rather than being copied
+			unmarshalledResult.DecodedBody = data[:len(data):len(data)]
+			unmarshalledResult.BodyLength = len(data)
+		case rpcproto.ResultHeaders:
+			if unmarshalledResult.Headers == nil {
+				unmarshalledResult.Headers = map[string]interface{}{}
+			}
+
+			return rpcproto.DecodeValueMapEntry(data, unmarshalledResult.Headers)
+		case rpcproto.ResultEventID:
+			unmarshalledResult.EventId = string(data)
+		case rpcproto.ResultRequestID:
+			unmarshalledResult.RequestID = string(data)
+		}
+
+		return nil
+	})
+}
@@ -18,9 +18,12 @@ package result
 
 import (
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 
+	"github.com/nuclio/nuclio/pkg/processor/databinding"
+
 	"github.com/nuclio/logger"
 )
 
@@ -29,6 +32,9 @@ type RpcLogRecord struct {
 	Level    string                 `json:"level"`
 	Message  string                 `json:"message"`
 	With     map[string]interface{} `json:"with"`
+
+	// set by wrappers that process several events at a time, to the request the record was logged for
+	RequestID string `json:"request_id"`
 }
 
 type Result struct {
@@ -36,13 +42,63 @@ type Result struct {
 	ContentType  string                 `json:"content_type"`
 	Body         string                 `json:"body"`
 	BodyEncoding string                 `json:"body_encoding"`
+	BodyLength   int                    `json:"body_length"`
 	Headers      map[string]interface{} `json:"headers"`
 	EventId      string                 `json:"event_id"`
+	RequestID    string                 `json:"request_id"`
 
 	DecodedBody []byte
 	Err         error
 }
 
+// DataBindingRequest is sent by the wrapper to perform an operation on one of the function's data bindings
+type DataBindingRequest struct {
+	ID                string            `json:"id"`
+	Name              string            `json:"name"`
+	Operation         string            `json:"operation"`
+	Topic             string            `json:"topic"`
+	Key               []byte            `json:"key"`
+	Value             []byte            `json:"value"`
+	Headers           map[string]string `json:"headers"`
+	Async             bool              `json:"async"`
+	Prefix            string            `json:"prefix"`
+	ContentType       string            `json:"content_type"`
+	Method            string            `json:"method"`
+	ExpirationSeconds int               `json:"expiration_seconds"`
+	RequestID         string            `json:"request_id"`
+}
+
+// DataBindingResponse is sent back to the wrapper once a synchronous data binding request completes
+type DataBindingResponse struct {
+	Kind      string                   `json:"kind" msgpack:"kind"`
+	ID        string                   `json:"id" msgpack:"id"`
+	Partition int32                    `json:"partition" msgpack:"partition"`
+	Offset    int64                    `json:"offset" msgpack:"offset"`
+	Value     []byte                   `json:"value,omitempty" msgpack:"value,omitempty"`
+	Objects   []databinding.ObjectInfo `json:"objects,omitempty" msgpack:"objects,omitempty"`
+	URL       string                   `json:"url,omitempty" msgpack:"url,omitempty"`
+	Error     string                   `json:"error,omitempty" msgpack:"error,omitempty"`
+}
+
+// DataBindingResponseKind identifies data binding responses among the messages sent to the wrapper
+const DataBindingResponseKind = "dataBindingResponse"
+
+// data binding operations. Send is the default, for backwards compatibility
+const (
+	DataBindingOperationSend            = "send"
+	DataBindingOperationGet             = "get"
+	DataBindingOperationPut             = "put"
+	DataBindingOperationList            = "list"
+	DataBindingOperationGetPresignedURL = "presign"
+)
+
+// body encodings. Raw bodies are only allowed in framed responses, where they follow the results' metadata
+const (
+	BodyEncodingText   = "text"
+	BodyEncodingBase64 = "base64"
+	BodyEncodingRaw    = "raw"
+)
+
 type BatchedResults struct {
 	Results []*Result
 	Err     error
@@ -52,16 +108,60 @@ func NewBatchedResults() *BatchedResults {
 	return &BatchedResults{Results: make([]*Result, 0)}
 }
 
+// GetRequestID returns the ID of the request the results reply to, or an empty string if the wrapper
+// didn't tag them
+func (br *BatchedResults) GetRequestID() string {
+	for _, unmarshalledResult := range br.Results {
+		if unmarshalledResult.RequestID != "" {
+			return unmarshalledResult.RequestID
+		}
+	}
+
+	return ""
+}
+
 func (br *BatchedResults) UnmarshalResponseData(logger logger.Logger, data []byte) {
+	br.unmarshalResponseData(logger, data, nil)
+}
+
+// UnmarshalFramedResponseData unmarshals a response sent in a frame, which holds the length of the results'
+// metadata, the metadata itself and then the raw bodies of the results, in order
+func (br *BatchedResults) UnmarshalFramedResponseData(logger logger.Logger, data []byte) {
+	if len(data) < 4 {
+		br.Err = fmt.Errorf("Framed response is too short - %d bytes", len(data))
+		return
+	}
+
+	metadataLength := uint64(binary.BigEndian.Uint32(data))
+	if metadataLength > uint64(len(data)-4) {
+		br.Err = fmt.Errorf("Framed response metadata length %d exceeds frame", metadataLength)
+		return
+	}
+
+	br.unmarshalResponseData(logger, data[4:4+metadataLength], data[4+metadataLength:])
+}
+
+func (br *BatchedResults) unmarshalResponseData(logger logger.Logger, data []byte, rawBodies []byte) {
 	var results []*Result
 
 	// define method to process a single result
 	handleSingleUnmarshalledResult := func(unmarshalledResult *Result) {
 		switch unmarshalledResult.BodyEncoding {
-		case "text":
+		case BodyEncodingText:
 			unmarshalledResult.DecodedBody = []byte(unmarshalledResult.Body)
-		case "base64":
+		case BodyEncodingBase64:
 			unmarshalledResult.DecodedBody, br.Err = base64.StdEncoding.DecodeString(unmarshalledResult.Body)
+		case BodyEncodingRaw:
+			if unmarshalledResult.BodyLength < 0 || unmarshalledResult.BodyLength > len(rawBodies) {
+				unmarshalledResult.Err = fmt.Errorf("Raw body length %d exceeds the %d remaining bytes",
+					unmarshalledResult.BodyLength,
+					len(rawBodies))
+				return
+			}
+
+			// the body references the frame, which isn't reused, rather than being copied
+			unmarshalledResult.DecodedBody = rawBodies[:unmarshalledResult.BodyLength:unmarshalledResult.BodyLength]
+			rawBodies = rawBodies[unmarshalledResult.BodyLength:]
 		default:
 			unmarshalledResult.Err = fmt.Errorf("Unknown body encoding - %q", unmarshalledResult.BodyEncoding)
 		}
@@ -18,11 +18,17 @@ limitations under the License.
 package result
 
 import (
+	"encoding/binary"
 	"testing"
 
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/rpcproto"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/rpcproto/rpcprototest"
+
 	"github.com/nuclio/logger"
 	nucliozap "github.com/nuclio/zap"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 type ResultSuite struct {
@@ -75,6 +81,264 @@ func (suite *ResultSuite) TestUnmarshalResponseData() {
 	}
 }
 
+func (suite *ResultSuite) TestUnmarshalFramedResponseData() {
+	for _, testCase := range []struct {
+		name               string
+		metadata           string
+		rawBodies          []byte
+		unmarshalledResult []*Result
+		expectedErr        bool
+	}{
+		{
+			name:      "single-result",
+			metadata:  `{"content_type": "image/png", "status_code": 200, "body_encoding": "raw", "body_length": 3}`,
+			rawBodies: []byte{0xff, 0x00, 0x0a},
+			unmarshalledResult: []*Result{{
+				StatusCode:   200,
+				ContentType:  "image/png",
+				BodyEncoding: BodyEncodingRaw,
+				BodyLength:   3,
+				DecodedBody:  []byte{0xff, 0x00, 0x0a},
+			}},
+		},
+		{
+			name: "batch-result",
+			metadata: `[{"status_code": 200, "body_encoding": "raw", "body_length": 2, "event_id": "1"},
+				{"status_code": 200, "body_encoding": "text", "body": "abc", "event_id": "2"},
+				{"status_code": 201, "body_encoding": "raw", "body_length": 1, "event_id": "3"}]`,
+			rawBodies: []byte("xyz"),
+			unmarshalledResult: []*Result{
+				{
+					StatusCode:   200,
+					BodyEncoding: BodyEncodingRaw,
+					BodyLength:   2,
+					EventId:      "1",
+					DecodedBody:  []byte("xy"),
+				},
+				{
+					StatusCode:   200,
+					BodyEncoding: BodyEncodingText,
+					Body:         "abc",
+					EventId:      "2",
+					DecodedBody:  []byte("abc"),
+				},
+				{
+					StatusCode:   201,
+					BodyEncoding: BodyEncodingRaw,
+					BodyLength:   1,
+					EventId:      "3",
+					DecodedBody:  []byte("z"),
+				},
+			},
+		},
+		{
+			name:      "body-exceeds-frame",
+			metadata:  `{"status_code": 200, "body_encoding": "raw", "body_length": 10}`,
+			rawBodies: []byte("short"),
+			unmarshalledResult: []*Result{{
+				StatusCode:   200,
+				BodyEncoding: BodyEncodingRaw,
+				BodyLength:   10,
+			}},
+			expectedErr: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			data := binary.BigEndian.AppendUint32(nil, uint32(len(testCase.metadata)))
+			data = append(data, testCase.metadata...)
+			data = append(data, testCase.rawBodies...)
+
+			unmarshalledResults := NewBatchedResults()
+			unmarshalledResults.UnmarshalFramedResponseData(suite.createLogger(), data)
+			suite.Require().NoError(unmarshalledResults.Err)
+
+			if testCase.expectedErr {
+				suite.Require().Error(unmarshalledResults.Results[0].Err)
+				unmarshalledResults.Results[0].Err = nil
+			}
+
+			suite.Require().Equal(testCase.unmarshalledResult, unmarshalledResults.Results)
+		})
+	}
+
+	// the metadata length must fit in the frame
+	unmarshalledResults := NewBatchedResults()
+	unmarshalledResults.UnmarshalFramedResponseData(suite.createLogger(), []byte{0, 0, 0, 10, '{', '}'})
+	suite.Require().Error(unmarshalledResults.Err)
+}
+
+func (suite *ResultSuite) TestUnmarshalProtobufResponseData() {
+	headers, err := rpcproto.AppendValueMap(nil, rpcproto.ResultHeaders, map[string]interface{}{
+		"h1": "hv1",
+		"h2": 2,
+	})
+	suite.Require().NoError(err)
+
+	encodedResult := rpcproto.AppendInt(nil, rpcproto.ResultStatusCode, 201)
+	encodedResult = rpcproto.AppendString(encodedResult, rpcproto.ResultContentType, "image/png")
+	encodedResult = rpcproto.AppendBytes(encodedResult, rpcproto.ResultBody, []byte{0x00, '\n', 0xff})
+	encodedResult = append(encodedResult, headers...)
+	encodedResult = rpcproto.AppendString(encodedResult, rpcproto.ResultRequestID, "7")
+
+	data := rpcproto.AppendMessage(nil, rpcproto.ResultsResults, encodedResult)
+	data = rpcproto.AppendMessage(data, rpcproto.ResultsResults, rpcproto.AppendInt(nil, rpcproto.ResultStatusCode, 204))
+
+	unmarshalledResults := NewBatchedResults()
+	unmarshalledResults.UnmarshalProtobufResponseData(suite.createLogger(), data)
+	suite.Require().NoError(unmarshalledResults.Err)
+	suite.Require().Equal([]*Result{
+		{
+			StatusCode:   201,
+			ContentType:  "image/png",
+			BodyEncoding: BodyEncodingRaw,
+			BodyLength:   3,
+			DecodedBody:  []byte{0x00, '\n', 0xff},
+			Headers:      map[string]interface{}{"h1": "hv1", "h2": int64(2)},
+			RequestID:    "7",
+		},
+		{
+			StatusCode:   204,
+			BodyEncoding: BodyEncodingRaw,
+		},
+	}, unmarshalledResults.Results)
+	suite.Require().Equal("7", unmarshalledResults.GetRequestID())
+
+	// truncated messages fail the whole response
+	unmarshalledResults = NewBatchedResults()
+	unmarshalledResults.UnmarshalProtobufResponseData(suite.createLogger(), data[:len(data)-1])
+	suite.Require().Error(unmarshalledResults.Err)
+}
+
+func (suite *ResultSuite) TestUnmarshalProtobufDataBindingRequest() {
+	headers := rpcproto.AppendString(nil, rpcproto.MapEntryKey, "h1")
+	headers = rpcproto.AppendString(headers, rpcproto.MapEntryValue, "hv1")
+
+	data := rpcproto.AppendString(nil, rpcproto.DataBindingRequestID, "1")
+	data = rpcproto.AppendString(data, rpcproto.DataBindingRequestName, "db0")
+	data = rpcproto.AppendString(data, rpcproto.DataBindingRequestTopic, "topic")
+	data = rpcproto.AppendBytes(data, rpcproto.DataBindingRequestValue, []byte("value"))
+	data = rpcproto.AppendMessage(data, rpcproto.DataBindingRequestHeaders, headers)
+	data = rpcproto.AppendBool(data, rpcproto.DataBindingRequestAsync, true)
+	data = rpcproto.AppendInt(data, rpcproto.DataBindingRequestExpirationSeconds, 60)
+
+	var dataBindingRequest DataBindingRequest
+	suite.Require().NoError(dataBindingRequest.UnmarshalProtobuf(data))
+	suite.Require().Equal(DataBindingRequest{
+		ID:                "1",
+		Name:              "db0",
+		Topic:             "topic",
+		Value:             []byte("value"),
+		Headers:           map[string]string{"h1": "hv1"},
+		Async:             true,
+		ExpirationSeconds: 60,
+	}, dataBindingRequest)
+}
+
+func (suite *ResultSuite) TestUnmarshalSchemaMessages() {
+	schema, err := rpcprototest.LoadSchema()
+	suite.Require().NoError(err)
+
+	// results
+	encodedResult := suite.newSchemaMessage(schema, "Result", map[string]interface{}{
+		"status_code":  int32(201),
+		"content_type": "image/png",
+		"body":         []byte{0x00, '\n', 0xff},
+		"event_id":     "e1",
+		"request_id":   "7",
+	})
+
+	results := schema.NewMessage("Results")
+	resultsList := results.Mutable(results.Descriptor().Fields().ByName("results")).List()
+	resultsList.Append(protoreflect.ValueOfMessage(encodedResult))
+
+	unmarshalledResults := NewBatchedResults()
+	unmarshalledResults.UnmarshalProtobufResponseData(suite.createLogger(), suite.marshal(results))
+	suite.Require().NoError(unmarshalledResults.Err)
+	suite.Require().Equal([]*Result{{
+		StatusCode:   201,
+		ContentType:  "image/png",
+		BodyEncoding: BodyEncodingRaw,
+		BodyLength:   3,
+		DecodedBody:  []byte{0x00, '\n', 0xff},
+		EventId:      "e1",
+		RequestID:    "7",
+	}}, unmarshalledResults.Results)
+
+	// log records
+	var logRecord RpcLogRecord
+	suite.Require().NoError(logRecord.UnmarshalProtobuf(suite.marshal(suite.newSchemaMessage(schema,
+		"LogRecord",
+		map[string]interface{}{
+			"datetime":   "2023-01-01T00:00:00Z",
+			"level":      "info",
+			"message":    "hello",
+			"request_id": "7",
+		}))))
+	suite.Require().Equal(RpcLogRecord{
+		DateTime:  "2023-01-01T00:00:00Z",
+		Level:     "info",
+		Message:   "hello",
+		RequestID: "7",
+	}, logRecord)
+
+	// metrics
+	var metric Metric
+	suite.Require().NoError(metric.UnmarshalProtobuf(suite.marshal(suite.newSchemaMessage(schema,
+		"Metric",
+		map[string]interface{}{"duration": 1.5}))))
+	suite.Require().Equal(1.5, metric.DurationSec)
+
+	// data binding requests
+	dataBindingRequestMessage := suite.newSchemaMessage(schema, "DataBindingRequest", map[string]interface{}{
+		"id":                 "1",
+		"name":               "db0",
+		"operation":          "send",
+		"value":              []byte("value"),
+		"async":              true,
+		"expiration_seconds": int64(60),
+		"request_id":         "7",
+	})
+
+	headers := dataBindingRequestMessage.Mutable(dataBindingRequestMessage.Descriptor().Fields().ByName("headers")).Map()
+	headers.Set(protoreflect.ValueOfString("h1").MapKey(), protoreflect.ValueOfString("hv1"))
+
+	var dataBindingRequest DataBindingRequest
+	suite.Require().NoError(dataBindingRequest.UnmarshalProtobuf(suite.marshal(dataBindingRequestMessage)))
+	suite.Require().Equal(DataBindingRequest{
+		ID:                "1",
+		Name:              "db0",
+		Operation:         "send",
+		Value:             []byte("value"),
+		Headers:           map[string]string{"h1": "hv1"},
+		Async:             true,
+		ExpirationSeconds: 60,
+		RequestID:         "7",
+	}, dataBindingRequest)
+}
+
+// newSchemaMessage returns a message built from the schema, holding the given scalar fields
+func (suite *ResultSuite) newSchemaMessage(schema *rpcprototest.Schema,
+	name string,
+	fields map[string]interface{}) protoreflect.Message {
+	message := schema.NewMessage(name)
+
+	for fieldName, fieldValue := range fields {
+		field := message.Descriptor().Fields().ByName(protoreflect.Name(fieldName))
+		suite.Require().NotNil(field, "Missing field %s.%s", name, fieldName)
+
+		message.Set(field, protoreflect.ValueOf(fieldValue))
+	}
+
+	return message
+}
+
+func (suite *ResultSuite) marshal(message protoreflect.Message) []byte {
+	data, err := proto.Marshal(message.Interface())
+	suite.Require().NoError(err)
+
+	return data
+}
+
 func TestRuntime(t *testing.T) {
 	suite.Run(t, new(ResultSuite))
 }
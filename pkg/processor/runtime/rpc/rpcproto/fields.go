@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpcproto
+
+import "google.golang.org/protobuf/encoding/protowire"
+
+// the field numbers of the messages in rpc.proto
+
+// Value fields
+const (
+	ValueString protowire.Number = 1
+	ValueInt    protowire.Number = 2
+	ValueDouble protowire.Number = 3
+	ValueBool   protowire.Number = 4
+	ValueBytes  protowire.Number = 5
+	ValueJSON   protowire.Number = 6
+)
+
+// map entry fields
+const (
+	MapEntryKey   protowire.Number = 1
+	MapEntryValue protowire.Number = 2
+)
+
+// ProcessorMessage fields
+const (
+	ProcessorMessageEvent               protowire.Number = 1
+	ProcessorMessageEventBatch          protowire.Number = 2
+	ProcessorMessageControlMessage      protowire.Number = 3
+	ProcessorMessageDataBindingResponse protowire.Number = 4
+)
+
+// TriggerInfo fields
+const (
+	TriggerInfoKind protowire.Number = 1
+	TriggerInfoName protowire.Number = 2
+)
+
+// Event fields
+const (
+	EventID          protowire.Number = 1
+	EventContentType protowire.Number = 2
+	EventTrigger     protowire.Number = 3
+	EventFields      protowire.Number = 4
+	EventHeaders     protowire.Number = 5
+	EventMethod      protowire.Number = 6
+	EventPath        protowire.Number = 7
+	EventURL         protowire.Number = 8
+	EventTimestamp   protowire.Number = 9
+	EventShardID     protowire.Number = 10
+	EventNumShards   protowire.Number = 11
+	EventType        protowire.Number = 12
+	EventTypeVersion protowire.Number = 13
+	EventVersion     protowire.Number = 14
+	EventOffset      protowire.Number = 15
+	EventTopic       protowire.Number = 16
+	EventBody        protowire.Number = 17
+	EventBodyJSON    protowire.Number = 18
+	EventRequestID   protowire.Number = 19
+)
+
+// EventBatch fields
+const (
+	EventBatchEvents protowire.Number = 1
+)
+
+// ControlMessage fields
+const (
+	ControlMessageKind       protowire.Number = 1
+	ControlMessageAttributes protowire.Number = 2
+)
+
+// ObjectInfo fields
+const (
+	ObjectInfoKey          protowire.Number = 1
+	ObjectInfoSize         protowire.Number = 2
+	ObjectInfoLastModified protowire.Number = 3
+	ObjectInfoETag         protowire.Number = 4
+)
+
+// DataBindingResponse fields
+const (
+	DataBindingResponseKind      protowire.Number = 1
+	DataBindingResponseID        protowire.Number = 2
+	DataBindingResponsePartition protowire.Number = 3
+	DataBindingResponseOffset    protowire.Number = 4
+	DataBindingResponseValue     protowire.Number = 5
+	DataBindingResponseObjects   protowire.Number = 6
+	DataBindingResponseURL       protowire.Number = 7
+	DataBindingResponseError     protowire.Number = 8
+)
+
+// Results fields
+const (
+	ResultsResults protowire.Number = 1
+)
+
+// Result fields
+const (
+	ResultStatusCode  protowire.Number = 1
+	ResultContentType protowire.Number = 2
+	ResultBody        protowire.Number = 3
+	ResultHeaders     protowire.Number = 4
+	ResultEventID     protowire.Number = 5
+	ResultRequestID   protowire.Number = 6
+)
+
+// LogRecord fields
+const (
+	LogRecordDateTime  protowire.Number = 1
+	LogRecordLevel     protowire.Number = 2
+	LogRecordMessage   protowire.Number = 3
+	LogRecordWith      protowire.Number = 4
+	LogRecordRequestID protowire.Number = 5
+)
+
+// Metric fields
+const (
+	MetricDuration protowire.Number = 1
+)
+
+// DataBindingRequest fields
+const (
+	DataBindingRequestID                protowire.Number = 1
+	DataBindingRequestName              protowire.Number = 2
+	DataBindingRequestOperation         protowire.Number = 3
+	DataBindingRequestTopic             protowire.Number = 4
+	DataBindingRequestKey               protowire.Number = 5
+	DataBindingRequestValue             protowire.Number = 6
+	DataBindingRequestHeaders           protowire.Number = 7
+	DataBindingRequestAsync             protowire.Number = 8
+	DataBindingRequestPrefix            protowire.Number = 9
+	DataBindingRequestContentType       protowire.Number = 10
+	DataBindingRequestMethod            protowire.Number = 11
+	DataBindingRequestExpirationSeconds protowire.Number = 12
+	DataBindingRequestRequestID         protowire.Number = 13
+)
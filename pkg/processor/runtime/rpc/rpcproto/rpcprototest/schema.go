@@ -0,0 +1,199 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rpcprototest builds the messages of rpc.proto from the file itself, so that tests can check the
+// hand-written codec in rpcproto against the schema wrappers generate their code from
+package rpcprototest
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/nuclio/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var (
+	packagePattern = regexp.MustCompile(`^package ([\w.]+);$`)
+	messagePattern = regexp.MustCompile(`^message (\w+) \{$`)
+	oneofPattern   = regexp.MustCompile(`^oneof (\w+) \{$`)
+	fieldPattern   = regexp.MustCompile(`^(repeated )?(map<(\w+), (\w+)>|\w+) (\w+) = (\d+);$`)
+
+	scalarTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+		"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		"bytes":  descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		"bool":   descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+		"double": descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+		"int32":  descriptorpb.FieldDescriptorProto_TYPE_INT32,
+		"int64":  descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	}
+)
+
+// Schema holds the messages of rpc.proto
+type Schema struct {
+	file protoreflect.FileDescriptor
+}
+
+// LoadSchema parses rpc.proto. Only the subset of the protobuf language rpc.proto uses is supported -
+// top level messages of scalar, message, repeated and map fields, and oneofs
+func LoadSchema() (*Schema, error) {
+	_, currentFilePath, _, _ := runtime.Caller(0)
+	schemaPath := filepath.Join(filepath.Dir(currentFilePath), "..", "rpc.proto")
+
+	schemaFile, err := os.Open(schemaPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open schema")
+	}
+
+	defer schemaFile.Close() // nolint: errcheck
+
+	fileDescriptor := &descriptorpb.FileDescriptorProto{
+		Name:   proto.String("rpc.proto"),
+		Syntax: proto.String("proto3"),
+	}
+
+	var message *descriptorpb.DescriptorProto
+	oneofIndex := int32(-1)
+	inBlockComment := false
+
+	scanner := bufio.NewScanner(schemaFile)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+
+		// block comments only hold the license
+		if inBlockComment || strings.HasPrefix(line, "/*") {
+			inBlockComment = !strings.HasSuffix(line, "*/")
+			continue
+		}
+
+		if commentIndex := strings.Index(line, "//"); commentIndex != -1 {
+			line = line[:commentIndex]
+		}
+
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "", strings.HasPrefix(line, "syntax "), strings.HasPrefix(line, "option "):
+		case packagePattern.MatchString(line):
+			fileDescriptor.Package = proto.String(packagePattern.FindStringSubmatch(line)[1])
+		case messagePattern.MatchString(line) && message == nil:
+			message = &descriptorpb.DescriptorProto{Name: proto.String(messagePattern.FindStringSubmatch(line)[1])}
+		case oneofPattern.MatchString(line) && message != nil && oneofIndex == -1:
+			oneofIndex = int32(len(message.OneofDecl))
+			message.OneofDecl = append(message.OneofDecl, &descriptorpb.OneofDescriptorProto{
+				Name: proto.String(oneofPattern.FindStringSubmatch(line)[1]),
+			})
+		case fieldPattern.MatchString(line) && message != nil:
+			field := newField(fileDescriptor.GetPackage(), message, fieldPattern.FindStringSubmatch(line))
+			if oneofIndex != -1 {
+				field.OneofIndex = proto.Int32(oneofIndex)
+			}
+
+			message.Field = append(message.Field, field)
+		case line == "}" && oneofIndex != -1:
+			oneofIndex = -1
+		case line == "}" && message != nil:
+			fileDescriptor.MessageType = append(fileDescriptor.MessageType, message)
+			message = nil
+		default:
+			return nil, errors.Errorf("Unsupported line %d of schema: %s", lineNumber, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Failed to read schema")
+	}
+
+	file, err := protodesc.NewFile(fileDescriptor, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to build schema")
+	}
+
+	return &Schema{file: file}, nil
+}
+
+// GetMessages returns the descriptors of the messages of the schema
+func (s *Schema) GetMessages() protoreflect.MessageDescriptors {
+	return s.file.Messages()
+}
+
+// NewMessage returns an empty message of the given name
+func (s *Schema) NewMessage(name string) *dynamicpb.Message {
+	return dynamicpb.NewMessage(s.file.Messages().ByName(protoreflect.Name(name)))
+}
+
+func newField(packageName string,
+	message *descriptorpb.DescriptorProto,
+	fieldMatch []string) *descriptorpb.FieldDescriptorProto {
+
+	fieldType, keyType, valueType, fieldName := fieldMatch[2], fieldMatch[3], fieldMatch[4], fieldMatch[5]
+	fieldNumber, _ := strconv.Atoi(fieldMatch[6])
+
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(fieldName),
+		JsonName: proto.String(fieldName),
+		Number:   proto.Int32(int32(fieldNumber)),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+
+	if fieldMatch[1] != "" {
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	}
+
+	// maps are repeated entries of a nested message, named after the field
+	if keyType != "" {
+		entryName := ""
+		for _, fieldNamePart := range strings.Split(fieldName, "_") {
+			entryName += strings.ToUpper(fieldNamePart[:1]) + fieldNamePart[1:]
+		}
+
+		entryName += "Entry"
+		keyField := newField(packageName, message, []string{"", "", keyType, "", "", "key", "1"})
+		valueField := newField(packageName, message, []string{"", "", valueType, "", "", "value", "2"})
+
+		message.NestedType = append(message.NestedType, &descriptorpb.DescriptorProto{
+			Name:    proto.String(entryName),
+			Field:   []*descriptorpb.FieldDescriptorProto{keyField, valueField},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		})
+
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		field.TypeName = proto.String("." + packageName + "." + message.GetName() + "." + entryName)
+
+		return field
+	}
+
+	if scalarType, isScalar := scalarTypes[fieldType]; isScalar {
+		field.Type = scalarType.Enum()
+	} else {
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		field.TypeName = proto.String("." + packageName + "." + fieldType)
+	}
+
+	return field
+}
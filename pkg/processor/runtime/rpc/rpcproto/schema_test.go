@@ -0,0 +1,220 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpcproto
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/rpcproto/rpcprototest"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type SchemaTestSuite struct {
+	suite.Suite
+	schema *rpcprototest.Schema
+}
+
+func (suite *SchemaTestSuite) SetupSuite() {
+	var err error
+
+	suite.schema, err = rpcprototest.LoadSchema()
+	suite.Require().NoError(err)
+}
+
+func (suite *SchemaTestSuite) TestFieldNumbers() {
+	fieldNumbers := []struct {
+		message string
+		field   string
+		number  protowire.Number
+	}{
+		{"Value", "string_value", ValueString},
+		{"Value", "int_value", ValueInt},
+		{"Value", "double_value", ValueDouble},
+		{"Value", "bool_value", ValueBool},
+		{"Value", "bytes_value", ValueBytes},
+		{"Value", "json_value", ValueJSON},
+		{"ProcessorMessage", "event", ProcessorMessageEvent},
+		{"ProcessorMessage", "event_batch", ProcessorMessageEventBatch},
+		{"ProcessorMessage", "control_message", ProcessorMessageControlMessage},
+		{"ProcessorMessage", "data_binding_response", ProcessorMessageDataBindingResponse},
+		{"TriggerInfo", "kind", TriggerInfoKind},
+		{"TriggerInfo", "name", TriggerInfoName},
+		{"Event", "id", EventID},
+		{"Event", "content_type", EventContentType},
+		{"Event", "trigger", EventTrigger},
+		{"Event", "fields", EventFields},
+		{"Event", "headers", EventHeaders},
+		{"Event", "method", EventMethod},
+		{"Event", "path", EventPath},
+		{"Event", "url", EventURL},
+		{"Event", "timestamp", EventTimestamp},
+		{"Event", "shard_id", EventShardID},
+		{"Event", "num_shards", EventNumShards},
+		{"Event", "type", EventType},
+		{"Event", "type_version", EventTypeVersion},
+		{"Event", "version", EventVersion},
+		{"Event", "offset", EventOffset},
+		{"Event", "topic", EventTopic},
+		{"Event", "body", EventBody},
+		{"Event", "body_json", EventBodyJSON},
+		{"Event", "request_id", EventRequestID},
+		{"EventBatch", "events", EventBatchEvents},
+		{"ControlMessage", "kind", ControlMessageKind},
+		{"ControlMessage", "attributes", ControlMessageAttributes},
+		{"ObjectInfo", "key", ObjectInfoKey},
+		{"ObjectInfo", "size", ObjectInfoSize},
+		{"ObjectInfo", "last_modified", ObjectInfoLastModified},
+		{"ObjectInfo", "etag", ObjectInfoETag},
+		{"DataBindingResponse", "kind", DataBindingResponseKind},
+		{"DataBindingResponse", "id", DataBindingResponseID},
+		{"DataBindingResponse", "partition", DataBindingResponsePartition},
+		{"DataBindingResponse", "offset", DataBindingResponseOffset},
+		{"DataBindingResponse", "value", DataBindingResponseValue},
+		{"DataBindingResponse", "objects", DataBindingResponseObjects},
+		{"DataBindingResponse", "url", DataBindingResponseURL},
+		{"DataBindingResponse", "error", DataBindingResponseError},
+		{"Results", "results", ResultsResults},
+		{"Result", "status_code", ResultStatusCode},
+		{"Result", "content_type", ResultContentType},
+		{"Result", "body", ResultBody},
+		{"Result", "headers", ResultHeaders},
+		{"Result", "event_id", ResultEventID},
+		{"Result", "request_id", ResultRequestID},
+		{"LogRecord", "datetime", LogRecordDateTime},
+		{"LogRecord", "level", LogRecordLevel},
+		{"LogRecord", "message", LogRecordMessage},
+		{"LogRecord", "with", LogRecordWith},
+		{"LogRecord", "request_id", LogRecordRequestID},
+		{"Metric", "duration", MetricDuration},
+		{"DataBindingRequest", "id", DataBindingRequestID},
+		{"DataBindingRequest", "name", DataBindingRequestName},
+		{"DataBindingRequest", "operation", DataBindingRequestOperation},
+		{"DataBindingRequest", "topic", DataBindingRequestTopic},
+		{"DataBindingRequest", "key", DataBindingRequestKey},
+		{"DataBindingRequest", "value", DataBindingRequestValue},
+		{"DataBindingRequest", "headers", DataBindingRequestHeaders},
+		{"DataBindingRequest", "async", DataBindingRequestAsync},
+		{"DataBindingRequest", "prefix", DataBindingRequestPrefix},
+		{"DataBindingRequest", "content_type", DataBindingRequestContentType},
+		{"DataBindingRequest", "method", DataBindingRequestMethod},
+		{"DataBindingRequest", "expiration_seconds", DataBindingRequestExpirationSeconds},
+		{"DataBindingRequest", "request_id", DataBindingRequestRequestID},
+	}
+
+	for _, fieldNumber := range fieldNumbers {
+		message := suite.schema.GetMessages().ByName(protoreflect.Name(fieldNumber.message))
+		suite.Require().NotNil(message, "Missing message %s", fieldNumber.message)
+
+		field := message.Fields().ByName(protoreflect.Name(fieldNumber.field))
+		suite.Require().NotNil(field, "Missing field %s.%s", fieldNumber.message, fieldNumber.field)
+		suite.Require().Equal(fieldNumber.number, field.Number(), "Bad number of %s.%s", fieldNumber.message, fieldNumber.field)
+	}
+
+	// every field of the schema has a number above, so fields added to the schema must be added here too
+	schemaFields := 0
+	for messageIndex := 0; messageIndex < suite.schema.GetMessages().Len(); messageIndex++ {
+		schemaFields += suite.schema.GetMessages().Get(messageIndex).Fields().Len()
+	}
+
+	suite.Require().Equal(schemaFields, len(fieldNumbers))
+}
+
+func (suite *SchemaTestSuite) TestEncodeValueMap() {
+	encodedResult, err := AppendValueMap(nil, ResultHeaders, map[string]interface{}{
+		"string": "value",
+		"int":    -3,
+		"double": 1.5,
+		"bool":   true,
+		"bytes":  []byte{0x00, 0xff},
+		"list":   []string{"a", "b"},
+	})
+	suite.Require().NoError(err)
+
+	decodedResult := suite.schema.NewMessage("Result")
+	suite.Require().NoError(proto.Unmarshal(encodedResult, decodedResult))
+
+	headers := decodedResult.Get(decodedResult.Descriptor().Fields().ByName("headers")).Map()
+	suite.Require().Equal(6, headers.Len())
+
+	for key, expectedValue := range map[string]struct {
+		field string
+		value interface{}
+	}{
+		"string": {"string_value", "value"},
+		"int":    {"int_value", int64(-3)},
+		"double": {"double_value", 1.5},
+		"bool":   {"bool_value", true},
+		"bytes":  {"bytes_value", []byte{0x00, 0xff}},
+		"list":   {"json_value", []byte(`["a","b"]`)},
+	} {
+		value := headers.Get(protoreflect.ValueOfString(key).MapKey()).Message()
+		field := value.Descriptor().Fields().ByName(protoreflect.Name(expectedValue.field))
+		suite.Require().Equal(field, value.WhichOneof(value.Descriptor().Oneofs().ByName("kind")), "Bad kind of %s", key)
+		suite.Require().Equal(expectedValue.value, value.Get(field).Interface(), "Bad value of %s", key)
+	}
+}
+
+func (suite *SchemaTestSuite) TestDecodeValueMap() {
+	logRecord := suite.schema.NewMessage("LogRecord")
+	with := logRecord.Mutable(logRecord.Descriptor().Fields().ByName("with")).Map()
+
+	for key, value := range map[string]struct {
+		field string
+		value protoreflect.Value
+	}{
+		"string": {"string_value", protoreflect.ValueOfString("value")},
+		"int":    {"int_value", protoreflect.ValueOfInt64(-3)},
+		"double": {"double_value", protoreflect.ValueOfFloat64(1.5)},
+		"bool":   {"bool_value", protoreflect.ValueOfBool(true)},
+		"bytes":  {"bytes_value", protoreflect.ValueOfBytes([]byte{0x00, 0xff})},
+		"list":   {"json_value", protoreflect.ValueOfBytes([]byte(`["a","b"]`))},
+	} {
+		encodedValue := with.NewValue()
+		encodedValue.Message().Set(encodedValue.Message().Descriptor().Fields().ByName(protoreflect.Name(value.field)),
+			value.value)
+		with.Set(protoreflect.ValueOfString(key).MapKey(), encodedValue)
+	}
+
+	encodedLogRecord, err := proto.Marshal(logRecord)
+	suite.Require().NoError(err)
+
+	decodedWith := map[string]interface{}{}
+	err = ConsumeFields(encodedLogRecord, func(number protowire.Number, _ protowire.Type, _ uint64, data []byte) error {
+		suite.Require().Equal(LogRecordWith, number)
+		return DecodeValueMapEntry(data, decodedWith)
+	})
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(map[string]interface{}{
+		"string": "value",
+		"int":    int64(-3),
+		"double": 1.5,
+		"bool":   true,
+		"bytes":  []byte{0x00, 0xff},
+		"list":   []interface{}{"a", "b"},
+	}, decodedWith)
+}
+
+func TestSchemaTestSuite(t *testing.T) {
+	suite.Run(t, new(SchemaTestSuite))
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rpcproto holds the protobuf schema of the messages exchanged with RPC wrappers (rpc.proto), and
+// the helpers the processor encodes and decodes them with. Messages are few and small, so they're written
+// field by field rather than through generated code
+package rpcproto
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+
+	"github.com/nuclio/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// AppendString appends a string field, unless it holds the default value
+func AppendString(b []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+// AppendBytes appends a bytes field, unless it holds the default value
+func AppendBytes(b []byte, number protowire.Number, value []byte) []byte {
+	if len(value) == 0 {
+		return b
+	}
+
+	return AppendMessage(b, number, value)
+}
+
+// AppendMessage appends an encoded message field. Empty messages are appended too, since they may be set
+// members of a oneof
+func AppendMessage(b []byte, number protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+// AppendInt appends an integer field, unless it holds the default value
+func AppendInt(b []byte, number protowire.Number, value int64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(value))
+}
+
+// AppendBool appends a bool field, unless it holds the default value
+func AppendBool(b []byte, number protowire.Number, value bool) []byte {
+	if !value {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeBool(value))
+}
+
+// AppendDouble appends a double field, unless it holds the default value
+func AppendDouble(b []byte, number protowire.Number, value float64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(value))
+}
+
+// AppendValueMap appends a map<string, Value> field, ordered by key so that encoding is deterministic
+func AppendValueMap(b []byte, number protowire.Number, values map[string]interface{}) ([]byte, error) {
+	for _, key := range sortedKeys(values) {
+		value, err := EncodeValue(values[key])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to encode value of %s", key)
+		}
+
+		entry := AppendString(nil, MapEntryKey, key)
+		entry = AppendMessage(entry, MapEntryValue, value)
+		b = AppendMessage(b, number, entry)
+	}
+
+	return b, nil
+}
+
+// EncodeValue encodes a Value message, holding the given value according to its type
+func EncodeValue(value interface{}) ([]byte, error) {
+	switch typedValue := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return protowire.AppendString(protowire.AppendTag(nil, ValueString, protowire.BytesType), typedValue), nil
+	case []byte:
+		return AppendMessage(nil, ValueBytes, typedValue), nil
+	case bool:
+		return protowire.AppendVarint(protowire.AppendTag(nil, ValueBool, protowire.VarintType),
+			protowire.EncodeBool(typedValue)), nil
+	case int:
+		return encodeIntValue(int64(typedValue)), nil
+	case int8:
+		return encodeIntValue(int64(typedValue)), nil
+	case int16:
+		return encodeIntValue(int64(typedValue)), nil
+	case int32:
+		return encodeIntValue(int64(typedValue)), nil
+	case int64:
+		return encodeIntValue(typedValue), nil
+	case uint8:
+		return encodeIntValue(int64(typedValue)), nil
+	case uint16:
+		return encodeIntValue(int64(typedValue)), nil
+	case uint32:
+		return encodeIntValue(int64(typedValue)), nil
+	case float32:
+		return encodeDoubleValue(float64(typedValue)), nil
+	case float64:
+		return encodeDoubleValue(typedValue), nil
+	}
+
+	// anything else (e.g. lists, maps and unsigned integers that may not fit) is encoded as JSON
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode value as JSON")
+	}
+
+	return AppendMessage(nil, ValueJSON, encodedValue), nil
+}
+
+func encodeIntValue(value int64) []byte {
+	return protowire.AppendVarint(protowire.AppendTag(nil, ValueInt, protowire.VarintType), uint64(value))
+}
+
+func encodeDoubleValue(value float64) []byte {
+	return protowire.AppendFixed64(protowire.AppendTag(nil, ValueDouble, protowire.Fixed64Type),
+		math.Float64bits(value))
+}
+
+// ConsumeFields calls handleField with every field of the encoded message. For varint and fixed size fields
+// the value is passed as a uint64, and for length-delimited fields the bytes are passed. Fields of unknown
+// types (i.e. groups) are skipped
+func ConsumeFields(b []byte,
+	handleField func(number protowire.Number, wireType protowire.Type, value uint64, data []byte) error) error {
+
+	for len(b) > 0 {
+		number, wireType, tagLength := protowire.ConsumeTag(b)
+		if tagLength < 0 {
+			return errors.Wrap(protowire.ParseError(tagLength), "Failed to decode field tag")
+		}
+
+		b = b[tagLength:]
+
+		var value uint64
+		var data []byte
+		var valueLength int
+
+		switch wireType {
+		case protowire.VarintType:
+			value, valueLength = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			value, valueLength = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var fixed32Value uint32
+			fixed32Value, valueLength = protowire.ConsumeFixed32(b)
+			value = uint64(fixed32Value)
+		case protowire.BytesType:
+			data, valueLength = protowire.ConsumeBytes(b)
+		default:
+			valueLength = protowire.ConsumeFieldValue(number, wireType, b)
+			if valueLength < 0 {
+				return errors.Wrapf(protowire.ParseError(valueLength), "Failed to skip field %d", number)
+			}
+
+			b = b[valueLength:]
+			continue
+		}
+
+		if valueLength < 0 {
+			return errors.Wrapf(protowire.ParseError(valueLength), "Failed to decode field %d", number)
+		}
+
+		b = b[valueLength:]
+
+		if err := handleField(number, wireType, value, data); err != nil {
+			return errors.Wrapf(err, "Failed to handle field %d", number)
+		}
+	}
+
+	return nil
+}
+
+// DecodeValue decodes a Value message into the Go value it holds
+func DecodeValue(b []byte) (interface{}, error) {
+	var decodedValue interface{}
+
+	err := ConsumeFields(b, func(number protowire.Number, wireType protowire.Type, value uint64, data []byte) error {
+		switch number {
+		case ValueString:
+			decodedValue = string(data)
+		case ValueInt:
+			decodedValue = int64(value)
+		case ValueDouble:
+			decodedValue = math.Float64frombits(value)
+		case ValueBool:
+			decodedValue = protowire.DecodeBool(value)
+		case ValueBytes:
+			decodedValue = data
+		case ValueJSON:
+			return json.Unmarshal(data, &decodedValue)
+		}
+
+		return nil
+	})
+
+	return decodedValue, err
+}
+
+// DecodeValueMapEntry decodes an entry of a map<string, Value> field into the given map
+func DecodeValueMapEntry(b []byte, values map[string]interface{}) error {
+	key, encodedValue, err := decodeMapEntry(b)
+	if err != nil {
+		return err
+	}
+
+	values[key], err = DecodeValue(encodedValue)
+	return err
+}
+
+// DecodeStringMapEntry decodes an entry of a map<string, string> field into the given map
+func DecodeStringMapEntry(b []byte, values map[string]string) error {
+	key, value, err := decodeMapEntry(b)
+	if err != nil {
+		return err
+	}
+
+	values[key] = string(value)
+	return nil
+}
+
+func decodeMapEntry(b []byte) (string, []byte, error) {
+	var key string
+	var value []byte
+
+	err := ConsumeFields(b, func(number protowire.Number, wireType protowire.Type, _ uint64, data []byte) error {
+		switch number {
+		case MapEntryKey:
+			key = string(data)
+		case MapEntryValue:
+			value = data
+		}
+
+		return nil
+	})
+
+	return key, value, err
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
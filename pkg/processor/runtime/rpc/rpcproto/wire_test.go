@@ -0,0 +1,87 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpcproto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type WireTestSuite struct {
+	suite.Suite
+}
+
+func (suite *WireTestSuite) TestValueMap() {
+	encodedMap, err := AppendValueMap(nil, 1, map[string]interface{}{
+		"string":  "value",
+		"int":     -3,
+		"double":  1.5,
+		"bool":    true,
+		"bytes":   []byte{0x00, 0xff},
+		"list":    []string{"a", "b"},
+		"missing": nil,
+	})
+	suite.Require().NoError(err)
+
+	decodedMap := map[string]interface{}{}
+	err = ConsumeFields(encodedMap, func(number protowire.Number, _ protowire.Type, _ uint64, data []byte) error {
+		suite.Require().Equal(protowire.Number(1), number)
+		return DecodeValueMapEntry(data, decodedMap)
+	})
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(map[string]interface{}{
+		"string":  "value",
+		"int":     int64(-3),
+		"double":  1.5,
+		"bool":    true,
+		"bytes":   []byte{0x00, 0xff},
+		"list":    []interface{}{"a", "b"},
+		"missing": nil,
+	}, decodedMap)
+}
+
+func (suite *WireTestSuite) TestConsumeFields() {
+	encodedMessage := AppendString(nil, 1, "known")
+	encodedMessage = protowire.AppendTag(encodedMessage, 7, protowire.Fixed32Type)
+	encodedMessage = protowire.AppendFixed32(encodedMessage, 42)
+	encodedMessage = AppendInt(encodedMessage, 2, 0)
+
+	var numbers []protowire.Number
+	err := ConsumeFields(encodedMessage, func(number protowire.Number, _ protowire.Type, _ uint64, _ []byte) error {
+		numbers = append(numbers, number)
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	// defaults aren't encoded, and fields unknown to the reader are passed along for it to ignore
+	suite.Require().Equal([]protowire.Number{1, 7}, numbers)
+
+	// truncated messages fail
+	err = ConsumeFields(encodedMessage[:3], func(protowire.Number, protowire.Type, uint64, []byte) error {
+		return nil
+	})
+	suite.Require().Error(err)
+}
+
+func TestWireTestSuite(t *testing.T) {
+	suite.Run(t, new(WireTestSuite))
+}
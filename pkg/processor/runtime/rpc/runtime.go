@@ -39,4 +39,8 @@ type Runtime interface {
 
 	// SupportsControlCommunication returns true if the runtime supports control communication
 	SupportsControlCommunication() bool
+
+	// SupportsShutdownSignals returns true if the wrapper handles the drain, termination and continue
+	// signals, calling the handler's shutdown callback when drained or terminated
+	SupportsShutdownSignals() bool
 }
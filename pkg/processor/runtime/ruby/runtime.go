@@ -76,7 +76,7 @@ func (r *ruby) RunWrapper(socketPaths []string, controlSocketPath string) (*os.P
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Env = env
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stderr = r.CaptureStderr(os.Stderr)
 	r.Logger.InfoWith("Running ruby wrapper", "command", strings.Join(cmd.Args, " "))
 
 	return cmd.Process, cmd.Start()
@@ -85,3 +85,8 @@ func (r *ruby) RunWrapper(socketPaths []string, controlSocketPath string) (*os.P
 func (r *ruby) GetEventEncoder(writer io.Writer) encoder.EventEncoder {
 	return encoder.NewEventJSONEncoder(r.Logger, writer)
 }
+
+// SupportsShutdownSignals returns true, as the wrapper calls the handler's shutdown_context when signaled
+func (r *ruby) SupportsShutdownSignals() bool {
+	return true
+}
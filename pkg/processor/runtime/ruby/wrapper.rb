@@ -112,6 +112,34 @@ def response_info_from_output(handler_output)
   end
 end
 
+# the signals the processor sends to drain or terminate the wrapper, and to continue once drained
+SHUTDOWN_SIGNALS = %w[USR1 USR2].freeze
+CONTINUE_SIGNAL = 'CONT'.freeze
+
+# calls shutdown_context once per drain or termination, and again only after continuing
+def handle_shutdown_signals(context)
+  shutdown_context_called = false
+
+  SHUTDOWN_SIGNALS.each do |signal|
+    Signal.trap(signal) do
+      next if shutdown_context_called || !defined?(shutdown_context)
+
+      shutdown_context_called = true
+
+      # trap handlers can't take locks, so the callback runs in a thread of its own
+      Thread.new do
+        begin
+          send('shutdown_context', context)
+        rescue StandardError => e
+          context.logger.warn('Failed to shut down context', error: e.message)
+        end
+      end
+    end
+  end
+
+  Signal.trap(CONTINUE_SIGNAL) { shutdown_context_called = false }
+end
+
 def parse_event(input)
   json = JSON.parse(input)
   trigger = Trigger.new(class_name: json['trigger']['class'], kind: json['trigger']['kind'])
@@ -145,6 +173,10 @@ if $PROGRAM_NAME == __FILE__
   logger = Logger.new(socket)
   context = Context.new(logger)
 
+  # the signals are handled before the processor is told the wrapper started, since they'd otherwise
+  # terminate it
+  handle_shutdown_signals(context)
+
   # check if init_context function is defined and execute it
   if defined?(init_context)
       send("init_context", context)
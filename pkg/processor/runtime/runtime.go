@@ -19,10 +19,14 @@ package runtime
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
 
 	"github.com/nuclio/nuclio/pkg/common/status"
 	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
 	"github.com/nuclio/nuclio/pkg/processor/databinding"
+	"github.com/nuclio/nuclio/pkg/processor/invocation"
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
@@ -65,15 +69,30 @@ type Runtime interface {
 	// SupportsRestart return true if the runtime supports restart
 	SupportsRestart() bool
 
-	// Drain signals to the runtime process to drain its accumulated events and waits for it to finish
+	// GetMaxConcurrentEvents returns the number of events the runtime may process at a time
+	GetMaxConcurrentEvents() int
+
+	// GetCrashLoopStatus returns the crashes of the runtime's wrapper process, or nil if the runtime doesn't
+	// run one
+	GetCrashLoopStatus() *CrashLoopStatus
+
+	// Drain signals to the runtime process to drain its accumulated events, which calls the handler's shutdown
+	// callback, and waits for it up to the worker termination timeout. Runtimes whose handlers have no shutdown
+	// callback return right away
 	Drain() error
 
-	// Continue signals the runtime process to continue event processing
+	// Continue signals the runtime process to continue event processing. The handler's shutdown callback is
+	// called once per drain or termination, and again only after continuing
 	Continue() error
 
-	// Terminate signals to the runtime process that processor is about to stop working
+	// Terminate signals to the runtime process that processor is about to stop working, which calls the
+	// handler's shutdown callback like Drain does
 	Terminate() error
 
+	// SetRestartListener sets a function to call whenever the runtime restarts by itself (e.g. when its
+	// wrapper process crashed), once it's ready to process events again
+	SetRestartListener(restartListener func())
+
 	// GetControlMessageBroker returns the control message broker
 	GetControlMessageBroker() controlcommunication.ControlMessageBroker
 }
@@ -84,9 +103,20 @@ type AbstractRuntime struct {
 	FunctionLogger logger.Logger
 	Context        *nuclio.Context
 	Statistics     Statistics
-	databindings   map[string]databinding.DataBinding
-	configuration  *Configuration
-	status         status.Status
+
+	// calls other functions on behalf of handlers. it's also available to them through the context. it's
+	// shared by the processor's workers, unless the runtime wasn't given one and created its own
+	InvocationClient     *invocation.Client
+	ownsInvocationClient bool
+
+	databindings  map[string]databinding.DataBinding
+	configuration *Configuration
+
+	// accessed atomically, since wrapper processes may be restarted in the background
+	status int32
+
+	// holds a func(), as runtimes may restart in the background
+	restartListener atomic.Value
 }
 
 // NewAbstractRuntime creates a new abstract runtime
@@ -119,7 +149,7 @@ func NewAbstractRuntime(logger logger.Logger, configuration *Configuration) (*Ab
 	}
 
 	// set the initial status
-	newAbstractRuntime.status = status.Initializing
+	newAbstractRuntime.status = int32(status.Initializing)
 
 	return &newAbstractRuntime, nil
 }
@@ -141,12 +171,12 @@ func (ar *AbstractRuntime) GetStatistics() *Statistics {
 
 // SetStatus sets the runtime's reported status
 func (ar *AbstractRuntime) SetStatus(newStatus status.Status) {
-	ar.status = newStatus
+	atomic.StoreInt32(&ar.status, int32(newStatus))
 }
 
 // GetStatus returns the runtime's reported status
 func (ar *AbstractRuntime) GetStatus() status.Status {
-	return ar.status
+	return status.Status(atomic.LoadInt32(&ar.status))
 }
 
 // Start starts the runtime, or does nothing if the runtime does not require starting (e.g. Go and shell runtimes)
@@ -165,18 +195,41 @@ func (ar *AbstractRuntime) SupportsRestart() bool {
 	return false
 }
 
+// GetMaxConcurrentEvents returns 1, as runtimes process one event at a time unless stated otherwise
+func (ar *AbstractRuntime) GetMaxConcurrentEvents() int {
+	return 1
+}
+
+// GetCrashLoopStatus returns nil, as runtimes don't run a wrapper process unless stated otherwise
+func (ar *AbstractRuntime) GetCrashLoopStatus() *CrashLoopStatus {
+	return nil
+}
+
 // SupportsControlCommunication returns true if the runtime supports control communication
 func (ar *AbstractRuntime) SupportsControlCommunication() bool {
 	return false
 }
 
 func (ar *AbstractRuntime) GetEnvFromConfiguration() []string {
-	return []string{
+	env := []string{
 		fmt.Sprintf("NUCLIO_FUNCTION_NAME=%s", ar.configuration.Meta.Name),
 		fmt.Sprintf("NUCLIO_FUNCTION_DESCRIPTION=%s", ar.configuration.Spec.Description),
 		fmt.Sprintf("NUCLIO_FUNCTION_VERSION=%d", ar.configuration.Spec.Version),
 		fmt.Sprintf("NUCLIO_FUNCTION_HANDLER=%s", ar.configuration.Spec.Handler),
 	}
+
+	// let wrappers know which data bindings they can send messages through
+	if len(ar.databindings) > 0 {
+		var databindingNames []string
+		for databindingName := range ar.databindings {
+			databindingNames = append(databindingNames, databindingName)
+		}
+
+		sort.Strings(databindingNames)
+		env = append(env, fmt.Sprintf("NUCLIO_DATA_BINDINGS=%s", strings.Join(databindingNames, ",")))
+	}
+
+	return env
 }
 
 // GetControlMessageBroker returns the control message broker
@@ -186,10 +239,29 @@ func (ar *AbstractRuntime) GetControlMessageBroker() controlcommunication.Contro
 
 // Stop stops the runtime
 func (ar *AbstractRuntime) Stop() error {
+	invocation.Unregister(ar.Context)
+
+	if ar.ownsInvocationClient {
+		ar.InvocationClient.Close()
+	}
+
 	ar.SetStatus(status.Stopped)
 	return nil
 }
 
+// SetRestartListener sets a function to call whenever the runtime restarts by itself
+func (ar *AbstractRuntime) SetRestartListener(restartListener func()) {
+	ar.restartListener.Store(restartListener)
+}
+
+// NotifyRestarted calls the restart listener, if one was set. Runtimes call it once they restarted by
+// themselves, rather than when told to restart
+func (ar *AbstractRuntime) NotifyRestarted() {
+	if restartListener, _ := ar.restartListener.Load().(func()); restartListener != nil {
+		restartListener()
+	}
+}
+
 func (ar *AbstractRuntime) Drain() error {
 	return nil
 }
@@ -261,6 +333,22 @@ func (ar *AbstractRuntime) createContext(parentLogger logger.Logger,
 		return nil, errors.Wrap(err, "Failed to initialize Platform")
 	}
 
+	// the platform only calls functions synchronously, so handlers that need more get an invocation client
+	invocationClient := configuration.InvocationClient
+	if invocationClient == nil {
+		if invocationClient, err = invocation.NewClient(parentLogger, &invocation.Configuration{
+			PlatformKind: ar.configuration.PlatformConfig.Kind,
+			Namespace:    ar.configuration.Meta.Namespace,
+		}); err != nil {
+			return nil, errors.Wrap(err, "Failed to create invocation client")
+		}
+
+		ar.ownsInvocationClient = true
+	}
+
+	invocation.Register(newContext, invocationClient)
+	ar.InvocationClient = invocationClient
+
 	// iterate through data bindings and get the context object - the thing users will actuall
 	// work with in the handlers
 	for databindingName, databindingInstance := range databindings {
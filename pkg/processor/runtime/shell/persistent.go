@@ -0,0 +1,190 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// framer writes events to a persistent command and reads its responses
+type framer interface {
+	writeFrame(writer io.Writer, data []byte) error
+	readFrame(reader *bufio.Reader) ([]byte, error)
+}
+
+func newFramer(configuration *Configuration) (framer, error) {
+	switch configuration.Framing {
+	case FramingDelimiter:
+		return &delimiterFramer{
+			delimiter:    []byte(configuration.Delimiter),
+			maxFrameSize: configuration.MaxFrameSize,
+		}, nil
+	case FramingLength:
+		return &lengthFramer{maxFrameSize: configuration.MaxFrameSize}, nil
+	default:
+		return nil, errors.Errorf("Unsupported framing %s", configuration.Framing)
+	}
+}
+
+type delimiterFramer struct {
+	delimiter    []byte
+	maxFrameSize int
+}
+
+func (f *delimiterFramer) writeFrame(writer io.Writer, data []byte) error {
+	if bytes.Contains(data, f.delimiter) {
+		return errors.New("Event body contains the delimiter")
+	}
+
+	if _, err := writer.Write(append(data[:len(data):len(data)], f.delimiter...)); err != nil {
+		return errors.Wrap(err, "Failed to write event")
+	}
+
+	return nil
+}
+
+func (f *delimiterFramer) readFrame(reader *bufio.Reader) ([]byte, error) {
+	var frame []byte
+
+	// read up to the last byte of the delimiter until the whole delimiter was read, a buffer at a time so that
+	// a response that's too large isn't read whole
+	for !bytes.HasSuffix(frame, f.delimiter) {
+		data, err := reader.ReadSlice(f.delimiter[len(f.delimiter)-1])
+		frame = append(frame, data...)
+
+		if len(frame) > f.maxFrameSize+len(f.delimiter) {
+			return nil, errors.Errorf("Response is larger than the max frame size (%d bytes)", f.maxFrameSize)
+		}
+
+		if err != nil && err != bufio.ErrBufferFull {
+			return frame, errors.Wrap(err, "Failed to read response")
+		}
+	}
+
+	return frame[:len(frame)-len(f.delimiter)], nil
+}
+
+type lengthFramer struct {
+	maxFrameSize int
+}
+
+func (f *lengthFramer) writeFrame(writer io.Writer, data []byte) error {
+	if len(data) > math.MaxUint32 {
+		return errors.Errorf("Event body is too large (%d bytes)", len(data))
+	}
+
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(data)), uint32(len(data)))
+	if _, err := writer.Write(append(frame, data...)); err != nil {
+		return errors.Wrap(err, "Failed to write event")
+	}
+
+	return nil
+}
+
+func (f *lengthFramer) readFrame(reader *bufio.Reader) ([]byte, error) {
+	var frameLength uint32
+	if err := binary.Read(reader, binary.BigEndian, &frameLength); err != nil {
+		return nil, errors.Wrap(err, "Failed to read response length")
+	}
+
+	if int64(frameLength) > int64(f.maxFrameSize) {
+		return nil, errors.Errorf("Response length %d is larger than the max frame size (%d bytes)",
+			frameLength,
+			f.maxFrameSize)
+	}
+
+	frame := make([]byte, frameLength)
+	if _, err := io.ReadFull(reader, frame); err != nil {
+		return nil, errors.Wrap(err, "Failed to read response")
+	}
+
+	return frame, nil
+}
+
+// persistentProcess is a command that is started once, and processes the events streamed to it one at a time
+type persistentProcess struct {
+	logger logger.Logger
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	framer framer
+}
+
+func newPersistentProcess(parentLogger logger.Logger, cmd *exec.Cmd, framer framer) (*persistentProcess, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create stdin pipe")
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create stdout pipe")
+	}
+
+	// stdout is reserved for responses, so the command may only log to stderr
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, "Failed to start command")
+	}
+
+	parentLogger.DebugWith("Started persistent command", "pid", cmd.Process.Pid, "args", cmd.Args)
+
+	return &persistentProcess{
+		logger: parentLogger,
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+		framer: framer,
+	}, nil
+}
+
+// processEvent writes the event body to the command and returns its response. Once it fails, the command
+// can no longer be used, as it can't be told where the next response starts
+func (p *persistentProcess) processEvent(body []byte) ([]byte, error) {
+	if err := p.framer.writeFrame(p.stdin, body); err != nil {
+		return nil, err
+	}
+
+	return p.framer.readFrame(p.stdout)
+}
+
+func (p *persistentProcess) stop() error {
+
+	// closing stdin lets commands that read until EOF exit, and the rest are killed
+	p.stdin.Close() // nolint: errcheck
+
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return errors.Wrap(err, "Failed to kill command")
+	}
+
+	// the exit status of a killed command is meaningless
+	p.cmd.Wait() // nolint: errcheck
+
+	p.logger.DebugWith("Stopped persistent command", "pid", p.cmd.Process.Pid)
+
+	return nil
+}
@@ -0,0 +1,134 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type PersistentTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *PersistentTestSuite) SetupSuite() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *PersistentTestSuite) TestFramers() {
+	for _, testCase := range []struct {
+		name   string
+		framer framer
+	}{
+		{name: "newline", framer: &delimiterFramer{delimiter: []byte("\n"), maxFrameSize: DefaultMaxFrameSize}},
+		{
+			name:   "multiByteDelimiter",
+			framer: &delimiterFramer{delimiter: []byte("\r\n--\r\n"), maxFrameSize: DefaultMaxFrameSize},
+		},
+		{name: "length", framer: &lengthFramer{maxFrameSize: DefaultMaxFrameSize}},
+	} {
+		suite.Run(testCase.name, func() {
+			var buffer bytes.Buffer
+
+			frames := [][]byte{[]byte("first\r"), {}, []byte("third -- frame")}
+			for _, frame := range frames {
+				suite.Require().NoError(testCase.framer.writeFrame(&buffer, frame))
+			}
+
+			reader := bufio.NewReader(&buffer)
+			for _, frame := range frames {
+				readFrame, err := testCase.framer.readFrame(reader)
+				suite.Require().NoError(err)
+				suite.Require().Equal(string(frame), string(readFrame))
+			}
+
+			// nothing is left, so reading fails
+			_, err := testCase.framer.readFrame(reader)
+			suite.Require().Error(err)
+		})
+	}
+}
+
+func (suite *PersistentTestSuite) TestFrameTooLarge() {
+	for _, testCase := range []struct {
+		name   string
+		framer framer
+	}{
+		{name: "delimiter", framer: &delimiterFramer{delimiter: []byte("\r\n"), maxFrameSize: 20}},
+		{name: "length", framer: &lengthFramer{maxFrameSize: 20}},
+	} {
+		suite.Run(testCase.name, func() {
+			var buffer bytes.Buffer
+
+			for _, frame := range []string{strings.Repeat("a", 20), strings.Repeat("b", 40)} {
+				suite.Require().NoError(testCase.framer.writeFrame(&buffer, []byte(frame)))
+			}
+
+			// frames up to the max frame size are read, even when larger than the reader's buffer, and larger
+			// frames fail
+			reader := bufio.NewReaderSize(&buffer, 16)
+			readFrame, err := testCase.framer.readFrame(reader)
+			suite.Require().NoError(err)
+			suite.Require().Equal(strings.Repeat("a", 20), string(readFrame))
+
+			_, err = testCase.framer.readFrame(reader)
+			suite.Require().ErrorContains(err, "max frame size")
+		})
+	}
+}
+
+func (suite *PersistentTestSuite) TestDelimiterInBody() {
+	var buffer bytes.Buffer
+
+	err := (&delimiterFramer{delimiter: []byte("\n"), maxFrameSize: DefaultMaxFrameSize}).writeFrame(&buffer, []byte("two\nlines"))
+	suite.Require().Error(err)
+	suite.Require().Zero(buffer.Len())
+}
+
+func (suite *PersistentTestSuite) TestProcessEvents() {
+	process, err := newPersistentProcess(suite.logger, exec.Command("cat"), &lengthFramer{maxFrameSize: DefaultMaxFrameSize})
+	suite.Require().NoError(err)
+
+	// the same process handles all events
+	for _, body := range []string{"first", "second\nwith newline"} {
+		response, err := process.processEvent([]byte(body))
+		suite.Require().NoError(err)
+		suite.Require().Equal(body, string(response))
+	}
+
+	suite.Require().NoError(process.stop())
+
+	_, err = process.processEvent([]byte("after stop"))
+	suite.Require().Error(err)
+}
+
+func TestPersistentTestSuite(t *testing.T) {
+	suite.Run(t, new(PersistentTestSuite))
+}
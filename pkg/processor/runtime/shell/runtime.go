@@ -24,6 +24,7 @@ import (
 	"os/exec"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
@@ -45,6 +46,11 @@ type shell struct {
 	commandInPath  bool
 	ctx            context.Context
 	restartChannel chan struct{}
+
+	// in persistent mode, the command that processes all events
+	framer                framer
+	persistentProcess     *persistentProcess
+	persistentProcessLock sync.Mutex
 }
 
 // NewRuntime returns a new shell runtime
@@ -83,14 +89,24 @@ func NewRuntime(parentLogger logger.Logger, configuration *Configuration) (runti
 		return nil, errors.Wrap(err, "Failed checking if command is in PATH")
 	}
 
+	if configuration.Mode == ModePersistent {
+		if newShellRuntime.framer, err = newFramer(configuration); err != nil {
+			return nil, errors.Wrap(err, "Failed to create framer")
+		}
+	}
+
 	newShellRuntime.SetStatus(status.Ready)
 
 	return newShellRuntime, nil
 }
 
 func (s *shell) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
-	command := []string{s.command}
-	command = append(command, s.getCommandArguments(event)...)
+	var command []string
+	if s.configuration.Mode == ModePersistent {
+		command = s.getPersistentCommand()
+	} else {
+		command = append([]string{s.command}, s.getCommandArguments(event)...)
+	}
 
 	// create a timeout context
 	ctx, cancel := context.WithCancel(s.ctx)
@@ -101,12 +117,17 @@ func (s *shell) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (
 		"eventID", event.GetID(),
 		"bodyLen", len(event.GetBody()),
 		"command", command,
+		"mode", s.configuration.Mode,
 		"eventTimeout", s.configuration.Spec.EventTimeout)
 
 	responseChan := make(chan nuclio.Response, 1)
 
 	// process event in background
-	go s.processEvent(ctx, command, event, responseChan)
+	if s.configuration.Mode == ModePersistent {
+		go s.processEventPersistent(command, event, responseChan)
+	} else {
+		go s.processEvent(ctx, command, event, responseChan)
+	}
 
 	// wait for event response, return once it is done (or errored)
 	for {
@@ -120,6 +141,9 @@ func (s *shell) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (
 		case <-s.restartChannel:
 			s.Logger.Warn("Cancelling execution due to an ongoing restart")
 			cancel()
+
+			// the restart fails the execution, which mustn't be mistaken for its response
+			return nil, nuclio.NewErrRequestTimeout("Failed waiting for function execution")
 		}
 	}
 }
@@ -143,18 +167,7 @@ func (s *shell) processEvent(context context.Context,
 		responseChan <- response
 	}()
 
-	var cmd *exec.Cmd
-
-	if s.commandInPath {
-
-		// if the command is an executable, run it as a command with sh -c.
-		cmd = exec.CommandContext(context, "sh", "-c", strings.Join(command, " "))
-	} else {
-
-		// if the command is a shell script run it with sh(without -c). this will make sh
-		// read the script and run it as shell script and run it.
-		cmd = exec.CommandContext(context, "sh", command...)
-	}
+	cmd := s.createCmd(context, command)
 
 	cmd.Stdin = strings.NewReader(string(event.GetBody()))
 
@@ -181,8 +194,66 @@ func (s *shell) processEvent(context context.Context,
 		return
 	}
 
-	// calculate call duration
-	callDuration := time.Since(startTime)
+	s.recordCallDuration(event, time.Since(startTime))
+
+	response.StatusCode = http.StatusOK
+	response.Body = out
+}
+
+func (s *shell) processEventPersistent(command []string,
+	event nuclio.Event,
+	responseChan chan nuclio.Response) {
+
+	response := nuclio.Response{
+		StatusCode: http.StatusInternalServerError,
+		Headers:    s.configuration.ResponseHeaders,
+	}
+
+	// write response upon finishing
+	defer func() {
+		responseChan <- response
+	}()
+
+	process, err := s.getPersistentProcess(command)
+	if err != nil {
+		s.Logger.ErrorWith("Failed to start persistent shell command",
+			"name", s.configuration.Meta.Name,
+			"command", command,
+			"err", err)
+		response.Body = []byte(fmt.Sprintf(ResponseErrorFormat, err, ""))
+		return
+	}
+
+	// save timestamp
+	startTime := time.Now()
+
+	out, err := process.processEvent(event.GetBody())
+	if err != nil {
+		s.Logger.ErrorWith("Failed to process event by persistent shell command",
+			"name", s.configuration.Meta.Name,
+			"version", s.configuration.Spec.Version,
+			"eventID", event.GetID(),
+			"bodyLen", len(event.GetBody()),
+			"command", command,
+			"err", err)
+		response.Body = []byte(fmt.Sprintf(ResponseErrorFormat, err, out))
+
+		// the command can't be trusted to read the next event where this one ends, so it's replaced
+		// when the next event arrives
+		if err := s.stopPersistentProcess(process); err != nil {
+			s.Logger.WarnWith("Failed to stop persistent shell command", "err", err)
+		}
+
+		return
+	}
+
+	s.recordCallDuration(event, time.Since(startTime))
+
+	response.StatusCode = http.StatusOK
+	response.Body = out
+}
+
+func (s *shell) recordCallDuration(event nuclio.Event, callDuration time.Duration) {
 
 	// add duration to sum
 	s.Statistics.DurationMilliSecondsSum += uint64(callDuration.Nanoseconds() / 1000000)
@@ -191,8 +262,67 @@ func (s *shell) processEvent(context context.Context,
 	s.Logger.DebugWith("Shell executed",
 		"eventID", event.GetID(),
 		"callDuration", callDuration)
-	response.StatusCode = http.StatusOK
-	response.Body = out
+}
+
+func (s *shell) createCmd(context context.Context, command []string) *exec.Cmd {
+	if s.commandInPath {
+
+		// if the command is an executable, run it as a command with sh -c.
+		return exec.CommandContext(context, "sh", "-c", strings.Join(command, " "))
+	}
+
+	// if the command is a shell script run it with sh(without -c). this will make sh
+	// read the script and run it as shell script and run it.
+	return exec.CommandContext(context, "sh", command...)
+}
+
+// getPersistentCommand returns the command run in persistent mode. As it serves all events, their
+// arguments header is ignored
+func (s *shell) getPersistentCommand() []string {
+	return append([]string{s.command}, strings.Split(s.configuration.Arguments, " ")...)
+}
+
+// getPersistentProcess returns the running persistent command, starting it if it isn't running
+func (s *shell) getPersistentProcess(command []string) (*persistentProcess, error) {
+	s.persistentProcessLock.Lock()
+	defer s.persistentProcessLock.Unlock()
+
+	if s.persistentProcess != nil {
+		return s.persistentProcess, nil
+	}
+
+	// the command outlives events, so it is only stopped explicitly
+	cmd := s.createCmd(context.Background(), command)
+	cmd.Env = s.env
+
+	process, err := newPersistentProcess(s.Logger, cmd, s.framer)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create persistent process")
+	}
+
+	s.persistentProcess = process
+
+	return process, nil
+}
+
+// stopPersistentProcess stops the given persistent command, or the running one if nil
+func (s *shell) stopPersistentProcess(process *persistentProcess) error {
+	s.persistentProcessLock.Lock()
+
+	if process == nil {
+		process = s.persistentProcess
+	}
+
+	// the process may have already been replaced
+	if process == nil || process != s.persistentProcess {
+		s.persistentProcessLock.Unlock()
+		return nil
+	}
+
+	s.persistentProcess = nil
+	s.persistentProcessLock.Unlock()
+
+	return process.stop()
 }
 
 func (s *shell) getCommand() (string, error) {
@@ -270,19 +400,38 @@ func (s *shell) getEnvFromEvent(event nuclio.Event) []string {
 }
 
 func (s *shell) Restart() error {
+	s.Logger.Warn("Restarting")
+
+	// cancel the ongoing execution before stopping, as stopping a persistent command fails it
+	s.restartChannel <- struct{}{}
+
 	if err := s.Stop(); err != nil {
 		return errors.Wrap(err, "Failed to stop runtime")
 	}
-	s.Logger.Warn("Restarting")
-	s.restartChannel <- struct{}{}
 	return s.Start()
 }
 
 func (s *shell) Start() error {
+
+	// in persistent mode the command is started up front, so that the first event doesn't pay for it
+	if s.configuration.Mode == ModePersistent {
+		if _, err := s.getPersistentProcess(s.getPersistentCommand()); err != nil {
+			return errors.Wrap(err, "Failed to start persistent command")
+		}
+	}
+
 	s.SetStatus(status.Ready)
 	return nil
 }
 
+func (s *shell) Stop() error {
+	if err := s.stopPersistentProcess(nil); err != nil {
+		return errors.Wrap(err, "Failed to stop persistent command")
+	}
+
+	return s.AbstractRuntime.Stop()
+}
+
 func (s *shell) SupportsRestart() bool {
 	return true
 }
@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"strings"
 	"testing"
 	"time"
 
@@ -116,6 +117,73 @@ func (suite *ShellRuntimeSuite) TestTimeout() {
 	suite.Require().Equal(http.StatusRequestTimeout, responseError.StatusCode())
 }
 
+func (suite *ShellRuntimeSuite) TestPersistent() {
+	handlerDir := os.Getenv("NUCLIO_SHELL_HANDLER_DIR")
+	defer os.Setenv("NUCLIO_SHELL_HANDLER_DIR", handlerDir) // nolint: errcheck
+
+	err := os.Setenv("NUCLIO_SHELL_HANDLER_DIR", path.Join(path.Dir(handlerDir), "persistent"))
+	suite.Require().NoError(err, "Failed to set NUCLIO_SHELL_HANDLER_DIR env")
+
+	runtimeConfiguration := suite.resolveRuntimeConfiguration(suite.logger)
+	runtimeConfiguration.Spec.Handler = "uppercase.sh:main"
+	runtimeConfiguration.Spec.RuntimeAttributes = map[string]interface{}{
+		"mode": ModePersistent,
+	}
+
+	configuration, err := NewConfiguration(runtimeConfiguration)
+	suite.Require().NoError(err, "Failed to create new configuration")
+
+	runtimeInstance, err := NewRuntime(suite.logger, configuration)
+	suite.Require().NoError(err, "Failed to create new shell runtime")
+	suite.Require().NoError(runtimeInstance.Start())
+	defer runtimeInstance.Stop() // nolint: errcheck
+
+	processEvent := func(body string) (nuclio.Response, error) {
+		eventInstance := &nuclio.MemoryEvent{Body: []byte(body)}
+		eventInstance.SetTriggerInfoProvider(&TestTriggerInfoProvider{})
+
+		response, err := runtimeInstance.ProcessEvent(eventInstance, suite.logger)
+		if err != nil {
+			return nuclio.Response{}, err
+		}
+
+		return response.(nuclio.Response), nil
+	}
+
+	// all events are handled by the same process
+	var pid string
+	for _, body := range []string{"first", "second"} {
+		response, err := processEvent(body)
+		suite.Require().NoError(err)
+		suite.Require().Equal(http.StatusOK, response.StatusCode)
+
+		fields := strings.Fields(string(response.Body))
+		suite.Require().Len(fields, 2)
+		suite.Require().Equal(strings.ToUpper(body), fields[0])
+
+		if pid == "" {
+			pid = fields[1]
+		}
+		suite.Require().Equal(pid, fields[1])
+	}
+
+	// restarting on timeout starts a new process, which speaks the same protocol
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		suite.Require().NoError(runtimeInstance.Restart())
+	}()
+
+	_, err = processEvent("hang")
+	suite.Require().Error(err)
+	suite.Require().Equal(http.StatusRequestTimeout, err.(*nuclio.ErrorWithStatusCode).StatusCode())
+
+	response, err := processEvent("third")
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, response.StatusCode)
+	suite.Require().True(strings.HasPrefix(string(response.Body), "THIRD "))
+	suite.Require().NotEqual(pid, strings.Fields(string(response.Body))[1])
+}
+
 func (suite *ShellRuntimeSuite) resolveRuntimeConfiguration(loggerInstance logger.Logger) *runtime.Configuration {
 	return &runtime.Configuration{
 		FunctionLogger: loggerInstance,
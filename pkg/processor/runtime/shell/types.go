@@ -25,10 +25,39 @@ import (
 
 const ResponseErrorFormat = "Failed to run shell command.\nError: %s\nOutput:%s"
 
+// modes in which the command is run
+const (
+
+	// the command is forked for every event
+	ModeFork = "fork"
+
+	// the command is started once per worker, and events are streamed to it over stdin
+	ModePersistent = "persistent"
+)
+
+// framings of events and responses streamed to and from a persistent command
+const (
+
+	// each event and response is followed by a delimiter
+	FramingDelimiter = "delimiter"
+
+	// each event and response is preceded by its length, as a 4 byte big endian unsigned integer
+	FramingLength = "length"
+
+	DefaultDelimiter = "\n"
+
+	// the largest response read from a persistent command, in bytes
+	DefaultMaxFrameSize = 64 * 1024 * 1024
+)
+
 type Configuration struct {
 	*runtime.Configuration
 	Arguments       string
 	ResponseHeaders map[string]interface{}
+	Mode            string
+	Framing         string
+	Delimiter       string
+	MaxFrameSize    int
 }
 
 func NewConfiguration(runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
@@ -41,5 +70,32 @@ func NewConfiguration(runtimeConfiguration *runtime.Configuration) (*Configurati
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
+	switch newConfiguration.Mode {
+	case "":
+		newConfiguration.Mode = ModeFork
+	case ModeFork, ModePersistent:
+	default:
+		return nil, errors.Errorf("Unsupported mode %s", newConfiguration.Mode)
+	}
+
+	switch newConfiguration.Framing {
+	case "":
+		newConfiguration.Framing = FramingDelimiter
+	case FramingDelimiter, FramingLength:
+	default:
+		return nil, errors.Errorf("Unsupported framing %s", newConfiguration.Framing)
+	}
+
+	if newConfiguration.Delimiter == "" {
+		newConfiguration.Delimiter = DefaultDelimiter
+	}
+
+	switch {
+	case newConfiguration.MaxFrameSize < 0:
+		return nil, errors.Errorf("Invalid max frame size %d", newConfiguration.MaxFrameSize)
+	case newConfiguration.MaxFrameSize == 0:
+		newConfiguration.MaxFrameSize = DefaultMaxFrameSize
+	}
+
 	return &newConfiguration, nil
 }
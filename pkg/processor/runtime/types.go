@@ -23,6 +23,7 @@ import (
 
 	"github.com/nuclio/nuclio/pkg/processor"
 	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/invocation"
 
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
@@ -56,6 +57,9 @@ type Configuration struct {
 	TriggerKind              string
 	WorkerTerminationTimeout time.Duration
 	ControlMessageBroker     *controlcommunication.AbstractControlMessageBroker
+
+	// the client all workers call other functions through. if nil, the runtime creates its own
+	InvocationClient *invocation.Client
 }
 
 type ResponseWithErrors struct {
@@ -67,3 +71,19 @@ type ResponseWithErrors struct {
 }
 
 var ErrNoResponseFromBatchResponse = errors.New("processor hasn't received corresponding response for the event")
+
+// CrashLoopStatus describes the crashes of a runtime's wrapper process
+type CrashLoopStatus struct {
+
+	// the number of times the wrapper crashed since it last ran long enough to be considered stable
+	Crashes int `json:"crashes"`
+
+	// set once the wrapper crashed too many times in a row, after which it's no longer restarted
+	CrashLooping bool `json:"crashLooping"`
+
+	LastCrashTime time.Time `json:"lastCrashTime"`
+	LastError     string    `json:"lastError,omitempty"`
+
+	// the last lines the wrapper wrote to stderr before its last crash
+	LastStderrLines []string `json:"lastStderrLines,omitempty"`
+}
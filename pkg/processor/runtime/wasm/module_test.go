@@ -0,0 +1,170 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+// a minimal wasm binary encoder, so that tests don't depend on a wasm toolchain
+
+const (
+	testLogMessage   = "handling event"
+	logMessageOffset = 0
+	responseOffset   = 256
+	eventOffset      = 4096
+)
+
+// loop forever: loop br 0 end unreachable
+var infiniteLoopBody = []byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x00}
+
+// newTestModule encodes a module that imports nuclio.log, exports memory with the given minimum number of pages
+// and a "handle" function with the given body. nuclio_alloc always returns eventOffset, and is only exported
+// if exportAlloc is set
+func newTestModule(memoryPages uint32, handleBody []byte, exportAlloc bool) []byte {
+	return newTestModuleWithShutdown(memoryPages, handleBody, exportAlloc, nil)
+}
+
+// newTestModuleWithShutdown encodes a test module that also exports nuclio_shutdown with the given body, if any
+func newTestModuleWithShutdown(memoryPages uint32, handleBody []byte, exportAlloc bool, shutdownBody []byte) []byte {
+	const (
+		valueTypeI32 = 0x7f
+		valueTypeI64 = 0x7e
+		functionType = 0x60
+	)
+
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+
+	// types: 0 = log(i32, i32, i32), 1 = alloc(i32) i32, 2 = handle(i32, i32) i64, 3 = shutdown()
+	module = append(module, encodeSection(1, encodeVector(
+		[]byte{functionType, 0x03, valueTypeI32, valueTypeI32, valueTypeI32, 0x00},
+		[]byte{functionType, 0x01, valueTypeI32, 0x01, valueTypeI32},
+		[]byte{functionType, 0x02, valueTypeI32, valueTypeI32, 0x01, valueTypeI64},
+		[]byte{functionType, 0x00, 0x00},
+	))...)
+
+	// imports: function 0 = nuclio.log
+	module = append(module, encodeSection(2, encodeVector(
+		concatBytes(encodeName(hostModuleName), encodeName(hostLogFunctionName), []byte{0x00, 0x00}),
+	))...)
+
+	// functions: 1 = alloc, 2 = handle, 3 = shutdown
+	functions := [][]byte{{0x01}, {0x02}}
+	functionBodies := [][]byte{encodeFunctionBody(i32Const(eventOffset)), encodeFunctionBody(handleBody)}
+
+	if shutdownBody != nil {
+		functions = append(functions, []byte{0x03})
+		functionBodies = append(functionBodies, encodeFunctionBody(shutdownBody))
+	}
+
+	module = append(module, encodeSection(3, encodeVector(functions...))...)
+
+	// memory 0, no maximum
+	module = append(module, encodeSection(5, encodeVector(concatBytes([]byte{0x00}, encodeUnsigned(memoryPages))))...)
+
+	exports := [][]byte{
+		concatBytes(encodeName(memoryName), []byte{0x02, 0x00}),
+		concatBytes(encodeName(DefaultEntrypoint), []byte{0x00, 0x02}),
+	}
+
+	if exportAlloc {
+		exports = append(exports, concatBytes(encodeName(allocFunctionName), []byte{0x00, 0x01}))
+	}
+
+	if shutdownBody != nil {
+		exports = append(exports, concatBytes(encodeName(shutdownFunctionName), []byte{0x00, 0x03}))
+	}
+
+	module = append(module, encodeSection(7, encodeVector(exports...))...)
+
+	// code: each body is prefixed with its size and an empty locals vector, and ends with "end"
+	module = append(module, encodeSection(10, encodeVector(functionBodies...))...)
+
+	// data: the log message and the response
+	module = append(module, encodeSection(11, encodeVector(
+		encodeDataSegment(logMessageOffset, []byte(testLogMessage)),
+		encodeDataSegment(responseOffset, []byte(testResponse)),
+	))...)
+
+	return module
+}
+
+func encodeSection(id byte, contents []byte) []byte {
+	return concatBytes([]byte{id}, encodeUnsigned(uint32(len(contents))), contents)
+}
+
+func encodeVector(items ...[]byte) []byte {
+	return concatBytes(append([][]byte{encodeUnsigned(uint32(len(items)))}, items...)...)
+}
+
+func encodeName(name string) []byte {
+	return concatBytes(encodeUnsigned(uint32(len(name))), []byte(name))
+}
+
+func encodeFunctionBody(instructions []byte) []byte {
+	body := concatBytes([]byte{0x00}, instructions, []byte{0x0b})
+	return concatBytes(encodeUnsigned(uint32(len(body))), body)
+}
+
+func encodeDataSegment(offset int32, data []byte) []byte {
+	return concatBytes([]byte{0x00}, i32Const(offset), []byte{0x0b}, encodeUnsigned(uint32(len(data))), data)
+}
+
+func i32Const(value int32) []byte {
+	return concatBytes([]byte{0x41}, encodeSigned(int64(value)))
+}
+
+func i64Const(value int64) []byte {
+	return concatBytes([]byte{0x42}, encodeSigned(value))
+}
+
+func encodeUnsigned(value uint32) []byte {
+	var encoded []byte
+
+	for {
+		currentByte := byte(value & 0x7f)
+		value >>= 7
+
+		if value == 0 {
+			return append(encoded, currentByte)
+		}
+
+		encoded = append(encoded, currentByte|0x80)
+	}
+}
+
+func encodeSigned(value int64) []byte {
+	var encoded []byte
+
+	for {
+		currentByte := byte(value & 0x7f)
+		value >>= 7
+
+		if (value == 0 && currentByte&0x40 == 0) || (value == -1 && currentByte&0x40 != 0) {
+			return append(encoded, currentByte)
+		}
+
+		encoded = append(encoded, currentByte|0x80)
+	}
+}
+
+func concatBytes(slices ...[]byte) []byte {
+	var concatenated []byte
+	for _, slice := range slices {
+		concatenated = append(concatenated, slice...)
+	}
+
+	return concatenated
+}
@@ -0,0 +1,458 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common/status"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/encoder"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/result"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// names of the functions and memory the module exports (or imports, for the host module)
+const (
+	hostModuleName       = "nuclio"
+	hostLogFunctionName  = "log"
+	allocFunctionName    = "nuclio_alloc"
+	freeFunctionName     = "nuclio_free"
+	initFunctionName     = "nuclio_init"
+	shutdownFunctionName = "nuclio_shutdown"
+	memoryName           = "memory"
+	reactorStartFunction = "_initialize"
+)
+
+// log levels, as passed by the module to the host log function
+const (
+	logLevelError uint32 = iota
+	logLevelWarn
+	logLevelInfo
+	logLevelDebug
+)
+
+// compiled modules are shared between all workers, so that only the first worker pays for compilation
+var compilationCache = wazero.NewCompilationCache()
+
+type functionLoggerKey struct{}
+
+type wasm struct {
+	*runtime.AbstractRuntime
+	configuration  *Configuration
+	ctx            context.Context
+	wasmRuntime    wazero.Runtime
+	compiledModule wazero.CompiledModule
+
+	// the current instance of the module, replaced whenever it traps or is interrupted
+	module         api.Module
+	memory         api.Memory
+	allocFunction  api.Function
+	freeFunction   api.Function
+	handleFunction api.Function
+	instances      int
+
+	// module instances can't be called concurrently, so events are serialized with the module's shutdown
+	// function. It's called once per drain or termination, until the runtime is told to continue
+	moduleLock     sync.Mutex
+	shutdownCalled bool
+}
+
+// NewRuntime returns a new wasm runtime
+func NewRuntime(parentLogger logger.Logger, configuration *Configuration) (runtime.Runtime, error) {
+	runtimeLogger := parentLogger.GetChild("wasm")
+
+	// create base
+	abstractRuntime, err := runtime.NewAbstractRuntime(runtimeLogger, configuration.Configuration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create abstract runtime")
+	}
+
+	newWasmRuntime := &wasm{
+		AbstractRuntime: abstractRuntime,
+		configuration:   configuration,
+		ctx:             context.Background(),
+	}
+
+	if err := newWasmRuntime.createWasmRuntime(); err != nil {
+		return nil, errors.Wrap(err, "Failed to create wasm runtime")
+	}
+
+	if err := newWasmRuntime.instantiate(); err != nil {
+		newWasmRuntime.wasmRuntime.Close(newWasmRuntime.ctx) // nolint: errcheck
+		return nil, errors.Wrap(err, "Failed to instantiate module")
+	}
+
+	newWasmRuntime.SetStatus(status.Ready)
+
+	return newWasmRuntime, nil
+}
+
+func (w *wasm) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	var encodedEvent bytes.Buffer
+
+	if err := encoder.NewEventJSONEncoder(w.Logger, &encodedEvent).Encode(event); err != nil {
+		return nil, errors.Wrap(err, "Failed to encode event")
+	}
+
+	w.Logger.DebugWith("Invoking module",
+		"name", w.configuration.Meta.Name,
+		"eventID", event.GetID(),
+		"bodyLen", len(event.GetBody()),
+		"maxExecutionTime", w.configuration.maxExecutionTime)
+
+	if functionLogger == nil {
+		functionLogger = w.FunctionLogger
+	}
+
+	w.moduleLock.Lock()
+
+	// the module is closed by wazero if it runs past the deadline
+	ctx, cancel := context.WithTimeout(context.WithValue(w.ctx, functionLoggerKey{}, functionLogger),
+		w.configuration.maxExecutionTime)
+	defer cancel()
+
+	startTime := time.Now()
+
+	encodedResponse, err := w.invoke(ctx, encodedEvent.Bytes())
+	if err != nil {
+
+		// the instance is either closed or in an unknown state, start over with a fresh one
+		w.reinstantiate()
+	}
+	w.moduleLock.Unlock()
+
+	if err != nil {
+		if stderrors.Is(err, context.DeadlineExceeded) {
+			return nil, nuclio.NewErrRequestTimeout("Module exceeded max execution time")
+		}
+
+		return nil, errors.Wrap(err, "Failed to invoke module")
+	}
+
+	callDuration := time.Since(startTime)
+
+	// add duration to sum
+	atomic.AddUint64(&w.Statistics.DurationMilliSecondsSum, uint64(callDuration.Milliseconds()))
+	atomic.AddUint64(&w.Statistics.DurationMilliSecondsCount, 1)
+
+	processingResult := result.NewBatchedResults()
+	processingResult.UnmarshalResponseData(w.Logger, encodedResponse)
+	if processingResult.Err != nil {
+		return nil, errors.Wrap(processingResult.Err, "Failed to decode module response")
+	}
+
+	if len(processingResult.Results) == 0 {
+		return nil, errors.New("Module returned no response")
+	}
+
+	return nuclio.Response{
+		Body:        processingResult.Results[0].DecodedBody,
+		ContentType: processingResult.Results[0].ContentType,
+		Headers:     processingResult.Results[0].Headers,
+		StatusCode:  processingResult.Results[0].StatusCode,
+	}, processingResult.Results[0].Err
+}
+
+func (w *wasm) ProcessBatch(batch []nuclio.Event, functionLogger logger.Logger) ([]*runtime.ResponseWithErrors, error) {
+	return nil, nuclio.ErrNotImplemented
+}
+
+func (w *wasm) Start() error {
+	w.SetStatus(status.Ready)
+	return nil
+}
+
+func (w *wasm) Stop() error {
+	if err := w.wasmRuntime.Close(w.ctx); err != nil {
+		return errors.Wrap(err, "Failed to close wasm runtime")
+	}
+
+	return w.AbstractRuntime.Stop()
+}
+
+func (w *wasm) Restart() error {
+	w.Logger.Warn("Restarting")
+
+	w.moduleLock.Lock()
+	defer w.moduleLock.Unlock()
+
+	if err := w.instantiate(); err != nil {
+		w.SetStatus(status.Error)
+		return errors.Wrap(err, "Failed to instantiate module")
+	}
+
+	w.SetStatus(status.Ready)
+	return nil
+}
+
+func (w *wasm) SupportsRestart() bool {
+	return true
+}
+
+// Drain calls the module's shutdown function, if it exports one, once in-flight events are done
+func (w *wasm) Drain() error {
+	return w.shutdownModule()
+}
+
+// Terminate calls the module's shutdown function, if it exports one, once in-flight events are done
+func (w *wasm) Terminate() error {
+	return w.shutdownModule()
+}
+
+// Continue allows the module's shutdown function to be called on the next drain
+func (w *wasm) Continue() error {
+	w.moduleLock.Lock()
+	defer w.moduleLock.Unlock()
+
+	w.shutdownCalled = false
+
+	return nil
+}
+
+func (w *wasm) shutdownModule() error {
+	w.moduleLock.Lock()
+	defer w.moduleLock.Unlock()
+
+	if w.module == nil || w.shutdownCalled {
+		return nil
+	}
+
+	shutdownFunction := w.module.ExportedFunction(shutdownFunctionName)
+	if shutdownFunction == nil {
+		return nil
+	}
+
+	w.shutdownCalled = true
+
+	// like an invocation, the module is closed by wazero if it runs past the worker termination timeout
+	ctx := context.WithValue(w.ctx, functionLoggerKey{}, w.FunctionLogger)
+	if w.configuration.WorkerTerminationTimeout != 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, w.configuration.WorkerTerminationTimeout)
+		defer cancel()
+	}
+
+	if _, err := shutdownFunction.Call(ctx); err != nil {
+		w.reinstantiate()
+		return errors.Wrap(err, "Failed to call module shutdown function")
+	}
+
+	return nil
+}
+
+// reinstantiate replaces a module instance that's closed or in an unknown state. Must be called while holding
+// the module lock
+func (w *wasm) reinstantiate() {
+	if err := w.instantiate(); err != nil {
+		w.Logger.ErrorWith("Failed to re-instantiate module", "err", err.Error())
+		w.SetStatus(status.Error)
+	}
+}
+
+func (w *wasm) createWasmRuntime() error {
+	moduleContents, err := os.ReadFile(w.configuration.modulePath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read module %s", w.configuration.modulePath)
+	}
+
+	runtimeConfig := wazero.NewRuntimeConfig().
+		WithCompilationCache(compilationCache).
+		WithMemoryLimitPages(w.configuration.getMemoryLimitPages()).
+		WithCloseOnContextDone(true)
+
+	w.wasmRuntime = wazero.NewRuntimeWithConfig(w.ctx, runtimeConfig)
+
+	if _, err := wasi_snapshot_preview1.Instantiate(w.ctx, w.wasmRuntime); err != nil {
+		w.wasmRuntime.Close(w.ctx) // nolint: errcheck
+		return errors.Wrap(err, "Failed to instantiate WASI")
+	}
+
+	if _, err := w.wasmRuntime.NewHostModuleBuilder(hostModuleName).
+		NewFunctionBuilder().
+		WithFunc(w.log).
+		WithParameterNames("level", "ptr", "len").
+		Export(hostLogFunctionName).
+		Instantiate(w.ctx); err != nil {
+		w.wasmRuntime.Close(w.ctx) // nolint: errcheck
+		return errors.Wrap(err, "Failed to instantiate host module")
+	}
+
+	// fails if the module's minimum memory exceeds the limit
+	w.compiledModule, err = w.wasmRuntime.CompileModule(w.ctx, moduleContents)
+	if err != nil {
+		w.wasmRuntime.Close(w.ctx) // nolint: errcheck
+		return errors.Wrapf(err, "Failed to compile module %s", w.configuration.modulePath)
+	}
+
+	return nil
+}
+
+// instantiate replaces the current module instance (if any) with a fresh one
+func (w *wasm) instantiate() error {
+	if w.module != nil {
+		w.module.Close(w.ctx) // nolint: errcheck
+		w.module = nil
+	}
+
+	w.instances++
+
+	moduleConfig := wazero.NewModuleConfig().
+		WithName(fmt.Sprintf("%s-%d", w.configuration.Meta.Name, w.instances)).
+		WithStartFunctions(reactorStartFunction).
+		WithStdout(os.Stdout).
+		WithStderr(os.Stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithEnv("NUCLIO_FUNCTION_NAME", w.configuration.Meta.Name).
+		WithEnv("NUCLIO_FUNCTION_VERSION", fmt.Sprintf("%d", w.configuration.Spec.Version))
+
+	for _, configEnv := range w.configuration.Spec.Env {
+		moduleConfig = moduleConfig.WithEnv(configEnv.Name, configEnv.Value)
+	}
+
+	// the module's initialization is bound by the same limits as invocations
+	ctx, cancel := context.WithTimeout(context.WithValue(w.ctx, functionLoggerKey{}, w.FunctionLogger),
+		w.configuration.maxExecutionTime)
+	defer cancel()
+
+	module, err := w.wasmRuntime.InstantiateModule(ctx, w.compiledModule, moduleConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to instantiate module")
+	}
+
+	w.memory = module.ExportedMemory(memoryName)
+	w.allocFunction = module.ExportedFunction(allocFunctionName)
+	w.freeFunction = module.ExportedFunction(freeFunctionName)
+	w.handleFunction = module.ExportedFunction(w.configuration.entrypoint)
+
+	for exportName, exported := range map[string]bool{
+		memoryName:                 w.memory != nil,
+		allocFunctionName:          w.allocFunction != nil,
+		w.configuration.entrypoint: w.handleFunction != nil,
+	} {
+		if !exported {
+			module.Close(w.ctx) // nolint: errcheck
+			return errors.Errorf("Module does not export %s", exportName)
+		}
+	}
+
+	if initFunction := module.ExportedFunction(initFunctionName); initFunction != nil {
+		if _, err := initFunction.Call(ctx); err != nil {
+			module.Close(w.ctx) // nolint: errcheck
+			return errors.Wrap(err, "Failed to initialize module")
+		}
+	}
+
+	w.module = module
+
+	return nil
+}
+
+// invoke writes the encoded event to the module's memory, calls the entrypoint and reads back the encoded response
+func (w *wasm) invoke(ctx context.Context, encodedEvent []byte) ([]byte, error) {
+	if w.module == nil {
+		return nil, errors.New("Module is not instantiated")
+	}
+
+	eventLength := uint64(len(encodedEvent))
+
+	allocResults, err := w.allocFunction.Call(ctx, eventLength)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to allocate event memory")
+	}
+
+	eventPointer := allocResults[0]
+	if !w.memory.Write(uint32(eventPointer), encodedEvent) {
+		return nil, errors.Errorf("Allocated event memory is out of range (pointer: %d, length: %d)",
+			eventPointer,
+			eventLength)
+	}
+
+	handleResults, err := w.handleFunction.Call(ctx, eventPointer, eventLength)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to call entrypoint")
+	}
+
+	// the response pointer and length are packed into the high and low halves of the result
+	responsePointer := uint32(handleResults[0] >> 32)
+	responseLength := uint32(handleResults[0])
+
+	responseView, inRange := w.memory.Read(responsePointer, responseLength)
+	if !inRange {
+		return nil, errors.Errorf("Response memory is out of range (pointer: %d, length: %d)",
+			responsePointer,
+			responseLength)
+	}
+
+	// copy the response, the module may reuse its memory
+	encodedResponse := make([]byte, len(responseView))
+	copy(encodedResponse, responseView)
+
+	if w.freeFunction != nil {
+		if _, err := w.freeFunction.Call(ctx, eventPointer, eventLength); err != nil {
+			return nil, errors.Wrap(err, "Failed to free event memory")
+		}
+
+		if _, err := w.freeFunction.Call(ctx, uint64(responsePointer), uint64(responseLength)); err != nil {
+			return nil, errors.Wrap(err, "Failed to free response memory")
+		}
+	}
+
+	return encodedResponse, nil
+}
+
+// log is exported to the module as nuclio.log(level, ptr, len)
+func (w *wasm) log(ctx context.Context, module api.Module, level uint32, pointer uint32, length uint32) {
+	message, inRange := module.Memory().Read(pointer, length)
+	if !inRange {
+		w.Logger.WarnWith("Module log message is out of range", "pointer", pointer, "length", length)
+		return
+	}
+
+	functionLogger, found := ctx.Value(functionLoggerKey{}).(logger.Logger)
+	if !found || functionLogger == nil {
+		functionLogger = w.Logger
+	}
+
+	switch level {
+	case logLevelError:
+		functionLogger.Error(string(message))
+	case logLevelWarn:
+		functionLogger.Warn(string(message))
+	case logLevelInfo:
+		functionLogger.Info(string(message))
+	case logLevelDebug:
+		functionLogger.Debug(string(message))
+	default:
+		functionLogger.InfoWith(string(message), "level", level)
+	}
+}
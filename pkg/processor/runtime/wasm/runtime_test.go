@@ -0,0 +1,270 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common/status"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+const testResponse = `{"status_code":201,"content_type":"text/plain","body":"hello","body_encoding":"text","headers":{"x-test":"value"}}`
+
+// nuclio.TriggerInfoProvider interface
+type TestTriggerInfoProvider struct{}
+
+func (ti *TestTriggerInfoProvider) GetClass() string { return "test class" }
+func (ti *TestTriggerInfoProvider) GetKind() string  { return "test kind" }
+func (ti *TestTriggerInfoProvider) GetName() string  { return "test name" }
+
+type RuntimeSuite struct {
+	suite.Suite
+	logger     logger.Logger
+	handlerDir string
+}
+
+func (suite *RuntimeSuite) SetupTest() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+	suite.handlerDir = suite.T().TempDir()
+	suite.T().Setenv("NUCLIO_WASM_HANDLER_DIR", suite.handlerDir)
+}
+
+func (suite *RuntimeSuite) TestProcessEvent() {
+	suite.writeModule("handler", newTestModule(1, suite.constantResponseBody(), true))
+
+	runtimeInstance := suite.createRuntime("handler", nil)
+	defer runtimeInstance.Stop() // nolint: errcheck
+
+	for iteration := 0; iteration < 3; iteration++ {
+		response, err := runtimeInstance.ProcessEvent(suite.createEvent(), suite.logger)
+		suite.Require().NoError(err)
+
+		nuclioResponse := response.(nuclio.Response)
+		suite.Require().Equal(http.StatusCreated, nuclioResponse.StatusCode)
+		suite.Require().Equal("text/plain", nuclioResponse.ContentType)
+		suite.Require().Equal("hello", string(nuclioResponse.Body))
+		suite.Require().Equal("value", nuclioResponse.Headers["x-test"])
+	}
+
+	suite.Require().Equal(uint64(3), runtimeInstance.GetStatistics().DurationMilliSecondsCount)
+}
+
+func (suite *RuntimeSuite) TestMaxExecutionTime() {
+	suite.writeModule("handler", newTestModule(1, infiniteLoopBody, true))
+
+	runtimeInstance := suite.createRuntime("handler", map[string]interface{}{
+		"maxExecutionTime": "100ms",
+	})
+	defer runtimeInstance.Stop() // nolint: errcheck
+
+	_, err := runtimeInstance.ProcessEvent(suite.createEvent(), suite.logger)
+	suite.Require().Error(err)
+
+	// error should be with status, to inform user his request has timed out
+	responseError, isErrorWithStatusCode := err.(*nuclio.ErrorWithStatusCode)
+	suite.Require().True(isErrorWithStatusCode)
+	suite.Require().Equal(http.StatusRequestTimeout, responseError.StatusCode())
+
+	// the interrupted instance was replaced
+	suite.Require().Equal(status.Ready, runtimeInstance.GetStatus())
+}
+
+func (suite *RuntimeSuite) TestShutdown() {
+
+	// the shutdown function never returns, so it's interrupted
+	suite.writeModule("handler", newTestModuleWithShutdown(1, suite.constantResponseBody(), true, infiniteLoopBody))
+
+	configuration, err := NewConfiguration(suite.createRuntimeConfiguration("handler", nil))
+	suite.Require().NoError(err)
+	configuration.WorkerTerminationTimeout = 100 * time.Millisecond
+
+	runtimeInstance, err := NewRuntime(suite.logger, configuration)
+	suite.Require().NoError(err)
+	defer runtimeInstance.Stop() // nolint: errcheck
+
+	suite.Require().Error(runtimeInstance.Drain())
+
+	// it's called once per drain, and the interrupted instance was replaced
+	suite.Require().NoError(runtimeInstance.Terminate())
+	suite.Require().Equal(status.Ready, runtimeInstance.GetStatus())
+
+	_, err = runtimeInstance.ProcessEvent(suite.createEvent(), suite.logger)
+	suite.Require().NoError(err)
+
+	// and again once continued
+	suite.Require().NoError(runtimeInstance.Continue())
+	suite.Require().Error(runtimeInstance.Drain())
+}
+
+func (suite *RuntimeSuite) TestMaxMemory() {
+
+	// the module requires 2MB of memory upfront
+	suite.writeModule("handler", newTestModule(32, suite.constantResponseBody(), true))
+
+	configuration, err := NewConfiguration(suite.createRuntimeConfiguration("handler", map[string]interface{}{
+		"maxMemoryMB": 1,
+	}))
+	suite.Require().NoError(err)
+
+	_, err = NewRuntime(suite.logger, configuration)
+	suite.Require().Error(err)
+}
+
+func (suite *RuntimeSuite) TestMissingExports() {
+	suite.writeModule("handler", newTestModule(1, suite.constantResponseBody(), false))
+
+	configuration, err := NewConfiguration(suite.createRuntimeConfiguration("handler", nil))
+	suite.Require().NoError(err)
+
+	_, err = NewRuntime(suite.logger, configuration)
+	suite.Require().Error(err)
+	suite.Require().Contains(errors.GetErrorStackString(err, 10), allocFunctionName)
+}
+
+func (suite *RuntimeSuite) TestResolveConfiguration() {
+	for _, testCase := range []struct {
+		name               string
+		handler            string
+		attributes         map[string]interface{}
+		expectedModulePath string
+		expectedEntrypoint string
+		expectedPages      uint32
+		expectError        bool
+	}{
+		{
+			name:               "defaults",
+			handler:            "handler",
+			expectedModulePath: filepath.Join(suite.handlerDir, "handler.wasm"),
+			expectedEntrypoint: DefaultEntrypoint,
+			expectedPages:      DefaultMaxMemoryMB * pagesPerMB,
+		},
+		{
+			name:               "moduleAndEntrypoint",
+			handler:            "main.wasm:process",
+			attributes:         map[string]interface{}{"maxMemoryMB": 64},
+			expectedModulePath: filepath.Join(suite.handlerDir, "main.wasm"),
+			expectedEntrypoint: "process",
+			expectedPages:      64 * pagesPerMB,
+		},
+		{
+			name:        "invalidMaxMemory",
+			handler:     "handler",
+			attributes:  map[string]interface{}{"maxMemoryMB": 8192},
+			expectError: true,
+		},
+		{
+			name:        "invalidMaxExecutionTime",
+			handler:     "handler",
+			attributes:  map[string]interface{}{"maxExecutionTime": "forever"},
+			expectError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			configuration, err := NewConfiguration(suite.createRuntimeConfiguration(testCase.handler,
+				testCase.attributes))
+			if testCase.expectError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedModulePath, configuration.modulePath)
+			suite.Require().Equal(testCase.expectedEntrypoint, configuration.entrypoint)
+			suite.Require().Equal(testCase.expectedPages, configuration.getMemoryLimitPages())
+			suite.Require().Equal(DefaultMaxExecutionTime, configuration.maxExecutionTime)
+		})
+	}
+}
+
+func (suite *RuntimeSuite) createRuntime(handler string, attributes map[string]interface{}) runtime.Runtime {
+	configuration, err := NewConfiguration(suite.createRuntimeConfiguration(handler, attributes))
+	suite.Require().NoError(err)
+
+	runtimeInstance, err := NewRuntime(suite.logger, configuration)
+	suite.Require().NoError(err)
+
+	return runtimeInstance
+}
+
+func (suite *RuntimeSuite) createRuntimeConfiguration(handler string,
+	attributes map[string]interface{}) *runtime.Configuration {
+	return &runtime.Configuration{
+		FunctionLogger: suite.logger,
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{
+				Meta: functionconfig.Meta{
+					Name: "test",
+				},
+				Spec: functionconfig.Spec{
+					Handler:           handler,
+					RuntimeAttributes: attributes,
+				},
+			},
+			PlatformConfig: &platformconfig.Config{},
+		},
+	}
+}
+
+func (suite *RuntimeSuite) createEvent() nuclio.Event {
+	eventInstance := &nuclio.MemoryEvent{
+		Body: []byte("request"),
+	}
+	eventInstance.SetTriggerInfoProvider(&TestTriggerInfoProvider{})
+
+	return eventInstance
+}
+
+func (suite *RuntimeSuite) writeModule(name string, contents []byte) {
+	err := os.WriteFile(filepath.Join(suite.handlerDir, name+".wasm"), contents, 0644)
+	suite.Require().NoError(err)
+}
+
+// constantResponseBody logs a message and returns the response placed at responseOffset by the data segment
+func (suite *RuntimeSuite) constantResponseBody() []byte {
+	var body []byte
+
+	// nuclio.log(info, logMessageOffset, len(logMessage))
+	body = append(body, i32Const(int32(logLevelInfo))...)
+	body = append(body, i32Const(logMessageOffset)...)
+	body = append(body, i32Const(int32(len(testLogMessage)))...)
+	body = append(body, 0x10, 0x00) // call 0
+
+	// return responseOffset << 32 | len(testResponse)
+	body = append(body, i64Const(int64(responseOffset)<<32|int64(len(testResponse)))...)
+
+	return body
+}
+
+func TestRuntimeSuite(t *testing.T) {
+	suite.Run(t, new(RuntimeSuite))
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+const (
+	DefaultMaxMemoryMB      = 128
+	DefaultMaxExecutionTime = 30 * time.Second
+	DefaultEntrypoint       = "handler"
+
+	// a wasm page is 64KiB
+	pagesPerMB = 16
+
+	// wasm32 memories can't grow beyond 4GiB
+	maxMemoryMB = 4096
+)
+
+type Configuration struct {
+	*runtime.Configuration
+
+	// the maximum linear memory a worker's module instance may use
+	MaxMemoryMB int
+
+	// the maximum time a single invocation may run before it is interrupted
+	MaxExecutionTime string
+
+	// resolved
+	maxExecutionTime time.Duration
+	modulePath       string
+	entrypoint       string
+}
+
+func NewConfiguration(runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
+	newConfiguration := Configuration{
+		Configuration: runtimeConfiguration,
+	}
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Spec.RuntimeAttributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if err := newConfiguration.resolve(); err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve configuration")
+	}
+
+	return &newConfiguration, nil
+}
+
+func (c *Configuration) resolve() error {
+	var err error
+
+	if c.MaxMemoryMB == 0 {
+		c.MaxMemoryMB = DefaultMaxMemoryMB
+	}
+
+	if c.MaxMemoryMB < 0 || c.MaxMemoryMB > maxMemoryMB {
+		return errors.Errorf("Max memory must be between 1 and %d MB, got %d", maxMemoryMB, c.MaxMemoryMB)
+	}
+
+	// default to the event timeout, so that the module is interrupted when the event is considered timed out
+	switch {
+	case c.MaxExecutionTime != "":
+		c.maxExecutionTime, err = time.ParseDuration(c.MaxExecutionTime)
+		if err != nil {
+			return errors.Wrap(err, "Failed to parse max execution time")
+		}
+	case c.Spec.EventTimeout != "":
+		c.maxExecutionTime, err = c.Spec.GetEventTimeout()
+		if err != nil {
+			return errors.Wrap(err, "Failed to parse event timeout")
+		}
+	default:
+		c.maxExecutionTime = DefaultMaxExecutionTime
+	}
+
+	if c.maxExecutionTime <= 0 {
+		return errors.Errorf("Max execution time must be positive, got %s", c.maxExecutionTime)
+	}
+
+	moduleName, entrypoint, err := functionconfig.ParseHandler(c.Spec.Handler)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse handler")
+	}
+
+	// a handler with one segment names the module, which exports the default entrypoint
+	if moduleName == "" {
+		moduleName = entrypoint
+		entrypoint = DefaultEntrypoint
+	}
+
+	if moduleName == "" {
+		return errors.New("Handler must name a wasm module")
+	}
+
+	if !strings.HasSuffix(moduleName, ".wasm") {
+		moduleName += ".wasm"
+	}
+
+	// if there's a directory passed as an environment telling us where to look for the module, use it. otherwise
+	// use /opt/nuclio
+	wasmHandlerDir := os.Getenv("NUCLIO_WASM_HANDLER_DIR")
+	if wasmHandlerDir == "" {
+		wasmHandlerDir = "/opt/nuclio"
+	}
+
+	c.modulePath = path.Join(wasmHandlerDir, moduleName)
+	c.entrypoint = entrypoint
+
+	return nil
+}
+
+func (c *Configuration) getMemoryLimitPages() uint32 {
+	return uint32(c.MaxMemoryMB * pagesPerMB)
+}
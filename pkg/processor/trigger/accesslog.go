@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// AccessLogger writes an entry per handled event, either through the logger sinks or to a file
+type AccessLogger struct {
+	logger     logger.Logger
+	sampleRate float64
+	writer     io.WriteCloser
+	writerLock sync.Mutex
+	closed     bool
+}
+
+// NewAccessLogger creates an access logger. Returns nil if access logging is not enabled
+func NewAccessLogger(parentLogger logger.Logger, configuration *functionconfig.AccessLog) (*AccessLogger, error) {
+	if configuration == nil || !configuration.Enabled {
+		return nil, nil
+	}
+
+	if configuration.SampleRate < 0 || configuration.SampleRate > 1 {
+		return nil, errors.Errorf("Access log sample rate must be between 0 and 1, got %f", configuration.SampleRate)
+	}
+
+	newAccessLogger := &AccessLogger{
+		logger:     parentLogger.GetChild("access"),
+		sampleRate: configuration.SampleRate,
+	}
+
+	// default to writing all entries
+	if newAccessLogger.sampleRate == 0 {
+		newAccessLogger.sampleRate = 1
+	}
+
+	if configuration.Path != "" {
+		accessLogFile, err := os.OpenFile(configuration.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to open access log file %s", configuration.Path)
+		}
+
+		newAccessLogger.writer = accessLogFile
+	}
+
+	return newAccessLogger, nil
+}
+
+// Sampled returns whether the next entry should be written. Callers check this before collecting
+// the entry's fields, so that unsampled events cost nothing
+func (al *AccessLogger) Sampled() bool {
+	return al.sampleRate >= 1 || rand.Float64() < al.sampleRate // nolint: gosec
+}
+
+// Write writes an entry, given as alternating keys and values
+func (al *AccessLogger) Write(vars ...interface{}) {
+	if al.writer == nil {
+		al.logger.InfoWith("Access", vars...)
+		return
+	}
+
+	entry := map[string]interface{}{
+		"time": time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	for varIndex := 0; varIndex+1 < len(vars); varIndex += 2 {
+		value := vars[varIndex+1]
+		if valueError, isError := value.(error); isError && valueError != nil {
+			value = valueError.Error()
+		}
+
+		entry[fmt.Sprint(vars[varIndex])] = value
+	}
+
+	encodedEntry, err := json.Marshal(entry)
+	if err != nil {
+		al.logger.WarnWith("Failed to encode access log entry", "err", err.Error())
+		return
+	}
+
+	al.writerLock.Lock()
+	defer al.writerLock.Unlock()
+
+	if al.closed {
+		return
+	}
+
+	if _, err := al.writer.Write(append(encodedEntry, '\n')); err != nil {
+		al.logger.WarnWith("Failed to write access log entry", "err", err.Error())
+	}
+}
+
+// Close closes the access log file, if entries are written to one. Entries written afterwards are dropped
+func (al *AccessLogger) Close() error {
+	if al.writer == nil {
+		return nil
+	}
+
+	al.writerLock.Lock()
+	defer al.writerLock.Unlock()
+
+	if al.closed {
+		return nil
+	}
+
+	al.closed = true
+
+	return al.writer.Close()
+}
@@ -0,0 +1,196 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type streamEvent struct {
+	nuclio.AbstractEvent
+	offset int
+}
+
+func (se *streamEvent) GetTopic() string {
+	return "my-topic"
+}
+
+func (se *streamEvent) GetShardID() int {
+	return 3
+}
+
+func (se *streamEvent) GetOffset() int {
+	return se.offset
+}
+
+func (se *streamEvent) GetBody() []byte {
+	return []byte("body")
+}
+
+// streamRuntime fails events whose offset is odd
+type streamRuntime struct {
+	runtime.Runtime
+}
+
+func (sr *streamRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	if event.GetOffset()%2 == 1 {
+		return nil, errors.New("odd offset")
+	}
+
+	return nil, nil
+}
+
+func (sr *streamRuntime) ProcessBatch(batch []nuclio.Event,
+	functionLogger logger.Logger) ([]*runtime.ResponseWithErrors, error) {
+	var responses []*runtime.ResponseWithErrors
+
+	for _, event := range batch {
+		_, processError := sr.ProcessEvent(event, functionLogger)
+		responses = append(responses, &runtime.ResponseWithErrors{
+			EventId:      string(event.GetID()),
+			ProcessError: processError,
+		})
+	}
+
+	return responses, nil
+}
+
+type AccessLogTestSuite struct {
+	suite.Suite
+	logger        logger.Logger
+	accessLogPath string
+	trigger       *AbstractTrigger
+	worker        *worker.Worker
+}
+
+func (suite *AccessLogTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+	suite.accessLogPath = filepath.Join(suite.T().TempDir(), "access.log")
+
+	suite.trigger = &AbstractTrigger{
+		Logger: suite.logger,
+		Name:   "my-stream",
+		Kind:   "kafka-cluster",
+		Class:  "async",
+	}
+
+	suite.trigger.AccessLogger, err = NewAccessLogger(suite.logger, &functionconfig.AccessLog{
+		Enabled: true,
+		Path:    suite.accessLogPath,
+	})
+	suite.Require().NoError(err)
+
+	suite.worker, err = worker.NewWorker(suite.logger, 2, &streamRuntime{})
+	suite.Require().NoError(err)
+}
+
+func (suite *AccessLogTestSuite) TestStreamEvents() {
+	for _, offset := range []int{10, 11} {
+		suite.trigger.SubmitEventToWorker(nil, suite.worker, &streamEvent{offset: offset}) // nolint: errcheck
+	}
+
+	entries := suite.readEntries()
+	suite.Require().Len(entries, 2)
+
+	for entryIndex, expectedOutcome := range []string{"success", "failure"} {
+		suite.Require().Equal("my-stream", entries[entryIndex]["trigger"])
+		suite.Require().Equal("my-topic", entries[entryIndex]["topic"])
+		suite.Require().Equal(float64(3), entries[entryIndex]["partition"])
+		suite.Require().Equal(float64(10+entryIndex), entries[entryIndex]["offset"])
+		suite.Require().Equal(float64(2), entries[entryIndex]["workerIndex"])
+		suite.Require().Equal(expectedOutcome, entries[entryIndex]["outcome"])
+	}
+
+	suite.Require().Equal("odd offset", entries[1]["error"])
+}
+
+func (suite *AccessLogTestSuite) TestBatchedStreamEvents() {
+	var batch []nuclio.Event
+	responseChans := map[string]*common.ChannelWithRecover{}
+
+	for _, eventID := range []string{"first", "second"} {
+		event := &streamEvent{offset: len(batch) + 20}
+		event.SetID(nuclio.ID(eventID))
+
+		batch = append(batch, event)
+		responseChans[eventID] = &common.ChannelWithRecover{
+			Context: context.Background(),
+			Channel: make(chan interface{}, 1),
+		}
+	}
+
+	suite.trigger.SubmitBatchAndSendResponses(batch, responseChans, suite.worker)
+
+	entries := suite.readEntries()
+	suite.Require().Len(entries, 2)
+
+	for entryIndex, expectedOutcome := range []string{"success", "failure"} {
+		suite.Require().Equal(string(batch[entryIndex].GetID()), entries[entryIndex]["eventID"])
+		suite.Require().Equal(float64(20+entryIndex), entries[entryIndex]["offset"])
+		suite.Require().Equal(float64(2), entries[entryIndex]["batchSize"])
+		suite.Require().Equal(expectedOutcome, entries[entryIndex]["outcome"])
+	}
+}
+
+func (suite *AccessLogTestSuite) TestClose() {
+	suite.trigger.SubmitEventToWorker(nil, suite.worker, &streamEvent{}) // nolint: errcheck
+	suite.Require().NoError(suite.trigger.AccessLogger.Close())
+
+	// entries written after the file is closed are dropped
+	suite.trigger.SubmitEventToWorker(nil, suite.worker, &streamEvent{}) // nolint: errcheck
+	suite.Require().Len(suite.readEntries(), 1)
+
+	suite.Require().NoError(suite.trigger.AccessLogger.Close())
+}
+
+func (suite *AccessLogTestSuite) readEntries() []map[string]interface{} {
+	accessLogContents, err := os.ReadFile(suite.accessLogPath)
+	suite.Require().NoError(err)
+
+	var entries []map[string]interface{}
+	for _, accessLogLine := range strings.Split(strings.TrimSpace(string(accessLogContents)), "\n") {
+		entry := map[string]interface{}{}
+		suite.Require().NoError(json.Unmarshal([]byte(accessLogLine), &entry))
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestAccessLogTestSuite(t *testing.T) {
+	suite.Run(t, new(AccessLogTestSuite))
+}
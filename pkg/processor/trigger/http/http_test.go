@@ -20,8 +20,11 @@ package http
 
 import (
 	"context"
+	"encoding/json"
 	"net"
 	nethttp "net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -53,7 +56,8 @@ func (suite *TestSuite) SetupSuite() {
 		AbstractTrigger: trigger.AbstractTrigger{
 			Logger: suite.logger,
 		},
-		configuration: &Configuration{},
+		configuration:      &Configuration{},
+		internalHealthPath: []byte(InternalHealthPath),
 	}
 	suite.fastDummyHTTPServer = fasthttputil.NewInmemoryListener()
 	suite.serveDummyHTTPServer(suite.trigger.onRequestFromFastHTTP())
@@ -213,6 +217,54 @@ func (suite *TestSuite) TestInternalHealthiness() {
 	}
 }
 
+func (suite *TestSuite) TestAccessLog() {
+	accessLogPath := filepath.Join(suite.T().TempDir(), "access.log")
+
+	accessLogger, err := trigger.NewAccessLogger(suite.logger, &functionconfig.AccessLog{
+		Enabled: true,
+		Path:    accessLogPath,
+	})
+	suite.Require().NoError(err)
+
+	suite.trigger.AccessLogger = accessLogger
+	suite.trigger.Name = "my-http"
+	defer func() {
+		suite.trigger.AccessLogger = nil
+	}()
+
+	// not ready, the request is rejected before reaching a worker
+	suite.trigger.status = status.Initializing
+
+	request, err := nethttp.NewRequest(nethttp.MethodPost, "http://foo.bar/some/path", strings.NewReader("body"))
+	suite.Require().NoError(err)
+	request.Header.Set(headers.RequestID, "my-request-id")
+
+	response, err := suite.getClient().Do(request)
+	suite.Require().NoError(err)
+	suite.Require().Equal(nethttp.StatusServiceUnavailable, response.StatusCode)
+
+	// internal health checks are not logged
+	suite.trigger.status = status.Ready
+	_, err = suite.getClient().Get("http://foo.bar" + string(suite.trigger.internalHealthPath))
+	suite.Require().NoError(err)
+
+	accessLogContents, err := os.ReadFile(accessLogPath)
+	suite.Require().NoError(err)
+
+	accessLogLines := strings.Split(strings.TrimSpace(string(accessLogContents)), "\n")
+	suite.Require().Len(accessLogLines, 1)
+
+	entry := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal([]byte(accessLogLines[0]), &entry))
+	suite.Require().Equal("my-http", entry["trigger"])
+	suite.Require().Equal("POST", entry["method"])
+	suite.Require().Equal("/some/path", entry["path"])
+	suite.Require().Equal(float64(nethttp.StatusServiceUnavailable), entry["status"])
+	suite.Require().Equal(float64(4), entry["requestBodySize"])
+	suite.Require().Equal(float64(-1), entry["workerIndex"])
+	suite.Require().Equal("my-request-id", entry["requestID"])
+}
+
 func (suite *TestSuite) TestHttpTriggerMode() {
 	for _, testCase := range []struct {
 		name         string
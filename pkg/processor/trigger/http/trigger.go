@@ -46,6 +46,13 @@ var (
 	timeoutResponse = []byte(`{"error": "handler timed out"}`)
 )
 
+const (
+	workerIndexUserValueKey = "nuclio-worker-index"
+
+	// set on requests whose access log entry was written when their batch was processed
+	batchAccessLoggedUserValueKey = "nuclio-batch-access-logged"
+)
+
 type http struct {
 	trigger.AbstractTrigger
 	configuration      *Configuration
@@ -229,6 +236,11 @@ func (h *http) AllocateWorkerAndSubmitEvent(ctx *fasthttp.RequestCtx,
 	event := &h.events[workerIndex]
 	event.ctx = ctx
 
+	// let the access log know which worker handled the request
+	if h.AccessLogger != nil {
+		ctx.SetUserValue(workerIndexUserValueKey, workerIndex)
+	}
+
 	// submit to worker
 	response, processError = h.SubmitEventToWorker(functionLogger, workerInstance, event)
 
@@ -434,6 +446,11 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	// write the access log entry once the response is ready
+	if h.AccessLogger != nil && h.AccessLogger.Sampled() {
+		defer h.writeAccessLog(ctx, time.Now())
+	}
+
 	// perform pre request handling validation
 	if !h.preHandleRequestValidation(ctx) {
 
@@ -492,8 +509,14 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 				response = typedResponse.Response
 				submitError = typedResponse.SubmitError
 				processError = typedResponse.ProcessError
+
+				// events that failed submission never reached the batch, and aren't logged with it
+				if submitError == nil {
+					ctx.SetUserValue(batchAccessLoggedUserValueKey, true)
+				}
 			case nuclio.Response:
 				response = typedResponse
+				ctx.SetUserValue(batchAccessLoggedUserValueKey, true)
 			}
 		}
 		// if event processing is not yet canceled, cancel it
@@ -646,6 +669,34 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 	}
 }
 
+func (h *http) writeAccessLog(ctx *fasthttp.RequestCtx, startTime time.Time) {
+	if ctx.UserValue(batchAccessLoggedUserValueKey) != nil {
+		return
+	}
+
+	workerIndex := -1
+	if userValueWorkerIndex, isInt := ctx.UserValue(workerIndexUserValueKey).(int); isInt {
+		workerIndex = userValueWorkerIndex
+	}
+
+	// don't read streamed bodies just to measure them
+	responseBodySize := ctx.Response.Header.ContentLength()
+	if !ctx.Response.IsBodyStream() {
+		responseBodySize = len(ctx.Response.Body())
+	}
+
+	h.AccessLogger.Write("trigger", h.Name,
+		"triggerKind", h.Kind,
+		"method", string(ctx.Method()),
+		"path", string(ctx.URI().Path()),
+		"status", ctx.Response.StatusCode(),
+		"latency", time.Since(startTime).String(),
+		"requestBodySize", len(ctx.Request.Body()),
+		"responseBodySize", responseBodySize,
+		"workerIndex", workerIndex,
+		"requestID", string(ctx.Request.Header.Peek(headers.RequestID)))
+}
+
 func (h *http) allocateEvents(size int) {
 	h.events = make([]Event, size)
 	for i := 0; i < size; i++ {
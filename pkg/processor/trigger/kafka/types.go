@@ -349,7 +349,7 @@ func NewConfiguration(id string,
 		&newConfiguration.AccessKey,
 		&newConfiguration.AccessCertificate,
 	} {
-		*cert = newConfiguration.unflattenCertificate(*cert)
+		*cert = common.UnflattenCertificate(*cert)
 	}
 
 	return &newConfiguration, nil
@@ -397,49 +397,6 @@ func (c *Configuration) resolveBrokers(brokers []string) ([]string, error) {
 	return nil, errors.New("Brokers must be passed either in url or attributes.brokers")
 }
 
-func (c *Configuration) unflattenCertificate(certificate string) string {
-
-	// if there are newlines in the certificate, it's not flat. return as is
-	if strings.Contains(certificate, "\n") {
-		return certificate
-	}
-
-	// in this mode, the user replaces newlines with "@"
-	if strings.Contains(certificate, "@") {
-		return strings.ReplaceAll(certificate, "@", "\n")
-	}
-
-	//
-	// try to be fancy and try to auto-unflatten the certificate
-	//
-
-	headers := []string{
-		"BEGIN CERTIFICATE",
-		"END CERTIFICATE",
-		"BEGIN PRIVATE KEY",
-		"END PRIVATE KEY",
-	}
-
-	// headers have spaces... remove them temporarily
-	for _, spacedHeader := range headers {
-		certificate = strings.ReplaceAll(certificate,
-			spacedHeader,
-			strings.ReplaceAll(spacedHeader, " ", "-"))
-	}
-
-	// now replace all spaces with newline
-	certificate = strings.ReplaceAll(certificate, " ", "\n")
-
-	// and revert header
-	for _, spacedHeader := range headers {
-		certificate = strings.ReplaceAll(certificate,
-			strings.ReplaceAll(spacedHeader, " ", "-"),
-			spacedHeader)
-	}
-
-	return certificate
-}
-
 // populateValuesFromMountedSecrets will populate sensitive configuration fields from mounted secrets, if the field is a path
 func (c *Configuration) populateValuesFromMountedSecrets(logger logger.Logger) error {
 	basePath := ""
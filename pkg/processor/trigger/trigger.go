@@ -24,8 +24,11 @@ import (
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/common/headers"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/eventrecorder"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
@@ -100,6 +103,12 @@ type Trigger interface {
 
 	// PostBatchHooks does trigger-specific actions after sending a batch
 	PostBatchHooks(batch []nuclio.Event, workerInstance *worker.Worker)
+
+	// GetEventRecorder returns the recorder of events received by the trigger
+	GetEventRecorder() *eventrecorder.Recorder
+
+	// GetAccessLogger returns the trigger's access logger, nil if access logging is not enabled
+	GetAccessLogger() *AccessLogger
 }
 
 // AbstractTrigger implements common trigger operations
@@ -120,6 +129,8 @@ type AbstractTrigger struct {
 	ProjectName     string
 	restartChan     chan Trigger
 	Batcher         *Batcher
+	AccessLogger    *AccessLogger
+	EventRecorder   *eventrecorder.Recorder
 }
 
 func NewAbstractTrigger(logger logger.Logger,
@@ -155,9 +166,33 @@ func NewAbstractTrigger(logger logger.Logger,
 	if functionconfig.BatchModeEnabled(configuration.Batch) {
 		trigger.Batcher = NewBatcher(logger, configuration.Batch.BatchSize)
 	}
+
+	accessLogger, err := NewAccessLogger(logger, configuration.AccessLog)
+	if err != nil {
+		return trigger, errors.Wrap(err, "Failed to create access logger")
+	}
+
+	trigger.AccessLogger = accessLogger
+
+	trigger.EventRecorder = eventrecorder.NewRecorder(logger, name, kind, getEventRecordingsDir(configuration))
+	if configuration.EventRecording != nil && configuration.EventRecording.Enabled {
+		if err := trigger.EventRecorder.Start(configuration.EventRecording); err != nil {
+			return trigger, errors.Wrap(err, "Failed to start event recording")
+		}
+	}
+
 	return trigger, nil
 }
 
+func getEventRecordingsDir(configuration *Configuration) string {
+	platformConfiguration := &platformconfig.Config{}
+	if configuration.RuntimeConfiguration != nil && configuration.RuntimeConfiguration.PlatformConfig != nil {
+		platformConfiguration = configuration.RuntimeConfiguration.PlatformConfig
+	}
+
+	return platformConfiguration.GetEventRecordingsDir()
+}
+
 // Initialize performs post creation initializations
 func (at *AbstractTrigger) Initialize() error {
 	return nil
@@ -273,6 +308,16 @@ func (at *AbstractTrigger) GetProjectName() string {
 	return at.ProjectName
 }
 
+// GetEventRecorder returns the recorder of events received by the trigger
+func (at *AbstractTrigger) GetEventRecorder() *eventrecorder.Recorder {
+	return at.EventRecorder
+}
+
+// GetAccessLogger returns the trigger's access logger, nil if access logging is not enabled
+func (at *AbstractTrigger) GetAccessLogger() *AccessLogger {
+	return at.AccessLogger
+}
+
 // HandleSubmitPanic handles a panic when submitting to worker
 func (at *AbstractTrigger) HandleSubmitPanic(workerInstance *worker.Worker,
 	submitError *error) {
@@ -302,15 +347,28 @@ func (at *AbstractTrigger) SubmitEventToWorker(functionLogger logger.Logger,
 	workerInstance *worker.Worker,
 	event nuclio.Event) (response interface{}, processError error) {
 
+	// record the event as received, before it's wrapped
+	if at.EventRecorder != nil {
+		at.EventRecorder.Record(event)
+	}
+
 	event, err := at.prepareEvent(event, workerInstance)
 	if err != nil {
 		return nil, err
 	}
 
+	startTime := time.Now()
+
 	response, processError = workerInstance.ProcessEvent(event, functionLogger)
 
 	// increment statistics based on results. if process error is nil, we successfully handled
 	at.UpdateStatistics(processError == nil, 1)
+
+	// sync triggers (http) know more about the request than we do here, and write their own entries
+	if at.AccessLogger != nil && at.Class != "sync" && at.AccessLogger.Sampled() {
+		at.writeEventAccessLog(event, workerInstance, startTime, processError)
+	}
+
 	return
 }
 
@@ -402,6 +460,88 @@ func (at *AbstractTrigger) SignalWorkersToTerminate() error {
 	return nil
 }
 
+func (at *AbstractTrigger) writeEventAccessLog(event nuclio.Event,
+	workerInstance *worker.Worker,
+	startTime time.Time,
+	processError error) {
+
+	outcome := "success"
+	if processError != nil {
+		outcome = "failure"
+	}
+
+	at.AccessLogger.Write("trigger", at.Name,
+		"triggerKind", at.Kind,
+		"eventID", event.GetID(),
+		"topic", event.GetTopic(),
+		"partition", event.GetShardID(),
+		"offset", event.GetOffset(),
+		"outcome", outcome,
+		"error", processError,
+		"latency", time.Since(startTime).String(),
+		"workerIndex", workerInstance.GetIndex())
+}
+
+func (at *AbstractTrigger) writeBatchAccessLog(batch []nuclio.Event,
+	responses []*runtime.ResponseWithErrors,
+	batchError error,
+	workerInstance *worker.Worker,
+	startTime time.Time) {
+
+	responsesByEventID := map[string]*runtime.ResponseWithErrors{}
+	for _, response := range responses {
+		responsesByEventID[response.EventId] = response
+	}
+
+	latency := time.Since(startTime).String()
+
+	for _, event := range batch {
+		if !at.AccessLogger.Sampled() {
+			continue
+		}
+
+		processError := batchError
+		statusCode := 0
+		if response, responseFound := responsesByEventID[string(event.GetID())]; responseFound {
+			processError = response.ProcessError
+			statusCode = response.StatusCode
+		} else if processError == nil {
+			processError = runtime.ErrNoResponseFromBatchResponse
+		}
+
+		outcome := "success"
+		if processError != nil {
+			outcome = "failure"
+		}
+
+		vars := []interface{}{"trigger", at.Name,
+			"triggerKind", at.Kind,
+			"eventID", event.GetID(),
+			"outcome", outcome,
+			"error", processError,
+			"latency", latency,
+			"workerIndex", workerInstance.GetIndex(),
+			"batchSize", len(batch),
+		}
+
+		if at.Class == "sync" {
+			vars = append(vars,
+				"method", event.GetMethod(),
+				"path", event.GetPath(),
+				"status", statusCode,
+				"requestBodySize", len(event.GetBody()),
+				"requestID", event.GetHeaderString(headers.RequestID))
+		} else {
+			vars = append(vars,
+				"topic", event.GetTopic(),
+				"partition", event.GetShardID(),
+				"offset", event.GetOffset())
+		}
+
+		at.AccessLogger.Write(vars...)
+	}
+}
+
 func (at *AbstractTrigger) prepareEvent(event nuclio.Event, workerInstance *worker.Worker) (nuclio.Event, error) {
 
 	// if the content type starts with application/cloudevents, the body
@@ -494,6 +634,10 @@ func (at *AbstractTrigger) SubmitBatchAndSendResponses(batch []nuclio.Event, res
 	// prepare batch
 	preparedBatch := make([]nuclio.Event, 0)
 	for _, event := range batch {
+		if at.EventRecorder != nil {
+			at.EventRecorder.Record(event)
+		}
+
 		preparedEvent, submitError := at.prepareEvent(event, workerInstance)
 
 		if submitError != nil {
@@ -512,8 +656,16 @@ func (at *AbstractTrigger) SubmitBatchAndSendResponses(batch []nuclio.Event, res
 		}
 	}
 
+	startTime := time.Now()
+
 	// sending batch to the runtime
 	responses, err := workerInstance.ProcessEventBatch(preparedBatch)
+
+	// write the entries before responding, as sync triggers may recycle their events once responded to
+	if at.AccessLogger != nil {
+		at.writeBatchAccessLog(preparedBatch, responses, err, workerInstance, startTime)
+	}
+
 	if err != nil {
 		for _, channel := range responseChans {
 			go channel.Write(at.Logger, &runtime.ResponseWithErrors{ProcessError: err})
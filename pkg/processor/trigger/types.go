@@ -81,6 +81,18 @@ func NewConfiguration(id string,
 	}
 	runtimeConfiguration.WorkerTerminationTimeout = workerTerminationTimeout
 
+	// the function's shutdown timeout applies to all of its triggers' workers
+	if runtimeConfiguration.Configuration != nil {
+		shutdownTimeout, err := runtimeConfiguration.Spec.Lifecycle.GetShutdownTimeout()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to parse lifecycle shutdown timeout")
+		}
+
+		if shutdownTimeout != 0 {
+			runtimeConfiguration.WorkerTerminationTimeout = shutdownTimeout
+		}
+	}
+
 	return configuration, nil
 }
 
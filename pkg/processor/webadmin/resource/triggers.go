@@ -17,12 +17,21 @@ limitations under the License.
 package resource
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/webadmin"
 	"github.com/nuclio/nuclio/pkg/restful"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
 )
 
 type triggersResource struct {
@@ -72,6 +81,32 @@ func (tr *triggersResource) GetCustomRoutes() ([]restful.CustomRoute, error) {
 			Method:    http.MethodGet,
 			RouteFunc: tr.getStatistics,
 		},
+		{
+			Pattern:   "/{id}/workers",
+			Method:    http.MethodGet,
+			RouteFunc: tr.getWorkers,
+		},
+		{
+			Pattern:   "/{id}/recording",
+			Method:    http.MethodGet,
+			RouteFunc: tr.getRecording,
+		},
+		{
+			Pattern:   "/{id}/recording",
+			Method:    http.MethodPost,
+			RouteFunc: tr.startRecording,
+		},
+		{
+			Pattern:   "/{id}/recording",
+			Method:    http.MethodDelete,
+			RouteFunc: tr.stopRecording,
+		},
+		{
+			Pattern:         "/{id}/recording/download",
+			Method:          http.MethodGet,
+			StreamRouteFunc: tr.downloadRecording,
+			Stream:          true,
+		},
 	}, nil
 }
 
@@ -88,6 +123,123 @@ func (tr *triggersResource) getStatistics(request *http.Request) (*restful.Custo
 	}, nil
 }
 
+// getWorkers returns the status of the trigger's workers, including the crashes of their wrapper processes
+func (tr *triggersResource) getWorkers(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
+	triggerInstance, err := tr.getTriggerFromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := map[string]restful.Attributes{}
+	for _, workerInstance := range triggerInstance.GetWorkers() {
+		workerAttributes := restful.Attributes{
+			"status": workerInstance.GetStatus().String(),
+		}
+
+		if crashLoopStatus := workerInstance.GetRuntime().GetCrashLoopStatus(); crashLoopStatus != nil {
+			workerAttributes["crashLoop"] = common.StructureToMap(crashLoopStatus)
+		}
+
+		workers[strconv.Itoa(workerInstance.GetIndex())] = workerAttributes
+	}
+
+	return &restful.CustomRouteFuncResponse{
+		ResourceType: "worker",
+		Resources:    workers,
+		StatusCode:   http.StatusOK,
+	}, nil
+}
+
+func (tr *triggersResource) getRecording(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
+	triggerInstance, err := tr.getTriggerFromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.createRecordingResponse(triggerInstance), nil
+}
+
+func (tr *triggersResource) startRecording(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
+	triggerInstance, err := tr.getTriggerFromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, nuclio.WrapErrInternalServerError(errors.Wrap(err, "Failed to read body"))
+	}
+
+	eventRecording := functionconfig.EventRecording{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &eventRecording); err != nil {
+			return nil, nuclio.WrapErrBadRequest(errors.Wrap(err, "Failed to parse JSON body"))
+		}
+	}
+
+	if err := triggerInstance.GetEventRecorder().Start(&eventRecording); err != nil {
+		return nil, nuclio.WrapErrBadRequest(errors.Wrap(err, "Failed to start recording"))
+	}
+
+	return tr.createRecordingResponse(triggerInstance), nil
+}
+
+func (tr *triggersResource) stopRecording(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
+	triggerInstance, err := tr.getTriggerFromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	triggerInstance.GetEventRecorder().Stop()
+
+	return tr.createRecordingResponse(triggerInstance), nil
+}
+
+// downloadRecording streams the current recording, or the last one if none is active, as JSON lines
+func (tr *triggersResource) downloadRecording(request *http.Request) (*restful.CustomRouteFuncStreamResponse, error) {
+	triggerInstance, err := tr.getTriggerFromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	recordingReader, err := triggerInstance.GetEventRecorder().OpenRecording()
+	if err != nil {
+		return nil, nuclio.WrapErrNotFound(err)
+	}
+
+	return &restful.CustomRouteFuncStreamResponse{
+		ReadCloser: recordingReader,
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":        "application/x-ndjson",
+			"Content-Disposition": fmt.Sprintf(`attachment; filename="%s.jsonl"`, triggerInstance.GetID()),
+		},
+	}, nil
+}
+
+func (tr *triggersResource) getTriggerFromRequest(request *http.Request) (trigger.Trigger, error) {
+	triggerID := chi.URLParam(request, "id")
+
+	for _, triggerInstance := range tr.getProcessor().GetTriggers() {
+		if triggerInstance.GetID() == triggerID {
+			return triggerInstance, nil
+		}
+	}
+
+	return nil, nuclio.NewErrNotFound("Trigger not found")
+}
+
+func (tr *triggersResource) createRecordingResponse(triggerInstance trigger.Trigger) *restful.CustomRouteFuncResponse {
+	return &restful.CustomRouteFuncResponse{
+		ResourceType: "recording",
+		Resources: map[string]restful.Attributes{
+			triggerInstance.GetID(): common.StructureToMap(triggerInstance.GetEventRecorder().GetStatus()),
+		},
+		Single:     true,
+		StatusCode: http.StatusOK,
+	}
+}
+
 func (tr *triggersResource) extractIDFromConfiguration(configuration map[string]interface{}) string {
 	id := configuration["ID"].(string)
 
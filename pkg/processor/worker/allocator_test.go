@@ -95,6 +95,53 @@ func (suite *AllocatorTestSuite) TestFixedPoolAllocator() {
 	suite.Require().True(fpa.Shareable())
 }
 
+func (suite *AllocatorTestSuite) TestFixedPoolAllocatorConcurrentEvents() {
+	mockRuntime := &MockRuntime{}
+	mockRuntime.On("GetMaxConcurrentEvents").Return(3)
+
+	worker1 := &Worker{index: 0, runtime: mockRuntime}
+	worker2 := &Worker{index: 1}
+	workers := WorkerFactorySingleton.createWorkerSlots([]*Worker{worker1, worker2})
+
+	// the first worker gets a slot per additional event its runtime may process, each with an index of its own
+	suite.Require().Len(workers, 4)
+	for workerIndex, workerInstance := range workers {
+		suite.Require().Equal(workerIndex, workerInstance.GetIndex())
+	}
+
+	suite.Require().False(workers[1].IsSlot())
+	suite.Require().True(workers[2].IsSlot())
+	suite.Require().True(workers[3].IsSlot())
+	suite.Require().Equal(mockRuntime, workers[3].GetRuntime())
+
+	fpa, err := NewFixedPoolWorkerAllocator(suite.logger, workers)
+	suite.Require().NoError(err)
+	suite.Require().Equal(4, fpa.GetNumWorkersAvailable())
+
+	// every slot is allocated once, and doesn't share its cloud events
+	allocatedWorkers := map[*Worker]int{}
+	for allocationIndex := 0; allocationIndex < 4; allocationIndex++ {
+		allocatedWorker, err := fpa.Allocate(time.Hour)
+		suite.Require().NoError(err)
+		allocatedWorkers[allocatedWorker]++
+	}
+
+	suite.Require().Len(allocatedWorkers, 4)
+	suite.Require().NotSame(workers[0].GetStructuredCloudEvent(), workers[2].GetStructuredCloudEvent())
+
+	// all slots are taken
+	failedAllocationWorker, err := fpa.Allocate(50 * time.Millisecond)
+	suite.Require().Error(err)
+	suite.Require().Nil(failedAllocationWorker)
+
+	// releasing a slot allows allocating it again
+	fpa.Release(workers[2])
+
+	allocatedWorker, err := fpa.Allocate(time.Hour)
+	suite.Require().NoError(err)
+	suite.Require().Equal(workers[2], allocatedWorker)
+}
+
 func TestAllocatorTestSuite(t *testing.T) {
 	suite.Run(t, new(AllocatorTestSuite))
 }
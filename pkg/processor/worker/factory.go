@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/nuclio/nuclio/pkg/errgroup"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
@@ -87,20 +88,81 @@ func (waf *Factory) createWorker(parentLogger logger.Logger,
 	runtimeKind := runtimeConfigurationCopy.Spec.Runtime
 	runtimeKind = strings.Split(runtimeKind, ":")[0]
 
-	// create a runtime for the worker
-	runtimeInstance, err := runtime.RegistrySingleton.NewRuntime(workerLogger,
-		runtimeKind,
-		&runtimeConfigurationCopy)
+	initTimeout, err := runtimeConfigurationCopy.Spec.Lifecycle.GetInitTimeout()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse lifecycle init timeout")
+	}
+
+	// create and start a runtime for the worker, which initializes the handler's context
+	runtimeInstance, err := waf.createAndStartRuntime(workerLogger, runtimeKind, &runtimeConfigurationCopy, initTimeout)
+	if err != nil {
+		return nil, err
+	}
 
+	workerInstance, err := NewWorker(workerLogger, workerIndex, runtimeInstance)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to create runtime")
+		return nil, errors.Wrap(err, "Failed to create worker")
 	}
 
-	if err := runtimeInstance.Start(); err != nil {
-		return nil, errors.Wrap(err, "Failed to start runtime")
+	if runtimeConfigurationCopy.Spec.Lifecycle != nil {
+		if err := workerInstance.Warmup(runtimeConfigurationCopy.Spec.Lifecycle.Warmup); err != nil {
+			runtimeInstance.Stop() // nolint: errcheck
+			return nil, errors.Wrap(err, "Failed to warm up worker")
+		}
 	}
 
-	return NewWorker(workerLogger, workerIndex, runtimeInstance)
+	return workerInstance, nil
+}
+
+func (waf *Factory) createAndStartRuntime(workerLogger logger.Logger,
+	runtimeKind string,
+	runtimeConfiguration *runtime.Configuration,
+	initTimeout time.Duration) (runtime.Runtime, error) {
+
+	type createResult struct {
+		runtimeInstance runtime.Runtime
+		err             error
+	}
+
+	createResultChan := make(chan createResult, 1)
+
+	go func() {
+		runtimeInstance, err := runtime.RegistrySingleton.NewRuntime(workerLogger,
+			runtimeKind,
+			runtimeConfiguration)
+		if err != nil {
+			createResultChan <- createResult{nil, errors.Wrap(err, "Failed to create runtime")}
+			return
+		}
+
+		if err := runtimeInstance.Start(); err != nil {
+			createResultChan <- createResult{runtimeInstance, errors.Wrap(err, "Failed to start runtime")}
+			return
+		}
+
+		createResultChan <- createResult{runtimeInstance, nil}
+	}()
+
+	// without an init timeout, the runtime may take as long as it needs
+	if initTimeout == 0 {
+		result := <-createResultChan
+		return result.runtimeInstance, result.err
+	}
+
+	select {
+	case result := <-createResultChan:
+		return result.runtimeInstance, result.err
+	case <-time.After(initTimeout):
+
+		// stop the runtime once it's created, since nothing will use it
+		go func() {
+			if result := <-createResultChan; result.runtimeInstance != nil {
+				result.runtimeInstance.Stop() // nolint: errcheck
+			}
+		}()
+
+		return nil, errors.Errorf("Runtime did not initialize within %s", initTimeout)
+	}
 }
 
 func (waf *Factory) createWorkers(logger logger.Logger,
@@ -129,5 +191,26 @@ func (waf *Factory) createWorkers(logger logger.Logger,
 		return nil, errors.Wrap(err, "Failed to create workers")
 	}
 
-	return workers, nil
+	return waf.createWorkerSlots(workers), nil
+}
+
+// createWorkerSlots adds a worker per additional event the runtimes may process at a time, so that each
+// event has a worker of its own. the slots are interleaved, so that events are spread across runtimes
+func (waf *Factory) createWorkerSlots(workers []*Worker) []*Worker {
+	numWorkers := len(workers)
+
+	for slotIndex := 1; ; slotIndex++ {
+		numSlotsCreated := 0
+
+		for _, workerInstance := range workers[:numWorkers] {
+			if slotIndex < workerInstance.GetMaxConcurrentEvents() {
+				workers = append(workers, workerInstance.newSlot(len(workers)))
+				numSlotsCreated++
+			}
+		}
+
+		if numSlotsCreated == 0 {
+			return workers
+		}
+	}
 }
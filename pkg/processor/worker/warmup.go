@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+const (
+
+	// set on warmup events, so that handlers can tell them apart
+	WarmupHeader = "X-Nuclio-Warmup"
+
+	warmupTriggerKind = "warmup"
+)
+
+type warmupTriggerInfoProvider struct{}
+
+func (p *warmupTriggerInfoProvider) GetClass() string { return "sync" }
+func (p *warmupTriggerInfoProvider) GetKind() string  { return warmupTriggerKind }
+func (p *warmupTriggerInfoProvider) GetName() string  { return warmupTriggerKind }
+
+func newWarmupEvent(warmupEvent *functionconfig.WarmupEvent) nuclio.Event {
+	headers := map[string]interface{}{
+		WarmupHeader: "true",
+	}
+
+	for headerKey, headerValue := range warmupEvent.Headers {
+		headers[headerKey] = headerValue
+	}
+
+	event := &nuclio.MemoryEvent{
+		Method:      warmupEvent.Method,
+		Path:        warmupEvent.Path,
+		ContentType: warmupEvent.ContentType,
+		Headers:     headers,
+		Body:        []byte(warmupEvent.Body),
+	}
+
+	event.SetTriggerInfoProvider(&warmupTriggerInfoProvider{})
+
+	return event
+}
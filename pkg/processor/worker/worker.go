@@ -22,11 +22,13 @@ import (
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common/status"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/cloudevent"
 	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/util/clock"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
 )
@@ -43,6 +45,16 @@ type Worker struct {
 	structuredCloudEvent cloudevent.Structured
 	binaryCloudEvent     cloudevent.Binary
 	eventTime            *time.Time
+
+	// a runtime that may process several events at a time is shared by a worker per event. the first
+	// of them manages the runtime, and the rest are its slots
+	parent *Worker
+	slots  []*Worker
+
+	// the warmup events the worker handles whenever its runtime starts. while handling them (accessed
+	// atomically), the worker reports it's initializing
+	warmup    *functionconfig.Warmup
+	warmingUp int32
 }
 
 // NewWorker creates a new worker
@@ -68,24 +80,11 @@ func (w *Worker) ProcessEvent(event nuclio.Event, functionLogger logger.Logger)
 	response, err := w.runtime.ProcessEvent(event, functionLogger)
 	w.eventTime = nil
 
-	// check if there was a processing error. if so, log it
-	if err != nil {
+	// check if there was a processing error, or the response has an error status
+	if err != nil || responseError(response) != nil {
 		atomic.AddUint64(&w.statistics.EventsHandledError, 1)
 	} else {
-		success := true
-
-		switch typedResponse := response.(type) {
-		case *nuclio.Response:
-			success = typedResponse.StatusCode < http.StatusBadRequest
-		case nuclio.Response:
-			success = typedResponse.StatusCode < http.StatusBadRequest
-		}
-
-		if success {
-			atomic.AddUint64(&w.statistics.EventsHandledSuccess, 1)
-		} else {
-			atomic.AddUint64(&w.statistics.EventsHandledError, 1)
-		}
+		atomic.AddUint64(&w.statistics.EventsHandledSuccess, 1)
 	}
 
 	return response, err
@@ -112,11 +111,39 @@ func (w *Worker) GetRuntime() runtime.Runtime {
 
 // GetStatus returns the status of the worker, as updated by the runtime
 func (w *Worker) GetStatus() status.Status {
-	return w.runtime.GetStatus()
+	if w.parent != nil {
+		return w.parent.GetStatus()
+	}
+
+	runtimeStatus := w.runtime.GetStatus()
+
+	// a runtime that's ready isn't taking traffic until it's warmed up
+	if runtimeStatus == status.Ready && atomic.LoadInt32(&w.warmingUp) == 1 {
+		return status.Initializing
+	}
+
+	return runtimeStatus
+}
+
+// Warmup passes the warmup events to the runtime, and does so again whenever the worker is restarted or the
+// runtime restarts by itself. Slots share the warmup of the worker that manages their runtime
+func (w *Worker) Warmup(warmup *functionconfig.Warmup) error {
+	if w.parent != nil {
+		return nil
+	}
+
+	w.warmup = warmup
+	w.runtime.SetRestartListener(w.warmupRestartedRuntime)
+
+	return w.runWarmup()
 }
 
 // Stop stops the worker and associated runtime
 func (w *Worker) Stop() error {
+	if w.parent != nil {
+		return nil
+	}
+
 	return w.runtime.Stop()
 }
 
@@ -140,10 +167,37 @@ func (w *Worker) ResetEventTime() {
 	w.eventTime = nil
 }
 
-// Restart restarts the worker
+// GetMaxConcurrentEvents returns the number of events the worker's runtime may process at a time
+func (w *Worker) GetMaxConcurrentEvents() int {
+	if w.runtime == nil {
+		return 1
+	}
+
+	return w.runtime.GetMaxConcurrentEvents()
+}
+
+// IsSlot returns true if the worker shares the runtime of another worker, which manages it
+func (w *Worker) IsSlot() bool {
+	return w.parent != nil
+}
+
+// Restart restarts the worker. Restarting a slot restarts the runtime it shares
 func (w *Worker) Restart() error {
-	w.eventTime = nil
-	return w.runtime.Restart()
+	if w.parent != nil {
+		return w.parent.Restart()
+	}
+
+	// the events of the slots are dropped along with the runtime
+	w.ResetEventTime()
+	for _, slot := range w.slots {
+		slot.ResetEventTime()
+	}
+
+	if err := w.runtime.Restart(); err != nil {
+		return err
+	}
+
+	return w.runWarmup()
 }
 
 // SupportsRestart returns true if the underlying runtime supports restart
@@ -152,6 +206,10 @@ func (w *Worker) SupportsRestart() bool {
 }
 
 func (w *Worker) Terminate() error {
+	if w.parent != nil {
+		return nil
+	}
+
 	if err := w.runtime.Terminate(); err != nil {
 		return err
 	}
@@ -160,6 +218,10 @@ func (w *Worker) Terminate() error {
 }
 
 func (w *Worker) Drain() error {
+	if w.parent != nil {
+		return nil
+	}
+
 	if err := w.runtime.Drain(); err != nil {
 		return err
 	}
@@ -168,6 +230,10 @@ func (w *Worker) Drain() error {
 }
 
 func (w *Worker) Continue() error {
+	if w.parent != nil {
+		return nil
+	}
+
 	if err := w.runtime.Continue(); err != nil {
 		return err
 	}
@@ -175,12 +241,137 @@ func (w *Worker) Continue() error {
 	return nil
 }
 
+func (w *Worker) runWarmup() error {
+	if w.warmup == nil || len(w.warmup.Events) == 0 {
+		return nil
+	}
+
+	timeout, err := w.warmup.GetTimeout()
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse warmup timeout")
+	}
+
+	atomic.StoreInt32(&w.warmingUp, 1)
+	defer atomic.StoreInt32(&w.warmingUp, 0)
+
+	w.logger.DebugWith("Warming up worker", "workerIndex", w.index, "events", len(w.warmup.Events))
+
+	warmupDone := make(chan error, 1)
+	go func() {
+		warmupDone <- w.processWarmupEvents()
+	}()
+
+	var timeoutChan <-chan time.Time
+	if timeout != 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		timeoutChan = timer.C
+	}
+
+	select {
+	case err := <-warmupDone:
+		if err != nil {
+			return errors.Wrap(err, "Failed to warm up worker")
+		}
+	case <-timeoutChan:
+		return errors.Errorf("Worker did not warm up within %s", timeout)
+	}
+
+	w.logger.DebugWith("Worker warmed up", "workerIndex", w.index)
+
+	return nil
+}
+
+// warmupRestartedRuntime warms up a runtime that restarted by itself, in the background. The worker reports
+// it's initializing from the moment the runtime restarted
+func (w *Worker) warmupRestartedRuntime() {
+	if w.warmup == nil || len(w.warmup.Events) == 0 {
+		return
+	}
+
+	atomic.StoreInt32(&w.warmingUp, 1)
+
+	go func() {
+		if err := w.runWarmup(); err != nil {
+			w.logger.WarnWith("Failed to warm up restarted runtime", "workerIndex", w.index, "err", err.Error())
+
+			// like a worker that fails to warm up when created, it doesn't take traffic
+			w.runtime.SetStatus(status.Error)
+		}
+	}()
+}
+
+func (w *Worker) processWarmupEvents() error {
+	for eventIndex, warmupEvent := range w.warmup.Events {
+		event := newWarmupEvent(&warmupEvent)
+
+		response, err := w.runtime.ProcessEvent(event, w.logger)
+		if err == nil {
+			err = responseError(response)
+		}
+
+		if err != nil {
+			if w.warmup.FailOnError {
+				return errors.Wrapf(err, "Warmup event %d failed", eventIndex)
+			}
+
+			w.logger.WarnWith("Warmup event failed",
+				"workerIndex", w.index,
+				"eventIndex", eventIndex,
+				"err", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// newSlot creates a worker that processes events at the worker's runtime alongside it
+func (w *Worker) newSlot(index int) *Worker {
+	slot := &Worker{
+		logger:  w.logger,
+		index:   index,
+		runtime: w.runtime,
+		parent:  w,
+	}
+
+	w.slots = append(w.slots, slot)
+
+	return slot
+}
+
 // Subscribe subscribes to a control message kind
 func (w *Worker) Subscribe(kind controlcommunication.ControlMessageKind, channel chan *controlcommunication.ControlMessage) error {
+	if w.parent != nil {
+		return nil
+	}
+
 	return w.runtime.GetControlMessageBroker().Subscribe(kind, channel)
 }
 
 // Unsubscribe unsubscribes from a control message kind
 func (w *Worker) Unsubscribe(kind controlcommunication.ControlMessageKind, channel chan *controlcommunication.ControlMessage) error {
+	if w.parent != nil {
+		return nil
+	}
+
 	return w.runtime.GetControlMessageBroker().Unsubscribe(kind, channel)
 }
+
+// responseError returns an error if the response has an error status
+func responseError(response interface{}) error {
+	statusCode := http.StatusOK
+
+	switch typedResponse := response.(type) {
+	case *nuclio.Response:
+		statusCode = typedResponse.StatusCode
+	case nuclio.Response:
+		statusCode = typedResponse.StatusCode
+	}
+
+	if statusCode >= http.StatusBadRequest {
+		return errors.Errorf("Responded with status %d", statusCode)
+	}
+
+	return nil
+}
@@ -19,11 +19,16 @@ limitations under the License.
 package worker
 
 import (
+	"errors"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/nuclio/nuclio/pkg/common/status"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/util/clock"
 
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
@@ -34,6 +39,7 @@ import (
 
 type MockRuntime struct {
 	mock.Mock
+	restartListener func()
 }
 
 func (mr *MockRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
@@ -95,11 +101,24 @@ func (mr *MockRuntime) Continue() error {
 	return args.Error(0)
 }
 
+func (mr *MockRuntime) GetMaxConcurrentEvents() int {
+	args := mr.Called()
+	return args.Int(0)
+}
+
+func (mr *MockRuntime) GetCrashLoopStatus() *runtime.CrashLoopStatus {
+	return nil
+}
+
 func (mr *MockRuntime) SupportsControlCommunication() bool {
 	args := mr.Called()
 	return args.Bool(0)
 }
 
+func (mr *MockRuntime) SetRestartListener(restartListener func()) {
+	mr.restartListener = restartListener
+}
+
 func (mr *MockRuntime) GetControlMessageBroker() controlcommunication.ControlMessageBroker {
 	return nil
 }
@@ -132,6 +151,154 @@ func (suite *WorkerTestSuite) TestProcessEvent() {
 	suite.Require().NotNil(event.GetID())
 }
 
+func (suite *WorkerTestSuite) TestSlots() {
+	mockRuntime := MockRuntime{}
+	worker, _ := NewWorker(suite.logger, 0, &mockRuntime)
+	slot := worker.newSlot(1)
+	firstEvent := &nuclio.AbstractEvent{}
+	secondEvent := &nuclio.AbstractEvent{}
+
+	firstEventProcessing := make(chan struct{})
+	firstEventDone := make(chan struct{})
+
+	// the first event is blocked until the second one is done
+	mockRuntime.On("ProcessEvent", firstEvent, suite.logger).Run(func(mock.Arguments) {
+		close(firstEventProcessing)
+		<-firstEventDone
+	}).Return(nil, nil).Once()
+	mockRuntime.On("ProcessEvent", secondEvent, suite.logger).Return(nil, nil).Once()
+
+	processed := make(chan struct{})
+	go func() {
+		worker.ProcessEvent(firstEvent, suite.logger) // nolint: errcheck
+		close(processed)
+	}()
+
+	<-firstEventProcessing
+	suite.Require().NotNil(worker.GetEventTime())
+
+	// the slot processes the second event at the same runtime, and tracks it on its own
+	_, err := slot.ProcessEvent(secondEvent, suite.logger)
+	suite.Require().NoError(err)
+	suite.Require().Nil(slot.GetEventTime())
+	suite.Require().NotNil(worker.GetEventTime())
+
+	close(firstEventDone)
+	<-processed
+	suite.Require().Nil(worker.GetEventTime())
+
+	// the runtime is managed by the worker alone
+	mockRuntime.On("Drain").Return(nil).Once()
+	suite.Require().NoError(slot.Drain())
+	suite.Require().NoError(worker.Drain())
+
+	// restarting the slot restarts the shared runtime, dropping the events of all of its workers
+	worker.eventTime = clock.Now()
+	slot.eventTime = clock.Now()
+	suite.Require().NoError(slot.Restart())
+	suite.Require().Nil(worker.GetEventTime())
+	suite.Require().Nil(slot.GetEventTime())
+
+	mockRuntime.AssertExpectations(suite.T())
+}
+
+func (suite *WorkerTestSuite) TestWarmup() {
+	mockRuntime := MockRuntime{}
+	worker, _ := NewWorker(suite.logger, 100, &mockRuntime)
+
+	var warmupStatuses []status.Status
+
+	// the worker doesn't report ready while warming up, and warmup events are marked as such
+	mockRuntime.On("ProcessEvent", mock.MatchedBy(func(event nuclio.Event) bool {
+		return event.GetHeaderString(WarmupHeader) == "true" || event.GetHeader(WarmupHeader) == "true"
+	}), suite.logger).Run(func(mock.Arguments) {
+		warmupStatuses = append(warmupStatuses, worker.GetStatus())
+	}).Return(nuclio.Response{StatusCode: http.StatusInternalServerError}, nil).Twice()
+
+	err := worker.Warmup(&functionconfig.Warmup{
+		Events: []functionconfig.WarmupEvent{
+			{Body: "load"},
+			{Path: "/predict", Body: "{}"},
+		},
+	})
+
+	// failing warmup events are only logged
+	suite.Require().NoError(err)
+	suite.Require().Equal([]status.Status{status.Initializing, status.Initializing}, warmupStatuses)
+	suite.Require().Equal(status.Ready, worker.GetStatus())
+	mockRuntime.AssertExpectations(suite.T())
+
+	// warmup events don't count as handled events
+	suite.Require().Zero(worker.GetStatistics().EventsHandledError)
+
+	// unless the worker should fail on them
+	mockRuntime.On("ProcessEvent", mock.Anything, suite.logger).Return(nil, errors.New("not loaded")).Once()
+
+	err = worker.Warmup(&functionconfig.Warmup{
+		Events:      []functionconfig.WarmupEvent{{Body: "load"}},
+		FailOnError: true,
+	})
+	suite.Require().Error(err)
+}
+
+func (suite *WorkerTestSuite) TestWarmupTimeout() {
+	mockRuntime := MockRuntime{}
+	worker, _ := NewWorker(suite.logger, 100, &mockRuntime)
+
+	warmupDone := make(chan struct{})
+	defer close(warmupDone)
+
+	mockRuntime.On("ProcessEvent", mock.Anything, suite.logger).Run(func(mock.Arguments) {
+		<-warmupDone
+	}).Return(nil, nil).Once()
+
+	err := worker.Warmup(&functionconfig.Warmup{
+		Events:  []functionconfig.WarmupEvent{{Body: "load"}},
+		Timeout: "100ms",
+	})
+	suite.Require().Error(err)
+	suite.Require().Equal(status.Ready, worker.GetStatus())
+}
+
+func (suite *WorkerTestSuite) TestWarmupAfterRuntimeRestart() {
+	mockRuntime := MockRuntime{}
+	worker, _ := NewWorker(suite.logger, 0, &mockRuntime)
+
+	mockRuntime.On("ProcessEvent", mock.Anything, suite.logger).Return(nil, nil).Once()
+
+	err := worker.Warmup(&functionconfig.Warmup{
+		Events: []functionconfig.WarmupEvent{{Body: "load"}},
+	})
+	suite.Require().NoError(err)
+
+	// slots are created once their worker warmed up, and share its warmup
+	slot := worker.newSlot(1)
+	suite.Require().NoError(slot.Warmup(nil))
+
+	warmupEventProcessing := make(chan struct{})
+	warmupEventDone := make(chan struct{})
+
+	mockRuntime.On("ProcessEvent", mock.Anything, suite.logger).Run(func(mock.Arguments) {
+		close(warmupEventProcessing)
+		<-warmupEventDone
+	}).Return(nil, nil).Once()
+
+	// the runtime restarted by itself (e.g. its wrapper crashed), so it's warmed up again
+	mockRuntime.restartListener()
+	<-warmupEventProcessing
+
+	suite.Require().Equal(status.Initializing, worker.GetStatus())
+	suite.Require().Equal(status.Initializing, slot.GetStatus())
+
+	close(warmupEventDone)
+
+	suite.Require().Eventually(func() bool {
+		return worker.GetStatus() == status.Ready && slot.GetStatus() == status.Ready
+	}, time.Second, 10*time.Millisecond)
+
+	mockRuntime.AssertExpectations(suite.T())
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestWorkerTestSuite(t *testing.T) {
@@ -19,6 +19,8 @@ package sinks
 import (
 	// import all sinks
 	_ "github.com/nuclio/nuclio/pkg/loggersink/appinsights"
+	_ "github.com/nuclio/nuclio/pkg/loggersink/loki"
+	_ "github.com/nuclio/nuclio/pkg/loggersink/otlp"
 	_ "github.com/nuclio/nuclio/pkg/loggersink/stdout"
 	_ "github.com/nuclio/nuclio/pkg/processor/metricsink/appinsights"
 	_ "github.com/nuclio/nuclio/pkg/processor/metricsink/prometheus/pull"
@@ -0,0 +1,24 @@
+#!/bin/sh
+
+# Copyright 2023 The Nuclio Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# started once, handles newline delimited events until stdin is closed. responds with the
+# uppercased event body, suffixed by the pid so callers can tell whether the process was reused
+while IFS= read -r line; do
+  case $line in
+    "hang") while true; do sleep 0.1; done ;;
+    *)      echo "$(echo "$line" | tr '[:lower:]' '[:upper:]') $$" ;;
+  esac
+done